@@ -0,0 +1,13 @@
+// Command shootlog extracts EXIF metadata from photos and summarizes
+// shooting sessions.
+package main
+
+import (
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
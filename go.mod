@@ -0,0 +1,3 @@
+module github.com/ryoh827/shootlog
+
+go 1.22
@@ -0,0 +1,168 @@
+// Package album reads and writes album manifests: a curated list of photos
+// with their order, captions and cover, kept apart from the folders the
+// files live in. Paths in a manifest file are relative to its directory,
+// so an album moves along with the photos.
+package album
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/yaml"
+)
+
+// Album is a manifest. Items are in presentation order.
+type Album struct {
+	Title string `json:"title,omitempty"`
+	// Cover is the path of the item that represents the album; "" selects
+	// the first item.
+	Cover string `json:"cover,omitempty"`
+	Items []Item `json:"items"`
+}
+
+// Item is one photo of an album.
+type Item struct {
+	Path    string `json:"path"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// New makes an album of summaries in order, captioned with their titles or
+// descriptions.
+func New(title string, summaries []*exif.Summary) *Album {
+	a := &Album{Title: title, Items: make([]Item, 0, len(summaries))}
+	for _, s := range summaries {
+		caption := s.Title
+		if caption == "" {
+			caption = s.Description
+		}
+		a.Items = append(a.Items, Item{Path: s.Path, Caption: caption})
+	}
+	return a
+}
+
+// Load reads a manifest, choosing the format from the file extension:
+// .json, or .yaml/.yml. Relative paths are resolved against the
+// manifest's directory.
+func Load(path string) (*Album, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var a Album
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &a)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &a)
+	default:
+		return nil, fmt.Errorf("album: %s: unsupported manifest format (want .json or .yaml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("album: %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+	a.Cover = resolve(a.Cover)
+	for i := range a.Items {
+		a.Items[i].Path = resolve(a.Items[i].Path)
+	}
+	if err := a.Validate(); err != nil {
+		return nil, fmt.Errorf("album: %s: %w", path, err)
+	}
+	return &a, nil
+}
+
+// Validate checks that every item has a path, no photo is listed twice
+// and the cover is one of the items.
+func (a *Album) Validate() error {
+	if len(a.Items) == 0 {
+		return errors.New("no items")
+	}
+	seen := map[string]bool{}
+	for i, it := range a.Items {
+		if it.Path == "" {
+			return fmt.Errorf("item %d: no path", i+1)
+		}
+		if seen[it.Path] {
+			return fmt.Errorf("item %d: %s is listed twice", i+1, it.Path)
+		}
+		seen[it.Path] = true
+	}
+	if a.Cover != "" && !seen[a.Cover] {
+		return fmt.Errorf("cover %s is not an item", a.Cover)
+	}
+	return nil
+}
+
+// Marshal encodes the album as indented JSON for a manifest file in dir,
+// with the paths made relative to it.
+func (a *Album) Marshal(dir string) ([]byte, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel := func(p string) (string, error) {
+		if p == "" {
+			return "", nil
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		r, err := filepath.Rel(dir, abs)
+		if err != nil {
+			// On another volume there is no relative path.
+			return abs, nil
+		}
+		return filepath.ToSlash(r), nil
+	}
+	out := Album{Title: a.Title, Items: make([]Item, len(a.Items))}
+	if out.Cover, err = rel(a.Cover); err != nil {
+		return nil, err
+	}
+	for i, it := range a.Items {
+		out.Items[i] = it
+		if out.Items[i].Path, err = rel(it.Path); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	return append(data, '\n'), err
+}
+
+// Paths returns the paths of the items in order.
+func (a *Album) Paths() []string {
+	paths := make([]string, len(a.Items))
+	for i, it := range a.Items {
+		paths[i] = it.Path
+	}
+	return paths
+}
+
+// Caption returns the caption of the item at path.
+func (a *Album) Caption(path string) string {
+	for _, it := range a.Items {
+		if it.Path == path {
+			return it.Caption
+		}
+	}
+	return ""
+}
+
+// CoverPath returns the path of the cover photo.
+func (a *Album) CoverPath() string {
+	if a.Cover == "" && len(a.Items) > 0 {
+		return a.Items[0].Path
+	}
+	return a.Cover
+}
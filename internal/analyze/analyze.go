@@ -0,0 +1,116 @@
+// Package analyze runs optional analyses of image files after their
+// metadata is extracted, such as exposure estimates and dominant colors
+// from the embedded thumbnail. Analyses cost more than reading metadata,
+// so each run opts into them by name; other packages can register their
+// own.
+package analyze
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Analyzer adds fields to a summary from the contents of its file.
+type Analyzer interface {
+	// Analyze sets the analyzer's fields of s from data, the file's
+	// contents. Files it has nothing to say about are left as they are
+	// without an error. It must be safe to call from several goroutines.
+	Analyze(s *exif.Summary, data []byte) error
+	// Done reports whether s already carries the analyzer's fields, so
+	// that summaries kept from an earlier run, as an index keeps them,
+	// need not be analyzed again.
+	Done(s *exif.Summary) bool
+}
+
+var (
+	mu        sync.RWMutex
+	analyzers = map[string]Analyzer{}
+)
+
+// Register makes an analyzer available under name. Built-in analyzers
+// register themselves as exposure, colors and image-hash.
+func Register(name string, a Analyzer) {
+	mu.Lock()
+	defer mu.Unlock()
+	analyzers[name] = a
+}
+
+// Names lists the registered analyzers.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(analyzers))
+	for n := range analyzers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// step is an analyzer of a pipeline and the name it was chosen by.
+type step struct {
+	name string
+	a    Analyzer
+}
+
+// Pipeline is the analyzers of one run, applied in order.
+type Pipeline []step
+
+// Parse returns the pipeline of the named analyzers. Names may repeat;
+// each analyzer runs once, in the order first named.
+func Parse(names []string) (Pipeline, error) {
+	var p Pipeline
+	seen := map[string]bool{}
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" || seen[n] {
+			continue
+		}
+		mu.RLock()
+		a, ok := analyzers[n]
+		mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("analyze: unknown analyzer %q (want one of %s)", n, strings.Join(Names(), ", "))
+		}
+		seen[n] = true
+		p = append(p, step{n, a})
+	}
+	return p, nil
+}
+
+// Names lists the analyzers of p in order.
+func (p Pipeline) Names() []string {
+	names := make([]string, len(p))
+	for i, s := range p {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Pending returns the analyzers of p that s still lacks the fields of.
+func (p Pipeline) Pending(s *exif.Summary) Pipeline {
+	var out Pipeline
+	for _, st := range p {
+		if !st.a.Done(s) {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// Run applies every analyzer of p to s and data. An analyzer that fails
+// does not stop the others; the errors are returned together, each
+// prefixed with the analyzer's name.
+func (p Pipeline) Run(s *exif.Summary, data []byte) []error {
+	var errs []error
+	for _, st := range p {
+		if err := st.a.Analyze(s, data); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", st.name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+package analyze
+
+import (
+	"errors"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exposure"
+	"github.com/ryoh827/shootlog/internal/palette"
+)
+
+func init() {
+	Register("exposure", exposureAnalyzer{})
+	Register("colors", colorsAnalyzer{})
+	Register("image-hash", imageHashAnalyzer{})
+}
+
+// exposureAnalyzer estimates luminance and clipping from the embedded
+// thumbnail.
+type exposureAnalyzer struct{}
+
+func (exposureAnalyzer) Analyze(s *exif.Summary, data []byte) error {
+	if e, tag, ok := exposure.FromFile(data); ok {
+		exposure.Apply(s, e, tag)
+	}
+	return nil
+}
+
+func (exposureAnalyzer) Done(s *exif.Summary) bool { return len(s.LuminanceHistogram) > 0 }
+
+// colorsAnalyzer names the dominant colors of the embedded thumbnail.
+type colorsAnalyzer struct{}
+
+func (colorsAnalyzer) Analyze(s *exif.Summary, data []byte) error {
+	if colors, tag, ok := palette.FromFile(data); ok {
+		palette.Apply(s, colors, tag)
+	}
+	return nil
+}
+
+func (colorsAnalyzer) Done(s *exif.Summary) bool { return s.Colors != nil }
+
+// imageHashAnalyzer hashes the image data apart from the metadata, so
+// that copies whose metadata differs can be matched.
+type imageHashAnalyzer struct{}
+
+func (imageHashAnalyzer) Analyze(s *exif.Summary, data []byte) error {
+	h, err := exif.ImageDataHash(data)
+	if errors.Is(err, exif.ErrFormat) {
+		// Formats whose image data cannot be told from their metadata.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.ImageHash = h
+	return nil
+}
+
+func (imageHashAnalyzer) Done(s *exif.Summary) bool { return s.ImageHash != "" }
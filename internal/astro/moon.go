@@ -0,0 +1,41 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// moonLongitude returns the moon's ecliptic longitude and latitude in
+// degrees, from the leading terms of its orbit.
+func moonLongitude(T float64) (lambda, beta float64) {
+	d := T * 36525
+	l := 218.316 + 13.176396*d
+	m := 134.963 + 13.064993*d
+	f := 93.272 + 13.229350*d
+	return l + 6.289*math.Sin(m*rad), 5.128 * math.Sin(f*rad)
+}
+
+// MoonPhase returns the moon's age as a fraction of its cycle, 0 at new
+// moon and 0.5 at full moon, and the illuminated fraction of its disc,
+// from 0 to 1.
+func MoonPhase(t time.Time) (phase, illumination float64) {
+	T := julianCenturies(t)
+	lambda, _ := moonLongitude(T)
+	elongation := math.Mod(math.Mod(lambda-sunLongitude(T), 360)+360, 360)
+	return elongation / 360, (1 - math.Cos(elongation*rad)) / 2
+}
+
+// Moon returns the moon's altitude above the horizon and its azimuth
+// east of north, both in degrees, at t for an observer at lat, lon.
+// Parallax, which lowers the moon by up to a degree, is included.
+func Moon(t time.Time, lat, lon float64) (altitude, azimuth float64) {
+	T := julianCenturies(t)
+	lambda, beta := moonLongitude(T)
+	eps := obliquity(T) * rad
+	l, b := lambda*rad, beta*rad
+	ra := math.Atan2(math.Sin(l)*math.Cos(eps)-math.Tan(b)*math.Sin(eps), math.Cos(l)) / rad
+	decl := math.Asin(math.Sin(b)*math.Cos(eps)+math.Cos(b)*math.Sin(eps)*math.Sin(l)) / rad
+	sidereal := 280.16 + 360.9856235*T*36525 + lon
+	altitude, azimuth = horizontal(math.Mod(sidereal-ra, 360), decl, lat)
+	return altitude - 0.95*math.Cos(altitude*rad), azimuth
+}
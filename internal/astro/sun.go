@@ -0,0 +1,97 @@
+// Package astro computes the positions of the sun and moon and the phase
+// of the moon as seen from a place on Earth. The sun is accurate to a
+// fraction of a degree and the moon to about a degree between 1900 and
+// 2100, which is plenty for classifying light.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Light phases by sun elevation.
+const (
+	// Day is the sun more than 6° above the horizon.
+	Day = "day"
+	// GoldenHour is the sun between 6° above and 4° below the horizon.
+	GoldenHour = "golden-hour"
+	// BlueHour is the sun between 4° and 6° below the horizon.
+	BlueHour = "blue-hour"
+	// Night is the sun more than 6° below the horizon.
+	Night = "night"
+)
+
+// Phases lists the light phases from brightest to darkest.
+var Phases = []string{Day, GoldenHour, BlueHour, Night}
+
+const rad = math.Pi / 180
+
+// julianCenturies returns the time since J2000.0 in Julian centuries.
+func julianCenturies(t time.Time) float64 {
+	jd := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+	return (jd - 2451545) / 36525
+}
+
+// Sun returns the sun's elevation above the horizon and its azimuth east
+// of north, both in degrees, at t for an observer at lat, lon. It follows
+// the NOAA solar calculator and ignores atmospheric refraction.
+func Sun(t time.Time, lat, lon float64) (elevation, azimuth float64) {
+	T := julianCenturies(t)
+	l0 := math.Mod(280.46646+T*(36000.76983+T*0.0003032), 360)
+	m := 357.52911 + T*(35999.05029-0.0001537*T)
+	e := 0.016708634 - T*(0.000042037+0.0000001267*T)
+	lambda := sunLongitude(T)
+	omega := 125.04 - 1934.136*T
+	eps := obliquity(T) + 0.00256*math.Cos(omega*rad)
+	decl := math.Asin(math.Sin(eps*rad) * math.Sin(lambda*rad))
+
+	y := math.Pow(math.Tan(eps*rad/2), 2)
+	eqTime := 4 / rad * (y*math.Sin(2*l0*rad) - 2*e*math.Sin(m*rad) +
+		4*e*y*math.Sin(m*rad)*math.Cos(2*l0*rad) -
+		0.5*y*y*math.Sin(4*l0*rad) - 1.25*e*e*math.Sin(2*m*rad))
+	u := t.UTC()
+	minutes := float64(u.Hour()*60+u.Minute()) + float64(u.Second())/60
+	trueSolar := math.Mod(minutes+eqTime+4*lon, 1440)
+	hourAngle := trueSolar/4 - 180
+
+	return horizontal(hourAngle, decl/rad, lat)
+}
+
+// sunLongitude returns the sun's apparent ecliptic longitude in degrees.
+func sunLongitude(T float64) float64 {
+	l0 := math.Mod(280.46646+T*(36000.76983+T*0.0003032), 360)
+	m := 357.52911 + T*(35999.05029-0.0001537*T)
+	c := math.Sin(m*rad)*(1.914602-T*(0.004817+0.000014*T)) +
+		math.Sin(2*m*rad)*(0.019993-0.000101*T) +
+		math.Sin(3*m*rad)*0.000289
+	omega := 125.04 - 1934.136*T
+	return l0 + c - 0.00569 - 0.00478*math.Sin(omega*rad)
+}
+
+// obliquity returns the mean obliquity of the ecliptic in degrees.
+func obliquity(T float64) float64 {
+	return 23 + (26+(21.448-T*(46.815+T*(0.00059-T*0.001813)))/60)/60
+}
+
+// horizontal converts an hour angle and declination, in degrees, into
+// elevation and azimuth for an observer at lat.
+func horizontal(hourAngle, decl, lat float64) (elevation, azimuth float64) {
+	h, d, phi := hourAngle*rad, decl*rad, lat*rad
+	sinEl := math.Sin(phi)*math.Sin(d) + math.Cos(phi)*math.Cos(d)*math.Cos(h)
+	elevation = math.Asin(math.Max(-1, math.Min(1, sinEl))) / rad
+	azimuth = math.Mod(math.Atan2(math.Sin(h), math.Cos(h)*math.Sin(phi)-math.Tan(d)*math.Cos(phi))/rad+180, 360)
+	return elevation, azimuth
+}
+
+// LightPhase classifies a sun elevation in degrees.
+func LightPhase(elevation float64) string {
+	switch {
+	case elevation > 6:
+		return Day
+	case elevation >= -4:
+		return GoldenHour
+	case elevation >= -6:
+		return BlueHour
+	}
+	return Night
+}
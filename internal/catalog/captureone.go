@@ -0,0 +1,261 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/pkg/sqlite"
+)
+
+// CaptureOne names Capture One, for sessions and their .cos adjustments.
+const CaptureOne = "Capture One"
+
+// Selects is the collection of the images in a session's Selects folder,
+// where Capture One moves the picks of a shoot.
+const Selects = "Selects"
+
+// readCaptureOne reads a Capture One session database (.cosessiondb),
+// whose image paths are relative to the session folder dir. Images have
+// variants, which carry the rating, keywords and album memberships; the
+// first variant is the one merged. Capture One's Core Data schema varies
+// between versions, so link tables are found by their columns.
+func readCaptureOne(d *sqlite.DB, dir string) (*Catalog, error) {
+	images, err := table(d, []string{"ZIMAGE"}, "Z_PK")
+	if err != nil {
+		return nil, err
+	}
+	nameCol := matchingName(images, "ZIMAGEFILENAME", "ZFILENAME", "ZDISPLAYNAME")
+	if nameCol == "" {
+		return nil, fmt.Errorf("%w: table ZIMAGE has no file name column", errSchema)
+	}
+	folders := map[int64]string{}
+	if t := d.Table("ZPATHLOCATION"); t != nil {
+		err := t.Rows(func(r sqlite.Row) error {
+			folders[r.Int("Z_PK")] = r.String("ZRELATIVEPATH")
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Catalog{App: CaptureOne}
+	byImage := map[int64]*Entry{}
+	err = images.Rows(func(r sqlite.Row) error {
+		rel := filepath.FromSlash(folders[r.Int(matchingName(images, "ZIMAGELOCATION", "ZLOCATION"))])
+		e := &Entry{Path: filepath.Join(dir, rel, r.String(nameCol))}
+		if first, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); first == Selects {
+			e.Collections = add(e.Collections, Selects)
+		}
+		byImage[r.Int("Z_PK")] = e
+		c.Entries = append(c.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Variant primary keys map to the image entries; a lower ZPOSITION,
+	// or key, is an earlier variant.
+	variants := map[int64]*Entry{}
+	if t := d.Table("ZVARIANT"); t != nil {
+		type variant struct {
+			pk, image, position int64
+			rating              int64
+		}
+		var vs []variant
+		err := t.Rows(func(r sqlite.Row) error {
+			vs = append(vs, variant{r.Int("Z_PK"), r.Int("ZIMAGE"), r.Int("ZPOSITION"), r.Int("ZRATING")})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(vs, func(i, j int) bool {
+			if vs[i].position != vs[j].position {
+				return vs[i].position < vs[j].position
+			}
+			return vs[i].pk < vs[j].pk
+		})
+		seen := map[int64]bool{}
+		for _, v := range vs {
+			e := byImage[v.image]
+			if e == nil || seen[v.image] {
+				continue
+			}
+			seen[v.image] = true
+			variants[v.pk] = e
+			e.Rating = rating(v.rating)
+		}
+	}
+	if t := d.Table("ZVARIANTMETADATA"); t != nil {
+		err := t.Rows(func(r sqlite.Row) error {
+			if e := variants[r.Int("ZVARIANT")]; e != nil && r.Int("ZRATING") != 0 {
+				e.Rating = rating(r.Int("ZRATING"))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := captureOneLinks(d, "ZKEYWORD", variants, byImage, func(e *Entry, name string) {
+		e.Keywords = add(e.Keywords, name)
+	}); err != nil {
+		return nil, err
+	}
+	if err := captureOneLinks(d, "ZCOLLECTION", variants, byImage, func(e *Entry, name string) {
+		e.Collections = add(e.Collections, name)
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// rating returns v when it is a 1-5 star rating, else 0.
+func rating(v int64) int {
+	if v < 1 || v > 5 {
+		return 0
+	}
+	return int(v)
+}
+
+// captureOneLinks reads the ZNAME of each row of table, then calls fn for
+// every variant or image linked to one by a table with a column named
+// after it and a ZVARIANT or ZIMAGE column.
+func captureOneLinks(d *sqlite.DB, table string, variants, images map[int64]*Entry, fn func(*Entry, string)) error {
+	t := d.Table(table)
+	if t == nil {
+		return nil
+	}
+	names := map[int64]string{}
+	err := t.Rows(func(r sqlite.Row) error {
+		names[r.Int("Z_PK")] = r.String("ZNAME")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range d.Tables() {
+		lt := d.Table(name)
+		if lt == t || lt.Column(table) < 0 {
+			continue
+		}
+		col, entries := "ZVARIANT", variants
+		if lt.Column(col) < 0 {
+			col, entries = "ZIMAGE", images
+			if lt.Column(col) < 0 {
+				continue
+			}
+		}
+		err := lt.Rows(func(r sqlite.Row) error {
+			if e, n := entries[r.Int(col)], names[r.Int(table)]; e != nil && n != "" {
+				fn(e, n)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingName returns the first of names that is a column of t, or "".
+func matchingName(t *sqlite.Table, names ...string) string {
+	for _, n := range names {
+		if t.Column(n) >= 0 {
+			return n
+		}
+	}
+	return ""
+}
+
+// captureOneMeta lists .cos keys that hold metadata rather than
+// adjustments.
+var captureOneMeta = map[string]bool{
+	"Rating": true, "Basic_Rating": true, "ColorLabel": true, "Basic_ColorTag": true,
+	"ColorTag": true, "Keywords": true, "Orientation": true, "ICCProfile": true,
+}
+
+// findCaptureOne returns the .cos adjustments file Capture One keeps for
+// the image at path in CaptureOne/Settings<version> next to it, taking
+// the newest settings version, or "".
+func findCaptureOne(path string) string {
+	dirs, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "CaptureOne", "Settings*"))
+	sort.Slice(dirs, func(i, j int) bool { return settingsVersion(dirs[i]) > settingsVersion(dirs[j]) })
+	for _, dir := range dirs {
+		cos := filepath.Join(dir, filepath.Base(path)+".cos")
+		if _, err := os.Stat(cos); err == nil {
+			return cos
+		}
+	}
+	return ""
+}
+
+func settingsVersion(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "Settings"))
+	return n
+}
+
+// ParseCaptureOne reads a Capture One .cos adjustments file, whose
+// settings are elements with a K(ey) and V(alue) attribute. Adjustments
+// are the keys, other than metadata, whose value is not zero; they are
+// listed in file order.
+func ParseCaptureOne(data []byte) (*Sidecar, error) {
+	sc := &Sidecar{Editor: CaptureOne}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var key, value string
+		var hasValue bool
+		for _, a := range el.Attr {
+			switch a.Name.Local {
+			case "K":
+				key = a.Value
+			case "V":
+				value, hasValue = a.Value, true
+			}
+		}
+		switch {
+		case key == "" || !hasValue:
+		case key == "Rating" || key == "Basic_Rating":
+			if n, err := strconv.Atoi(value); err == nil {
+				sc.Rating = rating(int64(n))
+			}
+		case !captureOneMeta[key] && !neutral(value):
+			sc.Modules = add(sc.Modules, key)
+		}
+	}
+	sc.Edited = len(sc.Modules) > 0
+	return sc, nil
+}
+
+// neutral reports whether a setting value is empty, false or all zeros,
+// as "0", "0.0" or "0;0;0".
+func neutral(v string) bool {
+	if v == "false" {
+		return true
+	}
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ' ' || r == ',' }) {
+		if n, err := strconv.ParseFloat(f, 64); err != nil || n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+// Package catalog reads the ratings, keywords and collections photo
+// applications keep in their own databases, so they can be merged with
+// the metadata stored in the files. Lightroom Classic catalogs (.lrcat),
+// Apple Photos libraries (.photoslibrary) and Capture One sessions
+// (.cosessiondb) are supported, as are the sidecars of raw developers.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/sqlite"
+)
+
+// Applications a catalog can come from.
+const (
+	Lightroom   = "Lightroom"
+	ApplePhotos = "Photos"
+)
+
+// Entry is what a catalog records about one photo.
+type Entry struct {
+	// Path is the absolute path of the file the catalog references.
+	Path string
+	// Name is the original file name, when the catalog stores the file
+	// under another one.
+	Name        string
+	Rating      int
+	Keywords    []string
+	Collections []string
+}
+
+// Catalog is the set of photos of one catalog.
+type Catalog struct {
+	App     string
+	Entries []*Entry
+
+	byPath, byName, byStem map[string][]*Entry
+}
+
+// Open reads a Lightroom catalog, an Apple Photos library or a Capture One
+// session. path is the .lrcat file, the .photoslibrary directory or its
+// database/Photos.sqlite, or the .cosessiondb file.
+func Open(path string) (*Catalog, error) {
+	db := path
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		db = filepath.Join(path, "database", "Photos.sqlite")
+	}
+	d, err := sqlite.Open(db)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	var c *Catalog
+	switch {
+	case d.Table("Adobe_images") != nil:
+		c, err = readLightroom(d)
+	case d.Table("ZASSET") != nil || d.Table("ZGENERICASSET") != nil:
+		c, err = readPhotos(d, libraryRoot(db))
+	case d.Table("ZIMAGE") != nil && d.Table("ZVARIANT") != nil:
+		c, err = readCaptureOne(d, filepath.Dir(db))
+	default:
+		return nil, fmt.Errorf("catalog: %s: not a Lightroom catalog, Photos library or Capture One session", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %s: %w", path, err)
+	}
+	c.index()
+	return c, nil
+}
+
+// libraryRoot returns the .photoslibrary directory holding the database
+// at db.
+func libraryRoot(db string) string {
+	return filepath.Dir(filepath.Dir(db))
+}
+
+// errSchema reports a table or column the reader depends on missing.
+var errSchema = errors.New("unsupported schema")
+
+// table returns the first of the named tables present in d, checking that
+// it has the columns given.
+func table(d *sqlite.DB, names []string, columns ...string) (*sqlite.Table, error) {
+	for _, n := range names {
+		t := d.Table(n)
+		if t == nil {
+			continue
+		}
+		for _, c := range columns {
+			if t.Column(c) < 0 {
+				return nil, fmt.Errorf("%w: table %s has no column %s", errSchema, t.Name, c)
+			}
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: no table %s", errSchema, names[0])
+}
+
+func (c *Catalog) index() {
+	c.byPath = map[string][]*Entry{}
+	c.byName = map[string][]*Entry{}
+	c.byStem = map[string][]*Entry{}
+	for _, e := range c.Entries {
+		c.byPath[filepath.Clean(e.Path)] = append(c.byPath[filepath.Clean(e.Path)], e)
+		names := []string{filepath.Base(e.Path)}
+		if e.Name != "" && !strings.EqualFold(e.Name, names[0]) {
+			names = append(names, e.Name)
+		}
+		for _, n := range names {
+			n = strings.ToLower(n)
+			c.byName[n] = append(c.byName[n], e)
+			stem := strings.TrimSuffix(n, filepath.Ext(n))
+			c.byStem[stem] = append(c.byStem[stem], e)
+		}
+	}
+}
+
+// Lookup returns the entry for the file at path: the one referencing it
+// by absolute path, else the only one with its file name, else the only
+// one with its name minus the extension, which pairs exported JPEGs and
+// RAW+JPEG siblings with the catalogued original.
+func (c *Catalog) Lookup(path string) *Entry {
+	if abs, err := filepath.Abs(path); err == nil {
+		if es := c.byPath[abs]; len(es) > 0 {
+			return es[0]
+		}
+	}
+	name := strings.ToLower(filepath.Base(path))
+	if es := c.byName[name]; len(es) == 1 {
+		return es[0]
+	}
+	if es := c.byStem[strings.TrimSuffix(name, filepath.Ext(name))]; len(es) == 1 {
+		return es[0]
+	}
+	return nil
+}
+
+// Apply merges the catalog entry for s into it and reports whether there
+// was one. The catalog's rating replaces the file's, keywords are added
+// to those of the file and collections are set.
+func (c *Catalog) Apply(s *exif.Summary) bool {
+	e := c.Lookup(s.Path)
+	if e == nil {
+		return false
+	}
+	var fields []string
+	if e.Rating != 0 {
+		s.Rating = e.Rating
+		fields = append(fields, "rating")
+	}
+	if added := merge(s.Keywords, e.Keywords); len(added) > len(s.Keywords) {
+		s.Keywords = added
+		fields = append(fields, "keywords")
+	}
+	if len(e.Collections) > 0 {
+		s.Collections = slices.Clone(e.Collections)
+		fields = append(fields, "collections")
+	}
+	if len(fields) > 0 {
+		if s.Sources == nil {
+			s.Sources = make(map[string]exif.Source)
+		}
+		for _, f := range fields {
+			s.Sources[f] = exif.Source{Location: "Catalog:" + c.App}
+		}
+	}
+	return true
+}
+
+// merge returns a followed by the elements of b it lacks, ignoring case.
+func merge(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, v := range b {
+		if !slices.ContainsFunc(out, func(w string) bool { return strings.EqualFold(v, w) }) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// add appends v to list unless it is empty or already present.
+func add(list []string, v string) []string {
+	if v == "" || slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"path/filepath"
+
+	"github.com/ryoh827/shootlog/pkg/sqlite"
+)
+
+// readLightroom reads a Lightroom Classic catalog. Images reference a
+// file, which sits in a folder below a root folder; keywords and
+// collections are joined to images by id_local.
+func readLightroom(d *sqlite.DB) (*Catalog, error) {
+	roots, err := column(d, "AgLibraryRootFolder", "absolutePath")
+	if err != nil {
+		return nil, err
+	}
+	t, err := table(d, []string{"AgLibraryFolder"}, "id_local", "rootFolder", "pathFromRoot")
+	if err != nil {
+		return nil, err
+	}
+	folders := map[int64]string{}
+	err = t.Rows(func(r sqlite.Row) error {
+		folders[r.Int("id_local")] = roots[r.Int("rootFolder")] + r.String("pathFromRoot")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t, err = table(d, []string{"AgLibraryFile"}, "id_local", "folder", "baseName", "extension"); err != nil {
+		return nil, err
+	}
+	files := map[int64]*Entry{}
+	err = t.Rows(func(r sqlite.Row) error {
+		name := r.String("baseName")
+		if ext := r.String("extension"); ext != "" {
+			name += "." + ext
+		}
+		files[r.Int("id_local")] = &Entry{
+			Path: filepath.FromSlash(folders[r.Int("folder")] + name),
+			Name: r.String("originalFilename"),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{App: Lightroom}
+	images := map[int64]*Entry{}
+	if t, err = table(d, []string{"Adobe_images"}, "id_local", "rootFile", "rating"); err != nil {
+		return nil, err
+	}
+	err = t.Rows(func(r sqlite.Row) error {
+		f := files[r.Int("rootFile")]
+		if f == nil {
+			return nil
+		}
+		// Virtual copies are further images of the same file; the
+		// master's metadata is the one merged.
+		if r.Int("masterImage") != 0 {
+			return nil
+		}
+		e := *f
+		if v, ok := r.Float("rating"); ok && v >= 1 && v <= 5 {
+			e.Rating = int(v)
+		}
+		images[r.Int("id_local")] = &e
+		c.Entries = append(c.Entries, &e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keywords, err := column(d, "AgLibraryKeyword", "name")
+	if err != nil {
+		return nil, err
+	}
+	err = join(d, "AgLibraryKeywordImage", "image", "tag", func(image, tag int64) {
+		if e := images[image]; e != nil {
+			e.Keywords = add(e.Keywords, keywords[tag])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	collections, err := column(d, "AgLibraryCollection", "name")
+	if err != nil {
+		return nil, err
+	}
+	err = join(d, "AgLibraryCollectionImage", "image", "collection", func(image, collection int64) {
+		if e := images[image]; e != nil {
+			e.Collections = add(e.Collections, collections[collection])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// column maps id_local to the named text column of a table.
+func column(d *sqlite.DB, name, col string) (map[int64]string, error) {
+	t, err := table(d, []string{name}, "id_local", col)
+	if err != nil {
+		return nil, err
+	}
+	m := map[int64]string{}
+	err = t.Rows(func(r sqlite.Row) error {
+		m[r.Int("id_local")] = r.String(col)
+		return nil
+	})
+	return m, err
+}
+
+// join calls fn with the two ids of each row of a join table.
+func join(d *sqlite.DB, name, left, right string, fn func(l, r int64)) error {
+	t, err := table(d, []string{name}, left, right)
+	if err != nil {
+		return err
+	}
+	return t.Rows(func(r sqlite.Row) error {
+		fn(r.Int(left), r.Int(right))
+		return nil
+	})
+}
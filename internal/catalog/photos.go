@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/ryoh827/shootlog/pkg/sqlite"
+)
+
+// Favorites is the collection Apple Photos favorites are listed in; Photos
+// has no star ratings.
+const Favorites = "Favorites"
+
+// albumKindUser is the ZGENERICALBUM.ZKIND of albums made by the user, as
+// opposed to folders, shared and smart albums.
+const albumKindUser = 2
+
+// Core Data names its many-to-many join tables and their columns after
+// entity numbers that differ between Photos versions, e.g. Z_1KEYWORDS
+// with Z_1ASSETATTRIBUTES and Z_38KEYWORDS; they are found by pattern.
+var (
+	keywordsLeft  = regexp.MustCompile(`^Z_\d+ASSETATTRIBUTES$`)
+	keywordsRight = regexp.MustCompile(`^Z_\d+KEYWORDS$`)
+	albumsLeft    = regexp.MustCompile(`^Z_\d+ASSETS$`)
+	albumsRight   = regexp.MustCompile(`^Z_\d+ALBUMS$`)
+)
+
+// readPhotos reads the Photos.sqlite database of an Apple Photos library
+// at root. Photos 5 and later keep originals below originals/ and name the
+// asset table ZASSET; earlier versions use Masters/ and ZGENERICASSET.
+func readPhotos(d *sqlite.DB, root string) (*Catalog, error) {
+	t, err := table(d, []string{"ZASSET", "ZGENERICASSET"}, "Z_PK", "ZDIRECTORY", "ZFILENAME")
+	if err != nil {
+		return nil, err
+	}
+	originals := filepath.Join(root, "originals")
+	if t.Name == "ZGENERICASSET" {
+		originals = filepath.Join(root, "Masters")
+	}
+	c := &Catalog{App: ApplePhotos}
+	assets := map[int64]*Entry{}
+	err = t.Rows(func(r sqlite.Row) error {
+		if r.Int("ZTRASHEDSTATE") != 0 {
+			return nil
+		}
+		e := &Entry{Path: filepath.Join(originals, filepath.FromSlash(r.String("ZDIRECTORY")), r.String("ZFILENAME"))}
+		if r.Int("ZFAVORITE") != 0 {
+			e.Collections = add(e.Collections, Favorites)
+		}
+		assets[r.Int("Z_PK")] = e
+		c.Entries = append(c.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Original file names and keywords hang off the additional attributes
+	// of an asset.
+	attributes := map[int64]*Entry{}
+	if t := d.Table("ZADDITIONALASSETATTRIBUTES"); t != nil {
+		err = t.Rows(func(r sqlite.Row) error {
+			if e := assets[r.Int("ZASSET")]; e != nil {
+				e.Name = r.String("ZORIGINALFILENAME")
+				attributes[r.Int("Z_PK")] = e
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := photosJoin(d, "ZKEYWORD", keywordsLeft, keywordsRight, attributes, nil, func(e *Entry, title string) {
+		e.Keywords = add(e.Keywords, title)
+	}); err != nil {
+		return nil, err
+	}
+	user := func(r sqlite.Row) bool {
+		return r.Int("ZKIND") == albumKindUser && r.Int("ZTRASHEDSTATE") == 0
+	}
+	if err := photosJoin(d, "ZGENERICALBUM", albumsLeft, albumsRight, assets, user, func(e *Entry, title string) {
+		e.Collections = add(e.Collections, title)
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// photosJoin reads the ZTITLE of the rows of table accepted by keep, then
+// calls fn for each entry linked to one through the join table whose
+// columns match left, keyed by entries, and right. A library without the
+// tables has nothing to join.
+func photosJoin(d *sqlite.DB, table string, left, right *regexp.Regexp, entries map[int64]*Entry, keep func(sqlite.Row) bool, fn func(*Entry, string)) error {
+	t := d.Table(table)
+	if t == nil {
+		return nil
+	}
+	titles := map[int64]string{}
+	err := t.Rows(func(r sqlite.Row) error {
+		if keep == nil || keep(r) {
+			titles[r.Int("Z_PK")] = r.String("ZTITLE")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range d.Tables() {
+		jt := d.Table(name)
+		l, r := matching(jt, left), matching(jt, right)
+		if l == "" || r == "" {
+			continue
+		}
+		err := jt.Rows(func(row sqlite.Row) error {
+			if e, title := entries[row.Int(l)], titles[row.Int(r)]; e != nil && title != "" {
+				fn(e, title)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matching returns the column of t that matches re, or "".
+func matching(t *sqlite.Table, re *regexp.Regexp) string {
+	for _, c := range t.Columns {
+		if re.MatchString(c) {
+			return c
+		}
+	}
+	return ""
+}
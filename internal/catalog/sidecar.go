@@ -0,0 +1,242 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Raw developers whose sidecars are read.
+const (
+	Darktable   = "darktable"
+	RawTherapee = "RawTherapee"
+)
+
+// Sidecar is the edit state a raw developer keeps next to an image.
+type Sidecar struct {
+	Editor string
+	// Edited reports whether any module beyond the ones the developer
+	// applies to every image is active.
+	Edited bool
+	// Modules are the active user-applied modules, in the order they were
+	// first applied.
+	Modules []string
+	Rating  int
+}
+
+// FindSidecar reads the sidecar of the image at path: path.xmp from
+// darktable, path.pp3 from RawTherapee or the .cos adjustments Capture One
+// keeps in a CaptureOne folder next to it, whichever was written last
+// when there are several. It returns nil without error when there is
+// none. XMP files that darktable did not write are ignored.
+func FindSidecar(path string) (*Sidecar, error) {
+	var found *Sidecar
+	var newest int64
+	for _, c := range []struct {
+		path  string
+		parse func([]byte) (*Sidecar, error)
+	}{
+		{path + ".xmp", ParseDarktable},
+		{path + ".pp3", ParseRawTherapee},
+		{findCaptureOne(path), ParseCaptureOne},
+	} {
+		if c.path == "" {
+			continue
+		}
+		fi, err := os.Stat(c.path)
+		if err != nil || fi.IsDir() || (found != nil && fi.ModTime().UnixNano() <= newest) {
+			continue
+		}
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			return nil, fmt.Errorf("catalog: %w", err)
+		}
+		sc, err := c.parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.path, err)
+		}
+		if sc != nil {
+			found, newest = sc, fi.ModTime().UnixNano()
+		}
+	}
+	return found, nil
+}
+
+// Apply merges the sidecar into s: its rating replaces the file's, and
+// the editor, edit status and modules are set.
+func (sc *Sidecar) Apply(s *exif.Summary) {
+	s.Editor = sc.Editor
+	s.Edited = sc.Edited
+	s.EditModules = sc.Modules
+	fields := []string{"editor", "edited", "edit_modules"}
+	if sc.Rating != 0 {
+		s.Rating = sc.Rating
+		fields = append(fields, "rating")
+	}
+	if s.Sources == nil {
+		s.Sources = make(map[string]exif.Source)
+	}
+	for _, f := range fields {
+		s.Sources[f] = exif.Source{Location: "Sidecar:" + sc.Editor}
+	}
+}
+
+const namespaceDarktable = "http://darktable.sf.net/"
+
+// darktableBuiltin lists the modules darktable puts in every history to
+// run the pipeline; they do not make an image edited.
+var darktableBuiltin = map[string]bool{
+	"rawprepare": true, "demosaic": true, "colorin": true, "colorout": true,
+	"gamma": true, "dither": true, "highlights": true, "temperature": true,
+	"flip": true, "finalscale": true, "mask_manager": true,
+}
+
+type darktableStep struct {
+	operation string
+	enabled   bool
+	// auto marks modules applied by a built-in preset, such as the
+	// scene-referred defaults, whose multi_name starts with _builtin_.
+	auto bool
+}
+
+// ParseDarktable reads a darktable XMP sidecar, or returns nil for XMP
+// without darktable properties. History steps at or past history_end
+// were undone in the editor and are ignored; a module's last step
+// decides whether it is active.
+func ParseDarktable(data []byte) (*Sidecar, error) {
+	props := exif.XMPProperties(data)
+	dt := func(name string) []string {
+		return props[xml.Name{Space: namespaceDarktable, Local: name}]
+	}
+	if len(dt("xmp_version")) == 0 {
+		return nil, nil
+	}
+	sc := &Sidecar{Editor: Darktable}
+	if v := props[xml.Name{Space: exif.NamespaceXMP, Local: "Rating"}]; len(v) > 0 {
+		if n, err := strconv.Atoi(v[0]); err == nil && n >= 1 && n <= 5 {
+			sc.Rating = n
+		}
+	}
+
+	steps := darktableHistory(data)
+	if len(steps) == 0 {
+		// darktable before 3.0 wrote the history as parallel sequences.
+		enabled := dt("history_enabled")
+		for i, op := range dt("history_operation") {
+			steps = append(steps, darktableStep{operation: op, enabled: i < len(enabled) && enabled[i] == "1"})
+		}
+	}
+	if v := dt("history_end"); len(v) > 0 {
+		if end, err := strconv.Atoi(v[0]); err == nil && end >= 0 && end < len(steps) {
+			steps = steps[:end]
+		}
+	}
+	active := map[string]bool{}
+	var order []string
+	for _, st := range steps {
+		if darktableBuiltin[st.operation] || st.auto {
+			continue
+		}
+		if _, seen := active[st.operation]; !seen {
+			order = append(order, st.operation)
+		}
+		active[st.operation] = st.enabled
+	}
+	for _, op := range order {
+		if active[op] {
+			sc.Modules = append(sc.Modules, op)
+		}
+	}
+	sc.Edited = len(sc.Modules) > 0
+	return sc, nil
+}
+
+// darktableHistory returns the steps of the darktable:history sequence,
+// whose items carry the step as attributes.
+func darktableHistory(data []byte) []darktableStep {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	var steps []darktableStep
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return steps
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var st darktableStep
+		for _, a := range el.Attr {
+			if a.Name.Space != namespaceDarktable {
+				continue
+			}
+			switch a.Name.Local {
+			case "operation":
+				st.operation = a.Value
+			case "enabled":
+				st.enabled = a.Value == "1"
+			case "multi_name":
+				st.auto = strings.HasPrefix(a.Value, "_builtin_")
+			}
+		}
+		if st.operation != "" {
+			steps = append(steps, st)
+		}
+	}
+}
+
+// rawTherapeeMeta lists .pp3 sections that hold file state rather than
+// processing tools.
+var rawTherapeeMeta = map[string]bool{
+	"Version": true, "General": true, "Exif": true, "IPTC": true, "MetaData": true,
+}
+
+// ParseRawTherapee reads a RawTherapee processing profile. Tools are
+// the sections with Enabled=true, in file order; the rank in [General]
+// is the rating.
+func ParseRawTherapee(data []byte) (*Sidecar, error) {
+	sc := &Sidecar{Editor: RawTherapee}
+	section := ""
+	seenVersion := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";"):
+		case strings.HasPrefix(text, "["):
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("catalog: line %d: malformed section %q", line, text)
+			}
+			section = text[1 : len(text)-1]
+			seenVersion = seenVersion || section == "Version"
+		default:
+			key, value, ok := strings.Cut(text, "=")
+			if !ok {
+				return nil, fmt.Errorf("catalog: line %d: expected key=value", line)
+			}
+			switch {
+			case section == "General" && key == "Rank":
+				if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= 5 {
+					sc.Rating = n
+				}
+			case key == "Enabled" && value == "true" && !rawTherapeeMeta[section]:
+				sc.Modules = append(sc.Modules, section)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+	if !seenVersion {
+		return nil, fmt.Errorf("catalog: not a RawTherapee profile: no [Version] section")
+	}
+	sc.Edited = len(sc.Modules) > 0
+	return sc, nil
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/analyze"
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+// analyzeFlags select the analyzers of a run: --analyze names them and
+// --exposure and --colors are shorthands for two of them. Without any,
+// the config file's analyze list applies.
+type analyzeFlags struct {
+	names    string
+	exposure bool
+	colors   bool
+}
+
+func (f *analyzeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.names, "analyze", "", "comma-separated analyzers to run after extraction: "+strings.Join(analyze.Names(), ", "))
+	fs.BoolVar(&f.exposure, "exposure", false, "estimate luminance and clipping from the embedded thumbnail of each photo; same as --analyze exposure")
+	fs.BoolVar(&f.colors, "colors", false, "name the dominant colors of the embedded thumbnail of each photo; same as --analyze colors")
+}
+
+// pipeline returns the analyzers selected by the flags, or else by cfg.
+func (f *analyzeFlags) pipeline(cfg *config.Config) (analyze.Pipeline, error) {
+	var names []string
+	if f.names != "" {
+		names = strings.Split(f.names, ",")
+	}
+	if f.exposure {
+		names = append(names, "exposure")
+	}
+	if f.colors {
+		names = append(names, "colors")
+	}
+	if len(names) == 0 {
+		names = cfg.Analyze
+	}
+	return analyze.Parse(names)
+}
+
+// withAnalyzers wraps decode to run p over each file it decodes. Analyzer
+// failures are reported and the summary kept without their fields.
+func (a *app) withAnalyzers(decode func(string) (*exif.Summary, error), p analyze.Pipeline) func(string) (*exif.Summary, error) {
+	if len(p) == 0 {
+		return decode
+	}
+	return func(path string) (*exif.Summary, error) {
+		s, err := decode(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, err := range p.Run(s, data) {
+			fmt.Fprintf(a.stderr, "shootlog: %s: %v\n", path, err)
+		}
+		return s, nil
+	}
+}
+
+// analyzeIndex runs p over the indexed files still lacking the fields of
+// any of its analyzers: those decoded in this run, and those the index
+// kept from runs without them while they stayed unchanged. Files an
+// analyzer has nothing to say about, such as photos without a thumbnail,
+// are read again on every run.
+func analyzeIndex(ix *index.Index, p analyze.Pipeline) []error {
+	var errs []error
+	for _, f := range ix.Files {
+		if f.Summary == nil {
+			continue
+		}
+		pending := p.Pending(f.Summary)
+		if len(pending) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, err := range pending.Run(f.Summary, data) {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+		}
+	}
+	return errs
+}
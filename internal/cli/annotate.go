@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/notes"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+func runAnnotate(a *app, args []string) error {
+	fs := a.newFlagSet("annotate", "shootlog annotate --notes file.csv|file.json [--input file | --dir dir] [--match auto|file|time] [--tolerance 1m] [--tz zone] [--output json|csv] [--catalog path] [--filter expr]")
+	var in inputFlags
+	in.register(fs)
+	notesFile := fs.String("notes", "", "shot list to merge: CSV with a header row, or a JSON array of objects")
+	match := fs.String("match", notes.MatchAuto, "how notes are paired with photos: "+strings.Join(notes.Matches, ", "))
+	tolerance := fs.Duration("tolerance", time.Minute, "largest gap between a note's time and a capture time for --match time")
+	tz := fs.String("tz", "Local", "time zone of note times and capture times without a UTC offset")
+	output := fs.String("output", report.FormatJSON, "output format: json or csv")
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *notesFile == "" {
+		return errors.New("missing --notes")
+	}
+	if !slices.Contains(notes.Matches, *match) {
+		return fmt.Errorf("unknown --match %q", *match)
+	}
+	if *output != report.FormatJSON && *output != report.FormatCSV {
+		return fmt.Errorf("unknown format %q", *output)
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid --tz: %w", err)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	list, err := notes.Load(*notesFile, loc)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cat.apply(a, s)
+		cfg.Privacy.Protect(s)
+		s.Sources = nil
+	}
+	summaries = where.apply(summaries)
+	if err := report.Sort(summaries, report.SortDatetime); err != nil {
+		return err
+	}
+
+	m := &notes.Matcher{List: list, Mode: *match, Tolerance: *tolerance, Location: loc}
+	used := map[*notes.Note]bool{}
+	rows := make([]report.Annotated, len(summaries))
+	for i, s := range summaries {
+		rows[i].Summary = s
+		if n := m.Match(s); n != nil {
+			rows[i].Notes = n.Fields
+			used[n] = true
+		}
+	}
+	for _, n := range list.Notes {
+		if !used[n] {
+			fmt.Fprintf(a.stderr, "shootlog: note %v matched no photo\n", n)
+		}
+	}
+	return report.WriteAnnotated(a.stdout, *output, rows, list.Columns)
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+const backupUsage = "shootlog backup mark --index path --set name (--dir dir | --backup-index path) | check --index path [--set name]... [--copies n]"
+
+// runBackup records in a library index which backup sets hold each file,
+// and lists the files short of backups.
+func runBackup(a *app, args []string) error {
+	if len(args) == 0 || (args[0] != "mark" && args[0] != "check") {
+		fmt.Fprintf(a.stderr, "usage: %s\n", backupUsage)
+		return errUsage
+	}
+	action := args[0]
+	fs := a.newFlagSet("backup "+action, backupUsage)
+	path := fs.String("index", "", "index file written by shootlog index")
+	var sets []string
+	addSet := func(v string) error {
+		if v == "" {
+			return errors.New("empty set name")
+		}
+		sets = append(sets, v)
+		return nil
+	}
+	var dir, backupIndex *string
+	var copies *int
+	if action == "mark" {
+		fs.Func("set", "name of the backup set, e.g. offsite", addSet)
+		dir = fs.String("dir", "", "directory holding the backup set, read and hashed in full")
+		backupIndex = fs.String("backup-index", "", "index of the backup set written by shootlog index, used instead of reading it")
+	} else {
+		fs.Func("set", "backup set every file must be in; repeatable", addSet)
+		copies = fs.Int("copies", 0, "number of backup sets every file must be in (default 1 without --set)")
+	}
+	if err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--index is required")
+	}
+	ix, err := loadIndex(*path)
+	if err != nil {
+		return err
+	}
+	if action == "check" {
+		return checkBackups(a, ix, sets, *copies)
+	}
+
+	if len(sets) != 1 {
+		return errors.New("mark takes one --set")
+	}
+	if (*dir == "") == (*backupIndex == "") {
+		return errors.New("mark takes one of --dir or --backup-index")
+	}
+	var n int
+	if *dir != "" {
+		paths, err := scanDir(*dir)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		n, errs = ix.MarkBackup(sets[0], paths)
+		for _, err := range errs {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %v\n", err)
+		}
+	} else {
+		backup, err := loadIndex(*backupIndex)
+		if err != nil {
+			return err
+		}
+		n = ix.MarkBackupIndex(sets[0], backup)
+	}
+	data, err := ix.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := writeCatalog(*path, data, ix); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "%s holds %d of %d files\n", sets[0], n, len(ix.Files))
+	return nil
+}
+
+// checkBackups prints the files missing from any of sets or held by fewer
+// than copies sets, and fails when there are any.
+func checkBackups(a *app, ix *index.Index, sets []string, copies int) error {
+	if copies < 0 {
+		return fmt.Errorf("invalid --copies %d", copies)
+	}
+	if copies == 0 && len(sets) == 0 {
+		copies = 1
+	}
+	short := 0
+	for _, f := range ix.Files {
+		var missing []string
+		for _, s := range sets {
+			if !slices.Contains(f.Backups, s) {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) == 0 && len(f.Backups) >= copies {
+			continue
+		}
+		short++
+		switch {
+		case len(missing) > 0:
+			fmt.Fprintf(a.stdout, "%s: not in %s\n", f.Path, strings.Join(missing, ", "))
+		default:
+			fmt.Fprintf(a.stdout, "%s: in %d of %d backup sets\n", f.Path, len(f.Backups), copies)
+		}
+	}
+	if short > 0 {
+		return fmt.Errorf("%d of %d files are short of backups", short, len(ix.Files))
+	}
+	return nil
+}
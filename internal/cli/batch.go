@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/sshsig"
+)
+
+// batchManifest is the record of a batch run: what was processed, what
+// failed and the summaries. It is built once the run is over; checkpoints
+// are batchCheckpoint journals.
+type batchManifest struct {
+	Dir       string          `json:"dir"`
+	Started   time.Time       `json:"started"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+	Files     int             `json:"files"`
+	Succeeded int             `json:"succeeded"`
+	Failed    []batchFailure  `json:"failed"`
+	Summaries []*exif.Summary `json:"summaries"`
+}
+
+type batchFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// sql renders the summaries of m as an SQL script creating and filling
+// the photos table, after comments recording the run and its failures.
+func (m *batchManifest) sql() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "-- shootlog batch of %s started %s: %d files, %d succeeded\n",
+		m.Dir, m.Started.Format(time.RFC3339), m.Files, m.Succeeded)
+	for _, f := range m.Failed {
+		fmt.Fprintf(&buf, "-- failed %s: %s\n", f.Path, strings.ReplaceAll(f.Error, "\n", " "))
+	}
+	err := report.WriteSQL(&buf, m.Summaries)
+	return buf.Bytes(), err
+}
+
+// encode renders m as indented JSON.
+func (m *batchManifest) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(m)
+	return buf.Bytes(), err
+}
+
+// runBatch processes a directory once and exits, for containers run as
+// batch jobs: unlike the default command it records failed files in the
+// manifest instead of skipping them, and it fails when any did. Progress
+// is checkpointed, so an interrupted run over a large volume can resume
+// where it stopped.
+func runBatch(a *app, args []string) error {
+	fs := a.newFlagSet("batch", "shootlog batch --dir dir [--manifest path] [--output json|sql] [--allow-failures] [--checkpoint path] [--checkpoint-interval 30s] [--resume] [--sign-key path]")
+	dir := fs.String("dir", "", "directory to process recursively, e.g. a mounted volume")
+	manifest := fs.String("manifest", "", "file to write the manifest to (default stdout)")
+	output := fs.String("output", report.FormatJSON, "format of the manifest: json, or sql creating and filling a photos table")
+	allowFailures := fs.Bool("allow-failures", false, "exit successfully even when some files could not be decoded")
+	checkpoint := fs.String("checkpoint", "", "file to save progress to periodically and on SIGINT or SIGTERM (default the manifest path plus .checkpoint)")
+	interval := fs.Duration("checkpoint-interval", 30*time.Second, "time between checkpoints")
+	resume := fs.Bool("resume", false, "continue the run saved in the checkpoint, skipping the files it processed")
+	signKey := fs.String("sign-key", "", "Ed25519 private key (OpenSSH or PKCS #8) to sign the manifest with, written to the manifest path plus .sig")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("--dir is required")
+	}
+	if *output != report.FormatJSON && *output != report.FormatSQL {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid --checkpoint-interval %s", *interval)
+	}
+	if *checkpoint == "" && *manifest != "" {
+		*checkpoint = *manifest + ".checkpoint"
+	}
+	if *resume && *checkpoint == "" {
+		return errors.New("--resume needs --checkpoint or --manifest")
+	}
+	var key ed25519.PrivateKey
+	if *signKey != "" {
+		if *manifest == "" {
+			return errors.New("--sign-key needs --manifest")
+		}
+		k, err := sshsig.LoadPrivateKey(*signKey)
+		if err != nil {
+			return err
+		}
+		key = k
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+
+	m := &batchManifest{Dir: *dir, Started: time.Now().UTC(), Failed: []batchFailure{}, Summaries: []*exif.Summary{}}
+	done := map[string]bool{}
+	var cp *batchCheckpoint
+	if *resume {
+		if m, cp, err = resumeCheckpoint(*checkpoint, *dir); err != nil {
+			return err
+		}
+		for _, s := range m.Summaries {
+			done[s.Path] = true
+		}
+		for _, f := range m.Failed {
+			done[f.Path] = true
+		}
+		fmt.Fprintf(a.stderr, "shootlog: resuming after %d files\n", len(done))
+	} else if *checkpoint != "" {
+		if cp, err = createCheckpoint(*checkpoint, m); err != nil {
+			return err
+		}
+	}
+	if cp != nil {
+		defer cp.f.Close()
+	}
+	save := func() error {
+		if cp == nil {
+			return nil
+		}
+		return cp.flush()
+	}
+
+	paths, err := scanDir(*dir)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	last := time.Now()
+	for _, p := range paths {
+		if done[p] {
+			continue
+		}
+		if ctx.Err() != nil {
+			if *checkpoint == "" {
+				return errors.New("interrupted")
+			}
+			if err := save(); err != nil {
+				return err
+			}
+			return fmt.Errorf("interrupted after %d files; continue with --resume", len(m.Summaries)+len(m.Failed))
+		}
+		data, err := os.ReadFile(p)
+		var s *exif.Summary
+		if err == nil {
+			s, err = exif.DecodeBytes(data)
+		}
+		var rec checkpointRecord
+		if err != nil {
+			m.Failed = append(m.Failed, batchFailure{Path: p, Error: err.Error()})
+			rec.Failed = &m.Failed[len(m.Failed)-1]
+		} else {
+			s.Path = p
+			s.Sources = nil
+			cfg.Privacy.Protect(s)
+			m.Summaries = append(m.Summaries, s)
+			rec.Summary = s
+		}
+		if cp != nil {
+			if err := cp.add(rec); err != nil {
+				return err
+			}
+		}
+		if time.Since(last) >= *interval {
+			if err := save(); err != nil {
+				return err
+			}
+			last = time.Now()
+		}
+	}
+	// Files removed since a checkpoint stay in the manifest: this run
+	// processed them.
+	m.Files, m.Succeeded = len(m.Summaries)+len(m.Failed), len(m.Summaries)
+	finished := time.Now().UTC()
+	m.Finished = &finished
+
+	encode := m.encode
+	if *output == report.FormatSQL {
+		encode = m.sql
+	}
+	data, err := encode()
+	if err != nil {
+		return err
+	}
+	if *manifest == "" {
+		_, err = a.stdout.Write(data)
+	} else {
+		err = writeFileAtomic(*manifest, data)
+	}
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		if err := writeFileAtomic(*manifest+".sig", sshsig.Sign(key, sshsig.Namespace, data)); err != nil {
+			return err
+		}
+	}
+	if cp != nil {
+		cp.f.Close()
+		if err := os.Remove(*checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	fmt.Fprintf(a.stderr, "shootlog: processed %d files, %d failed\n", m.Files, len(m.Failed))
+	if len(m.Failed) > 0 && !*allowFailures {
+		return fmt.Errorf("%d of %d files failed", len(m.Failed), m.Files)
+	}
+	return nil
+}
+
+// batchCheckpoint is the journal of an unfinished run, in JSON Lines: a
+// checkpointHeader naming the run, then a checkpointRecord per processed
+// file. Checkpoints append the files processed since the last one, so
+// their cost does not grow with the run, and a line torn by a crash is
+// dropped on resume.
+type batchCheckpoint struct {
+	f       *os.File
+	pending bytes.Buffer
+}
+
+type checkpointHeader struct {
+	Dir     string    `json:"dir"`
+	Started time.Time `json:"started"`
+}
+
+// checkpointRecord is one processed file: its summary, or why it failed.
+type checkpointRecord struct {
+	Summary *exif.Summary `json:"summary,omitempty"`
+	Failed  *batchFailure `json:"failed,omitempty"`
+}
+
+// createCheckpoint starts the journal of the run of m at path, replacing
+// any earlier one.
+func createCheckpoint(path string, m *batchManifest) (*batchCheckpoint, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	cp := &batchCheckpoint{f: f}
+	if err := cp.add(checkpointHeader{Dir: m.Dir, Started: m.Started}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := cp.flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return cp, nil
+}
+
+// add queues v as a line for the next flush.
+func (cp *batchCheckpoint) add(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	cp.pending.Write(line)
+	cp.pending.WriteByte('\n')
+	return nil
+}
+
+// flush appends the queued lines to the journal and syncs it.
+func (cp *batchCheckpoint) flush() error {
+	if cp.pending.Len() == 0 {
+		return nil
+	}
+	if _, err := cp.f.Write(cp.pending.Bytes()); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	cp.pending.Reset()
+	if err := cp.f.Sync(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// resumeCheckpoint reads the journal of an unfinished run over dir into
+// the manifest so far, and opens it to append the rest.
+func resumeCheckpoint(path, dir string) (*batchManifest, *batchCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	header, rest, ok := bytes.Cut(data, []byte("\n"))
+	var h checkpointHeader
+	if !ok {
+		return nil, nil, fmt.Errorf("checkpoint: %s is incomplete; run again without --resume", path)
+	}
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, nil, fmt.Errorf("checkpoint: %s: %w", path, err)
+	}
+	if h.Dir != dir {
+		return nil, nil, fmt.Errorf("checkpoint: %s is of a run over %s, not %s", path, h.Dir, dir)
+	}
+	m := &batchManifest{Dir: h.Dir, Started: h.Started, Failed: []batchFailure{}, Summaries: []*exif.Summary{}}
+	valid := len(header) + 1
+	for len(rest) > 0 {
+		line, next, complete := bytes.Cut(rest, []byte("\n"))
+		var rec checkpointRecord
+		if !complete || json.Unmarshal(line, &rec) != nil {
+			// Torn by a crash while appending; the file is processed
+			// again.
+			break
+		}
+		switch {
+		case rec.Summary != nil:
+			m.Summaries = append(m.Summaries, rec.Summary)
+		case rec.Failed != nil:
+			m.Failed = append(m.Failed, *rec.Failed)
+		}
+		valid += len(line) + 1
+		rest = next
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := f.Truncate(int64(valid)); err == nil {
+		_, err = f.Seek(int64(valid), io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	return m, &batchCheckpoint{f: f}, nil
+}
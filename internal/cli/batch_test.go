@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func TestBatchCheckpointResume(t *testing.T) {
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	// The tail is what a crash left after the last whole record.
+	tests := []struct {
+		name, tail string
+	}{
+		{"complete", ""},
+		{"torn record", `{"summary":{"path":"c.j`},
+		{"record without newline", `{"summary":{"path":"c.jpg"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "m.json.checkpoint")
+			cp, err := createCheckpoint(path, &batchManifest{Dir: "/photos", Started: started})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp.add(checkpointRecord{Summary: &exif.Summary{Path: "a.jpg", Make: "Canon"}})
+			cp.add(checkpointRecord{Summary: &exif.Summary{Path: "b.jpg"}})
+			cp.add(checkpointRecord{Failed: &batchFailure{Path: "bad.jpg", Error: "exif: no EXIF data"}})
+			if err := cp.flush(); err != nil {
+				t.Fatal(err)
+			}
+			cp.f.WriteString(tt.tail)
+			cp.f.Close()
+
+			m, cp, err := resumeCheckpoint(path, "/photos")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !m.Started.Equal(started) || m.Summaries[0].Make != "Canon" {
+				t.Errorf("manifest %+v", m)
+			}
+			// The run goes on appending after the last whole record.
+			cp.add(checkpointRecord{Summary: &exif.Summary{Path: "d.jpg"}})
+			if err := cp.flush(); err != nil {
+				t.Fatal(err)
+			}
+			cp.f.Close()
+			m, cp, err = resumeCheckpoint(path, "/photos")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp.f.Close()
+			var got []string
+			for _, s := range m.Summaries {
+				got = append(got, s.Path)
+			}
+			for _, f := range m.Failed {
+				got = append(got, f.Path)
+			}
+			want := []string{"a.jpg", "b.jpg", "d.jpg", "bad.jpg"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("resumed files %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestBatchCheckpointRejects(t *testing.T) {
+	tests := []struct {
+		name, data, dir, want string
+	}{
+		{"other directory", `{"dir":"/other","started":"2024-05-01T10:00:00Z"}` + "\n", "/photos", "is of a run over /other"},
+		{"torn header", `{"dir":"/pho`, "/photos", "incomplete"},
+		{"not a checkpoint", "{\n", "/photos", "checkpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := resumeCheckpoint(path, tt.dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/catalog"
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// catalogFlag merges the metadata of a Lightroom catalog, Apple Photos
+// library or Capture One session, and of raw developer sidecars, into
+// decoded summaries.
+type catalogFlag struct {
+	path string
+	c    *catalog.Catalog
+}
+
+func (f *catalogFlag) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.path, "catalog", "", "merge ratings, keywords and collections from a Lightroom .lrcat, Apple Photos .photoslibrary or Capture One .cosessiondb")
+}
+
+func (f *catalogFlag) load() error {
+	if f.path == "" {
+		return nil
+	}
+	var err error
+	f.c, err = catalog.Open(f.path)
+	return err
+}
+
+// apply merges the catalog entry of s, then its sidecar, which is the
+// more recent edit. Unreadable sidecars are reported on stderr.
+func (f *catalogFlag) apply(a *app, s *exif.Summary) {
+	if f.c != nil {
+		f.c.Apply(s)
+	}
+	sc, err := catalog.FindSidecar(s.Path)
+	if err != nil {
+		fmt.Fprintf(a.stderr, "shootlog: ignoring sidecar: %v\n", err)
+		return
+	}
+	if sc != nil {
+		sc.Apply(s)
+	}
+}
@@ -0,0 +1,133 @@
+// Package cli implements the shootlog command line interface.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// command is a shootlog subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+// commands lists the subcommands in the order shown by help. Running
+// shootlog without a subcommand extracts metadata.
+var commands = []command{
+	{"report", "summarize a shooting session", runReport},
+	{"flight", "list the altitude, heading and gimbal angle of each drone shot", runFlight},
+	{"validate", "check EXIF structure against the EXIF 2.32 spec", runValidate},
+	{"detect-pii", "flag personally identifying metadata such as GPS, owner names, serial numbers, contacts and faces", runDetectPII},
+	{"inspect", "show the raw IFD entries and an annotated hexdump of the EXIF block", runInspect},
+	{"template-funcs", "list the functions of --template, report and rename templates", runTemplateFuncs},
+	{"tags", "list the known tags with their types, descriptions and summary fields", runTags},
+	{"compat", "diff extracted fields against exiftool over a corpus", runCompat},
+	{"edit", "set rating, title and keywords in the EXIF data", runEdit},
+	{"embed", "write EXIF data built from a JSON summary into JPEGs", runEmbed},
+	{"film", "apply a film roll log to scanned frames", runFilm},
+	{"stamp", "write artist, copyright and creator tool into deliveries", runStamp},
+	{"delivery", "check a delivery folder against the configured metadata policy", runDelivery},
+	{"policy", "check or enforce metadata rules across files", runPolicy},
+	{"takeout-merge", "restore capture times, GPS and descriptions from Google Takeout sidecars", runTakeoutMerge},
+	{"clock-check", "flag capture times of cameras whose clock was reset and fix them from neighboring frames or file times", runClockCheck},
+	{"clock-offset", "estimate the clock offsets of several bodies from sync shots or GPS time", runClockOffset},
+	{"renumber", "interleave the photos of several bodies by corrected capture time and rename them in one sequence", runRenumber},
+	{"infer-dates", "infer missing capture dates from file and folder names, and embed them", runInferDates},
+	{"contactsheet", "lay out thumbnails with their exposure settings on printable pages", runContactSheet},
+	{"annotate", "merge a shot list of frame notes into a shooting log", runAnnotate},
+	{"manifest", "write upload manifests for Flickr, Instagram or SmugMug", runManifest},
+	{"scrub", "redact or coarsen GPS data of photos taken in home zones", runScrub},
+	{"watch", "print summaries and run hooks for images as they arrive", runWatch},
+	{"serve", "summarize uploaded images over HTTP", runServe},
+	{"batch", "summarize a directory once and write a manifest of the run, for batch jobs", runBatch},
+	{"verify-manifest", "check the signature of a batch manifest against the signer's SSH public key", runVerifyManifest},
+	{"index", "update an index of a library, decoding only the files that changed", runIndex},
+	{"query", "search a library index with a filter expression", runQuery},
+	{"daemon", "keep a library index loaded and answer queries over a unix socket, for editors and scripts", runDaemon},
+	{"rpc", "answer editor plugins in JSON-RPC on standard input and output, with file metadata for hover popups", runRPC},
+	{"export", "write a whole library index as a Parquet, Arrow, SQL, CSV or JSON file for data tools", runExport},
+	{"search-pages", "write an HTML page per photo of a library index for Spotlight or Windows Search to index", runSearchPages},
+	{"gear", "list the camera bodies and lenses of a library index by serial number", runGear},
+	{"risk", "flag shots likely to be noisy or soft from ISO, shutter time, focal length and stabilization", runRisk},
+	{"keepers", "compute keeper rates from star ratings by lens, focal length, shutter speed and focus mode", runKeepers},
+	{"trends", "compare the shooting of two periods or directories: volume, gear, focal lengths and ISO", runTrends},
+	{"jobs", "report the photos, dates, gear and deliverables of each client job", runJobs},
+	{"coverage", "report the gaps, photos per hour and per-room coverage of an event", runCoverage},
+	{"consistency", "flag frames whose shutter speed, aperture or ISO strays from the rest of their set", runConsistency},
+	{"repair", "rewrite corrupt EXIF blocks as clean ones, keeping their values", runRepair},
+	{"verify", "check the files of a library index against their hashes to detect bit rot", runVerify},
+	{"backup", "record which backup sets hold the files of a library index and list those short of backups", runBackup},
+	{"merge", "merge another machine's index of the same library, resolving conflicts by content hash", runMerge},
+	{"sync", "list the files and backup marks each of two indexes lacks", runSync},
+	{"restore", "put back the originals of files rewritten in place from the trash", runRestore},
+	{"provenance", "list the provenance log of the files shootlog changed and check that it is intact", runProvenance},
+}
+
+// app carries the streams shared by every command.
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes the command line and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	a := &app{stdout: stdout, stderr: stderr}
+	run := runExtract
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name == args[0] {
+				run, args = c.run, args[1:]
+				break
+			}
+		}
+	}
+	err := run(a, args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	}
+	fmt.Fprintf(stderr, "shootlog: %v\n", err)
+	return 1
+}
+
+// errUsage reports a command line error whose message the flag package
+// has already printed.
+var errUsage = errors.New("usage error")
+
+// newFlagSet returns a flag set whose errors are returned to Run.
+func (a *app) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// printCommands lists the subcommands after the root usage.
+func (a *app) printCommands() {
+	fmt.Fprintln(a.stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(a.stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+}
+
+// parse parses args and converts flag errors to errUsage.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/clock"
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// clockFix is one line of the --log of clock-check: a capture time that
+// was replaced.
+type clockFix struct {
+	Time   time.Time `json:"time"`
+	Path   string    `json:"path"`
+	Output string    `json:"output,omitempty"`
+	Was    string    `json:"was"`
+	Now    string    `json:"now"`
+	Basis  string    `json:"basis"`
+	Reason string    `json:"reason"`
+}
+
+// runClockCheck lists photos whose camera clock was evidently wrong with
+// the corrections their neighbors and file times suggest, and with --fix
+// writes the corrections of one basis.
+func runClockCheck(a *app, args []string) error {
+	fs := a.newFlagSet("clock-check", "shootlog clock-check [--input file | --dir dir] [--fix neighbors|mtime [--log file] [--out-dir dir | --force]]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	fix := fs.String("fix", "", "write the suggested capture times based on neighbors (the adjacent frames of the same body) or mtime (the file modification time)")
+	logPath := fs.String("log", "", "append each correction as a JSON line to this file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *fix != "" && *fix != clock.BasisNeighbors && *fix != clock.BasisMtime {
+		return fmt.Errorf("unknown basis %q (want neighbors or mtime)", *fix)
+	}
+	if *logPath != "" && *fix == "" {
+		return fmt.Errorf("--log needs --fix")
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	frames := make([]clock.Frame, len(summaries))
+	for i, s := range summaries {
+		frames[i].Summary = s
+		if fi, err := os.Stat(s.Path); err == nil {
+			frames[i].ModTime = fi.ModTime()
+		}
+	}
+	suspects := clock.Check(frames, time.Now())
+	const layout = "2006-01-02T15:04:05"
+	left := 0
+	for _, sp := range suspects {
+		if *fix == "" {
+			fmt.Fprintf(a.stdout, "%s  %s  %s\n", sp.Path, sp.Recorded.Format(layout), sp.Reason)
+			for _, sg := range sp.Suggestions {
+				fmt.Fprintf(a.stdout, "  %-9s  %s", sg.Basis, sg.Time.Format(layout))
+				if sg.Detail != "" {
+					fmt.Fprintf(a.stdout, "  %s", sg.Detail)
+				}
+				fmt.Fprintln(a.stdout)
+			}
+			if len(sp.Suggestions) == 0 {
+				fmt.Fprintln(a.stdout, "  no suggestion")
+			}
+			left++
+			continue
+		}
+		sg, ok := sp.Get(*fix)
+		if !ok {
+			fmt.Fprintf(a.stdout, "skipped %s: no %s suggestion\n", sp.Path, *fix)
+			left++
+			continue
+		}
+		change := fmt.Sprintf("%s -> %s (%s)", sp.Recorded.Format(layout), sg.Time.Format(layout), sp.Reason)
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would fix %s: %s\n", sp.Path, change)
+			continue
+		}
+		data, err := os.ReadFile(sp.Path)
+		if err != nil {
+			return err
+		}
+		v := sg.Time.Format("2006:01:02 15:04:05")
+		fixed, err := exif.Apply(data, exif.SetASCII(exif.TagDateTimeOriginal, v).In(exif.ExifIFD), exif.SetASCII(exif.TagDateTimeDigitized, v).In(exif.ExifIFD))
+		if err != nil {
+			return fmt.Errorf("%s: %w", sp.Path, err)
+		}
+		dst, err := out.write(sp.Path, data, fixed)
+		if err != nil {
+			return err
+		}
+		if *logPath != "" {
+			if err := appendClockFix(*logPath, sp, sg, dst); err != nil {
+				return fmt.Errorf("wrote %s but could not log it: %w", dst, err)
+			}
+		}
+		fmt.Fprintf(a.stdout, "fixed %s: %s\n", dst, change)
+	}
+	if *fix != "" && out.dryRun() && len(suspects) > left {
+		a.dryRunNote()
+	}
+	if left > 0 {
+		return fmt.Errorf("%d of %d photos have implausible capture times", left, len(summaries))
+	}
+	return nil
+}
+
+// appendClockFix adds the correction of sp to the log at path.
+func appendClockFix(path string, sp clock.Suspect, sg clock.Suggestion, dst string) error {
+	const layout = "2006-01-02T15:04:05"
+	e := clockFix{Time: time.Now().UTC(), Path: sp.Path, Was: sp.Recorded.Format(layout), Now: sg.Time.Format(layout), Basis: sg.Basis, Reason: sp.Reason}
+	if filepath.Clean(dst) != filepath.Clean(sp.Path) {
+		e.Output = dst
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/clock"
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// estimateFlags are the flags estimating body clock offsets: --sync pairs
+// of photos of the same moment and --gps-time.
+type estimateFlags struct {
+	sync []string
+	gps  bool
+	tz   string
+}
+
+func (f *estimateFlags) register(fs *flag.FlagSet) {
+	fs.Func("sync", "two photos of the same moment as reference=other, the reference from the body whose clock is trusted; repeatable", func(v string) error {
+		if ref, other, ok := strings.Cut(v, "="); !ok || ref == "" || other == "" {
+			return fmt.Errorf("invalid sync pair %q (want reference.jpg=other.jpg)", v)
+		}
+		f.sync = append(f.sync, v)
+		return nil
+	})
+	fs.BoolVar(&f.gps, "gps-time", false, "estimate offsets from the GPS time of photos with a position")
+	fs.StringVar(&f.tz, "tz", "Local", "time zone of capture times without a UTC offset, for --gps-time")
+}
+
+// set reports whether any estimate was asked for.
+func (f *estimateFlags) set() bool {
+	return f.gps || len(f.sync) > 0
+}
+
+// estimate computes the offsets of the bodies in summaries and of the sync
+// pairs. Sync estimates, asked for explicitly, replace GPS ones.
+func (f *estimateFlags) estimate(summaries []*exif.Summary) ([]clock.Estimate, error) {
+	var gps []clock.Estimate
+	if f.gps {
+		loc, err := time.LoadLocation(f.tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tz: %w", err)
+		}
+		gps = clock.EstimateGPS(summaries, loc)
+	}
+	var pairs []clock.SyncPair
+	for _, v := range f.sync {
+		ref, other, _ := strings.Cut(v, "=")
+		rs, err := exif.DecodeFile(ref)
+		if err != nil {
+			return nil, err
+		}
+		ss, err := exif.DecodeFile(other)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, clock.SyncPair{Reference: rs, Other: ss})
+	}
+	synced, err := clock.EstimateSync(pairs, gps)
+	if err != nil {
+		return nil, err
+	}
+	out := synced
+	for _, e := range gps {
+		if !hasEstimate(synced, e.Body) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func hasEstimate(es []clock.Estimate, body string) bool {
+	for _, e := range es {
+		if e.Body == body {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEstimates prints one line per estimate.
+func writeEstimates(w io.Writer, es []clock.Estimate) {
+	for _, e := range es {
+		fmt.Fprintf(w, "offset %s %s (%s", e.Body, clock.FormatOffset(e.Offset), e.Basis)
+		if e.Reference != "" {
+			fmt.Fprintf(w, " against %s", e.Reference)
+		}
+		photos := "photos"
+		if e.Samples == 1 {
+			photos = "photo"
+		}
+		fmt.Fprintf(w, ", %d %s", e.Samples, photos)
+		if e.Spread > 0 {
+			fmt.Fprintf(w, ", spread %s", e.Spread)
+		}
+		fmt.Fprintln(w, ")")
+	}
+}
+
+// runClockOffset estimates the clock offsets of the bodies that took a
+// shoot and prints them with the renumber flags applying them.
+func runClockOffset(a *app, args []string) error {
+	fs := a.newFlagSet("clock-offset", "shootlog clock-offset [--input file | --dir dir] [--sync reference.jpg=other.jpg ...] [--gps-time [--tz zone]]")
+	var in inputFlags
+	in.register(fs)
+	var est estimateFlags
+	est.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if !est.set() {
+		return fmt.Errorf("pass --sync or --gps-time")
+	}
+	var summaries []*exif.Summary
+	if in.input != "" || in.dir != "" || est.gps {
+		paths, err := in.paths()
+		if err != nil {
+			return err
+		}
+		if summaries, err = a.decodeAll(paths); err != nil {
+			return err
+		}
+	}
+	es, err := est.estimate(summaries)
+	if err != nil {
+		return err
+	}
+	if len(es) == 0 {
+		return fmt.Errorf("no photos to estimate offsets from")
+	}
+	writeEstimates(a.stdout, es)
+	args = nil
+	for _, e := range es {
+		if e.Offset != 0 {
+			args = append(args, fmt.Sprintf("--offset-for %q=%s", e.Body, clock.FormatOffset(e.Offset)))
+		}
+	}
+	if args != nil {
+		fmt.Fprintf(a.stdout, "\nshootlog renumber %s\n", strings.Join(args, " "))
+	}
+	return nil
+}
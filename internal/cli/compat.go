@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/compat"
+)
+
+func runCompat(a *app, args []string) error {
+	fs := a.newFlagSet("compat", "shootlog compat [--input file | --dir dir] [--exiftool path] [--output text|json]")
+	var in inputFlags
+	in.register(fs)
+	exiftool := fs.String("exiftool", "exiftool", "exiftool executable")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	rep, err := compat.Runner{Exiftool: *exiftool}.Compare(context.Background(), summaries)
+	if err != nil {
+		return err
+	}
+	switch *output {
+	case "text":
+		err = rep.WriteText(a.stdout)
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	default:
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err != nil {
+		return err
+	}
+	if rep.Failed() {
+		return errors.New("shootlog and exiftool disagree")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runConsistency flags the frames of each studio set whose shutter speed,
+// aperture or ISO strays from the rest of the set, catching flash sync
+// errors right after the shoot. It fails when any frame deviates.
+func runConsistency(a *app, args []string) error {
+	fs := a.newFlagSet("consistency", "shootlog consistency [--input file | --dir dir] [--gap 30m] [--tolerance 0.34] [--min-frames 5] [--filter expr] [--output text|json] [--lang en|ja]")
+	var in inputFlags
+	in.register(fs)
+	gap := fs.Duration("gap", 30*time.Minute, "pause that starts a new set within a directory; 0 makes each directory one set")
+	tolerance := fs.Float64("tolerance", 0.34, "stops a frame's shutter speed, aperture or ISO may differ from its set's before it is flagged")
+	minFrames := fs.Int("min-frames", 5, "smallest set checked")
+	output := fs.String("output", "text", "output format: text or json")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *gap < 0 {
+		return fmt.Errorf("--gap must not be negative, got %s", *gap)
+	}
+	if *tolerance < 0 {
+		return fmt.Errorf("--tolerance must not be negative, got %g", *tolerance)
+	}
+	if *minFrames < 2 {
+		return fmt.Errorf("--min-frames must be at least 2, got %d", *minFrames)
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	c := report.NewConsistency(where.apply(summaries), report.ConsistencyOptions{
+		Gap: *gap, Tolerance: *tolerance, MinFrames: *minFrames,
+	})
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(c)
+	} else {
+		err = c.WriteText(a.stdout, loc)
+	}
+	if err != nil {
+		return err
+	}
+	if c.Deviating > 0 {
+		return fmt.Errorf("%d of %d frames deviate from their set's exposure", c.Deviating, c.Frames)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/pdf"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/sheet"
+)
+
+// pageSizes are the named paper sizes of --page.
+var pageSizes = map[string]pdf.Size{
+	"a3": pdf.A3, "a4": pdf.A4, "a5": pdf.A5, "letter": pdf.Letter, "legal": pdf.Legal,
+}
+
+func runContactSheet(a *app, args []string) error {
+	fs := a.newFlagSet("contactsheet", "shootlog contactsheet --output sheet.jpg|sheet.png|sheet.pdf [--input file | --dir dir] [--columns 4] [--rows 5] [--page a4|a3|a5|letter|legal|WxH] [--landscape] [--dpi 150] [--title text] [--sort path|datetime|iso] [--catalog path] [--filter expr]")
+	var in inputFlags
+	in.register(fs)
+	output := fs.String("output", "", "file to write; the extension selects JPEG, PNG or PDF, and image sheets of several pages are numbered sheet-1.jpg, sheet-2.jpg and so on")
+	columns := fs.Int("columns", 4, "thumbnails per row")
+	rows := fs.Int("rows", 5, "rows per page")
+	page := fs.String("page", "a4", "page size: a3, a4, a5, letter, legal, or WxH in millimetres such as 210x297")
+	landscape := fs.Bool("landscape", false, "turn the page sideways")
+	dpi := fs.Int("dpi", 150, "resolution of image sheets and of the thumbnails in a PDF")
+	title := fs.String("title", "", "text printed at the foot of every page")
+	sortKey := fs.String("sort", report.SortPath, "order of the thumbnails: "+strings.Join(report.SortKeys, ", "))
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("missing --output")
+	}
+	ext := strings.ToLower(filepath.Ext(*output))
+	if !slices.Contains([]string{".jpg", ".jpeg", ".png", ".pdf"}, ext) {
+		return fmt.Errorf("--output: unknown format %q (want .jpg, .png or .pdf)", ext)
+	}
+	if !slices.Contains(report.SortKeys, *sortKey) {
+		return fmt.Errorf("unknown sort key %q", *sortKey)
+	}
+	size, err := parsePageSize(*page)
+	if err != nil {
+		return err
+	}
+	if *landscape {
+		size.Width, size.Height = size.Height, size.Width
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	// Files without EXIF data still get a thumbnail, with only their name
+	// under it.
+	var summaries []*exif.Summary
+	for _, p := range paths {
+		s, err := exif.DecodeFile(p)
+		switch {
+		case errors.Is(err, exif.ErrNoExif):
+			s = &exif.Summary{Path: p}
+		case err != nil:
+			fmt.Fprintf(a.stderr, "shootlog: skipping %v\n", err)
+			continue
+		}
+		cat.apply(a, s)
+		cfg.Privacy.Protect(s)
+		summaries = append(summaries, s)
+	}
+	summaries = where.apply(summaries)
+	if len(summaries) == 0 {
+		return errors.New("no photos for the contact sheet")
+	}
+	if err := report.Sort(summaries, *sortKey); err != nil {
+		return err
+	}
+
+	opts := sheet.Options{Columns: *columns, Rows: *rows, Page: size, DPI: *dpi, Title: *title}
+	if ext == ".pdf" {
+		doc, err := sheet.PDF(summaries, opts)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if _, err := doc.WriteTo(&buf); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(*output, buf.Bytes()); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "wrote %s\n", *output)
+		return nil
+	}
+	pages, err := sheet.Raster(summaries, opts)
+	if err != nil {
+		return err
+	}
+	for i, p := range pages {
+		dst := *output
+		if len(pages) > 1 {
+			dst = strings.TrimSuffix(dst, filepath.Ext(dst)) + "-" + strconv.Itoa(i+1) + filepath.Ext(dst)
+		}
+		var buf bytes.Buffer
+		if ext == ".png" {
+			err = png.Encode(&buf, p)
+		} else {
+			err = jpeg.Encode(&buf, p, &jpeg.Options{Quality: 90})
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(dst, buf.Bytes()); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "wrote %s\n", dst)
+	}
+	return nil
+}
+
+// parsePageSize reads a named paper size or WxH in millimetres.
+func parsePageSize(v string) (pdf.Size, error) {
+	if s, ok := pageSizes[strings.ToLower(v)]; ok {
+		return s, nil
+	}
+	w, h, ok := strings.Cut(strings.ToLower(v), "x")
+	if ok {
+		wmm, err1 := strconv.ParseFloat(w, 64)
+		hmm, err2 := strconv.ParseFloat(h, 64)
+		if err1 == nil && err2 == nil && wmm > 0 && hmm > 0 {
+			return pdf.Size{Width: wmm / 25.4 * 72, Height: hmm / 25.4 * 72}, nil
+		}
+	}
+	return pdf.Size{}, fmt.Errorf("invalid --page %q: want a3, a4, a5, letter, legal or WxH in millimetres", v)
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runCoverage reports how an event was covered: the longest spans without
+// shots, photos per hour by shooter, and per-room coverage, so a team of
+// shooters can see where and when they left holes.
+func runCoverage(a *app, args []string) error {
+	fs := a.newFlagSet("coverage", "shootlog coverage [--input file | --dir dir] [--min-gap 10m] [--rooms none|gps|keyword] [--room-radius 30] [--room-prefix room:] [--filter expr] [--output text|json] [--lang en|ja]")
+	var in inputFlags
+	in.register(fs)
+	minGap := fs.Duration("min-gap", 10*time.Minute, "shortest pause between shots reported as a gap")
+	rooms := fs.String("rooms", report.RoomsNone, "how to tell rooms apart: none, gps (clusters of nearby photos) or keyword")
+	radius := fs.Float64("room-radius", 30, "with --rooms gps, the distance in meters within which photos share a room")
+	prefix := fs.String("room-prefix", "room:", "with --rooms keyword, the prefix of the keyword naming the room")
+	output := fs.String("output", "text", "output format: text or json")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	switch *rooms {
+	case report.RoomsNone, report.RoomsGPS, report.RoomsKeyword:
+	default:
+		return fmt.Errorf("unknown --rooms %q (want none, gps or keyword)", *rooms)
+	}
+	if *rooms == report.RoomsGPS && !(*radius > 0) {
+		return fmt.Errorf("--room-radius must be positive, got %g", *radius)
+	}
+	if *minGap < 0 {
+		return fmt.Errorf("--min-gap must not be negative, got %s", *minGap)
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cfg.Privacy.Protect(s)
+	}
+	c := report.NewCoverage(where.apply(summaries), report.CoverageOptions{
+		MinGap: *minGap, Rooms: *rooms, RoomRadius: *radius, RoomPrefix: *prefix,
+	})
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+	return c.WriteText(a.stdout, loc)
+}
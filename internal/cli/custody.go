@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/index"
+	"github.com/ryoh827/shootlog/internal/provenance"
+	"github.com/ryoh827/shootlog/internal/trash"
+)
+
+// custody applies the archive section of the config file to what a
+// command writes: it refuses in-place rewrites of a read-only archive,
+// keeps the originals of rewritten files in the trash and records every
+// write in the provenance log.
+type custody struct {
+	loaded  bool
+	archive config.Archive
+}
+
+func (c *custody) load() error {
+	if c.loaded {
+		return nil
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	c.archive, c.loaded = cfg.Archive, true
+	return nil
+}
+
+// check fails when rewriting path in place is not allowed.
+func (c *custody) check(path string) error {
+	if err := c.load(); err != nil {
+		return err
+	}
+	if c.archive.ReadOnly {
+		return fmt.Errorf("%s: the archive is read-only (archive.read_only in the config file); write copies with --out-dir", path)
+	}
+	return nil
+}
+
+// keep puts original, the contents of path about to be replaced, in the
+// configured trash.
+func (c *custody) keep(path string, original []byte) error {
+	if err := c.load(); err != nil {
+		return err
+	}
+	if c.archive.Trash == "" {
+		return nil
+	}
+	t, err := trash.Open(c.archive.Trash)
+	if err != nil {
+		return err
+	}
+	if _, err := t.Put(path, original); err != nil {
+		return fmt.Errorf("%s: not written: %w", path, err)
+	}
+	return nil
+}
+
+// record logs that the command wrote after, formerly before, for path to
+// output.
+func (c *custody) record(action, path, output string, before, after []byte) error {
+	if err := c.load(); err != nil {
+		return err
+	}
+	if c.archive.Log == "" {
+		return nil
+	}
+	// Relative paths mean nothing once the log outlives the shell.
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if output, err = filepath.Abs(output); err != nil {
+		return err
+	}
+	e := provenance.NewEntry(os.Args, action, path)
+	if output != path {
+		e.Output = output
+	}
+	e.Before, e.After = provenance.Sum(before), provenance.Sum(after)
+	if err := provenance.Append(c.archive.Log, e); err != nil {
+		return fmt.Errorf("wrote %s but could not log it: %w", output, err)
+	}
+	return nil
+}
+
+// writeCatalog replaces the index at path with data and logs the update.
+// from, when set, is the index data was made from, loaded from path; the
+// write fails when another process replaced the file meanwhile, whose
+// update would otherwise be lost.
+func writeCatalog(path string, data []byte, from *index.Index) error {
+	before, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if from != nil {
+		if err := from.Check(before); err != nil {
+			return fmt.Errorf("%s: %w, probably by another shootlog; nothing was written, so run again", path, err)
+		}
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	var c custody
+	return c.record(provenance.Catalog, path, path, before, data)
+}
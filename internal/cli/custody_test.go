@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+// TestWriteCatalogConcurrent has two updates of one index read it before
+// either writes, as a watch and a one-off run might.
+func TestWriteCatalogConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(config.EnvPath, "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "library.db")
+	writeTestIndex(t, path)
+
+	update := func(ix *index.Index, set string) []byte {
+		t.Helper()
+		ix.Runs = append(ix.Runs, index.Run{Time: time.Now()})
+		ix.Files = append(ix.Files, &index.File{Path: set + ".jpg", SHA256: set})
+		data, err := ix.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstData := update(first, "a")
+	if err := writeCatalog(path, firstData, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCatalog(path, update(second, "b"), second); !errors.Is(err, index.ErrChanged) {
+		t.Fatalf("second write = %v, want ErrChanged", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, firstData) {
+		t.Error("the first update was overwritten")
+	}
+
+	// Read again, the second update goes through and keeps the first.
+	again, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCatalog(path, update(again, "b"), again); err != nil {
+		t.Fatal(err)
+	}
+	final, err := loadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(final.Files) != 2 || final.Files[0].Path != "a.jpg" || final.Files[1].Path != "b.jpg" {
+		t.Errorf("files %+v, want a.jpg and b.jpg", final.Files)
+	}
+
+	// Without the index it came from, the file is replaced regardless,
+	// as merge --out does to another index.
+	other := filepath.Join(dir, "other.db")
+	writeTestIndex(t, other)
+	if err := writeCatalog(other, firstData, nil); err != nil {
+		t.Error(err)
+	}
+}
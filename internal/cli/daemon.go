@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+	"github.com/ryoh827/shootlog/pkg/jsonrpc"
+)
+
+// runDaemon keeps a library index loaded and answers queries about it
+// over a unix socket in JSON-RPC 2.0, so that editors and scripts asking
+// often do not pay for reading the index each time. The index is read
+// again whenever shootlog index or merge has changed it.
+func runDaemon(a *app, args []string) error {
+	fs := a.newFlagSet("daemon", "shootlog daemon --index path [--socket path]")
+	path := fs.String("index", "", "index file written by shootlog index")
+	socket := fs.String("socket", defaultSocket(), "unix socket to listen on")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--index is required")
+	}
+	abs, err := filepath.Abs(*path)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	d := &daemon{path: abs, cfg: cfg, exprs: map[string]*filter.Expr{}}
+	if err := d.refresh(true); err != nil {
+		return err
+	}
+	if c, err := net.Dial("unix", *socket); err == nil {
+		c.Close()
+		return fmt.Errorf("%s: a daemon is already listening", *socket)
+	}
+	// A socket left by a daemon that did not shut down cleanly.
+	os.Remove(*socket)
+	ln, err := net.Listen("unix", *socket)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	if err := os.Chmod(*socket, 0o600); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var conns sync.Map
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+		conns.Range(func(c, _ any) bool {
+			c.(net.Conn).Close()
+			return true
+		})
+	}()
+	fmt.Fprintf(a.stderr, "shootlog: serving %d photos of %s on %s\n", len(d.files), abs, *socket)
+	var wg sync.WaitGroup
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		conns.Store(c, true)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conns.Delete(c)
+			defer c.Close()
+			jsonrpc.Serve(ctx, c, c, d.handle)
+		}()
+	}
+}
+
+// defaultSocket is the socket of the daemon: in $XDG_RUNTIME_DIR, which
+// only the user can reach, or else in the temporary directory under the
+// user's ID.
+func defaultSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "shootlog.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("shootlog-%d.sock", os.Getuid()))
+}
+
+// daemon holds an index with its home zones applied and the fields each
+// summary is matched by, computed once per load.
+type daemon struct {
+	path string
+	cfg  *config.Config
+
+	mu       sync.Mutex
+	size     int64
+	modTime  time.Time
+	loaded   time.Time
+	files    []daemonFile
+	byPath   map[string]*exif.Summary
+	exprs    map[string]*filter.Expr
+	requests atomic.Int64
+}
+
+type daemonFile struct {
+	summary *exif.Summary
+	fields  map[string]any
+}
+
+// refresh reads the index again when it changed on disk, or always with
+// force.
+func (d *daemon) refresh(force bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fi, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	if !force && fi.Size() == d.size && fi.ModTime().Equal(d.modTime) {
+		return nil
+	}
+	ix, err := loadIndex(d.path)
+	if err != nil {
+		return err
+	}
+	var files []daemonFile
+	byPath := map[string]*exif.Summary{}
+	for _, f := range ix.Files {
+		if f.Summary == nil {
+			continue
+		}
+		d.cfg.Privacy.Protect(f.Summary)
+		files = append(files, daemonFile{f.Summary, indexFields(f)})
+		byPath[f.Path] = f.Summary
+	}
+	d.files, d.byPath = files, byPath
+	d.size, d.modTime, d.loaded = fi.Size(), fi.ModTime(), time.Now()
+	return nil
+}
+
+// snapshot returns the files and paths of the latest load. Loads replace
+// them rather than change them, so they can be read without the lock.
+func (d *daemon) snapshot() ([]daemonFile, map[string]*exif.Summary) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.files, d.byPath
+}
+
+// expr parses src, remembering expressions asked for before.
+func (d *daemon) expr(src string) (*filter.Expr, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.exprs[src]; ok {
+		return e, nil
+	}
+	e, err := filter.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.exprs) >= 1000 {
+		clear(d.exprs)
+	}
+	d.exprs[src] = e
+	return e, nil
+}
+
+// Parameters and results of the daemon's methods.
+type (
+	daemonQuery struct {
+		// Index, when set, is the index the client meant; the daemon
+		// refuses to answer for another.
+		Index      string `json:"index,omitempty"`
+		Expr       string `json:"expr,omitempty"`
+		Provenance bool   `json:"provenance,omitempty"`
+	}
+	daemonPhotos struct {
+		Photos []*exif.Summary `json:"photos"`
+	}
+	daemonGet struct {
+		Path string `json:"path"`
+	}
+	daemonStats struct {
+		Index    string    `json:"index"`
+		Photos   int       `json:"photos"`
+		Loaded   time.Time `json:"loaded"`
+		Requests int64     `json:"requests"`
+	}
+)
+
+// handle answers the methods query, get, stats and reload.
+func (d *daemon) handle(_ context.Context, method string, params json.RawMessage) (any, error) {
+	d.requests.Add(1)
+	decode := func(v any) error {
+		if len(params) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(params, v); err != nil {
+			return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+		}
+		return nil
+	}
+	switch method {
+	case "query":
+		var q daemonQuery
+		if err := decode(&q); err != nil {
+			return nil, err
+		}
+		if q.Index != "" && q.Index != d.path {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "this daemon serves %s, not %s", d.path, q.Index)
+		}
+		return d.query(q)
+	case "get":
+		var g daemonGet
+		if err := decode(&g); err != nil {
+			return nil, err
+		}
+		if err := d.refresh(false); err != nil {
+			return nil, err
+		}
+		_, byPath := d.snapshot()
+		s, ok := byPath[g.Path]
+		if !ok {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s is not in the index", g.Path)
+		}
+		c := *s
+		c.Sources = nil
+		return &c, nil
+	case "stats":
+		if err := d.refresh(false); err != nil {
+			return nil, err
+		}
+		files, _ := d.snapshot()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return daemonStats{Index: d.path, Photos: len(files), Loaded: d.loaded, Requests: d.requests.Load()}, nil
+	case "reload":
+		return nil, d.refresh(true)
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "no method %q", method)
+}
+
+func (d *daemon) query(q daemonQuery) (any, error) {
+	var expr *filter.Expr
+	if q.Expr != "" {
+		var err error
+		if expr, err = d.expr(q.Expr); err != nil {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+		}
+	}
+	if err := d.refresh(false); err != nil {
+		return nil, err
+	}
+	files, _ := d.snapshot()
+	photos := []*exif.Summary{}
+	for _, f := range files {
+		if expr != nil && !expr.MatchFields(f.fields) {
+			continue
+		}
+		// Summaries are shared between requests.
+		c := *f.summary
+		if !q.Provenance {
+			c.Sources = nil
+		}
+		photos = append(photos, &c)
+	}
+	return daemonPhotos{photos}, nil
+}
+
+// queryDaemon asks the daemon on socket for the photos of its index
+// matching expr. index, when set, must be the daemon's.
+func queryDaemon(socket, index, expr string, provenance bool) ([]*exif.Summary, error) {
+	if index != "" {
+		abs, err := filepath.Abs(index)
+		if err != nil {
+			return nil, err
+		}
+		index = abs
+	}
+	c, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("no daemon (start one with shootlog daemon): %w", err)
+	}
+	defer c.Close()
+	var res daemonPhotos
+	if err := jsonrpc.NewClient(c).Call("query", daemonQuery{Index: index, Expr: expr, Provenance: provenance}, &res); err != nil {
+		return nil, err
+	}
+	return res.Photos, nil
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+	"github.com/ryoh827/shootlog/internal/geo"
+	"github.com/ryoh827/shootlog/internal/index"
+	"github.com/ryoh827/shootlog/internal/privacy"
+	"github.com/ryoh827/shootlog/pkg/jsonrpc"
+)
+
+// rpcClient serves h on one end of a net.Pipe and returns a client of
+// the other.
+func rpcClient(t *testing.T, h jsonrpc.Handler) *jsonrpc.Client {
+	t.Helper()
+	client, server := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jsonrpc.Serve(ctx, server, server, h)
+		server.Close()
+	}()
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		<-done
+	})
+	return jsonrpc.NewClient(client)
+}
+
+// rpcCode returns the code of an error response, or 0.
+func rpcCode(err error) int {
+	var e *jsonrpc.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// writeTestIndex writes an index of files, as shootlog index would.
+func writeTestIndex(t *testing.T, path string, files ...*index.File) {
+	t.Helper()
+	ix := &index.Index{Version: index.Version, Files: files, Runs: []index.Run{{Time: time.Now()}}}
+	data, err := ix.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testDaemon(t *testing.T) (*daemon, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "library.index")
+	writeTestIndex(t, path,
+		&index.File{Path: "a.jpg", SHA256: "aa", Summary: &exif.Summary{Path: "a.jpg", Make: "Canon", ISO: 400,
+			Latitude: ptr(35.6812), Longitude: ptr(139.7671),
+			Sources: map[string]exif.Source{"make": {Location: "IFD0", Tag: "Make"}}}},
+		&index.File{Path: "b.jpg", SHA256: "bb", Summary: &exif.Summary{Path: "b.jpg", Make: "Fujifilm", ISO: 3200}, Backups: []string{"nas"}},
+		&index.File{Path: "broken.jpg", SHA256: "cc", Error: "exif: no EXIF data"},
+	)
+	cfg := &config.Config{Privacy: privacy.Settings{Action: privacy.Redact,
+		HomeZones: []geo.Zone{{Name: "home", Latitude: 35.6812, Longitude: 139.7671, Radius: 500}}}}
+	d := &daemon{path: path, cfg: cfg, exprs: map[string]*filter.Expr{}}
+	if err := d.refresh(true); err != nil {
+		t.Fatal(err)
+	}
+	return d, path
+}
+
+func TestDaemonMethods(t *testing.T) {
+	d, path := testDaemon(t)
+	c := rpcClient(t, d.handle)
+	paths := func(p daemonPhotos) []string {
+		var out []string
+		for _, s := range p.Photos {
+			out = append(out, s.Path)
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		method string
+		params any
+		code   int
+		check  func(t *testing.T, c *jsonrpc.Client, method string, params any)
+	}{
+		{"query all", "query", daemonQuery{}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var res daemonPhotos
+			c.Call(method, params, &res)
+			if got := paths(res); len(got) != 2 || got[0] != "a.jpg" || got[1] != "b.jpg" {
+				t.Errorf("photos %v", got)
+			}
+			if res.Photos[0].Latitude != nil || res.Photos[0].Sources != nil {
+				t.Errorf("home zone or provenance shown: %+v", res.Photos[0])
+			}
+		}},
+		{"query no params", "query", nil, 0, nil},
+		{"query expr", "query", daemonQuery{Expr: "iso >= 1600 && backups = nas"}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var res daemonPhotos
+			c.Call(method, params, &res)
+			if got := paths(res); len(got) != 1 || got[0] != "b.jpg" {
+				t.Errorf("photos %v", got)
+			}
+		}},
+		{"query provenance", "query", daemonQuery{Index: path, Expr: "make = canon", Provenance: true}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var res daemonPhotos
+			c.Call(method, params, &res)
+			if len(res.Photos) != 1 || res.Photos[0].Sources["make"].Tag != "Make" {
+				t.Errorf("photos %+v", res.Photos)
+			}
+		}},
+		{"query bad expr", "query", daemonQuery{Expr: "iso >= high"}, jsonrpc.CodeInvalidParams, nil},
+		{"query other index", "query", daemonQuery{Index: "/elsewhere.index"}, jsonrpc.CodeInvalidParams, nil},
+		{"query bad params", "query", []int{1}, jsonrpc.CodeInvalidParams, nil},
+		{"get", "get", daemonGet{Path: "a.jpg"}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var s exif.Summary
+			c.Call(method, params, &s)
+			if s.Make != "Canon" || s.Latitude != nil || s.Sources != nil {
+				t.Errorf("summary %+v", s)
+			}
+		}},
+		{"get failed file", "get", daemonGet{Path: "broken.jpg"}, jsonrpc.CodeInvalidParams, nil},
+		{"get missing", "get", daemonGet{Path: "z.jpg"}, jsonrpc.CodeInvalidParams, nil},
+		{"stats", "stats", nil, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var st daemonStats
+			c.Call(method, params, &st)
+			if st.Index != path || st.Photos != 2 || st.Requests == 0 || st.Loaded.IsZero() {
+				t.Errorf("stats %+v", st)
+			}
+		}},
+		{"reload", "reload", nil, 0, nil},
+		{"unknown", "delete", nil, jsonrpc.CodeMethodNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Call(tt.method, tt.params, nil)
+			if code := rpcCode(err); code != tt.code || (tt.code == 0 && err != nil) {
+				t.Fatalf("error %v, want code %d", err, tt.code)
+			}
+			if tt.check != nil {
+				tt.check(t, c, tt.method, tt.params)
+			}
+		})
+	}
+}
+
+func TestDaemonRefresh(t *testing.T) {
+	d, path := testDaemon(t)
+	c := rpcClient(t, d.handle)
+	writeTestIndex(t, path, &index.File{Path: "new.jpg", SHA256: "dd", Summary: &exif.Summary{Path: "new.jpg"}})
+	// The rewrite may keep the size; a later time tells it apart.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	var res daemonPhotos
+	if err := c.Call("query", daemonQuery{}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Photos) != 1 || res.Photos[0].Path != "new.jpg" {
+		t.Errorf("photos after the index changed: %+v", res.Photos)
+	}
+	os.Remove(path)
+	if err := c.Call("stats", nil, nil); rpcCode(err) != jsonrpc.CodeInternalError {
+		t.Errorf("stats without an index: %v", err)
+	}
+}
+
+func TestQueryDaemon(t *testing.T) {
+	d, path := testDaemon(t)
+	dir, err := os.MkdirTemp("", "sl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "s.sock")
+	if _, err := queryDaemon(socket, "", "", false); err == nil {
+		t.Fatal("no error without a daemon")
+	}
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				jsonrpc.Serve(context.Background(), c, c, d.handle)
+			}()
+		}
+	}()
+	photos, err := queryDaemon(socket, path, "make = fujifilm", false)
+	if err != nil || len(photos) != 1 || photos[0].Path != "b.jpg" {
+		t.Errorf("photos %+v, %v", photos, err)
+	}
+	if _, err := queryDaemon(socket, filepath.Join(dir, "other.index"), "", false); rpcCode(err) != jsonrpc.CodeInvalidParams {
+		t.Errorf("query of another index: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/pathdate"
+)
+
+// runInferDates lists the capture dates the file and folder names of
+// undated photos suggest, and with --write embeds them, noting in XMP that
+// they were inferred.
+func runInferDates(a *app, args []string) error {
+	fs := a.newFlagSet("infer-dates", "shootlog infer-dates [--input file | --dir dir] [--write [--out-dir dir | --force]]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	write := fs.Bool("write", false, "embed the inferred dates as DateTimeOriginal")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	inferred := 0
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		s, err := exif.DecodeBytes(data)
+		if err != nil && !errors.Is(err, exif.ErrNoExif) {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: %v\n", p, err)
+			continue
+		}
+		if s != nil && s.DateTimeOriginal != "" {
+			continue
+		}
+		d, ok := pathdate.Infer(p)
+		if !ok {
+			fmt.Fprintf(a.stdout, "%s: no date in its name\n", p)
+			continue
+		}
+		inferred++
+		if !*write {
+			fmt.Fprintf(a.stdout, "%s: %s from %s %q\n", p, d, d.Source, d.Match)
+			continue
+		}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would write %s: datetime_original=%s from %s %q\n", p, d.Time.Format("2006-01-02T15:04:05"), d.Source, d.Match)
+			continue
+		}
+		dated, err := exif.Apply(data, exif.SetInferredTime(d.Time)...)
+		// Only JPEG files carry an XMP packet shootlog can write.
+		if err == nil && exif.IsJPEG(dated) {
+			dated, err = exif.AnnotateInferredDate(dated, d.Source, d.Match)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		dst, err := out.write(p, data, dated)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "wrote %s: datetime_original=%s from %s %q\n", dst, d.Time.Format("2006-01-02T15:04:05"), d.Source, d.Match)
+	}
+	if *write && out.dryRun() && inferred > 0 {
+		a.dryRunNote()
+	}
+	return nil
+}
+
+// inferDate fills in InferredDate from the path of a photo without a
+// capture time.
+func inferDate(s *exif.Summary) {
+	if s.DateTimeOriginal != "" {
+		return
+	}
+	d, ok := pathdate.Infer(s.Path)
+	if !ok {
+		return
+	}
+	s.InferredDate, s.DateSource = d.String(), d.Source
+	if s.Sources == nil {
+		s.Sources = map[string]exif.Source{}
+	}
+	src := exif.Source{Location: exif.LocationPath, Tag: d.Match}
+	s.Sources["inferred_date"], s.Sources["date_source"] = src, src
+}
+
+// keepUndated wraps decode so that files without EXIF yield an empty
+// summary, whose date may still be inferred, rather than being skipped.
+func keepUndated(decode func(string) (*exif.Summary, error)) func(string) (*exif.Summary, error) {
+	return func(path string) (*exif.Summary, error) {
+		s, err := decode(path)
+		if errors.Is(err, exif.ErrNoExif) {
+			return &exif.Summary{Path: path}, nil
+		}
+		return s, err
+	}
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/delivery"
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func runDelivery(a *app, args []string) error {
+	fs := a.newFlagSet("delivery", "shootlog delivery [--config file] [--input file | --dir dir] [--output text|json]")
+	var in inputFlags
+	in.register(fs)
+	configPath := fs.String("config", "", "config file (default $"+config.EnvPath+" or shootlog/config.yaml in the user config directory)")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	tree, err := config.LoadTree(*configPath)
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	results := make([]delivery.Result, 0, len(paths))
+	failed := 0
+	for _, p := range paths {
+		cfg, err := tree.For(p)
+		if err != nil {
+			return err
+		}
+		s, err := exif.DecodeFile(p)
+		var r delivery.Result
+		switch {
+		case err == nil:
+			r = delivery.Check(s, cfg.Delivery)
+		case errors.Is(err, exif.ErrNoExif):
+			// A file without metadata is an offender, not an error.
+			r = delivery.Check(&exif.Summary{Path: p}, cfg.Delivery)
+		default:
+			r = delivery.Result{Path: p, Problems: []string{err.Error()}}
+		}
+		if !r.OK() {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.OK() {
+				continue
+			}
+			fmt.Fprintf(a.stdout, "%s:\n", r.Path)
+			for _, p := range r.Problems {
+				fmt.Fprintf(a.stdout, "  %s\n", p)
+			}
+		}
+		fmt.Fprintf(a.stdout, "%d of %d files ready for delivery\n", len(results)-failed, len(results))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files violate the delivery policy", failed, len(results))
+	}
+	return nil
+}
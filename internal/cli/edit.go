@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func runEdit(a *app, args []string) error {
+	fs := a.newFlagSet("edit", "shootlog edit [--input file | --dir dir] [--rating n] [--title text] [--keywords a;b] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	rating := fs.Int("rating", 0, "star rating 0-5; 0 clears the rating")
+	title := fs.String("title", "", "title stored in XPTitle; empty clears it")
+	keywords := fs.String("keywords", "", "semicolon-separated keywords stored in XPKeywords; empty clears them")
+	var out outputFlags
+	out.register(fs, &in)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var edits []exif.Edit
+	var changes []string
+	if set["rating"] {
+		e, err := exif.SetRating(*rating)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, e...)
+		changes = append(changes, fmt.Sprintf("rating=%d", *rating))
+	}
+	if set["title"] {
+		if *title == "" {
+			edits = append(edits, exif.Delete(exif.TagXPTitle))
+		} else {
+			edits = append(edits, exif.SetXP(exif.TagXPTitle, *title))
+		}
+		changes = append(changes, fmt.Sprintf("title=%q", *title))
+	}
+	if set["keywords"] {
+		var list []string
+		for _, k := range strings.Split(*keywords, ";") {
+			if k = strings.TrimSpace(k); k != "" {
+				list = append(list, k)
+			}
+		}
+		edits = append(edits, exif.SetKeywords(list))
+		changes = append(changes, fmt.Sprintf("keywords=%q", strings.Join(list, ";")))
+	}
+	if len(edits) == 0 {
+		return errors.New("nothing to edit: pass --rating, --title or --keywords")
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	if out.dryRun() {
+		for _, p := range paths {
+			fmt.Fprintf(a.stdout, "would update %s: %s\n", p, strings.Join(changes, " "))
+		}
+		a.dryRunNote()
+		return nil
+	}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		edited, err := exif.Apply(data, edits...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		dst, err := out.write(p, data, edited)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "updated %s: %s\n", dst, strings.Join(changes, " "))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func runEmbed(a *app, args []string) error {
+	fs := a.newFlagSet("embed", "shootlog embed --metadata file.json [--input file | --dir dir] [--replace] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	metadata := fs.String("metadata", "", "JSON summary (as printed by shootlog) or array of summaries matched by file name")
+	replace := fs.Bool("replace", false, "replace existing EXIF data instead of skipping the file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *metadata == "" {
+		return errors.New("--metadata is required")
+	}
+	lookup, err := loadMetadata(*metadata)
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		s := lookup(p)
+		if s == nil {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: no metadata\n", p)
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !exif.IsJPEG(data) {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: not a JPEG file\n", p)
+			continue
+		}
+		if exif.HasExif(data) && !*replace {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: already has EXIF data (use --replace)\n", p)
+			continue
+		}
+		if s.Width == 0 || s.Height == 0 {
+			if s.Width, s.Height, err = exif.ImageSize(data); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		app1, err := exif.Build(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would embed %d bytes of EXIF into %s\n", len(app1), p)
+			continue
+		}
+		embedded, err := exif.Embed(data, app1, *replace)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		dst, err := out.write(p, data, embedded)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "embedded EXIF into %s\n", dst)
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	return nil
+}
+
+// loadMetadata reads a summary or an array of summaries. A summary without
+// a path applies to every file; otherwise summaries are matched by base
+// name. The returned function yields a fresh copy per file.
+func loadMetadata(path string) (func(string) *exif.Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []exif.Summary
+	if err := json.Unmarshal(data, &list); err != nil {
+		var one exif.Summary
+		if err := json.Unmarshal(data, &one); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		list = []exif.Summary{one}
+	}
+	byName := map[string]exif.Summary{}
+	var fallback *exif.Summary
+	for i := range list {
+		if list[i].Path == "" {
+			fallback = &list[i]
+			continue
+		}
+		byName[filepath.Base(list[i].Path)] = list[i]
+	}
+	return func(p string) *exif.Summary {
+		s, ok := byName[filepath.Base(p)]
+		if !ok {
+			if fallback == nil {
+				return nil
+			}
+			s = *fallback
+		}
+		s.Path, s.Sources = "", nil
+		return &s
+	}, nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// exportFormats are the formats export writes a whole index in.
+var exportFormats = []string{report.FormatParquet, report.FormatArrow, report.FormatSQL, report.FormatCSV, report.FormatJSON}
+
+// runExport writes every summary of a library index to one file for data
+// tools, such as a Parquet file for pandas or an SQL script for SQLite and
+// DuckDB, with home zones applied as for query.
+func runExport(a *app, args []string) error {
+	fs := a.newFlagSet("export", "shootlog export --index path [--format parquet|arrow|sql|csv|json] [--out file]")
+	path := fs.String("index", "", "index file written by shootlog index")
+	format := fs.String("format", report.FormatParquet, "format of the export: parquet, arrow (IPC stream), sql, csv or json")
+	out := fs.String("out", "", "file to write the export to (default standard output)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--index is required")
+	}
+	if !slices.Contains(exportFormats, *format) {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	ix, err := loadIndex(*path)
+	if err != nil {
+		return err
+	}
+	var summaries []*exif.Summary
+	for _, f := range ix.Files {
+		if s := f.Summary; s != nil {
+			cfg.Privacy.Protect(s)
+			s.Sources = nil
+			summaries = append(summaries, s)
+		}
+	}
+	if *out == "" {
+		return report.Write(a.stdout, *format, summaries)
+	}
+	var buf bytes.Buffer
+	if err := report.Write(&buf, *format, summaries); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(*out, buf.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "exported %d photos to %s\n", len(summaries), *out)
+	return nil
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+)
+
+func runExtract(a *app, args []string) error {
+	fs := a.newFlagSet("shootlog", "shootlog [command] [--input file | --dir dir] [--output json|csv|paths|null|parquet|arrow|sql] [--sort path|datetime|iso] [--group-by keys] [--catalog path] [--infer-dates] [--analyze names] [--filter expr] [--units metric|imperial] [--gps-format fmt] [--gps-precision n] [--exec cmd] [--profile] [--urls file] [--template text | --fields names]")
+	usage := fs.Usage
+	fs.Usage = func() {
+		usage()
+		a.printCommands()
+	}
+	var in inputFlags
+	in.register(fs)
+	var remote remoteFlags
+	remote.register(fs)
+	output := fs.String("output", report.FormatJSON, "output format: json, csv, paths (one per line), null (NUL-terminated), parquet, arrow (IPC stream) or sql")
+	sortKey := fs.String("sort", report.SortPath, "order of the output: "+strings.Join(report.SortKeys, ", "))
+	groupBy := fs.String("group-by", "", "comma-separated keys nesting the output: "+strings.Join(report.GroupKeys, ", "))
+	provenance := fs.Bool("provenance", false, "annotate each field with the directory and tag it was read from")
+	tmplText := fs.String("template", "", "print each photo with this Go template of its summary, such as '{{.Path}} {{formatExposure .ExposureTime}}', instead of --output; see shootlog template-funcs")
+	fieldList := fs.String("fields", "", "comma-separated summary fields, by JSON name, to print besides the path (default: the fields of the config, else all)")
+	inferDates := fs.Bool("infer-dates", false, "report inferred_date from the file or folder name of photos without a capture time")
+	var analyzers analyzeFlags
+	analyzers.register(fs)
+	var units unitsFlag
+	units.register(fs)
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	var gps gpsFlags
+	gps.register(fs)
+	var hooks hookFlags
+	hooks.register(fs)
+	var prof profileFlag
+	prof.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if !slices.Contains(report.SortKeys, *sortKey) {
+		return fmt.Errorf("unknown sort key %q", *sortKey)
+	}
+	var groupKeys []string
+	if *groupBy != "" {
+		for _, k := range strings.Split(*groupBy, ",") {
+			k = strings.TrimSpace(k)
+			if !slices.Contains(report.GroupKeys, k) {
+				return fmt.Errorf("unknown group key %q", k)
+			}
+			groupKeys = append(groupKeys, k)
+		}
+	}
+	var fields []string
+	if *fieldList != "" {
+		known := exif.FieldNames()
+		for _, f := range strings.Split(*fieldList, ",") {
+			f = strings.TrimSpace(f)
+			if !slices.Contains(known, f) {
+				return fmt.Errorf("--fields: unknown field %q", f)
+			}
+			fields = append(fields, f)
+		}
+	}
+	var tmpl *template.Template
+	if *tmplText != "" {
+		if fields != nil {
+			return errors.New("--template excludes --fields")
+		}
+		if groupKeys != nil {
+			return errors.New("--template excludes --group-by")
+		}
+		t, err := tmplfunc.Parse("template", *tmplText)
+		if err != nil {
+			return fmt.Errorf("--template: %w", err)
+		}
+		tmpl = t
+	}
+	if err := gps.check(); err != nil {
+		return err
+	}
+	if err := hooks.parse(); err != nil {
+		return err
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	tree, err := config.LoadTree("")
+	if err != nil {
+		return err
+	}
+	cfg := tree.Global
+	system, err := units.resolve(cfg)
+	if err != nil {
+		return err
+	}
+	var summaries []*exif.Summary
+	fetchFailed := 0
+	if remote.set() {
+		if in.input != "" || in.dir != "" {
+			return errors.New("--urls excludes --input and --dir")
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		summaries, fetchFailed, err = remote.decodeAll(ctx, a)
+		stop()
+	} else {
+		var paths []string
+		if paths, err = in.paths(); err != nil {
+			return err
+		}
+		decode := prof.decoder()
+		if *inferDates {
+			decode = keepUndated(decode)
+		}
+		pipeline, err := analyzers.pipeline(cfg)
+		if err != nil {
+			return err
+		}
+		decode = a.withAnalyzers(decode, pipeline)
+		summaries, err = a.decodeWith(paths, decode)
+		prof.write(a.stderr)
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cat.apply(a, s)
+		if *inferDates {
+			inferDate(s)
+		}
+		cfg.Privacy.Protect(s)
+	}
+	summaries = where.apply(summaries)
+	if err := report.Sort(summaries, *sortKey); err != nil {
+		return err
+	}
+	if err := report.ConvertUnits(summaries, system); err != nil {
+		return err
+	}
+	if err := gps.apply(summaries); err != nil {
+		return err
+	}
+	if !*provenance {
+		for _, s := range summaries {
+			s.Sources = nil
+		}
+	}
+	failed := 0
+	for _, s := range summaries {
+		if err := hooks.run(context.Background(), a, s); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+			failed++
+		}
+	}
+	// The fields to print of each photo: those of --fields, else of the
+	// config of its directory. The writers leave the others out, so the
+	// summaries stay whole for sorting, grouping and hooks.
+	var selected report.Fields
+	switch {
+	case tmpl != nil:
+	case fields != nil:
+		selected = func(*exif.Summary) []string { return fields }
+	case !remote.set():
+		of := make(map[*exif.Summary][]string, len(summaries))
+		for _, s := range summaries {
+			c, err := tree.For(s.Path)
+			if err != nil {
+				return err
+			}
+			of[s] = c.Fields
+		}
+		selected = func(s *exif.Summary) []string { return of[s] }
+	case cfg.Fields != nil:
+		// Fetched photos have no directory to configure them.
+		selected = func(*exif.Summary) []string { return cfg.Fields }
+	}
+	switch {
+	case tmpl != nil:
+		err = writeTemplate(a.stdout, tmpl, summaries)
+	case groupKeys != nil:
+		var groups []*report.Group
+		if groups, err = report.GroupBy(summaries, groupKeys); err != nil {
+			return err
+		}
+		err = report.WriteGroups(a.stdout, *output, groups, groupKeys, selected)
+	default:
+		err = report.WriteFields(a.stdout, *output, summaries, selected)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("hook failed for %d of %d files", failed, len(summaries))
+	}
+	if fetchFailed > 0 {
+		return fmt.Errorf("could not fetch or decode %d URLs", fetchFailed)
+	}
+	return nil
+}
+
+// writeTemplate prints each summary with tmpl, ending every one that does
+// not end the line itself with a newline.
+func writeTemplate(w io.Writer, tmpl *template.Template, summaries []*exif.Summary) error {
+	var b bytes.Buffer
+	for _, s := range summaries {
+		b.Reset()
+		if err := tmpl.Execute(&b, s); err != nil {
+			return fmt.Errorf("%s: %w", s.Path, err)
+		}
+		if !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+			b.WriteByte('\n')
+		}
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAll decodes every path. Files that cannot be decoded are reported
+// on stderr and skipped when processing several files.
+func (a *app) decodeAll(paths []string) ([]*exif.Summary, error) {
+	return a.decodeWith(paths, exif.DecodeFile)
+}
+
+// decodeWith is decodeAll with another decoding function.
+func (a *app) decodeWith(paths []string, decode func(string) (*exif.Summary, error)) ([]*exif.Summary, error) {
+	summaries := make([]*exif.Summary, 0, len(paths))
+	for _, p := range paths {
+		s, err := decode(p)
+		if err != nil {
+			if len(paths) == 1 {
+				return nil, err
+			}
+			fmt.Fprintf(a.stderr, "shootlog: skipping %v\n", err)
+			continue
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
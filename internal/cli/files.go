@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/provenance"
+)
+
+// outputFlags select where commands that modify images write their
+// results. Originals are only rewritten with --force; without it or
+// --out-dir commands report what they would do.
+type outputFlags struct {
+	outDir string
+	force  bool
+	// c2pa is one of the c2pa* modes, applied to files whose Content
+	// Credentials a change would invalidate.
+	c2pa string
+
+	// in selected the files; copies of files found with --dir keep their
+	// path below it.
+	in      *inputFlags
+	custody custody
+	// verify checks that writes leave the image data as it was.
+	verify bool
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet, in *inputFlags) {
+	f.in = in
+	fs.StringVar(&f.outDir, "out-dir", "", "write modified copies to this directory, keeping their path below --dir")
+	fs.BoolVar(&f.force, "force", false, "rewrite the original files")
+	fs.BoolVar(&f.verify, "verify", false, "refuse to write a file unless its image data is byte for byte as before")
+	f.c2pa = c2paRefuse
+	fs.Func("c2pa", "for files with C2PA Content Credentials the change would invalidate: refuse (default) to write them, strip the credentials, or preserve them and note the change in XMP", func(v string) error {
+		switch v {
+		case c2paRefuse, c2paStrip, c2paPreserve:
+			f.c2pa = v
+			return nil
+		}
+		return fmt.Errorf("unknown mode %q (want refuse, strip or preserve)", v)
+	})
+}
+
+// Modes of --c2pa.
+const (
+	c2paRefuse   = "refuse"
+	c2paStrip    = "strip"
+	c2paPreserve = "preserve"
+)
+
+// dryRun reports whether nothing should be written.
+func (f *outputFlags) dryRun() bool {
+	return f.outDir == "" && !f.force
+}
+
+// write stores data, the modified contents of path, and returns where
+// they went. original is the file as read, which is checked for Content
+// Credentials the change would invalidate and, with --verify, compared
+// with data for changes to the image itself. Writes are subject to the
+// archive settings of the config file.
+func (f *outputFlags) write(path string, original, data []byte) (string, error) {
+	data, err := f.credentials(path, original, data)
+	if err != nil {
+		return "", err
+	}
+	if f.verify {
+		if err := exif.VerifyImageData(original, data); err != nil {
+			return "", fmt.Errorf("%s: not written: %w", path, err)
+		}
+	}
+	dst := path
+	if f.outDir != "" {
+		rel := filepath.Base(path)
+		if f.in != nil && f.in.dir != "" {
+			if r, err := filepath.Rel(f.in.dir, path); err == nil {
+				rel = r
+			}
+		}
+		dst = filepath.Join(f.outDir, rel)
+	}
+	action := provenance.Copy
+	if filepath.Clean(dst) == filepath.Clean(path) {
+		action = provenance.Rewrite
+		if err := f.custody.check(path); err != nil {
+			return "", err
+		}
+	}
+	if f.outDir != "" {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return "", err
+		}
+	}
+	if action == provenance.Rewrite {
+		if err := f.custody.keep(path, original); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFileAtomic(dst, data); err != nil {
+		return "", err
+	}
+	return dst, f.custody.record(action, path, dst, original, data)
+}
+
+// credentials applies --c2pa to data when original carries a C2PA
+// manifest store whose asset hash data no longer matches. Manifests
+// cannot be re-signed here, so an invalidated store is either kept as it
+// is or removed.
+func (f *outputFlags) credentials(path string, original, data []byte) ([]byte, error) {
+	c, err := exif.ReadC2PA(original)
+	if err != nil || c == nil || bytes.Equal(original, data) {
+		return data, nil
+	}
+	m := c.Active()
+	if m != nil && m.CheckHash(data) == exif.C2PAHashMatch {
+		return data, nil
+	}
+	switch f.c2pa {
+	case c2paStrip:
+		return exif.StripC2PA(data), nil
+	case c2paPreserve:
+		if m == nil {
+			return data, nil
+		}
+		annotated, err := exif.AnnotateC2PA(data, m, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return annotated, nil
+	}
+	return nil, fmt.Errorf("%s carries C2PA Content Credentials that this change would invalidate; pass --c2pa strip to remove them or --c2pa preserve to keep them and note the change", path)
+}
+
+// dryRunNote is printed after listing the changes a dry run skipped.
+func (a *app) dryRunNote() {
+	fmt.Fprintln(a.stderr, "shootlog: dry run; pass --force to rewrite originals or --out-dir to write copies")
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so an interrupted write never leaves a truncated image.
+// The permissions of an existing file are preserved.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
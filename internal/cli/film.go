@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/film"
+)
+
+func runFilm(a *app, args []string) error {
+	fs := a.newFlagSet("film", "shootlog film --log roll.csv|roll.yaml [--input file | --dir dir] [--allow-mismatch] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	logPath := fs.String("log", "", "roll log in CSV or YAML")
+	allowMismatch := fs.Bool("allow-mismatch", false, "apply the log even when frame and scan counts differ")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *logPath == "" {
+		return errors.New("--log is required")
+	}
+	roll, err := film.Load(*logPath)
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	// Scans are matched to frames in file name order.
+	if len(paths) != len(roll.Frames) {
+		if !*allowMismatch {
+			return fmt.Errorf("log has %d frames but found %d scans (use --allow-mismatch to apply anyway)", len(roll.Frames), len(paths))
+		}
+		fmt.Fprintf(a.stderr, "shootlog: log has %d frames but found %d scans; matching in order\n", len(roll.Frames), len(paths))
+	}
+
+	for i, p := range paths {
+		if i >= len(roll.Frames) {
+			break
+		}
+		f := &roll.Frames[i]
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !exif.IsJPEG(data) {
+			return fmt.Errorf("%s: not a JPEG file", p)
+		}
+		s := f.Summary()
+		if s.Width, s.Height, err = exif.ImageSize(data); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would tag %s as frame %s\n", p, f.Number)
+			continue
+		}
+		app1, err := exif.Build(s)
+		if err != nil {
+			return fmt.Errorf("%s: frame %s: %w", p, f.Number, err)
+		}
+		// Scanner EXIF describes the scanner, not the exposure, so it is
+		// replaced.
+		tagged, err := exif.Embed(data, app1, true)
+		if err == nil {
+			tagged, err = exif.SetXMP(tagged, roll.XMP(f))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		dst, err := out.write(p, data, tagged)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "tagged %s as frame %s\n", dst, f.Number)
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+)
+
+// filterFlag selects the photos a command processes.
+type filterFlag struct {
+	src  string
+	expr *filter.Expr
+}
+
+func (f *filterFlag) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.src, "filter", "", `only process photos matching an expression, e.g. "moon_phase < 0.1 && iso >= 1600"`)
+}
+
+func (f *filterFlag) parse() error {
+	if f.src == "" {
+		return nil
+	}
+	var err error
+	f.expr, err = filter.Parse(f.src)
+	return err
+}
+
+func (f *filterFlag) match(s *exif.Summary) bool {
+	return f.expr == nil || f.expr.Match(s)
+}
+
+// apply returns the summaries that match, in order.
+func (f *filterFlag) apply(summaries []*exif.Summary) []*exif.Summary {
+	if f.expr == nil {
+		return summaries
+	}
+	kept := summaries[:0]
+	for _, s := range summaries {
+		if f.expr.Match(s) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+func runFlight(a *app, args []string) error {
+	fs := a.newFlagSet("flight", "shootlog flight [--input file | --dir dir] [--output text|json|csv] [--lang en|ja] [--filter expr] [--units metric|imperial]")
+	var in inputFlags
+	in.register(fs)
+	output := fs.String("output", "text", "output format: text, json or csv")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var units unitsFlag
+	units.register(fs)
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" && *output != "csv" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	system, err := units.resolve(cfg)
+	if err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cfg.Privacy.Protect(s)
+	}
+	flight := report.NewFlight(where.apply(summaries))
+	if flight.Shots == 0 {
+		fmt.Fprintln(a.stderr, "shootlog: no photos carry drone flight data")
+	}
+	if err := flight.ConvertUnits(system); err != nil {
+		return err
+	}
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(flight)
+	case "csv":
+		return flight.WriteCSV(a.stdout)
+	}
+	return flight.WriteText(a.stdout, loc)
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runGear lists the camera bodies and lenses of a library index by serial
+// number, for insurance documentation, and when each body's firmware
+// changed, to match metadata quirks with firmware updates.
+func runGear(a *app, args []string) error {
+	fs := a.newFlagSet("gear", "shootlog gear --index path [--output text|json|csv] [--lang en|ja]")
+	path := fs.String("index", "", "index file written by shootlog index")
+	output := fs.String("output", "text", "output format: text, json or csv")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--index is required")
+	}
+	if *output != "text" && *output != "json" && *output != "csv" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	ix, err := loadIndex(*path)
+	if err != nil {
+		return err
+	}
+	gear := report.NewGear(ix.Summaries())
+	if len(gear.Items) == 0 {
+		fmt.Fprintln(a.stderr, "shootlog: no photos carry a body or lens serial number")
+	}
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(gear)
+	case "csv":
+		return gear.WriteCSV(a.stdout)
+	}
+	return gear.WriteText(a.stdout, loc)
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/geo"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// gpsFlags selects how coordinates are printed.
+type gpsFlags struct {
+	format    string
+	precision int
+}
+
+func (f *gpsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "gps-format", geo.Decimal, "coordinate format: "+strings.Join(geo.Formats, ", "))
+	fs.IntVar(&f.precision, "gps-precision", geo.Full, "decimal places of degrees to keep, truncating coordinates for publication (-1 keeps them as recorded)")
+}
+
+func (f *gpsFlags) check() error {
+	if !slices.Contains(geo.Formats, f.format) {
+		return fmt.Errorf("unknown GPS format %q", f.format)
+	}
+	if f.precision < geo.Full {
+		return fmt.Errorf("invalid --gps-precision %d", f.precision)
+	}
+	return nil
+}
+
+func (f *gpsFlags) apply(summaries []*exif.Summary) error {
+	return report.FormatGPS(summaries, f.format, f.precision)
+}
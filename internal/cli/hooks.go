@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/hook"
+)
+
+// hookFlags select the command run for every processed file.
+type hookFlags struct {
+	exec string
+	cmd  *hook.Command
+}
+
+func (f *hookFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.exec, "exec", "", "command to run per file, e.g. 'notify {path} {iso}'; placeholders name summary fields")
+}
+
+// parse parses --exec once flags are parsed.
+func (f *hookFlags) parse() error {
+	if f.exec == "" {
+		return nil
+	}
+	var err error
+	f.cmd, err = hook.Parse(f.exec)
+	return err
+}
+
+// run runs the hook for s, if one is set. Hook output goes to stderr so
+// that shootlog's own output stays machine-readable.
+func (f *hookFlags) run(ctx context.Context, a *app, s *exif.Summary) error {
+	if f.cmd == nil {
+		return nil
+	}
+	if err := f.cmd.Run(ctx, s, a.stderr, a.stderr); err != nil {
+		return fmt.Errorf("%s: %w", s.Path, err)
+	}
+	return nil
+}
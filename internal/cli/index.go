@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+// loadIndex reads an index that shootlog index has written to; unlike
+// index.Load, a missing file is an error.
+func loadIndex(path string) (*index.Index, error) {
+	ix, err := index.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(ix.Runs) == 0 {
+		return nil, fmt.Errorf("%s: no index; create it with shootlog index", path)
+	}
+	return ix, nil
+}
+
+func runIndex(a *app, args []string) error {
+	fs := a.newFlagSet("index", "shootlog index --dir dir --index path [--workers n] [--analyze names] [--output text|json]")
+	dir := fs.String("dir", "", "library directory to index recursively")
+	path := fs.String("index", "", "index file to update, created on the first run")
+	workers := fs.Int("workers", index.DefaultWorkers, "number of files to read and decode at once")
+	output := fs.String("output", "text", "format of the run statistics: text or json")
+	var analyzers analyzeFlags
+	analyzers.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" || *path == "" {
+		return errors.New("--dir and --index are required")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if *workers < 1 {
+		return errors.New("--workers must be at least 1")
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	pipeline, err := analyzers.pipeline(cfg)
+	if err != nil {
+		return err
+	}
+	ix, err := index.Load(*path)
+	if err != nil {
+		return err
+	}
+	paths, err := scanDir(*dir)
+	if err != nil {
+		return err
+	}
+	churn, errs := ix.Update(paths, exif.DecodeBytes, *workers)
+	for _, err := range errs {
+		fmt.Fprintf(a.stderr, "shootlog: skipping %v\n", err)
+	}
+	for _, err := range analyzeIndex(ix, pipeline) {
+		fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+	}
+	data, err := ix.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := writeCatalog(*path, data, ix); err != nil {
+		return err
+	}
+	run := ix.Runs[len(ix.Runs)-1]
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(run)
+	}
+	fmt.Fprintf(a.stdout, "%d files in %s: %d added, %d modified, %d moved, %d removed, %d touched, %d unchanged\n",
+		len(ix.Files), run.Duration.Round(time.Millisecond), churn.Added, churn.Modified, churn.Moved, churn.Removed, churn.Touched, churn.Unchanged)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// imageExts lists the extensions picked up when scanning a directory.
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true, ".heic": true, ".heif": true, ".avif": true,
+	".dng": true, ".nef": true, ".cr2": true, ".arw": true, ".orf": true, ".rw2": true, ".raf": true,
+}
+
+// inputFlags are the flags selecting which files a command processes.
+type inputFlags struct {
+	input string
+	dir   string
+}
+
+func (f *inputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.input, "input", "", "image file to read")
+	fs.StringVar(&f.dir, "dir", "", "directory to scan recursively for images")
+}
+
+// paths resolves the selected files in lexical order.
+func (f *inputFlags) paths() ([]string, error) {
+	switch {
+	case f.input != "" && f.dir != "":
+		return nil, errors.New("--input and --dir are mutually exclusive")
+	case f.input != "":
+		return []string{f.input}, nil
+	case f.dir != "":
+		return scanDir(f.dir)
+	}
+	return nil, errors.New("one of --input or --dir is required")
+}
+
+// scanDir returns the image files below root, skipping hidden directories,
+// sorted by path. Walk order differs when names contain bytes that sort
+// before '/', such as "a-b.jpg" and "a/c.jpg".
+func scanDir(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if imageExts[strings.ToLower(filepath.Ext(path))] {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// ifdNames are the directory names accepted by --ifd.
+var ifdNames = map[string]exif.IFDKind{
+	"ifd0": exif.IFD0, "ifd1": exif.IFD1, "exif": exif.ExifIFD, "gps": exif.GPSIFD, "interop": exif.InteropIFD,
+}
+
+// hexElide is the size above which the thumbnail and data no entry
+// refers to are cut short in the annotated hexdump.
+const hexElide = 4096
+
+func runInspect(a *app, args []string) error {
+	fs := a.newFlagSet("inspect", "shootlog inspect [--input file | --dir dir] [--tag 0x9286] [--ifd ifd0|ifd1|exif|gps|interop] [--hex]")
+	var in inputFlags
+	in.register(fs)
+	tagFlag := fs.String("tag", "", "print the entry of this tag, by ID such as 0x9286 or 37510 or by name such as UserComment")
+	ifdFlag := fs.String("ifd", "", "only look in this directory: ifd0, ifd1, exif, gps or interop")
+	hex := fs.Bool("hex", false, "print raw bytes: the tag's value with --tag, else an annotated hexdump of the whole EXIF block")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	var tag uint16
+	// A tag given by name also selects its directory; IDs are reused
+	// across directories.
+	var named *exif.TagInfo
+	if *tagFlag != "" {
+		v, err := strconv.ParseUint(*tagFlag, 0, 16)
+		if err != nil {
+			if t, ok := exif.TagByName(*tagFlag); ok {
+				v, err, named = uint64(t.ID), nil, &t
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("invalid --tag %q: want a tag ID such as 0x9286 or a name listed by shootlog tags", *tagFlag)
+		}
+		tag = uint16(v)
+	}
+	ifd := exif.IFDKind(-1)
+	if *ifdFlag != "" {
+		k, ok := ifdNames[strings.ToLower(*ifdFlag)]
+		if !ok {
+			return fmt.Errorf("unknown --ifd %q (want ifd0, ifd1, exif, gps or interop)", *ifdFlag)
+		}
+		ifd = k
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	for i, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		b, err := exif.Inspect(data)
+		if errors.Is(err, exif.ErrNoExif) {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: no EXIF data\n", p)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if len(paths) > 1 {
+			if i > 0 {
+				fmt.Fprintln(a.stdout)
+			}
+			fmt.Fprintf(a.stdout, "== %s\n", p)
+		}
+		var fields []exif.Field
+		for _, f := range b.Fields {
+			if (*tagFlag == "" || f.Tag == tag) && (ifd < 0 || f.IFD == ifd) && (named == nil || inDirectory(f.IFD, named.IFD)) {
+				fields = append(fields, f)
+			}
+		}
+		switch {
+		case *tagFlag != "":
+			if len(fields) == 0 {
+				fmt.Fprintf(a.stderr, "shootlog: %s: no tag 0x%04X\n", p, tag)
+				missing++
+			}
+			for _, f := range fields {
+				printField(a.stdout, b, f, *hex)
+			}
+		case *hex:
+			printBlock(a.stdout, b)
+		default:
+			fmt.Fprintf(a.stdout, "%-8s %-6s  %-28s %-10s %6s %8s %8s\n", "IFD", "Tag", "Name", "Type", "Count", "Entry", "Value")
+			for _, f := range fields {
+				fmt.Fprintf(a.stdout, "%-8s 0x%04X  %-28s %-10s %6d %8d %8d\n",
+					f.IFD, f.Tag, f.Name, f.Type, f.Count, b.Offset+f.At, b.Offset+f.ValueAt)
+			}
+		}
+	}
+	if missing > 0 {
+		return fmt.Errorf("tag 0x%04X not found in %d of %d files", tag, missing, len(paths))
+	}
+	return nil
+}
+
+// inDirectory reports whether an entry of directory kind can be a tag of
+// directory of; IFD0 tags are also valid in IFD1.
+func inDirectory(kind, of exif.IFDKind) bool {
+	return kind == of || (of == exif.IFD0 && kind == exif.IFD1)
+}
+
+// printField describes one entry: its type, count and where the entry and
+// its value sit, as file offsets and as the offsets stored in the TIFF
+// structure.
+func printField(w io.Writer, b *exif.Block, f exif.Field, hex bool) {
+	name := f.Name
+	if name == "" {
+		name = "(unknown)"
+	}
+	fmt.Fprintf(w, "%s 0x%04X %s\n", f.IFD, f.Tag, name)
+	fmt.Fprintf(w, "  type   %s (%d)\n", f.Type, uint16(f.Type))
+	fmt.Fprintf(w, "  count  %d\n", f.Count)
+	fmt.Fprintf(w, "  entry  at %d (TIFF offset %d)\n", b.Offset+f.At, f.At-b.TIFF)
+	where := "inline in the entry"
+	if len(f.Value) > 4 {
+		where = fmt.Sprintf("TIFF offset %d", f.ValueAt-b.TIFF)
+	}
+	fmt.Fprintf(w, "  value  at %d (%s), %d bytes\n", b.Offset+f.ValueAt, where, len(f.Value))
+	if hex {
+		hexLines(w, f.Value, b.Offset+f.ValueAt, "  ")
+		return
+	}
+	fmt.Fprintf(w, "  %s\n", fieldValue(f.Entry))
+}
+
+// fieldValue renders the values of an entry as the registry formats its
+// tag, followed by the raw values when they differ.
+func fieldValue(e exif.Entry) string {
+	raw := exif.FormatEntry(e)
+	if e.Len() > 16 {
+		raw += "; --hex shows all bytes"
+	}
+	t, ok := exif.LookupTag(e.IFD, e.Tag)
+	if !ok {
+		return raw
+	}
+	if s := t.Format(e); s != raw {
+		return s + " (" + raw + ")"
+	}
+	return raw
+}
+
+// printBlock writes the annotated hexdump of b: each labelled structure in
+// turn, with the bytes between them marked as unreferenced.
+func printBlock(w io.Writer, b *exif.Block) {
+	fmt.Fprintf(w, "; lines start with the file offset in hex; the TIFF header is at file offset %d\n", b.Offset+b.TIFF)
+	pos := 0
+	gap := func(end int) {
+		if end > pos {
+			dump(w, b.Data[pos:end], b.Offset+pos, "unreferenced", true)
+		}
+	}
+	for _, s := range b.Spans {
+		gap(s.Offset)
+		label := s.Label
+		if s.Offset < pos {
+			label += " (overlaps the previous data)"
+		}
+		dump(w, b.Data[s.Offset:s.Offset+s.Length], b.Offset+s.Offset, label, strings.HasSuffix(s.Label, "thumbnail JPEG"))
+		pos = max(pos, s.Offset+s.Length)
+	}
+	gap(len(b.Data))
+}
+
+// dump writes data found at file offset off under label, cut short past
+// hexElide bytes when elide is set.
+func dump(w io.Writer, data []byte, off int, label string, elide bool) {
+	fmt.Fprintf(w, "; %s\n", label)
+	if elide && len(data) > hexElide {
+		hexLines(w, data[:256], off, "")
+		fmt.Fprintf(w, "  ... %d more bytes\n", len(data)-256)
+		return
+	}
+	hexLines(w, data, off, "")
+}
+
+// hexLines writes data 16 bytes to a line, each prefixed with its file
+// offset and followed by the printable ASCII characters.
+func hexLines(w io.Writer, data []byte, off int, indent string) {
+	for i := 0; i < len(data); i += 16 {
+		line := data[i:min(i+16, len(data))]
+		var hex, text strings.Builder
+		for j, c := range line {
+			if j > 0 {
+				hex.WriteByte(' ')
+			}
+			fmt.Fprintf(&hex, "%02x", c)
+			if c >= 0x20 && c < 0x7F {
+				text.WriteByte(c)
+			} else {
+				text.WriteByte('.')
+			}
+		}
+		fmt.Fprintf(w, "%s%08x  %-47s  %s\n", indent, off+i, hex.String(), text.String())
+	}
+}
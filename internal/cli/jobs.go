@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runJobs reports the photos, dates, gear and deliverables of each client
+// job, assigned by IPTC Job Identifier or the config file's jobs.folder.
+func runJobs(a *app, args []string) error {
+	fs := a.newFlagSet("jobs", "shootlog jobs --index path | --dir dir [--filter expr] [--output text|json|csv] [--lang en|ja]")
+	indexPath := fs.String("index", "", "index file written by shootlog index")
+	dir := fs.String("dir", "", "directory to scan recursively for images")
+	output := fs.String("output", "text", "output format: text, json or csv")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if (*indexPath == "") == (*dir == "") {
+		return errors.New("one of --index or --dir is required")
+	}
+	if *output != "text" && *output != "json" && *output != "csv" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	m, err := cfg.Jobs.Matcher()
+	if err != nil {
+		return err
+	}
+	var summaries []*exif.Summary
+	if *indexPath != "" {
+		ix, err := loadIndex(*indexPath)
+		if err != nil {
+			return err
+		}
+		summaries = ix.Summaries()
+	} else {
+		paths, err := scanDir(*dir)
+		if err != nil {
+			return err
+		}
+		if summaries, err = a.decodeAll(paths); err != nil {
+			return err
+		}
+	}
+	jobs := report.NewJobs(where.apply(summaries), m)
+	if len(jobs.Jobs) == 0 {
+		fmt.Fprintln(a.stderr, "shootlog: no photos carry an IPTC job identifier or match jobs.folder in the config file")
+	}
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jobs)
+	case "csv":
+		return jobs.WriteCSV(a.stdout)
+	}
+	return jobs.WriteText(a.stdout, loc)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runKeepers reports which lenses, focal lengths, shutter speeds and focus
+// modes produce the photos the user rated highly.
+func runKeepers(a *app, args []string) error {
+	fs := a.newFlagSet("keepers", "shootlog keepers [--input file | --dir dir] [--min-rating 3] [--filter expr] [--output text|json|csv] [--lang en|ja]")
+	var in inputFlags
+	in.register(fs)
+	minRating := fs.Int("min-rating", 3, "lowest star rating, 1-5, that counts as a keeper")
+	output := fs.String("output", "text", "output format: text, json or csv")
+	lang := fs.String("lang", "", "language of the text output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *minRating < 1 || *minRating > 5 {
+		return fmt.Errorf("--min-rating must be 1-5, got %d", *minRating)
+	}
+	if *output != "text" && *output != "json" && *output != "csv" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	k := report.NewKeepers(where.apply(summaries), *minRating)
+	if k.Keepers == 0 {
+		fmt.Fprintf(a.stderr, "shootlog: no photos are rated %d stars or more\n", *minRating)
+	}
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(k)
+	case "csv":
+		return k.WriteCSV(a.stdout)
+	}
+	return k.WriteText(a.stdout, loc)
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+func runManifest(a *app, args []string) error {
+	fs := a.newFlagSet("manifest", "shootlog manifest --service flickr|instagram|smugmug [--input file | --dir dir] [--output csv|json] [--catalog path] [--filter expr]")
+	var in inputFlags
+	in.register(fs)
+	service := fs.String("service", "", "service to write the upload manifest for: "+strings.Join(report.Services, ", "))
+	output := fs.String("output", report.FormatCSV, "output format: csv or json")
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if !slices.Contains(report.Services, *service) {
+		return fmt.Errorf("--service must be one of %s", strings.Join(report.Services, ", "))
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	// Manifests are for publishing, so home zones are always protected.
+	for _, s := range summaries {
+		cat.apply(a, s)
+		cfg.Privacy.Protect(s)
+	}
+	return report.WriteManifest(a.stdout, *service, *output, where.apply(summaries))
+}
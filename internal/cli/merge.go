@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+// rebaseFlags rewrite the paths of another machine's index to this one's.
+type rebaseFlags struct {
+	maps [][2]string
+}
+
+func (f *rebaseFlags) register(fs *flag.FlagSet) {
+	fs.Func("map", "read the other index's paths under directory from as under to, given as from=to; repeatable", func(v string) error {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("want from=to, got %q", v)
+		}
+		f.maps = append(f.maps, [2]string{from, to})
+		return nil
+	})
+}
+
+// load reads the index at path with the mappings applied.
+func (f *rebaseFlags) load(path string) (*index.Index, error) {
+	ix, err := loadIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range f.maps {
+		ix.Rebase(m[0], m[1])
+	}
+	return ix, nil
+}
+
+// loadPair reads the two indexes of merge and sync.
+func loadPair(path, other string, rebase *rebaseFlags) (*index.Index, *index.Index, error) {
+	if path == "" || other == "" {
+		return nil, nil, errors.New("--index and --other are required")
+	}
+	ix, err := loadIndex(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	theirs, err := rebase.load(other)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ix, theirs, nil
+}
+
+// runMerge combines the indexes of one library kept on two machines.
+func runMerge(a *app, args []string) error {
+	fs := a.newFlagSet("merge", "shootlog merge --index path --other path [--map from=to]... [--prefer ours|theirs|newer] [--out path]")
+	path := fs.String("index", "", "index to merge into")
+	other := fs.String("other", "", "index to merge from, e.g. another machine's")
+	prefer := fs.String("prefer", index.PreferNewer, "file kept where a path holds different content in each index: "+strings.Join(index.Prefers, ", "))
+	out := fs.String("out", "", "file to write the merged index to (default --index)")
+	var rebase rebaseFlags
+	rebase.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	ix, theirs, err := loadPair(*path, *other, &rebase)
+	if err != nil {
+		return err
+	}
+	before := len(ix.Files)
+	conflicts, err := ix.Merge(theirs, *prefer)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(a.stderr, "shootlog: %s differs in the two indexes; kept %s\n", c.Path, c.Kept)
+	}
+	data, err := ix.Marshal()
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = *path
+	}
+	// An index at another --out is replaced whatever it holds.
+	var from *index.Index
+	if *out == *path {
+		from = ix
+	}
+	if err := writeCatalog(*out, data, from); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "%d files: %d added, %d conflicts\n", len(ix.Files), len(ix.Files)-before, len(conflicts))
+	return nil
+}
+
+// runSync lists what each of two indexes lacks, for copying files and
+// backup marks between machines.
+func runSync(a *app, args []string) error {
+	fs := a.newFlagSet("sync", "shootlog sync --index path --other path [--map from=to]... [--output text|json]")
+	path := fs.String("index", "", "this machine's index")
+	other := fs.String("other", "", "the index to compare with")
+	output := fs.String("output", "text", "output format: text or json")
+	var rebase rebaseFlags
+	rebase.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	ix, theirs, err := loadPair(*path, *other, &rebase)
+	if err != nil {
+		return err
+	}
+	d := ix.Diff(theirs)
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	files := func(title string, list []index.DeltaFile) {
+		fmt.Fprintf(a.stdout, "%s (%d):\n", title, len(list))
+		for _, f := range list {
+			fmt.Fprintf(a.stdout, "  %s\n", f.Path)
+		}
+	}
+	files("missing from "+*other, d.OnlyOurs)
+	files("missing from "+*path, d.OnlyTheirs)
+	fmt.Fprintf(a.stdout, "backup marks differ (%d):\n", len(d.Backups))
+	for _, b := range d.Backups {
+		fmt.Fprintf(a.stdout, "  %s: only here %s; only there %s\n", b.Path, orNone(b.OnlyOurs), orNone(b.OnlyTheirs))
+	}
+	fmt.Fprintf(a.stdout, "conflicts (%d):\n", len(d.Conflicts))
+	for _, c := range d.Conflicts {
+		fmt.Fprintf(a.stdout, "  %s\n", c.Path)
+	}
+	return nil
+}
+
+// orNone joins sets, or says none.
+func orNone(sets []string) string {
+	if len(sets) == 0 {
+		return "none"
+	}
+	return strings.Join(sets, ", ")
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/privacy"
+)
+
+// piiResult is the detect-pii result for a single file.
+type piiResult struct {
+	Path     string            `json:"path"`
+	Findings []privacy.Finding `json:"findings"`
+}
+
+// runDetectPII lists the personally identifying metadata of each file for
+// compliance reviews. It fails when a file holds findings of at least the
+// --fail-on severity.
+func runDetectPII(a *app, args []string) error {
+	fs := a.newFlagSet("detect-pii", "shootlog detect-pii [--input file | --dir dir] [--min-severity low|medium|high] [--fail-on low|medium|high|none] [--values] [--output text|json]")
+	var in inputFlags
+	in.register(fs)
+	minFlag := fs.String("min-severity", "low", "only list findings of this severity or higher: low, medium or high")
+	failFlag := fs.String("fail-on", "high", "fail when a file has findings of this severity or higher, or none to never fail")
+	values := fs.Bool("values", false, "include the personal data found, not only where it is")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	minSev, err := privacy.ParseSeverity(*minFlag)
+	if err != nil {
+		return fmt.Errorf("--min-severity: %w", err)
+	}
+	failSev := privacy.SeverityHigh + 1
+	if *failFlag != "none" {
+		if failSev, err = privacy.ParseSeverity(*failFlag); err != nil {
+			return fmt.Errorf("--fail-on: %w", err)
+		}
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	results := []piiResult{}
+	failed := 0
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		findings, err := privacy.Detect(data)
+		if errors.Is(err, exif.ErrNoExif) {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: %v\n", p, err)
+			continue
+		}
+		r := piiResult{Path: p, Findings: []privacy.Finding{}}
+		fail := false
+		for _, f := range findings {
+			if f.Severity < minSev {
+				continue
+			}
+			fail = fail || f.Severity >= failSev
+			if !*values {
+				f.Value = ""
+			}
+			r.Findings = append(r.Findings, f)
+		}
+		if fail {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if len(r.Findings) == 0 {
+				fmt.Fprintf(a.stdout, "%s: ok\n", r.Path)
+				continue
+			}
+			fmt.Fprintf(a.stdout, "%s:\n", r.Path)
+			for _, f := range r.Findings {
+				if f.Value != "" {
+					fmt.Fprintf(a.stdout, "  %s (%s)\n", f, f.Value)
+				} else {
+					fmt.Fprintf(a.stdout, "  %s\n", f)
+				}
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files hold personal data rated %s or above", failed, len(results), failSev)
+	}
+	return nil
+}
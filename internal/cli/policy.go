@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/policy"
+)
+
+const policyUsage = "shootlog policy check|apply [--policy file | --config file] [--input file | --dir dir] [--output text|json] [--out-dir dir | --force]"
+
+func runPolicy(a *app, args []string) error {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "apply") {
+		fmt.Fprintf(a.stderr, "usage: %s\n", policyUsage)
+		return errUsage
+	}
+	action := args[0]
+	fs := a.newFlagSet("policy "+action, policyUsage)
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	if action == "apply" {
+		out.register(fs, &in)
+	}
+	policyPath := fs.String("policy", "", "policy file (default: the policy section of the config file)")
+	configPath := fs.String("config", "", "config file (default $"+config.EnvPath+" or shootlog/config.yaml in the user config directory)")
+	output := fs.String("output", "text", "output format for check: text or json")
+	if err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	// Each file follows --policy, or else the policy of its directory
+	// config or the config file.
+	policies := make([]*policy.Policy, len(paths))
+	ruled := false
+	if *policyPath != "" {
+		p, err := policy.Load(*policyPath)
+		if err != nil {
+			return err
+		}
+		for i := range policies {
+			policies[i] = p
+		}
+		ruled = len(p.Rules) > 0
+	} else {
+		tree, err := config.LoadTree(*configPath)
+		if err != nil {
+			return err
+		}
+		for i, path := range paths {
+			c, err := tree.For(path)
+			if err != nil {
+				return err
+			}
+			policies[i] = &c.Policy
+			ruled = ruled || len(c.Policy.Rules) > 0
+		}
+	}
+	if !ruled {
+		return errors.New("no policy rules: pass --policy or add a policy section to the config file or a " + config.LocalName)
+	}
+	if action == "apply" {
+		return applyPolicy(a, policies, paths, &out)
+	}
+
+	results := make([]policy.Result, 0, len(paths))
+	failed := 0
+	for i, path := range paths {
+		s, err := decodeForPolicy(path)
+		var r policy.Result
+		if err != nil {
+			r = policy.Result{Path: path, Violations: []policy.Violation{{Message: err.Error()}}}
+		} else {
+			r = policies[i].Check(s)
+		}
+		if !r.OK() {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.OK() {
+				continue
+			}
+			fmt.Fprintf(a.stdout, "%s:\n", r.Path)
+			printViolations(a, "", r.Violations)
+		}
+		fmt.Fprintf(a.stdout, "%d of %d files satisfy the policy\n", len(results)-failed, len(results))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files violate the policy", failed, len(results))
+	}
+	return nil
+}
+
+// applyPolicy remediates each file with its policy and reports the
+// violations its rewritten version still has.
+func applyPolicy(a *app, policies []*policy.Policy, paths []string, out *outputFlags) error {
+	failed := 0
+	for i, path := range paths {
+		p := policies[i]
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s, err := summarizeForPolicy(path, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		r := p.Check(s)
+		if r.OK() {
+			continue
+		}
+		fixed, changes, err := p.Remediate(data, s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		remaining := r.Violations
+		if len(changes) > 0 {
+			after, err := summarizeForPolicy(path, fixed)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			remaining = p.Check(after).Violations
+		}
+		if len(remaining) > 0 {
+			failed++
+		}
+
+		switch {
+		case len(changes) == 0:
+			fmt.Fprintf(a.stdout, "cannot fix %s:\n", path)
+			printViolations(a, "", remaining)
+			continue
+		case out.dryRun():
+			fmt.Fprintf(a.stdout, "would fix %s: %s\n", path, strings.Join(changes, " "))
+		default:
+			dst, err := out.write(path, data, fixed)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stdout, "fixed %s: %s\n", dst, strings.Join(changes, " "))
+		}
+		printViolations(a, "still ", remaining)
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files still violate the policy", failed, len(paths))
+	}
+	return nil
+}
+
+func printViolations(a *app, prefix string, vs []policy.Violation) {
+	for _, v := range vs {
+		if v.Rule == "" {
+			fmt.Fprintf(a.stdout, "  %s\n", v.Message)
+			continue
+		}
+		fmt.Fprintf(a.stdout, "  %s%s: %s\n", prefix, v.Rule, v.Message)
+	}
+}
+
+func decodeForPolicy(path string) (*exif.Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeForPolicy(path, data)
+}
+
+// summarizeForPolicy summarizes data, treating a file without metadata as
+// an empty summary: an offender, not an error.
+func summarizeForPolicy(path string, data []byte) (*exif.Summary, error) {
+	s, err := exif.DecodeBytes(data)
+	if errors.Is(err, exif.ErrNoExif) {
+		s, err = &exif.Summary{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Path = path
+	return s, nil
+}
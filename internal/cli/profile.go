@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// profileFlag is --profile, which times the parsing stages of each file
+// and prints them with their totals on stderr.
+type profileFlag struct {
+	enabled bool
+	paths   []string
+	stages  []exif.Profile
+}
+
+func (f *profileFlag) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.enabled, "profile", false, "print the parse time of each stage (segment scan, IFD, maker note, XMP) per file and in total on stderr")
+}
+
+// decoder returns the function decoding each file for app.decodeWith:
+// exif.DecodeFile, timing each file when the flag is set.
+func (f *profileFlag) decoder() func(string) (*exif.Summary, error) {
+	if !f.enabled {
+		return exif.DecodeFile
+	}
+	return f.decode
+}
+
+// decode is exif.DecodeFile through a Decoder whose profile is kept. Files
+// that fail are profiled too, since exotic files are the ones that matter.
+func (f *profileFlag) decode(path string) (*exif.Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := exif.NewDecoder(data)
+	s, err := d.Summary()
+	f.paths = append(f.paths, path)
+	f.stages = append(f.stages, d.Profile())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// write prints a table of the profiles, then their sum and mean.
+func (f *profileFlag) write(w io.Writer) {
+	if !f.enabled {
+		return
+	}
+	row := func(p exif.Profile, label string) {
+		fmt.Fprintf(w, "%10s %10s %10s %10s %10s  %s\n", duration(p.Scan), duration(p.IFD), duration(p.MakerNote), duration(p.XMP), duration(p.Total), label)
+	}
+	fmt.Fprintf(w, "%10s %10s %10s %10s %10s  %s\n", "Scan", "IFD", "Maker note", "XMP", "Total", "Path")
+	var sum exif.Profile
+	for i, p := range f.stages {
+		row(p, f.paths[i])
+		sum.Scan += p.Scan
+		sum.IFD += p.IFD
+		sum.MakerNote += p.MakerNote
+		sum.XMP += p.XMP
+		sum.Total += p.Total
+	}
+	if n := time.Duration(len(f.stages)); n > 1 {
+		row(sum, fmt.Sprintf("(sum of %d files)", n))
+		row(exif.Profile{Scan: sum.Scan / n, IFD: sum.IFD / n, MakerNote: sum.MakerNote / n, XMP: sum.XMP / n, Total: sum.Total / n}, "(mean)")
+	}
+}
+
+// duration renders d to the microsecond.
+func duration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
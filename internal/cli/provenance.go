@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/provenance"
+)
+
+// runProvenance lists the provenance log and checks that it is intact.
+func runProvenance(a *app, args []string) error {
+	fs := a.newFlagSet("provenance", "shootlog provenance [--log path] [--file path] [--output text|json]")
+	logPath := fs.String("log", "", "provenance log (default archive.log of the config file)")
+	file := fs.String("file", "", "only list the entries of this file, as source or output")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if *logPath == "" {
+		cfg, err := config.Load("")
+		if err != nil {
+			return err
+		}
+		if *logPath = cfg.Archive.Log; *logPath == "" {
+			return errors.New("no provenance log: pass --log or set archive.log in the config file")
+		}
+	}
+	if *file != "" {
+		abs, err := filepath.Abs(*file)
+		if err != nil {
+			return err
+		}
+		*file = abs
+	}
+	entries, err := provenance.Read(*logPath)
+	var chain *provenance.ChainError
+	if err != nil && !errors.As(err, &chain) {
+		return err
+	}
+	shown := []provenance.Entry{}
+	for _, e := range entries {
+		if *file == "" || e.Path == *file || e.Output == *file {
+			shown = append(shown, e)
+		}
+	}
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(shown); err != nil {
+			return err
+		}
+	} else {
+		for _, e := range shown {
+			target := e.Path
+			if e.Output != "" {
+				target += " -> " + e.Output
+			}
+			fmt.Fprintf(a.stdout, "%s %s@%s %-7s %s %s -> %s: %s\n", e.Time.Format(time.RFC3339), e.User, e.Host, e.Action,
+				target, short(e.Before), short(e.After), strings.Join(e.Command, " "))
+		}
+	}
+	return err
+}
+
+// short abbreviates a hash for display.
+func short(sum string) string {
+	if sum == "" {
+		return "(new)"
+	}
+	return sum[:min(len(sum), 12)]
+}
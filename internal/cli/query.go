@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/album"
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+	"github.com/ryoh827/shootlog/internal/index"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runQuery searches a library index, so finding photos does not decode
+// the library again. Saved searches from the config file act as smart
+// collections, which --links and --m3u hand to other tools.
+func runQuery(a *app, args []string) error {
+	fs := a.newFlagSet("query", "shootlog query --index path | --socket path [--saved name] [--output json|csv|paths|null|parquet|arrow|sql] [--sort path|datetime|iso] [--provenance] [--links dir] [--m3u file] [--album manifest.json [--album-title title]] [expr]")
+	path := fs.String("index", "", "index file written by shootlog index")
+	saved := fs.String("saved", "", "run the search of this name in the config file's queries")
+	links := fs.String("links", "", "also make this directory a symlink farm of the matches, replacing its previous links")
+	m3u := fs.String("m3u", "", "also write the matches to this M3U file list")
+	albumPath := fs.String("album", "", "also write the matches, in output order, to this album manifest for shootlog report --album")
+	albumTitle := fs.String("album-title", "", "title of the --album manifest (default the saved search name)")
+	output := fs.String("output", report.FormatJSON, "output format: json, csv, paths (one per line), null (NUL-terminated), parquet, arrow (IPC stream) or sql")
+	sortKey := fs.String("sort", report.SortPath, "order of the output: "+strings.Join(report.SortKeys, ", "))
+	provenance := fs.Bool("provenance", false, "annotate each field with the directory and tag it was read from")
+	socket := fs.String("socket", "", "ask the shootlog daemon listening on this socket, which keeps the index loaded, instead of reading --index")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	// Flags may also follow the expression.
+	src := fs.Arg(0)
+	if fs.NArg() > 0 {
+		if err := parse(fs, fs.Args()[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("query takes one expression, got %q and %q; quote it", src, fs.Arg(0))
+		}
+	}
+	if *path == "" && *socket == "" {
+		return errors.New("--index or --socket is required")
+	}
+	if !slices.Contains(report.SortKeys, *sortKey) {
+		return fmt.Errorf("unknown sort key %q", *sortKey)
+	}
+	if *saved != "" && src != "" {
+		return errors.New("--saved excludes an expression")
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	if *saved != "" {
+		q, ok := cfg.Queries[*saved]
+		if !ok {
+			return fmt.Errorf("no saved query %q in the config file", *saved)
+		}
+		src = q
+	}
+	var expr *filter.Expr
+	if src != "" {
+		if expr, err = filter.Parse(src); err != nil {
+			return err
+		}
+	}
+	var matches []*exif.Summary
+	if *socket != "" {
+		if matches, err = queryDaemon(*socket, *path, src, *provenance); err != nil {
+			return err
+		}
+	} else {
+		ix, err := loadIndex(*path)
+		if err != nil {
+			return err
+		}
+		for _, f := range ix.Files {
+			s := f.Summary
+			if s == nil {
+				continue
+			}
+			// The index keeps what the files hold; home zones are
+			// applied before matching, as for --filter.
+			cfg.Privacy.Protect(s)
+			if expr != nil && !expr.MatchFields(indexFields(f)) {
+				continue
+			}
+			if !*provenance {
+				s.Sources = nil
+			}
+			matches = append(matches, s)
+		}
+	}
+	if err := report.Sort(matches, *sortKey); err != nil {
+		return err
+	}
+	if *links != "" {
+		if err := writeLinks(*links, matches); err != nil {
+			return err
+		}
+	}
+	if *m3u != "" {
+		if err := writeM3U(*m3u, matches); err != nil {
+			return err
+		}
+	}
+	if *albumPath != "" {
+		if len(matches) == 0 {
+			return errors.New("--album: no photos match")
+		}
+		title := *albumTitle
+		if title == "" {
+			title = *saved
+		}
+		data, err := album.New(title, matches).Marshal(filepath.Dir(*albumPath))
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(*albumPath, data); err != nil {
+			return err
+		}
+	}
+	return report.Write(a.stdout, *output, matches)
+}
+
+// indexFields returns the fields queries match f against: those of its
+// summary and the backup sets holding it.
+func indexFields(f *index.File) map[string]any {
+	fields := f.Summary.Fields()
+	if len(f.Backups) > 0 {
+		backups := make([]any, len(f.Backups))
+		for i, b := range f.Backups {
+			backups[i] = b
+		}
+		fields["backups"] = backups
+	}
+	return fields
+}
+
+// writeLinks fills dir with a symbolic link to each photo, named after it
+// and numbered when names collide. Links left by an earlier run are
+// removed first, so the directory tracks the search; other files are
+// kept.
+func writeLinks(dir string, summaries []*exif.Summary) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Type()&fs.ModeSymlink != 0 {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range summaries {
+		target, err := filepath.Abs(s.Path)
+		if err != nil {
+			return err
+		}
+		base := filepath.Base(s.Path)
+		ext := filepath.Ext(base)
+		name := filepath.Join(dir, base)
+		for n := 2; ; n++ {
+			if _, err := os.Lstat(name); errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			name = filepath.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext))
+		}
+		if err := os.Symlink(target, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeM3U writes the absolute paths of the photos as an extended M3U
+// playlist, which slideshow and media tools read as a file list.
+func writeM3U(path string, summaries []*exif.Summary) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, s := range summaries {
+		p, err := filepath.Abs(s.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n%s\n", filepath.Base(p), p)
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
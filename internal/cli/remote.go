@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/fetch"
+)
+
+// remoteFlags select images to download instead of local files.
+type remoteFlags struct {
+	urls    string
+	fetcher fetch.Fetcher
+}
+
+func (f *remoteFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.urls, "urls", "", "file listing image URLs to download, one per line (- for stdin), e.g. presigned object URLs")
+	fs.IntVar(&f.fetcher.Concurrency, "fetch-concurrency", fetch.DefaultConcurrency, "downloads in flight with --urls")
+	fs.Float64Var(&f.fetcher.Rate, "fetch-rate", 0, "requests started per second with --urls, retries included (0 for unlimited)")
+	fs.IntVar(&f.fetcher.Retries, "fetch-retries", fetch.DefaultRetries, "retries of a URL after a network error, 429 or 5xx response (0 for none)")
+	fs.DurationVar(&f.fetcher.Backoff, "fetch-backoff", fetch.DefaultBackoff, "wait before the first retry, doubled for each further one")
+}
+
+// set reports whether --urls was given.
+func (f *remoteFlags) set() bool { return f.urls != "" }
+
+// list reads the URLs, skipping blank lines and # comments.
+func (f *remoteFlags) list() ([]string, error) {
+	var r io.Reader = os.Stdin
+	if f.urls != "-" {
+		file, err := os.Open(f.urls)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	var urls []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			return nil, fmt.Errorf("%s: not an http(s) URL: %q", f.urls, line)
+		}
+		urls = append(urls, line)
+	}
+	return urls, sc.Err()
+}
+
+// decodeAll downloads and decodes every URL. Failures are reported on
+// stderr and counted; once all URLs are tried they fail the run, so a
+// partial listing still produces output.
+func (f *remoteFlags) decodeAll(ctx context.Context, a *app) ([]*exif.Summary, int, error) {
+	if !(f.fetcher.Rate >= 0) {
+		return nil, 0, fmt.Errorf("--fetch-rate %v: want 0 or more requests per second", f.fetcher.Rate)
+	}
+	if f.fetcher.Retries < 0 {
+		return nil, 0, fmt.Errorf("--fetch-retries %d: want 0 or more", f.fetcher.Retries)
+	}
+	urls, err := f.list()
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	var summaries []*exif.Summary
+	failed, retried := 0, 0
+	f.fetcher.Fetch(ctx, urls, func(r fetch.Result) {
+		if r.Attempts > 1 {
+			retried++
+		}
+		name := fetch.Redact(r.URL)
+		if r.Err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %v (%d attempts)\n", r.Err, r.Attempts)
+			failed++
+			return
+		}
+		s, err := exif.DecodeBytes(r.Data)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %s: %v\n", name, err)
+			failed++
+			return
+		}
+		s.Path = name
+		summaries = append(summaries, s)
+	})
+	fmt.Fprintf(a.stderr, "shootlog: fetched %d of %d URLs in %s, %d retried, %d failed\n",
+		len(summaries), len(urls), time.Since(start).Round(time.Millisecond), retried, failed)
+	if err := ctx.Err(); err != nil {
+		return summaries, failed, err
+	}
+	return summaries, failed, nil
+}
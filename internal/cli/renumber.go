@@ -0,0 +1,500 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/clock"
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filename"
+	"github.com/ryoh827/shootlog/internal/policy"
+	"github.com/ryoh827/shootlog/internal/provenance"
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+)
+
+// nameData is what Go templates of --name see: the summary's fields and
+// Seq, the formatted sequence number.
+type nameData struct {
+	*exif.Summary
+	Seq string
+}
+
+// renumbered is one shot of renumber: the files sharing a name stem in a
+// directory, such as a raw file and its JPEG, and when they were taken.
+type renumbered struct {
+	paths   []string
+	summary *exif.Summary
+	taken   time.Time
+	body    string
+	offset  time.Duration
+	name    string
+	// dsts are the new paths of paths, unless the shot is skipped for a
+	// conflict and keeps its names.
+	dsts []string
+	skip bool
+}
+
+// runRenumber interleaves the photos of several bodies by capture time,
+// corrected by each body's clock offset, and renames them with one
+// sequence.
+func runRenumber(a *app, args []string) error {
+	fs := a.newFlagSet("renumber", "shootlog renumber [--input file | --dir dir] [--offset-for body=+hh:mm:ss ...] [--sync reference.jpg=other.jpg ...] [--gps-time [--tz zone]] [--prefix IMG_ | --name template] [--start 1] [--digits 4] [--on-conflict error|suffix|skip] [--conflict-report file] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	offsets := clock.Offsets{}
+	fs.Func("offset-for", "add this correction to the capture times of a body, named by model or serial number, e.g. X-T5=+00:02:13; repeatable", offsets.Set)
+	var est estimateFlags
+	est.register(fs)
+	prefix := fs.String("prefix", "IMG_", "file name before the sequence number")
+	start := fs.Int("start", 1, "first sequence number")
+	digits := fs.Int("digits", 4, "minimum digits of the sequence number")
+	nameTmpl := fs.String("name", "", "template of the new names instead of --prefix: {seq} is the sequence number and summary fields such as {model} or {year} are embedded with unsafe characters replaced; a Go template with {{.Seq}} and the summary's fields, such as {{.Model}}, may use the functions of shootlog template-funcs (default: the rename of the config)")
+	onConflict := fs.String("on-conflict", conflictError, "what to do when a new name is taken: error, suffix (add -2, -3, ...) or skip (keep the shot's names)")
+	conflictReport := fs.String("conflict-report", "", "also write the conflicts and how each was resolved to this JSON file")
+	outDir := fs.String("out-dir", "", "copy the files under their new names to this directory")
+	force := fs.Bool("force", false, "rename the original files in place")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *start < 0 || *digits < 1 {
+		return errors.New("--start must not be negative and --digits must be at least 1")
+	}
+	if *outDir != "" && *force {
+		return errors.New("--out-dir and --force are mutually exclusive")
+	}
+	if !slices.Contains(conflictStrategies, *onConflict) {
+		return fmt.Errorf("unknown --on-conflict %q (want %s)", *onConflict, strings.Join(conflictStrategies, ", "))
+	}
+	if *nameTmpl != "" {
+		if err := config.ValidateRename(*nameTmpl); err != nil {
+			return fmt.Errorf("--name: %w", err)
+		}
+	}
+	prefixSet := false
+	fs.Visit(func(f *flag.Flag) { prefixSet = prefixSet || f.Name == "prefix" })
+	tree, err := config.LoadTree("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	estimated := clock.Offsets{}
+	if est.set() {
+		summaries, err := a.decodeAll(paths)
+		if err != nil {
+			return err
+		}
+		es, err := est.estimate(summaries)
+		if err != nil {
+			return err
+		}
+		for _, e := range es {
+			estimated[e.Body] = e.Offset
+		}
+		writeEstimates(a.stdout, es)
+	}
+	shots, undated, err := renumberShots(paths, offsets, estimated)
+	if err != nil {
+		return err
+	}
+	for _, p := range undated {
+		fmt.Fprintf(a.stderr, "shootlog: skipping %s: no capture time\n", p)
+	}
+	sort.SliceStable(shots, func(i, j int) bool {
+		if !shots[i].taken.Equal(shots[j].taken) {
+			return shots[i].taken.Before(shots[j].taken)
+		}
+		return shots[i].paths[0] < shots[j].paths[0]
+	})
+	// Without --name or --prefix, each shot is named by the rename
+	// template of its directory config, if any.
+	parsed := map[string]*template.Template{}
+	for i := range shots {
+		seq := fmt.Sprintf("%0*d", *digits, *start+i)
+		shots[i].name = *prefix + seq
+		tmpl := *nameTmpl
+		if tmpl == "" && !prefixSet {
+			c, err := tree.For(shots[i].paths[0])
+			if err != nil {
+				return err
+			}
+			tmpl = c.Rename
+		}
+		switch {
+		case strings.Contains(tmpl, "{{"):
+			t, ok := parsed[tmpl]
+			if !ok {
+				var err error
+				if t, err = tmplfunc.Parse("name", tmpl); err != nil {
+					return fmt.Errorf("%s: name: %w", shots[i].paths[0], err)
+				}
+				parsed[tmpl] = t
+			}
+			// Go templates build names from code rather than from
+			// placeholders, so the whole name is made safe.
+			var b strings.Builder
+			if err := t.Execute(&b, nameData{shots[i].summary, seq}); err != nil {
+				return fmt.Errorf("%s: name: %w", shots[i].paths[0], err)
+			}
+			shots[i].name = filename.Sanitize(strings.TrimSpace(b.String()))
+		case tmpl != "":
+			shots[i].name = policy.ExpandFunc(strings.ReplaceAll(tmpl, "{seq}", seq), shots[i].summary, filename.Sanitize)
+		}
+	}
+	conflicts, err := planRenames(shots, *outDir, *onConflict)
+	for _, c := range conflicts {
+		fmt.Fprintf(a.stderr, "shootlog: %s\n", c)
+	}
+	if *conflictReport != "" {
+		if conflicts == nil {
+			conflicts = []nameConflict{}
+		}
+		data, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(*conflictReport, append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var cust custody
+	if *force {
+		if err := renameAll(&cust, shots); err != nil {
+			return err
+		}
+	}
+	for _, sh := range shots {
+		note := sh.taken.Format("2006-01-02T15:04:05")
+		if sh.offset != 0 {
+			note += fmt.Sprintf(", %s %s", sh.body, clock.FormatOffset(sh.offset))
+		}
+		if sh.skip {
+			for _, p := range sh.paths {
+				fmt.Fprintf(a.stdout, "skipped %s (%s)\n", p, note)
+			}
+			continue
+		}
+		for j, p := range sh.paths {
+			dst := sh.dsts[j]
+			verb := "would rename"
+			switch {
+			case *outDir != "":
+				if err := copyRenamed(&cust, p, dst); err != nil {
+					return err
+				}
+				verb = "copied"
+			case *force:
+				verb = "renamed"
+			}
+			fmt.Fprintf(a.stdout, "%s %s -> %s (%s)\n", verb, p, dst, note)
+		}
+	}
+	if *outDir == "" && !*force {
+		a.dryRunNote()
+	}
+	return nil
+}
+
+// renumberShots groups paths into shots and dates them, returning the
+// files without a capture time separately. Offsets given explicitly win
+// over estimated ones.
+func renumberShots(paths []string, offsets, estimated clock.Offsets) ([]renumbered, []string, error) {
+	byStem := map[string]*renumbered{}
+	var order []string
+	for _, p := range paths {
+		stem := strings.TrimSuffix(p, filepath.Ext(p))
+		sh, ok := byStem[stem]
+		if !ok {
+			sh = &renumbered{}
+			byStem[stem] = sh
+			order = append(order, stem)
+		}
+		sh.paths = append(sh.paths, p)
+		if !sh.taken.IsZero() {
+			continue
+		}
+		s, err := exif.DecodeFile(p)
+		if err != nil {
+			if len(paths) == 1 {
+				return nil, nil, err
+			}
+			continue
+		}
+		t, ok := s.CaptureTime()
+		if !ok {
+			continue
+		}
+		d, ok := offsets.For(s)
+		if !ok {
+			d, _ = estimated.For(s)
+		}
+		sh.summary = s
+		sh.taken, sh.body, sh.offset = clock.Wall(t).Add(d), clock.Body(s), d
+	}
+	var shots []renumbered
+	var undated []string
+	for _, stem := range order {
+		if sh := byStem[stem]; sh.taken.IsZero() {
+			undated = append(undated, sh.paths...)
+		} else {
+			shots = append(shots, *sh)
+		}
+	}
+	return shots, undated, nil
+}
+
+// Strategies for new names that are taken.
+const (
+	conflictError  = "error"
+	conflictSuffix = "suffix"
+	conflictSkip   = "skip"
+)
+
+var conflictStrategies = []string{conflictError, conflictSuffix, conflictSkip}
+
+// Reasons a new name is taken.
+const (
+	takenDuplicate = "duplicate"
+	takenExists    = "exists"
+)
+
+// nameConflict is a new name of renumber that was taken, and how it was
+// resolved.
+type nameConflict struct {
+	Path   string `json:"path"`
+	Target string `json:"target"`
+	// Holder is the file that has the name: another file renamed to it
+	// for a duplicate, or a file that exists and will not be renamed.
+	Holder     string `json:"holder"`
+	Reason     string `json:"reason"`
+	Resolution string `json:"resolution"`
+	RenamedTo  string `json:"renamed_to,omitempty"`
+}
+
+func (c nameConflict) String() string {
+	msg := fmt.Sprintf("%s -> %s: ", c.Path, c.Target)
+	if c.Reason == takenDuplicate {
+		msg += "also the new name of " + c.Holder
+	} else {
+		msg += "exists and is not renamed"
+	}
+	switch c.Resolution {
+	case conflictSuffix:
+		msg += "; renamed to " + c.RenamedTo + " instead"
+	case conflictSkip:
+		msg += "; skipping the shot"
+	}
+	return msg
+}
+
+// planRenames sets the new paths of shots from their names, in outDir or
+// next to the files, and checks that every name is valid on Windows,
+// macOS and Linux alike. Names are compared case-insensitively, as those
+// file systems usually do. A name already taken, by another shot or by a
+// file that stays, is resolved as strategy says; with conflictError all
+// conflicts are returned with an error and nothing is renamed.
+func planRenames(shots []renumbered, outDir, strategy string) ([]nameConflict, error) {
+	// Skipped shots keep their files, which can take names planned for
+	// earlier shots, so planning repeats until no more shots are skipped.
+	skipped := map[int][]nameConflict{}
+	for {
+		conflicts, err := planOnce(shots, outDir, strategy, skipped)
+		if err != nil {
+			return conflicts, err
+		}
+		var newly []int
+		for i := range shots {
+			if shots[i].skip && skipped[i] == nil {
+				newly = append(newly, i)
+			}
+		}
+		if len(newly) == 0 {
+			return conflicts, nil
+		}
+		for _, i := range newly {
+			for _, c := range conflicts {
+				if slices.Contains(shots[i].paths, c.Path) {
+					skipped[i] = append(skipped[i], c)
+				}
+			}
+		}
+	}
+}
+
+// planOnce plans the new paths of shots, keeping those skipped for the
+// conflicts recorded in skipped.
+func planOnce(shots []renumbered, outDir, strategy string, skipped map[int][]nameConflict) ([]nameConflict, error) {
+	type holder struct {
+		path  string
+		stays bool
+	}
+	taken := map[string]holder{}
+	moving := map[string]bool{}
+	for i, sh := range shots {
+		for _, p := range sh.paths {
+			if skipped[i] != nil {
+				taken[filename.Key(p)] = holder{p, true}
+			} else if outDir == "" {
+				moving[filename.Key(p)] = true
+			}
+		}
+	}
+	var conflicts []nameConflict
+	count := 0
+	for i := range shots {
+		sh := &shots[i]
+		sh.dsts, sh.skip = nil, skipped[i] != nil
+		if sh.skip {
+			conflicts = append(conflicts, skipped[i]...)
+			continue
+		}
+		var first []nameConflict
+		for n := 1; ; n++ {
+			name := sh.name
+			if n > 1 {
+				name = fmt.Sprintf("%s-%d", sh.name, n)
+			}
+			var found []nameConflict
+			dsts := make([]string, len(sh.paths))
+			own := map[string]string{}
+			for j, p := range sh.paths {
+				dir := filepath.Dir(p)
+				if outDir != "" {
+					dir = outDir
+				}
+				base := name + strings.ToLower(filepath.Ext(p))
+				if err := filename.Check(base); err != nil {
+					return conflicts, fmt.Errorf("new name of %s: %w", p, err)
+				}
+				dst := filepath.Join(dir, base)
+				key := filename.Key(dst)
+				if prev, ok := own[key]; ok {
+					return conflicts, fmt.Errorf("%s and %s would both be renamed to %s", prev, p, dst)
+				}
+				own[key] = p
+				dsts[j] = dst
+				c := nameConflict{Path: p, Target: dst}
+				if h, ok := taken[key]; ok {
+					c.Holder, c.Reason = h.path, takenDuplicate
+					if h.stays {
+						c.Reason = takenExists
+					}
+					found = append(found, c)
+				} else if _, err := os.Lstat(dst); err == nil && !moving[key] {
+					c.Holder, c.Reason = dst, takenExists
+					found = append(found, c)
+				}
+			}
+			if n == 1 {
+				first = found
+			}
+			if len(found) == 0 || strategy != conflictSuffix {
+				sh.dsts = dsts
+				break
+			}
+		}
+		for _, c := range first {
+			c.Resolution = strategy
+			if strategy == conflictSuffix {
+				c.RenamedTo = sh.dsts[slices.Index(sh.paths, c.Path)]
+			}
+			conflicts = append(conflicts, c)
+		}
+		if len(first) > 0 && strategy == conflictSkip {
+			sh.dsts, sh.skip = nil, true
+			continue
+		}
+		count += len(first)
+		for j, d := range sh.dsts {
+			if _, ok := taken[filename.Key(d)]; !ok {
+				taken[filename.Key(d)] = holder{sh.paths[j], false}
+			}
+		}
+	}
+	if strategy == conflictError && count > 0 {
+		return conflicts, fmt.Errorf("%d new names are taken; nothing was renamed (try --on-conflict suffix or skip)", count)
+	}
+	return conflicts, nil
+}
+
+// renameAll renames the files of shots in place, first to temporary names
+// so that new names may be old names of other files.
+func renameAll(cust *custody, shots []renumbered) error {
+	sources := map[string]bool{}
+	for _, sh := range shots {
+		for _, p := range sh.paths {
+			sources[filename.Key(p)] = !sh.skip
+		}
+	}
+	for _, sh := range shots {
+		for j, dst := range sh.dsts {
+			if _, err := os.Stat(dst); err == nil && !sources[filename.Key(dst)] {
+				return fmt.Errorf("%s exists and is not renumbered; not renaming %s", dst, sh.paths[j])
+			}
+		}
+	}
+	type move struct{ src, tmp, dst string }
+	var moves []move
+	for _, sh := range shots {
+		for j, p := range sh.dsts {
+			src := sh.paths[j]
+			if err := cust.check(src); err != nil {
+				return err
+			}
+			moves = append(moves, move{src, filepath.Join(filepath.Dir(src), ".shootlog-renumber-"+filepath.Base(src)), p})
+		}
+	}
+	for i, m := range moves {
+		if err := os.Rename(m.src, m.tmp); err != nil {
+			return fmt.Errorf("renaming %s: %w (renamed %d of %d files)", m.src, err, i, len(moves))
+		}
+	}
+	for _, m := range moves {
+		if err := os.Rename(m.tmp, m.dst); err != nil {
+			return fmt.Errorf("renaming %s: %w; it is left as %s", m.src, err, m.tmp)
+		}
+		data, err := os.ReadFile(m.dst)
+		if err != nil {
+			return err
+		}
+		if err := cust.record(provenance.Rename, m.src, m.dst, data, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRenamed copies src to dst, which must not exist yet, and logs the
+// copy.
+func copyRenamed(cust *custody, src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s exists; not copying %s over it", dst, src)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(dst, data); err != nil {
+		return err
+	}
+	return cust.record(provenance.Copy, src, dst, data, data)
+}
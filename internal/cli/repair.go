@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// runRepair rewrites broken APP1 Exif segments as clean blocks, keeping
+// their values, and lists each correction.
+func runRepair(a *app, args []string) error {
+	fs := a.newFlagSet("repair", "shootlog repair [--input file | --dir dir] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		repaired, fixes, err := exif.Repair(data)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %s: %v\n", p, err)
+			failed++
+			continue
+		}
+		if len(fixes) == 0 {
+			fmt.Fprintf(a.stdout, "%s: ok\n", p)
+			continue
+		}
+		// A repair must leave every value readable as before.
+		if before, err := exif.DecodeBytes(data); err == nil {
+			after, err := exif.DecodeBytes(repaired)
+			if err != nil || !keepsValues(before, after) {
+				fmt.Fprintf(a.stderr, "shootlog: %s: repair would lose or change decoded values; left as is\n", p)
+				failed++
+				continue
+			}
+		}
+		dst := p
+		if !out.dryRun() {
+			if dst, err = out.write(p, data, repaired); err != nil {
+				return err
+			}
+		}
+		verb := "repaired"
+		if out.dryRun() {
+			verb = "would repair"
+		}
+		fmt.Fprintf(a.stdout, "%s %s:\n", verb, dst)
+		for _, f := range fixes {
+			fmt.Fprintf(a.stdout, "  %s\n", f)
+		}
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be repaired", failed, len(paths))
+	}
+	return nil
+}
+
+// keepsValues reports whether after holds every value before held. It may
+// hold more: a repaired segment length can uncover values before missed.
+func keepsValues(before, after *exif.Summary) bool {
+	got := after.Fields()
+	for name, v := range before.Fields() {
+		if !reflect.DeepEqual(v, got[name]) {
+			return false
+		}
+	}
+	return true
+}
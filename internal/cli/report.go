@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/album"
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/pdf"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/weather"
+)
+
+func runReport(a *app, args []string) error {
+	fs := a.newFlagSet("report", "shootlog report [--input file | --dir dir | --album manifest] [--output text|json|html|svg|pdf | --template name] [--list-templates] [--sign-cert cert.pem --sign-key key.pem] [--tiles url|dir] [--weather file.csv|url] [--lang en|ja] [--catalog path] [--filter expr] [--units metric|imperial]")
+	var in inputFlags
+	in.register(fs)
+	albumPath := fs.String("album", "", "report on the photos of an album manifest (.json or .yaml), in its order and with its captions and cover")
+	output := fs.String("output", "text", "output format: text, json, html, pdf, or svg for the elevation profile")
+	tmplName := fs.String("template", "", "render the report with this template of the reports directory in the config file (an .html, .md or .txt file), or a template file, instead of --output")
+	listTemplates := fs.Bool("list-templates", false, "list the templates of the reports directory and exit")
+	tiles := fs.String("tiles", report.DefaultTiles, "map tiles of the html report: a URL template with {z}, {x} and {y}, or a directory of z/x/y.png tiles")
+	lang := fs.String("lang", "", "language of the text report: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	weatherSource := fs.String("weather", "", "record the weather of geotagged photos from a CSV history (time,latitude,longitude,temperature,conditions) or an http(s) URL template with {time}, {latitude} and {longitude}")
+	signCert := fs.String("sign-cert", "", "sign the pdf report with this PEM certificate (chain)")
+	signKey := fs.String("sign-key", "", "PEM private key of --sign-cert")
+	signReason := fs.String("sign-reason", "", "reason recorded in the pdf signature")
+	var units unitsFlag
+	units.register(fs)
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *tmplName != "" && *output != "text" {
+		return errors.New("--template excludes --output")
+	}
+	if (*signCert != "" || *signKey != "") && *output != "pdf" {
+		return errors.New("--sign-cert and --sign-key need --output pdf")
+	}
+	if (*signCert == "") != (*signKey == "") {
+		return errors.New("--sign-cert and --sign-key go together")
+	}
+	if *output == "pdf" && *lang != "" && *lang != locale.English.Tag {
+		return fmt.Errorf("--lang %s: pdf reports are in English only", *lang)
+	}
+	var signer *pdf.Signer
+	if *signCert != "" {
+		var err error
+		if signer, err = pdf.LoadSigner(*signCert, *signKey); err != nil {
+			return err
+		}
+		signer.Reason = *signReason
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	if *listTemplates {
+		names, err := report.ListTemplates(cfg.ReportsDir())
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			fmt.Fprintln(a.stdout, n)
+		}
+		return nil
+	}
+	var tmplPath string
+	if *tmplName != "" {
+		if tmplPath, err = report.FindTemplate(cfg.ReportsDir(), *tmplName); err != nil {
+			return err
+		}
+	}
+	system, err := units.resolve(cfg)
+	if err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	var alb *album.Album
+	var paths []string
+	if *albumPath != "" {
+		if in.input != "" || in.dir != "" {
+			return errors.New("--album excludes --input and --dir")
+		}
+		if alb, err = album.Load(*albumPath); err != nil {
+			return err
+		}
+		paths = alb.Paths()
+	} else if paths, err = in.paths(); err != nil {
+		return err
+	}
+	summaries, err := a.decodeAll(paths)
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cat.apply(a, s)
+		cfg.Privacy.Protect(s)
+	}
+	summaries = where.apply(summaries)
+	session := report.NewSession(summaries)
+	if *weatherSource != "" {
+		p, err := weather.Open(*weatherSource)
+		if err != nil {
+			return err
+		}
+		if err := session.AddWeather(context.Background(), summaries, p); err != nil {
+			return err
+		}
+	}
+	if err := session.ConvertUnits(system); err != nil {
+		return err
+	}
+	if tmplPath != "" {
+		return report.WriteTemplate(a.stdout, tmplPath, report.NewTemplateData(session, summaries, loc, alb))
+	}
+	switch *output {
+	case "text":
+		return session.WriteText(a.stdout, loc)
+	case "html":
+		return report.WriteHTML(a.stdout, session, summaries, loc, *tiles, alb)
+	case "pdf":
+		return report.WritePDF(a.stdout, session, summaries, report.PDFOptions{Signer: signer, Album: alb})
+	case "svg":
+		return session.WriteElevationSVG(a.stdout)
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(session)
+	}
+	return fmt.Errorf("unknown output format %q", *output)
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/provenance"
+	"github.com/ryoh827/shootlog/internal/trash"
+)
+
+// runRestore lists the trash of originals kept by in-place rewrites, or
+// puts the latest original of each given file back. The version being
+// replaced goes to the trash in turn, so a restore can be undone by
+// another.
+func runRestore(a *app, args []string) error {
+	fs := a.newFlagSet("restore", "shootlog restore [--trash dir] [--list] [--output text|json] [path ...]")
+	dir := fs.String("trash", "", `trash directory, or "os" for the desktop trash (default archive.trash of the config file)`)
+	list := fs.Bool("list", false, "list the files in the trash, most recent first, instead of restoring")
+	output := fs.String("output", "text", "output format of --list: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if *dir == "" {
+		cfg, err := config.Load("")
+		if err != nil {
+			return err
+		}
+		if *dir = cfg.Archive.Trash; *dir == "" {
+			return errors.New("no trash: pass --trash or set archive.trash in the config file")
+		}
+	}
+	t, err := trash.Open(*dir)
+	if err != nil {
+		return err
+	}
+	if *list {
+		items, err := t.List()
+		if err != nil {
+			return err
+		}
+		if *output == "json" {
+			if items == nil {
+				items = []trash.Item{}
+			}
+			enc := json.NewEncoder(a.stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(items)
+		}
+		for _, it := range items {
+			fmt.Fprintf(a.stdout, "%s %s (%s)\n", it.Deleted.Format(time.RFC3339), it.Path, it.Name)
+		}
+		return nil
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no files to restore; pass paths or --list")
+	}
+	var c custody
+	for _, p := range fs.Args() {
+		if err := restoreFile(&c, t, p); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "restored %s\n", p)
+	}
+	return nil
+}
+
+// restoreFile replaces path with its latest version in t.
+func restoreFile(c *custody, t *trash.Trash, path string) error {
+	it, ok, err := t.Latest(path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s: not in the trash at %s", path, t.Dir())
+	}
+	data, err := t.Read(it)
+	if err != nil {
+		return err
+	}
+	if err := c.check(path); err != nil {
+		return err
+	}
+	current, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if _, err := t.Put(path, current); err != nil {
+			return fmt.Errorf("%s: not restored: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	if err := t.Remove(it); err != nil {
+		return err
+	}
+	return c.record(provenance.Restore, path, path, current, data)
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ryoh827/shootlog/internal/analyze"
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/risk"
+)
+
+// riskResult is the risk of one shot, and its exposure warning when
+// thumbnails were analyzed.
+type riskResult struct {
+	Path string `json:"path"`
+	risk.Score
+	Exposure string `json:"exposure_warning,omitempty"`
+}
+
+// runRisk flags the shots most likely to be noisy or soft, riskiest
+// first, with the formula from the config file's risk section. With
+// --exposure it also flags shots whose thumbnail looks blown or crushed,
+// whatever their score.
+func runRisk(a *app, args []string) error {
+	fs := a.newFlagSet("risk", "shootlog risk [--input file | --dir dir] [--min 0.5] [--exposure] [--filter expr] [--output text|json|paths]")
+	var in inputFlags
+	in.register(fs)
+	minRisk := fs.Float64("min", 0, "only list shots scoring at least this, 0-1")
+	estimate := fs.Bool("exposure", false, "also flag shots whose embedded thumbnail shows blown highlights or crushed shadows")
+	output := fs.String("output", "text", "output format: text, json or paths (one per line)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" && *output != report.FormatPaths {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+	var pipeline analyze.Pipeline
+	if *estimate {
+		if pipeline, err = analyze.Parse([]string{"exposure"}); err != nil {
+			return err
+		}
+	}
+	summaries, err := a.decodeWith(paths, a.withAnalyzers(exif.DecodeFile, pipeline))
+	if err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		cfg.Privacy.Protect(s)
+	}
+
+	type shot struct {
+		riskResult
+		s *exif.Summary
+	}
+	var shots []shot
+	for _, s := range where.apply(summaries) {
+		sc, ok := cfg.Risk.Score(s)
+		if ok && sc.Risk >= *minRisk || s.ExposureWarning != "" {
+			shots = append(shots, shot{riskResult{Path: s.Path, Score: sc, Exposure: s.ExposureWarning}, s})
+		}
+	}
+	sort.SliceStable(shots, func(i, j int) bool { return shots[i].Risk > shots[j].Risk })
+
+	switch *output {
+	case "json":
+		results := make([]riskResult, len(shots))
+		for i, sh := range shots {
+			results[i] = sh.riskResult
+		}
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case report.FormatPaths:
+		kept := make([]*exif.Summary, len(shots))
+		for i, sh := range shots {
+			kept[i] = sh.s
+		}
+		return report.WritePaths(a.stdout, kept, '\n')
+	}
+	exposureColumn := ""
+	if *estimate {
+		exposureColumn = locale.Pad("Exposure", 8) + " "
+	}
+	fmt.Fprintf(a.stdout, "%s %s %s %s %s %s %s %s%s\n", locale.Pad("Risk", 5), locale.Pad("Noise", 5), locale.Pad("Blur", 5),
+		locale.Pad("ISO", 6), locale.Pad("Shutter", 8), locale.Pad("Safe", 8), locale.Pad("IS", 3), exposureColumn, "Path")
+	for _, sh := range shots {
+		r, s := sh.riskResult, sh.s
+		safe := "-"
+		if r.Safe > 0 {
+			safe = exif.FormatExposure(r.Safe)
+		}
+		iso := "-"
+		if s.ISO > 0 {
+			iso = strconv.Itoa(s.ISO)
+		}
+		shutter := "-"
+		if s.ExposureTime > 0 {
+			shutter = exif.FormatExposure(s.ExposureTime)
+		}
+		is := s.Stabilization
+		if is == "" {
+			is = "-"
+		}
+		warning := ""
+		if *estimate {
+			warning = "-"
+			if r.Exposure != "" {
+				warning = r.Exposure
+			}
+			warning = locale.Pad(warning, 8) + " "
+		}
+		fmt.Fprintf(a.stdout, "%5.2f %5.2f %5.2f %s %s %s %s %s%s\n", r.Risk, r.Noise, r.Blur,
+			locale.Pad(iso, 6), locale.Pad(shutter, 8), locale.Pad(safe, 8), locale.Pad(is, 3), warning, r.Path)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/pkg/jsonrpc"
+)
+
+// rpcMethods are the methods runRPC answers, as initialize lists them.
+var rpcMethods = []string{"initialize", "hover", "summary"}
+
+// runRPC serves JSON-RPC 2.0 on standard input and output for editor
+// plugins, which start it once and ask about the files under the cursor
+// rather than running shootlog for each. Requests may come in batches
+// and be cancelled with $/cancelRequest, as in the Language Server
+// Protocol; it exits when standard input closes.
+func runRPC(a *app, args []string) error {
+	fs := a.newFlagSet("rpc", "shootlog rpc [--lang "+strings.Join(locale.Tags(), "|")+"]")
+	lang := fs.String("lang", "", "language of hover text: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	s := &rpcServer{
+		cfg:   cfg,
+		loc:   loc,
+		slots: make(chan struct{}, runtime.NumCPU()),
+		cache: map[string]rpcCached{},
+	}
+	return jsonrpc.Serve(context.Background(), os.Stdin, a.stdout, s.handle)
+}
+
+// rpcServer answers editor requests. Files are decoded at most NumCPU at
+// a time, so a large batch queues instead of reading every file at once,
+// and summaries are kept until the file changes.
+type rpcServer struct {
+	cfg   *config.Config
+	loc   *locale.Locale
+	slots chan struct{}
+
+	mu    sync.Mutex
+	root  string
+	cache map[string]rpcCached
+}
+
+type rpcCached struct {
+	size    int64
+	modTime time.Time
+	summary *exif.Summary
+}
+
+// rpcMaxCached bounds the summaries kept; the cache starts over beyond it.
+const rpcMaxCached = 4096
+
+// Parameters and results of the editor methods.
+type (
+	rpcInitialize struct {
+		// Root is the directory relative paths are resolved against,
+		// usually the editor's workspace; by default the working
+		// directory of the server.
+		Root string `json:"root"`
+	}
+	rpcServerInfo struct {
+		Name    string   `json:"name"`
+		Methods []string `json:"methods"`
+	}
+	rpcFile struct {
+		Path string `json:"path"`
+	}
+	// rpcHover is shaped as the Hover result of the Language Server
+	// Protocol.
+	rpcHover struct {
+		Contents rpcMarkup `json:"contents"`
+	}
+	rpcMarkup struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	}
+)
+
+func (s *rpcServer) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	decode := func(v any) error {
+		if len(params) == 0 {
+			return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s takes params", method)
+		}
+		if err := json.Unmarshal(params, v); err != nil {
+			return jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+		}
+		return nil
+	}
+	switch method {
+	case "initialize":
+		var p rpcInitialize
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+			}
+		}
+		s.mu.Lock()
+		s.root = p.Root
+		s.mu.Unlock()
+		return rpcServerInfo{Name: "shootlog", Methods: rpcMethods}, nil
+	case "hover", "summary":
+		var p rpcFile
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if p.Path == "" {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no path")
+		}
+		sum, err := s.summary(ctx, p.Path)
+		if err != nil {
+			return nil, err
+		}
+		if method == "summary" {
+			return sum, nil
+		}
+		return rpcHover{rpcMarkup{Kind: "markdown", Value: report.Hover(sum, s.loc)}}, nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "no method %q", method)
+}
+
+// summary decodes the file at path, relative to the root, with home
+// zones applied. It gives up when ctx is cancelled while waiting for a
+// slot.
+func (s *rpcServer) summary(ctx context.Context, path string) (*exif.Summary, error) {
+	s.mu.Lock()
+	if !filepath.IsAbs(path) && s.root != "" {
+		path = filepath.Join(s.root, path)
+	}
+	s.mu.Unlock()
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+	}
+	s.mu.Lock()
+	c, ok := s.cache[path]
+	s.mu.Unlock()
+	if ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c.summary, nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.slots }()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sum, err := exif.DecodeFile(path)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+	}
+	s.cfg.Privacy.Protect(sum)
+	sum.Sources = nil
+	s.mu.Lock()
+	if len(s.cache) >= rpcMaxCached {
+		clear(s.cache)
+	}
+	s.cache[path] = rpcCached{fi.Size(), fi.ModTime(), sum}
+	s.mu.Unlock()
+	return sum, nil
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/pkg/jsonrpc"
+)
+
+func testJPEG(t *testing.T, path, camera string) {
+	t.Helper()
+	b := exiftest.New(binary.BigEndian)
+	b.IFD0().ASCII(exif.TagMake, camera)
+	if err := os.WriteFile(path, b.JPEG(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRPCServer(slots int) *rpcServer {
+	return &rpcServer{
+		cfg:   &config.Config{},
+		loc:   locale.English,
+		slots: make(chan struct{}, slots),
+		cache: map[string]rpcCached{},
+	}
+}
+
+func TestRPCMethods(t *testing.T) {
+	dir := t.TempDir()
+	testJPEG(t, filepath.Join(dir, "a.jpg"), "Canon")
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a photo"), 0o644)
+	c := rpcClient(t, testRPCServer(2).handle)
+
+	var info rpcServerInfo
+	if err := c.Call("initialize", rpcInitialize{Root: dir}, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "shootlog" || strings.Join(info.Methods, ",") != "initialize,hover,summary" {
+		t.Errorf("initialize = %+v", info)
+	}
+	tests := []struct {
+		name   string
+		method string
+		params any
+		code   int
+		check  func(t *testing.T, c *jsonrpc.Client, method string, params any)
+	}{
+		{"summary relative to the root", "summary", rpcFile{Path: "a.jpg"}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var s exif.Summary
+			c.Call(method, params, &s)
+			if s.Make != "Canon" || s.Sources != nil {
+				t.Errorf("summary %+v", s)
+			}
+		}},
+		{"summary absolute", "summary", rpcFile{Path: filepath.Join(dir, "a.jpg")}, 0, nil},
+		{"hover", "hover", rpcFile{Path: "a.jpg"}, 0, func(t *testing.T, c *jsonrpc.Client, method string, params any) {
+			var h rpcHover
+			c.Call(method, params, &h)
+			if h.Contents.Kind != "markdown" || !strings.HasPrefix(h.Contents.Value, "**a.jpg**") || !strings.Contains(h.Contents.Value, "Canon") {
+				t.Errorf("hover %+v", h)
+			}
+		}},
+		{"no params", "summary", nil, jsonrpc.CodeInvalidParams, nil},
+		{"no path", "hover", rpcFile{}, jsonrpc.CodeInvalidParams, nil},
+		{"bad params", "hover", []string{"a.jpg"}, jsonrpc.CodeInvalidParams, nil},
+		{"missing file", "summary", rpcFile{Path: "z.jpg"}, jsonrpc.CodeInvalidParams, nil},
+		{"not a photo", "summary", rpcFile{Path: "notes.txt"}, jsonrpc.CodeInvalidParams, nil},
+		{"bad initialize", "initialize", "root", jsonrpc.CodeInvalidParams, nil},
+		{"unknown", "definition", rpcFile{Path: "a.jpg"}, jsonrpc.CodeMethodNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Call(tt.method, tt.params, nil)
+			if code := rpcCode(err); code != tt.code || (tt.code == 0 && err != nil) {
+				t.Fatalf("error %v, want code %d", err, tt.code)
+			}
+			if tt.check != nil {
+				tt.check(t, c, tt.method, tt.params)
+			}
+		})
+	}
+}
+
+func TestRPCCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	testJPEG(t, path, "Canon")
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := testRPCServer(1)
+	makeOf := func() string {
+		t.Helper()
+		sum, err := s.handle(context.Background(), "summary", []byte(`{"path":"`+path+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum.(*exif.Summary).Make
+	}
+	if got := makeOf(); got != "Canon" {
+		t.Fatalf("make %q", got)
+	}
+	// The same size and time are taken to be the same file.
+	testJPEG(t, path, "Nikon")
+	os.Chtimes(path, fi.ModTime(), fi.ModTime())
+	if got := makeOf(); got != "Canon" {
+		t.Errorf("make %q from an unchanged file, want the cached Canon", got)
+	}
+	later := fi.ModTime().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got := makeOf(); got != "Nikon" {
+		t.Errorf("make %q after the file changed, want Nikon", got)
+	}
+}
+
+func TestRPCCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	testJPEG(t, path, "Canon")
+	// With no slot free, a cancelled request stops waiting for one.
+	s := testRPCServer(1)
+	s.slots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.handle(ctx, "hover", []byte(`{"path":"`+path+`"}`)); !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, want context.Canceled", err)
+	}
+}
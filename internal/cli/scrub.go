@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/privacy"
+)
+
+// scrubReport is the evidence of a scrub run: the tags each file lost or
+// had changed, and how often each tag was touched overall.
+type scrubReport struct {
+	DryRun bool            `json:"dry_run,omitempty"`
+	Files  []scrubbedFile  `json:"files"`
+	Tags   []scrubTagCount `json:"tags"`
+}
+
+type scrubbedFile struct {
+	Path    string        `json:"path"`
+	Output  string        `json:"output,omitempty"`
+	Zone    string        `json:"zone"`
+	Removed int           `json:"removed"`
+	Altered int           `json:"altered"`
+	Added   int           `json:"added,omitempty"`
+	Changes []exif.Change `json:"changes"`
+}
+
+// scrubTagCount is how many files one change of a tag was made to, with
+// the first of them as an example.
+type scrubTagCount struct {
+	Dir     string `json:"ifd"`
+	ID      string `json:"tag"`
+	Name    string `json:"name,omitempty"`
+	Kind    string `json:"kind"`
+	Files   int    `json:"files"`
+	Example string `json:"example"`
+}
+
+// add records the changes made to one file. Without values the original
+// values are left out, so the report does not keep what was scrubbed.
+func (r *scrubReport) add(f scrubbedFile, changes []exif.Change, values bool) {
+	f.Changes = []exif.Change{}
+	for _, c := range changes {
+		switch c.Kind {
+		case exif.ChangeRemoved:
+			f.Removed++
+		case exif.ChangeAltered:
+			f.Altered++
+		case exif.ChangeAdded:
+			f.Added++
+		}
+		if !values {
+			c.Before = ""
+		}
+		f.Changes = append(f.Changes, c)
+		i := sort.Search(len(r.Tags), func(i int) bool { return !tagCountLess(r.Tags[i], c) })
+		if i == len(r.Tags) || r.Tags[i].Dir != c.Dir || r.Tags[i].ID != c.ID || r.Tags[i].Kind != c.Kind {
+			r.Tags = append(r.Tags[:i], append([]scrubTagCount{{Dir: c.Dir, ID: c.ID, Name: c.Name, Kind: c.Kind, Example: f.Path}}, r.Tags[i:]...)...)
+		}
+		r.Tags[i].Files++
+	}
+	r.Files = append(r.Files, f)
+}
+
+// tagCountLess orders the tag counts by directory, tag and kind.
+func tagCountLess(t scrubTagCount, c exif.Change) bool {
+	if t.Dir != c.Dir {
+		return t.Dir < c.Dir
+	}
+	if t.ID != c.ID {
+		return t.ID < c.ID
+	}
+	return t.Kind < c.Kind
+}
+
+func runScrub(a *app, args []string) error {
+	fs := a.newFlagSet("scrub", "shootlog scrub [--config file] [--input file | --dir dir] [--out-dir dir | --force] [--report path [--report-values]]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	reportPath := fs.String("report", "", "file to write a JSON report of the tags removed or altered in each file to")
+	reportValues := fs.Bool("report-values", false, "include the original values of the changed tags in the report")
+	configPath := fs.String("config", "", "config file (default $"+config.EnvPath+" or shootlog/config.yaml in the user config directory)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Privacy.HomeZones) == 0 {
+		return errors.New("no home zones: add privacy.home_zones to the config file")
+	}
+	if *reportValues && *reportPath == "" {
+		return errors.New("--report-values needs --report")
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	report := &scrubReport{DryRun: out.dryRun(), Files: []scrubbedFile{}, Tags: []scrubTagCount{}}
+	verb := "redact"
+	if cfg.Privacy.Action == privacy.Coarsen {
+		verb = "coarsen"
+	}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		scrubbed, zone, err := cfg.Privacy.Scrub(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if zone == nil {
+			continue
+		}
+		f := scrubbedFile{Path: p, Zone: zone.Name}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would %s %s: in %s\n", verb, p, zone.Name)
+		} else {
+			dst, err := out.write(p, data, scrubbed)
+			if err != nil {
+				return err
+			}
+			if dst != p {
+				f.Output = dst
+			}
+			fmt.Fprintf(a.stdout, "%sed %s: in %s\n", verb, dst, zone.Name)
+		}
+		if *reportPath != "" {
+			changes, err := exif.Diff(data, scrubbed)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			report.add(f, changes, *reportValues)
+		}
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	if *reportPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(*reportPath, append(data, '\n'))
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runSearchPages writes an HTML page per photo of a library index into a
+// directory the desktop search indexes, mirroring the library below
+// --root, so that Spotlight and Windows Search find photos by the
+// metadata shootlog extracted. Pages are only rewritten when they change,
+// and pages of photos no longer in the index are removed.
+func runSearchPages(a *app, args []string) error {
+	fset := a.newFlagSet("search-pages", "shootlog search-pages --index path --out-dir dir [--root dir]")
+	path := fset.String("index", "", "index file written by shootlog index")
+	outDir := fset.String("out-dir", "", "directory to write the pages to, one the desktop search indexes")
+	root := fset.String("root", "", "library directory the pages mirror the layout of (default the directory holding every photo)")
+	if err := parse(fset, args); err != nil {
+		return err
+	}
+	if *path == "" || *outDir == "" {
+		return errors.New("--index and --out-dir are required")
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	ix, err := loadIndex(*path)
+	if err != nil {
+		return err
+	}
+	// Pages link to the photos relative to themselves.
+	paths := make([]string, len(ix.Files))
+	for i, f := range ix.Files {
+		if paths[i], err = filepath.Abs(f.Path); err != nil {
+			return err
+		}
+	}
+	if *root == "" {
+		*root = commonDir(paths)
+	}
+	if *root, err = filepath.Abs(*root); err != nil {
+		return err
+	}
+	out, err := filepath.Abs(*outDir)
+	if err != nil {
+		return err
+	}
+	pages := map[string]bool{}
+	written, unchanged := 0, 0
+	for i, f := range ix.Files {
+		s := f.Summary
+		if s == nil {
+			continue
+		}
+		rel, err := filepath.Rel(*root, paths[i])
+		if err != nil || !below(rel) {
+			return fmt.Errorf("%s is not below --root %s", f.Path, *root)
+		}
+		page := filepath.Join(out, rel+".html")
+		pages[page] = true
+		image, err := filepath.Rel(filepath.Dir(page), paths[i])
+		if err != nil {
+			image = paths[i]
+		}
+		cfg.Privacy.Protect(s)
+		var buf bytes.Buffer
+		if err := report.WriteSearchPage(&buf, s, (&url.URL{Path: filepath.ToSlash(image)}).String()); err != nil {
+			return err
+		}
+		if old, err := os.ReadFile(page); err == nil && bytes.Equal(old, buf.Bytes()) {
+			unchanged++
+			continue
+		}
+		written++
+		if err := os.MkdirAll(filepath.Dir(page), 0o755); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(page, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	removed, err := pruneSearchPages(out, pages)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "%d pages written, %d unchanged, %d removed in %s\n", written, unchanged, removed, out)
+	return nil
+}
+
+// pruneSearchPages removes the pages below dir that shootlog wrote for
+// photos no longer in keep. Other HTML files are left alone.
+func pruneSearchPages(dir string, keep map[string]bool) (int, error) {
+	removed := 0
+	marker := []byte(`<meta name="generator" content="` + report.SearchGenerator + `">`)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == dir {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".html") || keep[p] {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil || !bytes.Contains(data, marker) {
+			return err
+		}
+		removed++
+		return os.Remove(p)
+	})
+	return removed, err
+}
+
+// commonDir returns the deepest directory holding every path.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return "."
+	}
+	dir := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for dir != filepath.Dir(dir) {
+			if rel, err := filepath.Rel(dir, p); err == nil && below(rel) {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// below reports whether a relative path stays inside its base.
+func below(rel string) bool {
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
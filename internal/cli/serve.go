@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/server"
+)
+
+func runServe(a *app, args []string) error {
+	fs := a.newFlagSet("serve", "shootlog serve [--addr host:port] [--max-upload bytes] [--timeout 30s] [--max-concurrent n] [--shutdown-timeout 10s] [--drain-delay 0s] [--log-format json|text] [--tls-cert file --tls-key file [--client-ca file]] [--sink url]... [--debug]")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	maxUpload := fs.Int64("max-upload", server.DefaultMaxUploadBytes, "largest accepted upload in bytes")
+	maxSegment := fs.Int("max-segment", 16<<20, "largest JPEG segment or tag value in bytes")
+	maxTags := fs.Int("max-tags", 10000, "most IFD entries parsed per image, maker notes included")
+	timeout := fs.Duration("timeout", server.DefaultTimeout, "time limit of each request")
+	maxConcurrent := fs.Int("max-concurrent", server.DefaultMaxConcurrent, "requests handled at once; more are answered with 503")
+	shutdownTimeout := fs.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "time to let requests in flight finish on SIGINT or SIGTERM")
+	drainDelay := fs.Duration("drain-delay", 0, "time to keep serving with /readyz failing before shutting down")
+	logFormat := fs.String("log-format", "json", "access log format on stderr: json or text")
+	certFile := fs.String("tls-cert", "", "serve HTTPS with this PEM certificate chain")
+	keyFile := fs.String("tls-key", "", "PEM private key of --tls-cert")
+	clientCA := fs.String("client-ca", "", "require client certificates signed by the CAs in this PEM file (mutual TLS)")
+	var sinks sinkFlags
+	sinks.register(fs)
+	defer sinks.Close()
+	debug := fs.Bool("debug", false, "serve net/http/pprof under /debug/pprof/ and runtime/trace toggles under /debug/trace/, with the same tokens as /summary")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	var handler slog.Handler
+	switch *logFormat {
+	case "json":
+		handler = slog.NewJSONHandler(a.stderr, nil)
+	case "text":
+		handler = slog.NewTextHandler(a.stderr, nil)
+	default:
+		return fmt.Errorf("unknown log format %q", *logFormat)
+	}
+	for name, v := range map[string]int64{"max-upload": *maxUpload, "max-segment": int64(*maxSegment), "max-tags": int64(*maxTags), "max-concurrent": int64(*maxConcurrent)} {
+		if v <= 0 {
+			return fmt.Errorf("invalid --%s %d", name, v)
+		}
+	}
+	if *timeout <= 0 || *shutdownTimeout <= 0 || *drainDelay < 0 {
+		return fmt.Errorf("invalid --timeout %s, --shutdown-timeout %s or --drain-delay %s", *timeout, *shutdownTimeout, *drainDelay)
+	}
+	tlsConfig, err := serverTLS(*certFile, *keyFile, *clientCA)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	if len(cfg.Serve.Tokens) == 0 && *clientCA == "" {
+		fmt.Fprintln(a.stderr, "shootlog: warning: no tokens in the config file and no --client-ca; /summary is open to anyone who can reach it")
+		if *debug {
+			fmt.Fprintln(a.stderr, "shootlog: warning: so are the --debug profiling endpoints, which reveal the command line and memory contents")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &server.Server{
+		Tokens:          cfg.Serve.Tokens,
+		TLS:             tlsConfig,
+		MaxUploadBytes:  *maxUpload,
+		Limits:          exif.Limits{MaxSegmentBytes: *maxSegment, MaxTagCount: *maxTags},
+		Timeout:         *timeout,
+		MaxConcurrent:   *maxConcurrent,
+		ShutdownTimeout: *shutdownTimeout,
+		DrainDelay:      *drainDelay,
+		Protect:         func(s *exif.Summary) { cfg.Privacy.Protect(s) },
+		Log:             slog.New(handler),
+		Debug:           *debug,
+	}
+	if len(sinks.multi) > 0 {
+		s.Sink = sinks.multi
+	}
+	return s.ListenAndServe(ctx, *addr)
+}
+
+// serverTLS loads the TLS configuration of the flags, or nil for plain
+// HTTP.
+func serverTLS(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCA != "" {
+			return nil, errors.New("--client-ca needs --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("--tls-cert and --tls-key go together")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates", clientCA)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
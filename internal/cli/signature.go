@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/sshsig"
+)
+
+// runVerifyManifest checks a manifest signed by batch --sign-key. The
+// signature is in the ssh-keygen -Y sign format, so recipients without
+// shootlog can check it with ssh-keygen -Y verify -n and sshsig.Namespace.
+func runVerifyManifest(a *app, args []string) error {
+	fs := a.newFlagSet("verify-manifest", "shootlog verify-manifest --manifest path --public-key path [--signature path]")
+	manifest := fs.String("manifest", "", "signed manifest")
+	signature := fs.String("signature", "", "signature file (default the manifest path plus .sig)")
+	publicKey := fs.String("public-key", "", "signer's Ed25519 public key, or a file of allowed keys one per line in authorized_keys form")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *manifest == "" || *publicKey == "" {
+		return errors.New("--manifest and --public-key are required")
+	}
+	if *signature == "" {
+		*signature = *manifest + ".sig"
+	}
+	keys, err := sshsig.LoadPublicKeys(*publicKey)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*manifest)
+	if err != nil {
+		return err
+	}
+	sig, err := os.ReadFile(*signature)
+	if err != nil {
+		return err
+	}
+	signer, err := sshsig.Verify(keys, sshsig.Namespace, data, sig)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *manifest, err)
+	}
+	fmt.Fprintf(a.stdout, "%s: good signature by %s\n", *manifest, sshsig.Fingerprint(signer))
+	return nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/ryoh827/shootlog/internal/sink"
+)
+
+// sinkFlags collect the sinks of repeated --sink flags, which receive
+// every summary a command produces.
+type sinkFlags struct {
+	multi sink.Multi
+}
+
+func (f *sinkFlags) register(fs *flag.FlagSet) {
+	fs.Func("sink", "send each summary to a webhook (http[s]://...), NATS subject (nats://host:port/subject) or Kafka REST proxy (kafka+http[s]://host/topics/name); repeatable", func(v string) error {
+		k, err := sink.Open(v)
+		if err == nil {
+			f.multi = append(f.multi, k)
+		}
+		return err
+	})
+}
+
+// Close closes the sinks opened.
+func (f *sinkFlags) Close() error {
+	return f.multi.Close()
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSinkFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		sinks int
+		err   string
+	}{
+		{"none", nil, 0, ""},
+		{"repeated", []string{"--sink", "https://example.com/hook", "--sink", "kafka+http://localhost:8082/topics/photos"}, 2, ""},
+		{"unknown scheme", []string{"--sink", "ftp://example.com/"}, 0, `unsupported scheme "ftp"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			var sinks sinkFlags
+			sinks.register(fs)
+			defer sinks.Close()
+			err := fs.Parse(tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Parse = %v, want an error with %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || len(sinks.multi) != tt.sinks {
+				t.Errorf("Parse = %v with %d sinks, want %d", err, len(sinks.multi), tt.sinks)
+			}
+		})
+	}
+}
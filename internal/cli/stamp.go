@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/policy"
+)
+
+func runStamp(a *app, args []string) error {
+	fs := a.newFlagSet("stamp", "shootlog stamp [--artist name] [--copyright text] [--caption text] [--creator-tool name] [--codes file] [--input file | --dir dir] [--overwrite] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	artist := fs.String("artist", "", "Artist to write; may use {year} and other summary fields")
+	copyright := fs.String("copyright", "", "Copyright to write, e.g. \"© {year} Name\"")
+	caption := fs.String("caption", "", "caption to write to ImageDescription and, in JPEG files, IPTC Caption-Abstract")
+	codesFile := fs.String("codes", "", "code replacement file expanding {code} placeholders, in Photo Mechanic's tab-separated format")
+	creatorTool := fs.String("creator-tool", "", "XMP CreatorTool to write (JPEG only; existing values are kept)")
+	overwrite := fs.Bool("overwrite", false, "replace values that are already present instead of keeping them")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *artist == "" && *copyright == "" && *caption == "" && *creatorTool == "" {
+		return errors.New("nothing to stamp: pass --artist, --copyright, --caption or --creator-tool")
+	}
+	var codes policy.Codes
+	if *codesFile != "" {
+		var err error
+		if codes, err = policy.LoadCodes(*codesFile); err != nil {
+			return err
+		}
+		for _, t := range []struct{ flag, tmpl string }{
+			{"artist", *artist}, {"copyright", *copyright}, {"caption", *caption}, {"creator-tool", *creatorTool},
+		} {
+			if unknown := codes.Unknown(t.tmpl); len(unknown) > 0 {
+				return fmt.Errorf("--%s: unknown code %s", t.flag, strings.Join(unknown, ", "))
+			}
+		}
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		// Files without EXIF get a fresh segment from exif.Apply.
+		s, err := exif.DecodeBytes(data)
+		if err != nil && !errors.Is(err, exif.ErrNoExif) {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if s == nil {
+			s = &exif.Summary{}
+		}
+		var edits []exif.Edit
+		var changes []string
+		set := func(field string, tag uint16, tmpl, current string) bool {
+			if tmpl == "" || (current != "" && !*overwrite) {
+				return false
+			}
+			v := codes.Expand(tmpl, s)
+			if v == current {
+				return false
+			}
+			edits = append(edits, exif.SetASCII(tag, v))
+			changes = append(changes, fmt.Sprintf("%s=%q", field, v))
+			return true
+		}
+		set("artist", exif.TagArtist, *artist, s.Artist)
+		set("copyright", exif.TagCopyright, *copyright, s.Copyright)
+		// The caption goes to IPTC too, where news and sports workflows
+		// read it.
+		var iptc exif.IPTC
+		if set("description", exif.TagImageDescription, *caption, s.Description) && exif.IsJPEG(data) {
+			iptc = exif.IPTC{exif.IPTCCaption: {codes.Expand(*caption, s)}}
+		}
+
+		var packet []byte
+		// An existing CreatorTool is always kept, even with --overwrite:
+		// properties inside packets written by other software are only
+		// added, never rewritten.
+		if *creatorTool != "" && exif.IsJPEG(data) {
+			if current := exif.XMP(data); !exif.HasXMPProperty(current, "CreatorTool") {
+				v := codes.Expand(*creatorTool, s)
+				if packet, err = exif.AddXMPProperty(current, exif.NamespaceXMP, "xmp", "CreatorTool", v); err != nil {
+					return fmt.Errorf("%s: %w", p, err)
+				}
+				changes = append(changes, fmt.Sprintf("creator_tool=%q", v))
+			}
+		}
+
+		if len(changes) == 0 {
+			fmt.Fprintf(a.stdout, "skipped %s: already stamped\n", p)
+			continue
+		}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would stamp %s: %s\n", p, strings.Join(changes, " "))
+			continue
+		}
+		stamped := data
+		if len(edits) > 0 {
+			if stamped, err = exif.Apply(stamped, edits...); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		if iptc != nil {
+			if stamped, err = exif.SetIPTC(stamped, iptc); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		if packet != nil {
+			if stamped, err = exif.SetXMP(stamped, packet); err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+		}
+		dst, err := out.write(p, data, stamped)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "stamped %s: %s\n", dst, strings.Join(changes, " "))
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// tagJSON is a known tag as listed by tags --output json.
+type tagJSON struct {
+	IFD         string   `json:"ifd"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Types       []string `json:"types"`
+	Count       uint32   `json:"count,omitempty"`
+	Description string   `json:"description"`
+	Fields      []string `json:"fields,omitempty"`
+	// Values names enumerated values by their value.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+func runTags(a *app, args []string) error {
+	fs := a.newFlagSet("tags", "shootlog tags [--output text|json] [search]")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	// Flags may also follow the search term.
+	term := fs.Arg(0)
+	if fs.NArg() > 0 {
+		if err := parse(fs, fs.Args()[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("tags takes one search term, got %q and %q", term, fs.Arg(0))
+		}
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	search := strings.ToLower(term)
+
+	var list []tagJSON
+	for _, t := range exif.Tags() {
+		var types []string
+		for _, typ := range t.Types {
+			types = append(types, typ.String())
+		}
+		j := tagJSON{
+			IFD: t.IFD.String(), ID: fmt.Sprintf("0x%04X", t.ID), Name: t.Name,
+			Types: types, Count: t.Count, Description: t.Description, Fields: t.Fields,
+		}
+		for _, v := range t.Values {
+			if j.Values == nil {
+				j.Values = map[string]string{}
+			}
+			j.Values[v.Value] = v.Name
+		}
+		if search != "" && !matchesTag(j, t.ID, search) {
+			continue
+		}
+		list = append(list, j)
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no tags match %q", term)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	fmt.Fprintf(a.stdout, "%-8s %-6s  %-28s %-15s %-5s  %-20s %s\n", "IFD", "ID", "Name", "Type", "Count", "Field", "Description")
+	for _, t := range list {
+		count := "any"
+		if t.Count != 0 {
+			count = strconv.Itoa(int(t.Count))
+		}
+		fmt.Fprintf(a.stdout, "%-8s %-6s  %-28s %-15s %-5s  %-20s %s\n",
+			t.IFD, t.ID, t.Name, strings.Join(t.Types, "|"), count, strings.Join(t.Fields, ","), t.Description)
+	}
+	return nil
+}
+
+// matchesTag reports whether search, in lower case, is part of the tag's
+// ID in hex or decimal, its directory, name, description or fields.
+func matchesTag(t tagJSON, id uint16, search string) bool {
+	if search == strings.ToLower(t.ID) || search == strconv.Itoa(int(id)) {
+		return true
+	}
+	for _, s := range append([]string{t.IFD, t.Name, t.Description}, t.Fields...) {
+		if strings.Contains(strings.ToLower(s), search) {
+			return true
+		}
+	}
+	return false
+}
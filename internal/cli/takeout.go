@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/takeout"
+)
+
+func runTakeoutMerge(a *app, args []string) error {
+	fs := a.newFlagSet("takeout-merge", "shootlog takeout-merge [--input file | --dir dir] [--tz zone] [--overwrite] [--out-dir dir | --force]")
+	var in inputFlags
+	in.register(fs)
+	var out outputFlags
+	out.register(fs, &in)
+	tz := fs.String("tz", "Local", "time zone capture times are written in, e.g. Asia/Tokyo (Takeout records UTC)")
+	overwrite := fs.Bool("overwrite", false, "replace values the image already has instead of keeping them")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("invalid --tz: %w", err)
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		jsonPath, ok := takeout.Find(p)
+		if !ok {
+			fmt.Fprintf(a.stdout, "skipped %s: no sidecar\n", p)
+			continue
+		}
+		sc, err := takeout.Load(jsonPath)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		s, err := exif.DecodeBytes(data)
+		if err != nil && !errors.Is(err, exif.ErrNoExif) {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if s == nil {
+			s = &exif.Summary{}
+		}
+		edits, changes := sc.Edits(s, loc, *overwrite)
+		if len(edits) == 0 {
+			fmt.Fprintf(a.stdout, "skipped %s: nothing to restore\n", p)
+			continue
+		}
+		if out.dryRun() {
+			fmt.Fprintf(a.stdout, "would merge %s: %s\n", p, strings.Join(changes, " "))
+			continue
+		}
+		merged, err := exif.Apply(data, edits...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		dst, err := out.write(p, data, merged)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "merged %s: %s\n", dst, strings.Join(changes, " "))
+	}
+	if out.dryRun() {
+		a.dryRunNote()
+	}
+	return nil
+}
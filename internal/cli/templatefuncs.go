@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+)
+
+// runTemplateFuncs documents the functions of the Go templates shootlog
+// runs.
+func runTemplateFuncs(a *app, args []string) error {
+	fs := a.newFlagSet("template-funcs", "shootlog template-funcs [--output text|json]")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tmplfunc.Library)
+	case "text":
+		for _, f := range tmplfunc.Library {
+			fmt.Fprintf(a.stdout, "%s\n    %s\n", f.Usage, f.Text)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", *output)
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// runTrends compares the shooting of two periods of a library, or of two
+// directories: volume, cameras, lenses, focal lengths and ISO.
+func runTrends(a *app, args []string) error {
+	fs := a.newFlagSet("trends", "shootlog trends --a dir|period --b dir|period [--index path | --dir dir] [--filter expr] [--output text|json|svg] [--lang en|ja]")
+	sideA := fs.String("a", "", "directory, or with --index or --dir a period: 2023, 2023-06, 2023-06-01 or from..to")
+	sideB := fs.String("b", "", "directory or period to compare with --a")
+	indexPath := fs.String("index", "", "index file holding the periods' photos")
+	dir := fs.String("dir", "", "directory to scan recursively for the periods' photos")
+	output := fs.String("output", "text", "output format: text, json or svg")
+	lang := fs.String("lang", "", "language of the text and svg output: "+strings.Join(locale.Tags(), ", ")+" (default from $LC_ALL, $LC_MESSAGES or $LANG)")
+	var where filterFlag
+	where.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *sideA == "" || *sideB == "" {
+		return errors.New("--a and --b are required")
+	}
+	if *indexPath != "" && *dir != "" {
+		return errors.New("--index and --dir are mutually exclusive")
+	}
+	if *output != "text" && *output != "json" && *output != "svg" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	loc := locale.Detect()
+	if *lang != "" {
+		var err error
+		if loc, err = locale.Get(*lang); err != nil {
+			return err
+		}
+	}
+
+	// With a library, --a and --b are periods of it; otherwise each is a
+	// directory of its own.
+	var library []*exif.Summary
+	switch {
+	case *indexPath != "":
+		ix, err := loadIndex(*indexPath)
+		if err != nil {
+			return err
+		}
+		library = ix.Summaries()
+	case *dir != "":
+		paths, err := scanDir(*dir)
+		if err != nil {
+			return err
+		}
+		if library, err = a.decodeAll(paths); err != nil {
+			return err
+		}
+	}
+	side := func(flag, v string) ([]*exif.Summary, error) {
+		if *indexPath == "" && *dir == "" {
+			paths, err := scanDir(v)
+			if err != nil {
+				return nil, err
+			}
+			summaries, err := a.decodeAll(paths)
+			return where.apply(summaries), err
+		}
+		p, err := parsePeriod(v)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %w", flag, err)
+		}
+		var out []*exif.Summary
+		for _, s := range where.apply(library) {
+			if p.contains(s.DateTimeOriginal) {
+				out = append(out, s)
+			}
+		}
+		return out, nil
+	}
+	photosA, err := side("a", *sideA)
+	if err != nil {
+		return err
+	}
+	photosB, err := side("b", *sideB)
+	if err != nil {
+		return err
+	}
+	if len(photosA) == 0 || len(photosB) == 0 {
+		fmt.Fprintf(a.stderr, "shootlog: %d photos in %s and %d in %s\n", len(photosA), *sideA, len(photosB), *sideB)
+	}
+	t := report.NewTrends(*sideA, photosA, *sideB, photosB)
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	case "svg":
+		return t.WriteSVG(a.stdout, loc)
+	}
+	return t.WriteText(a.stdout, loc)
+}
+
+// period is a span of days, each end a year, month or day in the form of
+// the leading part of DateTimeOriginal.
+type period struct {
+	from, to string
+}
+
+// parsePeriod parses 2023, 2023-06, 2023-06-01 or two of those joined by
+// "..", inclusive.
+func parsePeriod(v string) (period, error) {
+	from, to, ok := strings.Cut(v, "..")
+	if !ok {
+		to = from
+	}
+	for _, end := range []string{from, to} {
+		valid := false
+		for _, layout := range []string{"2006", "2006-01", "2006-01-02"} {
+			if _, err := time.Parse(layout, end); err == nil && len(end) == len(layout) {
+				valid = true
+			}
+		}
+		if !valid {
+			return period{}, fmt.Errorf("invalid period %q (want 2023, 2023-06, 2023-06-01 or from..to)", v)
+		}
+	}
+	if n := min(len(from), len(to)); from[:n] > to[:n] {
+		return period{}, fmt.Errorf("period %q ends before it starts", v)
+	}
+	return period{from, to}, nil
+}
+
+// contains reports whether the capture time datetime, as formatted in
+// Summary.DateTimeOriginal, falls in p.
+func (p period) contains(datetime string) bool {
+	if len(datetime) < len("2006-01-02") {
+		return false
+	}
+	return datetime[:len(p.from)] >= p.from && datetime[:len(p.to)] <= p.to
+}
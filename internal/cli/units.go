@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/report"
+)
+
+// unitsFlag selects the unit system distances are printed in.
+type unitsFlag struct {
+	value string
+}
+
+func (f *unitsFlag) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.value, "units", "", "units for altitude and distances: "+strings.Join(report.UnitSystems, " or ")+" (default from the config file, else metric)")
+}
+
+// resolve returns the flag value, or the units set in cfg.
+func (f *unitsFlag) resolve(cfg *config.Config) (string, error) {
+	if f.value != "" {
+		if !slices.Contains(report.UnitSystems, f.value) {
+			return "", fmt.Errorf("unknown units %q", f.value)
+		}
+		return f.value, nil
+	}
+	return cfg.Units, nil
+}
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// validation is the validate result for a single file.
+type validation struct {
+	Path       string           `json:"path"`
+	Violations []exif.Violation `json:"violations"`
+}
+
+func runValidate(a *app, args []string) error {
+	fs := a.newFlagSet("validate", "shootlog validate [--input file | --dir dir] [--strict] [--output text|json]")
+	var in inputFlags
+	in.register(fs)
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	output := fs.String("output", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	paths, err := in.paths()
+	if err != nil {
+		return err
+	}
+
+	threshold := exif.SeverityError
+	if *strict {
+		threshold = exif.SeverityWarning
+	}
+	var results []validation
+	failed := 0
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		r := validation{Path: p, Violations: exif.Validate(data)}
+		if r.Violations == nil {
+			r.Violations = []exif.Violation{}
+		}
+		for _, v := range r.Violations {
+			if v.Severity >= threshold {
+				failed++
+				break
+			}
+		}
+		results = append(results, r)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if len(r.Violations) == 0 {
+				fmt.Fprintf(a.stdout, "%s: ok\n", r.Path)
+				continue
+			}
+			fmt.Fprintf(a.stdout, "%s:\n", r.Path)
+			for _, v := range r.Violations {
+				fmt.Fprintf(a.stdout, "  %s\n", v)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed validation", failed, len(results))
+	}
+	return nil
+}
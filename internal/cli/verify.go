@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/index"
+)
+
+// runVerify checks a library against the hashes in its index, for archives
+// checked on a schedule: it prints only the files that failed and exits
+// with 1 when any did, so cron and systemd timers report them.
+func runVerify(a *app, args []string) error {
+	fs := a.newFlagSet("verify", "shootlog verify --index path [--output text|json] [--allow-modified]")
+	path := fs.String("index", "", "index file written by shootlog index")
+	output := fs.String("output", "text", "output format: text or json")
+	allowModified := fs.Bool("allow-modified", false, "do not fail on files edited since the index was updated")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("--index is required")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+	ix, err := loadIndex(*path)
+	if err != nil {
+		return err
+	}
+	checks := ix.Verify()
+	var failed []index.Check
+	counts := map[string]int{}
+	for _, c := range checks {
+		counts[c.Status]++
+		if c.Status == index.StatusOK || c.Status == index.StatusModified && *allowModified {
+			continue
+		}
+		failed = append(failed, c)
+	}
+	if *output == "json" {
+		if failed == nil {
+			failed = []index.Check{}
+		}
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(failed); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range failed {
+			if c.Error != "" {
+				fmt.Fprintf(a.stdout, "%-10s %s: %s\n", c.Status, c.Path, c.Error)
+			} else {
+				fmt.Fprintf(a.stdout, "%-10s %s\n", c.Status, c.Path)
+			}
+		}
+	}
+	fmt.Fprintf(a.stderr, "shootlog: verified %d files: %d ok, %d corrupt, %d modified, %d missing, %d unreadable\n",
+		len(checks), counts[index.StatusOK], counts[index.StatusCorrupt], counts[index.StatusModified], counts[index.StatusMissing], counts[index.StatusUnreadable])
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d files failed verification", len(failed), len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/report"
+	"github.com/ryoh827/shootlog/internal/watch"
+)
+
+func runWatch(a *app, args []string) error {
+	fs := a.newFlagSet("watch", "shootlog watch --dir dir [--interval 2s] [--existing] [--catalog path] [--filter expr] [--units metric|imperial] [--gps-format fmt] [--gps-precision n] [--exec cmd] [--sink url]...")
+	dir := fs.String("dir", "", "directory to watch recursively for images")
+	interval := fs.Duration("interval", 2*time.Second, "time between directory scans")
+	existing := fs.Bool("existing", false, "also process the images already present at startup")
+	var units unitsFlag
+	units.register(fs)
+	var cat catalogFlag
+	cat.register(fs)
+	var where filterFlag
+	where.register(fs)
+	var gps gpsFlags
+	gps.register(fs)
+	var hooks hookFlags
+	hooks.register(fs)
+	var sinks sinkFlags
+	sinks.register(fs)
+	defer sinks.Close()
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("--dir is required")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid --interval %s", *interval)
+	}
+	if err := gps.check(); err != nil {
+		return err
+	}
+	if err := hooks.parse(); err != nil {
+		return err
+	}
+	if err := where.parse(); err != nil {
+		return err
+	}
+	if err := cat.load(); err != nil {
+		return err
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		return err
+	}
+	system, err := units.resolve(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	w := &watch.Watcher{
+		List:     func() ([]string, error) { return scanDir(*dir) },
+		Interval: *interval,
+		Existing: *existing,
+	}
+	enc := json.NewEncoder(a.stdout)
+	// Each summary is printed as one JSON line as soon as its file is
+	// complete; errors are reported and watching continues.
+	return w.Run(ctx, func(path string) {
+		s, err := exif.DecodeFile(path)
+		if err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: skipping %v\n", err)
+			return
+		}
+		cat.apply(a, s)
+		s.Sources = nil
+		cfg.Privacy.Protect(s)
+		if !where.match(s) {
+			return
+		}
+		if err := report.ConvertUnits([]*exif.Summary{s}, system); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+			return
+		}
+		if err := gps.apply([]*exif.Summary{s}); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+			return
+		}
+		if err := enc.Encode(s); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+		}
+		if err := sinks.multi.Send(ctx, s); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %s: %v\n", path, err)
+		}
+		if err := hooks.run(ctx, a, s); err != nil {
+			fmt.Fprintf(a.stderr, "shootlog: %v\n", err)
+		}
+	})
+}
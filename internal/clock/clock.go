@@ -0,0 +1,193 @@
+// Package clock finds photos whose capture time a camera with a wrong
+// clock recorded, such as one reset to 2000-01-01 by a flat battery, and
+// suggests corrections.
+package clock
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// resetDates are the dates camera clocks fall back to when they lose
+// power: the Unix and DOS epochs and the turn of the millennium.
+var resetDates = []time.Time{
+	time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// ResetWindow is how long after a reset date a capture time is taken for
+// a reset clock still running.
+const ResetWindow = 31 * 24 * time.Hour
+
+// Bases of a Suggestion.
+const (
+	// BasisNeighbors shifts a run of frames to follow the frame before
+	// them in the body's file sequence, or to precede the one after.
+	BasisNeighbors = "neighbors"
+	// BasisMtime takes the file's modification time.
+	BasisMtime = "mtime"
+)
+
+// Frame is a photo and the modification time of its file.
+type Frame struct {
+	Summary *exif.Summary
+	ModTime time.Time
+}
+
+// Suspect is a photo with an implausible capture time.
+type Suspect struct {
+	Path string `json:"path"`
+	Body string `json:"body"`
+	// Recorded is the capture time as recorded, without its offset.
+	Recorded time.Time `json:"recorded"`
+	Reason   string    `json:"reason"`
+	// Suggestions are the corrections found, by basis.
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// Suggestion is a corrected capture time, in the wall clock of the
+// recorded one, and what it is based on.
+type Suggestion struct {
+	Basis  string    `json:"basis"`
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Get returns the suggestion of basis.
+func (s *Suspect) Get(basis string) (Suggestion, bool) {
+	for _, sg := range s.Suggestions {
+		if sg.Basis == basis {
+			return sg, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+// implausible returns why t, a capture wall time, cannot be right, or "".
+func implausible(t, now time.Time) string {
+	for _, d := range resetDates {
+		if !t.Before(d) && t.Sub(d) < ResetWindow {
+			return "clock reset to " + d.Format("2006-01-02")
+		}
+	}
+	if t.After(now.Add(24 * time.Hour)) {
+		return "in the future"
+	}
+	return ""
+}
+
+// Check finds the frames with implausible capture times. Frames are put in
+// sequence per body by file name; a run of suspects is shifted as a whole
+// so that it follows the frame before it, keeping the intervals between
+// its shots, which a reset clock still measures. now bounds plausible
+// times.
+func Check(frames []Frame, now time.Time) []Suspect {
+	type shot struct {
+		f       Frame
+		t       time.Time
+		reason  string
+		suspect bool
+	}
+	byBody := map[string][]shot{}
+	var bodies []string
+	for _, f := range frames {
+		t, ok := f.Summary.CaptureTime()
+		if !ok {
+			continue
+		}
+		b := Body(f.Summary)
+		if _, ok := byBody[b]; !ok {
+			bodies = append(bodies, b)
+		}
+		t = Wall(t)
+		reason := implausible(t, now)
+		byBody[b] = append(byBody[b], shot{f, t, reason, reason != ""})
+	}
+	sort.Strings(bodies)
+
+	var out []Suspect
+	for _, b := range bodies {
+		shots := byBody[b]
+		sort.SliceStable(shots, func(i, j int) bool {
+			bi, bj := filepath.Base(shots[i].f.Summary.Path), filepath.Base(shots[j].f.Summary.Path)
+			if bi != bj {
+				return bi < bj
+			}
+			return shots[i].f.Summary.Path < shots[j].f.Summary.Path
+		})
+		for i := 0; i < len(shots); {
+			if !shots[i].suspect {
+				i++
+				continue
+			}
+			end := i
+			for end < len(shots) && shots[end].suspect {
+				end++
+			}
+			// The run is shots[i:end]; move it next to a plausible neighbor.
+			var offset time.Duration
+			var detail string
+			switch {
+			case i > 0:
+				offset = shots[i-1].t.Add(time.Second).Sub(shots[i].t)
+				detail = "after " + filepath.Base(shots[i-1].f.Summary.Path)
+			case end < len(shots):
+				offset = shots[end].t.Add(-time.Second).Sub(shots[end-1].t)
+				detail = "before " + filepath.Base(shots[end].f.Summary.Path)
+			}
+			for _, sh := range shots[i:end] {
+				s := Suspect{Path: sh.f.Summary.Path, Body: b, Recorded: sh.t, Reason: sh.reason, Suggestions: []Suggestion{}}
+				if detail != "" {
+					s.Suggestions = append(s.Suggestions, Suggestion{Basis: BasisNeighbors, Time: sh.t.Add(offset), Detail: detail})
+				}
+				if m := modWall(sh.f); !m.IsZero() && implausible(m, now) == "" {
+					s.Suggestions = append(s.Suggestions, Suggestion{Basis: BasisMtime, Time: m})
+				}
+				out = append(out, s)
+			}
+			i = end
+		}
+	}
+	return out
+}
+
+// modWall is the modification time of a frame's file in the zone of its
+// capture time, or the local zone when none was recorded.
+func modWall(f Frame) time.Time {
+	if f.ModTime.IsZero() {
+		return time.Time{}
+	}
+	m := f.ModTime.Local()
+	if t, ok := f.Summary.CaptureTime(); ok && len(f.Summary.DateTimeOriginal) > len("2006-01-02T15:04:05") {
+		m = f.ModTime.In(t.Location())
+	}
+	return Wall(m)
+}
+
+// Wall returns the wall clock reading of t as a UTC time, so that capture
+// times with and without a recorded offset compare as the camera showed
+// them.
+func Wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// Body names the camera body of s: its make, model and serial number.
+func Body(s *exif.Summary) string {
+	name := strings.TrimSpace(s.Make + " " + s.Model)
+	serial := s.BodySerial
+	if serial == "" {
+		serial = s.InternalSerial
+	}
+	if serial != "" {
+		name = strings.TrimSpace(name + " #" + serial)
+	}
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Bases of an Estimate.
+const (
+	// BasisSync compares photos of the same moment taken with two bodies.
+	BasisSync = "sync"
+	// BasisGPS compares the capture time with the GPS time of the fix.
+	BasisGPS = "gps"
+)
+
+// Estimate is the clock offset of a body: the correction Offsets adds to
+// its capture times.
+type Estimate struct {
+	Body   string
+	Offset time.Duration
+	Basis  string
+	// Samples counts the photos compared; Offset is their median and
+	// Spread the difference between the largest and smallest, which grows
+	// when a clock drifts over the shoot.
+	Samples int
+	Spread  time.Duration
+	// Reference is the body a sync estimate is relative to.
+	Reference string
+}
+
+// SyncPair is two photos of the same moment, such as a clap or a flash
+// fired for both bodies: Reference from the body whose clock is trusted.
+type SyncPair struct {
+	Reference, Other *exif.Summary
+}
+
+// EstimateGPS estimates the offset of each body whose photos record GPS
+// time. Capture times without a UTC offset are taken to be in loc.
+func EstimateGPS(summaries []*exif.Summary, loc *time.Location) []Estimate {
+	samples := map[string][]time.Duration{}
+	for _, s := range summaries {
+		gps, err := time.Parse(time.RFC3339, s.GPSTime)
+		if err != nil {
+			continue
+		}
+		t, ok := s.CaptureTime()
+		if !ok {
+			continue
+		}
+		if len(s.DateTimeOriginal) <= len("2006-01-02T15:04:05") {
+			w := Wall(t)
+			t = time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc)
+		}
+		b := Body(s)
+		samples[b] = append(samples[b], gps.Sub(t))
+	}
+	var out []Estimate
+	for b, ds := range samples {
+		e := summarize(ds)
+		e.Body, e.Basis = b, BasisGPS
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Body < out[j].Body })
+	return out
+}
+
+// EstimateSync estimates the offset of each body's clock from the
+// reference bodies' in pairs. When a reference body has an estimate in
+// base, such as from GPS, the offset is relative to true time instead.
+func EstimateSync(pairs []SyncPair, base []Estimate) ([]Estimate, error) {
+	type key struct{ body, ref string }
+	samples := map[key][]time.Duration{}
+	var keys []key
+	for _, p := range pairs {
+		rt, ok1 := p.Reference.CaptureTime()
+		ot, ok2 := p.Other.CaptureTime()
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("sync shots %s and %s both need a capture time", p.Reference.Path, p.Other.Path)
+		}
+		k := key{Body(p.Other), Body(p.Reference)}
+		if k.body == k.ref {
+			return nil, fmt.Errorf("sync shots %s and %s are from the same body", p.Reference.Path, p.Other.Path)
+		}
+		if _, ok := samples[k]; !ok {
+			keys = append(keys, k)
+		}
+		samples[k] = append(samples[k], Wall(rt).Sub(Wall(ot)))
+	}
+	var out []Estimate
+	for _, k := range keys {
+		e := summarize(samples[k])
+		e.Body, e.Basis, e.Reference = k.body, BasisSync, k.ref
+		for _, b := range base {
+			if b.Body == k.ref {
+				e.Offset += b.Offset
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// summarize returns the median and spread of samples, rounded to the
+// second.
+func summarize(ds []time.Duration) Estimate {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	mid := ds[len(ds)/2]
+	if len(ds)%2 == 0 {
+		mid = (ds[len(ds)/2-1] + mid) / 2
+	}
+	return Estimate{Offset: mid.Round(time.Second), Samples: len(ds), Spread: (ds[len(ds)-1] - ds[0]).Round(time.Second)}
+}
@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Offsets are the corrections added to the capture times of bodies whose
+// clocks ran fast or slow, keyed by model, serial number or the name Body
+// gives.
+type Offsets map[string]time.Duration
+
+// Set parses and adds one "body=offset" correction, such as
+// X-T5=+00:02:13 for a body 2 minutes 13 seconds slow. The offset is
+// [+-]hh:mm:ss or a Go duration such as -90s.
+func (o Offsets) Set(v string) error {
+	body, off, ok := strings.Cut(v, "=")
+	body = strings.TrimSpace(body)
+	if !ok || body == "" {
+		return fmt.Errorf("invalid offset %q (want body=+hh:mm:ss)", v)
+	}
+	d, err := ParseOffset(strings.TrimSpace(off))
+	if err != nil {
+		return err
+	}
+	o[body] = d
+	return nil
+}
+
+// ParseOffset parses [+-]hh:mm:ss, [+-]mm:ss or a Go duration.
+func ParseOffset(v string) (time.Duration, error) {
+	if !strings.Contains(v, ":") {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", v)
+		}
+		return d, nil
+	}
+	sign := time.Duration(1)
+	rest := v
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	} else {
+		rest = strings.TrimPrefix(rest, "+")
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid offset %q", v)
+	}
+	var d time.Duration
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		// Only the leading field may exceed 59.
+		if err != nil || n < 0 || i > 0 && n > 59 {
+			return 0, fmt.Errorf("invalid offset %q", v)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	return sign * d * time.Second, nil
+}
+
+// FormatOffset formats d as +hh:mm:ss.
+func FormatOffset(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	s := int64(d / time.Second)
+	return fmt.Sprintf("%s%02d:%02d:%02d", sign, s/3600, s/60%60, s%60)
+}
+
+// For returns the correction of the body of s: the offset keyed by its
+// Body name, serial number, model, or make and model, in that order.
+func (o Offsets) For(s *exif.Summary) (time.Duration, bool) {
+	for _, k := range []string{Body(s), s.BodySerial, s.InternalSerial, s.Model, strings.TrimSpace(s.Make + " " + s.Model)} {
+		if d, ok := o[k]; ok && k != "" {
+			return d, true
+		}
+	}
+	return 0, false
+}
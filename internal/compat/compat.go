@@ -0,0 +1,240 @@
+// Package compat compares shootlog summaries with exiftool output for the
+// same files and reports where the two disagree.
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// field maps a Summary field to the exiftool tag reporting the same value.
+type field struct {
+	name string
+	tag  string
+	// tol is the absolute tolerance for numeric comparison; 0 compares
+	// strings exactly.
+	tol float64
+}
+
+// fields lists the overlapping fields. Tags are requested with -n so
+// numeric values are not formatted by exiftool.
+var fields = []field{
+	{"make", "Make", 0},
+	{"model", "Model", 0},
+	{"lens_make", "LensMake", 0},
+	{"lens_model", "LensModel", 0},
+	{"software", "Software", 0},
+	{"description", "ImageDescription", 0},
+	{"artist", "Artist", 0},
+	{"copyright", "Copyright", 0},
+	{"datetime_original", "DateTimeOriginal", 0},
+	{"exposure_time", "ExposureTime", 1e-6},
+	{"f_number", "FNumber", 0.05},
+	{"iso", "ISO", 0.5},
+	{"exposure_bias", "ExposureCompensation", 0.01},
+	{"focal_length", "FocalLength", 0.05},
+	{"focal_length_35mm", "FocalLengthIn35mmFormat", 0.5},
+	{"width", "ExifImageWidth", 0.5},
+	{"height", "ExifImageHeight", 0.5},
+	{"orientation", "Orientation", 0.5},
+	{"latitude", "GPSLatitude", 1e-5},
+	{"longitude", "GPSLongitude", 1e-5},
+	{"altitude", "GPSAltitude", 0.05},
+}
+
+// Mismatch is an example of a disagreement.
+type Mismatch struct {
+	Path     string `json:"path"`
+	Shootlog string `json:"shootlog"`
+	Exiftool string `json:"exiftool"`
+}
+
+// FieldReport aggregates the comparison of one field across the corpus.
+type FieldReport struct {
+	Field        string     `json:"field"`
+	Tag          string     `json:"exiftool_tag"`
+	Compared     int        `json:"compared"`
+	Matched      int        `json:"matched"`
+	Mismatched   int        `json:"mismatched"`
+	OnlyShootlog int        `json:"only_shootlog"`
+	OnlyExiftool int        `json:"only_exiftool"`
+	Examples     []Mismatch `json:"examples,omitempty"`
+}
+
+// Report is the compatibility report for a corpus.
+type Report struct {
+	Files  int           `json:"files"`
+	Fields []FieldReport `json:"fields"`
+}
+
+// Failed reports whether any field disagreed or was missing on one side.
+func (r *Report) Failed() bool {
+	for _, f := range r.Fields {
+		if f.Mismatched > 0 || f.OnlyShootlog > 0 || f.OnlyExiftool > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// maxExamples bounds the examples kept per field.
+const maxExamples = 3
+
+// batchSize is the number of files passed to one exiftool invocation.
+const batchSize = 100
+
+// Runner invokes exiftool.
+type Runner struct {
+	// Exiftool is the executable to run; "exiftool" when empty.
+	Exiftool string
+}
+
+// Compare runs exiftool over the files backing summaries and diffs the
+// overlapping fields.
+func (r Runner) Compare(ctx context.Context, summaries []*exif.Summary) (*Report, error) {
+	bin := r.Exiftool
+	if bin == "" {
+		bin = "exiftool"
+	}
+	byPath := map[string]map[string]any{}
+	for start := 0; start < len(summaries); start += batchSize {
+		end := min(start+batchSize, len(summaries))
+		args := []string{"-json", "-n", "-OffsetTimeOriginal"}
+		for _, f := range fields {
+			if strings.HasPrefix(f.tag, "GPS") {
+				// Composite GPS tags carry the hemisphere sign.
+				args = append(args, "-Composite:"+f.tag)
+			} else {
+				args = append(args, "-"+f.tag)
+			}
+		}
+		for _, s := range summaries[start:end] {
+			args = append(args, s.Path)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, bin, args...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		// exiftool exits non-zero when some files are unreadable but still
+		// prints results for the rest.
+		if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+			return nil, fmt.Errorf("compat: running %s: %v: %s", bin, err, strings.TrimSpace(stderr.String()))
+		}
+		var records []map[string]any
+		if err := json.Unmarshal(stdout.Bytes(), &records); err != nil {
+			return nil, fmt.Errorf("compat: decoding exiftool output: %w", err)
+		}
+		for _, rec := range records {
+			if p, ok := rec["SourceFile"].(string); ok {
+				byPath[p] = rec
+			}
+		}
+	}
+	return diff(summaries, byPath), nil
+}
+
+func diff(summaries []*exif.Summary, byPath map[string]map[string]any) *Report {
+	rep := &Report{Files: len(summaries)}
+	values := make([]map[string]any, len(summaries))
+	for i, s := range summaries {
+		values[i] = s.Fields()
+	}
+	for _, f := range fields {
+		fr := FieldReport{Field: f.name, Tag: f.tag}
+		for i, s := range summaries {
+			rec := byPath[s.Path]
+			ours, hasOurs := values[i][f.name]
+			theirs, hasTheirs := exiftoolValue(rec, f)
+			switch {
+			case !hasOurs && !hasTheirs:
+				continue
+			case !hasTheirs:
+				fr.OnlyShootlog++
+				fr.addExample(s.Path, ours, "")
+				continue
+			case !hasOurs:
+				fr.OnlyExiftool++
+				fr.addExample(s.Path, "", fmt.Sprint(theirs))
+				continue
+			}
+			fr.Compared++
+			if equal(ours, theirs, f.tol) {
+				fr.Matched++
+			} else {
+				fr.Mismatched++
+				fr.addExample(s.Path, fmt.Sprint(ours), fmt.Sprint(theirs))
+			}
+		}
+		rep.Fields = append(rep.Fields, fr)
+	}
+	return rep
+}
+
+func (fr *FieldReport) addExample(path string, ours any, theirs string) {
+	if len(fr.Examples) < maxExamples {
+		fr.Examples = append(fr.Examples, Mismatch{Path: path, Shootlog: fmt.Sprint(ours), Exiftool: theirs})
+	}
+}
+
+func exiftoolValue(rec map[string]any, f field) (any, bool) {
+	if rec == nil {
+		return nil, false
+	}
+	v, ok := rec[f.tag]
+	if !ok {
+		return nil, false
+	}
+	if f.name == "datetime_original" {
+		s, _ := v.(string)
+		t, err := time.Parse("2006:01:02 15:04:05", s)
+		if err != nil {
+			return s, true
+		}
+		out := t.Format("2006-01-02T15:04:05")
+		if off, ok := rec["OffsetTimeOriginal"].(string); ok {
+			out += off
+		}
+		return out, true
+	}
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s), s != ""
+	}
+	return v, true
+}
+
+func equal(ours, theirs any, tol float64) bool {
+	if tol == 0 {
+		return fmt.Sprint(ours) == fmt.Sprint(theirs)
+	}
+	a, ok1 := ours.(float64)
+	b, ok2 := theirs.(float64)
+	if !ok1 || !ok2 {
+		return fmt.Sprint(ours) == fmt.Sprint(theirs)
+	}
+	return math.Abs(a-b) <= tol
+}
+
+// WriteText renders the report as a table followed by mismatch examples.
+func (r *Report) WriteText(w io.Writer) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Files: %d\n", r.Files)
+	fmt.Fprintf(&b, "%-20s %8s %8s %8s %8s %8s\n", "field", "compared", "matched", "mismatch", "only-sl", "only-et")
+	for _, f := range r.Fields {
+		fmt.Fprintf(&b, "%-20s %8d %8d %8d %8d %8d\n", f.Field, f.Compared, f.Matched, f.Mismatched, f.OnlyShootlog, f.OnlyExiftool)
+	}
+	for _, f := range r.Fields {
+		for _, ex := range f.Examples {
+			fmt.Fprintf(&b, "%s %s: shootlog=%q exiftool=%q\n", f.Field, ex.Path, ex.Shootlog, ex.Exiftool)
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
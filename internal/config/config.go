@@ -0,0 +1,225 @@
+// Package config locates and loads the shootlog configuration file, a
+// YAML document whose sections configure individual commands.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+	"github.com/ryoh827/shootlog/internal/job"
+	"github.com/ryoh827/shootlog/internal/policy"
+	"github.com/ryoh827/shootlog/internal/privacy"
+	"github.com/ryoh827/shootlog/internal/risk"
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+	"github.com/ryoh827/shootlog/pkg/yaml"
+)
+
+// EnvPath names the environment variable that points at the config file.
+const EnvPath = "SHOOTLOG_CONFIG"
+
+// Config is the parsed configuration file.
+type Config struct {
+	Delivery Delivery `json:"delivery"`
+	// Policy holds the rules shootlog policy uses when no --policy file
+	// is given.
+	Policy policy.Policy `json:"policy"`
+	// Units is the unit system distances are printed in: "metric" or
+	// "imperial". The --units flag overrides it.
+	Units string `json:"units"`
+	// Privacy names the home zones whose coordinates are redacted or
+	// coarsened in output and by shootlog scrub.
+	Privacy privacy.Settings `json:"privacy"`
+	Serve   Serve            `json:"serve"`
+	// Queries are the saved searches, or smart collections, of shootlog
+	// query --saved: filter expressions by name.
+	Queries map[string]string `json:"queries"`
+	Archive Archive           `json:"archive"`
+	// Risk is the formula of shootlog risk.
+	Risk risk.Settings `json:"risk"`
+	// Jobs is the filing convention of shootlog jobs.
+	Jobs job.Settings `json:"jobs"`
+	// Analyze names the analyzers run after extraction by the main
+	// command and shootlog index; the --analyze flag overrides it.
+	Analyze []string `json:"analyze"`
+	// Reports is the directory of the user's report templates, chosen by
+	// name with shootlog report --template; see ReportsDir.
+	Reports string `json:"reports"`
+	// Fields selects the summary fields, by JSON name, the main command
+	// prints besides the path; empty prints them all. The --fields flag
+	// overrides it.
+	Fields []string `json:"fields"`
+	// Rename is the name template of shootlog renumber when no --prefix
+	// or --name is given, as --name takes it.
+	Rename string `json:"rename"`
+}
+
+// ReportsDir returns the directory of report templates: Reports, or by
+// default shootlog/reports under the user config directory, beside the
+// default config file.
+func (c *Config) ReportsDir() string {
+	if c.Reports != "" {
+		return c.Reports
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "shootlog", "reports")
+}
+
+// Archive configures chain-of-custody safeguards for an archive.
+type Archive struct {
+	// Log is the append-only provenance log recording every file and
+	// index shootlog writes, usually kept beside the index. Empty
+	// disables it.
+	Log string `json:"log"`
+	// ReadOnly refuses to rewrite files in place; modified copies can
+	// still be written with --out-dir.
+	ReadOnly bool `json:"read_only"`
+	// Trash is where the originals of files rewritten in place are kept
+	// for shootlog restore: a directory, or "os" for the desktop trash of
+	// Linux and BSD systems. Empty discards them.
+	Trash string `json:"trash"`
+}
+
+// Serve configures shootlog serve.
+type Serve struct {
+	// Tokens are the bearer tokens clients authenticate with. When any
+	// are set, requests without one of them are rejected.
+	Tokens []string `json:"tokens"`
+}
+
+// Delivery is the policy shootlog delivery checks exported files against.
+type Delivery struct {
+	// Require lists summary fields, by JSON name, that must be set. An
+	// entry of the form "a|b" is satisfied by any of its alternatives.
+	Require []string `json:"require"`
+	// ForbidGPS rejects files that carry coordinates.
+	ForbidGPS bool `json:"forbid_gps"`
+	// ColorSpace is the required color space, e.g. "sRGB". An embedded
+	// ICC profile takes precedence over the EXIF color space. Empty
+	// disables the check.
+	ColorSpace string `json:"color_space"`
+}
+
+// Default returns the configuration used when no file exists. Loaded
+// files override it section by section and key by key.
+func Default() *Config {
+	return &Config{
+		Delivery: Delivery{
+			Require:    []string{"artist", "copyright", "contact|contact_email|contact_url|contact_phone"},
+			ForbidGPS:  true,
+			ColorSpace: "sRGB",
+		},
+		Units:   "metric",
+		Privacy: privacy.Default(),
+		Risk:    risk.Default(),
+	}
+}
+
+// Path returns the config file to use: explicit when set, else
+// $SHOOTLOG_CONFIG, else shootlog/config.yaml under the user config
+// directory. It returns "" when no file is configured and the default
+// location does not exist.
+func Path(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if p, ok := os.LookupEnv(EnvPath); ok && p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	p := filepath.Join(dir, "shootlog", "config.yaml")
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+// Load reads the config file chosen by Path on top of the defaults.
+func Load(explicit string) (*Config, error) {
+	c := Default()
+	path := Path(explicit)
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// validate checks the sections a file set.
+func (c *Config) validate() error {
+	if c.Units != "metric" && c.Units != "imperial" {
+		return fmt.Errorf("units: want metric or imperial, got %q", c.Units)
+	}
+	if err := c.Policy.Validate(); err != nil {
+		return err
+	}
+	if err := c.Privacy.Validate(); err != nil {
+		return err
+	}
+	if err := c.Risk.Validate(); err != nil {
+		return err
+	}
+	if err := c.Jobs.Validate(); err != nil {
+		return err
+	}
+	for _, t := range c.Serve.Tokens {
+		if t == "" {
+			return errors.New("serve: empty token")
+		}
+	}
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := filter.Parse(c.Queries[name]); err != nil {
+			return fmt.Errorf("queries: %s: %w", name, err)
+		}
+	}
+	known := exif.FieldNames()
+	for _, f := range c.Fields {
+		if !slices.Contains(known, f) {
+			return fmt.Errorf("fields: unknown field %q", f)
+		}
+	}
+	if err := ValidateRename(c.Rename); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+// ValidateRename checks a renumber name template: a Go template when it
+// contains "{{", else a name with {seq} and summary field placeholders.
+func ValidateRename(tmpl string) error {
+	if strings.Contains(tmpl, "{{") {
+		_, err := tmplfunc.Parse("name", tmpl)
+		return err
+	}
+	var codes policy.Codes
+	if unknown := codes.Unknown(strings.ReplaceAll(tmpl, "{seq}", "")); len(unknown) > 0 {
+		return fmt.Errorf("unknown placeholder %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
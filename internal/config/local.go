@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ryoh827/shootlog/internal/policy"
+	"github.com/ryoh827/shootlog/pkg/yaml"
+)
+
+// LocalName is the name of directory config files, which set the
+// conventions of a tree, such as one client's folder, over the config
+// file, as .editorconfig files do for editors.
+const LocalName = ".shootlog.yaml"
+
+// local is what a directory config may set. The other sections describe
+// the machine or the archive rather than a tree of photos, so they stay
+// in the config file. Root stops the search for directory configs at
+// its directory.
+type local struct {
+	Root     bool          `json:"root"`
+	Fields   []string      `json:"fields"`
+	Rename   string        `json:"rename"`
+	Policy   policy.Policy `json:"policy"`
+	Delivery Delivery      `json:"delivery"`
+}
+
+// Tree resolves the configuration of files from their directories: the
+// config file with the directory configs of the directory and its
+// parents merged over it, nearer ones key by key over farther ones.
+// Lists, such as the policy rules, are replaced as a whole. A Tree is
+// not safe for concurrent use.
+type Tree struct {
+	path string
+	data []byte
+	// Global is the configuration without directory configs.
+	Global *Config
+	dirs   map[string]*Config
+	locals map[string]*localFile
+}
+
+// localFile is a directory config read, or nil data when there is none.
+// rules records whether it sets policy rules.
+type localFile struct {
+	data  []byte
+	root  bool
+	rules bool
+}
+
+// LoadTree reads the config file chosen by Path, as Load does, and
+// resolves directory configs over it.
+func LoadTree(explicit string) (*Tree, error) {
+	c, err := Load(explicit)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{path: Path(explicit), Global: c, dirs: map[string]*Config{}, locals: map[string]*localFile{}}
+	if t.path != "" {
+		if t.data, err = os.ReadFile(t.path); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// For returns the configuration of the file at path.
+func (t *Tree) For(path string) (*Config, error) {
+	return t.Dir(filepath.Dir(path))
+}
+
+// Dir returns the configuration of the files in dir.
+func (t *Tree) Dir(dir string) (*Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := t.dirs[abs]; ok {
+		return c, nil
+	}
+	files, err := t.Locals(abs)
+	if err != nil {
+		return nil, err
+	}
+	c := t.Global
+	if len(files) > 0 {
+		// Decoding merges into the maps it finds, so the layers are
+		// decoded afresh rather than over a copy of Global.
+		c = Default()
+		if t.data != nil {
+			if err := yaml.Unmarshal(t.data, c); err != nil {
+				return nil, fmt.Errorf("config: %s: %w", t.path, err)
+			}
+		}
+		for _, p := range files {
+			// It also decodes into the rules already in the list, which
+			// would keep the keys of a farther rule a nearer one leaves out.
+			if t.locals[p].rules {
+				c.Policy.Rules = nil
+			}
+			if err := yaml.Unmarshal(t.locals[p].data, c); err != nil {
+				return nil, fmt.Errorf("config: %s: %w", p, err)
+			}
+		}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", files[len(files)-1], err)
+		}
+	}
+	t.dirs[abs] = c
+	return c, nil
+}
+
+// Locals returns the directory configs that apply to dir, farthest
+// first: those of dir and its parents up to the first with root: true.
+func (t *Tree) Locals(dir string) ([]string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for d := abs; ; {
+		p := filepath.Join(d, LocalName)
+		f, err := t.local(p)
+		if err != nil {
+			return nil, err
+		}
+		if f.data != nil {
+			files = append(files, p)
+			if f.root {
+				break
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	slices.Reverse(files)
+	return files, nil
+}
+
+// local reads and checks the directory config at p once.
+func (t *Tree) local(p string) (*localFile, error) {
+	if f, ok := t.locals[p]; ok {
+		return f, nil
+	}
+	f := &localFile{}
+	data, err := os.ReadFile(p)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("config: %w", err)
+	default:
+		var l local
+		if err := yaml.UnmarshalStrict(data, &l); err != nil {
+			return nil, fmt.Errorf("config: %s: %w (directory configs set only root, fields, rename, policy and delivery)", p, err)
+		}
+		f.data, f.root, f.rules = data, l.Root, l.Policy.Rules != nil
+	}
+	t.locals[p] = f
+	return f, nil
+}
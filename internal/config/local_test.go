@@ -0,0 +1,211 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const globalConfig = `units: imperial
+fields: [make]
+rename: "{seq}"
+delivery:
+  require: [artist]
+  forbid_gps: false
+  color_space: Adobe RGB
+policy:
+  rules:
+    - name: global
+      require: [artist]
+queries:
+  night: "iso >= 3200"
+`
+
+// testTree lays out a library under a temporary directory:
+//
+//	photos/.shootlog.yaml         fields, delivery color space
+//	photos/client/.shootlog.yaml  rename, policy
+//	photos/client/job/            no directory config
+//	photos/own/.shootlog.yaml     root: true, fields
+//	photos/own/sub/.shootlog.yaml delivery require
+func testTree(t *testing.T) (string, *Tree) {
+	t.Helper()
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.yaml")
+	writeFile(t, global, globalConfig)
+	photos := filepath.Join(dir, "photos")
+	writeFile(t, filepath.Join(photos, LocalName), "fields: [model, iso]\ndelivery:\n  color_space: sRGB\n")
+	writeFile(t, filepath.Join(photos, "client", LocalName), "rename: \"client-{seq}\"\npolicy:\n  rules:\n    - name: client\n      forbid: [gps]\n    - name: credit\n      require: [copyright]\n")
+	os.MkdirAll(filepath.Join(photos, "client", "job"), 0o755)
+	writeFile(t, filepath.Join(photos, "own", LocalName), "root: true\nfields: [lens_model]\n")
+	writeFile(t, filepath.Join(photos, "own", "sub", LocalName), "delivery:\n  require: [title]\n")
+	tree, err := LoadTree(global)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return photos, tree
+}
+
+func ruleNames(c *Config) []string {
+	var names []string
+	for _, r := range c.Policy.Rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestTreeMerge(t *testing.T) {
+	photos, tree := testTree(t)
+	if tree.Global.Units != "imperial" || !reflect.DeepEqual(tree.Global.Fields, []string{"make"}) {
+		t.Fatalf("global config %+v", tree.Global)
+	}
+	tests := []struct {
+		dir    string
+		locals []string
+		want   Config
+		rules  []string
+	}{
+		// The config file with no directory config over it.
+		{"", nil, Config{Fields: []string{"make"}, Rename: "{seq}",
+			Delivery: Delivery{Require: []string{"artist"}, ColorSpace: "Adobe RGB"}}, []string{"global"}},
+		// Keys a directory config sets replace the config file's; the
+		// rest of a section is kept.
+		{"photos", []string{"photos"}, Config{Fields: []string{"model", "iso"}, Rename: "{seq}",
+			Delivery: Delivery{Require: []string{"artist"}, ColorSpace: "sRGB"}}, []string{"global"}},
+		// Nearer directory configs win over farther ones, and lists are
+		// replaced as a whole.
+		{"photos/client", []string{"photos", "photos/client"}, Config{Fields: []string{"model", "iso"}, Rename: "client-{seq}",
+			Delivery: Delivery{Require: []string{"artist"}, ColorSpace: "sRGB"}}, []string{"client", "credit"}},
+		{"photos/client/job", []string{"photos", "photos/client"}, Config{Fields: []string{"model", "iso"}, Rename: "client-{seq}",
+			Delivery: Delivery{Require: []string{"artist"}, ColorSpace: "sRGB"}}, []string{"client", "credit"}},
+		// root: true hides the directory configs above it, but not the
+		// config file.
+		{"photos/own", []string{"photos/own"}, Config{Fields: []string{"lens_model"}, Rename: "{seq}",
+			Delivery: Delivery{Require: []string{"artist"}, ColorSpace: "Adobe RGB"}}, []string{"global"}},
+		{"photos/own/sub", []string{"photos/own", "photos/own/sub"}, Config{Fields: []string{"lens_model"}, Rename: "{seq}",
+			Delivery: Delivery{Require: []string{"title"}, ColorSpace: "Adobe RGB"}}, []string{"global"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			dir := filepath.Join(filepath.Dir(photos), tt.dir)
+			locals, err := tree.Locals(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, l := range tt.locals {
+				want = append(want, filepath.Join(filepath.Dir(photos), l, LocalName))
+			}
+			if !reflect.DeepEqual(locals, want) {
+				t.Errorf("Locals = %q, want %q", locals, want)
+			}
+			c, err := tree.Dir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.locals) == 0 && c != tree.Global {
+				t.Error("a directory without directory configs has a config of its own")
+			}
+			if !reflect.DeepEqual(c.Fields, tt.want.Fields) || c.Rename != tt.want.Rename || !reflect.DeepEqual(c.Delivery, tt.want.Delivery) {
+				t.Errorf("fields %q, rename %q, delivery %+v; want %q, %q, %+v", c.Fields, c.Rename, c.Delivery, tt.want.Fields, tt.want.Rename, tt.want.Delivery)
+			}
+			if got := ruleNames(c); !reflect.DeepEqual(got, tt.rules) {
+				t.Errorf("policy rules %q, want %q", got, tt.rules)
+			}
+			// Sections directory configs cannot set come from the config
+			// file.
+			if c.Units != "imperial" || c.Queries["night"] != "iso >= 3200" || len(c.Queries) != 1 {
+				t.Errorf("units %q, queries %q", c.Units, c.Queries)
+			}
+			if f, err := tree.For(filepath.Join(dir, "a.jpg")); err != nil || f != c {
+				t.Errorf("For a file in the directory = %p, %v, want %p", f, err, c)
+			}
+		})
+	}
+	// A nearer rule does not take the keys it leaves out from the rule it
+	// replaces.
+	c, err := tree.Dir(filepath.Join(photos, "client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Policy.Rules[0]; r.Require != nil || !reflect.DeepEqual(r.Forbid, []string{"gps"}) {
+		t.Errorf("client rule %+v, want only forbid: [gps]", r)
+	}
+	// Merging leaves the config file's values alone.
+	if tree.Global.Rename != "{seq}" || tree.Global.Delivery.ColorSpace != "Adobe RGB" || !reflect.DeepEqual(ruleNames(tree.Global), []string{"global"}) {
+		t.Errorf("global config changed: %+v", tree.Global)
+	}
+}
+
+func TestTreeDefaults(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvPath, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("HOME", dir)
+	writeFile(t, filepath.Join(dir, "photos", LocalName), "delivery:\n  forbid_gps: false\n")
+	tree, err := LoadTree("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := tree.Dir(filepath.Join(dir, "photos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a config file, directory configs are merged over the
+	// defaults.
+	want := Default().Delivery
+	want.ForbidGPS = false
+	if !reflect.DeepEqual(c.Delivery, want) || c.Units != "metric" {
+		t.Errorf("delivery %+v, units %q; want %+v, metric", c.Delivery, c.Units, want)
+	}
+	if !tree.Global.Delivery.ForbidGPS {
+		t.Error("the default config changed")
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		local string
+		want  string
+	}{
+		{"machine section", "units: metric\n", "directory configs set only root, fields, rename, policy and delivery"},
+		{"unknown key", "feilds: [make]\n", "directory configs set only"},
+		{"bad yaml", "fields: [make\n", "config: "},
+		{"unknown field", "fields: [nothing]\n", `fields: unknown field "nothing"`},
+		{"bad rename", "rename: \"{sequence}\"\n", "rename: unknown placeholder {sequence}"},
+		{"bad policy", "policy:\n  rules:\n    - require: [nobody]\n", `policy: rule 1: unknown field "nobody"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			global := filepath.Join(dir, "config.yaml")
+			writeFile(t, global, globalConfig)
+			local := filepath.Join(dir, "photos", LocalName)
+			writeFile(t, local, tt.local)
+			tree, err := LoadTree(global)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = tree.Dir(filepath.Join(dir, "photos", "job"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), local) {
+				t.Errorf("Dir = %v, want an error with %s and %q", err, local, tt.want)
+			}
+		})
+	}
+	if _, err := LoadTree(filepath.Join(t.TempDir(), "none.yaml")); err == nil {
+		t.Error("no error for a missing config file")
+	}
+}
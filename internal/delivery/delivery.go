@@ -0,0 +1,50 @@
+// Package delivery checks exported files against the metadata a client
+// delivery must carry.
+package delivery
+
+import (
+	"github.com/ryoh827/shootlog/internal/config"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/policy"
+)
+
+// Result lists the policy violations of one file.
+type Result struct {
+	Path     string   `json:"path"`
+	Problems []string `json:"problems"`
+}
+
+// OK reports whether the file satisfies the policy.
+func (r *Result) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Policy expresses the delivery settings as policy rules.
+func Policy(d config.Delivery) *policy.Policy {
+	p := &policy.Policy{}
+	if len(d.Require) > 0 {
+		p.Rules = append(p.Rules, policy.Rule{Name: "required fields", Require: d.Require})
+	}
+	if d.ForbidGPS {
+		p.Rules = append(p.Rules, policy.Rule{Name: "location", Forbid: []string{policy.GPS}})
+	}
+	if want := d.ColorSpace; want != "" {
+		// An embedded ICC profile takes precedence over the EXIF color
+		// space; profile descriptions only need to mention it.
+		p.Rules = append(p.Rules,
+			policy.Rule{Name: "color space", When: map[string]string{"icc_profile": "*"}, Match: map[string]string{"icc_profile": "*" + want + "*"}},
+			policy.Rule{Name: "color space", When: map[string]string{"icc_profile": ""}, Match: map[string]string{"color_space": want}},
+		)
+	}
+	return p
+}
+
+// Check evaluates s against the delivery settings.
+func Check(s *exif.Summary, d config.Delivery) Result {
+	res := Policy(d).Check(s)
+	r := Result{Path: s.Path, Problems: []string{}}
+	for _, v := range res.Violations {
+		r.Problems = append(r.Problems, v.Message)
+	}
+	return r
+}
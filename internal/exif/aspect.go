@@ -0,0 +1,68 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+)
+
+// Crop modes that are not an aspect ratio: a smaller area of the sensor
+// at its own ratio, such as an APS-C crop on a full-frame body.
+const (
+	CropAPSC = "APS-C"
+	CropAPSH = "APS-H"
+)
+
+// aspectRatios are the named ratios, long side first.
+var aspectRatios = []struct {
+	long, short int
+}{
+	{1, 1}, {5, 4}, {4, 3}, {7, 5}, {3, 2}, {16, 10}, {16, 9}, {2, 1}, {65, 24}, {3, 1},
+}
+
+// AspectRatio names the ratio of the long side of width x height to the
+// short one, such as "3:2" for a portrait shot too, or gives it as a
+// decimal like "2.35:1" when no common ratio is within 1%.
+func AspectRatio(width, height int) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	r := float64(max(width, height)) / float64(min(width, height))
+	for _, a := range aspectRatios {
+		if math.Abs(r/(float64(a.long)/float64(a.short))-1) < 0.01 {
+			return fmt.Sprintf("%d:%d", a.long, a.short)
+		}
+	}
+	return fmt.Sprintf("%.2f:1", r)
+}
+
+// summarizeAspect sets the aspect ratio from the pixel dimensions, or the
+// IFD0 ones of DNG and TIFF files that record none. A DNG DefaultCropSize
+// of another ratio than the full image is the crop mode, and the ratio
+// the image renders at. Maker notes may set the crop mode later.
+func summarizeAspect(x *Exif, s *Summary) {
+	w, h := s.Width, s.Height
+	if w == 0 || h == 0 {
+		iw, okW := x.Uint(IFD0, TagImageWidth)
+		ih, okH := x.Uint(IFD0, TagImageLength)
+		if !okW || !okH {
+			return
+		}
+		w, h = int(iw), int(ih)
+	}
+	if s.AspectRatio = AspectRatio(w, h); s.AspectRatio == "" {
+		return
+	}
+	e, ok := x.Lookup(IFD0, TagDefaultCropSize)
+	if !ok {
+		return
+	}
+	cw, okW := e.Float(0)
+	ch, okH := e.Float(1)
+	if !okW || !okH {
+		return
+	}
+	if crop := AspectRatio(int(math.Round(cw)), int(math.Round(ch))); crop != "" && crop != s.AspectRatio {
+		s.AspectRatio, s.CropMode = crop, crop
+		s.setSource(entrySource(e), "crop_mode")
+	}
+}
@@ -0,0 +1,357 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/jpeg"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// field is an entry to be serialized by encodeTIFF.
+type field struct {
+	tag   uint16
+	typ   Type
+	count uint32
+	value []byte
+}
+
+// fieldList accumulates the fields of one directory in a fixed byte order.
+type fieldList struct {
+	order  binary.AppendByteOrder
+	fields []field
+}
+
+func (l *fieldList) add(tag uint16, typ Type, count int, value []byte) {
+	l.fields = append(l.fields, field{tag, typ, uint32(count), value})
+}
+
+func (l *fieldList) ascii(tag uint16, s string) {
+	if s != "" {
+		l.add(tag, TypeASCII, len(s)+1, append([]byte(s), 0))
+	}
+}
+
+func (l *fieldList) short(tag uint16, v ...uint16) {
+	var b []byte
+	for _, n := range v {
+		b = l.order.AppendUint16(b, n)
+	}
+	l.add(tag, TypeShort, len(v), b)
+}
+
+func (l *fieldList) long(tag uint16, v uint32) {
+	l.add(tag, TypeLong, 1, l.order.AppendUint32(nil, v))
+}
+
+func (l *fieldList) rational(tag uint16, pairs ...uint32) {
+	var b []byte
+	for _, n := range pairs {
+		b = l.order.AppendUint32(b, n)
+	}
+	l.add(tag, TypeRational, len(pairs)/2, b)
+}
+
+func (l *fieldList) srational(tag uint16, num, den int32) {
+	b := l.order.AppendUint32(nil, uint32(num))
+	l.add(tag, TypeSRational, 1, l.order.AppendUint32(b, uint32(den)))
+}
+
+func (l *fieldList) xp(tag uint16, s string) {
+	if s != "" {
+		e := SetXP(tag, s)
+		l.add(tag, e.typ, int(e.count), e.value(l.order))
+	}
+}
+
+// Build serializes s into a complete JPEG APP1 Exif segment, marker and
+// length included, holding IFD0, the Exif IFD and, when s has coordinates,
+// the GPS IFD. Every tag EXIF 2.32 makes mandatory for a JPEG primary
+// image is written, so the result passes Validate. Fields decoded from
+// maker notes have no standard tag and are not written.
+func Build(s *Summary) ([]byte, error) {
+	tiff, err := buildTIFF(s, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	payload := len(exifHeader) + len(tiff)
+	if payload+2 > maxAPP1 {
+		return nil, fmt.Errorf("%w: EXIF segment would be %d bytes", ErrFormat, payload)
+	}
+	out := make([]byte, 0, payload+4)
+	out = append(out, 0xFF, markerAPP1, byte((payload+2)>>8), byte(payload+2))
+	out = append(out, exifHeader...)
+	return append(out, tiff...), nil
+}
+
+func buildTIFF(s *Summary, order binary.AppendByteOrder) ([]byte, error) {
+	var datetime, offset string
+	if s.DateTimeOriginal != "" {
+		t, ok := s.CaptureTime()
+		if !ok {
+			return nil, fmt.Errorf("exif: invalid datetime_original %q", s.DateTimeOriginal)
+		}
+		datetime = t.Format("2006:01:02 15:04:05")
+		if len(s.DateTimeOriginal) > len(captureLayout) {
+			offset = t.Format("-07:00")
+		}
+	}
+
+	ifd0 := &fieldList{order: order}
+	ifd0.ascii(TagImageDescription, s.Description)
+	ifd0.ascii(TagMake, s.Make)
+	ifd0.ascii(TagModel, s.Model)
+	if s.Orientation >= 1 && s.Orientation <= 8 {
+		ifd0.short(TagOrientation, uint16(s.Orientation))
+	}
+	ifd0.rational(TagXResolution, 72, 1)
+	ifd0.rational(TagYResolution, 72, 1)
+	ifd0.short(TagResolutionUnit, 2)
+	ifd0.ascii(TagSoftware, s.Software)
+	ifd0.ascii(TagDateTime, datetime)
+	ifd0.ascii(TagArtist, s.Artist)
+	ifd0.short(TagYCbCrPositioning, 1)
+	ifd0.ascii(TagCopyright, s.Copyright)
+	if s.Rating >= 1 && s.Rating <= 5 {
+		ifd0.short(TagRating, uint16(s.Rating))
+		ifd0.short(TagRatingPercent, ratingPercent[s.Rating])
+	}
+	ifd0.xp(TagXPTitle, s.Title)
+	ifd0.xp(TagXPAuthor, s.Author)
+	ifd0.xp(TagXPKeywords, strings.Join(s.Keywords, ";"))
+	ifd0.xp(TagXPSubject, s.Subject)
+
+	ex := &fieldList{order: order}
+	if s.ExposureTime > 0 {
+		ex.rational(TagExposureTime, exposureRational(s.ExposureTime)...)
+	}
+	if s.FNumber > 0 {
+		ex.rational(TagFNumber, uint32(math.Round(s.FNumber*10)), 10)
+	}
+	if s.ISO > 0 {
+		ex.short(TagISOSpeedRatings, uint16(min(s.ISO, math.MaxUint16)))
+	}
+	ex.add(TagExifVersion, TypeUndefined, 4, []byte("0232"))
+	ex.ascii(TagDateTimeOriginal, datetime)
+	ex.ascii(TagDateTimeDigitized, datetime)
+	ex.ascii(TagOffsetTimeOriginal, offset)
+	ex.add(TagComponentsConfiguration, TypeUndefined, 4, []byte{1, 2, 3, 0})
+	if s.ExposureBias != 0 {
+		num, den := biasRational(s.ExposureBias)
+		ex.srational(TagExposureBias, num, den)
+	}
+	if s.SubjectDistance > 0 {
+		ex.rational(TagSubjectDistance, uint32(math.Round(min(s.SubjectDistance, 1e6)*100)), 100)
+	}
+	if s.FocalLength > 0 {
+		ex.rational(TagFocalLength, uint32(math.Round(s.FocalLength*10)), 10)
+	}
+	if s.Comment != "" {
+		v := encodeUserComment(s.Comment, order)
+		ex.add(TagUserComment, TypeUndefined, len(v), v)
+	}
+	ex.add(TagFlashpixVersion, TypeUndefined, 4, []byte("0100"))
+	ex.short(TagColorSpace, 1)
+	ex.long(TagPixelXDimension, uint32(max(s.Width, 0)))
+	ex.long(TagPixelYDimension, uint32(max(s.Height, 0)))
+	if s.FocalLength35mm > 0 {
+		ex.short(TagFocalLength35mm, uint16(min(s.FocalLength35mm, math.MaxUint16)))
+	}
+	ex.ascii(TagBodySerialNumber, s.BodySerial)
+	ex.ascii(TagLensMake, s.LensMake)
+	ex.ascii(TagLensModel, s.LensModel)
+	ex.ascii(TagLensSerialNumber, s.LensSerial)
+
+	dirs := []*fieldList{ifd0, ex}
+	pointers := []uint16{TagExifIFDPointer}
+	if s.Latitude != nil && s.Longitude != nil {
+		gps := &fieldList{order: order}
+		gps.add(TagGPSVersionID, TypeByte, 4, []byte{2, 3, 0, 0})
+		gps.ascii(TagGPSLatitudeRef, hemisphere(*s.Latitude, "N", "S"))
+		gps.rational(TagGPSLatitude, dms(*s.Latitude)...)
+		gps.ascii(TagGPSLongitudeRef, hemisphere(*s.Longitude, "E", "W"))
+		gps.rational(TagGPSLongitude, dms(*s.Longitude)...)
+		if s.Altitude != nil {
+			ref := byte(0)
+			if *s.Altitude < 0 {
+				ref = 1
+			}
+			gps.add(TagGPSAltitudeRef, TypeByte, 1, []byte{ref})
+			gps.rational(TagGPSAltitude, uint32(math.Round(math.Abs(*s.Altitude)*100)), 100)
+		}
+		dirs = append(dirs, gps)
+		pointers = append(pointers, TagGPSIFDPointer)
+	}
+	// Pointers are LONGs, so adding them does not change directory sizes
+	// and the placeholders can be patched once offsets are known.
+	for _, tag := range pointers {
+		ifd0.long(tag, 0)
+	}
+	for _, l := range dirs {
+		sort.SliceStable(l.fields, func(i, j int) bool { return l.fields[i].tag < l.fields[j].tag })
+	}
+	offsets := make([]uint32, len(dirs))
+	pos := uint32(8)
+	for i, l := range dirs {
+		offsets[i] = pos
+		pos += dirSize(l)
+	}
+	for i, tag := range pointers {
+		for j := range ifd0.fields {
+			if ifd0.fields[j].tag == tag {
+				ifd0.fields[j].value = order.AppendUint32(nil, offsets[i+1])
+			}
+		}
+	}
+	return encodeTIFF(order, dirs, offsets), nil
+}
+
+// dirSize returns the encoded size of a directory and its out-of-line
+// values, which are padded to even offsets.
+func dirSize(l *fieldList) uint32 {
+	n := uint32(2 + 12*len(l.fields) + 4)
+	for _, f := range l.fields {
+		if len(f.value) > 4 {
+			n += uint32(len(f.value)+1) &^ 1
+		}
+	}
+	return n
+}
+
+// encodeTIFF writes a TIFF header followed by each directory at its
+// precomputed offset. Directories are not chained: IFD0 is the only one in
+// the main chain and the others are reached through pointer tags.
+func encodeTIFF(order binary.AppendByteOrder, dirs []*fieldList, offsets []uint32) []byte {
+	var out []byte
+	if order == binary.AppendByteOrder(binary.LittleEndian) {
+		out = append(out, 'I', 'I')
+	} else {
+		out = append(out, 'M', 'M')
+	}
+	out = order.AppendUint16(out, 42)
+	out = order.AppendUint32(out, offsets[0])
+	for i, l := range dirs {
+		values := offsets[i] + uint32(2+12*len(l.fields)+4)
+		var data []byte
+		out = order.AppendUint16(out, uint16(len(l.fields)))
+		for _, f := range l.fields {
+			out = order.AppendUint16(out, f.tag)
+			out = order.AppendUint16(out, uint16(f.typ))
+			out = order.AppendUint32(out, f.count)
+			if len(f.value) <= 4 {
+				var inline [4]byte
+				copy(inline[:], f.value)
+				out = append(out, inline[:]...)
+				continue
+			}
+			out = order.AppendUint32(out, values+uint32(len(data)))
+			data = append(data, f.value...)
+			if len(data)%2 != 0 {
+				data = append(data, 0)
+			}
+		}
+		out = order.AppendUint32(out, 0)
+		out = append(out, data...)
+	}
+	return out
+}
+
+// encodeUserComment stores ASCII comments with the ASCII character code and
+// anything else as UCS-2 in the TIFF byte order.
+func encodeUserComment(s string, order binary.AppendByteOrder) []byte {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return append(append([]byte(nil), codeASCII...), s...)
+	}
+	out := append([]byte(nil), codeUnicode...)
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = order.AppendUint16(out, u)
+	}
+	return out
+}
+
+// exposureRational converts an exposure time in seconds, preferring the
+// 1/n form cameras use for fast shutter speeds.
+func exposureRational(sec float64) []uint32 {
+	if sec < 1 {
+		if n := math.Round(1 / sec); math.Abs(1/n-sec) < sec*1e-3 {
+			return []uint32{1, uint32(n)}
+		}
+	}
+	return []uint32{uint32(math.Round(sec * 1e4)), 1e4}
+}
+
+// biasRational converts exposure compensation, preferring thirds and
+// halves of a stop.
+func biasRational(ev float64) (int32, int32) {
+	for _, den := range []float64{1, 2, 3} {
+		if n := math.Round(ev * den); math.Abs(n/den-ev) < 0.011 {
+			return int32(n), int32(den)
+		}
+	}
+	return int32(math.Round(ev * 100)), 100
+}
+
+func hemisphere(v float64, pos, neg string) string {
+	if v < 0 {
+		return neg
+	}
+	return pos
+}
+
+// dms converts decimal degrees into degree, minute and second rationals
+// with seconds kept to 1/1000.
+func dms(v float64) []uint32 {
+	v = math.Abs(v)
+	deg := math.Floor(v)
+	minutes := math.Floor((v - deg) * 60)
+	sec := math.Round(((v-deg)*60 - minutes) * 60 * 1000)
+	return []uint32{uint32(deg), 1, uint32(minutes), 1, uint32(sec), 1000}
+}
+
+// Embed inserts an APP1 segment produced by Build into a JPEG after SOI
+// and any JFIF APP0 segments. It fails when the image already carries
+// EXIF data unless replace is set, in which case the existing segment is
+// dropped. In a JFIF file the segment's resolution is set to the JFIF
+// density, which Build does not know.
+func Embed(image, app1 []byte, replace bool) ([]byte, error) {
+	segs, err := Segments(image)
+	if err != nil {
+		return nil, err
+	}
+	at, end, tiff := exifSpan(segs)
+	if tiff != nil && !replace {
+		return nil, fmt.Errorf("%w: image already has EXIF data", ErrFormat)
+	}
+	if jf, ok := readJFIF(segs); ok && len(jf.resolution()) > 0 && len(app1) > 4+len(exifHeader) {
+		tiff, err := applyTIFF(app1[4+len(exifHeader):], jf.resolution(), true)
+		if err != nil {
+			return nil, err
+		}
+		payload := len(exifHeader) + len(tiff)
+		if payload+2 > maxAPP1 {
+			return nil, fmt.Errorf("%w: EXIF segment would be %d bytes", ErrFormat, payload)
+		}
+		app1 = append([]byte{0xFF, markerAPP1, byte((payload + 2) >> 8), byte(payload + 2)}, exifHeader...)
+		app1 = append(app1, tiff...)
+	}
+	return placeExif(image, at, end, leadEnd(segs), app1), nil
+}
+
+// ImageSize returns the pixel dimensions recorded in a JPEG frame header.
+func ImageSize(image []byte) (width, height int, err error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(image))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
@@ -0,0 +1,494 @@
+package exif
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/pkg/cbor"
+)
+
+// markerAPP11 holds JPEG universal metadata boxes (JUMBF), the container
+// of C2PA manifests.
+const markerAPP11 = 0xEB
+
+// LocationC2PA is the Source location of values read from a C2PA
+// manifest.
+const LocationC2PA = "C2PA"
+
+// Results of checking the asset hash of a C2PA manifest.
+const (
+	C2PAHashMatch    = "match"
+	C2PAHashMismatch = "mismatch"
+	// C2PAHashUnchecked is reported for manifests without a data hash
+	// assertion or with an unsupported hash algorithm.
+	C2PAHashUnchecked = "unchecked"
+)
+
+// C2PA is a C2PA manifest store, the Content Credentials of a file.
+type C2PA struct {
+	// Manifests are in store order; the last is the active manifest,
+	// describing the file as it is, and earlier ones its ingredients.
+	Manifests []*C2PAManifest
+}
+
+// C2PAManifest summarizes one manifest: its claim, the certificate that
+// signed it and its assertions.
+type C2PAManifest struct {
+	Label string
+	// ClaimGenerator names the application that made the claim.
+	ClaimGenerator string
+	Title, Format  string
+	// Signer is the common name, else the organization, of the signing
+	// certificate; Issuer that of its issuer.
+	Signer, Issuer string
+	// Actions lists the c2pa.actions entries, such as "c2pa.created" or
+	// "c2pa.edited".
+	Actions []string
+	// Ingredients are the titles of the files the asset was made from.
+	Ingredients []string
+	// Assertions are the labels of all assertions.
+	Assertions []string
+
+	dataHash *c2paDataHash
+	alg      string
+}
+
+// c2paDataHash is a c2pa.hash.data assertion: a hash of the file
+// excluding the byte ranges holding the manifest store.
+type c2paDataHash struct {
+	alg        string
+	hash       []byte
+	exclusions [][2]int64
+}
+
+// Active returns the active manifest, or nil for an empty store.
+func (c *C2PA) Active() *C2PAManifest {
+	if len(c.Manifests) == 0 {
+		return nil
+	}
+	return c.Manifests[len(c.Manifests)-1]
+}
+
+// jumbfBox is an ISO/IEC 19566-5 box.
+type jumbfBox struct {
+	typ  string
+	data []byte
+}
+
+// jumbfBoxes splits data into boxes.
+func jumbfBoxes(data []byte) ([]jumbfBox, error) {
+	var out []jumbfBox
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return out, fmt.Errorf("%w: JUMBF box header", ErrTruncated)
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		head := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return out, fmt.Errorf("%w: JUMBF box header", ErrTruncated)
+			}
+			size, head = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < head || size > uint64(len(data)) {
+			return out, fmt.Errorf("%w: JUMBF box %q of %d bytes", ErrTruncated, typ, size)
+		}
+		out = append(out, jumbfBox{typ, data[head:size]})
+		data = data[size:]
+	}
+	return out, nil
+}
+
+// jumbfSuperbox is a parsed "jumb" superbox: its description and content
+// boxes.
+type jumbfSuperbox struct {
+	// kind is the first four bytes of the description's type UUID, which
+	// for C2PA spell the content type: "c2pa", "c2ma", "c2cl", "cbor"...
+	kind    string
+	label   string
+	content []jumbfBox
+}
+
+func parseSuperbox(b jumbfBox) (*jumbfSuperbox, error) {
+	if b.typ != "jumb" {
+		return nil, fmt.Errorf("%w: JUMBF box %q is not a superbox", ErrFormat, b.typ)
+	}
+	boxes, err := jumbfBoxes(b.data)
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) == 0 || boxes[0].typ != "jumd" || len(boxes[0].data) < 17 {
+		return nil, fmt.Errorf("%w: JUMBF superbox without a description", ErrFormat)
+	}
+	d := boxes[0].data
+	sb := &jumbfSuperbox{kind: string(d[:4]), content: boxes[1:]}
+	if toggles := d[16]; toggles&0x02 != 0 {
+		label := d[17:]
+		if i := bytes.IndexByte(label, 0); i >= 0 {
+			label = label[:i]
+		}
+		sb.label = string(label)
+	}
+	return sb, nil
+}
+
+// children returns the superboxes among the content boxes.
+func (sb *jumbfSuperbox) children() []*jumbfSuperbox {
+	var out []*jumbfSuperbox
+	for _, b := range sb.content {
+		if c, err := parseSuperbox(b); err == nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// cbor decodes the first CBOR content box.
+func (sb *jumbfSuperbox) cbor() (any, bool) {
+	for _, b := range sb.content {
+		if b.typ == "cbor" {
+			v, err := cbor.Unmarshal(b.data)
+			return v, err == nil
+		}
+	}
+	return nil, false
+}
+
+// c2paSegments returns the APP11 segments holding the C2PA manifest
+// store of a JPEG file, in file order.
+func c2paSegments(image []byte) []Segment {
+	segs, _ := Segments(image)
+	var out []Segment
+	for _, s := range segs {
+		if s.Marker == markerAPP11 && len(s.Data) >= 16 && s.Data[0] == 'J' && s.Data[1] == 'P' &&
+			string(s.Data[12:16]) == "jumb" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// jumbfStores reassembles the JUMBF boxes of APP11 segments. Each segment
+// holds a common identifier "JP", a box instance number and a packet
+// sequence number; packets after the first repeat the box header, which
+// is dropped.
+func jumbfStores(segs []Segment) [][]byte {
+	type packet struct {
+		seq  uint32
+		data []byte
+	}
+	byInstance := map[uint16][]packet{}
+	var order []uint16
+	for _, s := range segs {
+		en := binary.BigEndian.Uint16(s.Data[2:])
+		if _, ok := byInstance[en]; !ok {
+			order = append(order, en)
+		}
+		byInstance[en] = append(byInstance[en], packet{binary.BigEndian.Uint32(s.Data[4:]), s.Data[8:]})
+	}
+	var out [][]byte
+	for _, en := range order {
+		ps := byInstance[en]
+		sort.SliceStable(ps, func(i, j int) bool { return ps[i].seq < ps[j].seq })
+		box := append([]byte(nil), ps[0].data...)
+		for _, p := range ps[1:] {
+			head := 8
+			if len(p.data) >= 8 && binary.BigEndian.Uint32(p.data) == 1 {
+				head = 16
+			}
+			if len(p.data) > head {
+				box = append(box, p.data[head:]...)
+			}
+		}
+		out = append(out, box)
+	}
+	return out
+}
+
+// ReadC2PA returns the C2PA manifest store of a JPEG file, or nil when it
+// has none.
+func ReadC2PA(image []byte) (*C2PA, error) {
+	for _, store := range jumbfStores(c2paSegments(image)) {
+		boxes, err := jumbfBoxes(store)
+		if err != nil || len(boxes) == 0 {
+			continue
+		}
+		sb, err := parseSuperbox(boxes[0])
+		if err != nil {
+			return nil, err
+		}
+		if sb.label != "c2pa" {
+			continue
+		}
+		c := &C2PA{}
+		for _, m := range sb.children() {
+			c.Manifests = append(c.Manifests, parseManifest(m))
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+func parseManifest(sb *jumbfSuperbox) *C2PAManifest {
+	m := &C2PAManifest{Label: sb.label}
+	for _, c := range sb.children() {
+		switch {
+		case c.label == "c2pa.claim" || c.label == "c2pa.claim.v2":
+			if v, ok := c.cbor(); ok {
+				m.claim(v)
+			}
+		case c.label == "c2pa.signature":
+			if v, ok := c.cbor(); ok {
+				m.signature(v)
+			}
+		case c.label == "c2pa.assertions":
+			for _, a := range c.children() {
+				m.assertion(a)
+			}
+		}
+	}
+	return m
+}
+
+func (m *C2PAManifest) claim(v any) {
+	claim, _ := v.(map[any]any)
+	m.ClaimGenerator = text(claim["claim_generator"])
+	// Version 2 claims name the generator in claim_generator_info, a map,
+	// which version 1 claims may also carry as an array of maps.
+	if m.ClaimGenerator == "" {
+		info := claim["claim_generator_info"]
+		if list, ok := info.([]any); ok && len(list) > 0 {
+			info = list[0]
+		}
+		if g, ok := info.(map[any]any); ok {
+			m.ClaimGenerator = strings.TrimSpace(text(g["name"]) + " " + text(g["version"]))
+		}
+	}
+	m.Title = text(claim["dc:title"])
+	m.Format = text(claim["dc:format"])
+	m.alg = text(claim["alg"])
+}
+
+// signature reads the signing certificate of a COSE_Sign1 claim
+// signature, whose x5chain header (33) holds one certificate or an array
+// with the signer's first.
+func (m *C2PAManifest) signature(v any) {
+	if t, ok := v.(cbor.Tag); ok {
+		v = t.Content
+	}
+	sign1, ok := v.([]any)
+	if !ok || len(sign1) != 4 {
+		return
+	}
+	var chain any
+	if p, ok := sign1[0].([]byte); ok && len(p) > 0 {
+		if h, err := cbor.Unmarshal(p); err == nil {
+			if h, ok := h.(map[any]any); ok {
+				chain = h[int64(33)]
+			}
+		}
+	}
+	if u, ok := sign1[1].(map[any]any); ok && chain == nil {
+		chain = u[int64(33)]
+		if chain == nil {
+			chain = u["x5chain"]
+		}
+	}
+	if list, ok := chain.([]any); ok && len(list) > 0 {
+		chain = list[0]
+	}
+	der, ok := chain.([]byte)
+	if !ok {
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return
+	}
+	m.Signer = certName(cert.Subject.CommonName, cert.Subject.Organization)
+	m.Issuer = certName(cert.Issuer.CommonName, cert.Issuer.Organization)
+}
+
+func certName(cn string, org []string) string {
+	if cn != "" || len(org) == 0 {
+		return cn
+	}
+	return org[0]
+}
+
+// assertionBase strips the version and instance suffixes of an assertion
+// label: "c2pa.actions.v2" and "c2pa.ingredient__1" are read like
+// "c2pa.actions" and "c2pa.ingredient".
+func assertionBase(label string) string {
+	if i := strings.Index(label, "__"); i >= 0 {
+		label = label[:i]
+	}
+	if i := strings.LastIndex(label, ".v"); i >= 0 && i+2 < len(label) && strings.Trim(label[i+2:], "0123456789") == "" {
+		label = label[:i]
+	}
+	return label
+}
+
+func (m *C2PAManifest) assertion(a *jumbfSuperbox) {
+	m.Assertions = append(m.Assertions, a.label)
+	v, _ := a.cbor()
+	body, _ := v.(map[any]any)
+	switch assertionBase(a.label) {
+	case "c2pa.actions":
+		list, _ := body["actions"].([]any)
+		for _, e := range list {
+			if e, ok := e.(map[any]any); ok && text(e["action"]) != "" {
+				m.Actions = append(m.Actions, text(e["action"]))
+			}
+		}
+	case "c2pa.ingredient":
+		if t := text(body["dc:title"]); t != "" {
+			m.Ingredients = append(m.Ingredients, t)
+		}
+	case "c2pa.hash.data":
+		h := &c2paDataHash{alg: text(body["alg"])}
+		h.hash, _ = body["hash"].([]byte)
+		list, _ := body["exclusions"].([]any)
+		for _, e := range list {
+			e, _ := e.(map[any]any)
+			start, ok1 := e["start"].(int64)
+			length, ok2 := e["length"].(int64)
+			if ok1 && ok2 {
+				h.exclusions = append(h.exclusions, [2]int64{start, length})
+			}
+		}
+		if h.hash != nil {
+			m.dataHash = h
+		}
+	}
+}
+
+// text returns v when it is a string.
+func text(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// CheckHash compares the manifest's data hash with the hash of image
+// outside the excluded ranges, reporting C2PAHashMatch, C2PAHashMismatch
+// or C2PAHashUnchecked. Any change to the file outside the manifest
+// store, including to its EXIF data, breaks the match.
+func (m *C2PAManifest) CheckHash(image []byte) string {
+	h := m.dataHash
+	if h == nil {
+		return C2PAHashUnchecked
+	}
+	alg := h.alg
+	if alg == "" {
+		alg = m.alg
+	}
+	var d hash.Hash
+	switch alg {
+	case "", "sha256":
+		d = sha256.New()
+	case "sha384":
+		d = sha512.New384()
+	case "sha512":
+		d = sha512.New()
+	default:
+		return C2PAHashUnchecked
+	}
+	ex := append([][2]int64(nil), h.exclusions...)
+	sort.Slice(ex, func(i, j int) bool { return ex[i][0] < ex[j][0] })
+	pos := int64(0)
+	for _, e := range ex {
+		if e[0] < pos || e[1] < 0 || e[0]+e[1] > int64(len(image)) {
+			return C2PAHashMismatch
+		}
+		d.Write(image[pos:e[0]])
+		pos = e[0] + e[1]
+	}
+	d.Write(image[pos:])
+	if bytes.Equal(d.Sum(nil), h.hash) {
+		return C2PAHashMatch
+	}
+	return C2PAHashMismatch
+}
+
+// summarizeC2PA fills in the C2PA fields from the active manifest and
+// reports whether the file has a manifest store.
+func summarizeC2PA(data []byte, s *Summary) bool {
+	c, err := ReadC2PA(data)
+	if err != nil || c == nil {
+		return false
+	}
+	s.C2PAManifests = len(c.Manifests)
+	src := Source{Location: LocationC2PA}
+	s.setSource(src, "c2pa_manifests")
+	m := c.Active()
+	if m == nil {
+		return true
+	}
+	src.Tag = m.Label
+	if m.ClaimGenerator != "" {
+		s.C2PAClaimGenerator = m.ClaimGenerator
+		s.setSource(src, "c2pa_claim_generator")
+	}
+	if m.Signer != "" {
+		s.C2PASigner = m.Signer
+		s.setSource(src, "c2pa_signer")
+	}
+	if len(m.Actions) > 0 {
+		s.C2PAActions = m.Actions
+		s.setSource(src, "c2pa_actions")
+	}
+	s.C2PAHash = m.CheckHash(data)
+	s.setSource(src, "c2pa_hash")
+	return true
+}
+
+// StripC2PA returns a copy of a JPEG file without its C2PA manifest
+// store, or image itself when it has none.
+func StripC2PA(image []byte) []byte {
+	segs := c2paSegments(image)
+	if len(segs) == 0 {
+		return image
+	}
+	out := make([]byte, 0, len(image))
+	pos := 0
+	for _, s := range segs {
+		out = append(out, image[pos:s.Offset]...)
+		pos = s.Offset + 4 + len(s.Data)
+	}
+	return append(out, image[pos:]...)
+}
+
+// NamespaceC2PANote is the XMP namespace of the note AnnotateC2PA leaves
+// in files whose Content Credentials were kept through an edit.
+const NamespaceC2PANote = "https://github.com/ryoh827/shootlog/ns/c2pa/1.0/"
+
+// AnnotateC2PA records in the XMP packet of a JPEG file that it was
+// changed after its active manifest was signed: c2pa:EditedAfterSigning
+// holds the time of the first such change and c2pa:Manifest the label of
+// the manifest it invalidated. Later changes leave an existing note alone.
+func AnnotateC2PA(image []byte, m *C2PAManifest, at time.Time) ([]byte, error) {
+	packet := XMP(image)
+	if HasXMPProperty(packet, "EditedAfterSigning") {
+		return image, nil
+	}
+	packet, err := AddXMPProperty(packet, NamespaceC2PANote, "c2pa", "EditedAfterSigning", at.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	if packet, err = AddXMPProperty(packet, NamespaceC2PANote, "c2pa", "Manifest", m.Label); err != nil {
+		return nil, err
+	}
+	return SetXMP(image, packet)
+}
@@ -0,0 +1,395 @@
+package exif_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"maps"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+	"github.com/ryoh827/shootlog/pkg/cbor"
+)
+
+// signingChain returns a leaf certificate for "Field Camera" issued by
+// "Test Root CA".
+func signingChain(t *testing.T) []byte {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ca := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Test Root CA"},
+		NotBefore: now, NotAfter: now.Add(time.Hour), IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign}
+	leaf := &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{Organization: []string{"Field Camera"}},
+		NotBefore: now, NotAfter: now.Add(time.Hour), KeyUsage: x509.KeyUsageDigitalSignature}
+	der, err := x509.CreateCertificate(rand.Reader, leaf, ca, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// manifest returns a c2pa.claim manifest. hash, when not nil, is the
+// c2pa.hash.data assertion.
+func manifest(label string, claim map[string]any, cert []byte, hash map[string]any, actions []string, ingredients ...string) []byte {
+	var assertions [][]byte
+	if actions != nil {
+		var list []any
+		for _, a := range actions {
+			list = append(list, map[string]any{"action": a})
+		}
+		assertions = append(assertions, exiftest.JUMBF("cbor", "c2pa.actions.v2", exiftest.CBOR(map[string]any{"actions": list})))
+	}
+	for i, title := range ingredients {
+		label := "c2pa.ingredient"
+		if i > 0 {
+			label += "__" + string(rune('0'+i))
+		}
+		assertions = append(assertions, exiftest.JUMBF("cbor", label, exiftest.CBOR(map[string]any{"dc:title": title})))
+	}
+	if hash != nil {
+		assertions = append(assertions, exiftest.JUMBF("cbor", "c2pa.hash.data", exiftest.CBOR(hash)))
+	}
+	content := [][]byte{
+		exiftest.JUMBF("c2as", "c2pa.assertions", assertions...),
+		exiftest.JUMBF("c2cl", "c2pa.claim", exiftest.CBOR(claim)),
+	}
+	if cert != nil {
+		protected, _ := cbor.Marshal(map[any]any{int64(1): int64(-8), int64(33): cert})
+		sign1 := cbor.Tag{Number: 18, Content: []any{protected, map[any]any{}, nil, make([]byte, 64)}}
+		content = append(content, exiftest.JUMBF("c2cs", "c2pa.signature", exiftest.CBOR(sign1)))
+	}
+	return exiftest.JUMBF("c2ma", label, content...)
+}
+
+// signedJPEG returns a JPEG holding the manifest store made by store, in
+// APP11 packets of at most size bytes, with a c2pa.hash.data assertion
+// given to store that hashes the file outside them.
+func signedJPEG(t *testing.T, size int, store func(hash map[string]any) []byte) []byte {
+	t.Helper()
+	build := func(hash map[string]any) []byte {
+		b := exiftest.New(binary.BigEndian)
+		b.IFD0().ASCII(exif.TagMake, "Fujifilm")
+		for _, p := range exiftest.APP11(store(hash), 1, size) {
+			b.Segment(exiftest.MarkerAPP11, p)
+		}
+		return b.JPEG()
+	}
+	// The exclusion covers the APP11 segments, whose length depends on
+	// the exclusion itself; a couple of rounds settle it.
+	hash := map[string]any{"alg": "sha256", "hash": make([]byte, sha256.Size), "exclusions": []any{map[string]any{"start": 0, "length": 0}}}
+	var img []byte
+	for i := 0; i < 4; i++ {
+		img = build(hash)
+		var start, end int
+		segs, _ := exif.Segments(img)
+		for _, s := range segs {
+			if s.Marker == exiftest.MarkerAPP11 {
+				if start == 0 {
+					start = s.Offset
+				}
+				end = s.Offset + 4 + len(s.Data)
+			}
+		}
+		ex := map[string]any{"start": start, "length": end - start}
+		if reflect.DeepEqual(hash["exclusions"], []any{ex}) {
+			break
+		}
+		hash["exclusions"] = []any{ex}
+	}
+	ex := hash["exclusions"].([]any)[0].(map[string]any)
+	start, end := ex["start"].(int), ex["start"].(int)+ex["length"].(int)
+	sum := sha256.Sum256(append(img[:start:start], img[end:]...))
+	hash["hash"] = sum[:]
+	return build(hash)
+}
+
+// public returns the exported fields of m.
+func public(m *exif.C2PAManifest) exif.C2PAManifest {
+	return exif.C2PAManifest{Label: m.Label, ClaimGenerator: m.ClaimGenerator, Title: m.Title, Format: m.Format,
+		Signer: m.Signer, Issuer: m.Issuer, Actions: m.Actions, Ingredients: m.Ingredients, Assertions: m.Assertions}
+}
+
+func TestReadC2PA(t *testing.T) {
+	cert := signingChain(t)
+	claim := map[string]any{"claim_generator": "FieldCam/2.1", "dc:title": "DSCF0001.JPG", "dc:format": "image/jpeg", "alg": "sha256"}
+	active := exif.C2PAManifest{Label: "urn:uuid:active", ClaimGenerator: "FieldCam/2.1", Title: "DSCF0001.JPG",
+		Format: "image/jpeg", Signer: "Field Camera", Issuer: "Test Root CA", Actions: []string{"c2pa.created", "c2pa.color_adjustments"},
+		Assertions: []string{"c2pa.actions.v2", "c2pa.hash.data"}}
+	tests := []struct {
+		name  string
+		size  int
+		store func(hash map[string]any) []byte
+		want  []exif.C2PAManifest
+	}{
+		{"one manifest", 60000, func(hash map[string]any) []byte {
+			return exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:active", claim, cert, hash, active.Actions))
+		}, []exif.C2PAManifest{active}},
+		{"split across packets", 100, func(hash map[string]any) []byte {
+			return exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:active", claim, cert, hash, active.Actions))
+		}, []exif.C2PAManifest{active}},
+		{"with ingredients", 60000, func(hash map[string]any) []byte {
+			parent := manifest("urn:uuid:parent", map[string]any{"claim_generator_info": []any{map[string]any{"name": "Camera", "version": "1.0"}}},
+				nil, nil, []string{"c2pa.created"})
+			edit := manifest("urn:uuid:edit", map[string]any{"claim_generator_info": map[string]any{"name": "Editor"}, "dc:title": "edit.jpg"},
+				cert, hash, []string{"c2pa.opened", "c2pa.edited"}, "DSCF0001.JPG", "overlay.png")
+			return exiftest.JUMBF("c2pa", "c2pa", parent, edit)
+		}, []exif.C2PAManifest{
+			{Label: "urn:uuid:parent", ClaimGenerator: "Camera 1.0", Actions: []string{"c2pa.created"}, Assertions: []string{"c2pa.actions.v2"}},
+			{Label: "urn:uuid:edit", ClaimGenerator: "Editor", Title: "edit.jpg", Signer: "Field Camera", Issuer: "Test Root CA",
+				Actions: []string{"c2pa.opened", "c2pa.edited"}, Ingredients: []string{"DSCF0001.JPG", "overlay.png"},
+				Assertions: []string{"c2pa.actions.v2", "c2pa.ingredient", "c2pa.ingredient__1", "c2pa.hash.data"}},
+		}},
+		{"empty store", 60000, func(map[string]any) []byte { return exiftest.JUMBF("c2pa", "c2pa") }, []exif.C2PAManifest{}},
+		{"other store", 60000, func(map[string]any) []byte { return exiftest.JUMBF("xmpm", "xmp") }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := signedJPEG(t, tt.size, tt.store)
+			c, err := exif.ReadC2PA(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if c != nil {
+					t.Fatalf("got %d manifests, want no store", len(c.Manifests))
+				}
+				return
+			}
+			got := []exif.C2PAManifest{}
+			for _, m := range c.Manifests {
+				got = append(got, public(m))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+			if a := c.Active(); len(tt.want) > 0 && a.CheckHash(img) != exif.C2PAHashMatch {
+				t.Errorf("hash %s, want %s", a.CheckHash(img), exif.C2PAHashMatch)
+			}
+		})
+	}
+	if c, err := exif.ReadC2PA(exiftest.New(binary.BigEndian).JPEG()); c != nil || err != nil {
+		t.Errorf("file without APP11: got %v, %v", c, err)
+	}
+}
+
+func TestC2PACheckHash(t *testing.T) {
+	store := func(hash map[string]any) func(map[string]any) []byte {
+		return func(signed map[string]any) []byte {
+			if hash == nil {
+				signed = nil
+			} else {
+				signed = maps.Clone(signed)
+				maps.Copy(signed, hash)
+			}
+			return exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:a", map[string]any{"alg": "sha512"}, nil, signed, nil))
+		}
+	}
+	tests := []struct {
+		name string
+		hash map[string]any // overrides of the signed hash assertion
+		edit func(img []byte) []byte
+		want string
+	}{
+		{"match", map[string]any{}, nil, exif.C2PAHashMatch},
+		{"exif edited", map[string]any{}, func(img []byte) []byte {
+			out, err := exif.Apply(img, exif.SetASCII(exif.TagArtist, "Someone"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		}, exif.C2PAHashMismatch},
+		{"image byte flipped", map[string]any{}, func(img []byte) []byte {
+			img[len(img)-3] ^= 1
+			return img
+		}, exif.C2PAHashMismatch},
+		{"claim algorithm", map[string]any{"alg": nil}, nil, exif.C2PAHashMismatch}, // sha512 of the claim
+		{"unsupported algorithm", map[string]any{"alg": "md5"}, nil, exif.C2PAHashUnchecked},
+		{"no hash", nil, nil, exif.C2PAHashUnchecked},
+		{"exclusion past the end", map[string]any{"exclusions": []any{map[string]any{"start": 2, "length": 1 << 40}}}, nil, exif.C2PAHashMismatch},
+		{"negative exclusion", map[string]any{"exclusions": []any{map[string]any{"start": 2, "length": -4}}}, nil, exif.C2PAHashMismatch},
+		{"overlapping exclusions", map[string]any{"exclusions": []any{map[string]any{"start": 2, "length": 10}, map[string]any{"start": 4, "length": 1}}}, nil, exif.C2PAHashMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := signedJPEG(t, 60000, store(tt.hash))
+			if tt.edit != nil {
+				img = tt.edit(img)
+			}
+			c, err := exif.ReadC2PA(img)
+			if err != nil || c == nil {
+				t.Fatalf("ReadC2PA: %v, %v", c, err)
+			}
+			if got := c.Active().CheckHash(img); got != tt.want {
+				t.Errorf("CheckHash = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestC2PASummary(t *testing.T) {
+	cert := signingChain(t)
+	img := signedJPEG(t, 500, func(hash map[string]any) []byte {
+		return exiftest.JUMBF("c2pa", "c2pa",
+			manifest("urn:uuid:parent", map[string]any{"claim_generator": "Camera"}, nil, nil, []string{"c2pa.created"}),
+			manifest("urn:uuid:edit", map[string]any{"claim_generator": "Editor/3"}, cert, hash, []string{"c2pa.edited"}))
+	})
+	s, err := exif.DecodeBytes(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.C2PAManifests != 2 || s.C2PAClaimGenerator != "Editor/3" || s.C2PASigner != "Field Camera" ||
+		!reflect.DeepEqual(s.C2PAActions, []string{"c2pa.edited"}) || s.C2PAHash != exif.C2PAHashMatch {
+		t.Errorf("got %d manifests, generator %q, signer %q, actions %q, hash %q",
+			s.C2PAManifests, s.C2PAClaimGenerator, s.C2PASigner, s.C2PAActions, s.C2PAHash)
+	}
+	if src := s.Sources["c2pa_signer"]; src != (exif.Source{Location: exif.LocationC2PA, Tag: "urn:uuid:edit"}) {
+		t.Errorf("signer source %+v", src)
+	}
+
+	stripped := exif.StripC2PA(img)
+	if c, _ := exif.ReadC2PA(stripped); c != nil {
+		t.Error("stripped file has a manifest store")
+	}
+	if len(stripped) >= len(img) || !bytes.Equal(stripped[:20], img[:20]) || !bytes.Equal(stripped[len(stripped)-20:], img[len(img)-20:]) {
+		t.Error("StripC2PA changed more than the APP11 segments")
+	}
+	if s, err := exif.DecodeBytes(stripped); err != nil || s.C2PAManifests != 0 || s.Make != "Fujifilm" {
+		t.Errorf("stripped file decodes as %+v, %v", s, err)
+	}
+	if plain := exiftest.New(binary.BigEndian).JPEG(); !bytes.Equal(exif.StripC2PA(plain), plain) {
+		t.Error("StripC2PA changed a file without a store")
+	}
+}
+
+func TestAnnotateC2PA(t *testing.T) {
+	img := signedJPEG(t, 60000, func(hash map[string]any) []byte {
+		return exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:a", map[string]any{}, nil, hash, nil))
+	})
+	c, _ := exif.ReadC2PA(img)
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*3600))
+	out, err := exif.AnnotateC2PA(img, c.Active(), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet := exif.XMP(out)
+	for _, want := range []string{"2024-05-01T00:30:00Z", "urn:uuid:a", exif.NamespaceC2PANote} {
+		if !bytes.Contains(packet, []byte(want)) {
+			t.Errorf("XMP packet lacks %q:\n%s", want, packet)
+		}
+	}
+	if after, _ := exif.ReadC2PA(out); after == nil || after.Active().CheckHash(out) != exif.C2PAHashMismatch {
+		t.Error("annotated file still matches its hash")
+	}
+	again, err := exif.AnnotateC2PA(out, c.Active(), at.Add(time.Hour))
+	if err != nil || !bytes.Equal(again, out) {
+		t.Errorf("second annotation changed the file: %v", err)
+	}
+}
+
+// TestC2PAMalformed reads manifest stores that are truncated or corrupt,
+// as untrusted files may hold, which must not panic and must not yield
+// values from the damaged parts.
+func TestC2PAMalformed(t *testing.T) {
+	cert := signingChain(t)
+	claim := map[string]any{"claim_generator": "FieldCam/2.1", "alg": "sha256"}
+	good := exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:a", claim, cert, map[string]any{"hash": []byte{1}}, []string{"c2pa.created"}))
+	jpeg := func(store []byte) []byte {
+		b := exiftest.New(binary.BigEndian)
+		for _, p := range exiftest.APP11(store, 1, 60000) {
+			b.Segment(exiftest.MarkerAPP11, p)
+		}
+		return b.JPEG()
+	}
+	read := func(img []byte) (c *exif.C2PA, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("ReadC2PA panicked: %v", r)
+			}
+		}()
+		c, err = exif.ReadC2PA(img)
+		if c != nil && c.Active() != nil {
+			c.Active().CheckHash(img)
+		}
+		exif.DecodeBytes(img)
+		return c, err
+	}
+
+	tests := []struct {
+		name  string
+		store []byte
+		check func(t *testing.T, c *exif.C2PA, err error)
+	}{
+		{"no description", exiftest.Box("jumb", exiftest.Box("free")), func(t *testing.T, c *exif.C2PA, err error) {
+			if err == nil {
+				t.Error("no error")
+			}
+		}},
+		{"short description", exiftest.Box("jumb", exiftest.Box("jumd", []byte("c2pa"))), func(t *testing.T, c *exif.C2PA, err error) {
+			if err == nil {
+				t.Error("no error")
+			}
+		}},
+		{"box longer than the store", append(binary.BigEndian.AppendUint32(nil, 1<<20), "jumb"...), func(t *testing.T, c *exif.C2PA, err error) {
+			if c != nil || err != nil {
+				t.Errorf("got %v, %v", c, err)
+			}
+		}},
+		{"huge claim length", exiftest.JUMBF("c2pa", "c2pa", exiftest.JUMBF("c2ma", "urn:uuid:a",
+			exiftest.JUMBF("c2cl", "c2pa.claim", exiftest.Box("cbor", []byte("\xA1\x6Fclaim_generator\x7B\xFF\xFF\xFF\xFF\xFF\xFF\xFF\xFF"))))),
+			func(t *testing.T, c *exif.C2PA, err error) {
+				if err != nil || len(c.Manifests) != 1 || c.Active().ClaimGenerator != "" {
+					t.Errorf("got %+v, %v", c, err)
+				}
+			}},
+		{"deeply nested assertion", exiftest.JUMBF("c2pa", "c2pa", exiftest.JUMBF("c2ma", "urn:uuid:a",
+			exiftest.JUMBF("c2as", "c2pa.assertions", exiftest.JUMBF("cbor", "c2pa.actions",
+				exiftest.Box("cbor", append(bytes.Repeat([]byte{0x81}, 10000), 0x00)))))),
+			func(t *testing.T, c *exif.C2PA, err error) {
+				if err != nil || len(c.Manifests) != 1 || c.Active().Actions != nil {
+					t.Errorf("got %+v, %v", c, err)
+				}
+			}},
+		{"signature not a certificate", exiftest.JUMBF("c2pa", "c2pa", exiftest.JUMBF("c2ma", "urn:uuid:a",
+			exiftest.JUMBF("c2cs", "c2pa.signature", exiftest.CBOR(cbor.Tag{Number: 18, Content: []any{[]byte{0xA1, 0x18, 0x21, 0x43, 1, 2, 3}, map[any]any{}, nil, []byte{}}})))),
+			func(t *testing.T, c *exif.C2PA, err error) {
+				if err != nil || c.Active().Signer != "" {
+					t.Errorf("got %+v, %v", c, err)
+				}
+			}},
+		{"wrong types", exiftest.JUMBF("c2pa", "c2pa", manifest("urn:uuid:a", map[string]any{"claim_generator": 7, "dc:title": []any{"x"}},
+			nil, map[string]any{"hash": "text", "exclusions": "all"}, nil)),
+			func(t *testing.T, c *exif.C2PA, err error) {
+				if err != nil || c.Active().ClaimGenerator != "" || c.Active().Title != "" {
+					t.Errorf("got %+v, %v", c, err)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := read(jpeg(tt.store))
+			tt.check(t, c, err)
+		})
+	}
+
+	// Every truncation and byte corruption of a good store.
+	for i := 8; i < len(good); i++ {
+		read(jpeg(good[:i]))
+		for _, b := range []byte{0x00, 0x01, 0xFF} {
+			corrupt := bytes.Clone(good)
+			corrupt[i] = b
+			read(jpeg(corrupt))
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UserComment character code prefixes defined by EXIF 2.32.
+var (
+	codeASCII     = []byte("ASCII\x00\x00\x00")
+	codeJIS       = []byte("JIS\x00\x00\x00\x00\x00")
+	codeUnicode   = []byte("UNICODE\x00")
+	codeUndefined = []byte("\x00\x00\x00\x00\x00\x00\x00\x00")
+)
+
+// DecodeUserComment decodes a UserComment value according to its 8-byte
+// character code prefix. order is the byte order of the enclosing TIFF
+// structure, which EXIF prescribes for UNICODE (UCS-2) comments; a byte
+// order mark overrides it. Comments without a recognized prefix are
+// decoded like ASCII fields.
+func DecodeUserComment(v []byte, order binary.ByteOrder) string {
+	if len(v) >= 8 {
+		code, text := v[:8], v[8:]
+		switch {
+		case bytes.Equal(code, codeUnicode):
+			return cleanText(decodeUCS2(text, order))
+		case bytes.Equal(code, codeJIS):
+			return cleanText(decodeJIS(text))
+		case bytes.Equal(code, codeASCII), bytes.Equal(code, codeUndefined):
+			return cleanText(DecodeText(text))
+		}
+	}
+	return cleanText(DecodeText(v))
+}
+
+// DecodeText decodes a nominally ASCII value. EXIF requires 7-bit ASCII
+// but cameras and editing software routinely store UTF-8, Shift_JIS or
+// Latin-1; they are tried in that order.
+func DecodeText(v []byte) string {
+	if i := bytes.IndexByte(v, 0); i >= 0 {
+		v = v[:i]
+	}
+	if utf8.Valid(v) {
+		return string(v)
+	}
+	if s, ok := decodeShiftJIS(v); ok {
+		return s
+	}
+	return decodeLatin1(v)
+}
+
+// decodeXP decodes a Windows XP* tag (XPTitle, XPComment, ...), which holds
+// NUL-terminated UTF-16LE text in a BYTE array.
+func decodeXP(v []byte) string {
+	return cleanText(decodeUCS2(v, binary.LittleEndian))
+}
+
+// decodeUCS2 decodes UTF-16 text, honoring a leading byte order mark.
+func decodeUCS2(v []byte, order binary.ByteOrder) string {
+	if len(v) >= 2 {
+		switch {
+		case v[0] == 0xFE && v[1] == 0xFF:
+			order, v = binary.BigEndian, v[2:]
+		case v[0] == 0xFF && v[1] == 0xFE:
+			order, v = binary.LittleEndian, v[2:]
+		}
+	}
+	units := make([]uint16, 0, len(v)/2)
+	for i := 0; i+1 < len(v); i += 2 {
+		u := order.Uint16(v[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(v []byte) string {
+	var b strings.Builder
+	for _, c := range v {
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+var (
+	jisOnce  sync.Once
+	jisRunes [94][]rune
+)
+
+// jisRune maps a JIS X 0208 row and cell (both 1-94) to Unicode.
+func jisRune(row, cell int) (rune, bool) {
+	if row < 1 || row > 94 || cell < 1 || cell > 94 {
+		return utf8.RuneError, false
+	}
+	jisOnce.Do(func() {
+		for i, r := range jis0208 {
+			jisRunes[i] = []rune(r)
+		}
+	})
+	r := jisRunes[row-1][cell-1]
+	return r, r != utf8.RuneError
+}
+
+// decodeJIS decodes ISO-2022-JP text: ASCII and JIS X 0201 Roman by
+// default, JIS X 0208 after ESC $ @ or ESC $ B. Bytes without the escape
+// sequences are treated as raw JIS X 0208 pairs when they fall in range.
+func decodeJIS(v []byte) string {
+	var b strings.Builder
+	double := !bytes.Contains(v, []byte{0x1B})
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == 0x1B && i+2 < len(v) {
+			switch string(v[i+1 : i+3]) {
+			case "$@", "$B":
+				double = true
+				i += 2
+				continue
+			case "(B", "(J":
+				double = false
+				i += 2
+				continue
+			}
+		}
+		if c == 0 {
+			break
+		}
+		if double && i+1 < len(v) && c >= 0x21 && c <= 0x7E {
+			r, ok := jisRune(int(c)-0x20, int(v[i+1])-0x20)
+			if !ok {
+				r = utf8.RuneError
+			}
+			b.WriteRune(r)
+			i++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeShiftJIS decodes Shift_JIS text, reporting false when v is not
+// well-formed Shift_JIS.
+func decodeShiftJIS(v []byte) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c >= 0xA1 && c <= 0xDF:
+			// Half-width katakana.
+			b.WriteRune(rune(c) - 0xA1 + 0xFF61)
+		case (c >= 0x81 && c <= 0x9F) || (c >= 0xE0 && c <= 0xEF):
+			if i+1 >= len(v) {
+				return "", false
+			}
+			t := v[i+1]
+			if t < 0x40 || t > 0xFC || t == 0x7F {
+				return "", false
+			}
+			row := int(c-0x81)*2 + 1
+			if c >= 0xE0 {
+				row = int(c-0xC1)*2 + 1
+			}
+			var cell int
+			switch {
+			case t >= 0x9F:
+				row++
+				cell = int(t) - 0x9E
+			case t >= 0x80:
+				cell = int(t) - 0x40
+			default:
+				cell = int(t) - 0x3F
+			}
+			r, ok := jisRune(row, cell)
+			if !ok {
+				return "", false
+			}
+			b.WriteRune(r)
+			i++
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// cleanText trims NULs and surrounding whitespace that writers use as
+// padding.
+func cleanText(s string) string {
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
@@ -0,0 +1,220 @@
+package exif
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Decoder answers queries about one image, parsing only what they need:
+// the TIFF structure on the first tag query, the tag index on the first
+// Lookup and the summary on the first Summary or Field call. Each stage
+// runs at most once, and a Decoder is safe for concurrent use, so a server
+// can decode an upload once and serve many field queries from it.
+type Decoder struct {
+	data   []byte
+	budget *budget
+
+	exifOnce sync.Once
+	exif     *Exif
+	exifErr  error
+
+	indexOnce sync.Once
+	index     map[tagKey]int
+
+	summaryOnce sync.Once
+	summary     *Summary
+	summaryErr  error
+
+	fieldsOnce sync.Once
+	fields     map[string]any
+
+	profile Profile
+}
+
+// Profile is the time a Decoder spent in each parsing stage. Stages that
+// have not run, or found nothing to parse, take no time.
+type Profile struct {
+	// Scan is the search for the TIFF structure, a walk of the JPEG
+	// segments in JPEG files.
+	Scan time.Duration
+	// IFD is the parse of the IFD chain and its sub-IFDs.
+	IFD       time.Duration
+	MakerNote time.Duration
+	// XMP is the extraction and parse of the XMP packets.
+	XMP time.Duration
+	// Total is the whole summary: the IPTC, ICC and C2PA reads and the
+	// stages above, unless a tag query ran Scan and IFD first.
+	Total time.Duration
+}
+
+type tagKey struct {
+	ifd IFDKind
+	tag uint16
+}
+
+// NewDecoder returns a Decoder for an in-memory image. The Decoder keeps
+// data; it must not be modified afterwards.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// NewLimitedDecoder is NewDecoder for untrusted input. An image larger
+// than l.MaxFileBytes is rejected at once; the other limits are enforced
+// while parsing, and every query fails with the LimitExceededError of the
+// first limit hit.
+func NewLimitedDecoder(data []byte, l Limits) (*Decoder, error) {
+	if l.MaxFileBytes > 0 && int64(len(data)) > l.MaxFileBytes {
+		return nil, &LimitExceededError{Limit: "MaxFileBytes", Max: l.MaxFileBytes, Actual: int64(len(data))}
+	}
+	return &Decoder{data: data, budget: &budget{limits: l}}, nil
+}
+
+// Exif returns the parsed TIFF structure, or ErrNoExif. The result is
+// shared between callers and must not be modified.
+func (d *Decoder) Exif() (*Exif, error) {
+	d.exifOnce.Do(func() {
+		start := time.Now()
+		if d.budget != nil && d.budget.limits.MaxSegmentBytes > 0 && IsJPEG(d.data) {
+			segs, _ := Segments(d.data)
+			for _, s := range segs {
+				if err := d.budget.value(uint64(len(s.Data))); err != nil {
+					d.exifErr = err
+					return
+				}
+			}
+		}
+		tiff, err := findTIFF(d.data)
+		d.profile.Scan = time.Since(start)
+		if err != nil {
+			d.exifErr = err
+			return
+		}
+		start = time.Now()
+		d.exif, d.exifErr = parse(tiff, d.budget)
+		d.profile.IFD = time.Since(start)
+	})
+	return d.exif, d.exifErr
+}
+
+// Lookup returns the first entry with the given tag in the given
+// directory, through an index built on first use.
+func (d *Decoder) Lookup(ifd IFDKind, tag uint16) (Entry, bool, error) {
+	x, err := d.Exif()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	d.indexOnce.Do(func() {
+		d.index = make(map[tagKey]int, len(x.Entries))
+		for i, e := range x.Entries {
+			k := tagKey{e.IFD, e.Tag}
+			if _, ok := d.index[k]; !ok {
+				d.index[k] = i
+			}
+		}
+	})
+	i, ok := d.index[tagKey{ifd, tag}]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	return x.Entries[i], true, nil
+}
+
+// Summary returns the image summary as DecodeBytes does. Each call returns
+// a copy the caller may modify.
+func (d *Decoder) Summary() (*Summary, error) {
+	s, err := d.shared()
+	if err != nil {
+		return nil, err
+	}
+	return s.clone(), nil
+}
+
+// shared returns the summary shared by all callers, building it once.
+func (d *Decoder) shared() (*Summary, error) {
+	d.summaryOnce.Do(func() {
+		d.summary, d.summaryErr = d.summarize()
+	})
+	return d.summary, d.summaryErr
+}
+
+// Profile returns the time spent in each stage, building the summary
+// first if no Summary or Field call has.
+func (d *Decoder) Profile() Profile {
+	d.shared()
+	return d.profile
+}
+
+func (d *Decoder) summarize() (*Summary, error) {
+	start := time.Now()
+	defer func() { d.profile.Total = time.Since(start) }()
+	s := &Summary{}
+	x, err := d.Exif()
+	switch {
+	case err == nil:
+		s = summarizeTags(x)
+		mn := time.Now()
+		summarizeMakerNote(x, s)
+		d.profile.MakerNote = time.Since(mn)
+		// Maker notes are parsed within the same budget.
+		if d.budget != nil && d.budget.err != nil {
+			return nil, d.budget.err
+		}
+	case !errors.Is(err, ErrNoExif):
+		return nil, err
+	}
+	if IsHEIF(d.data) {
+		summarizeHEIFEncoding(d.data, s)
+	}
+	if IsJPEG(d.data) {
+		summarizeJPEGEncoding(d.data, s)
+		summarizeJFIF(d.data, s)
+		xs := time.Now()
+		xmp := XMPProperties(XMP(d.data))
+		d.profile.XMP = time.Since(xs)
+		if summarizeJPEG(d.data, xmp, s) {
+			return s, nil
+		}
+	}
+	if x == nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Field returns one summary field by JSON name, as in Summary.Fields. The
+// value is shared between callers and must not be modified.
+func (d *Decoder) Field(name string) (any, bool, error) {
+	s, err := d.shared()
+	if err != nil {
+		return nil, false, err
+	}
+	d.fieldsOnce.Do(func() {
+		d.fields = s.Fields()
+	})
+	v, ok := d.fields[name]
+	return v, ok, nil
+}
+
+// clone returns a deep copy of s.
+func (s *Summary) clone() *Summary {
+	c := *s
+	c.Keywords = slices.Clone(s.Keywords)
+	c.Collections = slices.Clone(s.Collections)
+	c.EditModules = slices.Clone(s.EditModules)
+	c.C2PAActions = slices.Clone(s.C2PAActions)
+	c.Computational = slices.Clone(s.Computational)
+	c.Sources = maps.Clone(s.Sources)
+	for _, p := range []**float64{
+		&c.Latitude, &c.Longitude, &c.Altitude, &c.SunElevation, &c.MoonPhase, &c.MoonIllumination, &c.MoonAltitude,
+		&c.PoseHeading, &c.RelativeAltitude, &c.FlightHeading, &c.GimbalPitch, &c.GimbalYaw, &c.FlightSpeed,
+	} {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,130 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of a Change.
+const (
+	ChangeRemoved = "removed"
+	ChangeAltered = "altered"
+	ChangeAdded   = "added"
+)
+
+// Change is a tag that differs between two versions of a file.
+type Change struct {
+	IFD IFDKind `json:"-"`
+	Tag uint16  `json:"-"`
+	// Dir and ID are IFD and Tag as text, for reports.
+	Dir  string `json:"ifd"`
+	ID   string `json:"tag"`
+	Name string `json:"name,omitempty"`
+	Kind string `json:"kind"`
+	// Before and After are the formatted values; Before is empty for an
+	// added tag and After for a removed one.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Diff compares the EXIF entries of two versions of a JPEG or TIFF-based
+// file, as read by Inspect, and returns the tags removed, altered or added
+// in the order of before followed by the added tags. Offsets and
+// sub-directory pointers are not reported as altered, since rewriting a
+// file moves them without changing the metadata. A file without EXIF data
+// has no entries.
+func Diff(before, after []byte) ([]Change, error) {
+	old, err := diffFields(before)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := diffFields(after)
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		ifd IFDKind
+		tag uint16
+	}
+	index := func(fields []Field) map[key]Field {
+		m := make(map[key]Field, len(fields))
+		for _, f := range fields {
+			if _, dup := m[key{f.IFD, f.Tag}]; !dup {
+				m[key{f.IFD, f.Tag}] = f
+			}
+		}
+		return m
+	}
+	oldBy, curBy := index(old), index(cur)
+	var changes []Change
+	seen := map[key]bool{}
+	for _, f := range old {
+		k := key{f.IFD, f.Tag}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		c := newChange(f)
+		c.Before = formatValue(f.Entry)
+		g, ok := curBy[k]
+		switch {
+		case !ok:
+			c.Kind = ChangeRemoved
+		case isOffset(f.IFD, f.Tag) || (f.Type == g.Type && string(f.Value) == string(g.Value)):
+			continue
+		default:
+			c.Kind, c.After = ChangeAltered, formatValue(g.Entry)
+		}
+		changes = append(changes, c)
+	}
+	for _, f := range cur {
+		k := key{f.IFD, f.Tag}
+		if _, ok := oldBy[k]; ok || seen[k] {
+			continue
+		}
+		seen[k] = true
+		c := newChange(f)
+		c.Kind, c.After = ChangeAdded, formatValue(f.Entry)
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// diffFields returns the entries of file, or none when it has no EXIF
+// data.
+func diffFields(file []byte) ([]Field, error) {
+	b, err := Inspect(file)
+	if errors.Is(err, ErrNoExif) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Fields, nil
+}
+
+func newChange(f Field) Change {
+	return Change{IFD: f.IFD, Tag: f.Tag, Dir: f.IFD.String(), ID: fmt.Sprintf("0x%04X", f.Tag), Name: f.Name}
+}
+
+// formatValue renders e as the registry formats its tag.
+func formatValue(e Entry) string {
+	if t, ok := LookupTag(e.IFD, e.Tag); ok {
+		return t.Format(e)
+	}
+	return FormatEntry(e)
+}
+
+// isOffset reports whether the tag's value is a position in the file.
+func isOffset(ifd IFDKind, tag uint16) bool {
+	switch tag {
+	case TagStripOffsets, TagJPEGInterchangeFormat:
+		return true
+	}
+	for _, p := range pointerTags {
+		if p.ID == tag && (p.IFD == ifd || (p.IFD == IFD0 && ifd == IFD1)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package exif
+
+import (
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NamespaceDJI is the schema DJI drones write their flight data in.
+const NamespaceDJI = "http://www.dji.com/drone-dji/1.0/"
+
+// summarizeDJI fills in the flight fields from DJI XMP. DJI writes
+// numbers with an explicit sign, such as "+45.20", and yaw angles from
+// -180 to 180, which become compass headings.
+func summarizeDJI(xmp map[xml.Name][]string, s *Summary) {
+	number := func(name string) (float64, bool) {
+		v := xmp[xml.Name{Space: NamespaceDJI, Local: name}]
+		if len(v) == 0 {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimPrefix(v[0], "+"), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	set := func(field, name string, dst **float64, places int, convert func(float64) float64) {
+		v, ok := number(name)
+		if !ok {
+			return
+		}
+		if convert != nil {
+			v = convert(v)
+		}
+		v = round(v, places)
+		*dst = &v
+		s.setSource(xmpSource(NamespaceDJI, name), field)
+	}
+	heading := func(v float64) float64 { return math.Mod(v+360, 360) }
+
+	set("relative_altitude", "RelativeAltitude", &s.RelativeAltitude, 1, nil)
+	set("flight_heading", "FlightYawDegree", &s.FlightHeading, 1, heading)
+	set("gimbal_pitch", "GimbalPitchDegree", &s.GimbalPitch, 1, nil)
+	set("gimbal_yaw", "GimbalYawDegree", &s.GimbalYaw, 1, heading)
+	// The speeds are north, east and down components in m/s; the ground
+	// speed leaves out the vertical one.
+	x, okX := number("FlightXSpeed")
+	y, okY := number("FlightYSpeed")
+	if okX && okY {
+		v := round(math.Hypot(x, y), 1)
+		s.FlightSpeed = &v
+		s.setSource(xmpSource(NamespaceDJI, "FlightXSpeed"), "flight_speed")
+	}
+}
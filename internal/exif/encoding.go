@@ -0,0 +1,155 @@
+package exif
+
+import "fmt"
+
+// Image codecs.
+const (
+	CodecJPEG = "jpeg"
+	CodecHEVC = "hevc"
+	CodecAV1  = "av1"
+)
+
+// JPEG encoding processes, from the SOF marker.
+const (
+	EncodingBaseline     = "baseline"
+	EncodingExtended     = "extended"
+	EncodingProgressive  = "progressive"
+	EncodingLossless     = "lossless"
+	EncodingHierarchical = "hierarchical"
+)
+
+// LocationJPEG and LocationHEIF are the image's own structure: the JPEG
+// frame header and the HEIF item properties.
+const (
+	LocationJPEG = "JPEG"
+	LocationHEIF = "HEIF"
+)
+
+// sofEncodings maps the SOF markers to their processes. Arithmetic-coded
+// frames share the process of their Huffman counterparts.
+var sofEncodings = map[byte]string{
+	0xC0: EncodingBaseline, 0xC1: EncodingExtended, 0xC2: EncodingProgressive, 0xC3: EncodingLossless,
+	0xC5: EncodingHierarchical, 0xC6: EncodingHierarchical, 0xC7: EncodingHierarchical,
+	0xC9: EncodingExtended, 0xCA: EncodingProgressive, 0xCB: EncodingLossless,
+	0xCD: EncodingHierarchical, 0xCE: EncodingHierarchical, 0xCF: EncodingHierarchical,
+}
+
+// summarizeJPEGEncoding fills in the encoding fields from the JPEG frame
+// header.
+func summarizeJPEGEncoding(data []byte, s *Summary) {
+	segs, _ := Segments(data)
+	for _, seg := range segs {
+		encoding, ok := sofEncodings[seg.Marker]
+		// The frame header is precision, height, width, the component
+		// count and 3 bytes per component.
+		if !ok || len(seg.Data) < 6 {
+			continue
+		}
+		n := int(seg.Data[5])
+		if len(seg.Data) < 6+3*n {
+			return
+		}
+		src := Source{Location: LocationJPEG, Tag: fmt.Sprintf("0xFF%02X", seg.Marker)}
+		s.Codec, s.Encoding, s.BitDepth, s.Components = CodecJPEG, encoding, int(seg.Data[0]), n
+		fields := []string{"codec", "encoding", "bit_depth", "components"}
+		if n == 3 {
+			// Sampling factors are packed horizontal<<4 | vertical.
+			y, cb, cr := seg.Data[7], seg.Data[10], seg.Data[13]
+			if cb == cr && cb&0xF != 0 && cb>>4 != 0 {
+				s.ChromaSubsampling = subsampling(int(y>>4)/int(cb>>4), int(y&0xF)/int(cb&0xF))
+			}
+			if s.ChromaSubsampling != "" {
+				fields = append(fields, "chroma_subsampling")
+			}
+		}
+		s.setSource(src, fields...)
+		return
+	}
+}
+
+// subsampling names the chroma subsampling of horizontal and vertical
+// luma-to-chroma ratios, or returns "" for an unusual one.
+func subsampling(h, v int) string {
+	switch {
+	case h == 1 && v == 1:
+		return "4:4:4"
+	case h == 2 && v == 1:
+		return "4:2:2"
+	case h == 2 && v == 2:
+		return "4:2:0"
+	case h == 4 && v == 1:
+		return "4:1:1"
+	case h == 1 && v == 2:
+		return "4:4:0"
+	}
+	return ""
+}
+
+// summarizeHEIFEncoding fills in the encoding fields from the codec
+// configuration of a HEIF file's primary image. Grid images, as phones
+// write, are described by their first tile.
+func summarizeHEIFEncoding(data []byte, s *Summary) {
+	m, err := readHEIFMeta(data)
+	if err != nil {
+		return
+	}
+	primary, ok := m.items[m.primary]
+	if !ok {
+		return
+	}
+	coded := primary
+	if primary.typ == "grid" {
+		for _, id := range m.order {
+			if t := m.items[id].typ; t == "hvc1" || t == "av01" {
+				coded = m.items[id]
+				break
+			}
+		}
+	}
+	set := func(typ string, fields ...string) {
+		s.setSource(Source{Location: LocationHEIF, Tag: typ}, fields...)
+	}
+	if b, ok := m.property(coded, "hvcC"); ok && len(b.data) >= 19 {
+		// chroma_format_idc and bit_depth_luma_minus8 sit in the low bits
+		// of bytes 16 and 17 of the HEVC decoder configuration record.
+		s.Codec, s.BitDepth = CodecHEVC, int(b.data[17]&7)+8
+		s.Components = 3
+		s.ChromaSubsampling = [...]string{"", "4:2:0", "4:2:2", "4:4:4"}[b.data[16]&3]
+		if b.data[16]&3 == 0 {
+			s.Components = 1
+		}
+		set(b.typ, "codec", "bit_depth", "components")
+	} else if b, ok := m.property(coded, "av1C"); ok && len(b.data) >= 3 {
+		flags := b.data[2]
+		s.Codec, s.BitDepth, s.Components = CodecAV1, 8, 3
+		switch {
+		case flags&0x40 != 0 && flags&0x20 != 0:
+			s.BitDepth = 12
+		case flags&0x40 != 0:
+			s.BitDepth = 10
+		}
+		x, y := flags&0x08 != 0, flags&0x04 != 0
+		switch {
+		case flags&0x10 != 0:
+			s.Components = 1
+		case x && y:
+			s.ChromaSubsampling = "4:2:0"
+		case x:
+			s.ChromaSubsampling = "4:2:2"
+		default:
+			s.ChromaSubsampling = "4:4:4"
+		}
+		set(b.typ, "codec", "bit_depth", "components")
+	} else {
+		return
+	}
+	if s.ChromaSubsampling != "" {
+		s.Sources["chroma_subsampling"] = s.Sources["codec"]
+	}
+	// pixi, when present, states the decoded bit depth and channels, alpha
+	// excluded since it is a separate item.
+	if b, ok := m.property(primary, "pixi"); ok && len(b.data) >= 6 {
+		s.Components, s.BitDepth = int(b.data[4]), int(b.data[5])
+		set(b.typ, "bit_depth", "components")
+	}
+}
@@ -0,0 +1,116 @@
+// Package exif decodes EXIF metadata embedded in JPEG and TIFF-based image
+// files and condenses it into a Summary.
+package exif
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Errors returned by the decoder. Wrapped errors carry additional context
+// and can be matched with errors.Is.
+var (
+	ErrNoExif    = errors.New("exif: no EXIF data")
+	ErrFormat    = errors.New("exif: malformed data")
+	ErrTruncated = errors.New("exif: truncated data")
+)
+
+// Exif holds every entry parsed from a TIFF structure.
+type Exif struct {
+	Order   binary.ByteOrder
+	Entries []Entry
+
+	// data is the TIFF structure, starting at the byte order mark. Maker
+	// note and thumbnail offsets are relative to it.
+	data []byte
+	// budget enforces the decoder's limits, including on maker notes
+	// parsed later.
+	budget *budget
+}
+
+// Parse decodes a TIFF structure such as the payload of a JPEG APP1 Exif
+// segment (without the "Exif\0\0" prefix). Broken sub-directories are
+// skipped so that as much metadata as possible is recovered; only a broken
+// IFD0 is reported as an error.
+func Parse(data []byte) (*Exif, error) {
+	return parse(data, nil)
+}
+
+// parse is Parse within a budget. Exceeding it fails the parse even when
+// the directory at fault is a sub-directory.
+func parse(data []byte, b *budget) (*Exif, error) {
+	order, off, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	x := &Exif{Order: order, data: data, budget: b}
+	r := ifdReader{data: data, order: order, budget: b}
+
+	ifd0, next, err := r.readIFD(off, IFD0)
+	if err != nil {
+		return nil, err
+	}
+	x.Entries = append(x.Entries, ifd0...)
+	if next != 0 && next != off {
+		if ifd1, _, err := r.readIFD(next, IFD1); err == nil {
+			x.Entries = append(x.Entries, ifd1...)
+		}
+	}
+
+	visited := map[uint32]bool{off: true, next: true}
+	follow := func(pointer uint16, from, kind IFDKind) {
+		e, ok := x.Lookup(from, pointer)
+		if !ok {
+			return
+		}
+		sub, ok := e.Uint(0)
+		if !ok || visited[sub] {
+			return
+		}
+		visited[sub] = true
+		if entries, _, err := r.readIFD(sub, kind); err == nil {
+			x.Entries = append(x.Entries, entries...)
+		}
+	}
+	for _, p := range pointerTags {
+		follow(p.ID, p.IFD, p.sub)
+	}
+	if b != nil && b.err != nil {
+		return nil, b.err
+	}
+	return x, nil
+}
+
+// Lookup returns the first entry with the given tag in the given directory.
+func (x *Exif) Lookup(ifd IFDKind, tag uint16) (Entry, bool) {
+	for _, e := range x.Entries {
+		if e.IFD == ifd && e.Tag == tag {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// String returns the trimmed ASCII value of a tag, or "" when absent.
+func (x *Exif) String(ifd IFDKind, tag uint16) string {
+	if e, ok := x.Lookup(ifd, tag); ok {
+		return e.String()
+	}
+	return ""
+}
+
+// Float returns the first value of a numeric tag.
+func (x *Exif) Float(ifd IFDKind, tag uint16) (float64, bool) {
+	if e, ok := x.Lookup(ifd, tag); ok {
+		return e.Float(0)
+	}
+	return 0, false
+}
+
+// Uint returns the first value of an unsigned integer tag.
+func (x *Exif) Uint(ifd IFDKind, tag uint16) (uint32, bool) {
+	if e, ok := x.Lookup(ifd, tag); ok {
+		return e.Uint(0)
+	}
+	return 0, false
+}
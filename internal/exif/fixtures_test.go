@@ -0,0 +1,170 @@
+package exif_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestDecoderScenarios(t *testing.T) {
+	for _, sc := range exiftest.Scenarios() {
+		t.Run(sc.Name, func(t *testing.T) {
+			img := sc.Encode()
+			dir := filepath.Join(exiftest.Testdata(), sc.Name)
+			if err := exiftest.CheckGolden(filepath.Join(dir, sc.File()), img, *update); err != nil {
+				t.Fatal(err)
+			}
+			s, err := exif.DecodeBytes(img)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := exiftest.MarshalGolden(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := exiftest.CheckGolden(filepath.Join(dir, "summary.json"), got, *update); err != nil {
+				t.Error(err)
+			}
+			for _, v := range exif.Validate(img) {
+				if v.Severity == exif.SeverityError {
+					t.Errorf("invalid EXIF: %s", v)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyScenarios edits every scenario and checks that what the edits
+// did not touch, maker notes included, decodes as before.
+func TestApplyScenarios(t *testing.T) {
+	edits := []struct {
+		name  string
+		edits []exif.Edit
+		want  map[string]any
+	}{
+		{"artist", []exif.Edit{exif.SetASCII(exif.TagArtist, "Hanako Yamada")}, map[string]any{"artist": "Hanako Yamada"}},
+		{"copyright", []exif.Edit{exif.SetASCII(exif.TagCopyright, "© 2024 Studio")}, map[string]any{"copyright": "© 2024 Studio"}},
+		{"delete-software", []exif.Edit{exif.Delete(exif.TagSoftware)}, map[string]any{"software": nil}},
+	}
+	for _, sc := range exiftest.Scenarios() {
+		for _, e := range edits {
+			t.Run(sc.Name+"/"+e.name, func(t *testing.T) {
+				img := sc.Encode()
+				before, err := exif.DecodeBytes(img)
+				if err != nil {
+					t.Fatal(err)
+				}
+				edited, err := exif.Apply(img, e.edits...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				after, err := exif.DecodeBytes(edited)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := before.Fields()
+				for k, v := range e.want {
+					if v == nil {
+						delete(want, k)
+					} else {
+						want[k] = v
+					}
+				}
+				if got := after.Fields(); !reflect.DeepEqual(got, want) {
+					t.Errorf("fields after the edit:\ngot  %v\nwant %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestApplyHEIF checks that edits of HEIF files leave the other items in
+// place and can be repeated.
+func TestApplyHEIF(t *testing.T) {
+	for _, sc := range exiftest.Scenarios() {
+		if sc.HEIF == nil {
+			continue
+		}
+		t.Run(sc.Name, func(t *testing.T) {
+			img := sc.Encode()
+			coded := img[bytes.Index(img, []byte("mdat"))+4:][:len("coded image")]
+			out := img
+			for _, rating := range []uint16{1, 3, 5} {
+				var err error
+				if out, err = exif.Apply(out, exif.SetShort(exif.TagRating, rating)); err != nil {
+					t.Fatal(err)
+				}
+				if !exif.IsHEIF(out) {
+					t.Fatal("edited file is not HEIF")
+				}
+				s, err := exif.DecodeBytes(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.Rating != int(rating) {
+					t.Errorf("rating %d, want %d", s.Rating, rating)
+				}
+				if s.Codec == "" {
+					t.Error("codec configuration lost")
+				}
+			}
+			if !bytes.Equal(out[bytes.Index(out, []byte("mdat"))+4:][:len(coded)], coded) {
+				t.Error("coded image data moved")
+			}
+		})
+	}
+}
+
+// TestApplyRepeatedEdits checks that repeating edits, of inline and out of
+// line values alike, and of values growing and shrinking in turn, does not
+// grow JPEG and HEIF files.
+func TestApplyRepeatedEdits(t *testing.T) {
+	rounds := []struct {
+		name  string
+		edits func(i int) []exif.Edit
+	}{
+		{"rating", func(i int) []exif.Edit { return []exif.Edit{exif.SetShort(exif.TagRating, 3)} }},
+		{"alternating", func(i int) []exif.Edit {
+			if i%2 == 0 {
+				return []exif.Edit{exif.SetASCII(exif.TagArtist, fmt.Sprintf("Artist %03d", i))}
+			}
+			return []exif.Edit{exif.SetASCII(exif.TagLensModel, fmt.Sprintf("Lens %03d", i))}
+		}},
+		{"growing and shrinking", func(i int) []exif.Edit {
+			return []exif.Edit{exif.SetASCII(exif.TagImageDescription, strings.Repeat("Sunset over the bay. ", 1+i%3*20))}
+		}},
+	}
+	for _, sc := range exiftest.Scenarios() {
+		for _, r := range rounds {
+			t.Run(sc.Name+"/"+r.name, func(t *testing.T) {
+				// The first rounds go through every size once; later ones
+				// must not outgrow the largest file those left.
+				out := sc.Encode()
+				var settled int
+				for i := 0; i < 300; i++ {
+					var err error
+					if out, err = exif.Apply(out, r.edits(i)...); err != nil {
+						t.Fatal(err)
+					}
+					if i < 6 {
+						settled = max(settled, len(out))
+					} else if len(out) > settled {
+						t.Fatalf("%d bytes after %d edits, at most %d after 6", len(out), i+1, settled)
+					}
+				}
+				if _, err := exif.DecodeBytes(out); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
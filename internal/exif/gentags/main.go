@@ -0,0 +1,229 @@
+// Command gentags turns the tag table internal/exif/tags.tsv into the Go
+// constants and registry of internal/exif/tags.go, or with -check verifies
+// that the committed file is up to date.
+//
+//	go generate ./internal/exif
+//	go run ./internal/exif/gentags -dir internal/exif -check
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// columns are the columns of tags.tsv in order.
+var columns = []string{"ifd", "id", "name", "const", "types", "count", "fields", "format", "values", "required", "recommended", "description"}
+
+// ifds maps the directory names of the table to their IFDKind.
+var ifds = map[string]string{
+	"IFD0": "IFD0", "IFD1": "IFD1", "ExifIFD": "ExifIFD", "GPS": "GPSIFD", "Interop": "InteropIFD",
+}
+
+// types maps TIFF type names to their Type constant.
+var types = map[string]string{
+	"BYTE": "TypeByte", "ASCII": "TypeASCII", "SHORT": "TypeShort", "LONG": "TypeLong",
+	"RATIONAL": "TypeRational", "SBYTE": "TypeSByte", "UNDEFINED": "TypeUndefined", "SSHORT": "TypeSShort",
+	"SLONG": "TypeSLong", "SRATIONAL": "TypeSRational", "FLOAT": "TypeFloat", "DOUBLE": "TypeDouble",
+}
+
+// formats are the renderings the exif package implements.
+var formats = map[string]bool{
+	"": true, "xp": true, "comment": true, "version": true, "components": true, "exposure": true,
+	"fnumber": true, "mm": true, "m": true, "ev": true, "decimal": true, "dms": true, "time": true,
+	"gpsversion": true, "lens": true,
+}
+
+func main() {
+	dir := flag.String("dir", ".", "directory holding tags.tsv and tags.go")
+	check := flag.Bool("check", false, "compare against the existing tags.go instead of writing it")
+	flag.Parse()
+	if err := run(*dir, *check); err != nil {
+		fmt.Fprintf(os.Stderr, "gentags: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, check bool) error {
+	table := filepath.Join(dir, "tags.tsv")
+	f, err := os.Open(table)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows, err := read(f)
+	if err != nil {
+		return fmt.Errorf("%s:%w", table, err)
+	}
+	src, err := generate(rows)
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(dir, "tags.go")
+	if !check {
+		return os.WriteFile(out, src, 0o644)
+	}
+	want, err := os.ReadFile(out)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(src, want) {
+		return fmt.Errorf("%s is out of date; run go generate ./internal/exif", out)
+	}
+	return nil
+}
+
+// row is a line of the table keyed by column.
+type row map[string]string
+
+// read parses the table, skipping comments and the header line.
+func read(f *os.File) ([]row, error) {
+	var rows []row
+	sc := bufio.NewScanner(f)
+	n := 0
+	header := false
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cells := strings.Split(line, "\t")
+		if len(cells) != len(columns) {
+			return nil, fmt.Errorf("%d: %d columns, want %d", n, len(cells), len(columns))
+		}
+		if !header {
+			if strings.Join(cells, "\t") != strings.Join(columns, "\t") {
+				return nil, fmt.Errorf("%d: unexpected header", n)
+			}
+			header = true
+			continue
+		}
+		r := row{"line": strconv.Itoa(n)}
+		for i, c := range columns {
+			r[c] = cells[i]
+		}
+		rows = append(rows, r)
+	}
+	return rows, sc.Err()
+}
+
+// generate renders tags.go from the rows.
+func generate(rows []row) ([]byte, error) {
+	var consts, table bytes.Buffer
+	seen := map[string]string{}
+	last := map[string]int64{}
+	for _, r := range rows {
+		at := func(format string, args ...any) error {
+			return fmt.Errorf("tags.tsv:%s: %s", r["line"], fmt.Sprintf(format, args...))
+		}
+		kind, ok := ifds[r["ifd"]]
+		if !ok {
+			return nil, at("unknown directory %q", r["ifd"])
+		}
+		id, err := strconv.ParseUint(r["id"], 0, 16)
+		if err != nil {
+			return nil, at("bad ID %q", r["id"])
+		}
+		if prev, ok := last[kind]; ok && int64(id) <= prev {
+			return nil, at("%s 0x%04X out of order", r["ifd"], id)
+		}
+		last[kind] = int64(id)
+		name := "Tag" + r["name"]
+		if r["const"] != "" {
+			name = "Tag" + r["const"]
+		}
+		if other, ok := seen[name]; ok {
+			return nil, at("%s already defined on line %s", name, other)
+		}
+		seen[name] = r["line"]
+		fmt.Fprintf(&consts, "\t%s uint16 = 0x%04X\n", name, id)
+
+		fmt.Fprintf(&table, "\t{IFD: %s, ID: %s, Name: %q", kind, name, r["name"])
+		var ts []string
+		for _, t := range strings.Split(r["types"], ",") {
+			c, ok := types[t]
+			if !ok {
+				return nil, at("unknown type %q", t)
+			}
+			ts = append(ts, c)
+		}
+		fmt.Fprintf(&table, ", Types: []Type{%s}", strings.Join(ts, ", "))
+		if r["count"] != "" {
+			if _, err := strconv.ParseUint(r["count"], 10, 32); err != nil {
+				return nil, at("bad count %q", r["count"])
+			}
+			fmt.Fprintf(&table, ", Count: %s", r["count"])
+		}
+		fmt.Fprintf(&table, ",\n\t\tDescription: %q", r["description"])
+		if r["fields"] != "" {
+			fmt.Fprintf(&table, ", Fields: %s", stringList(strings.Split(r["fields"], ",")))
+		}
+		if v := r["values"]; v != "" {
+			closed := !strings.HasSuffix(v, ";*")
+			v = strings.TrimSuffix(v, ";*")
+			var pairs []string
+			for _, p := range strings.Split(v, ";") {
+				value, name, ok := strings.Cut(p, "=")
+				if !ok {
+					return nil, at("value %q is not value=name", p)
+				}
+				pairs = append(pairs, fmt.Sprintf("{%q, %q}", value, name))
+			}
+			fmt.Fprintf(&table, ",\n\t\tValues: []TagValue{%s}", strings.Join(pairs, ", "))
+			if closed {
+				table.WriteString(", Closed: true")
+			}
+		}
+		for _, c := range []struct{ col, field string }{{"required", "Required"}, {"recommended", "Recommended"}} {
+			if r[c.col] == "" {
+				continue
+			}
+			var ks []string
+			for _, d := range strings.Split(r[c.col], ",") {
+				k, ok := ifds[d]
+				if !ok {
+					return nil, at("unknown directory %q in %s", d, c.col)
+				}
+				ks = append(ks, k)
+			}
+			fmt.Fprintf(&table, ", %s: []IFDKind{%s}", c.field, strings.Join(ks, ", "))
+		}
+		if f := r["format"]; strings.HasPrefix(f, "ifd:") {
+			k, ok := ifds[strings.TrimPrefix(f, "ifd:")]
+			if !ok {
+				return nil, at("unknown directory in format %q", f)
+			}
+			fmt.Fprintf(&table, ", sub: %s", k)
+		} else if !formats[f] {
+			return nil, at("unknown format %q", f)
+		} else if f != "" {
+			fmt.Fprintf(&table, ", format: %q", f)
+		}
+		table.WriteString("},\n")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gentags from tags.tsv; DO NOT EDIT.\n\npackage exif\n\n")
+	b.WriteString("// IDs of the tags in tags.tsv.\nconst (\n")
+	b.Write(consts.Bytes())
+	b.WriteString(")\n\n// tagTable holds the rows of tags.tsv in directory and ID order.\nvar tagTable = []TagInfo{\n")
+	b.Write(table.Bytes())
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// stringList renders a []string literal.
+func stringList(list []string) string {
+	q := make([]string, len(list))
+	for i, s := range list {
+		q[i] = strconv.Quote(s)
+	}
+	return "[]string{" + strings.Join(q, ", ") + "}"
+}
@@ -0,0 +1,499 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// heifBrands are the ftyp brands of HEIF images, HEIC and AVIF included.
+var heifBrands = map[string]bool{
+	"mif1": true, "msf1": true, "heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "avif": true, "avis": true,
+}
+
+// box is an ISO base media file format box. Data excludes the header.
+type box struct {
+	typ  string
+	data []byte
+	// offset is the position of data within the file.
+	offset int
+}
+
+// boxes splits data, found at offset within the file, into its boxes.
+func boxes(data []byte, offset int) ([]box, error) {
+	var out []box
+	for pos := 0; pos < len(data); {
+		if pos+8 > len(data) {
+			return out, fmt.Errorf("%w: box header at offset %d", ErrTruncated, offset+pos)
+		}
+		size := int64(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		header := 8
+		switch size {
+		case 0:
+			// The last box extends to the end of the file.
+			size = int64(len(data) - pos)
+		case 1:
+			if pos+16 > len(data) {
+				return out, fmt.Errorf("%w: box header at offset %d", ErrTruncated, offset+pos)
+			}
+			size = int64(binary.BigEndian.Uint64(data[pos+8:]))
+			header = 16
+		}
+		if size < int64(header) || size > int64(len(data)-pos) {
+			return out, fmt.Errorf("%w: box %q at offset %d", ErrTruncated, typ, offset+pos)
+		}
+		out = append(out, box{typ: typ, data: data[pos+header : pos+int(size)], offset: offset + pos + header})
+		pos += int(size)
+	}
+	return out, nil
+}
+
+// IsHEIF reports whether data starts with the ftyp box of a HEIF image,
+// such as HEIC or AVIF.
+func IsHEIF(data []byte) bool {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 16 || size > len(data) {
+		return false
+	}
+	if heifBrands[string(data[8:12])] {
+		return true
+	}
+	for i := 16; i+4 <= size; i += 4 {
+		if heifBrands[string(data[i:i+4])] {
+			return true
+		}
+	}
+	return false
+}
+
+// heifItem is an item of a HEIF meta box: an image, tile or metadata
+// block.
+type heifItem struct {
+	typ string
+	// extents are the item's data, concatenated.
+	extents [][2]uint64
+	// idat marks extents relative to the meta box's idat rather than the
+	// file.
+	idat bool
+	// props are the indexes of its properties in ipco, from 1.
+	props []int
+	// loc locates the item's iloc fields in the file.
+	loc ilocRef
+}
+
+// ilocRef is where the iloc fields of an item are in the file, so that
+// the item can be moved: its base offset and its first extent's offset
+// and length, each with their size in bytes, which may be 0.
+type ilocRef struct {
+	base, baseSize     int
+	offset, offsetSize int
+	length, lengthSize int
+	extents            int
+}
+
+// heifMeta is the parsed meta box of a HEIF file.
+type heifMeta struct {
+	primary uint32
+	items   map[uint32]*heifItem
+	// order lists the item IDs as iinf does.
+	order []uint32
+	props []box
+	idat  []byte
+}
+
+// readHEIFMeta parses the meta box of a HEIF file.
+func readHEIFMeta(data []byte) (*heifMeta, error) {
+	top, err := boxes(data, 0)
+	if err != nil && len(top) == 0 {
+		return nil, err
+	}
+	var meta []byte
+	var metaOffset int
+	for _, b := range top {
+		if b.typ == "meta" {
+			meta, metaOffset = b.data, b.offset
+			break
+		}
+	}
+	if len(meta) < 4 {
+		return nil, fmt.Errorf("%w: HEIF file has no meta box", ErrFormat)
+	}
+	children, err := boxes(meta[4:], metaOffset+4)
+	if err != nil {
+		return nil, err
+	}
+	m := &heifMeta{items: map[uint32]*heifItem{}}
+	item := func(id uint32) *heifItem {
+		it, ok := m.items[id]
+		if !ok {
+			it = &heifItem{}
+			m.items[id] = it
+		}
+		return it
+	}
+	for _, b := range children {
+		r := &boxReader{data: b.data, typ: b.typ}
+		switch b.typ {
+		case "pitm":
+			if r.version() == 0 {
+				m.primary = uint32(r.uint(2))
+			} else {
+				m.primary = uint32(r.uint(4))
+			}
+		case "iinf":
+			r.version()
+			r.pos += 2
+			if r.v > 0 {
+				r.pos += 2
+			}
+			entries, err := boxes(r.rest(), 0)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if e.typ != "infe" {
+					continue
+				}
+				er := &boxReader{data: e.data, typ: e.typ}
+				var id uint32
+				switch er.version() {
+				case 2:
+					id = uint32(er.uint(2))
+				case 3:
+					id = uint32(er.uint(4))
+				default:
+					continue
+				}
+				er.pos += 2
+				item(id).typ = string(er.bytes(4))
+				m.order = append(m.order, id)
+			}
+		case "iloc":
+			r.version()
+			sizes := r.uint(1)
+			offsetSize, lengthSize := int(sizes>>4), int(sizes&0xF)
+			sizes = r.uint(1)
+			baseSize, indexSize := int(sizes>>4), 0
+			if r.v >= 1 {
+				indexSize = int(sizes & 0xF)
+			}
+			count := r.uint(2)
+			if r.v == 2 {
+				count = r.uint(4)
+			}
+			for i := uint64(0); i < count && r.err == nil; i++ {
+				var id uint32
+				if r.v < 2 {
+					id = uint32(r.uint(2))
+				} else {
+					id = uint32(r.uint(4))
+				}
+				it := item(id)
+				method := uint64(0)
+				if r.v >= 1 {
+					method = r.uint(2) & 0xF
+				}
+				it.idat = method == 1
+				r.pos += 2
+				it.loc = ilocRef{base: b.offset + r.pos, baseSize: baseSize, offsetSize: offsetSize, lengthSize: lengthSize}
+				base := r.uint(baseSize)
+				extents := r.uint(2)
+				it.loc.extents = int(extents)
+				for j := uint64(0); j < extents && r.err == nil; j++ {
+					r.uint(indexSize)
+					if j == 0 {
+						it.loc.offset = b.offset + r.pos
+						it.loc.length = it.loc.offset + offsetSize
+					}
+					off := r.uint(offsetSize)
+					n := r.uint(lengthSize)
+					// Items built from other items are not read.
+					if method <= 1 {
+						it.extents = append(it.extents, [2]uint64{base + off, n})
+					}
+				}
+			}
+		case "idat":
+			m.idat = b.data
+		case "iprp":
+			sub, err := boxes(b.data, b.offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range sub {
+				switch s.typ {
+				case "ipco":
+					if m.props, err = boxes(s.data, s.offset); err != nil {
+						return nil, err
+					}
+				case "ipma":
+					pr := &boxReader{data: s.data, typ: s.typ}
+					pr.version()
+					entries := pr.uint(4)
+					for i := uint64(0); i < entries && pr.err == nil; i++ {
+						var id uint32
+						if pr.v < 1 {
+							id = uint32(pr.uint(2))
+						} else {
+							id = uint32(pr.uint(4))
+						}
+						it := item(id)
+						n := pr.uint(1)
+						for j := uint64(0); j < n && pr.err == nil; j++ {
+							// The top bit marks essential properties.
+							if pr.flags&1 != 0 {
+								it.props = append(it.props, int(pr.uint(2)&0x7FFF))
+							} else {
+								it.props = append(it.props, int(pr.uint(1)&0x7F))
+							}
+						}
+					}
+					if pr.err != nil {
+						return nil, pr.err
+					}
+				}
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+	return m, nil
+}
+
+// itemData returns the bytes of an item. An item stored in one extent,
+// as usual, is returned as a slice of data, so that it can be edited in
+// place.
+func (m *heifMeta) itemData(data []byte, it *heifItem) ([]byte, error) {
+	src := data
+	if it.idat {
+		src = m.idat
+	}
+	var out []byte
+	for _, e := range it.extents {
+		off, n := e[0], e[1]
+		if n == 0 {
+			// A zero length runs to the end of the file.
+			n = uint64(len(src)) - min(off, uint64(len(src)))
+		}
+		if off > uint64(len(src)) || n > uint64(len(src))-off {
+			return nil, fmt.Errorf("%w: HEIF item extent at offset %d", ErrTruncated, off)
+		}
+		if len(it.extents) == 1 {
+			return src[off : off+n : off+n], nil
+		}
+		out = append(out, src[off:off+n]...)
+	}
+	return out, nil
+}
+
+// property returns the first property of type typ associated with it.
+func (m *heifMeta) property(it *heifItem, typ string) (box, bool) {
+	for _, i := range it.props {
+		if i >= 1 && i <= len(m.props) && m.props[i-1].typ == typ {
+			return m.props[i-1], true
+		}
+	}
+	return box{}, false
+}
+
+// heifExif returns the TIFF structure of a HEIF file's Exif item.
+func heifExif(data []byte) ([]byte, error) {
+	m, err := readHEIFMeta(data)
+	if err != nil {
+		return nil, err
+	}
+	_, payload, err := m.exifItem(data)
+	if err != nil {
+		return nil, err
+	}
+	return exifItemTIFF(payload)
+}
+
+// exifItem returns the Exif item of a HEIF file and its payload.
+func (m *heifMeta) exifItem(data []byte) (*heifItem, []byte, error) {
+	for _, id := range m.order {
+		it := m.items[id]
+		if it.typ != "Exif" {
+			continue
+		}
+		payload, err := m.itemData(data, it)
+		if err != nil {
+			return nil, nil, err
+		}
+		return it, payload, nil
+	}
+	return nil, nil, ErrNoExif
+}
+
+// exifItemTIFF returns the TIFF structure within the payload of an Exif
+// item. The payload starts with the offset of the TIFF header past the
+// offset field itself, skipping an optional "Exif\0\0".
+func exifItemTIFF(payload []byte) ([]byte, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("%w: HEIF Exif item", ErrTruncated)
+	}
+	skip := uint64(binary.BigEndian.Uint32(payload))
+	if skip > uint64(len(payload)-4) {
+		return nil, fmt.Errorf("%w: HEIF Exif item header offset %d", ErrFormat, skip)
+	}
+	tiff := payload[4+skip:]
+	if skip == 0 && bytes.HasPrefix(tiff, exifHeader) {
+		tiff = tiff[len(exifHeader):]
+	}
+	return tiff, nil
+}
+
+// boxReader reads the big-endian fields of a box, recording the first
+// overrun in err.
+type boxReader struct {
+	data  []byte
+	typ   string
+	pos   int
+	v     int
+	flags uint32
+	err   error
+}
+
+// version reads the version and flags of a full box.
+func (r *boxReader) version() int {
+	vf := r.uint(4)
+	r.v, r.flags = int(vf>>24), uint32(vf&0xFFFFFF)
+	return r.v
+}
+
+// uint reads an n-byte unsigned integer; n may be 0.
+func (r *boxReader) uint(n int) uint64 {
+	b := r.bytes(n)
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func (r *boxReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("%w: box %q", ErrTruncated, r.typ)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *boxReader) rest() []byte {
+	if r.err != nil || r.pos > len(r.data) {
+		return nil
+	}
+	return r.data[r.pos:]
+}
+
+// applyHEIF rewrites the Exif item of a HEIF file with edits applied. The
+// edited item is written over the old one when it fits, padded to the
+// old length, and otherwise appended in a new mdat box with its iloc
+// entry pointed at it, so no other item moves; an mdat box an earlier
+// edit appended is replaced instead. The old item's bytes are cleared, so
+// values the edits removed do not linger in the file.
+func applyHEIF(image []byte, edits []Edit) ([]byte, error) {
+	m, err := readHEIFMeta(image)
+	if err != nil {
+		return nil, err
+	}
+	it, payload, err := m.exifItem(image)
+	if errors.Is(err, ErrNoExif) {
+		return nil, fmt.Errorf("%w: HEIF file has no Exif item to rewrite", ErrFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case it.idat:
+		return nil, fmt.Errorf("%w: HEIF Exif item is stored in the meta box and cannot grow", ErrFormat)
+	case it.loc.extents != 1:
+		return nil, fmt.Errorf("%w: HEIF Exif item has %d extents", ErrFormat, it.loc.extents)
+	case it.loc.lengthSize == 0 || it.loc.baseSize == 0 && it.loc.offsetSize == 0:
+		return nil, fmt.Errorf("%w: HEIF Exif item location cannot be rewritten", ErrFormat)
+	}
+	tiff, err := exifItemTIFF(payload)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := applyTIFF(tiff, edits, true)
+	if err != nil {
+		return nil, err
+	}
+	prefix := payload[:len(payload)-len(tiff)]
+	item := append(append([]byte(nil), prefix...), edited...)
+
+	out := append([]byte(nil), image...)
+	old := it.extents[0]
+	clear(out[old[0] : old[0]+old[1]])
+	put := func(pos, size int, v uint64) error {
+		if size < 8 && v >= 1<<(8*size) {
+			return fmt.Errorf("%w: HEIF Exif item offset %d does not fit the iloc entry", ErrFormat, v)
+		}
+		for i := size - 1; i >= 0; i-- {
+			out[pos+i] = byte(v)
+			v >>= 8
+		}
+		return nil
+	}
+	loc := it.loc
+	if uint64(len(item)) <= old[1] {
+		// The item keeps its extent, padded with zeros after the TIFF
+		// structure, so that a later edit may grow into the slack again.
+		copy(out[old[0]:], item)
+		return out, nil
+	}
+	top, err := boxes(out, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch last := top[len(top)-1]; {
+	case last.typ == "mdat" && uint64(last.offset) == old[0] && uint64(len(last.data)) == old[1] &&
+		last.offset+len(last.data) == len(out) && binary.BigEndian.Uint32(out[last.offset-8:]) == uint32(8+old[1]):
+		// The item has the final mdat to itself, as an earlier edit
+		// appended it: it is replaced rather than another added.
+		out = out[:last.offset-8]
+	case last.offset >= 8 && binary.BigEndian.Uint32(out[last.offset-8:]) == 0:
+		// Appending after a final box of size 0, which runs to the end of
+		// the file, needs its size written out.
+		size := len(out) - (last.offset - 8)
+		if uint64(size) > 0xFFFFFFFF {
+			return nil, fmt.Errorf("%w: HEIF box %q exceeds 4 GiB", ErrFormat, last.typ)
+		}
+		binary.BigEndian.PutUint32(out[last.offset-8:], uint32(size))
+	}
+	at := uint64(len(out) + 8)
+	out = binary.BigEndian.AppendUint32(out, uint32(8+len(item)))
+	out = append(out, "mdat"...)
+	out = append(out, item...)
+
+	// The new offset goes into the base when there is one, and the
+	// extent's own offset, if any, becomes 0.
+	if loc.baseSize > 0 {
+		err = put(loc.base, loc.baseSize, at)
+		if err == nil && loc.offsetSize > 0 {
+			err = put(loc.offset, loc.offsetSize, 0)
+		}
+	} else {
+		err = put(loc.offset, loc.offsetSize, at)
+	}
+	if err == nil {
+		err = put(loc.length, loc.lengthSize, uint64(len(item)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
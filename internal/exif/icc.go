@@ -0,0 +1,83 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"unicode/utf16"
+)
+
+// iccHeader prefixes each chunk of an ICC profile in APP2 segments.
+var iccHeader = []byte("ICC_PROFILE\x00")
+
+const markerAPP2 = 0xE2
+
+// ICCProfile reassembles the ICC profile embedded in a JPEG file, or
+// returns nil when it has none.
+func ICCProfile(image []byte) []byte {
+	segs, _ := Segments(image)
+	type chunk struct {
+		seq  byte
+		data []byte
+	}
+	var chunks []chunk
+	for _, s := range segs {
+		if s.Marker == markerAPP2 && bytes.HasPrefix(s.Data, iccHeader) && len(s.Data) >= len(iccHeader)+2 {
+			chunks = append(chunks, chunk{s.Data[len(iccHeader)], s.Data[len(iccHeader)+2:]})
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].seq < chunks[j].seq })
+	var out []byte
+	for _, c := range chunks {
+		out = append(out, c.data...)
+	}
+	return out
+}
+
+// ICCDescription returns the profile description ('desc' tag) of an ICC
+// profile, e.g. "sRGB IEC61966-2.1".
+func ICCDescription(profile []byte) string {
+	be := binary.BigEndian
+	if len(profile) < 132 {
+		return ""
+	}
+	n := int(be.Uint32(profile[128:]))
+	for i := 0; i < n && 132+12*(i+1) <= len(profile); i++ {
+		e := profile[132+12*i:]
+		if string(e[:4]) != "desc" {
+			continue
+		}
+		off, size := int(be.Uint32(e[4:])), int(be.Uint32(e[8:]))
+		if off < 0 || size < 12 || off+size > len(profile) {
+			return ""
+		}
+		tag := profile[off : off+size]
+		switch string(tag[:4]) {
+		case "desc":
+			// ICC v2 textDescriptionType: ASCII count and string.
+			count := int(be.Uint32(tag[8:]))
+			if 12+count > len(tag) {
+				return ""
+			}
+			return cleanText(DecodeText(tag[12 : 12+count]))
+		case "mluc":
+			// ICC v4 multiLocalizedUnicodeType: the first record wins.
+			if len(tag) < 28 {
+				return ""
+			}
+			length, start := int(be.Uint32(tag[20:])), int(be.Uint32(tag[24:]))
+			if start+length > len(tag) {
+				return ""
+			}
+			units := make([]uint16, 0, length/2)
+			for j := start; j+1 < start+length; j += 2 {
+				units = append(units, be.Uint16(tag[j:]))
+			}
+			return cleanText(string(utf16.Decode(units)))
+		}
+	}
+	return ""
+}
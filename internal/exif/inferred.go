@@ -0,0 +1,50 @@
+package exif
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// NamespaceInferred is the XMP namespace of the note AnnotateInferredDate
+// leaves in files whose capture time was inferred rather than recorded.
+const NamespaceInferred = "https://github.com/ryoh827/shootlog/ns/inferred/1.0/"
+
+// AnnotateInferredDate records in the XMP packet of a JPEG file that its
+// capture time was inferred: inferred:DateSource holds where from, e.g.
+// "filename", and inferred:DateFrom the text it was read from. A file
+// already noted is left alone.
+func AnnotateInferredDate(image []byte, source, from string) ([]byte, error) {
+	packet := XMP(image)
+	if HasXMPProperty(packet, "DateSource") {
+		return image, nil
+	}
+	packet, err := AddXMPProperty(packet, NamespaceInferred, "inferred", "DateSource", source)
+	if err != nil {
+		return nil, err
+	}
+	if packet, err = AddXMPProperty(packet, NamespaceInferred, "inferred", "DateFrom", from); err != nil {
+		return nil, err
+	}
+	return SetXMP(image, packet)
+}
+
+// SetInferredTime returns the edits recording t as DateTimeOriginal
+// without a UTC offset, which a name does not give.
+func SetInferredTime(t time.Time) []Edit {
+	return []Edit{
+		SetASCII(TagDateTimeOriginal, t.Format("2006:01:02 15:04:05")).In(ExifIFD),
+		Delete(TagOffsetTimeOriginal).In(ExifIFD),
+	}
+}
+
+// summarizeInferred reads the note of AnnotateInferredDate into
+// DateSource, when the file's capture time is still the one written.
+func summarizeInferred(xmp map[xml.Name][]string, s *Summary) {
+	if s.DateTimeOriginal == "" {
+		return
+	}
+	if v := xmp[xml.Name{Space: NamespaceInferred, Local: "DateSource"}]; len(v) > 0 {
+		s.DateSource = v[0]
+		s.setSource(xmpSource(NamespaceInferred, "DateSource"), "date_source")
+	}
+}
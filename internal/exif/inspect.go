@@ -0,0 +1,187 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Block is the EXIF block of a file laid out for inspection: the APP1
+// segment of a JPEG file, or the TIFF structure of a TIFF-based file.
+type Block struct {
+	// Offset is the position of Data within the file.
+	Offset int
+	// Data starts at the APP1 marker of a JPEG file and at the byte order
+	// mark of a TIFF-based file.
+	Data []byte
+	// TIFF is the position of the TIFF header within Data. Value offsets
+	// stored in IFD entries are relative to it.
+	TIFF int
+	// Spans label the structures found in Data, in order of position.
+	Spans []Span
+	// Fields are the entries of every directory read, in file order.
+	Fields []Field
+}
+
+// Span is a labelled byte range of a Block, relative to its Data.
+type Span struct {
+	Offset, Length int
+	Label          string
+}
+
+// Field is an IFD entry located within a Block.
+type Field struct {
+	Entry
+	// Name is the tag's name in EXIF 2.32, or "" for tags it does not
+	// define.
+	Name string
+	// At is the position of the 12-byte directory entry and ValueAt that
+	// of the value, inline or not, both relative to Block.Data.
+	At, ValueAt int
+}
+
+// Inspect locates the EXIF block of a JPEG or TIFF-based file and labels
+// its headers, directories, entries and out-of-line values. Maker note
+// contents are left as a single value. Directories that cannot be read
+// are skipped, as by Parse.
+func Inspect(file []byte) (*Block, error) {
+	b := &Block{}
+	switch {
+	case IsJPEG(file):
+		segs, err := Segments(file)
+		for _, s := range segs {
+			if s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifHeader) {
+				b.Offset = s.Offset
+				b.Data = file[s.Offset : s.Offset+4+len(s.Data)]
+				b.TIFF = 4 + len(exifHeader)
+				break
+			}
+		}
+		if b.Data == nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrNoExif
+		}
+		b.add(0, 4, fmt.Sprintf("APP1 marker, length %d", len(b.Data)-2))
+		b.add(4, len(exifHeader), "Exif header")
+	case bytes.HasPrefix(file, []byte("II*\x00")) || bytes.HasPrefix(file, []byte("MM\x00*")):
+		b.Data = file
+	default:
+		return nil, fmt.Errorf("%w: unsupported file format", ErrFormat)
+	}
+
+	tiff := b.Data[b.TIFF:]
+	order, off, err := readHeader(tiff)
+	if err != nil {
+		return nil, err
+	}
+	name := "little-endian"
+	if order == binary.BigEndian {
+		name = "big-endian"
+	}
+	b.add(b.TIFF, 8, fmt.Sprintf("TIFF header: %s, IFD0 at TIFF offset %d", name, off))
+
+	r := ifdReader{data: tiff, order: order}
+	visited := map[uint32]bool{}
+	dir := func(off uint32, kind IFDKind) (next uint32) {
+		if visited[off] {
+			return 0
+		}
+		visited[off] = true
+		entries, next, err := r.readIFD(off, kind)
+		if err != nil {
+			return 0
+		}
+		byPos := map[uint32]Entry{}
+		for _, e := range entries {
+			byPos[e.pos] = e
+		}
+		n := int(order.Uint16(tiff[off:]))
+		b.add(b.TIFF+int(off), 2, fmt.Sprintf("%s: %d entries", kind, n))
+		for i := 0; i < n; i++ {
+			pos := off + 2 + uint32(i)*12
+			e, ok := byPos[pos]
+			if !ok {
+				raw := tiff[pos : pos+12]
+				b.add(b.TIFF+int(pos), 12, fmt.Sprintf("%s 0x%04X: %s, skipped", kind, order.Uint16(raw), Type(order.Uint16(raw[2:]))))
+				continue
+			}
+			f := Field{Entry: e, At: b.TIFF + int(pos), ValueAt: b.TIFF + int(pos) + 8}
+			if t, ok := LookupTag(kind, e.Tag); ok {
+				f.Name = t.Name
+			}
+			id := fmt.Sprintf("%s 0x%04X", kind, e.Tag)
+			if f.Name != "" {
+				id += " " + f.Name
+			}
+			label := fmt.Sprintf("%s %s[%d]", id, e.Type, e.Count)
+			if len(e.Value) > 4 {
+				f.ValueAt = b.TIFF + int(e.Offset)
+				b.add(b.TIFF+int(pos), 12, fmt.Sprintf("%s, value at TIFF offset %d", label, e.Offset))
+				b.add(f.ValueAt, len(e.Value), id+" value")
+			} else {
+				b.add(b.TIFF+int(pos), 12, label)
+			}
+			b.Fields = append(b.Fields, f)
+		}
+		if end := int(off) + 2 + n*12; end+4 <= len(tiff) {
+			text := "end of chain"
+			if next != 0 {
+				text = fmt.Sprintf("next IFD at TIFF offset %d", next)
+			}
+			b.add(b.TIFF+end, 4, fmt.Sprintf("%s: %s", kind, text))
+		}
+		return next
+	}
+	follow := func(pointer uint16, from, kind IFDKind) {
+		for _, f := range b.Fields {
+			if f.IFD == from && f.Tag == pointer {
+				if sub, ok := f.Uint(0); ok {
+					dir(sub, kind)
+				}
+				return
+			}
+		}
+	}
+
+	if next := dir(off, IFD0); next != 0 {
+		dir(next, IFD1)
+	}
+	for _, p := range pointerTags {
+		follow(p.ID, p.IFD, p.sub)
+	}
+
+	// The thumbnail is data of IFD1 rather than a value of its entries.
+	var at, n uint32
+	for _, f := range b.Fields {
+		if f.IFD == IFD1 && f.Tag == TagJPEGInterchangeFormat {
+			at, _ = f.Uint(0)
+		}
+		if f.IFD == IFD1 && f.Tag == TagJPEGInterchangeFormatLength {
+			n, _ = f.Uint(0)
+		}
+	}
+	if n > 0 && uint64(at)+uint64(n) <= uint64(len(tiff)) {
+		b.add(b.TIFF+int(at), int(n), "IFD1 thumbnail JPEG")
+	}
+
+	sort.SliceStable(b.Spans, func(i, j int) bool { return b.Spans[i].Offset < b.Spans[j].Offset })
+	sort.SliceStable(b.Fields, func(i, j int) bool { return b.Fields[i].At < b.Fields[j].At })
+	if b.TIFF == 0 {
+		// A TIFF-based file is mostly image data; the block ends with the
+		// last structure found.
+		end := 0
+		for _, s := range b.Spans {
+			end = max(end, s.Offset+s.Length)
+		}
+		b.Data = b.Data[:end]
+	}
+	return b, nil
+}
+
+// add labels the range of Data at off.
+func (b *Block) add(off, n int, label string) {
+	b.Spans = append(b.Spans, Span{Offset: off, Length: n, Label: label})
+}
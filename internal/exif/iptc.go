@@ -0,0 +1,267 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// photoshopHeader prefixes the image resource blocks of an APP13 segment.
+var photoshopHeader = []byte("Photoshop 3.0\x00")
+
+// resourceIPTC is the image resource ID of an IPTC-IIM record.
+const resourceIPTC = 0x0404
+
+const markerAPP13 = 0xED
+
+// IPTC application record (2) datasets read into the Summary.
+const (
+	IPTCObjectName      = 5
+	IPTCKeywords        = 25
+	IPTCByline          = 80
+	IPTCCity            = 90
+	IPTCCountry         = 101
+	IPTCJobID           = 103 // Original Transmission Reference, the IPTC Core Job Identifier
+	IPTCCredit          = 110
+	IPTCSource          = 115
+	IPTCCopyrightNotice = 116
+	IPTCContact         = 118
+	IPTCCaption         = 120
+)
+
+// IPTC holds the application record datasets of an IPTC-IIM block, keyed
+// by dataset number. Repeatable datasets such as keywords keep every
+// value in order.
+type IPTC map[int][]string
+
+// Get returns the first value of a dataset.
+func (p IPTC) Get(dataset int) string {
+	if v := p[dataset]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// ReadIPTC returns the IPTC-IIM application record of a JPEG file, or nil
+// when it has none.
+func ReadIPTC(image []byte) IPTC {
+	segs, _ := Segments(image)
+	for _, s := range segs {
+		if s.Marker == markerAPP13 && bytes.HasPrefix(s.Data, photoshopHeader) {
+			if iim := photoshopResource(s.Data[len(photoshopHeader):], resourceIPTC); iim != nil {
+				return parseIIM(iim)
+			}
+		}
+	}
+	return nil
+}
+
+// photoshopResource returns the data of the image resource with the given
+// ID.
+func photoshopResource(data []byte, id uint16) []byte {
+	for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+		rid := uint16(data[4])<<8 | uint16(data[5])
+		// The name is a Pascal string padded to an even length.
+		nameLen := int(data[6]) + 1
+		nameLen += nameLen % 2
+		pos := 6 + nameLen
+		if pos+4 > len(data) {
+			return nil
+		}
+		size := int(uint32(data[pos])<<24 | uint32(data[pos+1])<<16 | uint32(data[pos+2])<<8 | uint32(data[pos+3]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			return nil
+		}
+		if rid == id {
+			return data[pos : pos+size]
+		}
+		pos += size + size%2
+		if pos > len(data) {
+			return nil
+		}
+		data = data[pos:]
+	}
+	return nil
+}
+
+// parseIIM decodes the record 2 datasets of an IIM stream, stopping at the
+// first malformed dataset.
+func parseIIM(data []byte) IPTC {
+	out := IPTC{}
+	for len(data) >= 5 && data[0] == 0x1C {
+		record, dataset := data[1], int(data[2])
+		size := int(data[3])<<8 | int(data[4])
+		pos := 5
+		if size&0x8000 != 0 {
+			// Extended datasets store the length in the next n bytes.
+			n := size & 0x7FFF
+			if n > 4 || pos+n > len(data) {
+				break
+			}
+			size = 0
+			for _, b := range data[pos : pos+n] {
+				size = size<<8 | int(b)
+			}
+			pos += n
+		}
+		if pos+size > len(data) {
+			break
+		}
+		if record == 2 {
+			if v := cleanText(DecodeText(data[pos : pos+size])); v != "" {
+				out[dataset] = append(out[dataset], v)
+			}
+		}
+		data = data[pos+size:]
+	}
+	return out
+}
+
+// iptcSource returns the Source of an application record dataset.
+func iptcSource(dataset int) Source {
+	return Source{Location: LocationIPTC, Tag: fmt.Sprintf("2:%d", dataset)}
+}
+
+// iptcUTF8 is the envelope record dataset 1:90 declaring UTF-8 text.
+var iptcUTF8 = []byte{0x1C, 1, 90, 0, 3, 0x1B, '%', 'G'}
+
+// SetIPTC returns a copy of a JPEG file whose IPTC-IIM application record
+// holds the given datasets, each replacing all existing values of its
+// dataset; an empty list removes the dataset. Other datasets and
+// Photoshop image resources are kept. Text is written as UTF-8, which is
+// declared in the envelope record unless another character set already
+// is, in which case only ASCII values can be written.
+func SetIPTC(image []byte, values IPTC) ([]byte, error) {
+	segs, err := Segments(image)
+	if err != nil {
+		return nil, err
+	}
+	var resources []byte
+	_, at, _ := exifSpan(segs)
+	end := at
+	for _, s := range segs {
+		if s.Marker == markerAPP13 && bytes.HasPrefix(s.Data, photoshopHeader) {
+			resources = s.Data[len(photoshopHeader):]
+			at, end = s.Offset, s.Offset+4+len(s.Data)
+			break
+		}
+	}
+	iim, err := setIIM(photoshopResource(resources, resourceIPTC), values)
+	if err != nil {
+		return nil, err
+	}
+	payload := append(append([]byte{}, photoshopHeader...), replaceResource(resources, resourceIPTC, iim)...)
+	if len(payload)+2 > maxAPP1 {
+		return nil, fmt.Errorf("%w: IPTC data of %d bytes does not fit in a segment", ErrFormat, len(payload))
+	}
+	out := make([]byte, 0, len(image)-(end-at)+len(payload)+4)
+	out = append(out, image[:at]...)
+	out = append(out, 0xFF, markerAPP13, byte((len(payload)+2)>>8), byte(len(payload)+2))
+	out = append(out, payload...)
+	return append(out, image[end:]...), nil
+}
+
+// setIIM rewrites an IIM stream with the record 2 datasets in values.
+func setIIM(iim []byte, values IPTC) ([]byte, error) {
+	// The envelope record precedes the application record.
+	var envelope, out []byte
+	charset := false
+	for len(iim) >= 5 && iim[0] == 0x1C {
+		size := int(iim[3])<<8 | int(iim[4])
+		n := 5 + size
+		if size&0x8000 != 0 || n > len(iim) {
+			// Extended datasets are only used for objects far larger
+			// than text; keep the rest of the stream as is.
+			out = append(out, iim...)
+			break
+		}
+		record, dataset := iim[1], int(iim[2])
+		if record == 1 && dataset == 90 {
+			charset = true
+			if !bytes.Equal(iim[:n], iptcUTF8) {
+				for _, vs := range values {
+					for _, v := range vs {
+						if !isASCII(v) {
+							return nil, fmt.Errorf("exif: IPTC uses character set %q; cannot write %q", iim[5:n], v)
+						}
+					}
+				}
+			}
+		}
+		switch _, replaced := values[dataset]; {
+		case record < 2:
+			envelope = append(envelope, iim[:n]...)
+		case record == 2 && replaced:
+		default:
+			out = append(out, iim[:n]...)
+		}
+		iim = iim[n:]
+	}
+	if !charset {
+		envelope = append(envelope, iptcUTF8...)
+	}
+	out = append(envelope, out...)
+	datasets := make([]int, 0, len(values))
+	for d := range values {
+		datasets = append(datasets, d)
+	}
+	sort.Ints(datasets)
+	for _, d := range datasets {
+		for _, v := range values[d] {
+			if len(v) > 0x7FFF {
+				return nil, fmt.Errorf("exif: IPTC dataset 2:%d of %d bytes is too long", d, len(v))
+			}
+			out = append(out, 0x1C, 2, byte(d), byte(len(v)>>8), byte(len(v)))
+			out = append(out, v...)
+		}
+	}
+	return out, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceResource returns the image resource blocks with the data of
+// resource id replaced, or appended when missing.
+func replaceResource(data []byte, id uint16, value []byte) []byte {
+	block := append([]byte("8BIM"), byte(id>>8), byte(id), 0, 0)
+	block = binary.BigEndian.AppendUint32(block, uint32(len(value)))
+	block = append(block, value...)
+	if len(value)%2 == 1 {
+		block = append(block, 0)
+	}
+	var out []byte
+	replaced := false
+	for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+		nameLen := int(data[6]) + 1
+		nameLen += nameLen % 2
+		pos := 6 + nameLen
+		if pos+4 > len(data) {
+			break
+		}
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		n := pos + 4 + size + size%2
+		if n > len(data) {
+			break
+		}
+		if rid := binary.BigEndian.Uint16(data[4:]); rid == id && !replaced {
+			out = append(out, block...)
+			replaced = true
+		} else {
+			out = append(out, data[:n]...)
+		}
+		data = data[n:]
+	}
+	if !replaced {
+		out = append(out, block...)
+	}
+	return out
+}
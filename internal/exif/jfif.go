@@ -0,0 +1,186 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// markerAPP0 holds the JFIF header and its JFXX extension, which must
+// directly follow SOI, ahead of the APP1 Exif segment.
+const markerAPP0 = 0xE0
+
+// jfifHeader prefixes the JFIF APP0 payload.
+var jfifHeader = []byte("JFIF\x00")
+
+// JFIF density units, and the EXIF ResolutionUnit values they match.
+const (
+	jfifAspect = 0
+	jfifInch   = 1
+	jfifCM     = 2
+)
+
+var jfifUnitNames = map[byte]string{jfifInch: "inches", jfifCM: "cm"}
+
+// jfif is a JFIF header: its version and pixel density.
+type jfif struct {
+	major, minor byte
+	units        byte
+	x, y         uint16
+	// at is the position of the units byte in the file, followed by the
+	// two densities.
+	at int
+}
+
+// readJFIF returns the JFIF header of a JPEG file: the APP0 segment
+// directly after SOI, or after an Exif segment misplaced ahead of it.
+func readJFIF(segs []Segment) (jfif, bool) {
+	lead := leadEnd(segs)
+	for _, s := range segs {
+		if s.Offset >= lead {
+			break
+		}
+		// Identifier, version, units and two 2-byte densities.
+		if s.Marker != markerAPP0 || !bytes.HasPrefix(s.Data, jfifHeader) || len(s.Data) < 12 {
+			continue
+		}
+		at := len(jfifHeader)
+		return jfif{
+			major: s.Data[at], minor: s.Data[at+1], units: s.Data[at+2],
+			x: binary.BigEndian.Uint16(s.Data[at+3:]), y: binary.BigEndian.Uint16(s.Data[at+5:]),
+			at: s.Offset + 4 + at + 2,
+		}, true
+	}
+	return jfif{}, false
+}
+
+// resolution returns the edits that set the EXIF resolution to the JFIF
+// density, or none when the density gives only the pixel aspect ratio.
+func (j jfif) resolution() []Edit {
+	if j.units != jfifInch && j.units != jfifCM || j.x == 0 || j.y == 0 {
+		return nil
+	}
+	return []Edit{
+		SetRational(TagXResolution, uint32(j.x), 1),
+		SetRational(TagYResolution, uint32(j.y), 1),
+		SetShort(TagResolutionUnit, uint16(j.units)+1),
+	}
+}
+
+// syncDensity sets the JFIF density in image to the resolution recorded
+// in IFD0 of tiff, so that readers of either header agree. A resolution
+// in whole dots per inch or centimeter within the 16-bit fields is
+// copied; other values, and headers that give only an aspect ratio, are
+// left as they are.
+func (j jfif) syncDensity(image, tiff []byte) {
+	if j.units == jfifAspect {
+		return
+	}
+	byteOrder, off, err := readHeader(tiff)
+	if err != nil {
+		return
+	}
+	raw, _, err := readDirectory(tiff, byteOrder, int64(off))
+	if err != nil {
+		return
+	}
+	density := func(tag uint16) (uint16, bool) {
+		e, ok := raw[tag]
+		if !ok || Type(byteOrder.Uint16(e[2:])) != TypeRational || byteOrder.Uint32(e[4:]) != 1 {
+			return 0, false
+		}
+		at := int(byteOrder.Uint32(e[8:]))
+		if at < 0 || at+8 > len(tiff) {
+			return 0, false
+		}
+		num, den := byteOrder.Uint32(tiff[at:]), byteOrder.Uint32(tiff[at+4:])
+		if den == 0 || num%den != 0 || num/den == 0 || num/den > 0xFFFF {
+			return 0, false
+		}
+		return uint16(num / den), true
+	}
+	units := byte(jfifInch)
+	if e, ok := raw[TagResolutionUnit]; ok && Type(byteOrder.Uint16(e[2:])) == TypeShort {
+		switch byteOrder.Uint16(e[8:]) {
+		case 2:
+		case 3:
+			units = jfifCM
+		default:
+			return
+		}
+	}
+	x, okX := density(TagXResolution)
+	y, okY := density(TagYResolution)
+	if !okX || !okY {
+		return
+	}
+	image[j.at] = units
+	binary.BigEndian.PutUint16(image[j.at+1:], x)
+	binary.BigEndian.PutUint16(image[j.at+3:], y)
+}
+
+// touchesResolution reports whether edits change the resolution tags of
+// IFD0.
+func touchesResolution(edits []Edit) bool {
+	for _, e := range edits {
+		if e.ifd == IFD0 && (e.Tag == TagXResolution || e.Tag == TagYResolution || e.Tag == TagResolutionUnit) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeExif returns image with the byte range at:end replaced by seg. An
+// APP1 segment found ahead of the APP0 segments, as some writers leave
+// it, is moved after them to lead, where JFIF readers expect it.
+func placeExif(image []byte, at, end, lead int, seg []byte) []byte {
+	out := make([]byte, 0, len(image)-(end-at)+len(seg))
+	out = append(out, image[:at]...)
+	if at < lead && end <= lead {
+		out = append(out, image[end:lead]...)
+		out = append(out, seg...)
+		return append(out, image[lead:]...)
+	}
+	out = append(out, seg...)
+	return append(out, image[end:]...)
+}
+
+// leadEnd returns the end of the APP0 segments that open a JPEG file, 2
+// when it has none. An Exif segment between them is looked past.
+func leadEnd(segs []Segment) int {
+	pos, lead := 2, 2
+	for _, s := range segs {
+		if s.Offset != pos {
+			break
+		}
+		switch {
+		case s.Marker == markerAPP0:
+			lead = s.Offset + 4 + len(s.Data)
+		case s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifHeader):
+		default:
+			return lead
+		}
+		pos = s.Offset + 4 + len(s.Data)
+	}
+	return lead
+}
+
+// summarizeJFIF fills in the JFIF fields from the APP0 header.
+func summarizeJFIF(data []byte, s *Summary) {
+	segs, _ := Segments(data)
+	j, ok := readJFIF(segs)
+	if !ok {
+		return
+	}
+	s.JFIFVersion = fmt.Sprintf("%d.%02d", j.major, j.minor)
+	s.JFIFDensityUnit = jfifUnitNames[j.units]
+	s.JFIFXDensity, s.JFIFYDensity = int(j.x), int(j.y)
+	fields := []string{"jfif_version"}
+	if s.JFIFDensityUnit != "" {
+		fields = append(fields, "jfif_density_unit")
+	}
+	if j.x != 0 || j.y != 0 {
+		fields = append(fields, "jfif_x_density", "jfif_y_density")
+	}
+	s.setSource(Source{Location: LocationJPEG, Tag: "0xFFE0"}, fields...)
+}
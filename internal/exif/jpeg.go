@@ -0,0 +1,101 @@
+package exif
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// JPEG markers referenced by the segment scanner.
+const (
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+)
+
+// exifHeader prefixes the TIFF structure inside an APP1 segment.
+var exifHeader = []byte("Exif\x00\x00")
+
+// Segment is a JPEG marker segment located before the start of scan.
+type Segment struct {
+	Marker byte
+	// Offset is the position of the 0xFF marker byte within the file.
+	Offset int
+	// Data is the segment payload, excluding the marker and length bytes.
+	Data []byte
+}
+
+// IsJPEG reports whether data starts with a JPEG SOI marker.
+func IsJPEG(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xFF && data[1] == markerSOI
+}
+
+// Segments returns the marker segments of a JPEG file up to and including
+// the SOS segment header. Entropy-coded image data is not scanned.
+func Segments(data []byte) ([]Segment, error) {
+	if !IsJPEG(data) {
+		return nil, fmt.Errorf("%w: missing SOI marker", ErrFormat)
+	}
+	var segs []Segment
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return segs, fmt.Errorf("%w: expected marker at offset %d", ErrFormat, pos)
+		}
+		// Markers may be preceded by any number of 0xFF fill bytes.
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			break
+		}
+		marker := data[pos]
+		start := pos - 1
+		pos++
+		if marker == markerEOI {
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			// Standalone markers carry no length.
+			continue
+		}
+		if pos+2 > len(data) {
+			return segs, fmt.Errorf("%w: segment length at offset %d", ErrTruncated, pos)
+		}
+		length := int(data[pos])<<8 | int(data[pos+1])
+		if length < 2 || pos+length > len(data) {
+			return segs, fmt.Errorf("%w: segment 0x%02X at offset %d", ErrTruncated, marker, start)
+		}
+		segs = append(segs, Segment{Marker: marker, Offset: start, Data: data[pos+2 : pos+length]})
+		pos += length
+		if marker == markerSOS {
+			break
+		}
+	}
+	return segs, nil
+}
+
+// findTIFF locates the TIFF structure holding EXIF data. JPEG files are
+// searched for an APP1 Exif segment and HEIF files for an Exif item;
+// TIFF-based files (including most raw formats) are returned as is.
+func findTIFF(data []byte) ([]byte, error) {
+	if IsJPEG(data) {
+		segs, err := Segments(data)
+		for _, s := range segs {
+			if s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifHeader) {
+				return s.Data[len(exifHeader):], nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrNoExif
+	}
+	if IsHEIF(data) {
+		return heifExif(data)
+	}
+	if len(data) >= 4 && (bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))) {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported file format", ErrFormat)
+}
@@ -0,0 +1,86 @@
+package exif
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits bound the memory and time spent on untrusted input. Zero fields
+// are unlimited.
+type Limits struct {
+	// MaxFileBytes bounds the size of the image.
+	MaxFileBytes int64
+	// MaxSegmentBytes bounds each JPEG segment and each tag value.
+	MaxSegmentBytes int
+	// MaxTagCount bounds the number of entries across all IFDs, including
+	// maker notes.
+	MaxTagCount int
+}
+
+// LimitExceededError reports the limit an input exceeded.
+type LimitExceededError struct {
+	// Limit is the name of the Limits field, e.g. "MaxTagCount".
+	Limit string
+	Max   int64
+	// Actual is the size or count that was reached.
+	Actual int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("exif: %s of %d exceeded (%d)", e.Limit, e.Max, e.Actual)
+}
+
+// ReadAll reads r up to MaxFileBytes, failing with a LimitExceededError
+// rather than reading an oversized stream to the end.
+func (l Limits) ReadAll(r io.Reader) ([]byte, error) {
+	if l.MaxFileBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, l.MaxFileBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.MaxFileBytes {
+		return nil, &LimitExceededError{Limit: "MaxFileBytes", Max: l.MaxFileBytes, Actual: int64(len(data))}
+	}
+	return data, nil
+}
+
+// budget tracks the limits while one image is parsed. The first limit hit
+// is kept in err: sub-directories and maker notes are parsed best effort,
+// so their errors are otherwise dropped. A nil budget is unlimited.
+type budget struct {
+	limits Limits
+	tags   int
+	err    error
+}
+
+func (b *budget) exceeded(limit string, max, actual int64) error {
+	if b.err == nil {
+		b.err = &LimitExceededError{Limit: limit, Max: max, Actual: actual}
+	}
+	return b.err
+}
+
+// entries accounts for a directory of n entries.
+func (b *budget) entries(n int) error {
+	if b == nil {
+		return nil
+	}
+	b.tags += n
+	if max := b.limits.MaxTagCount; max > 0 && b.tags > max {
+		return b.exceeded("MaxTagCount", int64(max), int64(b.tags))
+	}
+	return nil
+}
+
+// value checks the size of a tag value or segment.
+func (b *budget) value(n uint64) error {
+	if b == nil {
+		return nil
+	}
+	if max := b.limits.MaxSegmentBytes; max > 0 && n > uint64(max) {
+		return b.exceeded("MaxSegmentBytes", int64(max), int64(n))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package exif
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrImageDataChanged is returned by VerifyImageData when an edit touched
+// the image itself rather than only its metadata.
+var ErrImageDataChanged = errors.New("exif: image data changed")
+
+// ImageDataHash returns the hex SHA-256 of the image data of a file, which
+// metadata edits must leave alone: the scans of a JPEG file from its first
+// SOS marker through EOI, the items of a HEIF file other than Exif and XMP,
+// or the strips of a TIFF file's IFD0. Raw files keeping their image in
+// directories not parsed here yield an error.
+func ImageDataHash(image []byte) (string, error) {
+	h := sha256.New()
+	switch {
+	case IsJPEG(image):
+		scans, err := jpegScans(image)
+		if err != nil {
+			return "", err
+		}
+		h.Write(scans)
+	case IsHEIF(image):
+		m, err := readHEIFMeta(image)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range m.order {
+			it := m.items[id]
+			if it.typ == "Exif" || it.typ == "mime" {
+				continue
+			}
+			data, err := m.itemData(image, it)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%d:%s:%d:", id, it.typ, len(data))
+			h.Write(data)
+		}
+	default:
+		tiff, err := findTIFF(image)
+		if err != nil {
+			return "", err
+		}
+		x, err := Parse(tiff)
+		if err != nil {
+			return "", err
+		}
+		offsets, ok1 := x.Lookup(IFD0, TagStripOffsets)
+		counts, ok2 := x.Lookup(IFD0, TagStripByteCounts)
+		if !ok1 || !ok2 || offsets.Len() != counts.Len() {
+			return "", fmt.Errorf("%w: image data of the TIFF file cannot be located", ErrFormat)
+		}
+		for i := range offsets.Len() {
+			off, _ := offsets.Uint(i)
+			n, _ := counts.Uint(i)
+			if uint64(off)+uint64(n) > uint64(len(tiff)) {
+				return "", fmt.Errorf("%w: strip %d at offset %d", ErrTruncated, i, off)
+			}
+			h.Write(tiff[off : off+n])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyImageData checks that edited, a metadata edit of original, kept
+// its image data byte for byte. A JPEG original whose segments cannot be
+// walked, as before a repair, passes when it holds the edited file's scans
+// verbatim.
+func VerifyImageData(original, edited []byte) error {
+	after, err := ImageDataHash(edited)
+	if err != nil {
+		return err
+	}
+	before, err := ImageDataHash(original)
+	if err != nil && IsJPEG(original) && IsJPEG(edited) {
+		scans, _ := jpegScans(edited)
+		if bytes.Contains(original, scans) {
+			return nil
+		}
+		return fmt.Errorf("%w: the scans are not found in the original", ErrImageDataChanged)
+	}
+	if err != nil {
+		return err
+	}
+	if before != after {
+		return fmt.Errorf("%w: SHA-256 %s became %s", ErrImageDataChanged, before[:12], after[:12])
+	}
+	return nil
+}
+
+// jpegScans returns the bytes of a JPEG file from the first SOS marker
+// through EOI: the entropy-coded data together with the tables and scan
+// headers of later progressive scans. Data after EOI, such as the video
+// of a motion photo, is left out.
+func jpegScans(data []byte) ([]byte, error) {
+	segs, err := Segments(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 || segs[len(segs)-1].Marker != markerSOS {
+		return nil, fmt.Errorf("%w: JPEG file has no SOS marker", ErrFormat)
+	}
+	sos := segs[len(segs)-1]
+	start := sos.Offset
+	for pos := sos.Offset + 4 + len(sos.Data); pos+1 < len(data); pos++ {
+		if data[pos] != 0xFF {
+			continue
+		}
+		switch m := data[pos+1]; {
+		case m == 0xFF:
+			// A fill byte; the marker follows.
+		case m == 0x00 || m >= 0xD0 && m <= 0xD7:
+			// Stuffed bytes and restart markers are part of the scan.
+			pos++
+		case m == markerEOI:
+			return data[start : pos+2], nil
+		default:
+			// A marker segment between scans, such as DHT or SOS.
+			if pos+4 > len(data) {
+				return nil, fmt.Errorf("%w: segment at offset %d", ErrTruncated, pos)
+			}
+			pos += 1 + (int(data[pos+2])<<8 | int(data[pos+3]))
+		}
+	}
+	return nil, fmt.Errorf("%w: JPEG file has no EOI marker", ErrTruncated)
+}
@@ -0,0 +1,41 @@
+package exif
+
+// Apple maker note tags.
+const (
+	appleHDRImageType     uint16 = 0x000A
+	appleContentID        uint16 = 0x0011
+	appleImageCaptureType uint16 = 0x0014
+)
+
+// appleHDRImage marks the merged image of an HDR capture; 4 marks the
+// normal exposure kept alongside it.
+const appleHDRImage = 3
+
+// appleCaptureTypes maps ImageCaptureType values to computational
+// features. Night mode and Deep Fusion are not recorded in a documented
+// tag and are left out.
+var appleCaptureTypes = map[int64]string{2: PhonePortrait}
+
+func applyApple(m *MakerNote, s *Summary) {
+	if e, ok := m.Lookup(appleHDRImageType); ok {
+		if v, _ := e.Int(0); v == appleHDRImage {
+			s.addComputational(m.source(appleHDRImageType, -1), PhoneHDR)
+			if s.Composite == "" {
+				s.Composite = CompositeHDR
+				s.setSource(m.source(appleHDRImageType, -1), "composite")
+			}
+		}
+	}
+	if e, ok := m.Lookup(appleImageCaptureType); ok {
+		if v, _ := e.Int(0); appleCaptureTypes[v] != "" {
+			s.addComputational(m.source(appleImageCaptureType, -1), appleCaptureTypes[v])
+		}
+	}
+	// The content identifier is shared by the still and the video of a
+	// Live Photo.
+	if e, ok := m.Lookup(appleContentID); ok && e.Type == TypeASCII && e.String() != "" {
+		s.LivePhotoID = e.String()
+		s.setSource(m.source(appleContentID, -1), "live_photo_id")
+		s.addComputational(m.source(appleContentID, -1), PhoneLivePhoto)
+	}
+}
@@ -0,0 +1,101 @@
+package exif
+
+// Canon maker note tags.
+const (
+	canonCameraSettings       uint16 = 0x0001
+	canonSerialNumber         uint16 = 0x000C
+	canonInternalSerialNumber uint16 = 0x0096
+	canonAspectInfo           uint16 = 0x009A
+)
+
+// Indexes into the Canon CameraSettings array. Index 0 holds the array size
+// in bytes, so fields start at 1.
+const (
+	canonSelfTimer          = 2
+	canonFocusMode          = 7
+	canonContinuousDrive    = 5
+	canonImageStabilization = 34
+)
+
+var canonStabilizationModes = map[uint32]string{
+	2: "Shoot Only",
+	3: "Panning",
+	4: "Dynamic",
+}
+
+// canonCropModes maps the AspectRatio of AspectInfo to crop modes; 0 is
+// the sensor's own 3:2.
+var canonCropModes = map[uint32]string{
+	1:  "1:1",
+	2:  "4:3",
+	7:  "16:9",
+	8:  "4:5",
+	12: CropAPSH,
+	13: CropAPSC,
+}
+
+func applyCanon(m *MakerNote, s *Summary) {
+	m.serial(s, &s.BodySerial, "body_serial", canonSerialNumber)
+	m.serial(s, &s.InternalSerial, "internal_serial", canonInternalSerialNumber)
+	// AspectInfo starts with the aspect ratio, followed by the cropped
+	// size and position.
+	if e, ok := m.Lookup(canonAspectInfo); ok {
+		if v, ok := e.Uint(0); ok && canonCropModes[v] != "" {
+			s.CropMode = canonCropModes[v]
+			s.setSource(m.source(canonAspectInfo, 0), "crop_mode")
+		}
+	}
+	cs, ok := m.Lookup(canonCameraSettings)
+	if !ok {
+		return
+	}
+	if v, ok := cs.Uint(canonImageStabilization); ok && v != 0xFFFF {
+		// Values from 256 up mirror 0-4 for bodies with in-body IS.
+		mode := v & 0xFF
+		s.Stabilization = onOff(mode != 0)
+		s.StabilizationMode = canonStabilizationModes[mode]
+		s.setSource(m.source(canonCameraSettings, canonImageStabilization), "stabilization")
+		if s.StabilizationMode != "" {
+			s.setSource(m.source(canonCameraSettings, canonImageStabilization), "stabilization_mode")
+		}
+	}
+	drive := m.source(canonCameraSettings, canonContinuousDrive)
+	if v, ok := cs.Uint(canonContinuousDrive); ok {
+		switch v {
+		case 0:
+			s.DriveMode = DriveSingle
+		case 1, 3, 4, 5:
+			s.DriveMode = DriveContinuous
+		case 6, 9:
+			s.DriveMode, s.ShutterType = DriveSingle, ShutterElectronic
+		case 10:
+			s.DriveMode, s.ShutterType = DriveContinuous, ShutterElectronic
+		}
+		if s.DriveMode != "" {
+			s.setSource(drive, "drive_mode")
+		}
+		if s.ShutterType != "" {
+			s.setSource(drive, "shutter_type")
+		}
+	}
+	if v, ok := cs.Uint(canonFocusMode); ok {
+		// Values from 256 up are the same modes in live view.
+		switch v & 0xFF {
+		case 0, 4:
+			s.FocusMode = FocusSingle
+		case 1, 5:
+			s.FocusMode = FocusContinuous
+		case 2:
+			s.FocusMode = FocusAutomatic
+		case 3, 6:
+			s.FocusMode = FocusManual
+		}
+		if s.FocusMode != "" {
+			s.setSource(m.source(canonCameraSettings, canonFocusMode), "focus_mode")
+		}
+	}
+	if v, ok := cs.Uint(canonSelfTimer); ok && v != 0 && v != 0xFFFF && s.DriveMode == DriveSingle {
+		s.DriveMode = DriveSelfTimer
+		s.setSource(m.source(canonCameraSettings, canonSelfTimer), "drive_mode")
+	}
+}
@@ -0,0 +1,88 @@
+package exif
+
+// Fujifilm maker note tags.
+const (
+	fujiInternalSerialNumber uint16 = 0x0010
+	fujiFocusMode            uint16 = 0x1021
+	fujiFocusSettings        uint16 = 0x102D
+	fujiShutterType          uint16 = 0x1050
+	fujiAutoBracketing       uint16 = 0x1100
+	fujiDriveSettings        uint16 = 0x1103
+	fujiImageStabilization   uint16 = 0x1422
+)
+
+var fujiStabilizationTypes = map[uint32]string{
+	1:   "Optical",
+	2:   "Sensor-shift",
+	3:   "OIS Lens",
+	258: "IBIS/OIS + DIS",
+	512: "Digital",
+}
+
+func applyFujifilm(m *MakerNote, s *Summary) {
+	m.serial(s, &s.InternalSerial, "internal_serial", fujiInternalSerialNumber)
+	// ImageStabilization holds the stabilizer type followed by its mode.
+	if e, ok := m.Lookup(fujiImageStabilization); ok {
+		kind, _ := e.Uint(0)
+		if mode, ok := e.Uint(1); ok && kind != 0 {
+			s.Stabilization = onOff(mode != 0)
+			s.StabilizationMode = fujiStabilizationTypes[kind]
+			s.setSource(m.source(fujiImageStabilization, -1), "stabilization", "stabilization_mode")
+		}
+	}
+	if e, ok := m.Lookup(fujiDriveSettings); ok {
+		if v, ok := e.Uint(0); ok {
+			switch v & 0xFF {
+			case 0:
+				s.DriveMode = DriveSingle
+			case 1, 2:
+				s.DriveMode = DriveContinuous
+			}
+			if s.DriveMode != "" {
+				s.setSource(m.source(fujiDriveSettings, -1), "drive_mode")
+			}
+		}
+	}
+	if e, ok := m.Lookup(fujiAutoBracketing); ok {
+		if v, ok := e.Uint(0); ok && v == 1 {
+			s.DriveMode = DriveBracketing
+			s.setSource(m.source(fujiAutoBracketing, -1), "drive_mode")
+		}
+	}
+	// FocusMode only tells manual from auto; FocusSettings, written by
+	// newer bodies, holds the AF mode in its low four bits.
+	if e, ok := m.Lookup(fujiFocusMode); ok {
+		if v, ok := e.Uint(0); ok && v == 1 {
+			s.FocusMode = FocusManual
+			s.setSource(m.source(fujiFocusMode, -1), "focus_mode")
+		}
+	}
+	if e, ok := m.Lookup(fujiFocusSettings); ok && s.FocusMode == "" {
+		if v, ok := e.Uint(0); ok {
+			switch v & 0x0F {
+			case 0:
+				s.FocusMode = FocusManual
+			case 1:
+				s.FocusMode = FocusSingle
+			case 2:
+				s.FocusMode = FocusContinuous
+			}
+			if s.FocusMode != "" {
+				s.setSource(m.source(fujiFocusSettings, -1), "focus_mode")
+			}
+		}
+	}
+	if e, ok := m.Lookup(fujiShutterType); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0:
+			s.ShutterType = ShutterMechanical
+		case 1, 2:
+			s.ShutterType = ShutterElectronic
+		case 3:
+			s.ShutterType = ShutterElectronicFrontCurtain
+		}
+		if s.ShutterType != "" {
+			s.setSource(m.source(fujiShutterType, -1), "shutter_type")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package exif
+
+import "strings"
+
+// Nikon maker note tags.
+const (
+	nikonFocusMode         uint16 = 0x0007
+	nikonCropHiSpeed       uint16 = 0x001B
+	nikonSerialNumber      uint16 = 0x001D
+	nikonVRInfo            uint16 = 0x001F
+	nikonShootingMode      uint16 = 0x0089
+	nikonSilentPhotography uint16 = 0x00BF
+)
+
+var nikonVRModes = map[uint32]string{
+	0: "Normal",
+	2: "Active",
+	3: "Sport",
+}
+
+// nikonCropModes maps the first value of CropHiSpeed to crop modes; 0
+// and the uncropped values are left out.
+var nikonCropModes = map[uint32]string{
+	1:  "1.3x",
+	2:  CropAPSC,
+	3:  "5:4",
+	4:  "3:2",
+	6:  "16:9",
+	8:  "2.7x",
+	9:  CropAPSC,
+	10: "1.3x",
+	15: "1.5x",
+	17: "1:1",
+}
+
+// ShootingMode bits.
+const (
+	nikonContinuous = 1 << 0
+	nikonSelfTimer  = 1 << 3
+	nikonBracketing = 1<<4 | 1<<6 | 1<<8
+)
+
+func applyNikon(m *MakerNote, s *Summary) {
+	m.serial(s, &s.BodySerial, "body_serial", nikonSerialNumber)
+	if e, ok := m.Lookup(nikonCropHiSpeed); ok {
+		if v, ok := e.Uint(0); ok && nikonCropModes[v] != "" {
+			s.CropMode = nikonCropModes[v]
+			s.setSource(m.source(nikonCropHiSpeed, 0), "crop_mode")
+		}
+	}
+	// VRInfo is a 4-byte ASCII version followed by the VR state and mode.
+	if vr, ok := m.Lookup(nikonVRInfo); ok && len(vr.Value) >= 7 {
+		switch vr.Value[4] {
+		case 1:
+			s.Stabilization = "on"
+			s.StabilizationMode = nikonVRModes[uint32(vr.Value[6])]
+			s.setSource(m.source(nikonVRInfo, -1), "stabilization")
+			if s.StabilizationMode != "" {
+				s.setSource(m.source(nikonVRInfo, -1), "stabilization_mode")
+			}
+		case 2:
+			s.Stabilization = "off"
+			s.setSource(m.source(nikonVRInfo, -1), "stabilization")
+		}
+	}
+	if v, ok := m.Lookup(nikonShootingMode); ok {
+		if mode, ok := v.Uint(0); ok {
+			switch {
+			case mode&nikonBracketing != 0:
+				s.DriveMode = DriveBracketing
+			case mode&nikonContinuous != 0:
+				s.DriveMode = DriveContinuous
+			case mode&nikonSelfTimer != 0:
+				s.DriveMode = DriveSelfTimer
+			default:
+				s.DriveMode = DriveSingle
+			}
+			s.setSource(m.source(nikonShootingMode, -1), "drive_mode")
+		}
+	}
+	// FocusMode is text such as "AF-S  " or "MANUAL".
+	if e, ok := m.Lookup(nikonFocusMode); ok {
+		switch strings.TrimSpace(e.Text()) {
+		case "AF-S":
+			s.FocusMode = FocusSingle
+		case "AF-C", "AF-F":
+			s.FocusMode = FocusContinuous
+		case "AF-A":
+			s.FocusMode = FocusAutomatic
+		case "MANUAL", "Manual":
+			s.FocusMode = FocusManual
+		}
+		if s.FocusMode != "" {
+			s.setSource(m.source(nikonFocusMode, -1), "focus_mode")
+		}
+	}
+	if v, ok := m.Lookup(nikonSilentPhotography); ok {
+		if on, ok := v.Uint(0); ok && on == 1 {
+			s.ShutterType = ShutterElectronic
+			s.setSource(m.source(nikonSilentPhotography, -1), "shutter_type")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package exif
+
+// Panasonic maker note tags.
+const (
+	panasonicFocusMode            uint16 = 0x0007
+	panasonicImageStabilization   uint16 = 0x001A
+	panasonicInternalSerialNumber uint16 = 0x0025
+	panasonicBurstMode            uint16 = 0x002A
+	panasonicShutterType          uint16 = 0x009F
+)
+
+var panasonicStabilizationModes = map[uint32]string{
+	2:  "Optical",
+	4:  "Optical Mode 2",
+	5:  "Optical Panning",
+	6:  "Body-only",
+	7:  "Body-only Panning",
+	9:  "Dual IS",
+	10: "Dual IS Panning",
+	11: "Dual2 IS",
+	12: "Dual2 IS Panning",
+}
+
+func applyPanasonic(m *MakerNote, s *Summary) {
+	m.serial(s, &s.InternalSerial, "internal_serial", panasonicInternalSerialNumber)
+	if e, ok := m.Lookup(panasonicImageStabilization); ok {
+		v, _ := e.Uint(0)
+		if v == 3 {
+			s.Stabilization = "off"
+			s.setSource(m.source(panasonicImageStabilization, -1), "stabilization")
+		} else if mode, ok := panasonicStabilizationModes[v]; ok {
+			s.Stabilization, s.StabilizationMode = "on", mode
+			s.setSource(m.source(panasonicImageStabilization, -1), "stabilization", "stabilization_mode")
+		}
+	}
+	if e, ok := m.Lookup(panasonicBurstMode); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0:
+			s.DriveMode = DriveSingle
+		case 1, 4, 17:
+			s.DriveMode = DriveContinuous
+		case 2, 8, 18:
+			s.DriveMode = DriveBracketing
+		}
+		if s.DriveMode != "" {
+			s.setSource(m.source(panasonicBurstMode, -1), "drive_mode")
+		}
+	}
+	if e, ok := m.Lookup(panasonicFocusMode); ok {
+		switch v, _ := e.Uint(0); v {
+		case 4, 6:
+			s.FocusMode = FocusSingle
+		case 5, 7, 8:
+			s.FocusMode = FocusContinuous
+		case 1:
+			s.FocusMode = FocusAutomatic
+		case 2:
+			s.FocusMode = FocusManual
+		}
+		if s.FocusMode != "" {
+			s.setSource(m.source(panasonicFocusMode, -1), "focus_mode")
+		}
+	}
+	if e, ok := m.Lookup(panasonicShutterType); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0:
+			s.ShutterType = ShutterMechanical
+		case 1:
+			s.ShutterType = ShutterElectronic
+		case 2:
+			s.ShutterType = ShutterElectronicFrontCurtain
+		}
+		if s.ShutterType != "" {
+			s.setSource(m.source(panasonicShutterType, -1), "shutter_type")
+		}
+	}
+}
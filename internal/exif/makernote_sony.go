@@ -0,0 +1,49 @@
+package exif
+
+// Sony maker note tags.
+const (
+	sonyFocusMode          uint16 = 0x201B
+	sonyImageStabilization uint16 = 0xB026
+	sonyReleaseMode        uint16 = 0xB049
+)
+
+func applySony(m *MakerNote, s *Summary) {
+	if e, ok := m.Lookup(sonyImageStabilization); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0:
+			s.Stabilization = "off"
+			s.setSource(m.source(sonyImageStabilization, -1), "stabilization")
+		case 1:
+			s.Stabilization, s.StabilizationMode = "on", "SteadyShot"
+			s.setSource(m.source(sonyImageStabilization, -1), "stabilization", "stabilization_mode")
+		}
+	}
+	if e, ok := m.Lookup(sonyFocusMode); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0, 6: // 6 is direct manual focus
+			s.FocusMode = FocusManual
+		case 2:
+			s.FocusMode = FocusSingle
+		case 3:
+			s.FocusMode = FocusContinuous
+		case 4:
+			s.FocusMode = FocusAutomatic
+		}
+		if s.FocusMode != "" {
+			s.setSource(m.source(sonyFocusMode, -1), "focus_mode")
+		}
+	}
+	if e, ok := m.Lookup(sonyReleaseMode); ok {
+		switch v, _ := e.Uint(0); v {
+		case 0:
+			s.DriveMode = DriveSingle
+		case 2:
+			s.DriveMode = DriveContinuous
+		case 5, 6, 8:
+			s.DriveMode = DriveBracketing
+		}
+		if s.DriveMode != "" {
+			s.setSource(m.source(sonyReleaseMode, -1), "drive_mode")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package exif
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// XMP namespaces of panorama and composite metadata.
+const (
+	// NamespaceGPano is the Google Photo Sphere schema written by phones,
+	// 360° cameras and stitching software.
+	NamespaceGPano = "http://ns.google.com/photos/1.0/panorama/"
+	// NamespaceExifEX holds the EXIF 2.3 tags, CompositeImage among them,
+	// in XMP.
+	NamespaceExifEX = "http://cipa.jp/exif/1.0/"
+	// NamespaceGCamera is the Google Camera schema; HdrPlusMakernote
+	// marks an HDR+ merge of a burst.
+	NamespaceGCamera = "http://ns.google.com/photos/1.0/camera/"
+)
+
+// Normalized composite kinds.
+const (
+	// CompositeGeneral is an image combined from several frames after
+	// shooting, such as in an editor.
+	CompositeGeneral = "composite"
+	// CompositeInCamera is an image the camera combined while shooting,
+	// such as an in-camera panorama or multiple exposure.
+	CompositeInCamera = "in-camera"
+	// CompositeHDR is a merge of differently exposed frames.
+	CompositeHDR = "hdr"
+)
+
+// compositeKinds maps CompositeImage values to composite kinds.
+var compositeKinds = map[int64]string{2: CompositeGeneral, 3: CompositeInCamera}
+
+// summarizeComposite reads the EXIF CompositeImage tags.
+func summarizeComposite(x *Exif, s *Summary) {
+	if e, ok := x.Lookup(ExifIFD, TagCompositeImage); ok {
+		if v, ok := e.Int(0); ok && compositeKinds[v] != "" {
+			s.Composite = compositeKinds[v]
+			s.setSource(entrySource(e), "composite")
+		}
+	}
+	// The second value counts the frames the composite was made of.
+	if e, ok := x.Lookup(ExifIFD, TagSourceImageNumberOfCompositeImage); ok {
+		if v, ok := e.Int(1); ok && v > 0 {
+			s.CompositeFrames = int(v)
+			s.setSource(entrySource(e), "composite_frames")
+		}
+	}
+}
+
+// summarizePano fills in the GPano panorama fields and the composite kind
+// from XMP. An HDR+ marker overrides the kind EXIF recorded, which says
+// no more than that frames were combined.
+func summarizePano(xmp map[xml.Name][]string, s *Summary) {
+	get := func(ns, name string) (string, bool) {
+		v := xmp[xml.Name{Space: ns, Local: name}]
+		if len(v) == 0 || v[0] == "" {
+			return "", false
+		}
+		return v[0], true
+	}
+	number := func(field, name string) (float64, bool) {
+		v, ok := get(NamespaceGPano, name)
+		if !ok {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		if field != "" {
+			s.setSource(xmpSource(NamespaceGPano, name), field)
+		}
+		return f, true
+	}
+
+	if v, ok := get(NamespaceGPano, "ProjectionType"); ok {
+		s.Projection = v
+		s.setSource(xmpSource(NamespaceGPano, "ProjectionType"), "projection")
+	}
+	full, ok := number("pano_full_width", "FullPanoWidthPixels")
+	if ok && full > 0 {
+		s.PanoFullWidth = int(full)
+		// Equirectangular and cylindrical panoramas map their full width to
+		// 360°; a crop covers its share.
+		if s.Projection == "equirectangular" || s.Projection == "cylindrical" {
+			if crop, ok := number("", "CroppedAreaImageWidthPixels"); ok && crop > 0 && crop <= full {
+				s.PanoFOV = round(crop/full*360, 1)
+				s.setSource(xmpSource(NamespaceGPano, "CroppedAreaImageWidthPixels"), "pano_fov")
+			}
+		}
+	} else {
+		delete(s.Sources, "pano_full_width")
+	}
+	if v, ok := number("pose_heading", "PoseHeadingDegrees"); ok && v >= 0 && v < 360 {
+		v = round(v, 1)
+		s.PoseHeading = &v
+	} else {
+		delete(s.Sources, "pose_heading")
+	}
+
+	if _, ok := get(NamespaceGCamera, "HdrPlusMakernote"); ok {
+		s.Composite = CompositeHDR
+		s.setSource(xmpSource(NamespaceGCamera, "HdrPlusMakernote"), "composite")
+	} else if v, ok := get(NamespaceExifEX, "CompositeImage"); ok && s.Composite == "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && compositeKinds[n] != "" {
+			s.Composite = compositeKinds[n]
+			s.setSource(xmpSource(NamespaceExifEX, "CompositeImage"), "composite")
+		}
+	}
+}
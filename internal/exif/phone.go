@@ -0,0 +1,72 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// Computational photography features of phone shots, as listed in
+// Summary.Computational.
+const (
+	PhoneHDR         = "hdr"
+	PhonePortrait    = "portrait"
+	PhoneNight       = "night-mode"
+	PhoneLivePhoto   = "live-photo"
+	PhoneMotionPhoto = "motion-photo"
+)
+
+// googleSpecialTypes maps the suffix of a Google Camera SpecialTypeID,
+// such as "com.google.android.apps.camera.gallery.specialtype.SpecialType-NIGHT",
+// to its feature.
+var googleSpecialTypes = map[string]string{
+	"PORTRAIT": PhonePortrait,
+	"NIGHT":    PhoneNight,
+}
+
+// samsungMotionPhoto marks the video Samsung phones append after the
+// image data of a motion photo.
+var samsungMotionPhoto = []byte("MotionPhoto_Data")
+
+// addComputational adds a feature to s.Computational once. The field's
+// source is the first one recorded.
+func (s *Summary) addComputational(src Source, feature string) {
+	for _, f := range s.Computational {
+		if f == feature {
+			return
+		}
+	}
+	if len(s.Computational) == 0 {
+		s.setSource(src, "computational")
+	}
+	s.Computational = append(s.Computational, feature)
+}
+
+// summarizePhone reads the computational features Android phones record
+// in XMP and in the file trailer.
+func summarizePhone(data []byte, xmp map[xml.Name][]string, s *Summary) {
+	get := func(name string) string {
+		if v := xmp[xml.Name{Space: NamespaceGCamera, Local: name}]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if get("HdrPlusMakernote") != "" {
+		s.addComputational(xmpSource(NamespaceGCamera, "HdrPlusMakernote"), PhoneHDR)
+	}
+	if v := get("SpecialTypeID"); v != "" {
+		_, suffix, _ := strings.Cut(v, "SpecialType-")
+		if f := googleSpecialTypes[suffix]; f != "" {
+			s.addComputational(xmpSource(NamespaceGCamera, "SpecialTypeID"), f)
+		}
+	}
+	// MicroVideo is the older name of MotionPhoto.
+	for _, name := range []string{"MotionPhoto", "MicroVideo"} {
+		if get(name) == "1" {
+			s.addComputational(xmpSource(NamespaceGCamera, name), PhoneMotionPhoto)
+		}
+	}
+	if bytes.Contains(data, samsungMotionPhoto) {
+		s.addComputational(Source{Location: LocationTrailer, Tag: string(samsungMotionPhoto)}, PhoneMotionPhoto)
+	}
+}
@@ -0,0 +1,275 @@
+package exif
+
+//go:generate go run ./gentags
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TagInfo describes a tag the decoder knows. The registry is generated
+// from tags.tsv, which also drives the Tag constants, validation, the
+// directories the parser follows and shootlog tags.
+type TagInfo struct {
+	// IFD is the directory of the tag; IFD0 tags are also valid in IFD1.
+	IFD  IFDKind
+	ID   uint16
+	Name string
+	// Types are the types EXIF 2.32 allows and Count the number of
+	// values, 0 for any.
+	Types       []Type
+	Count       uint32
+	Description string
+	// Fields are the Summary fields, by JSON name, read from the tag.
+	Fields []string
+	// Values names enumerated values; Closed reports whether it lists
+	// every value EXIF 2.32 defines.
+	Values []TagValue
+	Closed bool
+	// Required and Recommended list the directories where EXIF 2.32
+	// makes the tag mandatory for JPEG files or recommends it.
+	Required, Recommended []IFDKind
+
+	// sub is the directory the tag points to; IFD0, which no tag points
+	// to, marks other tags.
+	sub IFDKind
+	// format selects the rendering of Format; see formatters.
+	format string
+}
+
+// TagValue names one value of an enumerated tag. Value is the number in
+// decimal, or the text of an ASCII tag.
+type TagValue struct {
+	Value, Name string
+}
+
+// Tags returns the known tags by directory and ID.
+func Tags() []TagInfo {
+	return append([]TagInfo(nil), tagTable...)
+}
+
+// tagIndex finds tags by directory and ID.
+var tagIndex = func() map[IFDKind]map[uint16]int {
+	m := map[IFDKind]map[uint16]int{}
+	for i, t := range tagTable {
+		if m[t.IFD] == nil {
+			m[t.IFD] = map[uint16]int{}
+		}
+		m[t.IFD][t.ID] = i
+	}
+	return m
+}()
+
+// LookupTag returns the tag with the given ID in a directory.
+func LookupTag(ifd IFDKind, id uint16) (TagInfo, bool) {
+	if ifd == IFD1 {
+		ifd = IFD0
+	}
+	i, ok := tagIndex[ifd][id]
+	if !ok {
+		return TagInfo{}, false
+	}
+	return tagTable[i], true
+}
+
+// TagByName returns the tag with the given name, ignoring case.
+func TagByName(name string) (TagInfo, bool) {
+	for _, t := range tagTable {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return TagInfo{}, false
+}
+
+// pointerTags lists the tags pointing to sub-directories, in table order
+// so that a directory is read before the directories it points to.
+var pointerTags = func() []TagInfo {
+	var out []TagInfo
+	for _, t := range tagTable {
+		if t.sub != IFD0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}()
+
+// tagsWhere returns, per directory, the IDs of the tags whose list
+// selected by get names that directory.
+func tagsWhere(get func(TagInfo) []IFDKind) map[IFDKind][]uint16 {
+	m := map[IFDKind][]uint16{}
+	for _, t := range tagTable {
+		for _, k := range get(t) {
+			m[k] = append(m[k], t.ID)
+		}
+	}
+	return m
+}
+
+// requiredTags and recommendedTags list, per directory, the tags EXIF
+// 2.32 makes mandatory for compressed (JPEG) primary images and those it
+// recommends.
+var (
+	requiredTags    = tagsWhere(func(t TagInfo) []IFDKind { return t.Required })
+	recommendedTags = tagsWhere(func(t TagInfo) []IFDKind { return t.Recommended })
+)
+
+// valueName returns the name of an enumerated value.
+func (t TagInfo) valueName(v string) (string, bool) {
+	for _, tv := range t.Values {
+		if tv.Value == v {
+			return tv.Name, true
+		}
+	}
+	return "", false
+}
+
+// enumValue returns the value of e as listed in tags.tsv, for ASCII
+// entries and single numbers.
+func enumValue(e Entry) (string, bool) {
+	if e.Type == TypeASCII {
+		return e.Text(), true
+	}
+	if e.Len() != 1 {
+		return "", false
+	}
+	v, ok := e.Int(0)
+	return strconv.FormatInt(v, 10), ok
+}
+
+// Format renders the value of an entry of the tag for people: the names
+// of enumerated values, decoded text, and units where the tag has them.
+func (t TagInfo) Format(e Entry) string {
+	if raw, ok := enumValue(e); ok && len(t.Values) > 0 {
+		if name, ok := t.valueName(raw); ok {
+			return name
+		}
+	}
+	if f, ok := formatters[t.format]; ok {
+		if s, ok := f(e); ok {
+			return s
+		}
+	}
+	return FormatEntry(e)
+}
+
+// formatters implement the format column of tags.tsv. They report false
+// for values they cannot render, which fall back to FormatEntry.
+var formatters = map[string]func(Entry) (string, bool){
+	"xp":      func(e Entry) (string, bool) { return strconv.Quote(decodeXP(e.Value)), true },
+	"comment": func(e Entry) (string, bool) { return strconv.Quote(DecodeUserComment(e.Value, e.order)), true },
+	"version": func(e Entry) (string, bool) {
+		v := string(e.Value)
+		if len(v) != 4 {
+			return "", false
+		}
+		major, _ := strconv.Atoi(v[:2])
+		return fmt.Sprintf("%d.%s", major, v[2:]), true
+	},
+	"components": func(e Entry) (string, bool) {
+		var b strings.Builder
+		for _, c := range e.Value {
+			if c > 0 && int(c) < len(componentNames) {
+				b.WriteString(componentNames[c])
+			}
+		}
+		return b.String(), b.Len() > 0
+	},
+	"exposure": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return FormatExposure(v) + " s", ok && v > 0
+	},
+	"fnumber": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return fmt.Sprintf("f/%g", round(v, 1)), ok
+	},
+	"mm": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return fmt.Sprintf("%g mm", round(v, 1)), ok
+	},
+	"m": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return fmt.Sprintf("%g m", round(v, 2)), ok
+	},
+	"ev": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return fmt.Sprintf("%+g EV", round(v, 2)), ok
+	},
+	"decimal": func(e Entry) (string, bool) {
+		v, ok := e.Float(0)
+		return strconv.FormatFloat(round(v, 2), 'f', -1, 64), ok
+	},
+	"dms": func(e Entry) (string, bool) {
+		d, ok1 := e.Float(0)
+		m, ok2 := e.Float(1)
+		s, ok3 := e.Float(2)
+		return fmt.Sprintf("%g° %g' %g\"", d, m, round(s, 2)), ok1 && ok2 && ok3
+	},
+	"time": func(e Entry) (string, bool) {
+		h, ok1 := e.Float(0)
+		m, ok2 := e.Float(1)
+		s, ok3 := e.Float(2)
+		return fmt.Sprintf("%02d:%02d:%02d", int(h), int(m), int(math.Round(s))), ok1 && ok2 && ok3
+	},
+	"gpsversion": func(e Entry) (string, bool) {
+		parts := make([]string, len(e.Value))
+		for i, b := range e.Value {
+			parts[i] = strconv.Itoa(int(b))
+		}
+		return strings.Join(parts, "."), len(parts) > 0
+	},
+	"lens": func(e Entry) (string, bool) {
+		var v [4]float64
+		for i := range v {
+			f, ok := e.Float(i)
+			if !ok {
+				return "", false
+			}
+			v[i] = f
+		}
+		focal := fmt.Sprintf("%gmm", v[0])
+		if v[1] != v[0] {
+			focal = fmt.Sprintf("%g-%gmm", v[0], v[1])
+		}
+		aperture := fmt.Sprintf("f/%g", v[2])
+		if v[3] != v[2] {
+			aperture = fmt.Sprintf("f/%g-%g", v[2], v[3])
+		}
+		return focal + " " + aperture, true
+	},
+}
+
+// componentNames are the channels of ComponentsConfiguration.
+var componentNames = []string{"", "Y", "Cb", "Cr", "R", "G", "B"}
+
+// FormatEntry renders the values of any entry: text for ASCII, bytes in
+// hex for UNDEFINED and numbers otherwise, with rationals as fractions.
+// Long values are cut short after 16 elements.
+func FormatEntry(e Entry) string {
+	if e.Type == TypeASCII {
+		return strconv.Quote(e.Text())
+	}
+	var vals []string
+	for i := 0; i < e.Len(); i++ {
+		if i == 16 {
+			vals = append(vals, fmt.Sprintf("... (%d values)", e.Len()))
+			break
+		}
+		switch e.Type {
+		case TypeRational, TypeSRational:
+			num, den, _ := e.Rational(i)
+			vals = append(vals, fmt.Sprintf("%d/%d", num, den))
+		case TypeUndefined:
+			vals = append(vals, fmt.Sprintf("%02x", e.Value[i]))
+		case TypeFloat, TypeDouble:
+			v, _ := e.Float(i)
+			vals = append(vals, strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			v, _ := e.Int(i)
+			vals = append(vals, strconv.FormatInt(v, 10))
+		}
+	}
+	return strings.Join(vals, " ")
+}
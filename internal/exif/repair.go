@@ -0,0 +1,455 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Fix is one defect Repair corrected.
+type Fix struct {
+	IFD     string `json:"ifd,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Fix) String() string {
+	switch {
+	case f.Tag != "":
+		return fmt.Sprintf("%s %s: %s", f.IFD, f.Tag, f.Message)
+	case f.IFD != "":
+		return fmt.Sprintf("%s: %s", f.IFD, f.Message)
+	}
+	return f.Message
+}
+
+// Tags holding the offset of data elsewhere in the TIFF structure, which
+// Repair recomputes rather than copies.
+const (
+	tagThumbnailOffset = 0x0201
+	tagThumbnailLength = 0x0202
+)
+
+// Repair rewrites the APP1 Exif segment of a JPEG file as a clean block
+// and lists what it corrected: a segment length that disagrees with where
+// the next segment starts, ASCII values without a terminating NUL,
+// next-IFD offsets that lead nowhere, duplicate and unsorted entries, and
+// pointers or values outside the block, which are dropped. Every other
+// value keeps its bytes, byte order and, for the maker note, its offset,
+// since vendors address maker note data relative to the TIFF header. A
+// file without defects is returned unchanged with no fixes.
+func Repair(image []byte) ([]byte, []Fix, error) {
+	if !IsJPEG(image) {
+		return nil, nil, fmt.Errorf("%w: only JPEG files can be repaired", ErrFormat)
+	}
+	at, tiffStart, end, fixes, err := app1Extent(image)
+	if err != nil {
+		return nil, nil, err
+	}
+	tiff, more, err := repairTIFF(image[tiffStart:end])
+	if err != nil {
+		return nil, nil, err
+	}
+	fixes = append(fixes, more...)
+	if len(fixes) == 0 {
+		return image, nil, nil
+	}
+	payload := len(exifHeader) + len(tiff)
+	if payload+2 > maxAPP1 {
+		return nil, nil, fmt.Errorf("%w: EXIF segment would be %d bytes", ErrFormat, payload)
+	}
+	out := make([]byte, 0, len(image)-(end-at)+payload+4)
+	out = append(out, image[:at]...)
+	out = append(out, 0xFF, markerAPP1, byte((payload+2)>>8), byte(payload+2))
+	out = append(out, exifHeader...)
+	out = append(out, tiff...)
+	return append(out, image[end:]...), fixes, nil
+}
+
+// app1Extent finds the APP1 Exif segment of a JPEG file: the offset of
+// its marker, of its TIFF header and of its end. A stored length that does
+// not end where a chain of valid segments starts is replaced by the
+// nearest end that does, past all the data the TIFF structure refers to.
+func app1Extent(data []byte) (at, tiffStart, end int, fixes []Fix, err error) {
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return 0, 0, 0, nil, ErrNoExif
+		}
+		marker := data[pos+1]
+		if marker == markerSOS || marker == markerEOI {
+			return 0, 0, 0, nil, ErrNoExif
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == markerAPP1 && bytes.HasPrefix(data[pos+4:], exifHeader) {
+			at, tiffStart = pos, pos+4+len(exifHeader)
+			end = pos + 2 + length
+			if end <= len(data) && end >= tiffStart && segmentChain(data, end) {
+				return at, tiffStart, end, nil, nil
+			}
+			break
+		}
+		if length < 2 || !segmentChain(data, pos+2+length) {
+			return 0, 0, 0, nil, fmt.Errorf("%w: segment 0x%02X at offset %d", ErrTruncated, marker, pos)
+		}
+		pos += 2 + length
+	}
+	used := tiffExtent(data[tiffStart:])
+	for end = tiffStart + used; end < len(data); end++ {
+		if data[end] == 0xFF && segmentChain(data, end) {
+			fix := Fix{Message: fmt.Sprintf("APP1 length %d corrected to %d", binary.BigEndian.Uint16(data[at+2:]), end-at-2)}
+			return at, tiffStart, end, []Fix{fix}, nil
+		}
+	}
+	return 0, 0, 0, nil, fmt.Errorf("%w: no JPEG segment follows the APP1 Exif segment", ErrFormat)
+}
+
+// segmentChain reports whether the JPEG marker segments starting at pos
+// run without a gap to the start of scan or the end of the image.
+func segmentChain(data []byte, pos int) bool {
+	for pos+2 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		switch {
+		case marker == markerEOI:
+			return true
+		case marker == 0xFF:
+			// Fill byte.
+			pos++
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD7:
+			pos += 2
+			continue
+		case marker < 0xC0:
+			return false
+		}
+		if pos+4 > len(data) {
+			return false
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return false
+		}
+		if marker == markerSOS {
+			return true
+		}
+		pos += 2 + length
+	}
+	return false
+}
+
+// tiffExtent returns the end of the last byte a TIFF structure at the
+// start of data refers to: its directories, their values and the
+// thumbnail. Directories that do not fit in data are not counted.
+func tiffExtent(data []byte) int {
+	order, off, err := readHeader(data)
+	if err != nil {
+		return 0
+	}
+	r := ifdReader{data: data, order: order}
+	extent := 8
+	seen := map[uint32]bool{}
+	var walk func(off uint32, kind IFDKind)
+	walk = func(off uint32, kind IFDKind) {
+		if off == 0 || seen[off] {
+			return
+		}
+		seen[off] = true
+		entries, next, err := r.readIFD(off, kind)
+		if err != nil {
+			return
+		}
+		dirEnd := int(off) + 2 + 12*int(order.Uint16(data[off:])) + 4
+		extent = max(extent, min(dirEnd, len(data)))
+		var thumb, thumbLen uint32
+		for _, e := range entries {
+			if len(e.Value) > 4 {
+				extent = max(extent, int(e.Offset)+len(e.Value))
+			}
+			switch e.Tag {
+			case tagThumbnailOffset:
+				thumb, _ = e.Uint(0)
+			case tagThumbnailLength:
+				thumbLen, _ = e.Uint(0)
+			}
+			for _, p := range pointerTags {
+				if p.IFD == kind && p.ID == e.Tag {
+					if sub, ok := e.Uint(0); ok {
+						walk(sub, p.sub)
+					}
+				}
+			}
+		}
+		if thumb != 0 && uint64(thumb)+uint64(thumbLen) <= uint64(len(data)) {
+			extent = max(extent, int(thumb+thumbLen))
+		}
+		if kind == IFD0 {
+			walk(next, IFD1)
+		}
+	}
+	walk(off, IFD0)
+	return extent
+}
+
+// repairDir is a directory as Repair rewrites it.
+type repairDir struct {
+	kind    IFDKind
+	entries []Entry
+	// offset is where the directory is written.
+	offset uint32
+}
+
+// repairTIFF rewrites a TIFF structure without its defects.
+func repairTIFF(data []byte) ([]byte, []Fix, error) {
+	order, off, err := readHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fixes []Fix
+	fix := func(kind IFDKind, tag int, format string, args ...any) {
+		f := Fix{IFD: kind.String(), Message: fmt.Sprintf(format, args...)}
+		if tag >= 0 {
+			f.Tag = fmt.Sprintf("0x%04X", tag)
+		}
+		fixes = append(fixes, f)
+	}
+	dirs := map[IFDKind]*repairDir{}
+	// read reads one directory in the original order, so that defects can
+	// be told apart, and returns its next-IFD offset.
+	read := func(kind IFDKind, off uint32) (uint32, bool) {
+		if uint64(off)+2 > uint64(len(data)) {
+			return 0, false
+		}
+		n := int(order.Uint16(data[off:]))
+		start := int(off) + 2
+		if n > maxIFDEntries || start+n*12 > len(data) {
+			return 0, false
+		}
+		d := &repairDir{kind: kind}
+		seen := map[uint16]bool{}
+		prev := -1
+		sorted := true
+		for i := 0; i < n; i++ {
+			b := data[start+i*12 : start+i*12+12]
+			e := Entry{
+				IFD:    kind,
+				Tag:    order.Uint16(b[0:]),
+				Type:   Type(order.Uint16(b[2:])),
+				Count:  order.Uint32(b[4:]),
+				Offset: order.Uint32(b[8:]),
+				order:  order,
+			}
+			if int(e.Tag) < prev {
+				sorted = false
+			}
+			prev = max(prev, int(e.Tag))
+			if seen[e.Tag] {
+				fix(kind, int(e.Tag), "duplicate entry removed")
+				continue
+			}
+			size := e.Type.Size()
+			if size == 0 {
+				fix(kind, int(e.Tag), "entry of unknown type %d removed", uint16(e.Type))
+				continue
+			}
+			total := uint64(size) * uint64(e.Count)
+			if total <= 4 {
+				e.Value = bytes.Clone(b[8 : 8+total])
+			} else if uint64(e.Offset)+total <= uint64(len(data)) {
+				e.Value = bytes.Clone(data[e.Offset : uint64(e.Offset)+total])
+			} else {
+				fix(kind, int(e.Tag), "entry with value beyond the end of the block removed")
+				continue
+			}
+			if e.Type == TypeASCII && (len(e.Value) == 0 || e.Value[len(e.Value)-1] != 0) {
+				e.Value = append(e.Value, 0)
+				e.Count++
+				fix(kind, int(e.Tag), "ASCII value terminated")
+			}
+			seen[e.Tag] = true
+			d.entries = append(d.entries, e)
+		}
+		if !sorted {
+			fix(kind, -1, "entries sorted in ascending tag order")
+			sort.SliceStable(d.entries, func(i, j int) bool { return d.entries[i].Tag < d.entries[j].Tag })
+		}
+		dirs[kind] = d
+		var next uint32
+		if end := start + n*12; end+4 <= len(data) {
+			next = order.Uint32(data[end:])
+		} else {
+			fix(kind, -1, "missing next-IFD offset added")
+		}
+		return next, true
+	}
+
+	next, ok := read(IFD0, off)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: IFD0 at offset %d is unreadable", ErrFormat, off)
+	}
+	if next != 0 {
+		if next == off {
+			fix(IFD0, -1, "next-IFD offset pointing back at IFD0 cleared")
+		} else if next1, ok := read(IFD1, next); !ok {
+			fix(IFD0, -1, "next-IFD offset %d leads to no directory; cleared", next)
+		} else if next1 != 0 {
+			fix(IFD1, -1, "next-IFD offset %d cleared; IFD1 ends the chain", next1)
+		}
+	}
+	for _, p := range pointerTags {
+		d := dirs[p.IFD]
+		if d == nil {
+			continue
+		}
+		for i, e := range d.entries {
+			if e.Tag != p.ID {
+				continue
+			}
+			sub, ok := e.Uint(0)
+			next, found := uint32(0), false
+			if ok && dirs[p.sub] == nil && sub != off {
+				next, found = read(p.sub, sub)
+			}
+			if !found {
+				fix(p.IFD, int(p.ID), "pointer to unreadable %s removed", p.sub)
+				d.entries = append(d.entries[:i], d.entries[i+1:]...)
+			} else if next != 0 {
+				fix(p.sub, -1, "next-IFD offset %d cleared", next)
+			}
+			break
+		}
+	}
+
+	var thumb []byte
+	if d := dirs[IFD1]; d != nil {
+		var at, n uint32
+		var hasAt, hasLen bool
+		for _, e := range d.entries {
+			switch e.Tag {
+			case tagThumbnailOffset:
+				at, hasAt = e.Uint(0)
+			case tagThumbnailLength:
+				n, hasLen = e.Uint(0)
+			}
+		}
+		if hasAt || hasLen {
+			if hasAt && hasLen && uint64(at)+uint64(n) <= uint64(len(data)) {
+				thumb = data[at : at+n]
+			} else {
+				fix(IFD1, tagThumbnailOffset, "thumbnail outside the block removed")
+				kept := d.entries[:0]
+				for _, e := range d.entries {
+					if e.Tag != tagThumbnailOffset && e.Tag != tagThumbnailLength {
+						kept = append(kept, e)
+					}
+				}
+				d.entries = kept
+			}
+		}
+	}
+	if len(fixes) == 0 {
+		return data, nil, nil
+	}
+	return encodeRepaired(order, dirs, thumb, data), fixes, nil
+}
+
+// encodeRepaired lays out the repaired directories after the header, each
+// followed by its values, then the thumbnail. The maker note is written at
+// its original offset: before the directories when they do not fit
+// between the header and it.
+func encodeRepaired(order binary.ByteOrder, dirs map[IFDKind]*repairDir, thumb, original []byte) []byte {
+	var list []*repairDir
+	for _, kind := range []IFDKind{IFD0, ExifIFD, GPSIFD, InteropIFD, IFD1} {
+		if d := dirs[kind]; d != nil {
+			list = append(list, d)
+		}
+	}
+	var note *Entry
+	if d := dirs[ExifIFD]; d != nil {
+		for i := range d.entries {
+			if e := &d.entries[i]; e.Tag == TagMakerNote && len(e.Value) > 4 && e.Offset >= 8 {
+				note = e
+			}
+		}
+	}
+	even := func(n uint32) uint32 { return (n + 1) &^ 1 }
+	size := uint32(0)
+	for _, d := range list {
+		size += uint32(2 + 12*len(d.entries) + 4)
+		for i := range d.entries {
+			if e := &d.entries[i]; len(e.Value) > 4 && e != note {
+				size += even(uint32(len(e.Value)))
+			}
+		}
+	}
+	size += even(uint32(len(thumb)))
+
+	start := uint32(8)
+	if note != nil && note.Offset < start+size {
+		start = even(note.Offset + uint32(len(note.Value)))
+	}
+	pos := start
+	for _, d := range list {
+		d.offset = pos
+		pos += uint32(2 + 12*len(d.entries) + 4)
+		for i := range d.entries {
+			if e := &d.entries[i]; len(e.Value) > 4 && e != note {
+				pos += even(uint32(len(e.Value)))
+			}
+		}
+	}
+	thumbAt := pos
+
+	total := pos + even(uint32(len(thumb)))
+	if note != nil {
+		total = max(total, even(note.Offset+uint32(len(note.Value))))
+	}
+	out := make([]byte, total)
+	copy(out, original[:4])
+	order.PutUint32(out[4:], dirs[IFD0].offset)
+	if note != nil {
+		copy(out[note.Offset:], note.Value)
+	}
+	copy(out[thumbAt:], thumb)
+	for _, d := range list {
+		p := d.offset
+		order.PutUint16(out[p:], uint16(len(d.entries)))
+		values := p + uint32(2+12*len(d.entries)+4)
+		p += 2
+		for _, e := range d.entries {
+			value := e.Value
+			long := func(v uint32) []byte {
+				b := make([]byte, 4)
+				order.PutUint32(b, v)
+				return b
+			}
+			for _, pt := range pointerTags {
+				if pt.IFD == d.kind && pt.ID == e.Tag {
+					value = long(dirs[pt.sub].offset)
+				}
+			}
+			if d.kind == IFD1 && e.Tag == tagThumbnailOffset {
+				value = long(thumbAt)
+			}
+			order.PutUint16(out[p:], e.Tag)
+			order.PutUint16(out[p+2:], uint16(e.Type))
+			order.PutUint32(out[p+4:], e.Count)
+			switch {
+			case e.Tag == TagMakerNote && note != nil && d.kind == ExifIFD:
+				order.PutUint32(out[p+8:], note.Offset)
+			case len(value) <= 4:
+				copy(out[p+8:p+12], value)
+			default:
+				order.PutUint32(out[p+8:], values)
+				copy(out[values:], value)
+				values += even(uint32(len(value)))
+			}
+			p += 12
+		}
+		// Only IFD0 chains to another directory.
+		if d.kind == IFD0 && dirs[IFD1] != nil {
+			order.PutUint32(out[p:], dirs[IFD1].offset)
+		}
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package exif
+
+import "strings"
+
+// settingTags are the EXIF tags of the camera's processing settings,
+// summarized by the names tags.tsv gives their values.
+var settingTags = []struct {
+	field string
+	tag   uint16
+	dst   func(*Summary) *string
+}{
+	{"exposure_mode", TagExposureMode, func(s *Summary) *string { return &s.ExposureMode }},
+	{"gain_control", TagGainControl, func(s *Summary) *string { return &s.GainControl }},
+	{"subject_distance_range", TagSubjectDistanceRange, func(s *Summary) *string { return &s.SubjectDistanceRange }},
+	{"custom_rendered", TagCustomRendered, func(s *Summary) *string { return &s.CustomRendered }},
+	{"contrast", TagContrast, func(s *Summary) *string { return &s.Contrast }},
+	{"saturation", TagSaturation, func(s *Summary) *string { return &s.Saturation }},
+	{"sharpness", TagSharpness, func(s *Summary) *string { return &s.Sharpness }},
+}
+
+// summarizeSettings reads the exposure mode, gain control, digital zoom
+// and the other processing settings. Values become their registry names
+// in lower case with hyphens, such as "auto-bracket"; undefined values
+// and an "unknown" subject distance range are left out.
+func summarizeSettings(x *Exif, s *Summary) {
+	for _, st := range settingTags {
+		e, ok := x.Lookup(ExifIFD, st.tag)
+		if !ok {
+			continue
+		}
+		info, _ := LookupTag(ExifIFD, st.tag)
+		raw, ok := enumValue(e)
+		if !ok {
+			continue
+		}
+		name, ok := info.valueName(raw)
+		if !ok || name == "Unknown" {
+			continue
+		}
+		*st.dst(s) = strings.ReplaceAll(strings.ToLower(name), " ", "-")
+		s.setSource(entrySource(e), st.field)
+	}
+	// A ratio of 0 means digital zoom was not used.
+	if e, ok := x.Lookup(ExifIFD, TagDigitalZoomRatio); ok {
+		if v, ok := e.Float(0); ok && v > 0 {
+			s.DigitalZoomRatio = round(v, 2)
+			s.setSource(entrySource(e), "digital_zoom_ratio")
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package exif
+
+import "fmt"
+
+// Source records where a Summary field was read from, so that values can
+// be traced back when two tools disagree about the "same" field.
+type Source struct {
+	// Location is the directory or metadata block: IFD0, ExifIFD, GPS,
+	// MakerNote:<vendor>, XMP, IPTC, ICC, C2PA, JPEG or HEIF (the image's
+	// own structure), Trailer (data after the end of the image), Path (the
+	// file or folder name of inferred dates), Thumbnail (the pixels of an
+	// embedded thumbnail or preview), or Catalog:<application>
+	// and Sidecar:<application> for values merged from a photo catalog or
+	// a raw developer's sidecar.
+	Location string `json:"location"`
+	// Tag is the raw tag ID in hex. Values taken from an element of a
+	// maker note array carry the element index, e.g. "0x0001[34]".
+	Tag string `json:"tag,omitempty"`
+}
+
+// Metadata blocks other than TIFF directories.
+const (
+	LocationXMP  = "XMP"
+	LocationIPTC = "IPTC"
+	LocationICC  = "ICC"
+	// LocationTrailer is data appended after the end of the image, such
+	// as the video of a motion photo.
+	LocationTrailer = "Trailer"
+	// LocationPath is the file or folder name a date was inferred from;
+	// the tag is the text it was read from.
+	LocationPath = "Path"
+	// LocationThumbnail is the embedded image values were computed from;
+	// the tag is "IFD1" for the EXIF thumbnail or "preview" for a raw
+	// file's preview.
+	LocationThumbnail = "Thumbnail"
+)
+
+// entrySource returns the Source of an EXIF entry.
+func entrySource(e Entry) Source {
+	return Source{Location: e.IFD.String(), Tag: fmt.Sprintf("0x%04X", e.Tag)}
+}
+
+// xmpSource returns the Source of an XMP property. The tag names the
+// property with its conventional prefix.
+func xmpSource(ns, name string) Source {
+	prefix := map[string]string{
+		NamespaceDC:        "dc",
+		NamespacePhotoshop: "photoshop",
+		NamespaceIPTCCore:  "Iptc4xmpCore",
+		NamespaceXMP:       "xmp",
+		NamespaceGPano:     "GPano",
+		NamespaceExifEX:    "exifEX",
+		NamespaceGCamera:   "GCamera",
+		NamespaceDJI:       "drone-dji",
+		NamespaceInferred:  "inferred",
+	}[ns]
+	return Source{Location: LocationXMP, Tag: prefix + ":" + name}
+}
+
+// source returns the Source of a maker note tag. index is the
+// element index for array tags, or -1.
+func (m *MakerNote) source(tag uint16, index int) Source {
+	src := Source{Location: "MakerNote:" + m.Vendor, Tag: fmt.Sprintf("0x%04X", tag)}
+	if index >= 0 {
+		src.Tag += fmt.Sprintf("[%d]", index)
+	}
+	return src
+}
+
+// setSource records the origin of the named Summary fields.
+func (s *Summary) setSource(src Source, fields ...string) {
+	if s.Sources == nil {
+		s.Sources = make(map[string]Source)
+	}
+	for _, f := range fields {
+		s.Sources[f] = src
+	}
+}
@@ -0,0 +1,738 @@
+package exif
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/astro"
+)
+
+// Summary is the condensed, normalized metadata of a single photo.
+type Summary struct {
+	Path string `json:"path,omitempty"`
+
+	Make      string `json:"make,omitempty"`
+	Model     string `json:"model,omitempty"`
+	LensMake  string `json:"lens_make,omitempty"`
+	LensModel string `json:"lens_model,omitempty"`
+	// BodySerial is the EXIF BodySerialNumber, or the serial number in a
+	// Canon or Nikon maker note, and LensSerial the LensSerialNumber.
+	// InternalSerial is the maker note InternalSerialNumber of Canon,
+	// Fujifilm and Panasonic bodies, which identifies a body that writes
+	// no serial number.
+	BodySerial     string `json:"body_serial,omitempty"`
+	LensSerial     string `json:"lens_serial,omitempty"`
+	InternalSerial string `json:"internal_serial,omitempty"`
+	Software       string `json:"software,omitempty"`
+	Artist         string `json:"artist,omitempty"`
+	Copyright      string `json:"copyright,omitempty"`
+
+	// Credit and the contact fields come from IPTC-IIM and the IPTC Core
+	// XMP schema. Artist, Copyright, Description, Title, Keywords and
+	// Rating also fall back to them when EXIF has no value.
+	Credit       string `json:"credit,omitempty"`
+	Contact      string `json:"contact,omitempty"`
+	ContactEmail string `json:"contact_email,omitempty"`
+	ContactURL   string `json:"contact_url,omitempty"`
+	ContactPhone string `json:"contact_phone,omitempty"`
+	// JobID identifies the job or assignment the photo was taken for,
+	// from IPTC-IIM or XMP TransmissionReference.
+	JobID string `json:"job_id,omitempty"`
+
+	// ColorSpace is "sRGB", "Adobe RGB" or "uncalibrated" as recorded by
+	// EXIF ColorSpace and the interoperability index.
+	ColorSpace string `json:"color_space,omitempty"`
+	// ICCProfile is the description of the embedded ICC profile.
+	ICCProfile string `json:"icc_profile,omitempty"`
+
+	// Description is the ImageDescription caption.
+	Description string `json:"description,omitempty"`
+	// Comment is the UserComment, decoded according to its character code
+	// header, or XPComment when UserComment is empty.
+	Comment string `json:"comment,omitempty"`
+
+	// Title, Author, Subject and Keywords come from the Windows XP* tags,
+	// which hold UTF-16 text.
+	Title    string   `json:"title,omitempty"`
+	Author   string   `json:"author,omitempty"`
+	Subject  string   `json:"subject,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Rating is the 1-5 star rating; 0 means unrated.
+	Rating int `json:"rating,omitempty"`
+	// Collections are the albums or collections holding the photo in a
+	// catalog application; files carry none themselves.
+	Collections []string `json:"collections,omitempty"`
+	// Editor is the raw developer whose sidecar accompanies the file,
+	// Edited whether it applied adjustments and EditModules which.
+	Editor      string   `json:"editor,omitempty"`
+	Edited      bool     `json:"edited,omitempty"`
+	EditModules []string `json:"edit_modules,omitempty"`
+	// C2PAManifests counts the manifests of the file's C2PA Content
+	// Credentials. The other C2PA fields describe the active manifest: the
+	// application that made its claim, the signing certificate's subject,
+	// the recorded actions and whether its hash of the file still matches
+	// ("match", "mismatch" or "unchecked").
+	C2PAManifests      int      `json:"c2pa_manifests,omitempty"`
+	C2PAClaimGenerator string   `json:"c2pa_claim_generator,omitempty"`
+	C2PASigner         string   `json:"c2pa_signer,omitempty"`
+	C2PAActions        []string `json:"c2pa_actions,omitempty"`
+	C2PAHash           string   `json:"c2pa_hash,omitempty"`
+
+	// DateTimeOriginal is the capture time formatted as
+	// 2006-01-02T15:04:05, followed by the UTC offset when the camera
+	// recorded one.
+	DateTimeOriginal string `json:"datetime_original,omitempty"`
+	// InferredDate is the capture date the file or folder name suggests
+	// for a photo recording none, when asked for: 2006-01-02T15:04:05, or
+	// 2006-01-02 when the name gives no time. DateSource says where a
+	// capture time the camera did not record came from, "filename" or
+	// "folder", for inferred dates and for dates written from them.
+	InferredDate string `json:"inferred_date,omitempty"`
+	DateSource   string `json:"date_source,omitempty"`
+
+	// ExposureTime is in seconds.
+	ExposureTime    float64 `json:"exposure_time,omitempty"`
+	FNumber         float64 `json:"f_number,omitempty"`
+	ISO             int     `json:"iso,omitempty"`
+	ExposureBias    float64 `json:"exposure_bias,omitempty"`
+	FocalLength     float64 `json:"focal_length,omitempty"`
+	FocalLength35mm int     `json:"focal_length_35mm,omitempty"`
+	// SubjectDistance is in meters; distances recorded as infinity are
+	// left out.
+	SubjectDistance float64 `json:"subject_distance,omitempty"`
+	// HyperfocalDistance is in meters, derived from the focal length,
+	// aperture and crop factor with a 0.03 mm full-frame circle of
+	// confusion. It needs FocalLength35mm.
+	HyperfocalDistance float64 `json:"hyperfocal_distance,omitempty"`
+
+	// The processing settings are the EXIF value names in lower case with
+	// hyphens, e.g. ExposureMode "auto-bracket" and GainControl
+	// "low-gain-up". DigitalZoomRatio is left out when zoom was not used.
+	ExposureMode         string  `json:"exposure_mode,omitempty"`
+	GainControl          string  `json:"gain_control,omitempty"`
+	DigitalZoomRatio     float64 `json:"digital_zoom_ratio,omitempty"`
+	SubjectDistanceRange string  `json:"subject_distance_range,omitempty"`
+	CustomRendered       string  `json:"custom_rendered,omitempty"`
+	Contrast             string  `json:"contrast,omitempty"`
+	Saturation           string  `json:"saturation,omitempty"`
+	Sharpness            string  `json:"sharpness,omitempty"`
+
+	Width       int `json:"width,omitempty"`
+	Height      int `json:"height,omitempty"`
+	Orientation int `json:"orientation,omitempty"`
+	// XResolution and YResolution are the pixels per ResolutionUnit,
+	// "inches" or "cm", recorded for printing. PrintWidth and PrintHeight
+	// are the size in inches the image prints at PrintDPI, from its pixel
+	// dimensions.
+	XResolution    float64 `json:"x_resolution,omitempty"`
+	YResolution    float64 `json:"y_resolution,omitempty"`
+	ResolutionUnit string  `json:"resolution_unit,omitempty"`
+	PrintWidth     float64 `json:"print_width,omitempty"`
+	PrintHeight    float64 `json:"print_height,omitempty"`
+	// AspectRatio is the ratio of the long side to the short one, such as
+	// "3:2" or "16:9". CropMode is the in-camera crop the photo was taken
+	// with: an aspect ratio other than the sensor's, or "APS-C" or
+	// "APS-H" for a smaller area of a larger sensor.
+	AspectRatio string `json:"aspect_ratio,omitempty"`
+	CropMode    string `json:"crop_mode,omitempty"`
+	// MeanLuminance, ClippedHighlights and ClippedShadows are estimated
+	// from the embedded thumbnail when asked for: the mean luminance and
+	// the shares of pixels clipped at either end, 0-1.
+	// LuminanceHistogram is the percentage of pixels in each of 16
+	// luminance bands, darkest first, and ExposureWarning "blown" or
+	// "crushed" for a large clipped share.
+	MeanLuminance      float64 `json:"mean_luminance,omitempty"`
+	ClippedHighlights  float64 `json:"clipped_highlights,omitempty"`
+	ClippedShadows     float64 `json:"clipped_shadows,omitempty"`
+	LuminanceHistogram []int   `json:"luminance_histogram,omitempty"`
+	ExposureWarning    string  `json:"exposure_warning,omitempty"`
+	// Colors are the names of the colors, such as "blue" or "white", that
+	// cover at least a tenth of the embedded thumbnail, most common
+	// first, when asked for. DominantColor is the first of them.
+	DominantColor string   `json:"dominant_color,omitempty"`
+	Colors        []string `json:"colors,omitempty"`
+	// ImageHash is the SHA-256 of the image data alone, as ImageDataHash
+	// computes it, when asked for: copies whose metadata differs share it.
+	ImageHash string `json:"image_hash,omitempty"`
+	// Codec is how the image is compressed: "jpeg", "hevc" (HEIC) or "av1"
+	// (AVIF). Encoding is the JPEG process named by the frame header:
+	// "baseline", "extended", "progressive", "lossless" or "hierarchical".
+	// BitDepth is the bits per sample, Components the color channels, 1
+	// for grayscale, and ChromaSubsampling a ratio such as "4:2:0".
+	Codec             string `json:"codec,omitempty"`
+	Encoding          string `json:"encoding,omitempty"`
+	BitDepth          int    `json:"bit_depth,omitempty"`
+	Components        int    `json:"components,omitempty"`
+	ChromaSubsampling string `json:"chroma_subsampling,omitempty"`
+	// JFIFVersion is the version of a JPEG's JFIF header, such as "1.01".
+	// JFIFXDensity and JFIFYDensity are its pixel density per
+	// JFIFDensityUnit, "inches" or "cm"; without a unit they give only the
+	// pixel aspect ratio.
+	JFIFVersion     string `json:"jfif_version,omitempty"`
+	JFIFDensityUnit string `json:"jfif_density_unit,omitempty"`
+	JFIFXDensity    int    `json:"jfif_x_density,omitempty"`
+	JFIFYDensity    int    `json:"jfif_y_density,omitempty"`
+
+	// Projection is the GPano projection of a panorama or photo sphere,
+	// such as "equirectangular". PanoFullWidth is the width in pixels of
+	// the full panorama the image was cropped from, PanoFOV the
+	// horizontal angle the image covers, 360 for a full sphere, and
+	// PoseHeading the compass heading of its center in degrees.
+	Projection    string   `json:"projection,omitempty"`
+	PanoFullWidth int      `json:"pano_full_width,omitempty"`
+	PanoFOV       float64  `json:"pano_fov,omitempty"`
+	PoseHeading   *float64 `json:"pose_heading,omitempty"`
+	// Composite is "composite", "in-camera" or "hdr" for images combined
+	// from several frames, as recorded by EXIF CompositeImage or XMP, and
+	// CompositeFrames the number of frames used.
+	Composite       string `json:"composite,omitempty"`
+	CompositeFrames int    `json:"composite_frames,omitempty"`
+
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+	// GPSTime is the UTC time of the position as the receiver recorded
+	// it, in RFC 3339; unlike the camera clock it is set by the satellites.
+	GPSTime string `json:"gps_time,omitempty"`
+	// RelativeAltitude is the height in meters above the take-off point
+	// recorded by DJI drones. FlightHeading is the drone's compass heading
+	// and GimbalYaw the camera's, GimbalPitch the camera's tilt, -90
+	// pointing straight down, all in degrees, and FlightSpeed the ground
+	// speed in m/s.
+	RelativeAltitude *float64 `json:"relative_altitude,omitempty"`
+	FlightHeading    *float64 `json:"flight_heading,omitempty"`
+	GimbalPitch      *float64 `json:"gimbal_pitch,omitempty"`
+	GimbalYaw        *float64 `json:"gimbal_yaw,omitempty"`
+	FlightSpeed      *float64 `json:"flight_speed,omitempty"`
+	// Position is the coordinates as text, e.g. a geohash, when output
+	// asks for a format other than decimal degrees. Decoding leaves it
+	// empty.
+	Position string `json:"position,omitempty"`
+	// SunElevation is the sun's height above the horizon in degrees at the
+	// place and time of capture, and LightPhase classifies it as "day",
+	// "golden-hour", "blue-hour" or "night". Both need coordinates and a
+	// capture time with a recorded UTC offset.
+	SunElevation *float64 `json:"sun_elevation,omitempty"`
+	LightPhase   string   `json:"light_phase,omitempty"`
+	// MoonPhase is the moon's age as a fraction of its cycle, 0 at new
+	// moon and 0.5 at full, and MoonIllumination the lit fraction of its
+	// disc; both need a capture time with a recorded UTC offset.
+	// MoonAltitude, in degrees above the horizon, also needs coordinates.
+	MoonPhase        *float64 `json:"moon_phase,omitempty"`
+	MoonIllumination *float64 `json:"moon_illumination,omitempty"`
+	MoonAltitude     *float64 `json:"moon_altitude,omitempty"`
+
+	// Stabilization is "on" or "off" when the maker note records the
+	// state of in-lens or in-body stabilization (IS, VR, OSS, IBIS).
+	Stabilization string `json:"stabilization,omitempty"`
+	// StabilizationMode is the vendor-specific mode, e.g. "Panning".
+	StabilizationMode string `json:"stabilization_mode,omitempty"`
+	// DriveMode is one of "single", "continuous", "self-timer" or
+	// "bracketing".
+	DriveMode string `json:"drive_mode,omitempty"`
+	// ShutterType is "mechanical", "electronic" (silent shutter) or
+	// "electronic-front-curtain".
+	ShutterType string `json:"shutter_type,omitempty"`
+	// FocusMode is "af-s" (single, Canon One Shot), "af-c" (continuous,
+	// AI Servo), "af-a" (the camera picks, AI Focus) or "manual".
+	FocusMode string `json:"focus_mode,omitempty"`
+
+	// LivePhotoID pairs an iPhone Live Photo still with its video, from
+	// the Apple maker note. Computational lists the phone processing the
+	// image went through: "hdr", "portrait", "night-mode", "live-photo" or
+	// "motion-photo".
+	LivePhotoID   string   `json:"live_photo_id,omitempty"`
+	Computational []string `json:"computational,omitempty"`
+
+	// Sources maps JSON field names to where their values were read from.
+	Sources map[string]Source `json:"sources,omitempty"`
+}
+
+// Normalized drive modes.
+const (
+	DriveSingle     = "single"
+	DriveContinuous = "continuous"
+	DriveSelfTimer  = "self-timer"
+	DriveBracketing = "bracketing"
+)
+
+// Normalized shutter types.
+const (
+	ShutterMechanical             = "mechanical"
+	ShutterElectronic             = "electronic"
+	ShutterElectronicFrontCurtain = "electronic-front-curtain"
+)
+
+// Normalized focus modes.
+const (
+	FocusSingle     = "af-s"
+	FocusContinuous = "af-c"
+	FocusAutomatic  = "af-a"
+	FocusManual     = "manual"
+)
+
+// captureLayout is the layout of Summary.DateTimeOriginal without offset.
+const captureLayout = "2006-01-02T15:04:05"
+
+// CaptureTime parses DateTimeOriginal. Times without a recorded offset are
+// returned in UTC.
+func (s *Summary) CaptureTime() (time.Time, bool) {
+	if s.DateTimeOriginal == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(captureLayout+"-07:00", s.DateTimeOriginal); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(captureLayout, s.DateTimeOriginal); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// Fields returns the summary keyed by JSON field name, exactly as shootlog
+// prints it, so rules and comparisons can address fields by those names.
+// Sources are left out.
+func (s *Summary) Fields() map[string]any {
+	c := *s
+	c.Sources = nil
+	var m map[string]any
+	if b, err := json.Marshal(&c); err == nil {
+		_ = json.Unmarshal(b, &m)
+	}
+	return m
+}
+
+// FieldNames lists the names Fields can return, in output order.
+func FieldNames() []string {
+	t := reflect.TypeOf(Summary{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "sources" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hyperfocal returns the hyperfocal distance in meters for a focal length
+// in mm, or 0 when the inputs are unknown.
+func hyperfocal(focal, fNumber float64, focal35 int) float64 {
+	if focal <= 0 || fNumber <= 0 || focal35 <= 0 {
+		return 0
+	}
+	coc := 0.03 * focal / float64(focal35)
+	return round((focal*focal/(fNumber*coc)+focal)/1000, 2)
+}
+
+// Decode reads an image from r and summarizes its EXIF metadata.
+func Decode(r io.Reader) (*Summary, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBytes(data)
+}
+
+// DecodeBytes summarizes the metadata of an in-memory image. JPEG files
+// without EXIF data are still summarized when they carry IPTC or XMP
+// metadata; otherwise ErrNoExif is returned.
+func DecodeBytes(data []byte) (*Summary, error) {
+	// The decoder is discarded, so its summary needs no copy.
+	return NewDecoder(data).shared()
+}
+
+// HasExif reports whether data holds an EXIF TIFF structure.
+func HasExif(data []byte) bool {
+	_, err := findTIFF(data)
+	return err == nil
+}
+
+// DecodeFile summarizes the EXIF metadata of the file at path.
+func DecodeFile(path string) (*Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := DecodeBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// Summarize condenses parsed EXIF entries into a Summary.
+func Summarize(x *Exif) *Summary {
+	s := summarizeTags(x)
+	summarizeMakerNote(x, s)
+	return s
+}
+
+// summarizeTags is Summarize without the maker note.
+func summarizeTags(x *Exif) *Summary {
+	s := &Summary{}
+	str := func(field string, ifd IFDKind, tag uint16) string {
+		e, ok := x.Lookup(ifd, tag)
+		if !ok || e.Text() == "" {
+			return ""
+		}
+		s.setSource(entrySource(e), field)
+		return e.Text()
+	}
+	xp := func(field string, tag uint16) string {
+		e, ok := x.Lookup(IFD0, tag)
+		if !ok {
+			return ""
+		}
+		v := decodeXP(e.Value)
+		if v != "" {
+			s.setSource(entrySource(e), field)
+		}
+		return v
+	}
+	num := func(field string, ifd IFDKind, tag uint16) (float64, bool) {
+		e, ok := x.Lookup(ifd, tag)
+		if !ok {
+			return 0, false
+		}
+		v, ok := e.Float(0)
+		if ok {
+			s.setSource(entrySource(e), field)
+		}
+		return v, ok
+	}
+
+	s.Make = str("make", IFD0, TagMake)
+	s.Model = str("model", IFD0, TagModel)
+	s.Software = str("software", IFD0, TagSoftware)
+	s.Artist = str("artist", IFD0, TagArtist)
+	s.Copyright = str("copyright", IFD0, TagCopyright)
+	s.LensMake = str("lens_make", ExifIFD, TagLensMake)
+	s.LensModel = str("lens_model", ExifIFD, TagLensModel)
+	s.BodySerial = str("body_serial", ExifIFD, TagBodySerialNumber)
+	s.LensSerial = str("lens_serial", ExifIFD, TagLensSerialNumber)
+	s.Description = str("description", IFD0, TagImageDescription)
+	if e, ok := x.Lookup(ExifIFD, TagUserComment); ok {
+		if s.Comment = DecodeUserComment(e.Value, x.Order); s.Comment != "" {
+			s.setSource(entrySource(e), "comment")
+		}
+	}
+	if s.Comment == "" {
+		s.Comment = xp("comment", TagXPComment)
+	}
+	s.Title = xp("title", TagXPTitle)
+	s.Author = xp("author", TagXPAuthor)
+	s.Subject = xp("subject", TagXPSubject)
+	s.Keywords = splitKeywords(xp("keywords", TagXPKeywords))
+	if v, ok := num("rating", IFD0, TagRating); ok && v >= 1 && v <= 5 {
+		s.Rating = int(v)
+	} else {
+		delete(s.Sources, "rating")
+	}
+	// DateTime (the file modification time) is only a fallback for files
+	// without DateTimeOriginal.
+	if e, ok := x.Lookup(ExifIFD, TagDateTimeOriginal); ok {
+		s.DateTimeOriginal = formatDateTime(e.String(), x.String(ExifIFD, TagOffsetTimeOriginal))
+		s.setSource(entrySource(e), "datetime_original")
+	} else if e, ok := x.Lookup(IFD0, TagDateTime); ok {
+		s.DateTimeOriginal = formatDateTime(e.String(), "")
+		s.setSource(entrySource(e), "datetime_original")
+	}
+	if s.DateTimeOriginal == "" {
+		delete(s.Sources, "datetime_original")
+	}
+	if v, ok := num("exposure_time", ExifIFD, TagExposureTime); ok {
+		s.ExposureTime = v
+	}
+	if v, ok := num("f_number", ExifIFD, TagFNumber); ok {
+		s.FNumber = round(v, 1)
+	}
+	if v, ok := num("iso", ExifIFD, TagISOSpeedRatings); ok {
+		s.ISO = int(v)
+	}
+	if v, ok := num("exposure_bias", ExifIFD, TagExposureBias); ok {
+		s.ExposureBias = round(v, 2)
+	}
+	if v, ok := num("focal_length", ExifIFD, TagFocalLength); ok {
+		s.FocalLength = round(v, 1)
+	}
+	if v, ok := num("focal_length_35mm", ExifIFD, TagFocalLength35mm); ok {
+		s.FocalLength35mm = int(v)
+	}
+	if e, ok := x.Lookup(ExifIFD, TagSubjectDistance); ok {
+		if n, _, ok := e.Rational(0); ok && n != 0xFFFFFFFF {
+			if v, _ := e.Float(0); v > 0 {
+				s.SubjectDistance = round(v, 2)
+				s.setSource(entrySource(e), "subject_distance")
+			}
+		}
+	}
+	s.HyperfocalDistance = hyperfocal(s.FocalLength, s.FNumber, s.FocalLength35mm)
+	if v, ok := num("width", ExifIFD, TagPixelXDimension); ok {
+		s.Width = int(v)
+	}
+	if v, ok := num("height", ExifIFD, TagPixelYDimension); ok {
+		s.Height = int(v)
+	}
+	if v, ok := num("orientation", IFD0, TagOrientation); ok {
+		s.Orientation = int(v)
+	}
+	if v, ok := num("x_resolution", IFD0, TagXResolution); ok && v > 0 {
+		s.XResolution = round(v, 2)
+	} else {
+		delete(s.Sources, "x_resolution")
+	}
+	if v, ok := num("y_resolution", IFD0, TagYResolution); ok && v > 0 {
+		s.YResolution = round(v, 2)
+	} else {
+		delete(s.Sources, "y_resolution")
+	}
+	if v, ok := x.Uint(IFD0, TagResolutionUnit); ok && (v == 2 || v == 3) {
+		s.ResolutionUnit = resolutionUnits[v]
+		e, _ := x.Lookup(IFD0, TagResolutionUnit)
+		s.setSource(entrySource(e), "resolution_unit")
+	}
+	s.PrintWidth, s.PrintHeight = printSize(s.Width), printSize(s.Height)
+	summarizeAspect(x, s)
+	if v, ok := x.Uint(ExifIFD, TagColorSpace); ok {
+		switch {
+		case v == 1:
+			s.ColorSpace = "sRGB"
+		case x.String(InteropIFD, TagInteroperabilityIndex) == "R03":
+			s.ColorSpace = "Adobe RGB"
+		case v == 0xFFFF:
+			s.ColorSpace = "uncalibrated"
+		}
+		if s.ColorSpace != "" {
+			e, _ := x.Lookup(ExifIFD, TagColorSpace)
+			s.setSource(entrySource(e), "color_space")
+		}
+	}
+	summarizeSettings(x, s)
+	summarizeComposite(x, s)
+	summarizeGPS(x, s)
+	summarizeSky(s)
+	return s
+}
+
+// summarizeMakerNote fills in the fields of a supported maker note.
+func summarizeMakerNote(x *Exif, s *Summary) {
+	if mn, err := x.MakerNote(s.Make); err == nil {
+		mn.apply(s)
+	}
+}
+
+// summarizeJPEG fills in metadata stored outside EXIF: IPTC-IIM, XMP, the
+// ICC profile and C2PA manifests. Values already set from EXIF are kept.
+// xmp holds the XMPProperties of the file. It reports whether the file
+// had IPTC, XMP or C2PA metadata.
+func summarizeJPEG(data []byte, xmp map[xml.Name][]string, s *Summary) bool {
+	iptc := ReadIPTC(data)
+	fill := func(field string, dst *string, dataset int, ns, name string) {
+		if *dst != "" {
+			return
+		}
+		if v := iptc.Get(dataset); dataset != 0 && v != "" {
+			*dst = v
+			s.setSource(iptcSource(dataset), field)
+		} else if v := xmp[xml.Name{Space: ns, Local: name}]; len(v) > 0 {
+			*dst = v[0]
+			s.setSource(xmpSource(ns, name), field)
+		}
+	}
+	fill("artist", &s.Artist, IPTCByline, NamespaceDC, "creator")
+	fill("copyright", &s.Copyright, IPTCCopyrightNotice, NamespaceDC, "rights")
+	fill("description", &s.Description, IPTCCaption, NamespaceDC, "description")
+	fill("title", &s.Title, IPTCObjectName, NamespaceDC, "title")
+	fill("credit", &s.Credit, IPTCCredit, NamespacePhotoshop, "Credit")
+	fill("contact", &s.Contact, IPTCContact, "", "")
+	fill("job_id", &s.JobID, IPTCJobID, NamespacePhotoshop, "TransmissionReference")
+	fill("contact_email", &s.ContactEmail, 0, NamespaceIPTCCore, "CiEmailWork")
+	fill("contact_url", &s.ContactURL, 0, NamespaceIPTCCore, "CiUrlWork")
+	fill("contact_phone", &s.ContactPhone, 0, NamespaceIPTCCore, "CiTelWork")
+	if len(s.Keywords) == 0 {
+		if v := iptc[IPTCKeywords]; len(v) > 0 {
+			s.Keywords = v
+			s.setSource(iptcSource(IPTCKeywords), "keywords")
+		} else if v := xmp[xml.Name{Space: NamespaceDC, Local: "subject"}]; len(v) > 0 {
+			s.Keywords = v
+			s.setSource(xmpSource(NamespaceDC, "subject"), "keywords")
+		}
+	}
+	if s.Rating == 0 {
+		if v := xmp[xml.Name{Space: NamespaceXMP, Local: "Rating"}]; len(v) > 0 {
+			if n, err := strconv.Atoi(v[0]); err == nil && n >= 1 && n <= 5 {
+				s.Rating = n
+				s.setSource(xmpSource(NamespaceXMP, "Rating"), "rating")
+			}
+		}
+	}
+	summarizePano(xmp, s)
+	summarizeDJI(xmp, s)
+	summarizePhone(data, xmp, s)
+	summarizeInferred(xmp, s)
+	if profile := ICCProfile(data); profile != nil {
+		s.ICCProfile = ICCDescription(profile)
+		if s.ICCProfile != "" {
+			s.setSource(Source{Location: LocationICC, Tag: "desc"}, "icc_profile")
+		}
+	}
+	c2pa := summarizeC2PA(data, s)
+	return len(iptc) > 0 || len(xmp) > 0 || c2pa
+}
+
+func summarizeGPS(x *Exif, s *Summary) {
+	if lat, ok := gpsCoordinate(x, TagGPSLatitude, TagGPSLatitudeRef, "S"); ok {
+		if lon, ok := gpsCoordinate(x, TagGPSLongitude, TagGPSLongitudeRef, "W"); ok {
+			s.Latitude, s.Longitude = &lat, &lon
+			e, _ := x.Lookup(GPSIFD, TagGPSLatitude)
+			s.setSource(entrySource(e), "latitude")
+			e, _ = x.Lookup(GPSIFD, TagGPSLongitude)
+			s.setSource(entrySource(e), "longitude")
+		}
+	}
+	if e, ok := x.Lookup(GPSIFD, TagGPSAltitude); ok {
+		if alt, ok := e.Float(0); ok {
+			if ref, ok := x.Uint(GPSIFD, TagGPSAltitudeRef); ok && ref == 1 {
+				alt = -alt
+			}
+			alt = round(alt, 1)
+			s.Altitude = &alt
+			s.setSource(entrySource(e), "altitude")
+		}
+	}
+	if t, ok := gpsTime(x); ok {
+		s.GPSTime = t.Format(time.RFC3339)
+		e, _ := x.Lookup(GPSIFD, TagGPSTimeStamp)
+		s.setSource(entrySource(e), "gps_time")
+	}
+}
+
+// gpsTime combines GPSDateStamp and GPSTimeStamp into a UTC time, dropping
+// fractions of a second.
+func gpsTime(x *Exif) (time.Time, bool) {
+	day, err := time.Parse("2006:01:02", strings.TrimSpace(x.String(GPSIFD, TagGPSDateStamp)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	e, ok := x.Lookup(GPSIFD, TagGPSTimeStamp)
+	if !ok || e.Len() < 3 {
+		return time.Time{}, false
+	}
+	h, ok1 := e.Float(0)
+	m, ok2 := e.Float(1)
+	sec, ok3 := e.Float(2)
+	if !ok1 || !ok2 || !ok3 || h < 0 || h >= 24 || m < 0 || m >= 60 || sec < 0 || sec >= 61 {
+		return time.Time{}, false
+	}
+	return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second), true
+}
+
+// summarizeSky derives the positions of the sun and moon from the capture
+// time and place. Times without an offset are skipped: their UTC instant,
+// and so the sky, is unknown.
+func summarizeSky(s *Summary) {
+	if len(s.DateTimeOriginal) <= len(captureLayout) {
+		return
+	}
+	t, ok := s.CaptureTime()
+	if !ok {
+		return
+	}
+	phase, lit := astro.MoonPhase(t)
+	phase, lit = round(phase, 3), round(lit, 3)
+	s.MoonPhase, s.MoonIllumination = &phase, &lit
+	if s.Latitude == nil || s.Longitude == nil {
+		return
+	}
+	sun, _ := astro.Sun(t, *s.Latitude, *s.Longitude)
+	sun = round(sun, 1)
+	s.SunElevation = &sun
+	s.LightPhase = astro.LightPhase(sun)
+	moon, _ := astro.Moon(t, *s.Latitude, *s.Longitude)
+	moon = round(moon, 1)
+	s.MoonAltitude = &moon
+}
+
+// splitKeywords splits a semicolon-separated keyword list.
+func splitKeywords(v string) []string {
+	var out []string
+	for _, k := range strings.Split(v, ";") {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// gpsCoordinate converts a degrees/minutes/seconds triple to signed decimal
+// degrees.
+func gpsCoordinate(x *Exif, tag, refTag uint16, negative string) (float64, bool) {
+	e, ok := x.Lookup(GPSIFD, tag)
+	if !ok || e.Len() < 3 {
+		return 0, false
+	}
+	deg, ok1 := e.Float(0)
+	min, ok2 := e.Float(1)
+	sec, ok3 := e.Float(2)
+	if !ok1 || !ok2 || !ok3 {
+		return 0, false
+	}
+	v := deg + min/60 + sec/3600
+	if strings.EqualFold(x.String(GPSIFD, refTag), negative) {
+		v = -v
+	}
+	return round(v, 6), true
+}
+
+// formatDateTime converts an EXIF "2006:01:02 15:04:05" timestamp to the
+// Summary layout, appending offset when it is a valid "+09:00" style value.
+func formatDateTime(v, offset string) string {
+	t, err := time.Parse("2006:01:02 15:04:05", v)
+	if err != nil {
+		return ""
+	}
+	out := t.Format(captureLayout)
+	if _, err := time.Parse("-07:00", offset); err == nil {
+		out += offset
+	}
+	return out
+}
+
+// FormatExposure renders an exposure time in seconds the way cameras
+// display it, e.g. "1/250" or "2.5".
+func FormatExposure(sec float64) string {
+	if sec <= 0 {
+		return ""
+	}
+	if sec < 0.25 {
+		return fmt.Sprintf("1/%d", int(math.Round(1/sec)))
+	}
+	return fmt.Sprintf("%g", round(sec, 1))
+}
+
+// PrintDPI is the resolution photo labs print at, which PrintWidth and
+// PrintHeight assume.
+const PrintDPI = 300
+
+// resolutionUnits names the ResolutionUnit values.
+var resolutionUnits = map[uint32]string{2: "inches", 3: "cm"}
+
+// printSize returns the inches pixels span at PrintDPI.
+func printSize(pixels int) float64 {
+	return round(float64(pixels)/PrintDPI, 2)
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
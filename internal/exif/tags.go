@@ -0,0 +1,450 @@
+// Code generated by gentags from tags.tsv; DO NOT EDIT.
+
+package exif
+
+// IDs of the tags in tags.tsv.
+const (
+	TagImageWidth                          uint16 = 0x0100
+	TagImageLength                         uint16 = 0x0101
+	TagBitsPerSample                       uint16 = 0x0102
+	TagCompression                         uint16 = 0x0103
+	TagPhotometricInterpretation           uint16 = 0x0106
+	TagImageDescription                    uint16 = 0x010E
+	TagMake                                uint16 = 0x010F
+	TagModel                               uint16 = 0x0110
+	TagStripOffsets                        uint16 = 0x0111
+	TagOrientation                         uint16 = 0x0112
+	TagSamplesPerPixel                     uint16 = 0x0115
+	TagRowsPerStrip                        uint16 = 0x0116
+	TagStripByteCounts                     uint16 = 0x0117
+	TagXResolution                         uint16 = 0x011A
+	TagYResolution                         uint16 = 0x011B
+	TagPlanarConfiguration                 uint16 = 0x011C
+	TagResolutionUnit                      uint16 = 0x0128
+	TagTransferFunction                    uint16 = 0x012D
+	TagSoftware                            uint16 = 0x0131
+	TagDateTime                            uint16 = 0x0132
+	TagArtist                              uint16 = 0x013B
+	TagWhitePoint                          uint16 = 0x013E
+	TagPrimaryChromaticities               uint16 = 0x013F
+	TagJPEGInterchangeFormat               uint16 = 0x0201
+	TagJPEGInterchangeFormatLength         uint16 = 0x0202
+	TagYCbCrCoefficients                   uint16 = 0x0211
+	TagYCbCrSubSampling                    uint16 = 0x0212
+	TagYCbCrPositioning                    uint16 = 0x0213
+	TagReferenceBlackWhite                 uint16 = 0x0214
+	TagRating                              uint16 = 0x4746
+	TagRatingPercent                       uint16 = 0x4749
+	TagCopyright                           uint16 = 0x8298
+	TagExifIFDPointer                      uint16 = 0x8769
+	TagGPSIFDPointer                       uint16 = 0x8825
+	TagXPTitle                             uint16 = 0x9C9B
+	TagXPComment                           uint16 = 0x9C9C
+	TagXPAuthor                            uint16 = 0x9C9D
+	TagXPKeywords                          uint16 = 0x9C9E
+	TagXPSubject                           uint16 = 0x9C9F
+	TagDefaultCropSize                     uint16 = 0xC620
+	TagExposureTime                        uint16 = 0x829A
+	TagFNumber                             uint16 = 0x829D
+	TagExposureProgram                     uint16 = 0x8822
+	TagSpectralSensitivity                 uint16 = 0x8824
+	TagISOSpeedRatings                     uint16 = 0x8827
+	TagSensitivityType                     uint16 = 0x8830
+	TagStandardOutputSensitivity           uint16 = 0x8831
+	TagRecommendedExposureIndex            uint16 = 0x8832
+	TagISOSpeed                            uint16 = 0x8833
+	TagISOSpeedLatitudeyyy                 uint16 = 0x8834
+	TagISOSpeedLatitudezzz                 uint16 = 0x8835
+	TagExifVersion                         uint16 = 0x9000
+	TagDateTimeOriginal                    uint16 = 0x9003
+	TagDateTimeDigitized                   uint16 = 0x9004
+	TagOffsetTime                          uint16 = 0x9010
+	TagOffsetTimeOriginal                  uint16 = 0x9011
+	TagOffsetTimeDigitized                 uint16 = 0x9012
+	TagComponentsConfiguration             uint16 = 0x9101
+	TagCompressedBitsPerPixel              uint16 = 0x9102
+	TagShutterSpeedValue                   uint16 = 0x9201
+	TagApertureValue                       uint16 = 0x9202
+	TagBrightnessValue                     uint16 = 0x9203
+	TagExposureBias                        uint16 = 0x9204
+	TagMaxApertureValue                    uint16 = 0x9205
+	TagSubjectDistance                     uint16 = 0x9206
+	TagMeteringMode                        uint16 = 0x9207
+	TagLightSource                         uint16 = 0x9208
+	TagFlash                               uint16 = 0x9209
+	TagFocalLength                         uint16 = 0x920A
+	TagSubjectArea                         uint16 = 0x9214
+	TagMakerNote                           uint16 = 0x927C
+	TagUserComment                         uint16 = 0x9286
+	TagSubSecTime                          uint16 = 0x9290
+	TagSubSecTimeOriginal                  uint16 = 0x9291
+	TagSubSecTimeDigitized                 uint16 = 0x9292
+	TagTemperature                         uint16 = 0x9400
+	TagHumidity                            uint16 = 0x9401
+	TagPressure                            uint16 = 0x9402
+	TagWaterDepth                          uint16 = 0x9403
+	TagAcceleration                        uint16 = 0x9404
+	TagCameraElevationAngle                uint16 = 0x9405
+	TagFlashpixVersion                     uint16 = 0xA000
+	TagColorSpace                          uint16 = 0xA001
+	TagPixelXDimension                     uint16 = 0xA002
+	TagPixelYDimension                     uint16 = 0xA003
+	TagRelatedSoundFile                    uint16 = 0xA004
+	TagInteropIFDPointer                   uint16 = 0xA005
+	TagFlashEnergy                         uint16 = 0xA20B
+	TagSpatialFrequencyResponse            uint16 = 0xA20C
+	TagFocalPlaneXResolution               uint16 = 0xA20E
+	TagFocalPlaneYResolution               uint16 = 0xA20F
+	TagFocalPlaneResolutionUnit            uint16 = 0xA210
+	TagSubjectLocation                     uint16 = 0xA214
+	TagExposureIndex                       uint16 = 0xA215
+	TagSensingMethod                       uint16 = 0xA217
+	TagFileSource                          uint16 = 0xA300
+	TagSceneType                           uint16 = 0xA301
+	TagCFAPattern                          uint16 = 0xA302
+	TagCustomRendered                      uint16 = 0xA401
+	TagExposureMode                        uint16 = 0xA402
+	TagWhiteBalance                        uint16 = 0xA403
+	TagDigitalZoomRatio                    uint16 = 0xA404
+	TagFocalLength35mm                     uint16 = 0xA405
+	TagSceneCaptureType                    uint16 = 0xA406
+	TagGainControl                         uint16 = 0xA407
+	TagContrast                            uint16 = 0xA408
+	TagSaturation                          uint16 = 0xA409
+	TagSharpness                           uint16 = 0xA40A
+	TagDeviceSettingDescription            uint16 = 0xA40B
+	TagSubjectDistanceRange                uint16 = 0xA40C
+	TagImageUniqueID                       uint16 = 0xA420
+	TagCameraOwnerName                     uint16 = 0xA430
+	TagBodySerialNumber                    uint16 = 0xA431
+	TagLensSpecification                   uint16 = 0xA432
+	TagLensMake                            uint16 = 0xA433
+	TagLensModel                           uint16 = 0xA434
+	TagLensSerialNumber                    uint16 = 0xA435
+	TagCompositeImage                      uint16 = 0xA460
+	TagSourceImageNumberOfCompositeImage   uint16 = 0xA461
+	TagSourceExposureTimesOfCompositeImage uint16 = 0xA462
+	TagGamma                               uint16 = 0xA500
+	TagGPSVersionID                        uint16 = 0x0000
+	TagGPSLatitudeRef                      uint16 = 0x0001
+	TagGPSLatitude                         uint16 = 0x0002
+	TagGPSLongitudeRef                     uint16 = 0x0003
+	TagGPSLongitude                        uint16 = 0x0004
+	TagGPSAltitudeRef                      uint16 = 0x0005
+	TagGPSAltitude                         uint16 = 0x0006
+	TagGPSTimeStamp                        uint16 = 0x0007
+	TagGPSImgDirectionRef                  uint16 = 0x0010
+	TagGPSImgDirection                     uint16 = 0x0011
+	TagGPSMapDatum                         uint16 = 0x0012
+	TagGPSProcessingMethod                 uint16 = 0x001B
+	TagGPSDateStamp                        uint16 = 0x001D
+	TagInteroperabilityIndex               uint16 = 0x0001
+	TagInteroperabilityVersion             uint16 = 0x0002
+)
+
+// tagTable holds the rows of tags.tsv in directory and ID order.
+var tagTable = []TagInfo{
+	{IFD: IFD0, ID: TagImageWidth, Name: "ImageWidth", Types: []Type{TypeShort, TypeLong}, Count: 1,
+		Description: "Width of the image in pixels"},
+	{IFD: IFD0, ID: TagImageLength, Name: "ImageLength", Types: []Type{TypeShort, TypeLong}, Count: 1,
+		Description: "Height of the image in pixels"},
+	{IFD: IFD0, ID: TagBitsPerSample, Name: "BitsPerSample", Types: []Type{TypeShort}, Count: 3,
+		Description: "Bits per component"},
+	{IFD: IFD0, ID: TagCompression, Name: "Compression", Types: []Type{TypeShort}, Count: 1,
+		Description: "Compression scheme",
+		Values:      []TagValue{{"1", "Uncompressed"}, {"6", "JPEG"}}, Required: []IFDKind{IFD1}},
+	{IFD: IFD0, ID: TagPhotometricInterpretation, Name: "PhotometricInterpretation", Types: []Type{TypeShort}, Count: 1,
+		Description: "Pixel composition, such as RGB or YCbCr",
+		Values:      []TagValue{{"2", "RGB"}, {"6", "YCbCr"}}},
+	{IFD: IFD0, ID: TagImageDescription, Name: "ImageDescription", Types: []Type{TypeASCII},
+		Description: "Caption of the image", Fields: []string{"description"}},
+	{IFD: IFD0, ID: TagMake, Name: "Make", Types: []Type{TypeASCII},
+		Description: "Manufacturer of the camera", Fields: []string{"make"}, Recommended: []IFDKind{IFD0}},
+	{IFD: IFD0, ID: TagModel, Name: "Model", Types: []Type{TypeASCII},
+		Description: "Model of the camera", Fields: []string{"model"}, Recommended: []IFDKind{IFD0}},
+	{IFD: IFD0, ID: TagStripOffsets, Name: "StripOffsets", Types: []Type{TypeShort, TypeLong},
+		Description: "Offsets of the image strips"},
+	{IFD: IFD0, ID: TagOrientation, Name: "Orientation", Types: []Type{TypeShort}, Count: 1,
+		Description: "How the image is rotated or mirrored, 1-8", Fields: []string{"orientation"},
+		Values: []TagValue{{"1", "Horizontal"}, {"2", "Mirror horizontal"}, {"3", "Rotate 180"}, {"4", "Mirror vertical"}, {"5", "Mirror horizontal and rotate 270 CW"}, {"6", "Rotate 90 CW"}, {"7", "Mirror horizontal and rotate 90 CW"}, {"8", "Rotate 270 CW"}}, Closed: true},
+	{IFD: IFD0, ID: TagSamplesPerPixel, Name: "SamplesPerPixel", Types: []Type{TypeShort}, Count: 1,
+		Description: "Components per pixel"},
+	{IFD: IFD0, ID: TagRowsPerStrip, Name: "RowsPerStrip", Types: []Type{TypeShort, TypeLong}, Count: 1,
+		Description: "Rows per image strip"},
+	{IFD: IFD0, ID: TagStripByteCounts, Name: "StripByteCounts", Types: []Type{TypeShort, TypeLong},
+		Description: "Bytes in each image strip"},
+	{IFD: IFD0, ID: TagXResolution, Name: "XResolution", Types: []Type{TypeRational}, Count: 1,
+		Description: "Horizontal resolution in pixels per ResolutionUnit", Fields: []string{"x_resolution"}, Required: []IFDKind{IFD0, IFD1}, format: "decimal"},
+	{IFD: IFD0, ID: TagYResolution, Name: "YResolution", Types: []Type{TypeRational}, Count: 1,
+		Description: "Vertical resolution in pixels per ResolutionUnit", Fields: []string{"y_resolution"}, Required: []IFDKind{IFD0, IFD1}, format: "decimal"},
+	{IFD: IFD0, ID: TagPlanarConfiguration, Name: "PlanarConfiguration", Types: []Type{TypeShort}, Count: 1,
+		Description: "Whether components are stored chunky or planar",
+		Values:      []TagValue{{"1", "Chunky"}, {"2", "Planar"}}, Closed: true},
+	{IFD: IFD0, ID: TagResolutionUnit, Name: "ResolutionUnit", Types: []Type{TypeShort}, Count: 1,
+		Description: "Unit of XResolution and YResolution", Fields: []string{"resolution_unit"},
+		Values: []TagValue{{"2", "inches"}, {"3", "cm"}}, Closed: true, Required: []IFDKind{IFD0, IFD1}},
+	{IFD: IFD0, ID: TagTransferFunction, Name: "TransferFunction", Types: []Type{TypeShort}, Count: 768,
+		Description: "Transfer function of the image"},
+	{IFD: IFD0, ID: TagSoftware, Name: "Software", Types: []Type{TypeASCII},
+		Description: "Software that wrote the file", Fields: []string{"software"}},
+	{IFD: IFD0, ID: TagDateTime, Name: "DateTime", Types: []Type{TypeASCII}, Count: 20,
+		Description: "Time the file was last changed; the capture time when DateTimeOriginal is missing", Fields: []string{"datetime_original"}, Recommended: []IFDKind{IFD0}},
+	{IFD: IFD0, ID: TagArtist, Name: "Artist", Types: []Type{TypeASCII},
+		Description: "Photographer or creator", Fields: []string{"artist"}},
+	{IFD: IFD0, ID: TagWhitePoint, Name: "WhitePoint", Types: []Type{TypeRational}, Count: 2,
+		Description: "Chromaticity of the white point"},
+	{IFD: IFD0, ID: TagPrimaryChromaticities, Name: "PrimaryChromaticities", Types: []Type{TypeRational}, Count: 6,
+		Description: "Chromaticities of the primaries"},
+	{IFD: IFD0, ID: TagJPEGInterchangeFormat, Name: "JPEGInterchangeFormat", Types: []Type{TypeLong}, Count: 1,
+		Description: "Offset of the JPEG thumbnail", Required: []IFDKind{IFD1}},
+	{IFD: IFD0, ID: TagJPEGInterchangeFormatLength, Name: "JPEGInterchangeFormatLength", Types: []Type{TypeLong}, Count: 1,
+		Description: "Length of the JPEG thumbnail in bytes", Required: []IFDKind{IFD1}},
+	{IFD: IFD0, ID: TagYCbCrCoefficients, Name: "YCbCrCoefficients", Types: []Type{TypeRational}, Count: 3,
+		Description: "Coefficients of the RGB to YCbCr transform"},
+	{IFD: IFD0, ID: TagYCbCrSubSampling, Name: "YCbCrSubSampling", Types: []Type{TypeShort}, Count: 2,
+		Description: "Chroma subsampling of YCbCr data"},
+	{IFD: IFD0, ID: TagYCbCrPositioning, Name: "YCbCrPositioning", Types: []Type{TypeShort}, Count: 1,
+		Description: "Position of chroma samples",
+		Values:      []TagValue{{"1", "Centered"}, {"2", "Co-sited"}}, Closed: true, Required: []IFDKind{IFD0}},
+	{IFD: IFD0, ID: TagReferenceBlackWhite, Name: "ReferenceBlackWhite", Types: []Type{TypeRational}, Count: 6,
+		Description: "Reference black and white values"},
+	{IFD: IFD0, ID: TagRating, Name: "Rating", Types: []Type{TypeShort}, Count: 1,
+		Description: "Star rating 0-5 written by cameras and Windows", Fields: []string{"rating"}},
+	{IFD: IFD0, ID: TagRatingPercent, Name: "RatingPercent", Types: []Type{TypeShort}, Count: 1,
+		Description: "Rating as a percentage, kept alongside Rating by Windows"},
+	{IFD: IFD0, ID: TagCopyright, Name: "Copyright", Types: []Type{TypeASCII},
+		Description: "Copyright notice", Fields: []string{"copyright"}},
+	{IFD: IFD0, ID: TagExifIFDPointer, Name: "ExifIFDPointer", Types: []Type{TypeLong}, Count: 1,
+		Description: "Offset of the Exif IFD", Required: []IFDKind{IFD0}, sub: ExifIFD},
+	{IFD: IFD0, ID: TagGPSIFDPointer, Name: "GPSInfoIFDPointer", Types: []Type{TypeLong}, Count: 1,
+		Description: "Offset of the GPS IFD", sub: GPSIFD},
+	{IFD: IFD0, ID: TagXPTitle, Name: "XPTitle", Types: []Type{TypeByte},
+		Description: "Windows title in UTF-16", Fields: []string{"title"}, format: "xp"},
+	{IFD: IFD0, ID: TagXPComment, Name: "XPComment", Types: []Type{TypeByte},
+		Description: "Windows comment in UTF-16; used when UserComment is empty", Fields: []string{"comment"}, format: "xp"},
+	{IFD: IFD0, ID: TagXPAuthor, Name: "XPAuthor", Types: []Type{TypeByte},
+		Description: "Windows author in UTF-16", Fields: []string{"author"}, format: "xp"},
+	{IFD: IFD0, ID: TagXPKeywords, Name: "XPKeywords", Types: []Type{TypeByte},
+		Description: "Windows keywords in UTF-16, separated by semicolons", Fields: []string{"keywords"}, format: "xp"},
+	{IFD: IFD0, ID: TagXPSubject, Name: "XPSubject", Types: []Type{TypeByte},
+		Description: "Windows subject in UTF-16", Fields: []string{"subject"}, format: "xp"},
+	{IFD: IFD0, ID: TagDefaultCropSize, Name: "DefaultCropSize", Types: []Type{TypeShort, TypeLong, TypeRational}, Count: 2,
+		Description: "DNG size of the image area to render, in raw pixels", Fields: []string{"crop_mode"}},
+	{IFD: ExifIFD, ID: TagExposureTime, Name: "ExposureTime", Types: []Type{TypeRational}, Count: 1,
+		Description: "Exposure time in seconds", Fields: []string{"exposure_time"}, format: "exposure"},
+	{IFD: ExifIFD, ID: TagFNumber, Name: "FNumber", Types: []Type{TypeRational}, Count: 1,
+		Description: "F-number of the aperture", Fields: []string{"f_number"}, format: "fnumber"},
+	{IFD: ExifIFD, ID: TagExposureProgram, Name: "ExposureProgram", Types: []Type{TypeShort}, Count: 1,
+		Description: "Exposure program, such as manual or aperture priority",
+		Values:      []TagValue{{"0", "Not defined"}, {"1", "Manual"}, {"2", "Program AE"}, {"3", "Aperture priority"}, {"4", "Shutter priority"}, {"5", "Creative"}, {"6", "Action"}, {"7", "Portrait"}, {"8", "Landscape"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSpectralSensitivity, Name: "SpectralSensitivity", Types: []Type{TypeASCII},
+		Description: "Spectral sensitivity of each channel"},
+	{IFD: ExifIFD, ID: TagISOSpeedRatings, Name: "PhotographicSensitivity", Types: []Type{TypeShort},
+		Description: "ISO speed", Fields: []string{"iso"}},
+	{IFD: ExifIFD, ID: TagSensitivityType, Name: "SensitivityType", Types: []Type{TypeShort}, Count: 1,
+		Description: "Which sensitivity PhotographicSensitivity records",
+		Values:      []TagValue{{"0", "Unknown"}, {"1", "Standard output sensitivity"}, {"2", "Recommended exposure index"}, {"3", "ISO speed"}, {"4", "Standard output sensitivity and recommended exposure index"}, {"5", "Standard output sensitivity and ISO speed"}, {"6", "Recommended exposure index and ISO speed"}, {"7", "Standard output sensitivity, recommended exposure index and ISO speed"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagStandardOutputSensitivity, Name: "StandardOutputSensitivity", Types: []Type{TypeLong}, Count: 1,
+		Description: "Standard output sensitivity (ISO 12232)"},
+	{IFD: ExifIFD, ID: TagRecommendedExposureIndex, Name: "RecommendedExposureIndex", Types: []Type{TypeLong}, Count: 1,
+		Description: "Recommended exposure index (ISO 12232)"},
+	{IFD: ExifIFD, ID: TagISOSpeed, Name: "ISOSpeed", Types: []Type{TypeLong}, Count: 1,
+		Description: "ISO speed (ISO 12232)"},
+	{IFD: ExifIFD, ID: TagISOSpeedLatitudeyyy, Name: "ISOSpeedLatitudeyyy", Types: []Type{TypeLong}, Count: 1,
+		Description: "ISO speed latitude yyy"},
+	{IFD: ExifIFD, ID: TagISOSpeedLatitudezzz, Name: "ISOSpeedLatitudezzz", Types: []Type{TypeLong}, Count: 1,
+		Description: "ISO speed latitude zzz"},
+	{IFD: ExifIFD, ID: TagExifVersion, Name: "ExifVersion", Types: []Type{TypeUndefined}, Count: 4,
+		Description: "EXIF version, such as 0232", Required: []IFDKind{ExifIFD}, format: "version"},
+	{IFD: ExifIFD, ID: TagDateTimeOriginal, Name: "DateTimeOriginal", Types: []Type{TypeASCII}, Count: 20,
+		Description: "Time the photo was taken", Fields: []string{"datetime_original"}, Recommended: []IFDKind{ExifIFD}},
+	{IFD: ExifIFD, ID: TagDateTimeDigitized, Name: "DateTimeDigitized", Types: []Type{TypeASCII}, Count: 20,
+		Description: "Time the photo was digitized", Recommended: []IFDKind{ExifIFD}},
+	{IFD: ExifIFD, ID: TagOffsetTime, Name: "OffsetTime", Types: []Type{TypeASCII}, Count: 7,
+		Description: "UTC offset of DateTime"},
+	{IFD: ExifIFD, ID: TagOffsetTimeOriginal, Name: "OffsetTimeOriginal", Types: []Type{TypeASCII}, Count: 7,
+		Description: "UTC offset of DateTimeOriginal", Fields: []string{"datetime_original"}},
+	{IFD: ExifIFD, ID: TagOffsetTimeDigitized, Name: "OffsetTimeDigitized", Types: []Type{TypeASCII}, Count: 7,
+		Description: "UTC offset of DateTimeDigitized"},
+	{IFD: ExifIFD, ID: TagComponentsConfiguration, Name: "ComponentsConfiguration", Types: []Type{TypeUndefined}, Count: 4,
+		Description: "Order of the components in compressed data", Required: []IFDKind{ExifIFD}, format: "components"},
+	{IFD: ExifIFD, ID: TagCompressedBitsPerPixel, Name: "CompressedBitsPerPixel", Types: []Type{TypeRational}, Count: 1,
+		Description: "Compression ratio in bits per pixel"},
+	{IFD: ExifIFD, ID: TagShutterSpeedValue, Name: "ShutterSpeedValue", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Shutter speed in APEX units", format: "decimal"},
+	{IFD: ExifIFD, ID: TagApertureValue, Name: "ApertureValue", Types: []Type{TypeRational}, Count: 1,
+		Description: "Aperture in APEX units", format: "decimal"},
+	{IFD: ExifIFD, ID: TagBrightnessValue, Name: "BrightnessValue", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Brightness in APEX units", format: "decimal"},
+	{IFD: ExifIFD, ID: TagExposureBias, Name: "ExposureBiasValue", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Exposure compensation in EV", Fields: []string{"exposure_bias"}, format: "ev"},
+	{IFD: ExifIFD, ID: TagMaxApertureValue, Name: "MaxApertureValue", Types: []Type{TypeRational}, Count: 1,
+		Description: "Smallest F-number of the lens in APEX units", format: "decimal"},
+	{IFD: ExifIFD, ID: TagSubjectDistance, Name: "SubjectDistance", Types: []Type{TypeRational}, Count: 1,
+		Description: "Distance to the subject in meters", Fields: []string{"subject_distance"}, format: "m"},
+	{IFD: ExifIFD, ID: TagMeteringMode, Name: "MeteringMode", Types: []Type{TypeShort}, Count: 1,
+		Description: "Metering mode, such as spot or pattern",
+		Values:      []TagValue{{"0", "Unknown"}, {"1", "Average"}, {"2", "Center-weighted average"}, {"3", "Spot"}, {"4", "Multi-spot"}, {"5", "Multi-segment"}, {"6", "Partial"}, {"255", "Other"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagLightSource, Name: "LightSource", Types: []Type{TypeShort}, Count: 1,
+		Description: "Kind of light source",
+		Values:      []TagValue{{"0", "Unknown"}, {"1", "Daylight"}, {"2", "Fluorescent"}, {"3", "Tungsten"}, {"4", "Flash"}, {"9", "Fine weather"}, {"10", "Cloudy"}, {"11", "Shade"}, {"12", "Daylight fluorescent"}, {"13", "Day white fluorescent"}, {"14", "Cool white fluorescent"}, {"15", "White fluorescent"}, {"16", "Warm white fluorescent"}, {"17", "Standard light A"}, {"18", "Standard light B"}, {"19", "Standard light C"}, {"20", "D55"}, {"21", "D65"}, {"22", "D75"}, {"23", "D50"}, {"24", "ISO studio tungsten"}, {"255", "Other"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagFlash, Name: "Flash", Types: []Type{TypeShort}, Count: 1,
+		Description: "Whether and how the flash fired",
+		Values:      []TagValue{{"0", "No flash"}, {"1", "Fired"}, {"5", "Fired, return not detected"}, {"7", "Fired, return detected"}, {"8", "On, did not fire"}, {"9", "On, fired"}, {"13", "On, return not detected"}, {"15", "On, return detected"}, {"16", "Off, did not fire"}, {"24", "Auto, did not fire"}, {"25", "Auto, fired"}, {"29", "Auto, fired, return not detected"}, {"31", "Auto, fired, return detected"}, {"32", "No flash function"}, {"65", "Fired, red-eye reduction"}, {"73", "On, red-eye reduction"}, {"89", "Auto, fired, red-eye reduction"}}},
+	{IFD: ExifIFD, ID: TagFocalLength, Name: "FocalLength", Types: []Type{TypeRational}, Count: 1,
+		Description: "Focal length of the lens in millimeters", Fields: []string{"focal_length"}, format: "mm"},
+	{IFD: ExifIFD, ID: TagSubjectArea, Name: "SubjectArea", Types: []Type{TypeShort},
+		Description: "Location and area of the main subject"},
+	{IFD: ExifIFD, ID: TagMakerNote, Name: "MakerNote", Types: []Type{TypeUndefined},
+		Description: "Manufacturer-specific data; read for Canon, Nikon, Sony, Fujifilm and Panasonic", Fields: []string{"stabilization", "stabilization_mode", "drive_mode", "shutter_type", "focus_mode"}},
+	{IFD: ExifIFD, ID: TagUserComment, Name: "UserComment", Types: []Type{TypeUndefined},
+		Description: "Comment with a character code header", Fields: []string{"comment"}, format: "comment"},
+	{IFD: ExifIFD, ID: TagSubSecTime, Name: "SubSecTime", Types: []Type{TypeASCII},
+		Description: "Fractions of a second of DateTime"},
+	{IFD: ExifIFD, ID: TagSubSecTimeOriginal, Name: "SubSecTimeOriginal", Types: []Type{TypeASCII},
+		Description: "Fractions of a second of DateTimeOriginal"},
+	{IFD: ExifIFD, ID: TagSubSecTimeDigitized, Name: "SubSecTimeDigitized", Types: []Type{TypeASCII},
+		Description: "Fractions of a second of DateTimeDigitized"},
+	{IFD: ExifIFD, ID: TagTemperature, Name: "Temperature", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Ambient temperature in degrees Celsius", format: "decimal"},
+	{IFD: ExifIFD, ID: TagHumidity, Name: "Humidity", Types: []Type{TypeRational}, Count: 1,
+		Description: "Ambient relative humidity in percent", format: "decimal"},
+	{IFD: ExifIFD, ID: TagPressure, Name: "Pressure", Types: []Type{TypeRational}, Count: 1,
+		Description: "Air pressure in hPa", format: "decimal"},
+	{IFD: ExifIFD, ID: TagWaterDepth, Name: "WaterDepth", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Depth under water in meters, negative above the surface", format: "m"},
+	{IFD: ExifIFD, ID: TagAcceleration, Name: "Acceleration", Types: []Type{TypeRational}, Count: 1,
+		Description: "Acceleration of the camera in mGal", format: "decimal"},
+	{IFD: ExifIFD, ID: TagCameraElevationAngle, Name: "CameraElevationAngle", Types: []Type{TypeSRational}, Count: 1,
+		Description: "Elevation of the camera's optical axis in degrees", format: "decimal"},
+	{IFD: ExifIFD, ID: TagFlashpixVersion, Name: "FlashpixVersion", Types: []Type{TypeUndefined}, Count: 4,
+		Description: "Supported Flashpix version", Required: []IFDKind{ExifIFD}, format: "version"},
+	{IFD: ExifIFD, ID: TagColorSpace, Name: "ColorSpace", Types: []Type{TypeShort}, Count: 1,
+		Description: "Color space", Fields: []string{"color_space"},
+		Values: []TagValue{{"1", "sRGB"}, {"65535", "Uncalibrated"}}, Required: []IFDKind{ExifIFD}},
+	{IFD: ExifIFD, ID: TagPixelXDimension, Name: "PixelXDimension", Types: []Type{TypeShort, TypeLong}, Count: 1,
+		Description: "Width of the image in pixels", Fields: []string{"width"}, Required: []IFDKind{ExifIFD}},
+	{IFD: ExifIFD, ID: TagPixelYDimension, Name: "PixelYDimension", Types: []Type{TypeShort, TypeLong}, Count: 1,
+		Description: "Height of the image in pixels", Fields: []string{"height"}, Required: []IFDKind{ExifIFD}},
+	{IFD: ExifIFD, ID: TagRelatedSoundFile, Name: "RelatedSoundFile", Types: []Type{TypeASCII}, Count: 13,
+		Description: "Name of a related audio file"},
+	{IFD: ExifIFD, ID: TagInteropIFDPointer, Name: "InteroperabilityIFDPointer", Types: []Type{TypeLong}, Count: 1,
+		Description: "Offset of the interoperability IFD", sub: InteropIFD},
+	{IFD: ExifIFD, ID: TagFlashEnergy, Name: "FlashEnergy", Types: []Type{TypeRational}, Count: 1,
+		Description: "Strobe energy in BCPS", format: "decimal"},
+	{IFD: ExifIFD, ID: TagSpatialFrequencyResponse, Name: "SpatialFrequencyResponse", Types: []Type{TypeUndefined},
+		Description: "Spatial frequency table and response of the camera"},
+	{IFD: ExifIFD, ID: TagFocalPlaneXResolution, Name: "FocalPlaneXResolution", Types: []Type{TypeRational}, Count: 1,
+		Description: "Horizontal focal plane resolution"},
+	{IFD: ExifIFD, ID: TagFocalPlaneYResolution, Name: "FocalPlaneYResolution", Types: []Type{TypeRational}, Count: 1,
+		Description: "Vertical focal plane resolution"},
+	{IFD: ExifIFD, ID: TagFocalPlaneResolutionUnit, Name: "FocalPlaneResolutionUnit", Types: []Type{TypeShort}, Count: 1,
+		Description: "Unit of the focal plane resolutions",
+		Values:      []TagValue{{"2", "inches"}, {"3", "cm"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSubjectLocation, Name: "SubjectLocation", Types: []Type{TypeShort}, Count: 2,
+		Description: "Position of the main subject in pixels"},
+	{IFD: ExifIFD, ID: TagExposureIndex, Name: "ExposureIndex", Types: []Type{TypeRational}, Count: 1,
+		Description: "Exposure index"},
+	{IFD: ExifIFD, ID: TagSensingMethod, Name: "SensingMethod", Types: []Type{TypeShort}, Count: 1,
+		Description: "Type of image sensor",
+		Values:      []TagValue{{"1", "Not defined"}, {"2", "One-chip color area"}, {"3", "Two-chip color area"}, {"4", "Three-chip color area"}, {"5", "Color sequential area"}, {"7", "Trilinear"}, {"8", "Color sequential linear"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagFileSource, Name: "FileSource", Types: []Type{TypeUndefined}, Count: 1,
+		Description: "Source of the image",
+		Values:      []TagValue{{"0", "Other"}, {"1", "Transparent scanner"}, {"2", "Reflection print scanner"}, {"3", "Digital camera"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSceneType, Name: "SceneType", Types: []Type{TypeUndefined}, Count: 1,
+		Description: "Scene type",
+		Values:      []TagValue{{"1", "Directly photographed"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagCFAPattern, Name: "CFAPattern", Types: []Type{TypeUndefined},
+		Description: "Color filter array pattern of the sensor"},
+	{IFD: ExifIFD, ID: TagCustomRendered, Name: "CustomRendered", Types: []Type{TypeShort}, Count: 1,
+		Description: "Whether special processing was applied", Fields: []string{"custom_rendered"},
+		Values: []TagValue{{"0", "Normal"}, {"1", "Custom"}}},
+	{IFD: ExifIFD, ID: TagExposureMode, Name: "ExposureMode", Types: []Type{TypeShort}, Count: 1,
+		Description: "Exposure mode: auto, manual or auto bracket", Fields: []string{"exposure_mode"},
+		Values: []TagValue{{"0", "Auto"}, {"1", "Manual"}, {"2", "Auto bracket"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagWhiteBalance, Name: "WhiteBalance", Types: []Type{TypeShort}, Count: 1,
+		Description: "White balance: auto or manual",
+		Values:      []TagValue{{"0", "Auto"}, {"1", "Manual"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagDigitalZoomRatio, Name: "DigitalZoomRatio", Types: []Type{TypeRational}, Count: 1,
+		Description: "Digital zoom ratio", Fields: []string{"digital_zoom_ratio"}, format: "decimal"},
+	{IFD: ExifIFD, ID: TagFocalLength35mm, Name: "FocalLengthIn35mmFilm", Types: []Type{TypeShort}, Count: 1,
+		Description: "Focal length equivalent on 35 mm film", Fields: []string{"focal_length_35mm"}, format: "mm"},
+	{IFD: ExifIFD, ID: TagSceneCaptureType, Name: "SceneCaptureType", Types: []Type{TypeShort}, Count: 1,
+		Description: "Type of scene, such as landscape or portrait",
+		Values:      []TagValue{{"0", "Standard"}, {"1", "Landscape"}, {"2", "Portrait"}, {"3", "Night"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagGainControl, Name: "GainControl", Types: []Type{TypeShort}, Count: 1,
+		Description: "Gain adjustment", Fields: []string{"gain_control"},
+		Values: []TagValue{{"0", "None"}, {"1", "Low gain up"}, {"2", "High gain up"}, {"3", "Low gain down"}, {"4", "High gain down"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagContrast, Name: "Contrast", Types: []Type{TypeShort}, Count: 1,
+		Description: "Contrast processing", Fields: []string{"contrast"},
+		Values: []TagValue{{"0", "Normal"}, {"1", "Low"}, {"2", "High"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSaturation, Name: "Saturation", Types: []Type{TypeShort}, Count: 1,
+		Description: "Saturation processing", Fields: []string{"saturation"},
+		Values: []TagValue{{"0", "Normal"}, {"1", "Low"}, {"2", "High"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSharpness, Name: "Sharpness", Types: []Type{TypeShort}, Count: 1,
+		Description: "Sharpness processing", Fields: []string{"sharpness"},
+		Values: []TagValue{{"0", "Normal"}, {"1", "Soft"}, {"2", "Hard"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagDeviceSettingDescription, Name: "DeviceSettingDescription", Types: []Type{TypeUndefined},
+		Description: "Picture-taking conditions of the camera model"},
+	{IFD: ExifIFD, ID: TagSubjectDistanceRange, Name: "SubjectDistanceRange", Types: []Type{TypeShort}, Count: 1,
+		Description: "Range of the distance to the subject", Fields: []string{"subject_distance_range"},
+		Values: []TagValue{{"0", "Unknown"}, {"1", "Macro"}, {"2", "Close"}, {"3", "Distant"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagImageUniqueID, Name: "ImageUniqueID", Types: []Type{TypeASCII}, Count: 33,
+		Description: "Unique identifier of the image"},
+	{IFD: ExifIFD, ID: TagCameraOwnerName, Name: "CameraOwnerName", Types: []Type{TypeASCII},
+		Description: "Owner of the camera"},
+	{IFD: ExifIFD, ID: TagBodySerialNumber, Name: "BodySerialNumber", Types: []Type{TypeASCII},
+		Description: "Serial number of the camera body", Fields: []string{"body_serial"}},
+	{IFD: ExifIFD, ID: TagLensSpecification, Name: "LensSpecification", Types: []Type{TypeRational}, Count: 4,
+		Description: "Focal length and F-number range of the lens", format: "lens"},
+	{IFD: ExifIFD, ID: TagLensMake, Name: "LensMake", Types: []Type{TypeASCII},
+		Description: "Manufacturer of the lens", Fields: []string{"lens_make"}},
+	{IFD: ExifIFD, ID: TagLensModel, Name: "LensModel", Types: []Type{TypeASCII},
+		Description: "Model of the lens", Fields: []string{"lens_model"}},
+	{IFD: ExifIFD, ID: TagLensSerialNumber, Name: "LensSerialNumber", Types: []Type{TypeASCII},
+		Description: "Serial number of the lens", Fields: []string{"lens_serial"}},
+	{IFD: ExifIFD, ID: TagCompositeImage, Name: "CompositeImage", Types: []Type{TypeShort}, Count: 1,
+		Description: "Whether the image combines several frames", Fields: []string{"composite"},
+		Values: []TagValue{{"0", "Unknown"}, {"1", "Not a composite"}, {"2", "General composite"}, {"3", "Composite captured when shooting"}}, Closed: true},
+	{IFD: ExifIFD, ID: TagSourceImageNumberOfCompositeImage, Name: "SourceImageNumberOfCompositeImage", Types: []Type{TypeShort}, Count: 2,
+		Description: "Frames taken and frames used for a composite image", Fields: []string{"composite_frames"}},
+	{IFD: ExifIFD, ID: TagSourceExposureTimesOfCompositeImage, Name: "SourceExposureTimesOfCompositeImage", Types: []Type{TypeUndefined},
+		Description: "Exposure times of the frames of a composite image"},
+	{IFD: ExifIFD, ID: TagGamma, Name: "Gamma", Types: []Type{TypeRational}, Count: 1,
+		Description: "Gamma coefficient of the transfer function", format: "decimal"},
+	{IFD: GPSIFD, ID: TagGPSVersionID, Name: "GPSVersionID", Types: []Type{TypeByte}, Count: 4,
+		Description: "Version of the GPS IFD", Required: []IFDKind{GPSIFD}, format: "gpsversion"},
+	{IFD: GPSIFD, ID: TagGPSLatitudeRef, Name: "GPSLatitudeRef", Types: []Type{TypeASCII}, Count: 2,
+		Description: "Hemisphere of GPSLatitude", Fields: []string{"latitude"},
+		Values: []TagValue{{"N", "North"}, {"S", "South"}}, Closed: true},
+	{IFD: GPSIFD, ID: TagGPSLatitude, Name: "GPSLatitude", Types: []Type{TypeRational}, Count: 3,
+		Description: "Latitude as degrees, minutes and seconds", Fields: []string{"latitude"}, format: "dms"},
+	{IFD: GPSIFD, ID: TagGPSLongitudeRef, Name: "GPSLongitudeRef", Types: []Type{TypeASCII}, Count: 2,
+		Description: "Hemisphere of GPSLongitude", Fields: []string{"longitude"},
+		Values: []TagValue{{"E", "East"}, {"W", "West"}}, Closed: true},
+	{IFD: GPSIFD, ID: TagGPSLongitude, Name: "GPSLongitude", Types: []Type{TypeRational}, Count: 3,
+		Description: "Longitude as degrees, minutes and seconds", Fields: []string{"longitude"}, format: "dms"},
+	{IFD: GPSIFD, ID: TagGPSAltitudeRef, Name: "GPSAltitudeRef", Types: []Type{TypeByte}, Count: 1,
+		Description: "Whether GPSAltitude is above or below sea level", Fields: []string{"altitude"},
+		Values: []TagValue{{"0", "Above sea level"}, {"1", "Below sea level"}}, Closed: true},
+	{IFD: GPSIFD, ID: TagGPSAltitude, Name: "GPSAltitude", Types: []Type{TypeRational}, Count: 1,
+		Description: "Altitude in meters", Fields: []string{"altitude"}, format: "m"},
+	{IFD: GPSIFD, ID: TagGPSTimeStamp, Name: "GPSTimeStamp", Types: []Type{TypeRational}, Count: 3,
+		Description: "UTC time of the position", Fields: []string{"gps_time"}, format: "time"},
+	{IFD: GPSIFD, ID: TagGPSImgDirectionRef, Name: "GPSImgDirectionRef", Types: []Type{TypeASCII}, Count: 2,
+		Description: "Reference of GPSImgDirection",
+		Values:      []TagValue{{"T", "True north"}, {"M", "Magnetic north"}}, Closed: true},
+	{IFD: GPSIFD, ID: TagGPSImgDirection, Name: "GPSImgDirection", Types: []Type{TypeRational}, Count: 1,
+		Description: "Direction the camera pointed, in degrees", format: "decimal"},
+	{IFD: GPSIFD, ID: TagGPSMapDatum, Name: "GPSMapDatum", Types: []Type{TypeASCII},
+		Description: "Geodetic datum of the position"},
+	{IFD: GPSIFD, ID: TagGPSProcessingMethod, Name: "GPSProcessingMethod", Types: []Type{TypeUndefined},
+		Description: "Method used to find the position", format: "comment"},
+	{IFD: GPSIFD, ID: TagGPSDateStamp, Name: "GPSDateStamp", Types: []Type{TypeASCII}, Count: 11,
+		Description: "UTC date of the position", Fields: []string{"gps_time"}},
+	{IFD: InteropIFD, ID: TagInteroperabilityIndex, Name: "InteroperabilityIndex", Types: []Type{TypeASCII}, Count: 4,
+		Description: "Interoperability rule", Fields: []string{"color_space"},
+		Values: []TagValue{{"R98", "sRGB (DCF basic)"}, {"R03", "Adobe RGB (DCF option)"}, {"THM", "Thumbnail"}}, Required: []IFDKind{InteropIFD}},
+	{IFD: InteropIFD, ID: TagInteroperabilityVersion, Name: "InteroperabilityVersion", Types: []Type{TypeUndefined}, Count: 4,
+		Description: "Interoperability version", format: "version"},
+}
@@ -0,0 +1,80 @@
+package exif
+
+import (
+	"bytes"
+	"image/jpeg"
+)
+
+// Thumbnail returns the JPEG thumbnail recorded in IFD1, or nil. Raw
+// files, which hold a larger preview than the thumbnail, are better
+// served by Previews.
+func Thumbnail(data []byte) []byte {
+	tiff, err := findTIFF(data)
+	if err != nil {
+		return nil
+	}
+	x, err := Parse(tiff)
+	if err != nil {
+		return nil
+	}
+	off, ok := x.Uint(IFD1, TagJPEGInterchangeFormat)
+	if !ok {
+		return nil
+	}
+	n, ok := x.Uint(IFD1, TagJPEGInterchangeFormatLength)
+	if !ok || n == 0 || uint64(off)+uint64(n) > uint64(len(tiff)) {
+		return nil
+	}
+	thumb := tiff[off : off+n]
+	if !IsJPEG(thumb) {
+		return nil
+	}
+	return thumb
+}
+
+// Previews returns the JPEG images embedded in a TIFF-based raw file,
+// where cameras store a preview for their own display, in file order.
+// Each starts at an SOI marker followed by another marker and runs to the
+// next EOI, so an image may be cut short by an embedded one; callers
+// check that what they pick decodes.
+func Previews(data []byte) [][]byte {
+	if IsJPEG(data) {
+		return nil
+	}
+	var out [][]byte
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte{0xFF, markerSOI, 0xFF})
+		if i < 0 {
+			return out
+		}
+		start := pos + i
+		end := bytes.Index(data[start:], []byte{0xFF, markerEOI})
+		if end < 0 {
+			return out
+		}
+		out = append(out, data[start:start+end+2])
+		pos = start + 3
+	}
+}
+
+// SmallImage returns the smallest embedded JPEG that stands for the whole
+// image: the IFD1 thumbnail, or else the smallest raw preview with a
+// readable frame header. The tag names which, as for a LocationThumbnail Source. It
+// returns nil when there is neither, so that callers can analyze a photo
+// without decoding it in full.
+func SmallImage(data []byte) (image []byte, tag string) {
+	if t := Thumbnail(data); t != nil {
+		return t, "IFD1"
+	}
+	area := 0
+	for _, p := range Previews(data) {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(p))
+		if err == nil && (image == nil || cfg.Width*cfg.Height < area) {
+			image, area = p, cfg.Width*cfg.Height
+		}
+	}
+	if image == nil {
+		return nil, ""
+	}
+	return image, "preview"
+}
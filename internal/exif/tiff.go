@@ -0,0 +1,308 @@
+package exif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Type is a TIFF field type as stored in an IFD entry.
+type Type uint16
+
+// TIFF field types defined by TIFF 6.0 and EXIF 2.32.
+const (
+	TypeByte      Type = 1
+	TypeASCII     Type = 2
+	TypeShort     Type = 3
+	TypeLong      Type = 4
+	TypeRational  Type = 5
+	TypeSByte     Type = 6
+	TypeUndefined Type = 7
+	TypeSShort    Type = 8
+	TypeSLong     Type = 9
+	TypeSRational Type = 10
+	TypeFloat     Type = 11
+	TypeDouble    Type = 12
+)
+
+// Size returns the size in bytes of a single value of the type, or 0 for
+// unknown types.
+func (t Type) Size() int {
+	switch t {
+	case TypeByte, TypeASCII, TypeSByte, TypeUndefined:
+		return 1
+	case TypeShort, TypeSShort:
+		return 2
+	case TypeLong, TypeSLong, TypeFloat:
+		return 4
+	case TypeRational, TypeSRational, TypeDouble:
+		return 8
+	}
+	return 0
+}
+
+func (t Type) String() string {
+	switch t {
+	case TypeByte:
+		return "BYTE"
+	case TypeASCII:
+		return "ASCII"
+	case TypeShort:
+		return "SHORT"
+	case TypeLong:
+		return "LONG"
+	case TypeRational:
+		return "RATIONAL"
+	case TypeSByte:
+		return "SBYTE"
+	case TypeUndefined:
+		return "UNDEFINED"
+	case TypeSShort:
+		return "SSHORT"
+	case TypeSLong:
+		return "SLONG"
+	case TypeSRational:
+		return "SRATIONAL"
+	case TypeFloat:
+		return "FLOAT"
+	case TypeDouble:
+		return "DOUBLE"
+	}
+	return fmt.Sprintf("Type(%d)", uint16(t))
+}
+
+// IFDKind identifies which directory an entry was read from.
+type IFDKind int
+
+// Directories understood by the parser.
+const (
+	IFD0 IFDKind = iota
+	IFD1
+	ExifIFD
+	GPSIFD
+	InteropIFD
+	MakerNoteIFD
+)
+
+func (k IFDKind) String() string {
+	switch k {
+	case IFD0:
+		return "IFD0"
+	case IFD1:
+		return "IFD1"
+	case ExifIFD:
+		return "ExifIFD"
+	case GPSIFD:
+		return "GPS"
+	case InteropIFD:
+		return "Interop"
+	case MakerNoteIFD:
+		return "MakerNote"
+	}
+	return fmt.Sprintf("IFDKind(%d)", int(k))
+}
+
+// Entry is a single decoded IFD entry.
+type Entry struct {
+	IFD   IFDKind
+	Tag   uint16
+	Type  Type
+	Count uint32
+	// Offset is the value offset field as stored in the entry. It is only
+	// meaningful when the value does not fit in four bytes.
+	Offset uint32
+	// Value holds the raw value bytes in the file's byte order.
+	Value []byte
+
+	order binary.ByteOrder
+	// pos is the offset of the 12-byte entry within the TIFF structure.
+	pos uint32
+}
+
+// Len returns the number of values held by the entry.
+func (e Entry) Len() int {
+	size := e.Type.Size()
+	if size == 0 {
+		return 0
+	}
+	return len(e.Value) / size
+}
+
+// Uint returns the i-th value as an unsigned integer. It accepts the
+// unsigned integer types BYTE, SHORT, LONG and UNDEFINED.
+func (e Entry) Uint(i int) (uint32, bool) {
+	if i < 0 || i >= e.Len() {
+		return 0, false
+	}
+	switch e.Type {
+	case TypeByte, TypeUndefined:
+		return uint32(e.Value[i]), true
+	case TypeShort:
+		return uint32(e.order.Uint16(e.Value[i*2:])), true
+	case TypeLong:
+		return e.order.Uint32(e.Value[i*4:]), true
+	}
+	return 0, false
+}
+
+// Int returns the i-th value as a signed integer. Unsigned types are
+// converted, signed types are sign extended.
+func (e Entry) Int(i int) (int64, bool) {
+	if i < 0 || i >= e.Len() {
+		return 0, false
+	}
+	switch e.Type {
+	case TypeSByte:
+		return int64(int8(e.Value[i])), true
+	case TypeSShort:
+		return int64(int16(e.order.Uint16(e.Value[i*2:]))), true
+	case TypeSLong:
+		return int64(int32(e.order.Uint32(e.Value[i*4:]))), true
+	}
+	v, ok := e.Uint(i)
+	return int64(v), ok
+}
+
+// Rational returns the i-th value as a numerator/denominator pair.
+func (e Entry) Rational(i int) (num, den int64, ok bool) {
+	if i < 0 || i >= e.Len() {
+		return 0, 0, false
+	}
+	switch e.Type {
+	case TypeRational:
+		return int64(e.order.Uint32(e.Value[i*8:])), int64(e.order.Uint32(e.Value[i*8+4:])), true
+	case TypeSRational:
+		return int64(int32(e.order.Uint32(e.Value[i*8:]))), int64(int32(e.order.Uint32(e.Value[i*8+4:]))), true
+	}
+	return 0, 0, false
+}
+
+// Float returns the i-th value as a float64 for any numeric type.
+// Rationals with a zero denominator are reported as not ok.
+func (e Entry) Float(i int) (float64, bool) {
+	if i < 0 || i >= e.Len() {
+		return 0, false
+	}
+	switch e.Type {
+	case TypeRational, TypeSRational:
+		num, den, _ := e.Rational(i)
+		if den == 0 {
+			return 0, false
+		}
+		return float64(num) / float64(den), true
+	case TypeFloat:
+		return float64(math.Float32frombits(e.order.Uint32(e.Value[i*4:]))), true
+	case TypeDouble:
+		return math.Float64frombits(e.order.Uint64(e.Value[i*8:])), true
+	}
+	v, ok := e.Int(i)
+	return float64(v), ok
+}
+
+// String returns the value of an ASCII entry with trailing NULs and
+// surrounding spaces removed. Other types return their raw bytes.
+func (e Entry) String() string {
+	s := string(e.Value)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Text returns the value of a text entry decoded to UTF-8. Unlike String it
+// recovers non-ASCII text stored in the encodings cameras actually use; see
+// DecodeText.
+func (e Entry) Text() string {
+	return cleanText(DecodeText(e.Value))
+}
+
+// ifdReader reads IFDs out of a buffer whose value offsets are relative to
+// the start of the buffer.
+type ifdReader struct {
+	data   []byte
+	order  binary.ByteOrder
+	budget *budget
+}
+
+// maxIFDEntries bounds the entry count of a single IFD so a corrupt count
+// cannot make the parser allocate unbounded memory.
+const maxIFDEntries = 4096
+
+// readIFD reads the directory at off and returns its entries and the offset
+// of the next IFD.
+func (r ifdReader) readIFD(off uint32, kind IFDKind) ([]Entry, uint32, error) {
+	if uint64(off)+2 > uint64(len(r.data)) {
+		return nil, 0, fmt.Errorf("%w: %s offset %d out of range", ErrTruncated, kind, off)
+	}
+	n := int(r.order.Uint16(r.data[off:]))
+	if n > maxIFDEntries {
+		return nil, 0, fmt.Errorf("%w: %s has %d entries", ErrFormat, kind, n)
+	}
+	if err := r.budget.entries(n); err != nil {
+		return nil, 0, err
+	}
+	start := int(off) + 2
+	if start+n*12 > len(r.data) {
+		return nil, 0, fmt.Errorf("%w: %s entries exceed buffer", ErrTruncated, kind)
+	}
+	entries := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		b := r.data[start+i*12 : start+i*12+12]
+		e := Entry{
+			IFD:    kind,
+			Tag:    r.order.Uint16(b[0:]),
+			Type:   Type(r.order.Uint16(b[2:])),
+			Count:  r.order.Uint32(b[4:]),
+			Offset: r.order.Uint32(b[8:]),
+			order:  r.order,
+			pos:    uint32(start + i*12),
+		}
+		size := e.Type.Size()
+		if size == 0 {
+			// Unknown types are skipped per TIFF 6.0.
+			continue
+		}
+		total := uint64(size) * uint64(e.Count)
+		if err := r.budget.value(total); err != nil {
+			return nil, 0, err
+		}
+		if total <= 4 {
+			e.Value = b[8 : 8+total]
+		} else {
+			if uint64(e.Offset)+total > uint64(len(r.data)) {
+				// Values pointing outside the buffer are dropped rather
+				// than failing the whole directory.
+				continue
+			}
+			e.Value = r.data[e.Offset : uint64(e.Offset)+total]
+		}
+		entries = append(entries, e)
+	}
+	var next uint32
+	if end := start + n*12; end+4 <= len(r.data) {
+		next = r.order.Uint32(r.data[end:])
+	}
+	return entries, next, nil
+}
+
+// readHeader parses a TIFF header at the start of data and returns the byte
+// order and the offset of IFD0.
+func readHeader(data []byte) (binary.ByteOrder, uint32, error) {
+	if len(data) < 8 {
+		return nil, 0, fmt.Errorf("%w: TIFF header", ErrTruncated)
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, fmt.Errorf("%w: bad byte order mark %q", ErrFormat, data[:2])
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, 0, fmt.Errorf("%w: bad TIFF magic", ErrFormat)
+	}
+	return order, order.Uint32(data[4:]), nil
+}
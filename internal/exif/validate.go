@@ -0,0 +1,304 @@
+package exif
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity ranks a spec violation.
+type Severity int
+
+// Severities in increasing order of importance.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity by name.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// Violation is a departure from the EXIF 2.32 specification.
+type Violation struct {
+	Severity Severity `json:"severity"`
+	IFD      string   `json:"ifd,omitempty"`
+	// Tag is the tag ID in hex, empty for structural violations.
+	Tag     string `json:"tag,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Offset  int    `json:"offset"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	loc := v.IFD
+	if v.Tag != "" {
+		loc += " " + v.Tag
+		if v.Name != "" {
+			loc += " " + v.Name
+		}
+	}
+	if loc == "" {
+		return fmt.Sprintf("%-7s @%d: %s", v.Severity, v.Offset, v.Message)
+	}
+	return fmt.Sprintf("%-7s %s @%d: %s", v.Severity, loc, v.Offset, v.Message)
+}
+
+// Validate checks an image file against the EXIF 2.32 specification:
+// mandatory tags, field types and counts, word alignment of offsets, and
+// termination of ASCII values. Offsets in violations are relative to the
+// TIFF header. Mandatory-tag rules for compressed images are only applied
+// to JPEG files.
+func Validate(data []byte) []Violation {
+	v := &validator{}
+	jpeg := IsJPEG(data)
+	if jpeg {
+		if _, err := Segments(data); err != nil {
+			v.add(SeverityError, "", 0, 0, "JPEG structure: %v", err)
+		}
+	}
+	tiff, err := findTIFF(data)
+	if err != nil {
+		if errors.Is(err, ErrNoExif) {
+			v.add(SeverityError, "", 0, 0, "no APP1 Exif segment")
+		} else if len(v.violations) == 0 {
+			v.add(SeverityError, "", 0, 0, "%v", err)
+		}
+		return v.sorted()
+	}
+	v.data = tiff
+	v.run(jpeg)
+	return v.sorted()
+}
+
+// validator walks a TIFF structure without the leniency of Parse so that
+// every defect is reported rather than skipped.
+type validator struct {
+	data       []byte
+	order      binary.ByteOrder
+	violations []Violation
+	tags       map[IFDKind]map[uint16]Entry
+	offsets    map[IFDKind]int
+}
+
+func (v *validator) add(sev Severity, ifd string, off int, tag int, format string, args ...any) {
+	viol := Violation{Severity: sev, IFD: ifd, Offset: off, Message: fmt.Sprintf(format, args...)}
+	if tag >= 0 && ifd != "" {
+		viol.Tag = fmt.Sprintf("0x%04X", tag)
+	}
+	v.violations = append(v.violations, viol)
+}
+
+func (v *validator) sorted() []Violation {
+	sort.SliceStable(v.violations, func(i, j int) bool {
+		return v.violations[i].Severity > v.violations[j].Severity
+	})
+	return v.violations
+}
+
+func (v *validator) run(jpeg bool) {
+	order, off, err := readHeader(v.data)
+	if err != nil {
+		v.add(SeverityError, "", 0, -1, "TIFF header: %v", err)
+		return
+	}
+	v.order = order
+	v.tags = map[IFDKind]map[uint16]Entry{}
+	v.offsets = map[IFDKind]int{}
+
+	next := v.ifd(IFD0, off)
+	if next != 0 {
+		v.ifd(IFD1, next)
+	}
+	for _, p := range pointerTags {
+		if e, ok := v.tags[p.IFD][p.ID]; ok {
+			if sub, ok := e.Uint(0); ok {
+				v.ifd(p.sub, sub)
+			}
+		}
+	}
+
+	for _, kind := range []IFDKind{IFD0, IFD1, ExifIFD, GPSIFD, InteropIFD} {
+		present, parsed := v.tags[kind]
+		if !parsed {
+			continue
+		}
+		if jpeg {
+			for _, tag := range requiredTags[kind] {
+				if _, ok := present[tag]; !ok {
+					t, _ := LookupTag(kind, tag)
+					v.add(SeverityError, kind.String(), v.offsets[kind], int(tag), "mandatory tag %s missing", t.Name)
+				}
+			}
+		}
+		for _, tag := range recommendedTags[kind] {
+			if _, ok := present[tag]; !ok {
+				t, _ := LookupTag(kind, tag)
+				v.add(SeverityWarning, kind.String(), v.offsets[kind], int(tag), "recommended tag %s missing", t.Name)
+			}
+		}
+	}
+	if _, ok := v.tags[ExifIFD]; !ok && jpeg {
+		v.add(SeverityError, ExifIFD.String(), 0, -1, "Exif IFD missing")
+	}
+}
+
+// ifd validates the directory at off and returns the next IFD offset.
+func (v *validator) ifd(kind IFDKind, off uint32) uint32 {
+	name := kind.String()
+	if _, seen := v.tags[kind]; seen {
+		return 0
+	}
+	if uint64(off)+2 > uint64(len(v.data)) {
+		v.add(SeverityError, name, int(off), -1, "IFD offset %d beyond end of data (%d bytes)", off, len(v.data))
+		return 0
+	}
+	if off%2 != 0 {
+		v.add(SeverityWarning, name, int(off), -1, "IFD offset is not word aligned")
+	}
+	n := int(v.order.Uint16(v.data[off:]))
+	start := int(off) + 2
+	if start+n*12+4 > len(v.data) {
+		v.add(SeverityError, name, int(off), -1, "%d entries overrun the data", n)
+		return 0
+	}
+	present := map[uint16]Entry{}
+	v.tags[kind] = present
+	v.offsets[kind] = int(off)
+	prev := -1
+	for i := 0; i < n; i++ {
+		pos := start + i*12
+		b := v.data[pos : pos+12]
+		e := Entry{
+			IFD:    kind,
+			Tag:    v.order.Uint16(b[0:]),
+			Type:   Type(v.order.Uint16(b[2:])),
+			Count:  v.order.Uint32(b[4:]),
+			Offset: v.order.Uint32(b[8:]),
+			order:  v.order,
+		}
+		tag := int(e.Tag)
+		spec, known := LookupTag(kind, e.Tag)
+		add := func(sev Severity, format string, args ...any) {
+			v.add(sev, name, pos, tag, format, args...)
+			if known {
+				v.violations[len(v.violations)-1].Name = spec.Name
+			}
+		}
+		if tag <= prev {
+			if tag == prev {
+				add(SeverityError, "duplicate entry")
+			} else {
+				add(SeverityWarning, "entries not sorted in ascending tag order")
+			}
+		}
+		prev = max(prev, tag)
+
+		size := e.Type.Size()
+		if size == 0 {
+			add(SeverityError, "unknown field type %d", uint16(e.Type))
+			continue
+		}
+		total := uint64(size) * uint64(e.Count)
+		if total <= 4 {
+			e.Value = b[8 : 8+total]
+		} else {
+			if e.Offset%2 != 0 {
+				add(SeverityWarning, "value offset %d is not word aligned", e.Offset)
+			}
+			if uint64(e.Offset)+total > uint64(len(v.data)) {
+				add(SeverityError, "value (%d bytes at offset %d) extends beyond end of data", total, e.Offset)
+				continue
+			}
+			e.Value = v.data[e.Offset : uint64(e.Offset)+total]
+		}
+		if _, dup := present[e.Tag]; !dup {
+			present[e.Tag] = e
+		}
+
+		if !known {
+			continue
+		}
+		typeOK := typeAllowed(spec.Types, e.Type)
+		if !typeOK {
+			add(SeverityError, "type %s, want %s", e.Type, typeNames(spec.Types))
+		}
+		if spec.Count != 0 && e.Count != spec.Count {
+			add(SeverityError, "count %d, want %d", e.Count, spec.Count)
+		}
+		if e.Type == TypeASCII {
+			v.checkASCII(e, add)
+		}
+		if raw, ok := enumValue(e); ok && typeOK && spec.Closed {
+			if _, ok := spec.valueName(raw); !ok {
+				add(SeverityWarning, "value %s is not defined by EXIF 2.32", raw)
+			}
+		}
+	}
+	return v.order.Uint32(v.data[start+n*12:])
+}
+
+func (v *validator) checkASCII(e Entry, add func(Severity, string, ...any)) {
+	if len(e.Value) == 0 {
+		add(SeverityError, "ASCII value has zero count")
+		return
+	}
+	if e.Value[len(e.Value)-1] != 0 {
+		add(SeverityError, "ASCII value is not NUL-terminated")
+	}
+	for _, c := range e.Value {
+		if c >= 0x80 {
+			add(SeverityWarning, "ASCII value contains non-ASCII byte 0x%02X", c)
+			break
+		}
+	}
+	switch e.Tag {
+	case TagDateTime, TagDateTimeOriginal, TagDateTimeDigitized:
+		if e.IFD == GPSIFD || e.IFD == InteropIFD {
+			return
+		}
+		s := e.String()
+		// Unknown date/times may be blank or filled with colons and spaces.
+		if strings.Trim(s, ": ") == "" {
+			return
+		}
+		if _, err := time.Parse("2006:01:02 15:04:05", s); err != nil {
+			add(SeverityWarning, "date/time %q does not match YYYY:MM:DD HH:MM:SS", s)
+		}
+	}
+}
+
+func typeAllowed(types []Type, t Type) bool {
+	for _, allowed := range types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+func typeNames(types []Type) string {
+	s := ""
+	for i, t := range types {
+		if i > 0 {
+			s += " or "
+		}
+		s += t.String()
+	}
+	return s
+}
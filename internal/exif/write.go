@@ -0,0 +1,655 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// maxAPP1 is the largest payload a JPEG segment can hold.
+const maxAPP1 = 0xFFFF - 2
+
+// Edit sets or removes a single tag, in IFD0 unless moved with In. Build
+// edits with SetShort, SetASCII, SetXP, SetRational or Delete.
+type Edit struct {
+	Tag uint16
+
+	ifd   IFDKind
+	typ   Type
+	count uint32
+	// value encodes the value in the byte order of the target file; nil
+	// removes the tag.
+	value func(order binary.AppendByteOrder) []byte
+}
+
+// SetShort sets tag to one or more SHORT values.
+func SetShort(tag uint16, v ...uint16) Edit {
+	return Edit{Tag: tag, typ: TypeShort, count: uint32(len(v)), value: func(order binary.AppendByteOrder) []byte {
+		b := make([]byte, 0, 2*len(v))
+		for _, n := range v {
+			b = order.AppendUint16(b, n)
+		}
+		return b
+	}}
+}
+
+// SetASCII sets tag to a NUL-terminated ASCII value. Non-ASCII text is
+// stored as UTF-8, which DecodeText reads back.
+func SetASCII(tag uint16, s string) Edit {
+	v := append([]byte(s), 0)
+	return Edit{Tag: tag, typ: TypeASCII, count: uint32(len(v)), value: func(binary.AppendByteOrder) []byte { return v }}
+}
+
+// SetXP sets a Windows XP* tag to s encoded as NUL-terminated UTF-16LE.
+func SetXP(tag uint16, s string) Edit {
+	var v []byte
+	for _, u := range utf16.Encode([]rune(s + "\x00")) {
+		v = binary.LittleEndian.AppendUint16(v, u)
+	}
+	return Edit{Tag: tag, typ: TypeByte, count: uint32(len(v)), value: func(binary.AppendByteOrder) []byte { return v }}
+}
+
+// SetRational sets tag to RATIONAL values given as numerator and
+// denominator pairs.
+func SetRational(tag uint16, pairs ...uint32) Edit {
+	return Edit{Tag: tag, typ: TypeRational, count: uint32(len(pairs) / 2), value: func(order binary.AppendByteOrder) []byte {
+		var b []byte
+		for _, n := range pairs[:len(pairs)/2*2] {
+			b = order.AppendUint32(b, n)
+		}
+		return b
+	}}
+}
+
+// SetBytes sets tag to BYTE values.
+func SetBytes(tag uint16, v ...byte) Edit {
+	return Edit{Tag: tag, typ: TypeByte, count: uint32(len(v)), value: func(binary.AppendByteOrder) []byte {
+		return append([]byte(nil), v...)
+	}}
+}
+
+// setLong sets tag to a LONG value.
+func setLong(tag uint16, v uint32) Edit {
+	return Edit{Tag: tag, typ: TypeLong, count: 1, value: func(order binary.AppendByteOrder) []byte {
+		return order.AppendUint32(nil, v)
+	}}
+}
+
+// In returns the edit applied to ifd instead: IFD0, ExifIFD or GPSIFD.
+func (e Edit) In(ifd IFDKind) Edit {
+	e.ifd = ifd
+	return e
+}
+
+// SetCaptureTime returns the edits recording t as DateTimeOriginal, with
+// its UTC offset in OffsetTimeOriginal.
+func SetCaptureTime(t time.Time) []Edit {
+	return []Edit{
+		SetASCII(TagDateTimeOriginal, t.Format("2006:01:02 15:04:05")).In(ExifIFD),
+		SetASCII(TagOffsetTimeOriginal, t.Format("-07:00")).In(ExifIFD),
+	}
+}
+
+// SetGPS returns the edits replacing the GPS coordinates, and the
+// altitude in meters unless alt is nil.
+func SetGPS(lat, lon float64, alt *float64) []Edit {
+	edits := []Edit{
+		SetBytes(TagGPSVersionID, 2, 3, 0, 0).In(GPSIFD),
+		SetASCII(TagGPSLatitudeRef, hemisphere(lat, "N", "S")).In(GPSIFD),
+		SetRational(TagGPSLatitude, dms(lat)...).In(GPSIFD),
+		SetASCII(TagGPSLongitudeRef, hemisphere(lon, "E", "W")).In(GPSIFD),
+		SetRational(TagGPSLongitude, dms(lon)...).In(GPSIFD),
+	}
+	if alt != nil {
+		ref := byte(0)
+		if *alt < 0 {
+			ref = 1
+		}
+		edits = append(edits,
+			SetBytes(TagGPSAltitudeRef, ref).In(GPSIFD),
+			SetRational(TagGPSAltitude, uint32(math.Round(math.Abs(*alt)*100)), 100).In(GPSIFD))
+	}
+	return edits
+}
+
+// Delete removes tag.
+func Delete(tag uint16) Edit {
+	return Edit{Tag: tag}
+}
+
+// ratingPercent maps star ratings to the RatingPercent values Windows
+// writes.
+var ratingPercent = [...]uint16{0, 1, 25, 50, 75, 99}
+
+// SetRating returns the edits recording a 0-5 star rating, written as both
+// Rating and the RatingPercent Windows keeps alongside it. A rating of 0
+// clears both tags.
+func SetRating(stars int) ([]Edit, error) {
+	if stars < 0 || stars >= len(ratingPercent) {
+		return nil, fmt.Errorf("exif: rating %d out of range 0-5", stars)
+	}
+	if stars == 0 {
+		return []Edit{Delete(TagRating), Delete(TagRatingPercent)}, nil
+	}
+	return []Edit{SetShort(TagRating, uint16(stars)), SetShort(TagRatingPercent, ratingPercent[stars])}, nil
+}
+
+// SetKeywords returns the edit storing keywords in XPKeywords. An empty
+// list removes the tag.
+func SetKeywords(keywords []string) Edit {
+	if len(keywords) == 0 {
+		return Delete(TagXPKeywords)
+	}
+	return SetXP(TagXPKeywords, strings.Join(keywords, ";"))
+}
+
+// Apply returns a copy of image with edits applied. JPEG files get their
+// APP1 Exif segment rewritten, or a new one holding the mandatory tags
+// when they have none; TIFF-based files are edited in place; HEIF files
+// get their Exif item rewritten, and moved to the end of the file when it
+// grows, which needs one.
+//
+// Maker notes and the other data offsets may point at are never moved, so
+// those offsets stay valid: the directories are rewritten after the TIFF
+// structure and the header and IFD0 are pointed at them. In JPEG and HEIF
+// files the copies earlier edits left are dropped, so repeated edits do
+// not grow the file.
+func Apply(image []byte, edits ...Edit) ([]byte, error) {
+	if IsHEIF(image) {
+		return applyHEIF(image, edits)
+	}
+	if !IsJPEG(image) {
+		if _, err := findTIFF(image); err != nil {
+			return nil, err
+		}
+		return applyTIFF(image, edits, false)
+	}
+	segs, err := Segments(image)
+	if err != nil {
+		return nil, err
+	}
+	at, end, tiff := exifSpan(segs)
+	jf, hasJFIF := readJFIF(segs)
+	if tiff == nil {
+		// Start from the mandatory tags so the new segment is conformant,
+		// at the density the JFIF header records rather than 72 dpi.
+		w, h, err := ImageSize(image)
+		if err != nil {
+			return nil, err
+		}
+		if tiff, err = buildTIFF(&Summary{Width: w, Height: h}, binary.BigEndian); err != nil {
+			return nil, err
+		}
+		if hasJFIF {
+			edits = append(jf.resolution(), edits...)
+		}
+	}
+	tiff, err = applyTIFF(tiff, edits, true)
+	if err != nil {
+		return nil, err
+	}
+	payload := len(exifHeader) + len(tiff)
+	if payload+2 > maxAPP1 {
+		return nil, fmt.Errorf("%w: EXIF segment would be %d bytes", ErrFormat, payload)
+	}
+	seg := make([]byte, 0, payload+4)
+	seg = append(seg, 0xFF, markerAPP1, byte((payload+2)>>8), byte(payload+2))
+	seg = append(seg, exifHeader...)
+	seg = append(seg, tiff...)
+	out := placeExif(image, at, end, leadEnd(segs), seg)
+	if touchesResolution(edits) {
+		// Moving the segment may have moved the JFIF header too.
+		if segs, err := Segments(out); err == nil {
+			if jf, ok := readJFIF(segs); ok {
+				jf.syncDensity(out, tiff)
+			}
+		}
+	}
+	return out, nil
+}
+
+// exifSpan returns the byte range of the APP1 Exif segment and its TIFF
+// payload. Without one it returns the empty range where a new segment
+// belongs: after SOI and the APP0 segments (JFIF and JFXX) directly
+// following it, and so ahead of any Adobe APP14 segment.
+func exifSpan(segs []Segment) (at, end int, tiff []byte) {
+	for _, s := range segs {
+		if s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifHeader) {
+			return s.Offset, s.Offset + 4 + len(s.Data), s.Data[len(exifHeader):]
+		}
+	}
+	at = leadEnd(segs)
+	return at, at, nil
+}
+
+// subIFDs are the directories edits can target besides IFD0, with the
+// IFD0 tags pointing at them.
+var subIFDs = []struct {
+	kind    IFDKind
+	pointer uint16
+}{
+	{ExifIFD, TagExifIFDPointer},
+	{GPSIFD, TagGPSIFDPointer},
+}
+
+// applyTIFF writes edited copies of the directories edits target after
+// the TIFF structure in data, IFD0 last, and points the header at the new
+// IFD0.
+//
+// With compact set, as for the metadata-only structures of JPEG and HEIF
+// files, every write rebuilds IFD0 and its Exif and GPS directories from
+// scratch instead: data is cut back to the end of what must not move (see
+// pinnedEnd), so the copies earlier edits appended are dropped rather
+// than piling up. TIFF-based files are not compacted, as their image data
+// may lie anywhere after the directories.
+func applyTIFF(data []byte, edits []Edit, compact bool) ([]byte, error) {
+	byteOrder, off, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	byIFD := map[IFDKind][]Edit{}
+	for _, e := range edits {
+		byIFD[e.ifd] = append(byIFD[e.ifd], e)
+	}
+	ifd0, next, err := readDirectory(data, byteOrder, int64(off))
+	if err != nil {
+		return nil, fmt.Errorf("IFD0: %w", err)
+	}
+	dirs := map[IFDKind]directory{IFD0: {ifd0, next}}
+	for _, sub := range subIFDs {
+		if slices.ContainsFunc(byIFD[IFD0], func(e Edit) bool { return e.Tag == sub.pointer }) {
+			// An edit of the pointer itself, as StripGPS deletes it, wins.
+			continue
+		}
+		b, ok := ifd0[sub.pointer]
+		if !ok {
+			// A missing directory is created from the edits alone.
+			if len(byIFD[sub.kind]) > 0 {
+				dirs[sub.kind] = directory{raw: map[uint16][]byte{}}
+			}
+			continue
+		}
+		if !compact && len(byIFD[sub.kind]) == 0 {
+			continue
+		}
+		raw, next, err := readDirectory(data, byteOrder, int64(byteOrder.Uint32(b[8:])))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sub.kind, err)
+		}
+		dirs[sub.kind] = directory{raw, next}
+	}
+	base := len(data)
+	if compact {
+		base = pinnedEnd(data, byteOrder, dirs, byIFD)
+	}
+	out := append([]byte(nil), data[:base]...)
+	var pointers []Edit
+	for _, sub := range subIFDs {
+		d, ok := dirs[sub.kind]
+		if !ok {
+			continue
+		}
+		var at uint32
+		if out, at, err = appendIFD(out, data, byteOrder, d, base, byIFD[sub.kind]); err != nil {
+			return nil, fmt.Errorf("%s: %w", sub.kind, err)
+		}
+		pointers = append(pointers, setLong(sub.pointer, at))
+	}
+	out, at, err := appendIFD(out, data, byteOrder, dirs[IFD0], base, append(byIFD[IFD0], pointers...))
+	if err != nil {
+		return nil, fmt.Errorf("IFD0: %w", err)
+	}
+	byteOrder.PutUint32(out[4:], at)
+	return out, nil
+}
+
+// directory is a directory as readDirectory returns it.
+type directory struct {
+	raw  map[uint16][]byte
+	next uint32
+}
+
+// readDirectory returns the raw 12-byte entries of the directory at off
+// by tag, and its next-directory offset.
+func readDirectory(data []byte, byteOrder binary.ByteOrder, off int64) (map[uint16][]byte, uint32, error) {
+	if off < 0 || off+2 > int64(len(data)) {
+		return nil, 0, fmt.Errorf("%w: offset %d out of range", ErrTruncated, off)
+	}
+	n := int(byteOrder.Uint16(data[off:]))
+	start := int(off) + 2
+	if start+n*12+4 > len(data) {
+		return nil, 0, fmt.Errorf("%w: entries exceed buffer", ErrTruncated)
+	}
+	raw := map[uint16][]byte{}
+	for i := 0; i < n; i++ {
+		b := data[start+i*12 : start+i*12+12]
+		raw[byteOrder.Uint16(b)] = b
+	}
+	return raw, byteOrder.Uint32(data[start+n*12:]), nil
+}
+
+// appendIFD appends to out a copy of the directory d of data with edits
+// applied, and returns its offset. Existing entries are copied verbatim
+// and their values stay where they are, unless they lie at or after base,
+// past the part of data out keeps, in which case they are copied along.
+func appendIFD(out, data []byte, byteOrder binary.ByteOrder, d directory, base int, edits []Edit) ([]byte, uint32, error) {
+	order := byteOrder.(binary.AppendByteOrder)
+	raw := maps.Clone(d.raw)
+	pending := map[uint16]Edit{}
+	for _, e := range edits {
+		delete(raw, e.Tag)
+		delete(pending, e.Tag)
+		if e.value != nil {
+			pending[e.Tag] = e
+		}
+	}
+	tags := make([]uint16, 0, len(raw)+len(pending))
+	for t := range raw {
+		tags = append(tags, t)
+	}
+	for t := range pending {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	if len(out)%2 != 0 {
+		out = append(out, 0)
+	}
+	ifdOff := len(out)
+	valueOff := ifdOff + 2 + len(tags)*12 + 4
+	var values []byte
+	addValue := func(v []byte) {
+		out = order.AppendUint32(out, uint32(valueOff+len(values)))
+		values = append(values, v...)
+		if len(values)%2 != 0 {
+			values = append(values, 0)
+		}
+	}
+	out = order.AppendUint16(out, uint16(len(tags)))
+	for _, t := range tags {
+		if b, ok := raw[t]; ok {
+			if start, end, ok := valueSpan(b, byteOrder); ok && start >= int64(base) && end <= int64(len(data)) {
+				out = append(out, b[:8]...)
+				addValue(data[start:end])
+				continue
+			}
+			out = append(out, b...)
+			continue
+		}
+		e := pending[t]
+		v := e.value(order)
+		out = order.AppendUint16(out, t)
+		out = order.AppendUint16(out, uint16(e.typ))
+		out = order.AppendUint32(out, e.count)
+		if len(v) <= 4 {
+			var inline [4]byte
+			copy(inline[:], v)
+			out = append(out, inline[:]...)
+			continue
+		}
+		addValue(v)
+	}
+	out = order.AppendUint32(out, d.next)
+	out = append(out, values...)
+	if uint64(len(out)) > 0xFFFFFFFF {
+		return nil, 0, fmt.Errorf("%w: TIFF structure exceeds 4 GiB", ErrFormat)
+	}
+	return out, uint32(ifdOff), nil
+}
+
+// valueSpan returns the byte range of the value of the raw entry b when it
+// is stored out of line. Entries of unknown types report false.
+func valueSpan(b []byte, byteOrder binary.ByteOrder) (start, end int64, ok bool) {
+	size := int64(Type(byteOrder.Uint16(b[2:])).Size()) * int64(byteOrder.Uint32(b[4:]))
+	if size <= 4 {
+		return 0, 0, false
+	}
+	start = int64(byteOrder.Uint32(b[8:]))
+	return start, start + size, true
+}
+
+// Tags whose values are offsets of data, paired with the tags of its
+// lengths, and tags pointing at directories, besides those of subIFDs.
+const (
+	tagTileOffsets    uint16 = 0x0144
+	tagTileByteCounts uint16 = 0x0145
+	tagSubIFDs        uint16 = 0x014A
+)
+
+var offsetTags = map[uint16]uint16{
+	TagStripOffsets:          TagStripByteCounts,
+	tagTileOffsets:           tagTileByteCounts,
+	TagJPEGInterchangeFormat: TagJPEGInterchangeFormatLength,
+}
+
+// pinnedEnd returns how much of data a compacting write keeps in place
+// when it rewrites dirs with edits: everything up to the end of what other
+// bytes may point at, so cannot move. That is the maker note, whose
+// offsets may be relative to the TIFF header, the data of offset tags
+// such as the thumbnail, and the directories not rewritten, such as IFD1
+// and Interop, with their values. The values of the rewritten directories
+// that start before the end are kept too; those after it are copied by
+// appendIFD. Entries of unknown types, whose size is unknown, keep all of
+// data.
+func pinnedEnd(data []byte, byteOrder binary.ByteOrder, dirs map[IFDKind]directory, byIFD map[IFDKind][]Edit) int {
+	p := &pins{data: data, byteOrder: byteOrder, end: 8, seen: map[int64]bool{}}
+	rewritten := map[uint16]bool{}
+	for _, sub := range subIFDs {
+		if _, ok := dirs[sub.kind]; ok {
+			rewritten[sub.pointer] = true
+		}
+	}
+	var movable [][2]int64
+	for kind, d := range dirs {
+		edited := map[uint16]bool{}
+		for _, e := range byIFD[kind] {
+			edited[e.Tag] = true
+		}
+		for tag, b := range d.raw {
+			switch {
+			case edited[tag], kind == IFD0 && rewritten[tag]:
+			case tag == TagMakerNote || p.special(d.raw, tag, b):
+				p.value(b)
+			default:
+				if start, end, ok := valueSpan(b, byteOrder); ok {
+					movable = append(movable, [2]int64{start, end})
+				} else if Type(byteOrder.Uint16(b[2:])).Size() == 0 {
+					p.pin(0, int64(len(data)))
+				}
+			}
+		}
+		p.directory(int64(d.next))
+	}
+	for grown := true; grown; {
+		grown = false
+		for _, v := range movable {
+			if v[0] < p.end && v[1] > p.end {
+				p.pin(v[0], v[1])
+				grown = true
+			}
+		}
+	}
+	return int(min(p.end, int64(len(data))))
+}
+
+// pins accumulates the end of the pinned ranges of a TIFF structure.
+type pins struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+	end       int64
+	seen      map[int64]bool
+}
+
+func (p *pins) pin(start, end int64) {
+	if start < int64(len(p.data)) {
+		p.end = max(p.end, end)
+	}
+}
+
+// value pins the value of the raw entry b.
+func (p *pins) value(b []byte) {
+	if start, end, ok := valueSpan(b, p.byteOrder); ok {
+		p.pin(start, end)
+	} else if Type(p.byteOrder.Uint16(b[2:])).Size() == 0 {
+		p.pin(0, int64(len(p.data)))
+	}
+}
+
+// special pins what the raw entry b of a directory with entries raw
+// points at if it is a directory pointer or an offset tag, and reports
+// whether it was.
+func (p *pins) special(raw map[uint16][]byte, tag uint16, b []byte) bool {
+	switch tag {
+	case TagExifIFDPointer, TagGPSIFDPointer, TagInteropIFDPointer, tagSubIFDs:
+		for _, off := range p.uints(b) {
+			p.directory(int64(off))
+		}
+		return true
+	}
+	lengthTag, ok := offsetTags[tag]
+	if !ok {
+		return false
+	}
+	offs := p.uints(b)
+	var lengths []uint32
+	if lb, ok := raw[lengthTag]; ok {
+		lengths = p.uints(lb)
+	}
+	for i, off := range offs {
+		if i < len(lengths) {
+			p.pin(int64(off), int64(off)+int64(lengths[i]))
+		} else {
+			// Without a length, nothing after the offset can move.
+			p.pin(int64(off), int64(len(p.data)))
+		}
+	}
+	return true
+}
+
+// directory pins the directory at off, its values and what it points at,
+// and the directories after it.
+func (p *pins) directory(off int64) {
+	if off == 0 || p.seen[off] {
+		return
+	}
+	p.seen[off] = true
+	raw, next, err := readDirectory(p.data, p.byteOrder, off)
+	if err != nil {
+		return
+	}
+	p.pin(off, off+2+int64(len(raw))*12+4)
+	for tag, b := range raw {
+		if !p.special(raw, tag, b) {
+			p.value(b)
+		}
+	}
+	p.directory(int64(next))
+}
+
+// uints returns the SHORT or LONG values of the raw entry b.
+func (p *pins) uints(b []byte) []uint32 {
+	t, n := Type(p.byteOrder.Uint16(b[2:])), int64(p.byteOrder.Uint32(b[4:]))
+	if t != TypeShort && t != TypeLong {
+		return nil
+	}
+	v := b[8:12]
+	if start, end, ok := valueSpan(b, p.byteOrder); ok {
+		if end > int64(len(p.data)) {
+			return nil
+		}
+		v = p.data[start:end]
+	}
+	var out []uint32
+	for i := int64(0); i < n && int(i)*t.Size() < len(v); i++ {
+		if t == TypeShort {
+			out = append(out, uint32(p.byteOrder.Uint16(v[2*i:])))
+		} else {
+			out = append(out, p.byteOrder.Uint32(v[4*i:]))
+		}
+	}
+	return out
+}
+
+// StripGPS returns a copy of image without GPS data. Removing the GPS IFD
+// pointer alone would leave the coordinates readable in the file, so the
+// directory and its values are zeroed in place before IFD0 is rewritten
+// without the pointer. Images without GPS data are returned unchanged.
+func StripGPS(image []byte) ([]byte, error) {
+	out := append([]byte(nil), image...)
+	tiff, err := findTIFF(out)
+	if errors.Is(err, ErrNoExif) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	x, err := Parse(tiff)
+	if err != nil {
+		return nil, err
+	}
+	ptr, ok := x.Lookup(IFD0, TagGPSIFDPointer)
+	if !ok {
+		return out, nil
+	}
+	for _, e := range x.Entries {
+		if e.IFD == GPSIFD {
+			clear(e.Value)
+		}
+	}
+	if off, ok := ptr.Uint(0); ok && uint64(off)+2 <= uint64(len(tiff)) {
+		byteOrder, _, _ := readHeader(tiff)
+		end := min(int(off)+2+int(byteOrder.Uint16(tiff[off:]))*12+4, len(tiff))
+		clear(tiff[off:end])
+	}
+	return Apply(out, Delete(TagGPSIFDPointer))
+}
+
+// TruncateGPS returns a copy of image with its GPS latitude and longitude
+// truncated toward zero to digits (0-7) decimal places of degrees. The
+// new values are written over the old ones in place, as whole degrees
+// with zero minutes and seconds, so nothing else in the file moves.
+// Images without GPS coordinates are returned unchanged.
+func TruncateGPS(image []byte, digits int) ([]byte, error) {
+	if digits < 0 || digits > 7 {
+		return nil, fmt.Errorf("exif: GPS precision %d out of range 0-7", digits)
+	}
+	out := append([]byte(nil), image...)
+	tiff, err := findTIFF(out)
+	if errors.Is(err, ErrNoExif) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	x, err := Parse(tiff)
+	if err != nil {
+		return nil, err
+	}
+	den := uint32(1)
+	for range digits {
+		den *= 10
+	}
+	for _, tag := range []uint16{TagGPSLatitude, TagGPSLongitude} {
+		e, ok := x.Lookup(GPSIFD, tag)
+		if !ok || e.Type != TypeRational || e.Len() < 3 {
+			continue
+		}
+		deg, _ := e.Float(0)
+		min, _ := e.Float(1)
+		sec, _ := e.Float(2)
+		v := deg + min/60 + sec/3600
+		clear(e.Value)
+		for i, n := range []uint32{uint32(math.Trunc(v * float64(den))), den, 0, 1, 0, 1} {
+			e.order.PutUint32(e.Value[4*i:], n)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,168 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// xmpHeader prefixes an XMP packet inside a JPEG APP1 segment.
+var xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+// XMP returns the XMP packet of a JPEG file, or nil when it has none.
+func XMP(image []byte) []byte {
+	segs, _ := Segments(image)
+	for _, s := range segs {
+		if s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, xmpHeader) {
+			return s.Data[len(xmpHeader):]
+		}
+	}
+	return nil
+}
+
+// SetXMP returns a copy of a JPEG file with its XMP packet replaced by
+// packet. A file without XMP gets a new segment after the EXIF segment, or
+// where one would go.
+func SetXMP(image, packet []byte) ([]byte, error) {
+	segs, err := Segments(image)
+	if err != nil {
+		return nil, err
+	}
+	payload := len(xmpHeader) + len(packet)
+	if payload+2 > maxAPP1 {
+		return nil, fmt.Errorf("%w: XMP packet of %d bytes needs extended XMP", ErrFormat, len(packet))
+	}
+	// Without EXIF data exifSpan returns an empty range, so its end is the
+	// insertion point either way.
+	_, at, _ := exifSpan(segs)
+	end := at
+	for _, s := range segs {
+		if s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, xmpHeader) {
+			at, end = s.Offset, s.Offset+4+len(s.Data)
+			break
+		}
+	}
+	out := make([]byte, 0, len(image)-(end-at)+payload+4)
+	out = append(out, image[:at]...)
+	out = append(out, 0xFF, markerAPP1, byte((payload+2)>>8), byte(payload+2))
+	out = append(out, xmpHeader...)
+	out = append(out, packet...)
+	return append(out, image[end:]...), nil
+}
+
+// NamespaceXMP is the XMP basic schema, which holds CreatorTool.
+const NamespaceXMP = "http://ns.adobe.com/xap/1.0/"
+
+// HasXMPProperty reports whether packet sets the property name, written
+// either as an element or as an attribute of rdf:Description.
+func HasXMPProperty(packet []byte, name string) bool {
+	return bytes.Contains(packet, []byte(":"+name+">")) || bytes.Contains(packet, []byte(":"+name+"="))
+}
+
+// AddXMPProperty adds a simple text property to the first rdf:Description
+// of packet, declaring the namespace on it. A nil or empty packet yields a
+// new one holding just the property.
+func AddXMPProperty(packet []byte, ns, prefix, name, value string) ([]byte, error) {
+	var esc bytes.Buffer
+	if err := xml.EscapeText(&esc, []byte(value)); err != nil {
+		return nil, err
+	}
+	elem := fmt.Sprintf("<%s:%s>%s</%s:%s>", prefix, name, esc.String(), prefix, name)
+	if len(bytes.TrimSpace(packet)) == 0 {
+		return []byte("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" +
+			"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n" +
+			" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n" +
+			"  <rdf:Description rdf:about=\"\" xmlns:" + prefix + "=\"" + ns + "\">\n" +
+			"   " + elem + "\n" +
+			"  </rdf:Description>\n" +
+			" </rdf:RDF>\n" +
+			"</x:xmpmeta>\n" +
+			"<?xpacket end=\"w\"?>"), nil
+	}
+	start := bytes.Index(packet, []byte("<rdf:Description"))
+	if start < 0 {
+		return nil, fmt.Errorf("%w: XMP packet has no rdf:Description", ErrFormat)
+	}
+	end := bytes.IndexByte(packet[start:], '>')
+	if end < 0 {
+		return nil, fmt.Errorf("%w: unterminated rdf:Description", ErrFormat)
+	}
+	end += start
+	open := packet[start:end]
+	selfClosing := bytes.HasSuffix(open, []byte("/"))
+	if selfClosing {
+		open = open[:len(open)-1]
+	}
+	var b bytes.Buffer
+	b.Write(packet[:start])
+	b.Write(open)
+	if !bytes.Contains(open, []byte("xmlns:"+prefix+"=")) {
+		fmt.Fprintf(&b, " xmlns:%s=\"%s\"", prefix, ns)
+	}
+	b.WriteString(">" + elem)
+	if selfClosing {
+		b.WriteString("</rdf:Description>")
+	}
+	b.Write(packet[end+1:])
+	return b.Bytes(), nil
+}
+
+// XMP namespaces read into the Summary.
+const (
+	NamespaceDC         = "http://purl.org/dc/elements/1.1/"
+	NamespacePhotoshop  = "http://ns.adobe.com/photoshop/1.0/"
+	NamespaceIPTCCore   = "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+	namespaceRDF        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	namespaceXMPMeta    = "adobe:ns:meta/"
+	namespaceXMLPrefix  = "xmlns"
+	namespaceXMLBuiltin = "http://www.w3.org/XML/1998/namespace"
+)
+
+// XMPProperties returns the text values of an XMP packet keyed by
+// namespace URI and local name. Values of rdf:Alt, rdf:Bag and rdf:Seq
+// containers are listed in order, and properties nested in structures
+// such as Iptc4xmpCore:CreatorContactInfo are keyed by their own name.
+// Malformed packets yield whatever was read before the error.
+func XMPProperties(packet []byte) map[xml.Name][]string {
+	props := map[xml.Name][]string{}
+	structural := func(n xml.Name) bool {
+		switch n.Space {
+		case namespaceRDF, namespaceXMPMeta, namespaceXMLPrefix, namespaceXMLBuiltin, "":
+			return true
+		}
+		return false
+	}
+	d := xml.NewDecoder(bytes.NewReader(packet))
+	d.Strict = false
+	var stack []xml.Name
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return props
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			for _, a := range t.Attr {
+				if !structural(a.Name) && a.Value != "" {
+					props[a.Name] = append(props[a.Name], a.Value)
+				}
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			v := string(bytes.TrimSpace(t))
+			if v == "" {
+				continue
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if !structural(stack[i]) {
+					props[stack[i]] = append(props[stack[i]], v)
+					break
+				}
+			}
+		}
+	}
+}
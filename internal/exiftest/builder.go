@@ -0,0 +1,441 @@
+// Package exiftest builds synthetic EXIF-bearing images for tests and
+// fixtures. A Builder lays out arbitrary IFD trees in either byte order,
+// stores values inline or out of line as the TIFF rules dictate, and can
+// embed maker notes whose offsets are relative to the TIFF header or to the
+// note itself.
+package exiftest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"math"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Value produces the bytes of an entry value. pos is the offset, relative
+// to the TIFF header, at which the value will be stored when it does not
+// fit inline; the length of the result must not depend on pos.
+type Value func(pos uint32) []byte
+
+// Entry is a single IFD entry under construction.
+type Entry struct {
+	Tag   uint16
+	Type  exif.Type
+	Count uint32
+	Value Value
+}
+
+// IFD is a directory under construction.
+type IFD struct {
+	order    binary.ByteOrder
+	entries  []Entry
+	preserve bool
+}
+
+// Builder assembles a TIFF structure and wraps it in a JPEG.
+type Builder struct {
+	order   binary.ByteOrder
+	ifd0    *IFD
+	ifd1    *IFD
+	exif    *IFD
+	gps     *IFD
+	interop *IFD
+
+	thumbnail []byte
+	segments  []segment
+	img       image.Image
+}
+
+type segment struct {
+	marker byte
+	data   []byte
+}
+
+// New returns a Builder using the given byte order.
+func New(order binary.ByteOrder) *Builder {
+	b := &Builder{order: order}
+	b.ifd0 = b.newIFD()
+	return b
+}
+
+func (b *Builder) newIFD() *IFD { return NewIFD(b.order) }
+
+// Order returns the byte order of the TIFF structure.
+func (b *Builder) Order() binary.ByteOrder { return b.order }
+
+// IFD0 returns the primary image directory.
+func (b *Builder) IFD0() *IFD { return b.ifd0 }
+
+// IFD1 returns the thumbnail directory, creating it on first use.
+func (b *Builder) IFD1() *IFD {
+	if b.ifd1 == nil {
+		b.ifd1 = b.newIFD()
+	}
+	return b.ifd1
+}
+
+// Exif returns the Exif sub-IFD, creating it on first use. The pointer
+// from IFD0 is added automatically.
+func (b *Builder) Exif() *IFD {
+	if b.exif == nil {
+		b.exif = b.newIFD()
+	}
+	return b.exif
+}
+
+// GPS returns the GPS sub-IFD, creating it on first use.
+func (b *Builder) GPS() *IFD {
+	if b.gps == nil {
+		b.gps = b.newIFD()
+	}
+	return b.gps
+}
+
+// Interop returns the Interoperability sub-IFD, creating it on first use.
+// It is linked from the Exif IFD, which is created if needed.
+func (b *Builder) Interop() *IFD {
+	if b.interop == nil {
+		b.interop = b.newIFD()
+		b.Exif()
+	}
+	return b.interop
+}
+
+// NewIFD returns a standalone directory, for use with MakerNote.
+func NewIFD(order binary.ByteOrder) *IFD { return &IFD{order: order} }
+
+// Thumbnail stores data as the IFD1 JPEG thumbnail.
+func (b *Builder) Thumbnail(data []byte) *Builder {
+	b.thumbnail = data
+	b.IFD1()
+	return b
+}
+
+// Segment adds an extra marker segment after the APP1 Exif segment.
+func (b *Builder) Segment(marker byte, data []byte) *Builder {
+	b.segments = append(b.segments, segment{marker, data})
+	return b
+}
+
+// Image sets the picture encoded by JPEG. A small gray image is used when
+// none is set.
+func (b *Builder) Image(img image.Image) *Builder {
+	b.img = img
+	return b
+}
+
+// PreserveOrder keeps entries in insertion order instead of sorting them
+// by tag, to build directories that violate the TIFF ordering rule.
+func (d *IFD) PreserveOrder() *IFD {
+	d.preserve = true
+	return d
+}
+
+// Raw adds an entry with an explicit type, count and value. It does not
+// check that they agree, so it can describe malformed entries.
+func (d *IFD) Raw(tag uint16, typ exif.Type, count uint32, value []byte) *IFD {
+	return d.Add(Entry{Tag: tag, Type: typ, Count: count, Value: func(uint32) []byte { return value }})
+}
+
+// Add adds an entry.
+func (d *IFD) Add(e Entry) *IFD {
+	d.entries = append(d.entries, e)
+	return d
+}
+
+// Remove drops every entry with the given tag.
+func (d *IFD) Remove(tag uint16) *IFD {
+	kept := d.entries[:0]
+	for _, e := range d.entries {
+		if e.Tag != tag {
+			kept = append(kept, e)
+		}
+	}
+	d.entries = kept
+	return d
+}
+
+// ASCII adds a NUL-terminated ASCII entry.
+func (d *IFD) ASCII(tag uint16, s string) *IFD {
+	v := append([]byte(s), 0)
+	return d.Raw(tag, exif.TypeASCII, uint32(len(v)), v)
+}
+
+// Bytes adds a BYTE entry.
+func (d *IFD) Bytes(tag uint16, v ...byte) *IFD {
+	return d.Raw(tag, exif.TypeByte, uint32(len(v)), v)
+}
+
+// XP adds a Windows XP* entry holding s as NUL-terminated UTF-16LE.
+func (d *IFD) XP(tag uint16, s string) *IFD {
+	var v []byte
+	for _, u := range utf16.Encode([]rune(s + "\x00")) {
+		v = binary.LittleEndian.AppendUint16(v, u)
+	}
+	return d.Bytes(tag, v...)
+}
+
+// Undefined adds an UNDEFINED entry.
+func (d *IFD) Undefined(tag uint16, v []byte) *IFD {
+	return d.Raw(tag, exif.TypeUndefined, uint32(len(v)), v)
+}
+
+// Short adds a SHORT entry.
+func (d *IFD) Short(tag uint16, v ...uint16) *IFD {
+	b := make([]byte, 2*len(v))
+	for i, x := range v {
+		d.order.PutUint16(b[2*i:], x)
+	}
+	return d.Raw(tag, exif.TypeShort, uint32(len(v)), b)
+}
+
+// Long adds a LONG entry.
+func (d *IFD) Long(tag uint16, v ...uint32) *IFD {
+	return d.Raw(tag, exif.TypeLong, uint32(len(v)), d.longs(v))
+}
+
+// SLong adds an SLONG entry.
+func (d *IFD) SLong(tag uint16, v ...int32) *IFD {
+	u := make([]uint32, len(v))
+	for i, x := range v {
+		u[i] = uint32(x)
+	}
+	return d.Raw(tag, exif.TypeSLong, uint32(len(v)), d.longs(u))
+}
+
+// Rational adds a RATIONAL entry from numerator/denominator pairs.
+func (d *IFD) Rational(tag uint16, pairs ...uint32) *IFD {
+	if len(pairs)%2 != 0 {
+		panic("exiftest: Rational needs numerator/denominator pairs")
+	}
+	return d.Raw(tag, exif.TypeRational, uint32(len(pairs)/2), d.longs(pairs))
+}
+
+// SRational adds an SRATIONAL entry from numerator/denominator pairs.
+func (d *IFD) SRational(tag uint16, pairs ...int32) *IFD {
+	if len(pairs)%2 != 0 {
+		panic("exiftest: SRational needs numerator/denominator pairs")
+	}
+	u := make([]uint32, len(pairs))
+	for i, x := range pairs {
+		u[i] = uint32(x)
+	}
+	return d.Raw(tag, exif.TypeSRational, uint32(len(pairs)/2), d.longs(u))
+}
+
+// Double adds a DOUBLE entry.
+func (d *IFD) Double(tag uint16, v ...float64) *IFD {
+	b := make([]byte, 8*len(v))
+	for i, x := range v {
+		d.order.PutUint64(b[8*i:], math.Float64bits(x))
+	}
+	return d.Raw(tag, exif.TypeDouble, uint32(len(v)), b)
+}
+
+func (d *IFD) longs(v []uint32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		d.order.PutUint32(b[4*i:], x)
+	}
+	return b
+}
+
+// NoteBase selects what maker note offsets are relative to.
+type NoteBase int
+
+const (
+	// BaseTIFF makes offsets relative to the TIFF header (Canon, Sony,
+	// Panasonic).
+	BaseTIFF NoteBase = iota
+	// BaseNote makes offsets relative to the start of the maker note
+	// (Fujifilm).
+	BaseNote
+)
+
+// MakerNote adds a MakerNote entry holding prefix followed by note encoded
+// in note's byte order. The note must be complete when MakerNote is
+// called, as its size is fixed at that point.
+func (d *IFD) MakerNote(prefix []byte, note *IFD, base NoteBase) *IFD {
+	size := uint32(len(prefix)) + note.size()
+	return d.Add(Entry{Tag: exif.TagMakerNote, Type: exif.TypeUndefined, Count: size, Value: func(pos uint32) []byte {
+		start := uint32(len(prefix))
+		if base == BaseTIFF {
+			start += pos
+		}
+		return append(append([]byte{}, prefix...), note.encode(start)...)
+	}})
+}
+
+// sorted returns the entries in the order they are written.
+func (d *IFD) sorted() []Entry {
+	entries := append([]Entry(nil), d.entries...)
+	if !d.preserve {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Tag < entries[j].Tag })
+	}
+	return entries
+}
+
+// size is the encoded size of the directory and its out-of-line values.
+func (d *IFD) size() uint32 {
+	n := uint32(2 + 12*len(d.entries) + 4)
+	for _, e := range d.entries {
+		if l := uint32(len(e.Value(0))); l > 4 {
+			n += l + l%2
+		}
+	}
+	return n
+}
+
+// encode serializes the directory as if it started at offset start; the
+// next IFD offset is zero.
+func (d *IFD) encode(start uint32) []byte {
+	return d.encodeNext(start, 0)
+}
+
+func (d *IFD) encodeNext(start, next uint32) []byte {
+	entries := d.sorted()
+	head := make([]byte, 2+12*len(entries)+4)
+	d.order.PutUint16(head, uint16(len(entries)))
+	var data bytes.Buffer
+	valuePos := start + uint32(len(head))
+	for i, e := range entries {
+		b := head[2+12*i:]
+		d.order.PutUint16(b, e.Tag)
+		d.order.PutUint16(b[2:], uint16(e.Type))
+		d.order.PutUint32(b[4:], e.Count)
+		pos := valuePos + uint32(data.Len())
+		v := e.Value(pos)
+		if len(v) <= 4 {
+			copy(b[8:], v)
+			continue
+		}
+		d.order.PutUint32(b[8:], pos)
+		data.Write(v)
+		if data.Len()%2 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	d.order.PutUint32(head[len(head)-4:], next)
+	return append(head, data.Bytes()...)
+}
+
+// pointer adds or replaces a LONG pointer entry whose value is resolved at
+// layout time.
+func (d *IFD) pointer(tag uint16, target *uint32) {
+	d.Remove(tag)
+	d.Add(Entry{Tag: tag, Type: exif.TypeLong, Count: 1, Value: func(uint32) []byte {
+		return d.longs([]uint32{*target})
+	}})
+}
+
+// TIFF returns the TIFF structure: header, IFD0, IFD1, then the Exif, GPS
+// and Interop directories, each followed by its out-of-line values.
+func (b *Builder) TIFF() []byte {
+	var exifOff, gpsOff, interopOff, thumbOff uint32
+	if b.exif != nil {
+		b.ifd0.pointer(exif.TagExifIFDPointer, &exifOff)
+	}
+	if b.gps != nil {
+		b.ifd0.pointer(exif.TagGPSIFDPointer, &gpsOff)
+	}
+	if b.interop != nil {
+		b.exif.pointer(exif.TagInteropIFDPointer, &interopOff)
+	}
+	if b.thumbnail != nil {
+		b.ifd1.pointer(exif.TagJPEGInterchangeFormat, &thumbOff)
+		b.ifd1.Remove(exif.TagJPEGInterchangeFormatLength).Long(exif.TagJPEGInterchangeFormatLength, uint32(len(b.thumbnail)))
+	}
+
+	type placed struct {
+		ifd *IFD
+		off uint32
+	}
+	var layout []placed
+	pos := uint32(8)
+	for _, p := range []struct {
+		ifd *IFD
+		off *uint32
+	}{{b.ifd0, nil}, {b.ifd1, nil}, {b.exif, &exifOff}, {b.gps, &gpsOff}, {b.interop, &interopOff}} {
+		if p.ifd == nil {
+			continue
+		}
+		if p.off != nil {
+			*p.off = pos
+		}
+		layout = append(layout, placed{p.ifd, pos})
+		pos += p.ifd.size()
+	}
+	thumbOff = pos
+
+	out := make([]byte, 8, pos+uint32(len(b.thumbnail)))
+	if b.order == binary.LittleEndian {
+		copy(out, "II")
+	} else {
+		copy(out, "MM")
+	}
+	b.order.PutUint16(out[2:], 42)
+	b.order.PutUint32(out[4:], 8)
+	for i, p := range layout {
+		var next uint32
+		if p.ifd == b.ifd0 && b.ifd1 != nil {
+			next = layout[i+1].off
+		}
+		out = append(out, p.ifd.encodeNext(p.off, next)...)
+	}
+	return append(out, b.thumbnail...)
+}
+
+// APP1 returns the payload of an APP1 Exif segment.
+func (b *Builder) APP1() []byte {
+	return append([]byte("Exif\x00\x00"), b.TIFF()...)
+}
+
+// JPEG returns a decodable JPEG whose first segment is the APP1 Exif
+// segment, followed by any extra segments.
+func (b *Builder) JPEG() []byte {
+	img := b.img
+	if img == nil {
+		img = Gray(16, 16, 0x80)
+	}
+	body := EncodeJPEG(img)[2:] // drop SOI
+	var out bytes.Buffer
+	out.Write([]byte{0xFF, 0xD8})
+	writeSegment(&out, 0xE1, b.APP1())
+	for _, s := range b.segments {
+		writeSegment(&out, s.marker, s.data)
+	}
+	out.Write(body)
+	return out.Bytes()
+}
+
+func writeSegment(w *bytes.Buffer, marker byte, data []byte) {
+	n := len(data) + 2
+	if n > 0xFFFF {
+		panic("exiftest: segment too large")
+	}
+	w.Write([]byte{0xFF, marker, byte(n >> 8), byte(n)})
+	w.Write(data)
+}
+
+// EncodeJPEG encodes img as a baseline JPEG without metadata.
+func EncodeJPEG(img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// Gray returns a solid gray image, handy for Image.
+func Gray(w, h int, level uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	return img
+}
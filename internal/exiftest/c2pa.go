@@ -0,0 +1,63 @@
+package exiftest
+
+import (
+	"encoding/binary"
+
+	"github.com/ryoh827/shootlog/pkg/cbor"
+)
+
+// MarkerAPP11 is the marker of the segments JUMBF builds.
+const MarkerAPP11 = 0xEB
+
+// Box returns an ISO BMFF style box of the given type holding the
+// concatenated content.
+func Box(typ string, content ...[]byte) []byte {
+	n := 8
+	for _, c := range content {
+		n += len(c)
+	}
+	out := binary.BigEndian.AppendUint32(make([]byte, 0, n), uint32(n))
+	out = append(out, typ...)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// JUMBF returns a "jumb" superbox whose description has a type UUID
+// starting with kind, as C2PA spells its content types ("c2pa", "c2ma",
+// "c2cl", "cbor"...), and the given label.
+func JUMBF(kind, label string, content ...[]byte) []byte {
+	desc := append([]byte(kind), 0x00, 0x11, 0x00, 0x10, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71)
+	desc = append(desc, 0x03) // requestable, labelled
+	desc = append(append(desc, label...), 0)
+	return Box("jumb", append([][]byte{Box("jumd", desc)}, content...)...)
+}
+
+// CBOR returns a "cbor" content box holding v. It panics when v cannot be
+// encoded.
+func CBOR(v any) []byte {
+	b, err := cbor.Marshal(v)
+	if err != nil {
+		panic("exiftest: " + err.Error())
+	}
+	return Box("cbor", b)
+}
+
+// APP11 splits a JUMBF superbox into APP11 payloads carrying at most size
+// bytes of it each, as box instance instance. Packets after the first
+// repeat the superbox header, as ISO/IEC 19566-5 requires.
+func APP11(box []byte, instance uint16, size int) [][]byte {
+	var out [][]byte
+	for seq, pos := uint32(1), 0; pos < len(box); seq++ {
+		end := min(pos+size, len(box))
+		p := binary.BigEndian.AppendUint16([]byte("JP"), instance)
+		p = binary.BigEndian.AppendUint32(p, seq)
+		if pos > 0 {
+			p = append(p, box[:8]...)
+		}
+		out = append(out, append(p, box[pos:end]...))
+		pos = end
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+// Command genfixtures writes the exiftest scenarios to testdata/ as
+// <scenario>/image.jpg, or image.heic and image.avif for HEIF scenarios,
+// plus a golden summary.json, or with -check verifies that the decoder
+// still produces the committed golden summaries.
+//
+//	go run ./internal/exiftest/genfixtures
+//	go run ./internal/exiftest/genfixtures -check
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+)
+
+func main() {
+	dir := flag.String("dir", "testdata", "fixture root directory")
+	check := flag.Bool("check", false, "compare against existing golden files instead of writing them")
+	flag.Parse()
+	if err := run(*dir, *check); err != nil {
+		fmt.Fprintf(os.Stderr, "genfixtures: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, check bool) error {
+	failed := 0
+	for _, sc := range exiftest.Scenarios() {
+		img := sc.Encode()
+		s, err := exif.DecodeBytes(img)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sc.Name, err)
+		}
+		// Round trip: the fixture must also decode to a spec-valid block.
+		for _, v := range exif.Validate(img) {
+			if v.Severity == exif.SeverityError {
+				return fmt.Errorf("%s: builder produced invalid EXIF: %s", sc.Name, v)
+			}
+		}
+		golden, err := exiftest.MarshalGolden(s)
+		if err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			path string
+			data []byte
+		}{
+			{filepath.Join(dir, sc.Name, sc.File()), img},
+			{filepath.Join(dir, sc.Name, "summary.json"), golden},
+		} {
+			if err := exiftest.CheckGolden(f.path, f.data, !check); err != nil {
+				if !check {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "%s: differs from the committed fixture\n", f.path)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d fixtures out of date; rerun without -check to regenerate", failed)
+	}
+	return nil
+}
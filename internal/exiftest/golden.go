@@ -0,0 +1,60 @@
+package exiftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Testdata returns the repository's testdata directory, where the
+// scenarios are written. It is found from this source file, so the tests
+// of any package reach the same fixtures whatever their directory.
+func Testdata() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "testdata")
+}
+
+// CheckGolden compares got with the golden file at path, or rewrites it
+// when update is set. Tests pass update from their -update flag.
+func CheckGolden(path string, got []byte, update bool) error {
+	if update {
+		return WriteGolden(path, got)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading golden file (rerun with -update to create it): %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%s differs (rerun with -update to accept)\ngot:\n%s\nwant:\n%s", path, printable(got), printable(want))
+	}
+	return nil
+}
+
+// printable returns golden data for a failure message, leaving out the
+// bytes of images.
+func printable(b []byte) string {
+	if json.Valid(b) {
+		return string(b)
+	}
+	return fmt.Sprintf("(%d bytes)", len(b))
+}
+
+// MarshalGolden renders v the way golden JSON files are stored.
+func MarshalGolden(v any) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// WriteGolden writes a golden file, creating its directory.
+func WriteGolden(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,225 @@
+package exiftest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// HEIF describes the container Builder.HEIF writes around the TIFF
+// structure: an ftyp box, a meta box locating the items and one mdat box
+// holding them. The coded images are stand-ins; only their decoder
+// configuration properties are realistic.
+type HEIF struct {
+	// Brand is the major brand: "heic", the default, with HEVC images,
+	// or "avif" with AV1 ones.
+	Brand string
+	// Tiles, above 1, makes the primary item a grid of that many coded
+	// tiles in one row, as phones write.
+	Tiles int
+	// BitDepth is the depth of the coded images; 8 by default.
+	BitDepth int
+	// Pixi adds a pixi property stating the depth of the primary item.
+	Pixi bool
+	// BaseOffset locates items with the iloc base offset, leaving out the
+	// extent offsets, rather than the other way round.
+	BaseOffset bool
+	// NoExifHeader leaves out the "Exif\0\0" that usually precedes the
+	// TIFF header in the Exif item.
+	NoExifHeader bool
+}
+
+// Item IDs of the files HEIF writes: the primary image, its tiles from
+// heifTile on and the Exif item last.
+const (
+	heifPrimary = 1
+	heifTile    = 2
+)
+
+// HEIF returns a HEIF file whose Exif item holds the TIFF structure.
+func (b *Builder) HEIF(h HEIF) []byte {
+	if h.Brand == "" {
+		h.Brand = "heic"
+	}
+	if h.BitDepth == 0 {
+		h.BitDepth = 8
+	}
+	codec, config := "hvc1", hvcC(h.BitDepth)
+	if h.Brand == "avif" {
+		codec, config = "av01", av1C(h.BitDepth)
+	}
+	exifID := uint16(heifTile + max(h.Tiles, 1))
+
+	// The items in mdat order, with their types, data and properties.
+	type item struct {
+		id    uint16
+		typ   string
+		data  []byte
+		props []byte
+	}
+	var items []item
+	const (
+		propConfig = 1
+		propSize   = 2
+		propPixi   = 3
+	)
+	if h.Tiles > 1 {
+		grid := []byte{0, 0, 0, byte(h.Tiles - 1)}
+		grid = binary.BigEndian.AppendUint16(grid, uint16(64*h.Tiles))
+		grid = binary.BigEndian.AppendUint16(grid, 64)
+		props := []byte{propSize}
+		if h.Pixi {
+			props = append(props, propPixi)
+		}
+		items = append(items, item{heifPrimary, "grid", grid, props})
+		for i := 0; i < h.Tiles; i++ {
+			items = append(items, item{uint16(heifTile + i), codec, []byte(fmt.Sprintf("tile %d", i)), []byte{0x80 | propConfig, propSize}})
+		}
+	} else {
+		props := []byte{0x80 | propConfig, propSize}
+		if h.Pixi {
+			props = append(props, propPixi)
+		}
+		items = append(items, item{heifPrimary, codec, []byte("coded image"), props})
+	}
+	payload := []byte{0, 0, 0, 6}
+	payload = append(payload, "Exif\x00\x00"...)
+	if h.NoExifHeader {
+		payload = []byte{0, 0, 0, 0}
+	}
+	items = append(items, item{exifID, "Exif", append(payload, b.TIFF()...), nil})
+
+	var infe [][]byte
+	for _, it := range items {
+		v := binary.BigEndian.AppendUint16(nil, it.id)
+		v = append(v, 0, 0)
+		v = append(append(v, it.typ...), 0)
+		flags := uint32(0)
+		if it.id >= heifTile && it.id < exifID {
+			flags = 1 // hidden
+		}
+		infe = append(infe, fullBox("infe", 2, flags, v))
+	}
+	iinf := fullBox("iinf", 0, 0, binary.BigEndian.AppendUint16(nil, uint16(len(items))), infe...)
+
+	ref := func(typ string, from uint16, to ...uint16) []byte {
+		v := binary.BigEndian.AppendUint16(nil, from)
+		v = binary.BigEndian.AppendUint16(v, uint16(len(to)))
+		for _, id := range to {
+			v = binary.BigEndian.AppendUint16(v, id)
+		}
+		return heifBox(typ, v)
+	}
+	refs := [][]byte{ref("cdsc", exifID, heifPrimary)}
+	if h.Tiles > 1 {
+		tiles := make([]uint16, h.Tiles)
+		for i := range tiles {
+			tiles[i] = uint16(heifTile + i)
+		}
+		refs = append(refs, ref("dimg", heifPrimary, tiles...))
+	}
+	iref := fullBox("iref", 0, 0, nil, refs...)
+
+	ispe := binary.BigEndian.AppendUint32(nil, 64)
+	ispe = binary.BigEndian.AppendUint32(ispe, 64)
+	ipco := heifBox("ipco", nil, heifBox(configBox(codec), config), fullBox("ispe", 0, 0, ispe),
+		fullBox("pixi", 0, 0, []byte{3, byte(h.BitDepth), byte(h.BitDepth), byte(h.BitDepth)}))
+	ipma := binary.BigEndian.AppendUint32(nil, uint32(len(items)-1))
+	for _, it := range items[:len(items)-1] {
+		ipma = binary.BigEndian.AppendUint16(ipma, it.id)
+		ipma = append(append(ipma, byte(len(it.props))), it.props...)
+	}
+	iprp := heifBox("iprp", nil, ipco, fullBox("ipma", 0, 0, ipma))
+
+	// iloc is laid out before the offsets are known; its size does not
+	// depend on them.
+	iloc := func(start uint32) []byte {
+		sizes := []byte{0x44, 0x00}
+		if h.BaseOffset {
+			sizes = []byte{0x04, 0x40}
+		}
+		v := binary.BigEndian.AppendUint16(sizes, uint16(len(items)))
+		at := start
+		for _, it := range items {
+			v = binary.BigEndian.AppendUint16(v, it.id)
+			v = append(v, 0, 0)
+			if h.BaseOffset {
+				v = binary.BigEndian.AppendUint32(v, at)
+			}
+			v = binary.BigEndian.AppendUint16(v, 1)
+			if !h.BaseOffset {
+				v = binary.BigEndian.AppendUint32(v, at)
+			}
+			v = binary.BigEndian.AppendUint32(v, uint32(len(it.data)))
+			at += uint32(len(it.data))
+		}
+		return fullBox("iloc", 0, 0, v)
+	}
+	hdlr := fullBox("hdlr", 0, 0, append(append(make([]byte, 4), "pict"...), make([]byte, 13)...))
+	pitm := fullBox("pitm", 0, 0, binary.BigEndian.AppendUint16(nil, heifPrimary))
+	meta := func(start uint32) []byte {
+		return fullBox("meta", 0, 0, nil, hdlr, pitm, iloc(start), iinf, iref, iprp)
+	}
+
+	ftyp := append([]byte(h.Brand), 0, 0, 0, 0)
+	ftyp = append(ftyp, "mif1miaf"...)
+	ftyp = append(ftyp, h.Brand...)
+	head := heifBox("ftyp", ftyp)
+	start := uint32(len(head) + len(meta(0)) + 8)
+	out := append(head, meta(start)...)
+	var mdat []byte
+	for _, it := range items {
+		mdat = append(mdat, it.data...)
+	}
+	return append(out, heifBox("mdat", mdat)...)
+}
+
+// heifBox returns a box of typ holding data followed by children.
+func heifBox(typ string, data []byte, children ...[]byte) []byte {
+	size := 8 + len(data)
+	for _, c := range children {
+		size += len(c)
+	}
+	out := binary.BigEndian.AppendUint32(make([]byte, 0, size), uint32(size))
+	out = append(append(out, typ...), data...)
+	for _, c := range children {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// fullBox is heifBox for a box with a version and flags.
+func fullBox(typ string, version byte, flags uint32, data []byte, children ...[]byte) []byte {
+	vf := binary.BigEndian.AppendUint32(nil, uint32(version)<<24|flags)
+	return heifBox(typ, append(vf, data...), children...)
+}
+
+func configBox(codec string) string {
+	if codec == "av01" {
+		return "av1C"
+	}
+	return "hvcC"
+}
+
+// hvcC returns an HEVC decoder configuration record of a 4:2:0 image of
+// depth bits, without parameter set arrays.
+func hvcC(depth int) []byte {
+	c := make([]byte, 23)
+	c[0] = 1
+	c[16] = 0xFC | 1
+	c[17] = 0xF8 | byte(depth-8)
+	c[18] = 0xF8 | byte(depth-8)
+	return c
+}
+
+// av1C returns an AV1 codec configuration record of a 4:2:0 image of
+// depth bits.
+func av1C(depth int) []byte {
+	flags := byte(0x08 | 0x04)
+	switch depth {
+	case 10:
+		flags |= 0x40
+	case 12:
+		flags |= 0x60
+	}
+	return []byte{0x81, 0, flags, 0}
+}
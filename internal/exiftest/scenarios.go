@@ -0,0 +1,329 @@
+package exiftest
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Scenario is a named fixture image.
+type Scenario struct {
+	Name  string
+	Build func() *Builder
+	// HEIF, when set, wraps the TIFF structure in a HEIF file of this
+	// layout rather than a JPEG.
+	HEIF *HEIF
+}
+
+// File is the name of the scenario's image in its testdata directory.
+func (sc Scenario) File() string {
+	switch {
+	case sc.HEIF == nil:
+		return "image.jpg"
+	case sc.HEIF.Brand == "avif":
+		return "image.avif"
+	}
+	return "image.heic"
+}
+
+// Encode builds the scenario's image.
+func (sc Scenario) Encode() []byte {
+	if sc.HEIF != nil {
+		return sc.Build().HEIF(*sc.HEIF)
+	}
+	return sc.Build().JPEG()
+}
+
+// Scenarios returns the standard fixture set written to testdata/ by
+// genfixtures. Each new tag or maker note layout should land with a
+// scenario here so that it is covered by the golden summaries.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{"spec-minimal-be", func() *Builder { return Conformant(binary.BigEndian) }, nil},
+		{"spec-minimal-le", func() *Builder { return Conformant(binary.LittleEndian) }, nil},
+		{"gps-south-west", gpsSouthWest, nil},
+		{"canon-continuous-silent", canon, nil},
+		{"nikon-vr-sport", nikon, nil},
+		{"sony-steadyshot", sony, nil},
+		{"fujifilm-ibis-electronic", fujifilm, nil},
+		{"panasonic-dual-is", panasonic, nil},
+		{"thumbnail", thumbnail, nil},
+		{"unicode-text", unicodeText, nil},
+		{"user-comment-unicode", userCommentUnicode, nil},
+		{"user-comment-jis", userCommentJIS, nil},
+		{"delivery-ready", deliveryReady, nil},
+		{"delivery-offender", deliveryOffender, nil},
+		{"panorama-sphere", panoramaSphere, nil},
+		{"dji-flight", djiFlight, nil},
+		{"apple-live-photo-hdr", appleLivePhotoHDR, nil},
+		{"pixel-night-motion", pixelNightMotion, nil},
+		{"processing-settings", processingSettings, nil},
+		{"heic-iphone-grid", heicIPhone, &HEIF{Tiles: 4, BitDepth: 10}},
+		{"heic-base-offset", func() *Builder { return Conformant(binary.LittleEndian) }, &HEIF{BaseOffset: true, NoExifHeader: true, Pixi: true}},
+		{"avif-10bit", func() *Builder { return Conformant(binary.BigEndian) }, &HEIF{Brand: "avif", BitDepth: 10}},
+	}
+}
+
+// Conformant returns a Builder pre-populated with every tag EXIF 2.32
+// marks mandatory for a compressed primary image plus common capture
+// settings, so exif.Validate reports no errors.
+func Conformant(order binary.ByteOrder) *Builder {
+	b := New(order)
+	b.IFD0().
+		ASCII(exif.TagMake, "Shootlog").
+		ASCII(exif.TagModel, "Fixture One").
+		Rational(exif.TagXResolution, 72, 1).
+		Rational(exif.TagYResolution, 72, 1).
+		Short(exif.TagResolutionUnit, 2).
+		ASCII(exif.TagDateTime, "2024:05:01 10:00:00").
+		Short(exif.TagYCbCrPositioning, 1)
+	b.Exif().
+		Rational(exif.TagExposureTime, 1, 250).
+		Rational(exif.TagFNumber, 28, 10).
+		Short(exif.TagISOSpeedRatings, 400).
+		Undefined(exif.TagExifVersion, []byte("0232")).
+		ASCII(exif.TagDateTimeOriginal, "2024:05:01 10:00:00").
+		ASCII(exif.TagDateTimeDigitized, "2024:05:01 10:00:00").
+		ASCII(exif.TagOffsetTimeOriginal, "+09:00").
+		Undefined(exif.TagComponentsConfiguration, []byte{1, 2, 3, 0}).
+		SRational(exif.TagExposureBias, -1, 3).
+		Rational(exif.TagFocalLength, 35, 1).
+		Undefined(exif.TagFlashpixVersion, []byte("0100")).
+		Short(exif.TagColorSpace, 1).
+		Long(exif.TagPixelXDimension, 16).
+		Long(exif.TagPixelYDimension, 16).
+		Short(exif.TagFocalLength35mm, 52).
+		ASCII(exif.TagLensModel, "Fixture 35mm F2.8")
+	b.Interop().ASCII(exif.TagInteroperabilityIndex, "R98").Undefined(exif.TagInteroperabilityVersion, []byte("0100"))
+	return b
+}
+
+func gpsSouthWest() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.GPS().
+		Bytes(exif.TagGPSVersionID, 2, 3, 0, 0).
+		ASCII(exif.TagGPSLatitudeRef, "S").
+		Rational(exif.TagGPSLatitude, 33, 1, 52, 1, 4, 1).
+		ASCII(exif.TagGPSLongitudeRef, "W").
+		Rational(exif.TagGPSLongitude, 70, 1, 39, 1, 3600, 100).
+		Bytes(exif.TagGPSAltitudeRef, 1).
+		Rational(exif.TagGPSAltitude, 125, 10)
+	b.Exif().Rational(exif.TagSubjectDistance, 350, 100)
+	return b
+}
+
+func canon() *Builder {
+	b := Conformant(binary.LittleEndian)
+	b.IFD0().Remove(exif.TagMake).ASCII(exif.TagMake, "Canon")
+	settings := make([]uint16, 40)
+	settings[0] = uint16(len(settings) * 2)
+	settings[5] = 10 // Continuous, Silent
+	settings[34] = 3 // Panning
+	note := NewIFD(b.Order()).Short(0x0001, settings...)
+	b.Exif().MakerNote(nil, note, BaseTIFF)
+	return b
+}
+
+func nikon() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.IFD0().Remove(exif.TagMake).ASCII(exif.TagMake, "NIKON CORPORATION")
+	// Type 3 notes embed a complete TIFF structure after a 10-byte header.
+	inner := New(binary.BigEndian)
+	inner.IFD0().
+		Undefined(0x001F, []byte("0100\x01\x00\x03\x00")).
+		Short(0x0089, 1)
+	b.Exif().Undefined(exif.TagMakerNote, append([]byte("Nikon\x00\x02\x10\x00\x00"), inner.TIFF()...))
+	return b
+}
+
+func sony() *Builder {
+	b := Conformant(binary.LittleEndian)
+	b.IFD0().Remove(exif.TagMake).ASCII(exif.TagMake, "SONY")
+	note := NewIFD(b.Order()).
+		Long(0xB026, 1).
+		Short(0xB049, 2)
+	b.Exif().MakerNote([]byte("SONY DSC \x00\x00\x00"), note, BaseTIFF)
+	return b
+}
+
+func fujifilm() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.IFD0().Remove(exif.TagMake).ASCII(exif.TagMake, "FUJIFILM")
+	// Fujifilm notes are little-endian whatever the file byte order, and
+	// the header carries the IFD offset relative to the note.
+	note := NewIFD(binary.LittleEndian).
+		Short(0x1050, 1).
+		Long(0x1103, 2).
+		Short(0x1422, 2, 1, 0)
+	b.Exif().MakerNote([]byte("FUJIFILM\x0c\x00\x00\x00"), note, BaseNote)
+	return b
+}
+
+func panasonic() *Builder {
+	b := Conformant(binary.LittleEndian)
+	b.IFD0().Remove(exif.TagMake).ASCII(exif.TagMake, "Panasonic")
+	note := NewIFD(b.Order()).
+		Short(0x001A, 9).
+		Short(0x002A, 0).
+		Short(0x009F, 1)
+	b.Exif().MakerNote([]byte("Panasonic\x00\x00\x00"), note, BaseTIFF)
+	return b
+}
+
+func thumbnail() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.IFD1().
+		Short(exif.TagCompression, 6).
+		Rational(exif.TagXResolution, 72, 1).
+		Rational(exif.TagYResolution, 72, 1).
+		Short(exif.TagResolutionUnit, 2)
+	b.Thumbnail(EncodeJPEG(Gray(8, 8, 0x40)))
+	return b
+}
+
+func unicodeText() *Builder {
+	b := Conformant(binary.LittleEndian)
+	// "写真日記 1.0" in Shift_JIS and a UTF-8 lens name, both stored in ASCII
+	// fields as Japanese software commonly does.
+	b.IFD0().
+		Raw(exif.TagSoftware, exif.TypeASCII, 13, []byte("\x8e\xca\x90\x5e\x93\xfa\x8b\x4c 1.0\x00")).
+		XP(exif.TagXPTitle, "桜の下で").
+		XP(exif.TagXPComment, "Café at dawn ☕").
+		XP(exif.TagXPAuthor, "山田 花子").
+		XP(exif.TagXPKeywords, "桜; spring ;Tokyo")
+	b.Exif().Remove(exif.TagLensModel).ASCII(exif.TagLensModel, "Objectif 35mm f/2 — Édition")
+	return b
+}
+
+func userCommentUnicode() *Builder {
+	b := Conformant(binary.BigEndian)
+	// UNICODE comments follow the TIFF byte order.
+	comment := []byte("UNICODE\x00")
+	for _, u := range utf16.Encode([]rune("夕暮れの港、三脚使用")) {
+		comment = binary.BigEndian.AppendUint16(comment, u)
+	}
+	b.IFD0().ASCII(exif.TagImageDescription, "Harbour at dusk")
+	b.Exif().Undefined(exif.TagUserComment, comment)
+	return b
+}
+
+func userCommentJIS() *Builder {
+	b := Conformant(binary.LittleEndian)
+	// "富士山" as raw JIS X 0208 code points, padded with spaces.
+	b.Exif().Undefined(exif.TagUserComment, []byte("JIS\x00\x00\x00\x00\x00\x49\x59\x3B\x4E\x3B\x33    "))
+	b.IFD0().XP(exif.TagXPComment, "ignored: UserComment wins")
+	return b
+}
+
+func deliveryReady() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.Segment(MarkerAPP2, ICC("sRGB IEC61966-2.1"))
+	b.Segment(MarkerAPP13, IPTC(map[int][]string{
+		exif.IPTCByline:          {"Hanako Yamada"},
+		exif.IPTCCopyrightNotice: {"© 2024 Hanako Yamada"},
+		exif.IPTCCredit:          {"Studio Fixture"},
+		exif.IPTCKeywords:        {"portrait", "studio"},
+	}))
+	b.Segment(MarkerAPP1, XMP(
+		`xmlns:Iptc4xmpCore="http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"`,
+		`<Iptc4xmpCore:CreatorContactInfo rdf:parseType="Resource">`+
+			`<Iptc4xmpCore:CiEmailWork>hanako@example.com</Iptc4xmpCore:CiEmailWork>`+
+			`<Iptc4xmpCore:CiUrlWork>https://example.com</Iptc4xmpCore:CiUrlWork>`+
+			`</Iptc4xmpCore:CreatorContactInfo>`))
+	return b
+}
+
+func deliveryOffender() *Builder {
+	b := gpsSouthWest()
+	// Adobe RGB is recorded as uncalibrated plus the R03 interop index.
+	b.Exif().Remove(exif.TagColorSpace).Short(exif.TagColorSpace, 0xFFFF)
+	b.Interop().Remove(exif.TagInteroperabilityIndex).ASCII(exif.TagInteroperabilityIndex, "R03")
+	b.Segment(MarkerAPP2, ICC("Adobe RGB (1998)"))
+	b.Segment(MarkerAPP1, XMP(`xmlns:dc="http://purl.org/dc/elements/1.1/"`,
+		`<dc:creator><rdf:Seq><rdf:li>Unknown Second Shooter</rdf:li></rdf:Seq></dc:creator>`))
+	return b
+}
+
+func panoramaSphere() *Builder {
+	b := Conformant(binary.LittleEndian)
+	// A photo sphere stitched in camera from six frames.
+	b.Exif().
+		Short(exif.TagCompositeImage, 3).
+		Short(exif.TagSourceImageNumberOfCompositeImage, 6, 6)
+	b.Segment(MarkerAPP1, XMP(`xmlns:GPano="http://ns.google.com/photos/1.0/panorama/"`,
+		`<GPano:ProjectionType>equirectangular</GPano:ProjectionType>`+
+			`<GPano:UsePanoramaViewer>True</GPano:UsePanoramaViewer>`+
+			`<GPano:FullPanoWidthPixels>8192</GPano:FullPanoWidthPixels>`+
+			`<GPano:FullPanoHeightPixels>4096</GPano:FullPanoHeightPixels>`+
+			`<GPano:CroppedAreaImageWidthPixels>8192</GPano:CroppedAreaImageWidthPixels>`+
+			`<GPano:CroppedAreaImageHeightPixels>4096</GPano:CroppedAreaImageHeightPixels>`+
+			`<GPano:PoseHeadingDegrees>87.5</GPano:PoseHeadingDegrees>`))
+	return b
+}
+
+func djiFlight() *Builder {
+	b := Conformant(binary.LittleEndian)
+	b.IFD0().
+		Remove(exif.TagMake).ASCII(exif.TagMake, "DJI").
+		Remove(exif.TagModel).ASCII(exif.TagModel, "FC3582")
+	// DJI writes its flight data as attributes, with explicit signs.
+	b.Segment(MarkerAPP1, XMP(`xmlns:drone-dji="http://www.dji.com/drone-dji/1.0/" `+
+		`drone-dji:AbsoluteAltitude="+152.31" drone-dji:RelativeAltitude="+48.70" `+
+		`drone-dji:GimbalRollDegree="+0.00" drone-dji:GimbalYawDegree="-93.40" drone-dji:GimbalPitchDegree="-45.10" `+
+		`drone-dji:FlightRollDegree="+1.20" drone-dji:FlightYawDegree="-92.80" drone-dji:FlightPitchDegree="-3.10" `+
+		`drone-dji:FlightXSpeed="+3.00" drone-dji:FlightYSpeed="-4.00" drone-dji:FlightZSpeed="+0.20"`, ""))
+	return b
+}
+
+func appleLivePhotoHDR() *Builder {
+	b := Conformant(binary.BigEndian)
+	b.IFD0().
+		Remove(exif.TagMake).ASCII(exif.TagMake, "Apple").
+		Remove(exif.TagModel).ASCII(exif.TagModel, "iPhone 15 Pro")
+	// Apple notes carry their own byte order mark and offsets relative to
+	// the start of the note.
+	note := NewIFD(binary.BigEndian).
+		SLong(0x000A, 3).
+		ASCII(0x0011, "5E3B7C1A-9D2F-4E8B-A6C0-1F2D3E4A5B6C").
+		SLong(0x0014, 2)
+	b.Exif().MakerNote([]byte("Apple iOS\x00\x00\x01MM"), note, BaseNote)
+	return b
+}
+
+func pixelNightMotion() *Builder {
+	b := Conformant(binary.LittleEndian)
+	b.IFD0().
+		Remove(exif.TagMake).ASCII(exif.TagMake, "Google").
+		Remove(exif.TagModel).ASCII(exif.TagModel, "Pixel 8")
+	b.Segment(MarkerAPP1, XMP(`xmlns:GCamera="http://ns.google.com/photos/1.0/camera/" `+
+		`GCamera:SpecialTypeID="com.google.android.apps.camera.gallery.specialtype.SpecialType-NIGHT" `+
+		`GCamera:MotionPhoto="1" GCamera:MotionPhotoVersion="1" GCamera:MotionPhotoPresentationTimestampUs="968000"`, ""))
+	return b
+}
+
+func processingSettings() *Builder {
+	b := Conformant(binary.LittleEndian)
+	// An auto-bracketed macro shot at 2x digital zoom with boosted gain and
+	// in-camera picture settings; CustomRendered 6 is an iPhone value
+	// outside EXIF and is left out.
+	b.Exif().
+		Short(exif.TagCustomRendered, 6).
+		Short(exif.TagExposureMode, 2).
+		Rational(exif.TagDigitalZoomRatio, 2, 1).
+		Short(exif.TagGainControl, 1).
+		Short(exif.TagContrast, 2).
+		Short(exif.TagSaturation, 1).
+		Short(exif.TagSharpness, 0).
+		Short(exif.TagSubjectDistanceRange, 1)
+	return b
+}
+
+func heicIPhone() *Builder {
+	b := appleLivePhotoHDR()
+	b.IFD0().ASCII(exif.TagSoftware, "17.4")
+	b.Exif().
+		Remove(exif.TagLensModel).ASCII(exif.TagLensModel, "iPhone 15 Pro back triple camera 6.86mm f/1.78").
+		Remove(exif.TagFNumber).Rational(exif.TagFNumber, 178, 100)
+	return b
+}
@@ -0,0 +1,78 @@
+package exiftest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Markers of the metadata segments built here.
+const (
+	MarkerAPP1  = 0xE1
+	MarkerAPP2  = 0xE2
+	MarkerAPP13 = 0xED
+)
+
+// IPTC returns an APP13 payload holding an IPTC-IIM application record
+// with the given datasets, in dataset order.
+func IPTC(datasets map[int][]string) []byte {
+	keys := make([]int, 0, len(datasets))
+	for k := range datasets {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var iim bytes.Buffer
+	// 1:90 declares UTF-8.
+	iim.Write([]byte{0x1C, 1, 90, 0, 3, 0x1B, '%', 'G'})
+	for _, k := range keys {
+		for _, v := range datasets[k] {
+			iim.Write([]byte{0x1C, 2, byte(k), byte(len(v) >> 8), byte(len(v))})
+			iim.WriteString(v)
+		}
+	}
+	var out bytes.Buffer
+	out.WriteString("Photoshop 3.0\x00")
+	out.WriteString("8BIM")
+	out.Write([]byte{0x04, 0x04, 0, 0}) // resource ID, empty padded name
+	binary.Write(&out, binary.BigEndian, uint32(iim.Len()))
+	out.Write(iim.Bytes())
+	if iim.Len()%2 != 0 {
+		out.WriteByte(0)
+	}
+	return out.Bytes()
+}
+
+// XMP returns an APP1 payload holding an XMP packet whose rdf:Description
+// carries body. Namespaces used by body must be declared in attrs.
+func XMP(attrs, body string) []byte {
+	return []byte("http://ns.adobe.com/xap/1.0/\x00" +
+		"<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>" +
+		"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"><rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">" +
+		"<rdf:Description rdf:about=\"\" " + attrs + ">" + body + "</rdf:Description>" +
+		"</rdf:RDF></x:xmpmeta><?xpacket end=\"w\"?>")
+}
+
+// ICC returns an APP2 payload holding a minimal ICC v2 profile whose only
+// tag is the profile description.
+func ICC(description string) []byte {
+	be := binary.BigEndian
+	desc := []byte("desc\x00\x00\x00\x00")
+	desc = be.AppendUint32(desc, uint32(len(description)+1))
+	desc = append(desc, description...)
+	desc = append(desc, 0)
+	// Unicode and ScriptCode parts of textDescriptionType, left empty.
+	desc = append(desc, make([]byte, 4+4+2+1+67)...)
+
+	profile := make([]byte, 128)
+	profile = be.AppendUint32(profile, 1)
+	profile = append(profile, "desc"...)
+	profile = be.AppendUint32(profile, 128+4+12)
+	profile = be.AppendUint32(profile, uint32(len(desc)))
+	profile = append(profile, desc...)
+	be.PutUint32(profile[0:], uint32(len(profile)))
+	copy(profile[36:], "acsp")
+
+	out := []byte("ICC_PROFILE\x00")
+	out = append(out, 1, 1) // chunk 1 of 1
+	return append(out, profile...)
+}
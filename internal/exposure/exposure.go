@@ -0,0 +1,137 @@
+// Package exposure estimates how a photo is exposed from its embedded
+// thumbnail rather than the full image, which is quick enough to run over
+// a whole card before culling: a coarse luminance histogram and the share
+// of clipped highlights and shadows.
+package exposure
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Bins is the number of luminance bands in a histogram.
+const Bins = 16
+
+// Levels at and beyond which 8-bit luminance counts as clipped. Thumbnails
+// are small and heavily compressed, so clipped areas rarely read as pure
+// white or black.
+const (
+	highlightLevel = 250
+	shadowLevel    = 5
+)
+
+// Shares of clipped pixels above which a photo is flagged.
+const (
+	blownShare   = 0.05
+	crushedShare = 0.10
+)
+
+// Warnings.
+const (
+	Blown   = "blown"
+	Crushed = "crushed"
+)
+
+// Estimate is the exposure of one photo as its thumbnail shows it.
+type Estimate struct {
+	// Mean is the mean luminance, 0-1.
+	Mean float64 `json:"mean_luminance"`
+	// Highlights and Shadows are the shares of pixels clipped at either
+	// end, 0-1.
+	Highlights float64 `json:"clipped_highlights"`
+	Shadows    float64 `json:"clipped_shadows"`
+	// Histogram is the percentage of pixels in each of Bins luminance
+	// bands, darkest first.
+	Histogram []int `json:"luminance_histogram"`
+	// Warning is Blown or Crushed when many pixels are clipped, blown
+	// highlights taking precedence, or "".
+	Warning string `json:"exposure_warning,omitempty"`
+}
+
+// Analyze measures the luminance of img with Rec. 709 weights, on the
+// gamma-encoded values as a histogram on a camera's screen does.
+func Analyze(img image.Image) Estimate {
+	b := img.Bounds()
+	var counts [Bins]int
+	var sum float64
+	high, low, n := 0, 0, 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			l := (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(bl)) / 257
+			sum += l
+			counts[min(int(l)*Bins/256, Bins-1)]++
+			switch {
+			case l >= highlightLevel:
+				high++
+			case l <= shadowLevel:
+				low++
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return Estimate{}
+	}
+	e := Estimate{
+		Mean:       round(sum / float64(n) / 255),
+		Highlights: round(float64(high) / float64(n)),
+		Shadows:    round(float64(low) / float64(n)),
+		Histogram:  make([]int, Bins),
+	}
+	for i, c := range counts {
+		e.Histogram[i] = int(math.Round(float64(c) * 100 / float64(n)))
+	}
+	switch {
+	case e.Highlights >= blownShare:
+		e.Warning = Blown
+	case e.Shadows >= crushedShare:
+		e.Warning = Crushed
+	}
+	return e
+}
+
+// FromFile estimates the exposure of an image file from its embedded
+// thumbnail or raw preview, and reports false when it has none that
+// decodes. The tag names the image used, as exif.SmallImage does.
+func FromFile(data []byte) (e Estimate, tag string, ok bool) {
+	small, tag := exif.SmallImage(data)
+	if small == nil {
+		return Estimate{}, "", false
+	}
+	img, err := jpeg.Decode(bytes.NewReader(small))
+	if err != nil {
+		return Estimate{}, "", false
+	}
+	return Analyze(img), tag, true
+}
+
+// Apply copies e into the exposure fields of s, with tag the image it
+// was computed from.
+func Apply(s *exif.Summary, e Estimate, tag string) {
+	s.MeanLuminance, s.ClippedHighlights, s.ClippedShadows = e.Mean, e.Highlights, e.Shadows
+	s.LuminanceHistogram, s.ExposureWarning = e.Histogram, e.Warning
+	if s.Sources == nil {
+		s.Sources = map[string]exif.Source{}
+	}
+	src := exif.Source{Location: exif.LocationThumbnail, Tag: tag}
+	for f, set := range map[string]bool{
+		"mean_luminance":      e.Mean != 0,
+		"clipped_highlights":  e.Highlights != 0,
+		"clipped_shadows":     e.Shadows != 0,
+		"luminance_histogram": len(e.Histogram) > 0,
+		"exposure_warning":    e.Warning != "",
+	} {
+		if set {
+			s.Sources[f] = src
+		}
+	}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
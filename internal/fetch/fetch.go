@@ -0,0 +1,237 @@
+// Package fetch downloads remote images for decoding. It keeps to a
+// request rate and a number of connections, and retries throttled and
+// failed requests with exponential backoff, so listing a large bucket
+// through HTTP does not trip the provider's limits.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of the Fetcher fields left zero.
+const (
+	DefaultConcurrency = 4
+	DefaultRetries     = 3
+	DefaultBackoff     = 500 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+	DefaultMaxBytes    = 256 << 20
+)
+
+// Fetcher downloads URLs with GET.
+type Fetcher struct {
+	// Client sends the requests; nil uses a client with a 60 s timeout.
+	Client *http.Client
+	// Concurrency bounds the downloads in flight.
+	Concurrency int
+	// Rate bounds the requests started per second, retries included. Zero
+	// is unlimited, as are rates above one a nanosecond, the finest the
+	// limit can space them.
+	Rate float64
+	// Retries is the number of further attempts after a network error,
+	// 429 or 5xx response. Zero disables retries; negative uses
+	// DefaultRetries.
+	Retries int
+	// Backoff is the wait before the first retry. It doubles with each
+	// retry up to MaxBackoff, with jitter; a Retry-After header takes
+	// precedence.
+	Backoff, MaxBackoff time.Duration
+	// MaxBytes bounds each download.
+	MaxBytes int64
+}
+
+// Result is the outcome of one URL.
+type Result struct {
+	URL  string
+	Data []byte
+	// Attempts counts the requests made, retries included.
+	Attempts int
+	Err      error
+}
+
+// defaultClient bounds each request so a stalled server cannot hold a
+// connection slot forever.
+var defaultClient = &http.Client{Timeout: 60 * time.Second}
+
+// Fetch downloads urls and calls fn with each result as it completes, from
+// one goroutine at a time. It returns when all are done or ctx is.
+func (f *Fetcher) Fetch(ctx context.Context, urls []string, fn func(Result)) {
+	n := f.Concurrency
+	if n <= 0 {
+		n = DefaultConcurrency
+	}
+	var tick <-chan time.Time
+	if f.Rate > 0 && f.Rate <= float64(time.Second) {
+		t := time.NewTicker(time.Duration(float64(time.Second) / f.Rate))
+		defer t.Stop()
+		tick = t.C
+	}
+	// wait admits one request under the rate limit.
+	wait := func() error {
+		if tick == nil {
+			return ctx.Err()
+		}
+		select {
+		case <-tick:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				r := f.get(ctx, u, wait)
+				mu.Lock()
+				fn(r)
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, u := range urls {
+		select {
+		case jobs <- u:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// retryable marks errors worth another attempt and the delay the server
+// asked for, if asked is set.
+type retryable struct {
+	err   error
+	after time.Duration
+	asked bool
+}
+
+func (e *retryable) Error() string { return e.err.Error() }
+func (e *retryable) Unwrap() error { return e.err }
+
+// get downloads u with retries.
+func (f *Fetcher) get(ctx context.Context, u string, wait func() error) Result {
+	retries := f.Retries
+	if retries < 0 {
+		retries = DefaultRetries
+	}
+	backoff := f.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	maxBackoff := f.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	r := Result{URL: u}
+	for {
+		if r.Err = wait(); r.Err != nil {
+			return r
+		}
+		r.Attempts++
+		r.Data, r.Err = f.once(ctx, u)
+		var re *retryable
+		if r.Err == nil || !errors.As(r.Err, &re) || r.Attempts > retries {
+			return r
+		}
+		// Full jitter keeps clients that were throttled together from
+		// retrying together.
+		delay := re.after
+		if !re.asked {
+			delay = min(backoff<<(r.Attempts-1), maxBackoff)
+			delay = time.Duration(rand.Int64N(int64(delay)) + 1)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			r.Err = ctx.Err()
+			return r
+		}
+	}
+}
+
+// once makes one request.
+func (f *Fetcher) once(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			ue.URL = Redact(u)
+		}
+		return nil, &retryable{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("fetch: GET %s: %s", Redact(u), resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			after, asked := retryAfter(resp.Header.Get("Retry-After"))
+			return nil, &retryable{err: err, after: after, asked: asked}
+		}
+		return nil, err
+	}
+	limit := f.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMaxBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, &retryable{err: fmt.Errorf("fetch: GET %s: %w", Redact(u), err)}
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("fetch: GET %s: larger than %d bytes", Redact(u), limit)
+	}
+	return data, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date,
+// reporting whether there was one.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// Redact drops the credentials and query of u, which hold the signature
+// of presigned URLs, for messages and output.
+func Redact(u string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	p.User, p.RawQuery = nil, ""
+	return p.String()
+}
@@ -0,0 +1,84 @@
+// Package filename checks file names built from metadata against the
+// rules of the file systems photos are commonly copied to, so that a name
+// valid on the machine that made it does not fail on a Windows share or
+// an exFAT card, and folds names for the case-insensitive comparisons
+// those file systems make.
+package filename
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest name in bytes. ext4 counts bytes, while APFS
+// and NTFS count characters; 255 bytes fits all three.
+const MaxLength = 255
+
+// reserved are characters Windows does not allow in names; ':' is also
+// the path separator of the classic Mac OS and of Finder.
+const reserved = `<>:"\|?*`
+
+// devices are names Windows reserves with any extension.
+var devices = map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true}
+
+func init() {
+	for i := '1'; i <= '9'; i++ {
+		devices["COM"+string(i)] = true
+		devices["LPT"+string(i)] = true
+	}
+}
+
+// Check reports why name cannot be used as a file name everywhere, or
+// returns nil.
+func Check(name string) error {
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("filename: %q: %s", name, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case name == "", name == ".", name == "..":
+		return bad("not a file name")
+	case len(name) > MaxLength:
+		return bad("%d bytes long, over %d", len(name), MaxLength)
+	case !utf8.ValidString(name):
+		return bad("not valid UTF-8")
+	}
+	for _, r := range name {
+		switch {
+		case r == '/' || r == 0:
+			return bad("contains %q", r)
+		case r < 0x20 || r == 0x7F:
+			return bad("contains the control character %U", r)
+		case strings.ContainsRune(reserved, r):
+			return bad("contains %q, reserved on Windows", r)
+		}
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return bad("ends in a dot or space, which Windows drops")
+	}
+	stem, _, _ := strings.Cut(name, ".")
+	if devices[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		return bad("a device name reserved on Windows")
+	}
+	return nil
+}
+
+// Sanitize makes a metadata value safe to embed in a name: characters
+// Check rejects become "_". The name as a whole still needs Check, for
+// its length and ending and for the parts that are not values.
+func Sanitize(value string) string {
+	value = strings.ToValidUTF8(value, "_")
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r < 0x20 || r == 0x7F || strings.ContainsRune(reserved, r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// Key folds name for comparing names as case-insensitive file systems,
+// the default on macOS and Windows, do: names of equal keys are the same
+// file there.
+func Key(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,344 @@
+// Package film reads the roll logs film shooters keep and turns each
+// logged frame into EXIF metadata and an XMP packet for its scan.
+package film
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/yaml"
+)
+
+// Namespace is the XMP namespace holding film-specific properties.
+const Namespace = "https://github.com/ryoh827/shootlog/ns/film/1.0/"
+
+// Frame is one exposure from a roll log.
+type Frame struct {
+	Number string
+	Make   string
+	Camera string
+	Lens   string
+	// Stock is the film, e.g. "Kodak Portra 400".
+	Stock string
+	// ISO is the speed the roll was exposed at.
+	ISO int
+	// Shutter is in seconds.
+	Shutter     float64
+	Aperture    float64
+	FocalLength float64
+	// Date is formatted like exif.Summary.DateTimeOriginal.
+	Date  string
+	Notes string
+}
+
+// Roll is a parsed roll log.
+type Roll struct {
+	ID     string
+	Frames []Frame
+}
+
+// keys lists the accepted columns (CSV) and keys (YAML). The YAML format
+// also accepts every key except frame at the top level, as a default for
+// all frames, plus roll for the roll ID.
+var keys = []string{"frame", "make", "camera", "lens", "film", "iso", "shutter", "aperture", "focal_length", "date", "notes"}
+
+// Load reads a roll log, choosing the format from the file extension:
+// .csv, or .yaml/.yml.
+func Load(path string) (*Roll, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r *Roll
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		r, err = ParseCSV(strings.NewReader(string(data)))
+	case ".yaml", ".yml":
+		r, err = ParseYAML(data)
+	default:
+		return nil, fmt.Errorf("film: %s: unsupported log format (want .csv or .yaml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ParseCSV parses a log with a header row naming the columns in keys.
+func ParseCSV(r io.Reader) (*Roll, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("film: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("film: empty log")
+	}
+	header := records[0]
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+		if !validKey(header[i]) {
+			return nil, fmt.Errorf("film: unknown column %q", h)
+		}
+	}
+	roll := &Roll{}
+	for n, rec := range records[1:] {
+		row := map[string]string{}
+		for i, v := range rec {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		f, err := parseFrame(row, nil)
+		if err != nil {
+			return nil, fmt.Errorf("film: row %d: %w", n+2, err)
+		}
+		roll.Frames = append(roll.Frames, f)
+	}
+	return roll, nil
+}
+
+// ParseYAML parses a log holding roll-wide defaults and a frames list.
+func ParseYAML(data []byte) (*Roll, error) {
+	doc, err := yaml.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("film: log must be a mapping with a frames list")
+	}
+	defaults := map[string]string{}
+	roll := &Roll{}
+	for k, v := range top {
+		switch {
+		case k == "roll":
+			roll.ID = scalarString(v)
+		case k == "frames":
+		case k != "frame" && validKey(k):
+			defaults[k] = scalarString(v)
+		default:
+			return nil, fmt.Errorf("film: unknown key %q", k)
+		}
+	}
+	frames, ok := top["frames"].([]any)
+	if !ok {
+		return nil, errors.New("film: missing frames list")
+	}
+	for i, item := range frames {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("film: frame %d: expected a mapping", i+1)
+		}
+		row := map[string]string{}
+		for k, v := range m {
+			if !validKey(k) {
+				return nil, fmt.Errorf("film: frame %d: unknown key %q", i+1, k)
+			}
+			row[k] = scalarString(v)
+		}
+		f, err := parseFrame(row, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("film: frame %d: %w", i+1, err)
+		}
+		roll.Frames = append(roll.Frames, f)
+	}
+	return roll, nil
+}
+
+func validKey(k string) bool {
+	for _, key := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// parseFrame converts a row of raw values, falling back to defaults for
+// missing or empty keys.
+func parseFrame(row, defaults map[string]string) (Frame, error) {
+	get := func(k string) string {
+		if v := row[k]; v != "" {
+			return v
+		}
+		return defaults[k]
+	}
+	f := Frame{
+		Number: get("frame"),
+		Make:   get("make"),
+		Camera: get("camera"),
+		Lens:   get("lens"),
+		Stock:  get("film"),
+		Notes:  get("notes"),
+	}
+	var err error
+	if v := get("iso"); v != "" {
+		if f.ISO, err = strconv.Atoi(v); err != nil || f.ISO <= 0 {
+			return f, fmt.Errorf("invalid iso %q", v)
+		}
+	}
+	if v := get("shutter"); v != "" {
+		if f.Shutter, err = ParseShutter(v); err != nil {
+			return f, err
+		}
+	}
+	if v := get("aperture"); v != "" {
+		if f.Aperture, err = parseNumber(v, "f/", "f"); err != nil {
+			return f, fmt.Errorf("invalid aperture %q", v)
+		}
+	}
+	if v := get("focal_length"); v != "" {
+		if f.FocalLength, err = parseNumber(strings.TrimSuffix(v, "mm"), ""); err != nil {
+			return f, fmt.Errorf("invalid focal_length %q", v)
+		}
+	}
+	if v := get("date"); v != "" {
+		if f.Date, err = parseDate(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// ParseShutter parses a shutter speed such as "1/125", "2", "2s" or "1/4s"
+// into seconds.
+func ParseShutter(v string) (float64, error) {
+	s := strings.TrimSuffix(strings.TrimSpace(v), "s")
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 == nil && err2 == nil && n > 0 && d > 0 {
+			return n / d, nil
+		}
+	} else if sec, err := strconv.ParseFloat(s, 64); err == nil && sec > 0 {
+		return sec, nil
+	}
+	return 0, fmt.Errorf("invalid shutter %q", v)
+}
+
+func parseNumber(v string, prefixes ...string) (float64, error) {
+	v = strings.TrimSpace(v)
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(strings.ToLower(v), p) {
+			v = v[len(p):]
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) {
+		return 0, errors.New("invalid number")
+	}
+	return n, nil
+}
+
+// dateLayouts are the accepted date formats, most specific first.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006:01:02 15:04:05",
+}
+
+func parseDate(v string) (string, error) {
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, v)
+		if err != nil {
+			continue
+		}
+		out := t.Format("2006-01-02T15:04:05")
+		if layout == time.RFC3339 {
+			out += t.Format("-07:00")
+		}
+		return out, nil
+	}
+	return "", fmt.Errorf("invalid date %q", v)
+}
+
+// Summary returns the EXIF fields recorded for the frame. Make defaults to
+// the first word of the camera name.
+func (f *Frame) Summary() *exif.Summary {
+	s := &exif.Summary{
+		Make:             f.Make,
+		Model:            f.Camera,
+		LensModel:        f.Lens,
+		ISO:              f.ISO,
+		ExposureTime:     f.Shutter,
+		FNumber:          f.Aperture,
+		FocalLength:      f.FocalLength,
+		DateTimeOriginal: f.Date,
+		Comment:          f.Notes,
+	}
+	if s.Make == "" {
+		s.Make, _, _ = strings.Cut(f.Camera, " ")
+	}
+	return s
+}
+
+// XMP returns an XMP packet describing frame f of the roll in Namespace.
+func (r *Roll) XMP(f *Frame) []byte {
+	props := map[string]string{
+		"Roll":   r.ID,
+		"Frame":  f.Number,
+		"Stock":  f.Stock,
+		"Camera": f.Camera,
+		"Lens":   f.Lens,
+		"Notes":  f.Notes,
+	}
+	if f.ISO > 0 {
+		props["ISO"] = strconv.Itoa(f.ISO)
+	}
+	names := make([]string, 0, len(props))
+	for k, v := range props {
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:film=\"" + Namespace + "\">\n")
+	for _, k := range names {
+		fmt.Fprintf(&b, "   <film:%s>%s</film:%s>\n", k, escape(props[k]), k)
+	}
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return []byte(b.String())
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,579 @@
+// Package filter selects photos with boolean expressions over their
+// summary fields, addressed by JSON name:
+//
+//	moon_phase < 0.1 && iso >= 1600
+//	make = Fujifilm || (lens_model ~ "*35mm*" && !latitude)
+//
+// A comparison is field, operator and value, and the field decides how
+// they compare. Numeric fields take numbers, rationals like 1/100
+// included, with =, !=, <, <=, > and >=. Text fields take = and !=,
+// ignoring case, and ~, a case-insensitive glob. Dates and times take the
+// operators of numbers and ~; a bound of a day, such as 2024-05-01, or a
+// minute, such as 2024-05-01T18:30, stands for all of it, and times
+// compare as the camera's clock read, whatever their offset. edited takes
+// true or false with = and !=. A value the field cannot compare with, or
+// an operator it does not take, is an error. On a list field such as
+// keywords, comparisons test any element and != tests that none equals
+// the value. A field on its own tests that it is set. Comparisons with an
+// unset field are false, except !=. Terms combine with !, && and || and
+// group with parentheses.
+//
+// The catalog query syntax is accepted as well: AND, OR and NOT in any
+// case stand for &&, || and !, field BETWEEN a AND b tests a <= field <= b,
+// camera and lens name the model and lens_model fields, and date is the
+// day of datetime_original:
+//
+//	camera = 'X-T5' AND focal_length_35mm BETWEEN 23 AND 35 AND date >= 2024-01-01
+package filter
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Expr is a parsed filter expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+type node interface {
+	eval(fields map[string]any) bool
+}
+
+// A kind is how a field's values compare.
+type kind int
+
+const (
+	kindText kind = iota
+	kindNumber
+	kindTime
+	kindBool
+)
+
+func (k kind) String() string {
+	return [...]string{"text", "a number", "a date", "true or false"}[k]
+}
+
+// timeFields are the text fields holding a date or time as 2006-01-02 or
+// 2006-01-02T15:04:05, followed by anything, such as a UTC offset.
+var timeFields = []string{"datetime_original", "inferred_date", "gps_time", "date"}
+
+// kinds holds the fields expressions may use, with the kind of their
+// values, or of their elements for lists. Aliases are resolved first.
+var kinds = func() map[string]kind {
+	m := map[string]kind{}
+	t := reflect.TypeOf(exif.Summary{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "sources" {
+			continue
+		}
+		typ := t.Field(i).Type
+		if typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Int, reflect.Float64:
+			m[name] = kindNumber
+		case reflect.Bool:
+			m[name] = kindBool
+		default:
+			m[name] = kindText
+		}
+	}
+	for _, f := range IndexFields {
+		m[f] = kindText
+	}
+	for _, f := range timeFields {
+		m[f] = kindTime
+	}
+	return m
+}()
+
+// IndexFields are the fields shootlog query adds from the library index
+// to MatchFields. They are unset elsewhere.
+var IndexFields = []string{"backups"}
+
+// aliases maps the shorthand field names of queries to summary fields.
+var aliases = map[string]string{
+	"camera": "model",
+	"lens":   "lens_model",
+}
+
+// derived holds the fields computed from other fields.
+var derived = map[string]func(fields map[string]any) any{
+	// datetime_original starts with the date as 2006-01-02.
+	"date": func(f map[string]any) any {
+		if s, ok := f["datetime_original"].(string); ok && len(s) >= 10 {
+			return s[:10]
+		}
+		return nil
+	},
+}
+
+// lookup returns the value of field in f, computing derived fields.
+func lookup(f map[string]any, field string) any {
+	if d, ok := derived[field]; ok {
+		return d(f)
+	}
+	return f[field]
+}
+
+// Parse parses an expression, rejecting unknown fields.
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression as written.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match reports whether s satisfies the expression.
+func (e *Expr) Match(s *exif.Summary) bool {
+	return e.root.eval(s.Fields())
+}
+
+// MatchFields is Match for fields already rendered by Summary.Fields.
+func (e *Expr) MatchFields(fields map[string]any) bool {
+	return e.root.eval(fields)
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// lex splits src into tokens. Words run until whitespace, a parenthesis
+// or an operator character, so unquoted values like golden-hour and
+// 2024-05-01 stay whole.
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			toks = append(toks, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, token{tokOr, "||", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "!="), strings.HasPrefix(src[i:], "<="),
+			strings.HasPrefix(src[i:], ">="), strings.HasPrefix(src[i:], "=="):
+			toks = append(toks, token{tokOp, src[i : i+2], i})
+			i += 2
+		case c == '!':
+			toks = append(toks, token{tokNot, "!", i})
+			i++
+		case c == '=' || c == '<' || c == '>' || c == '~':
+			toks = append(toks, token{tokOp, src[i : i+1], i})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				b.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, fmt.Errorf("filter: unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{tokString, b.String(), i})
+			i = j + 1
+		default:
+			j := i
+			for j < len(src) && !strings.ContainsRune(" \t\n()!=<>~&|\"'", rune(src[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("filter: unexpected %q at offset %d", src[i], i)
+			}
+			toks = append(toks, token{tokWord, src[i:j], i})
+			i = j
+		}
+	}
+	return append(toks, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+type parser struct {
+	src  string
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("filter: offset %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+// keyword reports whether the next token is the word kw, in any case.
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && (p.peek().kind == tokOr || p.keyword("or")) {
+		p.next()
+		var right node
+		if right, err = p.and(); err == nil {
+			left = orNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	for err == nil && (p.peek().kind == tokAnd || p.keyword("and")) {
+		p.next()
+		var right node
+		if right, err = p.unary(); err == nil {
+			left = andNode{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.keyword("not") {
+		p.next()
+		n, err := p.unary()
+		return notNode{n}, err
+	}
+	switch t := p.next(); t.kind {
+	case tokNot:
+		n, err := p.unary()
+		return notNode{n}, err
+	case tokLParen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, p.errorf(t, "want ), got %q", t.text)
+		}
+		return n, nil
+	case tokWord:
+		field := t.text
+		if f, ok := aliases[field]; ok {
+			field = f
+		}
+		if _, ok := kinds[field]; !ok {
+			if tag, ok := exif.TagByName(t.text); ok && len(tag.Fields) > 0 {
+				return nil, p.errorf(t, "unknown field %q; tag %s is read into %s", t.text, tag.Name, strings.Join(tag.Fields, ", "))
+			}
+			return nil, p.errorf(t, "unknown field %q", t.text)
+		}
+		if p.keyword("between") {
+			p.next()
+			lo, err := p.value(field, ">=")
+			if err != nil {
+				return nil, err
+			}
+			if !p.keyword("and") {
+				t := p.peek()
+				return nil, p.errorf(t, "want AND in BETWEEN, got %q", t.text)
+			}
+			p.next()
+			hi, err := p.value(field, "<=")
+			if err != nil {
+				return nil, err
+			}
+			return andNode{lo, hi}, nil
+		}
+		if p.peek().kind != tokOp {
+			return setNode{field}, nil
+		}
+		op := p.next().text
+		if op == "==" {
+			op = "="
+		}
+		return p.value(field, op)
+	default:
+		return nil, p.errorf(t, "want a field, ! or (, got %q", t.text)
+	}
+}
+
+// value parses the value of a comparison of field with op, as the kind of
+// the field takes it.
+func (p *parser) value(field, op string) (node, error) {
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, p.errorf(v, "want a value after %s, got %q", op, v.text)
+	}
+	k := kinds[field]
+	c := cmpNode{field: field, op: op, kind: k, text: v.text}
+	if op == "~" {
+		if k != kindText && k != kindTime {
+			return nil, p.errorf(v, "%s is %s; ~ matches text", field, k)
+		}
+		if _, err := path.Match(v.text, ""); err != nil {
+			return nil, p.errorf(v, "invalid pattern %q", v.text)
+		}
+		c.kind = kindText
+		return c, nil
+	}
+	var ok bool
+	switch k {
+	case kindNumber:
+		c.num, ok = number(v.text)
+	case kindTime:
+		c.lo, c.hi, ok = bound(v.text)
+	case kindBool:
+		if op != "=" && op != "!=" {
+			return nil, p.errorf(v, "%s is true or false; %s compares numbers and dates", field, op)
+		}
+		c.b, ok = parseBool(v.text)
+	case kindText:
+		if op != "=" && op != "!=" {
+			return nil, p.errorf(v, "%s is text; %s compares numbers and dates", field, op)
+		}
+		ok = true
+	}
+	if !ok {
+		return nil, p.errorf(v, "%s is %s, not %q", field, k, v.text)
+	}
+	return c, nil
+}
+
+// number parses a decimal number or a rational such as 1/100.
+func number(s string) (float64, bool) {
+	num, den, isRat := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, false
+	}
+	if isRat {
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+		n /= d
+	}
+	return n, true
+}
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// clock is the layout dates and times compare in: the camera's clock,
+// without an offset.
+const clock = "2006-01-02T15:04:05"
+
+// bound parses a date or time value as the first and last second it
+// stands for: a day, a minute or a second.
+func bound(s string) (lo, hi string, ok bool) {
+	for _, b := range []struct{ layout, first, last string }{
+		{"2006-01-02", "T00:00:00", "T23:59:59"},
+		{"2006-01-02T15:04", ":00", ":59"},
+		{clock, "", ""},
+	} {
+		if _, err := time.Parse(b.layout, s); err == nil {
+			return s + b.first, s + b.last, true
+		}
+	}
+	return "", "", false
+}
+
+// span returns the seconds a date or time field's value covers: a day for
+// a date without a time, one second otherwise.
+func span(s string) (lo, hi string, ok bool) {
+	if len(s) >= len(clock) && (s[10] == 'T' || s[10] == ' ') {
+		t := s[:10] + "T" + s[11:len(clock)]
+		_, err := time.Parse(clock, t)
+		return t, t, err == nil
+	}
+	if len(s) == len("2006-01-02") {
+		return bound(s)
+	}
+	return "", "", false
+}
+
+type orNode struct{ a, b node }
+
+func (n orNode) eval(f map[string]any) bool { return n.a.eval(f) || n.b.eval(f) }
+
+type andNode struct{ a, b node }
+
+func (n andNode) eval(f map[string]any) bool { return n.a.eval(f) && n.b.eval(f) }
+
+type notNode struct{ n node }
+
+func (n notNode) eval(f map[string]any) bool { return !n.n.eval(f) }
+
+type setNode struct{ field string }
+
+func (n setNode) eval(f map[string]any) bool {
+	v := lookup(f, n.field)
+	return v != "" && v != nil
+}
+
+type cmpNode struct {
+	field string
+	op    string
+	kind  kind
+	text  string
+	num   float64
+	// lo and hi are the first and last second of a date or time bound,
+	// formatted as clock.
+	lo, hi string
+	b      bool
+}
+
+func (n cmpNode) eval(f map[string]any) bool {
+	v := lookup(f, n.field)
+	if v == nil && n.kind == kindBool {
+		v = false // summaries leave out false
+	}
+	if v == nil || v == "" {
+		return n.op == "!="
+	}
+	if list, ok := v.([]any); ok {
+		// != on a list means no element equals the value.
+		op := n.op
+		if op == "!=" {
+			op = "="
+		}
+		found := false
+		for _, e := range list {
+			if n.compare(e, op) {
+				found = true
+				break
+			}
+		}
+		return found != (n.op == "!=")
+	}
+	return n.compare(v, n.op)
+}
+
+// compare applies op to a single value. Values of another kind than the
+// comparison's, which summaries do not hold, compare as unequal.
+func (n cmpNode) compare(v any, op string) bool {
+	switch n.kind {
+	case kindNumber:
+		x, ok := v.(float64)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "=":
+			return x == n.num
+		case "!=":
+			return x != n.num
+		case "<":
+			return x < n.num
+		case "<=":
+			return x <= n.num
+		case ">":
+			return x > n.num
+		case ">=":
+			return x >= n.num
+		}
+	case kindTime:
+		s, _ := v.(string)
+		lo, hi, ok := span(s)
+		if !ok {
+			return op == "!="
+		}
+		// The field's span against the bound's: < when it ends before the
+		// bound starts, = when they overlap, > when it starts after the
+		// bound ends.
+		switch op {
+		case "=":
+			return lo <= n.hi && n.lo <= hi
+		case "!=":
+			return lo > n.hi || n.lo > hi
+		case "<":
+			return hi < n.lo
+		case "<=":
+			return lo <= n.hi
+		case ">":
+			return lo > n.hi
+		case ">=":
+			return hi >= n.lo
+		}
+	case kindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return op == "!="
+		}
+		return (b == n.b) == (op == "=")
+	case kindText:
+		s, ok := v.(string)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "=":
+			return strings.EqualFold(s, n.text)
+		case "!=":
+			return !strings.EqualFold(s, n.text)
+		case "~":
+			ok, _ := path.Match(strings.ToLower(n.text), strings.ToLower(s))
+			return ok
+		}
+	}
+	return false
+}
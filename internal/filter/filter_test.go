@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func TestMatch(t *testing.T) {
+	lat := 35.0
+	s := &exif.Summary{
+		Make:             "FUJIFILM",
+		Model:            "X-T5",
+		ISO:              1600,
+		ExposureTime:     0.01,
+		Latitude:         &lat,
+		Keywords:         []string{"travel", "Kyoto"},
+		Edited:           true,
+		DateTimeOriginal: "2024-05-01T18:30:15+09:00",
+		InferredDate:     "2023-12-31",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"iso >= 1600", true},
+		{"iso > 1600", false},
+		{"iso = 1600.0", true},
+		{"exposure_time = 1/100", true},
+		{"exposure_time < 1/60", true},
+		{"exposure_time BETWEEN 1/250 AND 1/125", false},
+		{"make = fujifilm", true},
+		{"make != Canon", true},
+		{"camera ~ 'x-t*'", true},
+		{"keywords = kyoto", true},
+		{"keywords != travel", false},
+		{"latitude && !longitude", true},
+		{"edited = true", true},
+		{"edited != TRUE", false},
+
+		// A day stands for all of it, on either side.
+		{"datetime_original = 2024-05-01", true},
+		{"datetime_original <= 2024-05-01", true},
+		{"datetime_original >= 2024-05-01", true},
+		{"datetime_original < 2024-05-01", false},
+		{"datetime_original > 2024-05-01", false},
+		{"datetime_original != 2024-05-01", false},
+		{"datetime_original BETWEEN 2024-01-01 AND 2024-05-01", true},
+		{"datetime_original > 2024-04-30", true},
+		{"datetime_original = 2024-05-01T18:30", true},
+		{"datetime_original < 2024-05-01T18:30:15", false},
+		{"datetime_original >= 2024-05-01T18:30:15", true},
+		{"datetime_original > 2024-05-01T18:30", false},
+		{"datetime_original ~ '2024-05-*'", true},
+		{"date = 2024-05-01", true},
+		{"date < 2024-05-01T12:00", false},
+		{"date <= 2024-05-01T12:00", true},
+		{"date > 2024-05-01T12:00", false},
+		{"date >= 2024-05-01T12:00", true},
+		{"inferred_date = 2023-12-31T09:00", true},
+		{"inferred_date < 2024-01-01", true},
+		{"gps_time < 2030-01-01", false},
+		{"gps_time != 2030-01-01", true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Match(s); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestMatchUnset(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"iso < 100", false},
+		{"iso != 100", true},
+		{"edited = false", true},
+		{"edited = true", false},
+		{"date = 2024-05-01", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := e.Match(&exif.Summary{}); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"iso >= high", `iso is a number, not "high"`},
+		{"exposure_time < 1/0", `exposure_time is a number, not "1/0"`},
+		{"iso ~ '16*'", "~ matches text"},
+		{"make < M", "make is text; < compares numbers and dates"},
+		{"lens BETWEEN a AND b", "lens_model is text; >= compares numbers and dates"},
+		{"date >= yesterday", `date is a date, not "yesterday"`},
+		{"datetime_original = 2024-05-01T18:30:15+09:00", "is a date, not"},
+		{"edited = yes", `edited is true or false, not "yes"`},
+		{"edited > false", "edited is true or false; > compares"},
+		{"shutter = 1/100", `unknown field "shutter"`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): got error %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
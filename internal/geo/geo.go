@@ -0,0 +1,172 @@
+// Package geo formats coordinates as decimal degrees, degrees-minutes-
+// seconds, geohashes and Open Location Codes (Plus Codes), optionally
+// truncated to a coarser precision.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Formats accepted by Format.
+const (
+	Decimal  = "decimal"
+	DMS      = "dms"
+	Geohash  = "geohash"
+	PlusCode = "pluscode"
+)
+
+// Formats lists the accepted formats, default first.
+var Formats = []string{Decimal, DMS, Geohash, PlusCode}
+
+// Full is the precision that keeps coordinates as recorded.
+const Full = -1
+
+// Truncate cuts v to digits decimal places toward zero, so the result
+// never points more precisely than requested. Full returns v.
+func Truncate(v float64, digits int) float64 {
+	if digits < 0 {
+		return v
+	}
+	p := math.Pow(10, float64(digits))
+	return math.Trunc(v*p) / p
+}
+
+// Format renders a coordinate pair. digits is the number of decimal
+// places of degrees to keep, or Full. Geohashes and Plus Codes use the
+// longest code whose cells are no smaller than that precision.
+func Format(lat, lon float64, format string, digits int) (string, error) {
+	lat, lon = Truncate(lat, digits), Truncate(lon, digits)
+	switch format {
+	case Decimal:
+		return fmt.Sprintf("%s,%s", decimal(lat), decimal(lon)), nil
+	case DMS:
+		dec := 2
+		if digits >= 0 {
+			// 0.0001° is about a third of a second.
+			dec = max(digits-4, 0)
+		}
+		return dms(lat, "N", "S", dec) + " " + dms(lon, "E", "W", dec), nil
+	case Geohash:
+		n := 9
+		if digits >= 0 {
+			n = geohashLength(digits)
+		}
+		return EncodeGeohash(lat, lon, n), nil
+	case PlusCode:
+		n := 10
+		if digits >= 0 {
+			n = plusCodeLength(digits)
+		}
+		return EncodePlusCode(lat, lon, n), nil
+	}
+	return "", fmt.Errorf("geo: unknown format %q (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+func decimal(v float64) string {
+	return fmt.Sprint(v)
+}
+
+func dms(v float64, pos, neg string, decimals int) string {
+	hemi := pos
+	if v < 0 {
+		hemi, v = neg, -v
+	}
+	scale := math.Pow(10, float64(decimals))
+	// Work in whole units of the last printed digit so no component rounds
+	// up to 60.
+	units := int64(math.Floor(v * 3600 * scale))
+	perMinute, perDegree := int64(60*scale), int64(3600*scale)
+	deg := units / perDegree
+	min := units % perDegree / perMinute
+	sec := float64(units%perMinute) / scale
+	return fmt.Sprintf("%d°%d'%.*f\"%s", deg, min, decimals, sec, hemi)
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of length n (1-12) containing the
+// coordinates.
+func EncodeGeohash(lat, lon float64, n int) string {
+	n = min(max(n, 1), 12)
+	latR, lonR := [2]float64{-90, 90}, [2]float64{-180, 180}
+	var b strings.Builder
+	bit, ch, even := 0, 0, true
+	for b.Len() < n {
+		r, v := &latR, lat
+		if even {
+			r, v = &lonR, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			b.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return b.String()
+}
+
+// geohashLength is the longest geohash whose cells are at least 10^-digits
+// degrees on both axes.
+func geohashLength(digits int) int {
+	cell := math.Pow(10, -float64(digits))
+	n := 1
+	for n < 12 {
+		bits := 5 * (n + 1)
+		latCell := 180 / math.Pow(2, float64(bits/2))
+		lonCell := 360 / math.Pow(2, float64(bits-bits/2))
+		if min(latCell, lonCell) < cell {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+const plusCodeAlphabet = "23456789CFGHJMPQRVWX"
+
+// plusCodeScale is the number of finest (10-digit) cells per degree.
+const plusCodeScale = 8000
+
+// EncodePlusCode returns the Open Location Code of n digits (2, 4, 6, 8
+// or 10) containing the coordinates. Codes shorter than 8 digits are
+// padded with zeros, as the specification requires.
+func EncodePlusCode(lat, lon float64, n int) string {
+	n = min(max(n-n%2, 2), 10)
+	lat = math.Min(math.Max(lat, -90), 90)
+	lon = math.Mod(math.Mod(lon+180, 360)+360, 360)
+	latVal := min(int64(math.Floor((lat+90)*plusCodeScale)), 180*plusCodeScale-1)
+	lonVal := int64(math.Floor(lon * plusCodeScale))
+	var digits [10]byte
+	for i := 4; i >= 0; i-- {
+		digits[2*i] = plusCodeAlphabet[latVal%20]
+		digits[2*i+1] = plusCodeAlphabet[lonVal%20]
+		latVal /= 20
+		lonVal /= 20
+	}
+	code := string(digits[:n])
+	if n < 8 {
+		return code + strings.Repeat("0", 8-n) + "+"
+	}
+	return code[:8] + "+" + code[8:]
+}
+
+// plusCodeLength is the longest code whose cells are at least 10^-digits
+// degrees.
+func plusCodeLength(digits int) int {
+	cell := math.Pow(10, -float64(digits))
+	n, size := 2, 20.0
+	for n < 10 && size/20 >= cell {
+		n, size = n+2, size/20
+	}
+	return n
+}
@@ -0,0 +1,21 @@
+package geo
+
+import "math"
+
+// TileSize is the edge of a web map tile in pixels.
+const TileSize = 256
+
+// maxMercatorLat is the latitude where the Web Mercator square ends.
+const maxMercatorLat = 85.05112878
+
+// Project returns the position of a point in pixels of the whole world
+// map at zoom, in the Web Mercator projection slippy map tiles use: x
+// grows east from the antimeridian and y south from the top edge.
+func Project(lat, lon float64, zoom int) (x, y float64) {
+	lat = math.Min(math.Max(lat, -maxMercatorLat), maxMercatorLat)
+	size := float64(TileSize) * math.Exp2(float64(zoom))
+	sin := math.Sin(lat * math.Pi / 180)
+	x = (lon + 180) / 360 * size
+	y = (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * size
+	return x, y
+}
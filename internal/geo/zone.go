@@ -0,0 +1,45 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+// Distance returns the great-circle distance in meters between two
+// points, by the haversine formula.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// Zone is a circular area: Radius meters around a center.
+type Zone struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
+
+// Contains reports whether the point lies within the zone.
+func (z *Zone) Contains(lat, lon float64) bool {
+	return Distance(z.Latitude, z.Longitude, lat, lon) <= z.Radius
+}
+
+// Validate reports centers off the globe and non-positive radii.
+func (z *Zone) Validate() error {
+	switch {
+	case z.Latitude < -90 || z.Latitude > 90:
+		return fmt.Errorf("zone %q: latitude %v out of range", z.Name, z.Latitude)
+	case z.Longitude < -180 || z.Longitude > 180:
+		return fmt.Errorf("zone %q: longitude %v out of range", z.Name, z.Longitude)
+	case z.Radius <= 0:
+		return fmt.Errorf("zone %q: radius must be positive", z.Name)
+	}
+	return nil
+}
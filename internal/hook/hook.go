@@ -0,0 +1,109 @@
+// Package hook runs external commands for processed files, with the
+// file's metadata substituted into the command line.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/policy"
+)
+
+// Command is a parsed command template such as "convert {path} {iso}".
+type Command struct {
+	args []string
+}
+
+// Parse splits tmpl into words the way a POSIX shell would, honoring
+// single quotes, double quotes and backslash escapes, but without running
+// a shell. Placeholders are substituted per word, so values containing
+// spaces or shell metacharacters stay a single argument.
+func Parse(tmpl string) (*Command, error) {
+	var (
+		args    []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range tmpl {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("hook: unterminated quote or escape in %q", tmpl)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("hook: empty command")
+	}
+	return &Command{args: args}, nil
+}
+
+// Args returns the command line for s: every word with its {name}
+// placeholders replaced by summary fields, as in policy.Expand.
+func (c *Command) Args(s *exif.Summary) []string {
+	args := make([]string, len(c.args))
+	for i, a := range c.args {
+		args[i] = policy.Expand(a, s)
+	}
+	return args
+}
+
+// Run runs the command for s. The summary is written to its standard
+// input as JSON; its output goes to stdout and stderr.
+func (c *Command) Run(ctx context.Context, s *exif.Summary, stdout, stderr io.Writer) error {
+	args := c.Args(s)
+	input, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook: %s: %w", args[0], err)
+	}
+	return nil
+}
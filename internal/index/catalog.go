@@ -0,0 +1,121 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/sqlite"
+)
+
+// The tables of the catalog. Summaries are stored as JSON, which SQLite
+// queries with json_extract, so the schema does not change with them.
+var (
+	filesColumns = []string{"path TEXT NOT NULL", "size INTEGER", "mod_time TEXT", "sha256 TEXT", "summary TEXT", "error TEXT", "backups TEXT"}
+	runsColumns  = []string{"time TEXT", "duration INTEGER", "added INTEGER", "modified INTEGER", "moved INTEGER", "removed INTEGER", "touched INTEGER", "unchanged INTEGER"}
+)
+
+// Marshal encodes the index for saving, as a SQLite database with a files
+// and a runs table whose user_version is Version.
+func (ix *Index) Marshal() ([]byte, error) {
+	files := make([][]any, 0, len(ix.Files))
+	for _, f := range ix.Files {
+		var summary, backups any
+		if f.Summary != nil {
+			b, err := json.Marshal(f.Summary)
+			if err != nil {
+				return nil, fmt.Errorf("index: %s: %w", f.Path, err)
+			}
+			summary = string(b)
+		}
+		if len(f.Backups) > 0 {
+			b, _ := json.Marshal(f.Backups)
+			backups = string(b)
+		}
+		files = append(files, []any{f.Path, f.Size, f.ModTime.Format(time.RFC3339Nano), f.SHA256, summary, nullString(f.Error), backups})
+	}
+	runs := make([][]any, 0, len(ix.Runs))
+	for _, r := range ix.Runs {
+		c := r.Churn
+		runs = append(runs, []any{r.Time.Format(time.RFC3339Nano), int64(r.Duration),
+			int64(c.Added), int64(c.Modified), int64(c.Moved), int64(c.Removed), int64(c.Touched), int64(c.Unchanged)})
+	}
+	return sqlite.Build(Version,
+		sqlite.NewTable{Name: "files", Columns: filesColumns, Rows: files},
+		sqlite.NewTable{Name: "runs", Columns: runsColumns, Rows: runs})
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// decode reads an index file: a catalog, or the JSON file of version 1,
+// which the next save turns into a catalog.
+func decode(data []byte) (*Index, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var ix Index
+		if err := json.Unmarshal(data, &ix); err != nil {
+			return nil, err
+		}
+		if ix.Version != 1 {
+			return nil, fmt.Errorf("unsupported version %d", ix.Version)
+		}
+		ix.Version = Version
+		return &ix, nil
+	}
+	db, err := sqlite.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if v := db.UserVersion(); v != Version {
+		return nil, fmt.Errorf("unsupported version %d", v)
+	}
+	files, runs := db.Table("files"), db.Table("runs")
+	if files == nil || runs == nil {
+		return nil, fmt.Errorf("%w: not an index catalog", sqlite.ErrFormat)
+	}
+	ix := &Index{Version: Version}
+	err = files.Rows(func(r sqlite.Row) error {
+		f := &File{Path: r.String("path"), Size: r.Int("size"), SHA256: r.String("sha256"), Error: r.String("error")}
+		var err error
+		if f.ModTime, err = time.Parse(time.RFC3339Nano, r.String("mod_time")); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		if s := r.String("summary"); s != "" {
+			f.Summary = &exif.Summary{}
+			if err := json.Unmarshal([]byte(s), f.Summary); err != nil {
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+		}
+		if s := r.String("backups"); s != "" {
+			if err := json.Unmarshal([]byte(s), &f.Backups); err != nil {
+				return fmt.Errorf("%s: %w", f.Path, err)
+			}
+		}
+		ix.Files = append(ix.Files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = runs.Rows(func(r sqlite.Row) error {
+		t, err := time.Parse(time.RFC3339Nano, r.String("time"))
+		if err != nil {
+			return err
+		}
+		ix.Runs = append(ix.Runs, Run{Time: t, Duration: time.Duration(r.Int("duration")), Churn: Churn{
+			Added: int(r.Int("added")), Modified: int(r.Int("modified")), Moved: int(r.Int("moved")),
+			Removed: int(r.Int("removed")), Touched: int(r.Int("touched")), Unchanged: int(r.Int("unchanged")),
+		}})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
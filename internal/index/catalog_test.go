@@ -0,0 +1,123 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func testIndex() *Index {
+	mod := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+	return &Index{
+		Version: Version,
+		Files: []*File{
+			{Path: "/photos/a.jpg", Size: 1024, ModTime: mod, SHA256: "aa", Summary: &exif.Summary{Path: "/photos/a.jpg", Make: "Canon", Rating: 3}, Backups: []string{"offsite", "usb"}},
+			{Path: "/photos/b.jpg", Size: 7, ModTime: mod.Add(time.Hour), SHA256: "bb", Error: "exif: no EXIF data"},
+		},
+		Runs: []Run{{Time: mod, Duration: 1500 * time.Millisecond, Churn: Churn{Added: 2, Modified: 1, Moved: 3, Removed: 4, Touched: 5, Unchanged: 6}}},
+	}
+}
+
+func TestCatalogRoundTrip(t *testing.T) {
+	legacy := testIndex()
+	legacy.Version = 1
+	legacyJSON, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := testIndex().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := (&Index{Version: Version}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want *Index
+	}{
+		{"catalog", catalog, testIndex()},
+		{"empty catalog", empty, &Index{Version: Version}},
+		{"version 1 JSON", legacyJSON, testIndex()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				g, _ := json.Marshal(got)
+				w, _ := json.Marshal(tt.want)
+				t.Errorf("decoded\n%s\nwant\n%s", g, w)
+			}
+		})
+	}
+}
+
+func TestCatalogVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"future JSON", `{"version":3,"files":[]}`},
+		{"not a database", "GIF89a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decode([]byte(tt.data)); err == nil {
+				t.Error("decoded without error")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	mine := &Index{Version: Version, Runs: []Run{{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}}}
+	theirs := &Index{Version: Version, Runs: []Run{{Time: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)}}}
+	mineData, err := mine.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirsData, err := theirs.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		loaded  []byte // nil for no file
+		current []byte
+		changed bool
+	}{
+		{"unchanged", mineData, mineData, false},
+		{"replaced", mineData, theirsData, true},
+		{"removed", mineData, nil, true},
+		{"still none", nil, nil, false},
+		{"created", nil, theirsData, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "library.db")
+			if tt.loaded != nil {
+				if err := os.WriteFile(path, tt.loaded, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ix, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ix.Check(tt.current); errors.Is(err, ErrChanged) != tt.changed {
+				t.Errorf("Check = %v, want changed %v", err, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+// Package index keeps the summaries of a photo library in a SQLite
+// catalog, so that runs after the first only decode the files that
+// changed. Files are
+// matched by path, size and modification time; when those differ the
+// content hash decides whether the file really changed or was only
+// touched, and finds files that were moved or renamed. The hashes also let
+// Verify detect files whose content changed on disk unnoticed.
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Version is the format version of index files, the user_version of the
+// catalog. Version 1 was a JSON file, which Load still reads.
+const Version = 2
+
+// maxRuns bounds the run history kept in an index.
+const maxRuns = 100
+
+// Index is the state of a library at its last update.
+type Index struct {
+	Version int     `json:"version"`
+	Files   []*File `json:"files"`
+	// Runs are the most recent updates, oldest first.
+	Runs []Run `json:"runs"`
+
+	// read is the SHA-256 of the file Load read, or nil when there was
+	// none.
+	read []byte
+}
+
+// ErrChanged reports an index file replaced since Load read it, usually
+// by another shootlog updating the same index at the same time.
+var ErrChanged = errors.New("index: changed since it was read")
+
+// Check returns ErrChanged unless current, the contents of the file now
+// or nil when there is none, is the file Load read. Writers check just
+// before they replace the file, so that of two updates at once one fails
+// rather than silently discarding the other.
+func (ix *Index) Check(current []byte) error {
+	var sum []byte
+	if current != nil {
+		h := sha256.Sum256(current)
+		sum = h[:]
+	}
+	if !slices.Equal(sum, ix.read) {
+		return ErrChanged
+	}
+	return nil
+}
+
+// File is one indexed file.
+type File struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+	// Summary is the decoded metadata, or nil when decoding failed with
+	// Error. Failed files are retried once they change.
+	Summary *exif.Summary `json:"summary,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	// Backups names the backup sets found to hold this content, sorted.
+	// They carry over while the content stays the same.
+	Backups []string `json:"backups,omitempty"`
+}
+
+// Run records one update.
+type Run struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Churn    Churn         `json:"churn"`
+}
+
+// Churn counts what an update found. Only Added and Modified files are
+// decoded.
+type Churn struct {
+	Added    int `json:"added"`
+	Modified int `json:"modified"`
+	// Moved files were found under a new path with the content of a
+	// removed one; their summaries are kept.
+	Moved   int `json:"moved"`
+	Removed int `json:"removed"`
+	// Touched files have a new size or modification time but the same
+	// content.
+	Touched   int `json:"touched"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Load reads an index file. A missing file is an empty index.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Index{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index: %w", err)
+	}
+	ix, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("index: %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	ix.read = sum[:]
+	return ix, nil
+}
+
+// Summaries returns the summaries of the files that decoded, in path
+// order.
+func (ix *Index) Summaries() []*exif.Summary {
+	var out []*exif.Summary
+	for _, f := range ix.Files {
+		if f.Summary != nil {
+			out = append(out, f.Summary)
+		}
+	}
+	return out
+}
+
+// DefaultWorkers is the number of files Update reads and decodes at once
+// when given no other.
+const DefaultWorkers = 8
+
+// scan is what a worker of Update found for one path.
+type scan struct {
+	file *File
+	err  error
+	// unchanged marks files matching their previous entry by size and
+	// modification time, which are not read.
+	unchanged bool
+	// decoded is false for files whose content matches a removed file
+	// and may be moves. Only their hash is kept, not their content, so
+	// they are read again should they turn out not to be.
+	decoded bool
+}
+
+// Update brings the index in line with paths, the current files of the
+// library, decoding new and changed ones with decode, and records the run.
+// Files are read, hashed and decoded by workers at once, so decode must be
+// safe for concurrent use; the index itself is only changed once all are
+// done. Files that cannot be read are reported in the returned errors and
+// left out of the index.
+func (ix *Index) Update(paths []string, decode func(data []byte) (*exif.Summary, error), workers int) (Churn, []error) {
+	start := time.Now()
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	var churn Churn
+	var errs []error
+	old := make(map[string]*File, len(ix.Files))
+	for _, f := range ix.Files {
+		old[f.Path] = f
+	}
+	current := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		current[p] = true
+	}
+	// Files that disappeared may reappear elsewhere as moves.
+	gone := map[string]*File{}
+	removed := 0
+	for _, f := range ix.Files {
+		if !current[f.Path] {
+			gone[f.SHA256] = f
+			removed++
+		}
+	}
+
+	scans := make([]scan, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(len(paths), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				scans[i] = scanFile(paths[i], old[paths[i]], gone, decode)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	files := make([]*File, 0, len(paths))
+	for i, p := range paths {
+		sc := scans[i]
+		if sc.err != nil {
+			errs = append(errs, sc.err)
+			continue
+		}
+		prev, f := old[p], sc.file
+		switch {
+		case sc.unchanged:
+			churn.Unchanged++
+		case prev != nil && prev.SHA256 == f.SHA256:
+			f.Summary, f.Error, f.Backups = prev.Summary, prev.Error, prev.Backups
+			churn.Touched++
+		case prev == nil && gone[f.SHA256] != nil:
+			moved := gone[f.SHA256]
+			delete(gone, f.SHA256)
+			f.Summary, f.Error, f.Backups = moved.Summary, moved.Error, moved.Backups
+			if f.Summary != nil {
+				f.Summary.Path = p
+			}
+			churn.Moved++
+		default:
+			if !sc.decoded {
+				// A second copy of a moved file.
+				data, err := os.ReadFile(p)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				sum := sha256.Sum256(data)
+				f.Size, f.SHA256 = int64(len(data)), hex.EncodeToString(sum[:])
+				decodeInto(f, data, decode)
+			}
+			if prev == nil {
+				churn.Added++
+			} else {
+				churn.Modified++
+			}
+		}
+		files = append(files, f)
+	}
+	churn.Removed = removed - churn.Moved
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	ix.Files = files
+	ix.Runs = append(ix.Runs, Run{Time: start.UTC(), Duration: time.Since(start), Churn: churn})
+	if len(ix.Runs) > maxRuns {
+		ix.Runs = ix.Runs[len(ix.Runs)-maxRuns:]
+	}
+	return churn, errs
+}
+
+// scanFile stats, reads and hashes the file at p, whose previous entry is
+// prev, and decodes it unless its content is that of prev or of a file in
+// gone, which Update resolves in path order.
+func scanFile(p string, prev *File, gone map[string]*File, decode func([]byte) (*exif.Summary, error)) scan {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return scan{err: err}
+	}
+	if prev != nil && prev.Size == fi.Size() && prev.ModTime.Equal(fi.ModTime()) {
+		return scan{file: prev, unchanged: true}
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return scan{err: err}
+	}
+	sum := sha256.Sum256(data)
+	f := &File{Path: p, Size: fi.Size(), ModTime: fi.ModTime(), SHA256: hex.EncodeToString(sum[:])}
+	switch {
+	case prev != nil && prev.SHA256 == f.SHA256:
+		return scan{file: f}
+	case prev == nil && gone[f.SHA256] != nil:
+		return scan{file: f}
+	}
+	decodeInto(f, data, decode)
+	return scan{file: f, decoded: true}
+}
+
+// decodeInto sets the summary of f, or its error, from data.
+func decodeInto(f *File, data []byte, decode func([]byte) (*exif.Summary, error)) {
+	s, err := decode(data)
+	if err != nil {
+		f.Error = err.Error()
+		return
+	}
+	s.Path = f.Path
+	f.Summary = s
+}
+
+// MarkBackup records which indexed files the backup set holds, given
+// paths, the current files of the set. Files are matched by content, so
+// the set may be laid out differently; files no longer in it lose the
+// mark. It returns the number of indexed files in the set.
+func (ix *Index) MarkBackup(set string, paths []string) (int, []error) {
+	var errs []error
+	held := map[string]bool{}
+	for _, p := range paths {
+		_, sum, err := hashFile(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		held[sum] = true
+	}
+	return ix.markBackup(set, held), errs
+}
+
+// MarkBackupIndex is MarkBackup with the hashes of an index of the backup
+// set, which saves reading the set again.
+func (ix *Index) MarkBackupIndex(set string, backup *Index) int {
+	held := map[string]bool{}
+	for _, f := range backup.Files {
+		held[f.SHA256] = true
+	}
+	return ix.markBackup(set, held)
+}
+
+func (ix *Index) markBackup(set string, held map[string]bool) int {
+	n := 0
+	for _, f := range ix.Files {
+		i, found := slices.BinarySearch(f.Backups, set)
+		switch {
+		case held[f.SHA256] && !found:
+			f.Backups = slices.Insert(f.Backups, i, set)
+		case !held[f.SHA256] && found:
+			f.Backups = slices.Delete(f.Backups, i, i+1)
+		}
+		if held[f.SHA256] {
+			n++
+		}
+	}
+	return n
+}
+
+// Outcomes of Verify.
+const (
+	StatusOK = "ok"
+	// StatusCorrupt is new content under the same size and modification
+	// time, which editors do not leave behind: bit rot or tampering.
+	StatusCorrupt = "corrupt"
+	// StatusModified is new content with a new size or modification time,
+	// i.e. an edit since the last update.
+	StatusModified = "modified"
+	StatusMissing  = "missing"
+	// StatusUnreadable files exist but could not be read.
+	StatusUnreadable = "unreadable"
+)
+
+// Check is the outcome of verifying one file.
+type Check struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// SHA256 is the hash of the current content when it differs from the
+	// indexed one.
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Verify hashes every indexed file again and compares it with the index,
+// in path order. It does not change the index.
+func (ix *Index) Verify() []Check {
+	checks := make([]Check, 0, len(ix.Files))
+	for _, f := range ix.Files {
+		c := Check{Path: f.Path, Status: StatusOK}
+		fi, sum, err := hashFile(f.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			c.Status = StatusMissing
+		case err != nil:
+			c.Status, c.Error = StatusUnreadable, err.Error()
+		case sum == f.SHA256:
+		case fi.Size() == f.Size && fi.ModTime().Equal(f.ModTime):
+			c.Status, c.SHA256 = StatusCorrupt, sum
+		default:
+			c.Status, c.SHA256 = StatusModified, sum
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// hashFile returns the file information and content hash of path,
+// streaming the content so large files are not held in memory.
+func hashFile(path string) (os.FileInfo, string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer fh.Close()
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return nil, "", err
+	}
+	return fi, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,115 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+)
+
+// library writes n JPEG files of the fixture scenarios to a temporary
+// directory and returns their paths.
+func library(tb testing.TB, n int) []string {
+	tb.Helper()
+	var images [][]byte
+	for _, sc := range exiftest.Scenarios() {
+		if sc.HEIF == nil {
+			images = append(images, sc.Encode())
+		}
+	}
+	dir := tb.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("%05d.jpg", i))
+		// The trailing bytes make every file's content distinct.
+		data := append(slices.Clip(images[i%len(images)]), fmt.Sprint(i)...)
+		if err := os.WriteFile(paths[i], data, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestUpdateChurn(t *testing.T) {
+	paths := library(t, 6)
+	dir := filepath.Dir(paths[0])
+	moved := filepath.Join(dir, "moved.jpg")
+	copied := filepath.Join(dir, "copy.jpg")
+	data, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name   string
+		change func() error
+		paths  []string
+		want   Churn
+	}{
+		{"first run", func() error { return nil }, paths, Churn{Added: 6}},
+		{"no change", func() error { return nil }, paths, Churn{Unchanged: 6}},
+		{"move and copy", func() error {
+			if err := os.Rename(paths[1], moved); err != nil {
+				return err
+			}
+			return os.WriteFile(copied, data, 0o644)
+		}, []string{paths[0], paths[2], paths[3], paths[4], paths[5], moved, copied}, Churn{Added: 1, Moved: 1, Unchanged: 5}},
+		{"remove", func() error { return os.Remove(paths[5]) }, []string{paths[0], paths[2], paths[3], paths[4], moved, copied}, Churn{Removed: 1, Unchanged: 6}},
+	}
+	ix := &Index{Version: Version}
+	for _, st := range steps {
+		if err := st.change(); err != nil {
+			t.Fatal(err)
+		}
+		churn, errs := ix.Update(st.paths, exif.DecodeBytes, 4)
+		if len(errs) > 0 {
+			t.Fatalf("%s: %v", st.name, errs)
+		}
+		if churn != st.want {
+			t.Errorf("%s: churn %+v, want %+v", st.name, churn, st.want)
+		}
+	}
+	for _, f := range ix.Files {
+		if f.Summary == nil || f.Summary.Path != f.Path {
+			t.Errorf("%s: summary %+v", f.Path, f.Summary)
+		}
+	}
+}
+
+// BenchmarkUpdate indexes a library of new files with 16 workers and
+// writes the catalog, the work of a first run. The files/s it reports
+// should stay above 5000.
+func BenchmarkUpdate(b *testing.B) {
+	paths := library(b, 5000)
+	b.ResetTimer()
+	for range b.N {
+		ix := &Index{Version: Version}
+		if _, errs := ix.Update(paths, exif.DecodeBytes, 16); len(errs) > 0 {
+			b.Fatal(errs[0])
+		}
+		if _, err := ix.Marshal(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(paths)*b.N)/b.Elapsed().Seconds(), "files/s")
+}
+
+// BenchmarkMarshal writes the catalog of 5000 decoded files, the part of
+// every run that grows with the library rather than with its changes.
+func BenchmarkMarshal(b *testing.B) {
+	paths := library(b, 5000)
+	ix := &Index{Version: Version}
+	if _, errs := ix.Update(paths, exif.DecodeBytes, 16); len(errs) > 0 {
+		b.Fatal(errs[0])
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ix.Marshal(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(paths)*b.N)/b.Elapsed().Seconds(), "files/s")
+}
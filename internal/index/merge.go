@@ -0,0 +1,188 @@
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Policies of Merge for a path that holds different content in the two
+// indexes.
+const (
+	PreferOurs   = "ours"
+	PreferTheirs = "theirs"
+	// PreferNewer keeps the file modified last.
+	PreferNewer = "newer"
+)
+
+// Prefers lists the policies Merge accepts.
+var Prefers = []string{PreferOurs, PreferTheirs, PreferNewer}
+
+// Conflict is a path that holds different content in two indexes.
+type Conflict struct {
+	Path   string `json:"path"`
+	Ours   string `json:"ours"`
+	Theirs string `json:"theirs"`
+	// Kept is PreferOurs or PreferTheirs, after Merge resolved it.
+	Kept string `json:"kept,omitempty"`
+}
+
+// Rebase moves the files under the directory from to the directory to, so
+// an index made on another machine lines up with this one by path.
+func (ix *Index) Rebase(from, to string) {
+	from, to = filepath.Clean(from), filepath.Clean(to)
+	for _, f := range ix.Files {
+		rest, ok := strings.CutPrefix(f.Path, from)
+		if !ok || rest != "" && !strings.HasPrefix(rest, string(filepath.Separator)) {
+			continue
+		}
+		f.Path = to + rest
+		if f.Summary != nil {
+			f.Summary.Path = f.Path
+		}
+	}
+	sort.Slice(ix.Files, func(i, j int) bool { return ix.Files[i].Path < ix.Files[j].Path })
+}
+
+// Merge adds the files of other, with its backup marks, to the index.
+// Content is matched by hash: content the index already holds keeps its
+// path here, and only content new to it is added. A path holding different
+// content on each side is resolved by prefer. The run history stays the
+// index's own.
+func (ix *Index) Merge(other *Index, prefer string) ([]Conflict, error) {
+	if !slices.Contains(Prefers, prefer) {
+		return nil, fmt.Errorf("index: unknown merge policy %q", prefer)
+	}
+	byPath := map[string]*File{}
+	byHash := map[string]*File{}
+	for _, f := range ix.Files {
+		byPath[f.Path] = f
+		if byHash[f.SHA256] == nil {
+			byHash[f.SHA256] = f
+		}
+	}
+	var conflicts []Conflict
+	for _, t := range other.Files {
+		if o := byPath[t.Path]; o != nil {
+			if o.SHA256 == t.SHA256 {
+				o.Backups = union(o.Backups, t.Backups)
+				continue
+			}
+			c := Conflict{Path: t.Path, Ours: o.SHA256, Theirs: t.SHA256, Kept: PreferOurs}
+			if prefer == PreferTheirs || prefer == PreferNewer && t.ModTime.After(o.ModTime) {
+				c.Kept = PreferTheirs
+				if byHash[o.SHA256] == o {
+					delete(byHash, o.SHA256)
+				}
+				*o = *t
+				byHash[t.SHA256] = o
+			}
+			conflicts = append(conflicts, c)
+			continue
+		}
+		if o := byHash[t.SHA256]; o != nil {
+			o.Backups = union(o.Backups, t.Backups)
+			continue
+		}
+		f := *t
+		ix.Files = append(ix.Files, &f)
+		byPath[f.Path], byHash[f.SHA256] = &f, &f
+	}
+	sort.Slice(ix.Files, func(i, j int) bool { return ix.Files[i].Path < ix.Files[j].Path })
+	return conflicts, nil
+}
+
+// union returns the sorted union of two sorted lists.
+func union(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, s := range b {
+		if i, found := slices.BinarySearch(out, s); !found {
+			out = slices.Insert(out, i, s)
+		}
+	}
+	return out
+}
+
+// Delta is what each of two indexes lacks that the other holds.
+type Delta struct {
+	// OnlyOurs is the content missing from the other index, and
+	// OnlyTheirs that missing from this one.
+	OnlyOurs   []DeltaFile `json:"only_ours"`
+	OnlyTheirs []DeltaFile `json:"only_theirs"`
+	// Backups lists content both hold whose backup marks differ.
+	Backups []BackupDelta `json:"backups"`
+	// Conflicts are paths holding different content on each side.
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// DeltaFile identifies a file of a Delta.
+type DeltaFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BackupDelta is content whose backup marks differ between two indexes.
+type BackupDelta struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	// OnlyOurs and OnlyTheirs are the sets marked on one side only.
+	OnlyOurs   []string `json:"only_ours"`
+	OnlyTheirs []string `json:"only_theirs"`
+}
+
+// Diff compares the index with other by content, in path order.
+func (ix *Index) Diff(other *Index) Delta {
+	d := Delta{OnlyOurs: []DeltaFile{}, OnlyTheirs: []DeltaFile{}, Backups: []BackupDelta{}, Conflicts: []Conflict{}}
+	ours, theirs := hashes(ix), hashes(other)
+	for _, f := range ix.Files {
+		t := theirs[f.SHA256]
+		if t == nil {
+			d.OnlyOurs = append(d.OnlyOurs, DeltaFile{f.Path, f.SHA256, f.Size})
+			continue
+		}
+		if ours[f.SHA256] == f {
+			a, b := difference(f.Backups, t.Backups), difference(t.Backups, f.Backups)
+			if len(a) > 0 || len(b) > 0 {
+				d.Backups = append(d.Backups, BackupDelta{Path: f.Path, SHA256: f.SHA256, OnlyOurs: a, OnlyTheirs: b})
+			}
+		}
+	}
+	paths := map[string]*File{}
+	for _, f := range ix.Files {
+		paths[f.Path] = f
+	}
+	for _, t := range other.Files {
+		if ours[t.SHA256] == nil {
+			d.OnlyTheirs = append(d.OnlyTheirs, DeltaFile{t.Path, t.SHA256, t.Size})
+		}
+		if o := paths[t.Path]; o != nil && o.SHA256 != t.SHA256 {
+			d.Conflicts = append(d.Conflicts, Conflict{Path: t.Path, Ours: o.SHA256, Theirs: t.SHA256})
+		}
+	}
+	return d
+}
+
+// hashes maps the content hashes of ix to their first file.
+func hashes(ix *Index) map[string]*File {
+	m := make(map[string]*File, len(ix.Files))
+	for _, f := range ix.Files {
+		if m[f.SHA256] == nil {
+			m[f.SHA256] = f
+		}
+	}
+	return m
+}
+
+// difference returns the elements of a not in b.
+func difference(a, b []string) []string {
+	out := []string{}
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
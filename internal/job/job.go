@@ -0,0 +1,126 @@
+// Package job assigns photos to the client jobs or projects they were
+// shot for, from their IPTC Job Identifier or the folder they are filed
+// in, for per-job reports.
+package job
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/filter"
+)
+
+// Settings is the studio's filing convention. In YAML:
+//
+//	jobs:
+//	  folder: Clients/{client}/{job}
+//	  deliverables: "rating >= 4 || keywords = delivered"
+type Settings struct {
+	// Folder is a run of path elements naming the job, matched anywhere in
+	// a photo's path: {job} and {client} each stand for part or all of one
+	// element and * for any text within it, as in "Clients/{client}/{job}"
+	// or "Jobs/{client}_{job}". Photos with an IPTC Job Identifier keep it
+	// as their job. Empty disables it.
+	Folder string `json:"folder"`
+	// Deliverables is the filter expression selecting the photos delivered
+	// to the client. Empty leaves deliverables uncounted.
+	Deliverables string `json:"deliverables"`
+}
+
+// Validate reports an unusable folder pattern or deliverables expression.
+func (j *Settings) Validate() error {
+	_, err := j.Matcher()
+	return err
+}
+
+// Matcher is the compiled form of Settings.
+type Matcher struct {
+	folder       *regexp.Regexp
+	deliverables *filter.Expr
+}
+
+// Matcher compiles the settings.
+func (j *Settings) Matcher() (*Matcher, error) {
+	m := &Matcher{}
+	if j.Folder != "" {
+		re, err := folderRegexp(j.Folder)
+		if err != nil {
+			return nil, fmt.Errorf("jobs: folder: %w", err)
+		}
+		m.folder = re
+	}
+	if j.Deliverables != "" {
+		e, err := filter.Parse(j.Deliverables)
+		if err != nil {
+			return nil, fmt.Errorf("jobs: deliverables: %w", err)
+		}
+		m.deliverables = e
+	}
+	return m, nil
+}
+
+// folderRegexp translates a folder pattern to a regular expression over a
+// slash-separated path.
+func folderRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+	if !strings.Contains(pattern, "{job}") {
+		return nil, fmt.Errorf("%q has no {job}", pattern)
+	}
+	var b strings.Builder
+	b.WriteString("(?:^|/)")
+	for rest := pattern; rest != ""; {
+		i := strings.IndexAny(rest, "{*")
+		if i < 0 {
+			b.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(rest[:i]))
+		rest = rest[i:]
+		switch {
+		case rest[0] == '*':
+			b.WriteString("[^/]*")
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "{job}"), strings.HasPrefix(rest, "{client}"):
+			name := rest[1:strings.IndexByte(rest, '}')]
+			if strings.Contains(b.String(), "(?P<"+name+">") {
+				return nil, fmt.Errorf("%q names {%s} twice", pattern, name)
+			}
+			b.WriteString("(?P<" + name + ">[^/]+?)")
+			rest = rest[len(name)+2:]
+		default:
+			placeholder, _, _ := strings.Cut(rest, "}")
+			return nil, fmt.Errorf("%q: unknown placeholder %s}; want {job} or {client}", pattern, placeholder)
+		}
+	}
+	b.WriteString("/")
+	return regexp.Compile(b.String())
+}
+
+// Job returns the client and job of s: its IPTC or XMP job identifier,
+// else the job its folder names, and the client its folder names. Both
+// are empty for a photo outside any job.
+func (m *Matcher) Job(s *exif.Summary) (client, job string) {
+	job = s.JobID
+	if m.folder != nil {
+		if sub := m.folder.FindStringSubmatch(filepath.ToSlash(s.Path)); sub != nil {
+			if i := m.folder.SubexpIndex("client"); i > 0 {
+				client = sub[i]
+			}
+			if job == "" {
+				job = sub[m.folder.SubexpIndex("job")]
+			}
+		}
+	}
+	return client, job
+}
+
+// CountsDeliverables reports whether deliverables are configured.
+func (m *Matcher) CountsDeliverables() bool { return m.deliverables != nil }
+
+// Deliverable reports whether s was delivered to the client.
+func (m *Matcher) Deliverable(s *exif.Summary) bool {
+	return m.deliverables != nil && m.deliverables.Match(s)
+}
@@ -0,0 +1,233 @@
+// Package locale renders human-readable output in the user's language:
+// report text, normalized enum values and dates. Machine-readable output
+// (JSON, CSV) is never localized.
+package locale
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Locale holds the renderings of one language. Messages are keyed by
+// their English text, so English needs no table and untranslated messages
+// fall back to it.
+type Locale struct {
+	// Tag is the language code, e.g. "ja".
+	Tag string
+	// dateTime is the time.Format layout for dates with minutes.
+	dateTime string
+	messages map[string]string
+}
+
+// English is the default locale.
+var English = &Locale{Tag: "en", dateTime: "2006-01-02 15:04"}
+
+// Japanese renders messages and enum values in Japanese.
+var Japanese = &Locale{Tag: "ja", dateTime: "2006/01/02 15:04", messages: map[string]string{
+	// Report text.
+	"Shots: %d\n":                        "撮影枚数: %d\n",
+	"Period: %s - %s\n":                  "期間: %s 〜 %s\n",
+	"Altitude: %s - %s %s\n":             "高度: %s 〜 %s %s\n",
+	"Stabilization":                      "手ぶれ補正",
+	"Drive mode":                         "ドライブモード",
+	"Shutter":                            "シャッター方式",
+	"Light":                              "光の状態",
+	"Rating":                             "レーティング",
+	"Collections":                        "コレクション",
+	"Panoramas: %d\n":                    "パノラマ: %d 枚\n",
+	"Composite":                          "合成",
+	"Phone processing":                   "スマートフォンの処理",
+	"Print at 300 dpi":                   "300 dpi での印刷",
+	"smaller than 4x6":                   "4x6 未満",
+	"Temperature: %s - %s %s\n":          "気温: %s 〜 %s %s\n",
+	"Weather":                            "天気",
+	"Bursts: %d":                         "連写: %d 回",
+	" (%d frames, avg %.1f, longest %d)": " (%d コマ、平均 %.1f コマ、最長 %d コマ)",
+	// Flight report.
+	"Height above take-off: %s - %s %s\n": "離陸地点からの高さ: %s 〜 %s %s\n",
+	"Top speed: %s m/s\n":                 "最高速度: %s m/s\n",
+	"Time":                                "日時",
+	"Height":                              "高さ",
+	"Heading":                             "機首方位",
+	"Gimbal":                              "ジンバル",
+	"Speed":                               "速度",
+	"Path":                                "パス",
+	// Gear inventory.
+	"Kind":                                   "種類",
+	"Make":                                   "メーカー",
+	"Model":                                  "機種",
+	"Serial":                                 "シリアル番号",
+	"Period":                                 "期間",
+	"body":                                   "ボディ",
+	"lens":                                   "レンズ",
+	"%d photos name no body serial number\n": "%d 枚はボディのシリアル番号がありません\n",
+	"Firmware history:":                      "ファームウェアの履歴:",
+	"  changed to %s between %s and %s\n":    "  %[2]s 〜 %[3]s の間に %[1]s に更新\n",
+	// Keeper rates.
+	"Keepers (rated %d or more): %d of %d (%.1f%%)\n": "キーパー (評価 %d 以上): %[3]d 枚中 %[2]d 枚 (%.1[4]f%%)\n",
+	"Keepers":       "キーパー",
+	"Rate":          "割合",
+	"Focal length":  "焦点距離",
+	"Shutter speed": "シャッター速度",
+	"Focus mode":    "フォーカスモード",
+	// Trends.
+	"Days":                "日数",
+	"Median focal length": "焦点距離の中央値",
+	"Median ISO":          "ISO の中央値",
+	"Change":              "増減",
+	// Jobs.
+	"Photos:":                      "写真:",
+	" (%d delivered)":              " (納品 %d 枚)",
+	"Period:":                      "期間:",
+	" (%d days)":                   " (%d 日)",
+	"Cameras:":                     "カメラ:",
+	"Lenses:":                      "レンズ:",
+	"%d photos belong to no job\n": "%d 枚はどの案件にも属しません\n",
+	// Event coverage.
+	"No photos with a capture time\n":                    "撮影日時のある写真がありません\n",
+	"Coverage: %s - %s (%s), %d photos by %d shooters\n": "撮影: %s 〜 %s (%s)、%d 枚、撮影者 %d 人\n",
+	"%d photos have no capture time\n":                   "%d 枚は撮影日時がありません\n",
+	"Longest gaps:":                                      "長い空白:",
+	"Photos per hour:":                                   "1 時間ごとの枚数:",
+	"Room":                                               "部屋",
+	"First":                                              "最初",
+	"Last":                                               "最後",
+	"Longest gap":                                        "最長の空白",
+	"%d photos are in no room\n":                         "%d 枚はどの部屋にも属しません\n",
+	// Exposure consistency.
+	"  %d frames": "  %d コマ",
+	"consistent":  "露出は一定",
+	"shutter":     "シャッター",
+	"aperture":    "絞り",
+	"iso":         "ISO",
+	"%d of %d frames deviate from their set's exposure\n": "%[2]d コマ中 %[1]d コマがセットの露出から外れています\n",
+	"%d photos are in sets too small to check\n":          "%d 枚は確認するには小さすぎるセットにあります\n",
+	// HTML report.
+	"Shooting report":   "撮影レポート",
+	"Map":               "地図",
+	"Elevation profile": "高度の推移",
+	"Photos":            "写真",
+	"Camera":            "カメラ",
+	"Lens":              "レンズ",
+	"Taken":             "撮影日時",
+	"Exposure":          "露出",
+	"Location":          "位置",
+	"Caption":           "キャプション",
+
+	// Normalized summary values.
+	"on":                       "オン",
+	"off":                      "オフ",
+	"unknown":                  "不明",
+	"single":                   "単写",
+	"continuous":               "連写",
+	"self-timer":               "セルフタイマー",
+	"bracketing":               "ブラケット",
+	"mechanical":               "メカシャッター",
+	"electronic":               "電子シャッター",
+	"electronic-front-curtain": "電子先幕",
+	"af-s":                     "AF-S",
+	"af-c":                     "AF-C",
+	"af-a":                     "AF-A",
+	"manual":                   "MF",
+	"day":                      "日中",
+	"golden-hour":              "ゴールデンアワー",
+	"blue-hour":                "ブルーアワー",
+	"night":                    "夜間",
+	"unrated":                  "評価なし",
+	"composite":                "合成",
+	"in-camera":                "撮影時合成",
+	"hdr":                      "HDR",
+	"portrait":                 "ポートレート",
+	"night-mode":               "ナイトモード",
+	"live-photo":               "Live Photos",
+	"motion-photo":             "モーションフォト",
+}}
+
+var locales = map[string]*Locale{"en": English, "ja": Japanese}
+
+// Tags lists the available language codes.
+func Tags() []string {
+	tags := make([]string, 0, len(locales))
+	for t := range locales {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Get returns the locale for a language code. Region and encoding
+// suffixes are ignored, so "ja-JP" and "ja_JP.UTF-8" select Japanese.
+func Get(tag string) (*Locale, error) {
+	lang := strings.ToLower(tag)
+	if i := strings.IndexAny(lang, "-_."); i >= 0 {
+		lang = lang[:i]
+	}
+	if l, ok := locales[lang]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("locale: unsupported language %q (want one of %s)", tag, strings.Join(Tags(), ", "))
+}
+
+// Detect returns the locale named by the first of LC_ALL, LC_MESSAGES
+// and LANG that is set, or English.
+func Detect() *Locale {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			if l, err := Get(v); err == nil {
+				return l
+			}
+			break
+		}
+	}
+	return English
+}
+
+// Text translates an English message or format string.
+func (l *Locale) Text(msg string) string {
+	if l != nil {
+		if t, ok := l.messages[msg]; ok {
+			return t
+		}
+	}
+	return msg
+}
+
+// DateTime formats t to the minute.
+func (l *Locale) DateTime(t time.Time) string {
+	if l == nil {
+		l = English
+	}
+	return t.Format(l.dateTime)
+}
+
+// Pad right-pads s with spaces to width terminal columns, counting wide
+// East Asian characters as two columns.
+func Pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		w++
+		if wide(r) {
+			w++
+		}
+	}
+	if w >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-w)
+}
+
+// wide reports whether r occupies two terminal columns: CJK ideographs,
+// kana, Hangul and fullwidth forms.
+func wide(r rune) bool {
+	return r >= 0x1100 && r != utf8.RuneError && (r <= 0x115F ||
+		(r >= 0x2E80 && r <= 0xA4CF && r != 0x303F) ||
+		(r >= 0xAC00 && r <= 0xD7A3) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0xFE30 && r <= 0xFE4F) ||
+		(r >= 0xFF00 && r <= 0xFF60) ||
+		(r >= 0xFFE0 && r <= 0xFFE6))
+}
@@ -0,0 +1,324 @@
+// Package notes reads shot lists kept alongside a shoot, such as an
+// assistant's frame notes or a tethering application's log, and pairs
+// their entries with photos by file name or capture time.
+package notes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Columns with a meaning of their own; every other column is a note.
+const (
+	ColumnFile = "file"
+	ColumnTime = "time"
+)
+
+// Note is one entry of a shot list.
+type Note struct {
+	// Line is the CSV line or JSON array index (from 1) of the entry.
+	Line int
+	// File is the file name the entry is about, if recorded.
+	File string
+	// Time is when the entry was written, if recorded. Clock entries only
+	// hold a time of day, taken to be on the day of the photo.
+	Time  time.Time
+	Timed bool
+	Clock bool
+	// Fields are the note columns that are set.
+	Fields map[string]string
+}
+
+// List is a parsed shot list.
+type List struct {
+	// Columns are the note columns in file order.
+	Columns []string
+	Notes   []*Note
+}
+
+// timeLayouts are the accepted time formats, tried in order. Those
+// without a UTC offset are read in the list's time zone.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006:01:02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// clockLayouts are times of day.
+var clockLayouts = []string{"15:04:05", "15:04"}
+
+// Load reads a shot list in CSV or, for .json files, JSON, reading times
+// without a UTC offset in loc.
+func Load(path string, loc *time.Location) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("notes: %w", err)
+	}
+	parse := ParseCSV
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		parse = ParseJSON
+	}
+	l, err := parse(data, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// ParseCSV parses a shot list with a header row. Column names are
+// matched case-insensitively; "filename" is accepted for file and
+// "timestamp" for time.
+func ParseCSV(data []byte, loc *time.Location) (*List, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("notes: header: %w", err)
+	}
+	names := make([]string, len(header))
+	l := &List{}
+	for i, h := range header {
+		names[i] = column(h)
+		if names[i] != ColumnFile && names[i] != ColumnTime {
+			l.Columns = append(l.Columns, names[i])
+		}
+	}
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("notes: %w", err)
+		}
+		values := map[string]string{}
+		for i, v := range rec {
+			if i < len(names) {
+				values[names[i]] = v
+			}
+		}
+		n, err := newNote(line, values, loc)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			l.Notes = append(l.Notes, n)
+		}
+	}
+}
+
+// ParseJSON parses a shot list given as an array of objects. Numbers and
+// booleans are kept as written; the first appearance of a key sets its
+// column position.
+func ParseJSON(data []byte, loc *time.Location) (*List, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("notes: %w", err)
+	}
+	l := &List{}
+	seen := map[string]bool{}
+	for i, obj := range raw {
+		d := json.NewDecoder(bytes.NewReader(obj))
+		d.UseNumber()
+		if tok, err := d.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("notes: entry %d: not an object", i+1)
+		}
+		values := map[string]string{}
+		for d.More() {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, fmt.Errorf("notes: entry %d: %w", i+1, err)
+			}
+			key := column(tok.(string))
+			var v any
+			if err := d.Decode(&v); err != nil {
+				return nil, fmt.Errorf("notes: entry %d: %w", i+1, err)
+			}
+			switch v := v.(type) {
+			case nil:
+			case string:
+				values[key] = v
+			case json.Number, bool:
+				values[key] = fmt.Sprint(v)
+			default:
+				return nil, fmt.Errorf("notes: entry %d: %s: want a string or number", i+1, key)
+			}
+			if key != ColumnFile && key != ColumnTime && !seen[key] {
+				seen[key] = true
+				l.Columns = append(l.Columns, key)
+			}
+		}
+		n, err := newNote(i+1, values, loc)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			l.Notes = append(l.Notes, n)
+		}
+	}
+	return l, nil
+}
+
+// column normalizes a column name.
+func column(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "filename":
+		return ColumnFile
+	case "timestamp":
+		return ColumnTime
+	}
+	return name
+}
+
+// newNote builds the note of one entry, or nil for an empty one.
+func newNote(line int, values map[string]string, loc *time.Location) (*Note, error) {
+	n := &Note{Line: line, File: strings.TrimSpace(values[ColumnFile]), Fields: map[string]string{}}
+	if v := strings.TrimSpace(values[ColumnTime]); v != "" {
+		t, clock, ok := parseTime(v, loc)
+		if !ok {
+			return nil, fmt.Errorf("notes: line %d: invalid time %q", line, v)
+		}
+		n.Time, n.Timed, n.Clock = t, true, clock
+	}
+	for k, v := range values {
+		if k != ColumnFile && k != ColumnTime && strings.TrimSpace(v) != "" {
+			n.Fields[k] = strings.TrimSpace(v)
+		}
+	}
+	if n.File == "" && !n.Timed && len(n.Fields) == 0 {
+		return nil, nil
+	}
+	return n, nil
+}
+
+func parseTime(v string, loc *time.Location) (t time.Time, clock, ok bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, false, true
+		}
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, true, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// Match selects how notes are paired with photos.
+const (
+	MatchAuto = "auto"
+	MatchFile = "file"
+	MatchTime = "time"
+)
+
+// Matches lists the accepted match modes, default first.
+var Matches = []string{MatchAuto, MatchFile, MatchTime}
+
+// Matcher pairs photos with the notes of a list.
+type Matcher struct {
+	List *List
+	// Mode is one of Matches.
+	Mode string
+	// Tolerance is the largest distance between a note's time and a
+	// capture time that still pairs them.
+	Tolerance time.Duration
+	// Location is the zone of capture times without a UTC offset.
+	Location *time.Location
+}
+
+// Match returns the note for s, or nil. By name, an entry naming the
+// file wins over one naming a file with the same name minus its
+// extension, such as the JPEG of a raw. By time, the nearest entry
+// within the tolerance wins. Auto tries the name first.
+func (m *Matcher) Match(s *exif.Summary) *Note {
+	if m.Mode != MatchTime {
+		if n := m.byFile(s.Path); n != nil || m.Mode == MatchFile {
+			return n
+		}
+	}
+	return m.byTime(s)
+}
+
+func (m *Matcher) byFile(path string) *Note {
+	name := strings.ToLower(filepath.Base(path))
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	var sibling *Note
+	for _, n := range m.List.Notes {
+		if n.File == "" {
+			continue
+		}
+		f := strings.ToLower(filepath.Base(filepath.FromSlash(n.File)))
+		if f == name {
+			return n
+		}
+		if sibling == nil && (f == stem || strings.TrimSuffix(f, filepath.Ext(f)) == stem) {
+			sibling = n
+		}
+	}
+	return sibling
+}
+
+func (m *Matcher) byTime(s *exif.Summary) *Note {
+	t, ok := s.CaptureTime()
+	if !ok {
+		return nil
+	}
+	loc := m.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	if !hasOffset(s.DateTimeOriginal) {
+		// The camera clock is taken to run in the list's zone.
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+	var best *Note
+	var bestGap time.Duration
+	for _, n := range m.List.Notes {
+		if !n.Timed {
+			continue
+		}
+		at := n.Time
+		if n.Clock {
+			day := t.In(loc)
+			at = time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), at.Second(), 0, loc)
+		}
+		gap := t.Sub(at).Abs()
+		if gap <= m.Tolerance && (best == nil || gap < bestGap) {
+			best, bestGap = n, gap
+		}
+	}
+	return best
+}
+
+// hasOffset reports whether a DateTimeOriginal value carries a UTC
+// offset after its 19 characters of date and time.
+func hasOffset(v string) bool {
+	return len(v) > len("2006-01-02T15:04:05")
+}
+
+// String describes where an entry came from, for messages.
+func (n *Note) String() string {
+	switch {
+	case n.File != "":
+		return fmt.Sprintf("line %d (%s)", n.Line, n.File)
+	case n.Timed && n.Clock:
+		return fmt.Sprintf("line %d (%s)", n.Line, n.Time.Format("15:04:05"))
+	case n.Timed:
+		return fmt.Sprintf("line %d (%s)", n.Line, n.Time.Format(time.RFC3339))
+	}
+	return "line " + strconv.Itoa(n.Line)
+}
@@ -0,0 +1,145 @@
+// Package palette names the dominant colors of a photo from its embedded
+// thumbnail, so that a library can be searched for mostly blue or mostly
+// green pictures without decoding full images.
+package palette
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+	"sort"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Color names, in the order ties are broken.
+const (
+	Red    = "red"
+	Orange = "orange"
+	Yellow = "yellow"
+	Green  = "green"
+	Cyan   = "cyan"
+	Blue   = "blue"
+	Purple = "purple"
+	Pink   = "pink"
+	Brown  = "brown"
+	White  = "white"
+	Gray   = "gray"
+	Black  = "black"
+)
+
+var names = []string{Red, Orange, Yellow, Green, Cyan, Blue, Purple, Pink, Brown, White, Gray, Black}
+
+// MinShare is the share of pixels a color needs to be listed.
+const MinShare = 0.1
+
+// Color is one named color of a photo.
+type Color struct {
+	Name string `json:"name"`
+	// Share is the fraction of pixels of the color, 0-1.
+	Share float64 `json:"share"`
+}
+
+// Dominant returns the colors covering at least MinShare of img, most
+// common first.
+func Dominant(img image.Image) []Color {
+	b := img.Bounds()
+	counts := map[string]int{}
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			counts[Name(float64(r)/0xFFFF, float64(g)/0xFFFF, float64(bl)/0xFFFF)]++
+			n++
+		}
+	}
+	var out []Color
+	for _, name := range names {
+		if share := float64(counts[name]) / float64(max(n, 1)); share >= MinShare {
+			out = append(out, Color{Name: name, Share: math.Round(share*100) / 100})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Share > out[j].Share })
+	return out
+}
+
+// Name names the color of an RGB pixel with components 0-1, by its hue
+// when it is saturated enough, else as black, gray or white.
+func Name(r, g, b float64) string {
+	v := max(r, g, b)
+	c := v - min(r, g, b)
+	switch {
+	case v < 0.2:
+		return Black
+	case c/v < 0.15:
+		if v > 0.85 {
+			return White
+		}
+		return Gray
+	}
+	var h float64
+	switch v {
+	case r:
+		h = math.Mod((g-b)/c+6, 6)
+	case g:
+		h = (b-r)/c + 2
+	default:
+		h = (r-g)/c + 4
+	}
+	h *= 60
+	switch {
+	case h < 15 || h >= 345:
+		return Red
+	case h < 45:
+		if v < 0.6 {
+			return Brown
+		}
+		return Orange
+	case h < 70:
+		return Yellow
+	case h < 165:
+		return Green
+	case h < 195:
+		return Cyan
+	case h < 255:
+		return Blue
+	case h < 290:
+		return Purple
+	default:
+		return Pink
+	}
+}
+
+// FromFile names the dominant colors of an image file from its embedded
+// thumbnail or raw preview, and reports false when it has none that
+// decodes. The tag names the image used, as exif.SmallImage does.
+func FromFile(data []byte) (colors []Color, tag string, ok bool) {
+	small, tag := exif.SmallImage(data)
+	if small == nil {
+		return nil, "", false
+	}
+	img, err := jpeg.Decode(bytes.NewReader(small))
+	if err != nil {
+		return nil, "", false
+	}
+	return Dominant(img), tag, true
+}
+
+// Apply copies colors into the color fields of s, with tag the image
+// they were found in.
+func Apply(s *exif.Summary, colors []Color, tag string) {
+	if len(colors) == 0 {
+		return
+	}
+	s.Colors = make([]string, len(colors))
+	for i, c := range colors {
+		s.Colors[i] = c.Name
+	}
+	s.DominantColor = s.Colors[0]
+	if s.Sources == nil {
+		s.Sources = map[string]exif.Source{}
+	}
+	src := exif.Source{Location: exif.LocationThumbnail, Tag: tag}
+	s.Sources["dominant_color"], s.Sources["colors"] = src, src
+}
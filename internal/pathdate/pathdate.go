@@ -0,0 +1,119 @@
+// Package pathdate infers capture dates from the names photos are filed
+// under, for images whose EXIF records none: phone and camera file names
+// such as IMG_20240101_123456.jpg, and folders such as "2024-01-01 Wedding"
+// or 2024/01/01.
+package pathdate
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Where a date was inferred from.
+const (
+	SourceFilename = "filename"
+	SourceFolder   = "folder"
+)
+
+// Date is an inferred capture date.
+type Date struct {
+	// Time is in no particular zone; names do not record one. Its clock
+	// is midnight when only the day is known.
+	Time time.Time
+	// Clock reports whether the name gave the time of day as well.
+	Clock bool
+	// Source is SourceFilename or SourceFolder, and Match the part of the
+	// name the date was read from.
+	Source string
+	Match  string
+}
+
+// String formats the date as 2006-01-02T15:04:05, or 2006-01-02 when the
+// time of day is unknown.
+func (d Date) String() string {
+	if d.Clock {
+		return d.Time.Format("2006-01-02T15:04:05")
+	}
+	return d.Time.Format("2006-01-02")
+}
+
+// stamp matches a date, optionally followed by a time, with or without
+// separators: 20240101_123456, 2024-01-01-12-34-56, 2024-01-01 12.34.56.
+// Digits may follow the seconds, as the milliseconds of PXL_ names do.
+var stamp = regexp.MustCompile(`(?:^|\D)((?:19|20)\d\d)[-_.]?(0[1-9]|1[0-2])[-_.]?(0[1-9]|[12]\d|3[01])(?:[-_ T.]?([01]\d|2[0-3])[-_.:]?([0-5]\d)[-_.:]?([0-5]\d)\d{0,3})?(?:\D|$)`)
+
+// Folder names of a year/month/day hierarchy.
+var (
+	yearDir = regexp.MustCompile(`^(?:19|20)\d\d$`)
+	dayDir  = regexp.MustCompile(`^\d\d?$`)
+)
+
+// Infer returns the capture date the path of a photo suggests. The file
+// name is tried first, then its folders from the nearest up.
+func Infer(path string) (Date, bool) {
+	base := filepath.Base(path)
+	if d, ok := parse(base[:len(base)-len(filepath.Ext(base))]); ok {
+		d.Source = SourceFilename
+		return d, true
+	}
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		name := filepath.Base(dir)
+		if name == dir || name == "." || name == string(filepath.Separator) {
+			break
+		}
+		dirs = append(dirs, name)
+	}
+	for i, name := range dirs {
+		if d, ok := parse(name); ok {
+			d.Source = SourceFolder
+			return d, true
+		}
+		// 2024/01/15, with dirs listed from the nearest.
+		if i+2 < len(dirs) && dayDir.MatchString(name) && dayDir.MatchString(dirs[i+1]) && yearDir.MatchString(dirs[i+2]) {
+			y, _ := strconv.Atoi(dirs[i+2])
+			m, _ := strconv.Atoi(dirs[i+1])
+			day, _ := strconv.Atoi(name)
+			if t, ok := date(y, m, day, 0, 0, 0); ok {
+				return Date{Time: t, Source: SourceFolder, Match: filepath.Join(dirs[i+2], dirs[i+1], name)}, true
+			}
+		}
+	}
+	return Date{}, false
+}
+
+// parse finds the first valid date in name.
+func parse(name string) (Date, bool) {
+	for _, m := range stamp.FindAllStringSubmatchIndex(name, -1) {
+		n := func(i int) int {
+			if m[2*i] < 0 {
+				return 0
+			}
+			v, _ := strconv.Atoi(name[m[2*i]:m[2*i+1]])
+			return v
+		}
+		t, ok := date(n(1), n(2), n(3), n(4), n(5), n(6))
+		if !ok {
+			continue
+		}
+		end := m[7]
+		clock := m[8] >= 0
+		if clock {
+			end = m[13]
+		}
+		return Date{Time: t, Clock: clock, Match: name[m[2]:end]}, true
+	}
+	return Date{}, false
+}
+
+// date builds a time, rejecting dates that do not exist such as February
+// 30 and dates in the future.
+func date(y, mo, d, h, mi, s int) (time.Time, bool) {
+	t := time.Date(y, time.Month(mo), d, h, mi, s, 0, time.UTC)
+	if t.Day() != d || t.Month() != time.Month(mo) || t.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,275 @@
+// Package pdf writes simple PDF documents: pages of JPEG images, lines,
+// rectangles and text in the standard Helvetica fonts. Coordinates are in
+// points from the top-left corner of the page, unlike PDF's bottom-left
+// origin.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes in points, portrait.
+var (
+	A3     = Size{842, 1191}
+	A4     = Size{595, 842}
+	A5     = Size{420, 595}
+	Letter = Size{612, 792}
+	Legal  = Size{612, 1008}
+)
+
+// Size is a page size in points.
+type Size struct {
+	Width, Height float64
+}
+
+// Fonts available to Text.
+const (
+	Helvetica     = "F1"
+	HelveticaBold = "F2"
+	Courier       = "F3"
+)
+
+var fontNames = map[string]string{Helvetica: "Helvetica", HelveticaBold: "Helvetica-Bold", Courier: "Courier"}
+
+// fontOrder fixes the order fonts are written in.
+var fontOrder = []string{Helvetica, HelveticaBold, Courier}
+
+// Document is a PDF under construction.
+type Document struct {
+	Pages []*Page
+	// Info holds document information entries such as Title and Author.
+	Info map[string]string
+	// Signature, when set, signs the document on writing.
+	Signature *Signer
+}
+
+// Page is one page of a document.
+type Page struct {
+	Size    Size
+	content bytes.Buffer
+	images  []image
+}
+
+type image struct {
+	jpeg          []byte
+	width, height int
+	gray          bool
+}
+
+// AddPage appends an empty page.
+func (d *Document) AddPage(size Size) *Page {
+	p := &Page{Size: size}
+	d.Pages = append(d.Pages, p)
+	return p
+}
+
+// y converts a top-left based coordinate into PDF space.
+func (p *Page) y(y float64) float64 {
+	return p.Size.Height - y
+}
+
+// Image draws JPEG data of the given pixel dimensions into the box at
+// x, y of size w, h. gray selects single-channel JPEGs.
+func (p *Page) Image(jpeg []byte, width, height int, gray bool, x, y, w, h float64) {
+	p.images = append(p.images, image{jpeg, width, height, gray})
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(p.y(y+h)), len(p.images))
+}
+
+// Text draws s with its baseline starting at x, y. Characters outside
+// Latin-1 are replaced with "?".
+func (p *Page) Text(font string, size, x, y float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(p.y(y)), escape(s))
+}
+
+// Line draws a line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(p.y(y1)), num(x2), num(p.y(y2)))
+}
+
+// Rect strokes a rectangle.
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n", num(width), num(x), num(p.y(y+h)), num(w), num(h))
+}
+
+// Gray sets the stroke and fill gray level, 0 black to 1 white.
+func (p *Page) Gray(level float64) {
+	fmt.Fprintf(&p.content, "%s G %s g\n", num(level), num(level))
+}
+
+// TextWidth returns the width of s in the font at size, in points.
+func TextWidth(font string, size float64, s string) float64 {
+	if font == Courier {
+		return float64(len(latin1(s))) * 600 * size / 1000
+	}
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range latin1(s) {
+		if c >= 32 && int(c-32) < len(widths) {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Fit shortens s with "..." until it is at most width points wide in the
+// font at size.
+func Fit(font string, size float64, s string, width float64) string {
+	if TextWidth(font, size, s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && TextWidth(font, size, string(r)+"...") > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// WriteTo writes the document, signing it when Signature is set.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(body string, stream []byte) int {
+		offsets = append(offsets, b.Len())
+		n := len(offsets)
+		fmt.Fprintf(&b, "%d 0 obj\n%s\n", n, body)
+		if stream != nil {
+			b.WriteString("stream\n")
+			b.Write(stream)
+			b.WriteString("\nendstream\n")
+		}
+		b.WriteString("endobj\n")
+		return n
+	}
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 and 2 are the catalog and page tree; pages follow, so
+	// their numbers are known before the tree is written.
+	offsets = append(offsets, 0, 0)
+	var fonts []string
+	for _, f := range fontOrder {
+		n := obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[f]), nil)
+		fonts = append(fonts, fmt.Sprintf("/%s %d 0 R", f, n))
+	}
+	// A signature is an invisible form field on the first page whose
+	// value is the signature dictionary.
+	var signedAt time.Time
+	sigDict, field := 0, 0
+	if d.Signature != nil {
+		signedAt = d.Signature.Time
+		if signedAt.IsZero() {
+			signedAt = time.Now()
+		}
+		sigDict = b.Len()
+		v := obj(d.Signature.dictionary(signedAt), nil)
+		field = obj(fmt.Sprintf("<< /Type /Annot /Subtype /Widget /FT /Sig /T (Signature1) /V %d 0 R /F 132 /Rect [0 0 0 0] >>", v), nil)
+	}
+	var kids []string
+	for i, p := range d.Pages {
+		var xobjects []string
+		for i, im := range p.images {
+			space := "/DeviceRGB"
+			if im.gray {
+				space = "/DeviceGray"
+			}
+			n := obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+				im.width, im.height, space, len(im.jpeg)), im.jpeg)
+			xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, n))
+		}
+		content := obj(fmt.Sprintf("<< /Length %d >>", p.content.Len()), p.content.Bytes())
+		annots := ""
+		if i == 0 && field != 0 {
+			annots = fmt.Sprintf(" /Annots [%d 0 R]", field)
+		}
+		page := obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R%s /Resources << /Font << %s >> /XObject << %s >> >> >>",
+			num(p.Size.Width), num(p.Size.Height), content, annots, strings.Join(fonts, " "), strings.Join(xobjects, " ")), nil)
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	info := 0
+	if len(d.Info) > 0 {
+		var entries []string
+		for _, k := range []string{"Title", "Author", "Subject", "Creator", "Producer", "CreationDate"} {
+			if v, ok := d.Info[k]; ok {
+				entries = append(entries, fmt.Sprintf("/%s (%s)", k, escape(v)))
+			}
+		}
+		info = obj("<< "+strings.Join(entries, " ")+" >>", nil)
+	}
+	offsets[0] = b.Len()
+	acroForm := ""
+	if field != 0 {
+		acroForm = fmt.Sprintf(" /AcroForm << /Fields [%d 0 R] /SigFlags 3 >>", field)
+	}
+	fmt.Fprintf(&b, "1 0 obj\n<< /Type /Catalog /Pages 2 0 R%s >>\nendobj\n", acroForm)
+	offsets[1] = b.Len()
+	fmt.Fprintf(&b, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(kids))
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R", len(offsets)+1)
+	if info != 0 {
+		fmt.Fprintf(&b, " /Info %d 0 R", info)
+	}
+	fmt.Fprintf(&b, " >>\nstartxref\n%d\n%%%%EOF\n", xref)
+	if d.Signature != nil {
+		if err := d.Signature.sign(b.Bytes(), sigDict, signedAt); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(float64(int64(v*100+0.5*sign(v)))/100, 'f', -1, 64)
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// latin1 converts s to Latin-1 bytes, replacing other characters with ?.
+func latin1(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+// escape renders s as the contents of a PDF literal string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range latin1(s) {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32:
+			b.WriteByte(' ')
+		case c >= 0x80:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
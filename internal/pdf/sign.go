@@ -0,0 +1,292 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+)
+
+// Signer signs a document with a detached CMS signature
+// (adbe.pkcs7.detached), which PDF readers verify against the signing
+// certificate.
+type Signer struct {
+	Key crypto.Signer
+	// Certificates holds the signing certificate first, followed by any
+	// intermediates to embed.
+	Certificates []*x509.Certificate
+	// Reason and Location are shown by readers next to the signature.
+	Reason, Location string
+	// Time is the signing time; zero means now.
+	Time time.Time
+}
+
+// Name returns the common name of the signing certificate.
+func (s *Signer) Name() string {
+	return s.Certificates[0].Subject.CommonName
+}
+
+// LoadSigner reads a PEM certificate chain and a PEM private key in
+// PKCS #8, PKCS #1 or SEC 1 form. RSA and ECDSA keys are supported.
+func LoadSigner(certFile, keyFile string) (*Signer, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	s := &Signer{}
+	for {
+		var b *pem.Block
+		b, data = pem.Decode(data)
+		if b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("pdf: %s: %w", certFile, err)
+		}
+		s.Certificates = append(s.Certificates, c)
+	}
+	if len(s.Certificates) == 0 {
+		return nil, fmt.Errorf("pdf: %s: no PEM certificate", certFile)
+	}
+	data, err = os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, fmt.Errorf("pdf: %s: no PEM private key", keyFile)
+	}
+	var key any
+	switch b.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(b.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(b.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(b.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %s: %w", keyFile, err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.Key = k
+	case *ecdsa.PrivateKey:
+		s.Key = k
+	default:
+		return nil, fmt.Errorf("pdf: %s: unsupported key type %T (want RSA or ECDSA)", keyFile, key)
+	}
+	type equaler interface{ Equal(crypto.PublicKey) bool }
+	if !s.Key.Public().(equaler).Equal(s.Certificates[0].PublicKey) {
+		return nil, fmt.Errorf("pdf: %s does not match the certificate of %s", keyFile, certFile)
+	}
+	return s, nil
+}
+
+// signatureSize is the room reserved for the CMS signature, in bytes.
+// The document is signed over everything but this placeholder, so its
+// size must be fixed before the signature is known.
+const signatureSize = 16384
+
+// Date formats t as a PDF date string.
+func Date(t time.Time) string {
+	_, offset := t.Zone()
+	sign := byte('+')
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("D:%s%c%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset/60%60)
+}
+
+// byteRangeWidth fits the four numbers of a /ByteRange.
+const byteRangeWidth = 4*11 + 2
+
+// dictionary returns the signature dictionary with its /ByteRange and
+// /Contents left blank for sign to fill in.
+func (s *Signer) dictionary(at time.Time) string {
+	dict := "<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached" +
+		" /ByteRange [" + string(bytes.Repeat([]byte(" "), byteRangeWidth)) + "]" +
+		" /Contents <" + string(bytes.Repeat([]byte("0"), 2*signatureSize)) + ">" +
+		" /M (" + Date(at) + ")"
+	if n := s.Name(); n != "" {
+		dict += " /Name (" + escape(n) + ")"
+	}
+	if s.Reason != "" {
+		dict += " /Reason (" + escape(s.Reason) + ")"
+	}
+	if s.Location != "" {
+		dict += " /Location (" + escape(s.Location) + ")"
+	}
+	return dict + " >>"
+}
+
+// sign fills in the /ByteRange and /Contents of the signature dictionary
+// written at offset dict of the finished document doc.
+func (s *Signer) sign(doc []byte, dict int, at time.Time) error {
+	br := dict + bytes.Index(doc[dict:], []byte("/ByteRange [")) + len("/ByteRange [")
+	start := dict + bytes.Index(doc[dict:], []byte("/Contents <")) + len("/Contents ")
+	end := start + 2 + 2*signatureSize
+	ranges := fmt.Sprintf("0 %d %d %d", start, end, len(doc)-end)
+	copy(doc[br:br+byteRangeWidth], ranges)
+
+	h := sha256.New()
+	h.Write(doc[:start])
+	h.Write(doc[end:])
+	sig, err := s.cms(h.Sum(nil), at)
+	if err != nil {
+		return fmt.Errorf("pdf: signing: %w", err)
+	}
+	if len(sig) > signatureSize {
+		return fmt.Errorf("pdf: signing: signature of %d bytes exceeds the %d reserved", len(sig), signatureSize)
+	}
+	hex.Encode(doc[start+1:], sig)
+	return nil
+}
+
+// Object identifiers of the CMS structures used.
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+type algorithm struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    algorithm
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm algorithm
+	Signature          []byte
+}
+
+type encapContent struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithm `asn1:"set"`
+	EncapContentInfo encapContent
+	Certificates     asn1.RawValue
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+// null is the ASN.1 NULL of algorithm parameters.
+var null = asn1.RawValue{Tag: asn1.TagNull}
+
+// cms returns a DER ContentInfo holding a detached SignedData over the
+// SHA-256 digest.
+func (s *Signer) cms(digest []byte, at time.Time) ([]byte, error) {
+	var attrs [][]byte
+	for _, a := range []struct {
+		oid asn1.ObjectIdentifier
+		v   any
+	}{
+		{oidContentType, oidData},
+		{oidSigningTime, at.UTC()},
+		{oidMessageDigest, digest},
+	} {
+		v, err := asn1.Marshal(a.v)
+		if err != nil {
+			return nil, err
+		}
+		der, err := asn1.Marshal(attribute{a.oid, []asn1.RawValue{{FullBytes: v}}})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, der)
+	}
+	// DER orders the members of a SET OF by their encoding.
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	set := bytes.Join(attrs, nil)
+
+	// The signature covers the attributes tagged as a SET; they are
+	// embedded with the implicit [0] tag instead.
+	signed, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: set})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(signed)
+	sig, err := s.Key.Sign(rand.Reader, h[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	var sigAlg algorithm
+	switch s.Key.(type) {
+	case *rsa.PrivateKey:
+		sigAlg = algorithm{oidRSA, null}
+	case *ecdsa.PrivateKey:
+		sigAlg = algorithm{Algorithm: oidECDSASHA256}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	var certs []byte
+	for _, c := range s.Certificates {
+		certs = append(certs, c.Raw...)
+	}
+	cert := s.Certificates[0]
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithm{{oidSHA256, null}},
+		EncapContentInfo: encapContent{oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                issuerAndSerial{asn1.RawValue{FullBytes: cert.RawIssuer}, cert.SerialNumber},
+			DigestAlgorithm:    algorithm{oidSHA256, null},
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: set},
+			SignatureAlgorithm: sigAlg,
+			Signature:          sig,
+		}},
+	}
+	content, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+}
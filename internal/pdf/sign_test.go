@@ -0,0 +1,149 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T, key crypto.Signer) *Signer {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Signer"},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2034, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Signer{Key: key, Certificates: []*x509.Certificate{cert}, Reason: "test", Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+}
+
+var byteRange = regexp.MustCompile(`/ByteRange \[\s*(\d+) (\d+) (\d+) (\d+)\s*\]`)
+
+func TestSignerByteRange(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"rsa", rsaKey},
+		{"ecdsa", ecKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Document{Signature: testSigner(t, tt.key)}
+			d.AddPage(A4).Text(Helvetica, 12, 72, 72, "signed")
+			var b bytes.Buffer
+			if _, err := d.WriteTo(&b); err != nil {
+				t.Fatal(err)
+			}
+			doc := b.Bytes()
+
+			m := byteRange.FindSubmatch(doc)
+			if m == nil {
+				t.Fatal("no /ByteRange")
+			}
+			var r [4]int
+			for i := range r {
+				fmt.Sscan(string(m[i+1]), &r[i])
+			}
+			if r[0] != 0 || r[2]+r[3] != len(doc) {
+				t.Fatalf("/ByteRange %v does not cover the %d bytes of the document", r, len(doc))
+			}
+			// The gap is exactly the hex string of /Contents, delimiters
+			// included.
+			gap := doc[r[1]:r[2]]
+			if gap[0] != '<' || gap[len(gap)-1] != '>' || !bytes.HasSuffix(doc[:r[1]], []byte("/Contents ")) {
+				t.Fatalf("/ByteRange gap %q...%q is not the /Contents string", gap[:8], gap[len(gap)-8:])
+			}
+			sig := make([]byte, hex.DecodedLen(len(gap)-2))
+			if _, err := hex.Decode(sig, gap[1:len(gap)-1]); err != nil {
+				t.Fatalf("/Contents: %v", err)
+			}
+
+			digest := sha256.New()
+			digest.Write(doc[r[0]:r[1]])
+			digest.Write(doc[r[2] : r[2]+r[3]])
+			verifyCMS(t, sig, digest.Sum(nil), tt.key.Public())
+		})
+	}
+}
+
+// verifyCMS checks that the signed attributes of the SignedData in der,
+// padded with zeros, carry digest and are signed by pub.
+func verifyCMS(t *testing.T, der, digest []byte, pub crypto.PublicKey) {
+	t.Helper()
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		t.Fatalf("ContentInfo: %v", err)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatalf("SignedData: %v", err)
+	}
+	si := sd.SignerInfos[0]
+	found := false
+	for rest := si.SignedAttrs.Bytes; len(rest) > 0; {
+		var a attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &a); err != nil {
+			t.Fatalf("signed attribute: %v", err)
+		}
+		if a.Type.Equal(oidMessageDigest) {
+			var md []byte
+			if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &md); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(md, digest) {
+				t.Errorf("messageDigest %x, want the digest of the byte ranges %x", md, digest)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no messageDigest attribute")
+	}
+	signed, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: si.SignedAttrs.Bytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(signed)
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], si.Signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, h[:], si.Signature) {
+			err = fmt.Errorf("ecdsa: invalid signature")
+		}
+	}
+	if err != nil {
+		t.Errorf("signature: %v", err)
+	}
+}
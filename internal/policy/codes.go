@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Codes is a code replacement dictionary in the tab-separated format of
+// Photo Mechanic: each line holds a shortcode and one or more
+// replacements,
+//
+//	player23	Jane Doe	Doe	#23
+//
+// In templates {player23} expands to the first replacement and
+// {player23#3} to the third. Summary fields and {year} keep their
+// meaning; a code of the same name is never used.
+type Codes map[string][]string
+
+// codePlaceholder matches a {code} or {code#n} placeholder.
+var codePlaceholder = regexp.MustCompile(`\{([^{}#\s]+)(?:#([0-9]+))?\}`)
+
+// LoadCodes reads a code replacement file.
+func LoadCodes(path string) (Codes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	c, err := ParseCodes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseCodes parses a code replacement file. Blank lines are skipped;
+// codes must be unique.
+func ParseCodes(data []byte) (Codes, error) {
+	c := Codes{}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		code := strings.TrimSpace(fields[0])
+		if len(fields) < 2 || code == "" {
+			return nil, fmt.Errorf("policy: line %d: want a code and its replacement separated by a tab", line)
+		}
+		if strings.ContainsAny(code, "{}#") {
+			return nil, fmt.Errorf("policy: line %d: code %q may not contain {, } or #", line, code)
+		}
+		if _, dup := c[code]; dup {
+			return nil, fmt.Errorf("policy: line %d: duplicate code %q", line, code)
+		}
+		c[code] = fields[1:]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	return c, nil
+}
+
+// Expand replaces the codes of tmpl, then expands it with Expand. Unknown
+// codes are left as they are; Unknown lists them.
+func (c Codes) Expand(tmpl string, s *exif.Summary) string {
+	return Expand(c.replace(tmpl), s)
+}
+
+func (c Codes) replace(tmpl string) string {
+	return codePlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		if v, ok := c.lookup(m); ok {
+			return v
+		}
+		return m
+	})
+}
+
+// lookup returns the replacement of the placeholder m.
+func (c Codes) lookup(m string) (string, bool) {
+	sub := codePlaceholder.FindStringSubmatch(m)
+	name := sub[1]
+	if name == "year" || (known[name] && sub[2] == "") {
+		return "", false
+	}
+	repl, ok := c[name]
+	if !ok {
+		return "", false
+	}
+	n := 1
+	if sub[2] != "" {
+		n, _ = strconv.Atoi(sub[2])
+	}
+	if n < 1 || n > len(repl) {
+		return "", false
+	}
+	return repl[n-1], true
+}
+
+// Unknown returns the placeholders of tmpl that are neither summary
+// fields nor codes, such as a mistyped {playr23} or a {player23#4} of a
+// code with three replacements.
+func (c Codes) Unknown(tmpl string) []string {
+	var out []string
+	for _, m := range codePlaceholder.FindAllString(tmpl, -1) {
+		if _, ok := c.lookup(m); ok {
+			continue
+		}
+		name := codePlaceholder.FindStringSubmatch(m)[1]
+		if placeholder.MatchString(m) && (name == "year" || known[name]) {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
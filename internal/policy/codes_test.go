@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func TestParseCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Codes
+		err  string
+	}{
+		{"photo mechanic", "\ufeffplayer23\tJane Doe\tDoe\t#23\r\n\r\n  \nvenue\tNational Stadium\n", Codes{
+			"player23": {"Jane Doe", "Doe", "#23"},
+			"venue":    {"National Stadium"},
+		}, ""},
+		{"empty replacement", "blank\t\n", Codes{"blank": {""}}, ""},
+		{"trimmed code", " ref \tReferee\n", Codes{"ref": {"Referee"}}, ""},
+		{"empty", "", Codes{}, ""},
+		{"no tab", "a\tA\nplayer23 Jane Doe\n", nil, "policy: line 2: want a code and its replacement separated by a tab"},
+		{"no code", "\tJane Doe\n", nil, "policy: line 1: want a code"},
+		{"brace", "a{b}\tx\n", nil, `policy: line 1: code "a{b}" may not contain {, } or #`},
+		{"hash", "a#2\tx\n", nil, "may not contain"},
+		{"duplicate", "a\tx\n\na\ty\n", nil, `policy: line 3: duplicate code "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCodes([]byte(tt.data))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ParseCodes = %v, want an error with %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCodes = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCodes(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "codes.txt")
+	os.WriteFile(good, []byte("a\tA\n"), 0o644)
+	bad := filepath.Join(dir, "bad.txt")
+	os.WriteFile(bad, []byte("a\n"), 0o644)
+	if c, err := LoadCodes(good); err != nil || c["a"][0] != "A" {
+		t.Errorf("LoadCodes = %q, %v", c, err)
+	}
+	if _, err := LoadCodes(bad); err == nil || !strings.HasPrefix(err.Error(), bad+": policy: line 1: ") {
+		t.Errorf("LoadCodes of a bad file: %v", err)
+	}
+	if _, err := LoadCodes(filepath.Join(dir, "none.txt")); err == nil || !strings.HasPrefix(err.Error(), "policy: ") {
+		t.Errorf("LoadCodes of a missing file: %v", err)
+	}
+}
+
+func TestCodesExpand(t *testing.T) {
+	c := Codes{
+		"player23": {"Jane Doe", "Doe", "#23"},
+		"artist":   {"not the artist"},
+		"year":     {"not the year"},
+		"team":     {"{artist} FC"},
+	}
+	s := &exif.Summary{Artist: "John Roe", DateTimeOriginal: "2024-05-01T10:00:00"}
+	tests := []struct {
+		tmpl    string
+		want    string
+		unknown []string
+	}{
+		{"{player23} scores", "Jane Doe scores", nil},
+		{"{player23#2} ({player23#3})", "Doe (#23)", nil},
+		{"{player23#1}", "Jane Doe", nil},
+		{"{player23#4} {player23#0}", "{player23#4} {player23#0}", []string{"{player23#4}", "{player23#0}"}},
+		{"© {year} {artist}", "© 2024 John Roe", nil},
+		// A code named after a field is reached only with #n.
+		{"{artist#1}", "not the artist", nil},
+		{"{year#1}", "{year#1}", []string{"{year#1}"}},
+		// Replacements are expanded as templates in turn.
+		{"{team}", "John Roe FC", nil},
+		{"{playr23} {Player23}", "{playr23} {Player23}", []string{"{playr23}", "{Player23}"}},
+		{"{copyright}", "", nil},
+	}
+	for _, tt := range tests {
+		if got := c.Expand(tt.tmpl, s); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+		if got := c.Unknown(tt.tmpl); !reflect.DeepEqual(got, tt.unknown) {
+			t.Errorf("Unknown(%q) = %q, want %q", tt.tmpl, got, tt.unknown)
+		}
+	}
+}
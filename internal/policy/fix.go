@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// writers maps the fields a policy can write to the IFD0 edits storing a
+// value; an empty value removes the field.
+var writers = map[string]func(v string) ([]exif.Edit, error){
+	"artist":      ascii(exif.TagArtist),
+	"copyright":   ascii(exif.TagCopyright),
+	"description": ascii(exif.TagImageDescription),
+	"software":    ascii(exif.TagSoftware),
+	"title":       xp(exif.TagXPTitle),
+	"author":      xp(exif.TagXPAuthor),
+	"subject":     xp(exif.TagXPSubject),
+	"keywords": func(v string) ([]exif.Edit, error) {
+		return []exif.Edit{exif.SetKeywords(splitKeywords(v))}, nil
+	},
+	"rating": func(v string) ([]exif.Edit, error) {
+		if v == "" {
+			return exif.SetRating(0)
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("policy: invalid rating %q", v)
+		}
+		return exif.SetRating(n)
+	},
+}
+
+// splitKeywords splits a keywords value at ";", dropping blank entries.
+func splitKeywords(v string) []string {
+	var keywords []string
+	for _, k := range strings.Split(v, ";") {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// value expands the template tmpl of field f as it reads back once
+// written, so "a; b" for keywords compares equal to the stored a and b.
+func value(f, tmpl string, s *exif.Summary) string {
+	v := Expand(tmpl, s)
+	if f == "keywords" {
+		return strings.Join(splitKeywords(v), ";")
+	}
+	return v
+}
+
+func ascii(tag uint16) func(string) ([]exif.Edit, error) {
+	return func(v string) ([]exif.Edit, error) {
+		if v == "" {
+			return []exif.Edit{exif.Delete(tag)}, nil
+		}
+		return []exif.Edit{exif.SetASCII(tag, v)}, nil
+	}
+}
+
+func xp(tag uint16) func(string) ([]exif.Edit, error) {
+	return func(v string) ([]exif.Edit, error) {
+		if v == "" {
+			return []exif.Edit{exif.Delete(tag)}, nil
+		}
+		return []exif.Edit{exif.SetXP(tag, v)}, nil
+	}
+}
+
+// Remediate rewrites image, summarized by s, towards the policy: Set and
+// Default values are written, and forbidden fields removed where shootlog
+// can write them. It returns the rewritten image and a description of each
+// change; with no changes, the image is returned as is.
+//
+// Only EXIF data is rewritten. Violations in fields read from IPTC or XMP,
+// or that no rule can fix, remain; check the result to find them.
+func (p *Policy) Remediate(image []byte, s *exif.Summary) ([]byte, []string, error) {
+	v := newValues(s)
+	fixes := map[string]string{}
+	var order []string
+	put := func(f, value string) {
+		if _, ok := fixes[f]; !ok {
+			order = append(order, f)
+		}
+		fixes[f] = value
+	}
+	stripGPS := false
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !v.matchAll(r.When) {
+			continue
+		}
+		for _, f := range r.Forbid {
+			if _, ok := writers[f]; ok && v.get(f) != "" {
+				put(f, "")
+			}
+			stripGPS = stripGPS || (f == GPS && v.get(GPS) != "")
+		}
+		for _, f := range sortedKeys(r.Set) {
+			if want := value(f, r.Set[f], s); v.get(f) != want {
+				put(f, want)
+			}
+		}
+		for _, f := range sortedKeys(r.Default) {
+			if v.get(f) == "" {
+				put(f, value(f, r.Default[f], s))
+			}
+		}
+	}
+
+	var edits []exif.Edit
+	var changes []string
+	for _, f := range order {
+		e, err := writers[f](fixes[f])
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, e...)
+		if fixes[f] == "" {
+			changes = append(changes, f+" removed")
+		} else {
+			changes = append(changes, fmt.Sprintf("%s=%q", f, fixes[f]))
+		}
+	}
+	out := image
+	var err error
+	if stripGPS {
+		if out, err = exif.StripGPS(out); err != nil {
+			return nil, nil, err
+		}
+		changes = append(changes, "gps removed")
+	}
+	if len(edits) > 0 {
+		if out, err = exif.Apply(out, edits...); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, changes, nil
+}
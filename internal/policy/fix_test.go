@@ -0,0 +1,156 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+)
+
+// fixImage is a photo by Jane Doe taken in 2024, with GPS data and the
+// software that edited it.
+func fixImage() []byte {
+	b := exiftest.Conformant(binary.BigEndian)
+	b.IFD0().ASCII(exif.TagArtist, "Jane Doe").ASCII(exif.TagSoftware, "Editor 1.0").XP(exif.TagXPTitle, "Old title")
+	b.GPS().
+		ASCII(exif.TagGPSLatitudeRef, "N").
+		Rational(exif.TagGPSLatitude, 35, 1, 30, 1, 0, 1).
+		ASCII(exif.TagGPSLongitudeRef, "E").
+		Rational(exif.TagGPSLongitude, 139, 1, 15, 1, 0, 1)
+	return b.JPEG()
+}
+
+func TestRemediate(t *testing.T) {
+	image := fixImage()
+	tests := []struct {
+		name    string
+		yaml    string
+		changes []string
+		check   func(t *testing.T, s *exif.Summary)
+	}{
+		{"default ascii", "rules:\n  - default:\n      copyright: \"© {year} {artist}\"\n", []string{`copyright="© 2024 Jane Doe"`}, func(t *testing.T, s *exif.Summary) {
+			if s.Copyright != "© 2024 Jane Doe" {
+				t.Errorf("copyright %q", s.Copyright)
+			}
+		}},
+		{"default kept", "rules:\n  - default:\n      artist: Someone\n", nil, nil},
+		{"set ascii", "rules:\n  - set:\n      description: \"{model}\"\n      artist: Jane Doe\n", []string{`description="Fixture One"`}, func(t *testing.T, s *exif.Summary) {
+			if s.Description != "Fixture One" || s.Artist != "Jane Doe" {
+				t.Errorf("description %q, artist %q", s.Description, s.Artist)
+			}
+		}},
+		{"set xp", "rules:\n  - set:\n      title: New title\n      author: \"{artist}\"\n      subject: Race\n", []string{`author="Jane Doe"`, `subject="Race"`, `title="New title"`}, func(t *testing.T, s *exif.Summary) {
+			if s.Title != "New title" || s.Author != "Jane Doe" || s.Subject != "Race" {
+				t.Errorf("title %q, author %q, subject %q", s.Title, s.Author, s.Subject)
+			}
+		}},
+		{"set keywords", "rules:\n  - set:\n      keywords: \"sport; ; final \"\n", []string{`keywords="sport;final"`}, func(t *testing.T, s *exif.Summary) {
+			if strings.Join(s.Keywords, "|") != "sport|final" {
+				t.Errorf("keywords %q", s.Keywords)
+			}
+		}},
+		{"set rating", "rules:\n  - set:\n      rating: \"4\"\n", []string{`rating="4"`}, func(t *testing.T, s *exif.Summary) {
+			if s.Rating != 4 {
+				t.Errorf("rating %d", s.Rating)
+			}
+		}},
+		{"forbid ascii", "rules:\n  - forbid: [software, copyright]\n", []string{"software removed"}, func(t *testing.T, s *exif.Summary) {
+			if s.Software != "" {
+				t.Errorf("software %q", s.Software)
+			}
+		}},
+		{"forbid xp", "rules:\n  - forbid: [title]\n", []string{"title removed"}, func(t *testing.T, s *exif.Summary) {
+			if s.Title != "" {
+				t.Errorf("title %q", s.Title)
+			}
+		}},
+		{"forbid gps", "rules:\n  - forbid: [gps]\n", []string{"gps removed"}, func(t *testing.T, s *exif.Summary) {
+			if s.Latitude != nil || s.Longitude != nil {
+				t.Errorf("GPS %v, %v", *s.Latitude, *s.Longitude)
+			}
+			if s.Artist != "Jane Doe" {
+				t.Errorf("artist %q", s.Artist)
+			}
+		}},
+		{"set empty removes", "rules:\n  - set:\n      software: \"{copyright}\"\n", []string{"software removed"}, func(t *testing.T, s *exif.Summary) {
+			if s.Software != "" {
+				t.Errorf("software %q", s.Software)
+			}
+		}},
+		{"when applies", "rules:\n  - when:\n      make: shoot*\n    forbid: [gps]\n    set:\n      rating: \"1\"\n", []string{`rating="1"`, "gps removed"}, nil},
+		{"when skips", "rules:\n  - when:\n      make: Canon\n    forbid: [gps]\n    set:\n      rating: \"1\"\n", nil, nil},
+		{"unfixable rules", "rules:\n  - require: [copyright]\n    match:\n      make: Canon\n", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := exif.DecodeBytes(image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, changes, err := p.Remediate(image, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(changes, ", ") != strings.Join(tt.changes, ", ") {
+				t.Errorf("changes %q, want %q", changes, tt.changes)
+			}
+			if len(changes) == 0 {
+				if !bytes.Equal(out, image) {
+					t.Error("image rewritten without changes")
+				}
+				return
+			}
+			fixed, err := exif.DecodeBytes(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, fixed)
+			}
+			// What Remediate can fix, it has.
+			if strings.Contains(tt.yaml, "require") || strings.Contains(tt.yaml, "match") {
+				return
+			}
+			if res := p.Check(fixed); !res.OK() {
+				t.Errorf("violations after fixing: %q", violations(res))
+			}
+		})
+	}
+}
+
+func TestRemediateErrors(t *testing.T) {
+	image := fixImage()
+	tests := []struct {
+		name  string
+		yaml  string
+		image []byte
+		want  string
+	}{
+		{"bad rating", "rules:\n  - set:\n      rating: \"{model}\"\n", image, `policy: invalid rating "Fixture One"`},
+		{"edit a non-image", "rules:\n  - set:\n      artist: x\n", []byte("not a jpeg"), ""},
+		{"strip a non-image", "rules:\n  - forbid: [gps]\n", []byte("not a jpeg"), ""},
+	}
+	s, err := exif.DecodeBytes(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = p.Remediate(tt.image, s)
+			if err == nil || (tt.want != "" && err.Error() != tt.want) {
+				t.Errorf("Remediate = %v, want an error %s", err, tt.want)
+			}
+		})
+	}
+}
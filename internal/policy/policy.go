@@ -0,0 +1,332 @@
+// Package policy checks image metadata against YAML-defined rules and
+// rewrites files so they satisfy them.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/yaml"
+)
+
+// GPS is the pseudo-field standing for all coordinates: latitude,
+// longitude and altitude.
+const GPS = "gps"
+
+// Policy is an ordered list of rules. In YAML:
+//
+//	rules:
+//	  - name: credit
+//	    require: [artist, copyright]
+//	    default:
+//	      copyright: "© {year} {artist}"
+//	  - name: location
+//	    forbid: [gps]
+//	  - name: color
+//	    match:
+//	      color_space: sRGB
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule constrains summary fields, addressed by their JSON names.
+type Rule struct {
+	// Name identifies the rule in reports. It defaults to "rule N".
+	Name string `json:"name"`
+	// When limits the rule to files whose fields match these patterns.
+	When map[string]string `json:"when"`
+	// Require lists fields that must be set. An entry of the form "a|b"
+	// is satisfied by any of its alternatives.
+	Require []string `json:"require"`
+	// Forbid lists fields that must be empty. Applying the policy removes
+	// them.
+	Forbid []string `json:"forbid"`
+	// Match maps fields to the patterns their values must match.
+	Match map[string]string `json:"match"`
+	// Set maps fields to the values they must hold. Applying the policy
+	// writes them.
+	Set map[string]string `json:"set"`
+	// Default maps fields to the values written when they are empty.
+	Default map[string]string `json:"default"`
+}
+
+// Violation is a rule a file does not satisfy.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Result lists the violations of one file.
+type Result struct {
+	Path       string      `json:"path"`
+	Violations []Violation `json:"violations"`
+}
+
+// OK reports whether the file satisfies the policy.
+func (r *Result) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Load reads a policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse parses and validates a YAML policy.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// known holds the field names rules may use.
+var known = func() map[string]bool {
+	m := map[string]bool{GPS: true}
+	for _, f := range exif.FieldNames() {
+		m[f] = true
+	}
+	return m
+}()
+
+// placeholder matches the {name} placeholders of a template.
+var placeholder = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+// Validate reports rules naming unknown fields, malformed patterns, or
+// values that cannot be written.
+func (p *Policy) Validate() error {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		bad := func(format string, args ...any) error {
+			return fmt.Errorf("policy: %s: %s", r.label(i), fmt.Sprintf(format, args...))
+		}
+		if len(r.Require)+len(r.Forbid)+len(r.Match)+len(r.Set)+len(r.Default) == 0 {
+			return bad("no require, forbid, match, set or default")
+		}
+		for _, req := range r.Require {
+			for _, f := range strings.Split(req, "|") {
+				if !known[strings.TrimSpace(f)] {
+					return bad("unknown field %q", strings.TrimSpace(f))
+				}
+			}
+		}
+		for _, f := range r.Forbid {
+			if !known[f] {
+				return bad("unknown field %q", f)
+			}
+		}
+		for _, patterns := range []map[string]string{r.When, r.Match} {
+			for f, pat := range patterns {
+				if !known[f] {
+					return bad("unknown field %q", f)
+				}
+				if _, err := path.Match(pat, ""); err != nil {
+					return bad("%s: invalid pattern %q", f, pat)
+				}
+			}
+		}
+		for _, values := range []map[string]string{r.Set, r.Default} {
+			for f, tmpl := range values {
+				if _, ok := writers[f]; !ok {
+					return bad("field %q cannot be written", f)
+				}
+				for _, m := range placeholder.FindAllStringSubmatch(tmpl, -1) {
+					if m[1] != "year" && !known[m[1]] {
+						return bad("%s: unknown placeholder %s", f, m[0])
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) label(i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "rule " + strconv.Itoa(i+1)
+}
+
+// Check evaluates s against every rule that applies to it.
+func (p *Policy) Check(s *exif.Summary) Result {
+	res := Result{Path: s.Path, Violations: []Violation{}}
+	v := newValues(s)
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !v.matchAll(r.When) {
+			continue
+		}
+		add := func(field, format string, args ...any) {
+			res.Violations = append(res.Violations, Violation{Rule: r.label(i), Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+		for _, req := range r.Require {
+			alts := strings.Split(req, "|")
+			found := false
+			for j, f := range alts {
+				alts[j] = strings.TrimSpace(f)
+				found = found || v.get(alts[j]) != ""
+			}
+			if !found {
+				add(req, "missing %s", strings.Join(alts, " or "))
+			}
+		}
+		for _, f := range r.Forbid {
+			if v.get(f) != "" {
+				if f == GPS {
+					add(f, "contains GPS data")
+				} else {
+					add(f, "contains %s", f)
+				}
+			}
+		}
+		for _, f := range sortedKeys(r.Match) {
+			pat := r.Match[f]
+			switch got := v.get(f); {
+			case v.match(f, pat):
+			case pat == "":
+				add(f, "%s is %q, want it empty", f, got)
+			case got == "":
+				add(f, "missing %s (want %q)", f, pat)
+			default:
+				add(f, "%s is %q, want %q", f, got, pat)
+			}
+		}
+		for _, f := range sortedKeys(r.Set) {
+			want := value(f, r.Set[f], s)
+			switch got := v.get(f); {
+			case got == want:
+			case got == "":
+				add(f, "missing %s (want %q)", f, want)
+			default:
+				add(f, "%s is %q, want %q", f, got, want)
+			}
+		}
+		for _, f := range sortedKeys(r.Default) {
+			if v.get(f) == "" {
+				add(f, "missing %s (default %q)", f, value(f, r.Default[f], s))
+			}
+		}
+	}
+	return res
+}
+
+// Expand substitutes the {name} placeholders of tmpl with the fields of s
+// and trims the result. {year} is the capture year, or the current year
+// for files without a capture time.
+func Expand(tmpl string, s *exif.Summary) string {
+	return ExpandFunc(tmpl, s, func(v string) string { return v })
+}
+
+// ExpandFunc is Expand with each field value passed through escape before
+// it is substituted, such as to keep values from adding path separators
+// to a file name.
+func ExpandFunc(tmpl string, s *exif.Summary, escape func(string) string) string {
+	v := newValues(s)
+	out := placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := m[1 : len(m)-1]
+		if name == "year" {
+			if t, ok := s.CaptureTime(); ok {
+				return strconv.Itoa(t.Year())
+			}
+			return strconv.Itoa(time.Now().Year())
+		}
+		if !known[name] {
+			return m
+		}
+		return escape(v.get(name))
+	})
+	return strings.TrimSpace(out)
+}
+
+// values renders summary fields as the strings rules compare.
+type values struct {
+	fields map[string]any
+}
+
+func newValues(s *exif.Summary) values {
+	return values{fields: s.Fields()}
+}
+
+// get returns field f as text: numbers in shortest form, lists joined
+// with ";", and "" for unset fields.
+func (v values) get(f string) string {
+	if f == GPS {
+		var parts []string
+		for _, c := range []string{"latitude", "longitude", "altitude"} {
+			if s := v.get(c); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+	return format(v.fields[f])
+}
+
+func format(x any) string {
+	switch x := x.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []any:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = format(e)
+		}
+		return strings.Join(parts, ";")
+	}
+	return fmt.Sprint(x)
+}
+
+// match reports whether field f matches pat, a case-insensitive glob. An
+// empty pattern matches only an unset field, and a set field is required
+// for every other pattern.
+func (v values) match(f, pat string) bool {
+	got := v.get(f)
+	if pat == "" || got == "" {
+		return pat == got
+	}
+	ok, _ := path.Match(strings.ToLower(pat), strings.ToLower(got))
+	return ok
+}
+
+func (v values) matchAll(patterns map[string]string) bool {
+	for f, pat := range patterns {
+		if !v.match(f, pat) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+// violations formats the violations of r as "rule/field: message".
+func violations(r Result) []string {
+	out := []string{}
+	for _, v := range r.Violations {
+		out = append(out, v.Rule+"/"+v.Field+": "+v.Message)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // error, or empty
+	}{
+		{"every rule kind", `
+rules:
+  - name: credit
+    when:
+      make: "can*"
+    require: [artist, "copyright | author"]
+    default:
+      copyright: "© {year} {artist}"
+  - forbid: [gps, software]
+  - match:
+      color_space: sRGB
+  - set:
+      rating: "3"
+      keywords: "a; b"
+`, ""},
+		{"no constraints", "rules:\n  - name: empty\n    when:\n      make: Canon\n", "policy: empty: no require, forbid, match, set or default"},
+		{"unnamed rule", "rules:\n  - require: [artist]\n  - name: \"\"\n", "policy: rule 2: no require"},
+		{"unknown required", "rules:\n  - require: [artst]\n", `unknown field "artst"`},
+		{"unknown alternative", "rules:\n  - require: [\"artist|authr\"]\n", `unknown field "authr"`},
+		{"unknown forbidden", "rules:\n  - forbid: [location]\n", `unknown field "location"`},
+		{"unknown when", "rules:\n  - when:\n      maker: Canon\n    require: [artist]\n", `unknown field "maker"`},
+		{"unknown match", "rules:\n  - match:\n      colour_space: sRGB\n", `unknown field "colour_space"`},
+		{"bad pattern", "rules:\n  - match:\n      make: \"[Canon\"\n", `make: invalid pattern "[Canon"`},
+		{"bad when pattern", "rules:\n  - when:\n      make: \"[\"\n    forbid: [gps]\n", `invalid pattern "["`},
+		{"unwritable set", "rules:\n  - set:\n      make: Canon\n", `field "make" cannot be written`},
+		{"unwritable default", "rules:\n  - default:\n      gps: \"0,0\"\n", `field "gps" cannot be written`},
+		{"unknown placeholder", "rules:\n  - default:\n      copyright: \"© {yaer}\"\n", "copyright: unknown placeholder {yaer}"},
+		{"unknown key", "rules:\n  - name: x\n    requires: [artist]\n", "requires"},
+		{"not yaml", "rules: [", "yaml: line 1: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.yaml))
+			switch {
+			case tt.want == "":
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(p.Rules) == 0 {
+					t.Error("no rules")
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.want):
+				t.Errorf("Parse = %v, want an error with %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(good, []byte("rules:\n  - require: [artist]\n"), 0o644)
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte("rules:\n  - require: [nobody]\n"), 0o644)
+	if p, err := Load(good); err != nil || len(p.Rules) != 1 {
+		t.Errorf("Load = %v, %v", p, err)
+	}
+	if _, err := Load(bad); err == nil || !strings.HasPrefix(err.Error(), bad+": policy: rule 1: ") {
+		t.Errorf("Load of an invalid policy: %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "none.yaml")); err == nil || !strings.HasPrefix(err.Error(), "policy: ") {
+		t.Errorf("Load of a missing file: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	canon := &exif.Summary{
+		Path: "a.jpg", Make: "Canon", Model: "EOS R5", Artist: "Jane Doe", ColorSpace: "sRGB", Rating: 3,
+		DateTimeOriginal: "2024-05-01T10:00:00+09:00", Keywords: []string{"sport", "final"},
+		Latitude: ptr(35.5), Longitude: ptr(139.25),
+	}
+	bare := &exif.Summary{Path: "b.jpg", Make: "Nikon"}
+	tests := []struct {
+		name string
+		yaml string
+		s    *exif.Summary
+		want []string
+	}{
+		{"require met", "rules:\n  - require: [artist, make]\n", canon, []string{}},
+		{"require missing", "rules:\n  - name: credit\n    require: [artist, copyright]\n", canon, []string{"credit/copyright: missing copyright"}},
+		{"require alternatives met", "rules:\n  - require: [\"copyright | artist\"]\n", canon, []string{}},
+		{"require alternatives missing", "rules:\n  - require: [\"copyright | author\"]\n", canon, []string{"rule 1/copyright | author: missing copyright or author"}},
+		{"forbid gps", "rules:\n  - name: location\n    forbid: [gps]\n", canon, []string{"location/gps: contains GPS data"}},
+		{"forbid field", "rules:\n  - forbid: [artist, software]\n", canon, []string{"rule 1/artist: contains artist"}},
+		{"forbid absent gps", "rules:\n  - forbid: [gps]\n", bare, []string{}},
+		{"match glob", "rules:\n  - match:\n      model: \"eos *\"\n      color_space: SRGB\n", canon, []string{}},
+		{"match wrong", "rules:\n  - match:\n      color_space: Adobe*\n", canon, []string{`rule 1/color_space: color_space is "sRGB", want "Adobe*"`}},
+		{"match missing", "rules:\n  - match:\n      color_space: sRGB\n", bare, []string{`rule 1/color_space: missing color_space (want "sRGB")`}},
+		{"match empty", "rules:\n  - match:\n      artist: \"\"\n", canon, []string{`rule 1/artist: artist is "Jane Doe", want it empty`}},
+		{"match empty unset", "rules:\n  - match:\n      artist: \"\"\n", bare, []string{}},
+		{"match number and list", "rules:\n  - match:\n      rating: \"3\"\n      keywords: \"sport;*\"\n      latitude: \"35.5\"\n", canon, []string{}},
+		{"set met", "rules:\n  - set:\n      artist: \"{artist}\"\n      rating: \"3\"\n", canon, []string{}},
+		{"set keywords met", "rules:\n  - set:\n      keywords: \"sport; final \"\n", canon, []string{}},
+		{"set wrong", "rules:\n  - set:\n      rating: \"5\"\n", canon, []string{`rule 1/rating: rating is "3", want "5"`}},
+		{"set missing", "rules:\n  - set:\n      copyright: \"© {year} {artist}\"\n", canon, []string{`rule 1/copyright: missing copyright (want "© 2024 Jane Doe")`}},
+		{"default met", "rules:\n  - default:\n      artist: Someone\n", canon, []string{}},
+		{"default missing", "rules:\n  - default:\n      copyright: \"© {year} {artist}\"\n", canon, []string{`rule 1/copyright: missing copyright (default "© 2024 Jane Doe")`}},
+		{"when applies", "rules:\n  - when:\n      make: canon\n    require: [copyright]\n", canon, []string{"rule 1/copyright: missing copyright"}},
+		{"when skips", "rules:\n  - when:\n      make: canon\n    require: [copyright]\n", bare, []string{}},
+		{"when unset field skips", "rules:\n  - when:\n      artist: \"*\"\n    require: [copyright]\n", bare, []string{}},
+		{"rules in order", "rules:\n  - name: b\n    require: [copyright]\n  - name: a\n    match:\n      make: Nikon\n    forbid: [gps]\n", canon, []string{
+			"b/copyright: missing copyright",
+			"a/gps: contains GPS data",
+			`a/make: make is "Canon", want "Nikon"`,
+		}},
+		{"fields in order", "rules:\n  - match:\n      model: X\n      make: Y\n", canon, []string{
+			`rule 1/make: make is "Canon", want "Y"`,
+			`rule 1/model: model is "EOS R5", want "X"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := p.Check(tt.s)
+			got := violations(res)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if res.Path != tt.s.Path || res.OK() != (len(tt.want) == 0) {
+				t.Errorf("result %+v", res)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	s := &exif.Summary{
+		Artist: "Jane Doe", Rating: 4, FNumber: 2.8, Keywords: []string{"a", "b"},
+		DateTimeOriginal: "2019-12-31T23:59:59",
+	}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"© {year} {artist}", "© 2019 Jane Doe"},
+		{"  {artist}  ", "Jane Doe"},
+		{"{rating}/{f_number}", "4/2.8"},
+		{"{keywords}", "a;b"},
+		{"{copyright}", ""},
+		{"{Artist} {nope} {year", "{Artist} {nope} {year"},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.tmpl, s); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+	if got, want := Expand("{year}", &exif.Summary{}), strconv.Itoa(time.Now().Year()); got != want {
+		t.Errorf("{year} without a capture time = %q, want %q", got, want)
+	}
+	escape := func(v string) string { return strings.ReplaceAll(v, " ", "_") }
+	if got := ExpandFunc("{artist} {year}", s, escape); got != "Jane_Doe 2019" {
+		t.Errorf("ExpandFunc = %q", got)
+	}
+}
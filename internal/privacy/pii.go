@@ -0,0 +1,135 @@
+package privacy
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Severity ranks how directly a finding identifies a person.
+type Severity int
+
+// Severities in increasing order of importance.
+const (
+	// SeverityLow is data that points at a person only with other sources,
+	// such as a copyright notice or a web site.
+	SeverityLow Severity = iota
+	// SeverityMedium names the photographer or the equipment used.
+	SeverityMedium
+	// SeverityHigh locates, names or shows a person: coordinates, owner
+	// names, contact details and faces.
+	SeverityHigh
+)
+
+var severityNames = []string{"low", "medium", "high"}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity by name.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// ParseSeverity returns the severity named s: low, medium or high.
+func ParseSeverity(s string) (Severity, error) {
+	for i, n := range severityNames {
+		if n == s {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (want low, medium or high)", s)
+}
+
+// Finding is one piece of personally identifying metadata in a file.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Field names what was found: a summary field such as artist, an EXIF
+	// tag or XMP property such as CameraOwnerName, or gps or face_regions.
+	Field string `json:"field"`
+	// Message says why the field identifies someone.
+	Message string `json:"message"`
+	// Value is the data found, which is itself personal data.
+	Value string `json:"value,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%-6s %s: %s", f.Severity, f.Field, f.Message)
+}
+
+// XMP namespaces of people shown in a photo.
+const (
+	namespaceMWGRegions = "http://www.metadataworkinggroup.com/schemas/regions/"
+	namespaceMPRegion   = "http://ns.microsoft.com/photo/1.2/t/Region#"
+	namespaceIPTCExt    = "http://iptc.org/std/Iptc4xmpExt/2008-02-29/"
+)
+
+// Detect lists the personally identifying metadata of an image, most
+// severe first: GPS coordinates, the camera owner, contact details,
+// creator names, serial numbers, copyright notices and face regions or
+// people named in XMP. It is a heuristic for compliance reviews and does
+// not look at the pixels or free-text captions.
+func Detect(image []byte) ([]Finding, error) {
+	d := exif.NewDecoder(image)
+	s, err := d.Summary()
+	if err != nil {
+		return nil, err
+	}
+	var out []Finding
+	add := func(sev Severity, field, msg, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			out = append(out, Finding{Severity: sev, Field: field, Message: msg, Value: value})
+		}
+	}
+	if s.Latitude != nil && s.Longitude != nil {
+		add(SeverityHigh, "gps", "GPS coordinates locate where the photo was taken",
+			strconv.FormatFloat(*s.Latitude, 'f', -1, 64)+", "+strconv.FormatFloat(*s.Longitude, 'f', -1, 64))
+	}
+	if e, ok, err := d.Lookup(exif.ExifIFD, exif.TagCameraOwnerName); err == nil && ok {
+		add(SeverityHigh, "CameraOwnerName", "names the owner of the camera", e.Text())
+	}
+	add(SeverityMedium, "body_serial", "ties the photo to one camera and its owner", s.BodySerial)
+	add(SeverityMedium, "internal_serial", "ties the photo to one camera and its owner", s.InternalSerial)
+	add(SeverityMedium, "lens_serial", "ties the photo to one lens and its owner", s.LensSerial)
+	add(SeverityHigh, "contact_email", "is the creator's e-mail address", s.ContactEmail)
+	add(SeverityHigh, "contact_phone", "is the creator's phone number", s.ContactPhone)
+	add(SeverityMedium, "contact", "names the contact for the photo", s.Contact)
+	add(SeverityMedium, "artist", "names the photographer", s.Artist)
+	if s.Author != s.Artist {
+		add(SeverityMedium, "author", "names the photographer", s.Author)
+	}
+	if s.Credit != s.Artist {
+		add(SeverityMedium, "credit", "names the photographer or agency", s.Credit)
+	}
+	add(SeverityLow, "contact_url", "links to the creator", s.ContactURL)
+	add(SeverityLow, "copyright", "names the rights holder", s.Copyright)
+
+	if exif.IsJPEG(image) {
+		props := exif.XMPProperties(exif.XMP(image))
+		faces := len(props[xml.Name{Space: namespaceMPRegion, Local: "Rectangle"}])
+		for _, t := range props[xml.Name{Space: namespaceMWGRegions, Local: "Type"}] {
+			if t == "Face" {
+				faces++
+			}
+		}
+		names := append(props[xml.Name{Space: namespaceMWGRegions, Local: "Name"}],
+			props[xml.Name{Space: namespaceMPRegion, Local: "PersonDisplayName"}]...)
+		if faces > 0 || len(names) > 0 {
+			v := fmt.Sprintf("%d faces", faces)
+			if len(names) > 0 {
+				v += ": " + strings.Join(names, ", ")
+			}
+			add(SeverityHigh, "face_regions", "marks or names the people shown in the photo", v)
+		}
+		add(SeverityHigh, "Iptc4xmpExt:PersonInImage", "names people shown in the photo",
+			strings.Join(props[xml.Name{Space: namespaceIPTCExt, Local: "PersonInImage"}], ", "))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Severity > out[j].Severity })
+	return out, nil
+}
@@ -0,0 +1,121 @@
+// Package privacy keeps the locations of configured home zones out of
+// reports and published files.
+package privacy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/geo"
+)
+
+// Actions taken for photos inside a home zone.
+const (
+	// Redact removes the coordinates, altitude and sun and moon elevations.
+	Redact = "redact"
+	// Coarsen truncates the coordinates to Precision decimal places.
+	Coarsen = "coarsen"
+)
+
+// Settings lists the home zones and what to do with photos taken inside
+// them. In YAML:
+//
+//	privacy:
+//	  action: coarsen
+//	  precision: 1
+//	  home_zones:
+//	    - name: home
+//	      latitude: 35.6812
+//	      longitude: 139.7671
+//	      radius: 500
+type Settings struct {
+	HomeZones []geo.Zone `json:"home_zones"`
+	// Action is Redact or Coarsen.
+	Action string `json:"action"`
+	// Precision is the number of decimal places of degrees Coarsen keeps,
+	// 0-7. One place is about 11 km, two about 1 km.
+	Precision int `json:"precision"`
+}
+
+// Default returns the settings used when the config file has none: no
+// zones, redacting with a fallback precision of two places.
+func Default() Settings {
+	return Settings{Action: Redact, Precision: 2}
+}
+
+// Validate reports unknown actions, out-of-range precisions and invalid
+// zones.
+func (p *Settings) Validate() error {
+	if p.Action != Redact && p.Action != Coarsen {
+		return fmt.Errorf("privacy: action: want %s or %s, got %q", Redact, Coarsen, p.Action)
+	}
+	if p.Precision < 0 || p.Precision > 7 {
+		return fmt.Errorf("privacy: precision %d out of range 0-7", p.Precision)
+	}
+	for i := range p.HomeZones {
+		if err := p.HomeZones[i].Validate(); err != nil {
+			return fmt.Errorf("privacy: %w", err)
+		}
+	}
+	return nil
+}
+
+// Zone returns the first home zone containing s, or nil.
+func (p *Settings) Zone(s *exif.Summary) *geo.Zone {
+	if s.Latitude == nil || s.Longitude == nil {
+		return nil
+	}
+	for i := range p.HomeZones {
+		if z := &p.HomeZones[i]; z.Contains(*s.Latitude, *s.Longitude) {
+			return z
+		}
+	}
+	return nil
+}
+
+// Protect redacts or coarsens the location of s when it lies in a home
+// zone, and reports whether it did.
+func (p *Settings) Protect(s *exif.Summary) bool {
+	if p.Zone(s) == nil {
+		return false
+	}
+	if p.Action == Redact {
+		// The sun's and moon's heights at a known time narrow down the
+		// place too.
+		s.Latitude, s.Longitude, s.Altitude, s.SunElevation, s.MoonAltitude = nil, nil, nil, nil, nil
+	} else {
+		lat, lon := geo.Truncate(*s.Latitude, p.Precision), geo.Truncate(*s.Longitude, p.Precision)
+		s.Latitude, s.Longitude = &lat, &lon
+	}
+	for _, f := range []string{"latitude", "longitude", "altitude"} {
+		delete(s.Sources, f)
+	}
+	return true
+}
+
+// Scrub returns a copy of image with its location protected when it lies
+// in a home zone, and the zone, or nil when the image was left alone.
+func (p *Settings) Scrub(image []byte) ([]byte, *geo.Zone, error) {
+	s, err := exif.DecodeBytes(image)
+	if errors.Is(err, exif.ErrNoExif) {
+		return image, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	z := p.Zone(s)
+	if z == nil {
+		return image, nil, nil
+	}
+	var out []byte
+	if p.Action == Redact {
+		out, err = exif.StripGPS(image)
+	} else {
+		out, err = exif.TruncateGPS(image, p.Precision)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, z, nil
+}
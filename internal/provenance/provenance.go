@@ -0,0 +1,168 @@
+// Package provenance keeps an append-only log of the changes shootlog
+// makes to files: who ran which command when, and the content hashes
+// before and after, as a chain-of-custody record. Each line carries the
+// hash of the line before it, so editing or removing entries anywhere but
+// at the end breaks the chain.
+package provenance
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Actions of an entry.
+const (
+	// Rewrite replaced a file in place.
+	Rewrite = "rewrite"
+	// Copy wrote a modified copy of a file to Output.
+	Copy = "copy"
+	// Rename moved a file, unchanged, to Output.
+	Rename = "rename"
+	// Catalog updated a library index.
+	Catalog = "catalog"
+	// Restore put back a file's version from the trash.
+	Restore = "restore"
+)
+
+// Entry is one line of the log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Command []string  `json:"command"`
+	Action  string    `json:"action"`
+	Path    string    `json:"path"`
+	Output  string    `json:"output,omitempty"`
+	// Before and After are the SHA-256 of the content before and after
+	// the change; Before is empty for new files.
+	Before string `json:"sha256_before,omitempty"`
+	After  string `json:"sha256_after"`
+	// Prev is the SHA-256 of the previous line, or empty on the first.
+	Prev string `json:"prev"`
+}
+
+// Sum returns the hex SHA-256 of data, or "" for nil.
+func Sum(data []byte) string {
+	if data == nil {
+		return ""
+	}
+	s := sha256.Sum256(data)
+	return hex.EncodeToString(s[:])
+}
+
+// NewEntry returns an entry for the current user, host and time.
+func NewEntry(command []string, action, path string) Entry {
+	e := Entry{Time: time.Now().UTC(), Command: command, Action: action, Path: path}
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	} else {
+		e.User = os.Getenv("USER")
+	}
+	e.Host, _ = os.Hostname()
+	return e
+}
+
+// Append adds e to the log at path, creating it, and sets e.Prev. The log
+// is synced before Append returns. Writers are assumed to take turns:
+// two processes appending at once may both chain to the same line.
+func Append(path string, e Entry) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("provenance: %w", err)
+	}
+	defer f.Close()
+	last, err := lastLine(f)
+	if err != nil {
+		return fmt.Errorf("provenance: %s: %w", path, err)
+	}
+	if last != nil {
+		e.Prev = Sum(last)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("provenance: %w", err)
+	}
+	return f.Sync()
+}
+
+// lastLine returns the last line of f without its newline, or nil when f
+// is empty.
+func lastLine(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		return nil, nil
+	}
+	// Entries are short; read back in growing chunks until a line start.
+	for chunk := int64(4096); ; chunk *= 4 {
+		off := max(size-chunk, 0)
+		buf := make([]byte, size-off)
+		if _, err := f.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if !bytes.HasSuffix(buf, []byte("\n")) {
+			return nil, errors.New("last line is incomplete")
+		}
+		buf = buf[:len(buf)-1]
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+		if off == 0 {
+			return buf, nil
+		}
+	}
+}
+
+// ChainError reports a line whose Prev does not match the line before it.
+type ChainError struct {
+	Line int
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("provenance: line %d does not follow line %d; the log was altered", e.Line, e.Line-1)
+}
+
+// Read parses the log at path and checks its chain. On a broken chain it
+// returns the entries with a *ChainError.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("provenance: %w", err)
+	}
+	defer f.Close()
+	var entries []Entry
+	var chainErr error
+	prev := ""
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("provenance: %s: line %d: %w", path, n, err)
+		}
+		if e.Prev != prev && chainErr == nil {
+			chainErr = &ChainError{Line: n}
+		}
+		prev = Sum(sc.Bytes())
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return entries, fmt.Errorf("provenance: %s: %w", path, err)
+	}
+	return entries, chainErr
+}
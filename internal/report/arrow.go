@@ -0,0 +1,42 @@
+package report
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/arrow"
+)
+
+var arrowTypes = map[reflect.Kind]arrow.Type{
+	reflect.String:  arrow.String,
+	reflect.Int:     arrow.Int64,
+	reflect.Float64: arrow.Double,
+	reflect.Bool:    arrow.Boolean,
+}
+
+// WriteArrow writes summaries as an Arrow IPC stream with a typed column
+// per field, lists as list columns. Fields the JSON output leaves out are
+// null.
+func WriteArrow(w io.Writer, summaries []*exif.Summary) error {
+	return writeArrow(w, summaries, newSelection(summaries, nil))
+}
+
+func writeArrow(w io.Writer, summaries []*exif.Summary, sel *selection) error {
+	fields := sel.typedFields()
+	cols := make([]arrow.Column, len(fields))
+	for i, f := range fields {
+		cols[i] = arrow.Column{Name: f.name, Type: arrowTypes[f.kind], Repeated: f.list}
+	}
+	aw := arrow.NewWriter(w, cols)
+	row := make([]any, len(fields))
+	for _, s := range summaries {
+		for i, f := range fields {
+			row[i] = f.selected(s, sel)
+		}
+		if err := aw.Write(row); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
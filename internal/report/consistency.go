@@ -0,0 +1,242 @@
+package report
+
+import (
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// ConsistencyOptions configures NewConsistency.
+type ConsistencyOptions struct {
+	// Gap splits the photos of a directory into separate sets at pauses of
+	// at least this long; 0 keeps each directory one set.
+	Gap time.Duration
+	// Tolerance is how far, in stops, a frame may stray from its set's
+	// usual shutter speed, aperture or ISO before it is flagged.
+	Tolerance float64
+	// MinFrames is the smallest set checked; smaller sets have no
+	// meaningful usual exposure.
+	MinFrames int
+}
+
+// Consistency lists the frames whose exposure differs from the rest of
+// their set, such as one frame at 1/60 in a 1/200 strobe session, which
+// usually means a missed flash sync or a bumped dial.
+type Consistency struct {
+	Sets []ConsistencySet `json:"sets"`
+	// Frames counts the photos of the checked sets, Deviating those
+	// flagged and Skipped the photos of sets too small to check.
+	Frames    int `json:"frames"`
+	Deviating int `json:"deviating"`
+	Skipped   int `json:"skipped,omitempty"`
+}
+
+// ConsistencySet is one set: the photos of a directory taken without a
+// long pause. Shutter, Aperture and ISO are the set's most common values.
+type ConsistencySet struct {
+	Dir        string      `json:"dir"`
+	First      *time.Time  `json:"first,omitempty"`
+	Last       *time.Time  `json:"last,omitempty"`
+	Frames     int         `json:"frames"`
+	Shutter    string      `json:"shutter,omitempty"`
+	Aperture   string      `json:"aperture,omitempty"`
+	ISO        int         `json:"iso,omitempty"`
+	Deviations []Deviation `json:"deviations"`
+}
+
+// Deviation is a frame whose exposure strays from its set's.
+type Deviation struct {
+	Path   string           `json:"path"`
+	Fields []DeviationField `json:"fields"`
+}
+
+// DeviationField is one setting of a deviating frame: "shutter",
+// "aperture" or "iso", its value, the set's usual value, and the
+// difference in stops, negative for less light.
+type DeviationField struct {
+	Field string  `json:"field"`
+	Value string  `json:"value"`
+	Mode  string  `json:"mode"`
+	Stops float64 `json:"stops"`
+}
+
+// exposureSetting reads one setting of a frame as its displayed value and
+// its light in stops; ok is false when the frame does not record it.
+type exposureSetting struct {
+	field string
+	read  func(s *exif.Summary) (value string, stops float64, ok bool)
+}
+
+var exposureSettings = []exposureSetting{
+	{"shutter", func(s *exif.Summary) (string, float64, bool) {
+		return exif.FormatExposure(s.ExposureTime), math.Log2(s.ExposureTime), s.ExposureTime > 0
+	}},
+	{"aperture", func(s *exif.Summary) (string, float64, bool) {
+		// Each stop closes the aperture by a factor of √2.
+		return "f/" + formatFloat(s.FNumber), -2 * math.Log2(s.FNumber), s.FNumber > 0
+	}},
+	{"iso", func(s *exif.Summary) (string, float64, bool) {
+		return strconv.Itoa(s.ISO), math.Log2(float64(s.ISO)), s.ISO > 0
+	}},
+}
+
+// NewConsistency splits summaries into sets and flags the frames of each
+// whose shutter speed, aperture or ISO is more than opt.Tolerance stops
+// from the set's most common value.
+func NewConsistency(summaries []*exif.Summary, opt ConsistencyOptions) *Consistency {
+	type shot struct {
+		s     *exif.Summary
+		t     time.Time
+		timed bool
+	}
+	byDir := map[string][]shot{}
+	var dirs []string
+	for _, s := range summaries {
+		dir := filepath.Dir(s.Path)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		t, ok := s.CaptureTime()
+		byDir[dir] = append(byDir[dir], shot{s, t, ok})
+	}
+	sort.Strings(dirs)
+
+	c := &Consistency{Sets: []ConsistencySet{}}
+	for _, dir := range dirs {
+		shots := byDir[dir]
+		// Untimed frames sort last and join the directory's final set.
+		sort.SliceStable(shots, func(i, j int) bool {
+			if shots[i].timed != shots[j].timed {
+				return shots[i].timed
+			}
+			return shots[i].t.Before(shots[j].t)
+		})
+		start := 0
+		for i := 1; i <= len(shots); i++ {
+			if i < len(shots) && (opt.Gap <= 0 || !shots[i].timed || shots[i].t.Sub(shots[i-1].t) < opt.Gap) {
+				continue
+			}
+			set := make([]*exif.Summary, i-start)
+			for j, sh := range shots[start:i] {
+				set[j] = sh.s
+			}
+			if len(set) < opt.MinFrames {
+				c.Skipped += len(set)
+			} else {
+				cs := checkSet(dir, set, opt.Tolerance)
+				c.Frames += cs.Frames
+				c.Deviating += len(cs.Deviations)
+				c.Sets = append(c.Sets, cs)
+			}
+			start = i
+		}
+	}
+	return c
+}
+
+// checkSet finds the usual exposure of one set and the frames straying
+// from it.
+func checkSet(dir string, set []*exif.Summary, tolerance float64) ConsistencySet {
+	cs := ConsistencySet{Dir: dir, Frames: len(set), Deviations: []Deviation{}}
+	for _, s := range set {
+		if t, ok := s.CaptureTime(); ok {
+			if cs.First == nil || t.Before(*cs.First) {
+				cs.First = &t
+			}
+			if cs.Last == nil || t.After(*cs.Last) {
+				cs.Last = &t
+			}
+		}
+	}
+	type mode struct {
+		value string
+		stops float64
+		ok    bool
+	}
+	modes := make([]mode, len(exposureSettings))
+	for i, es := range exposureSettings {
+		counts := map[string]int{}
+		best := 0
+		for _, s := range set {
+			v, st, ok := es.read(s)
+			if !ok {
+				continue
+			}
+			counts[v]++
+			// Ties go to the value seen first.
+			if counts[v] > best {
+				best = counts[v]
+				modes[i] = mode{v, st, true}
+			}
+		}
+	}
+	cs.Shutter, cs.Aperture = modes[0].value, modes[1].value
+	if modes[2].ok {
+		cs.ISO, _ = strconv.Atoi(modes[2].value)
+	}
+	for _, s := range set {
+		var fields []DeviationField
+		for i, es := range exposureSettings {
+			v, st, ok := es.read(s)
+			if !ok || !modes[i].ok {
+				continue
+			}
+			if d := st - modes[i].stops; math.Abs(d) > tolerance+1e-9 {
+				fields = append(fields, DeviationField{Field: es.field, Value: v, Mode: modes[i].value, Stops: math.Round(d*10) / 10})
+			}
+		}
+		if fields != nil {
+			cs.Deviations = append(cs.Deviations, Deviation{Path: s.Path, Fields: fields})
+		}
+	}
+	return cs
+}
+
+// WriteText renders each set with its usual exposure and deviating frames
+// in locale l; nil selects English.
+func (c *Consistency) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	for i, cs := range c.Sets {
+		if i > 0 {
+			ew.printf("\n")
+		}
+		ew.printf("%s", cs.Dir)
+		if cs.First != nil {
+			ew.printf("  %s - %s", l.DateTime(*cs.First), l.DateTime(*cs.Last))
+		}
+		ew.printf(l.Text("  %d frames"), cs.Frames)
+		for _, v := range []string{cs.Shutter, cs.Aperture} {
+			if v != "" {
+				ew.printf(" %s", v)
+			}
+		}
+		if cs.ISO > 0 {
+			ew.printf(" ISO %d", cs.ISO)
+		}
+		ew.printf("\n")
+		if len(cs.Deviations) == 0 {
+			ew.printf("  %s\n", l.Text("consistent"))
+		}
+		for _, d := range cs.Deviations {
+			ew.printf("  %s", d.Path)
+			for _, f := range d.Fields {
+				ew.printf("  %s %s (%+.1f EV)", l.Text(f.Field), f.Value, f.Stops)
+			}
+			ew.printf("\n")
+		}
+	}
+	if len(c.Sets) > 0 {
+		ew.printf("\n")
+	}
+	ew.printf(l.Text("%d of %d frames deviate from their set's exposure\n"), c.Deviating, c.Frames)
+	if c.Skipped > 0 {
+		ew.printf(l.Text("%d photos are in sets too small to check\n"), c.Skipped)
+	}
+	return ew.err
+}
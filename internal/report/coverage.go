@@ -0,0 +1,295 @@
+package report
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/geo"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Ways of telling the rooms or areas of an event apart.
+const (
+	RoomsNone    = "none"
+	RoomsGPS     = "gps"
+	RoomsKeyword = "keyword"
+)
+
+// CoverageOptions configures NewCoverage.
+type CoverageOptions struct {
+	// MinGap is the shortest pause between shots reported as a gap.
+	MinGap time.Duration
+	// Rooms is RoomsNone, RoomsGPS or RoomsKeyword. GPS clusters the
+	// photos taken within RoomRadius meters of each other; keyword takes
+	// the room from the first keyword starting with RoomPrefix, as in
+	// "room:Ballroom".
+	Rooms      string
+	RoomRadius float64
+	RoomPrefix string
+}
+
+// Coverage is how an event was covered over time: the spans nobody shot,
+// the photos of each hour per shooter and, optionally, per room.
+type Coverage struct {
+	Photos int        `json:"photos"`
+	Start  *time.Time `json:"start,omitempty"`
+	End    *time.Time `json:"end,omitempty"`
+	// Untimed counts the photos without a capture time, which are left out
+	// of the rest.
+	Untimed int `json:"untimed,omitempty"`
+	// Shooters are the names of the photographers, from Artist or else the
+	// camera and its serial number.
+	Shooters []string `json:"shooters"`
+	// Gaps are the pauses of at least the minimum gap, longest first.
+	Gaps  []CoverageGap  `json:"gaps"`
+	Hours []CoverageHour `json:"hours"`
+	Rooms []CoverageRoom `json:"rooms,omitempty"`
+	// Unplaced counts the timed photos no room was found for.
+	Unplaced int `json:"unplaced,omitempty"`
+}
+
+// CoverageGap is a span between two consecutive shots. Before and After
+// are the photos taken at its start and end.
+type CoverageGap struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Seconds float64   `json:"seconds"`
+	Before  string    `json:"before"`
+	After   string    `json:"after"`
+}
+
+// CoverageHour counts the photos of one clock hour, in total and by
+// shooter in the order of Coverage.Shooters. Hours without photos between
+// the first and last shot are included.
+type CoverageHour struct {
+	Hour     time.Time `json:"hour"`
+	Photos   int       `json:"photos"`
+	Shooters []int     `json:"shooters"`
+}
+
+// CoverageRoom is the coverage of one room or area.
+type CoverageRoom struct {
+	Name   string    `json:"name"`
+	Photos int       `json:"photos"`
+	First  time.Time `json:"first"`
+	Last   time.Time `json:"last"`
+	// LongestGap is the longest pause between two shots in the room, in
+	// seconds.
+	LongestGap float64 `json:"longest_gap_seconds"`
+	// Latitude and Longitude are the center of a GPS cluster.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// shooter names the photographer of s.
+func shooter(s *exif.Summary) string {
+	if s.Artist != "" {
+		return s.Artist
+	}
+	name := strings.TrimSpace(s.Make + " " + s.Model)
+	serial := s.BodySerial
+	if serial == "" {
+		serial = s.InternalSerial
+	}
+	if serial != "" {
+		name = strings.TrimSpace(name + " #" + serial)
+	}
+	if name == "" {
+		return unknown
+	}
+	return name
+}
+
+// NewCoverage measures the coverage of an event's photos.
+func NewCoverage(summaries []*exif.Summary, opt CoverageOptions) *Coverage {
+	c := &Coverage{Photos: len(summaries), Shooters: []string{}, Gaps: []CoverageGap{}, Hours: []CoverageHour{}}
+	type shot struct {
+		t time.Time
+		s *exif.Summary
+	}
+	var shots []shot
+	for _, s := range summaries {
+		if t, ok := s.CaptureTime(); ok {
+			shots = append(shots, shot{t, s})
+		} else {
+			c.Untimed++
+		}
+	}
+	if len(shots) == 0 {
+		return c
+	}
+	sort.SliceStable(shots, func(i, j int) bool { return shots[i].t.Before(shots[j].t) })
+	start, end := shots[0].t, shots[len(shots)-1].t
+	c.Start, c.End = &start, &end
+
+	shooterIndex := map[string]int{}
+	for _, sh := range shots {
+		name := shooter(sh.s)
+		if _, ok := shooterIndex[name]; !ok {
+			shooterIndex[name] = len(c.Shooters)
+			c.Shooters = append(c.Shooters, name)
+		}
+	}
+	hourOf := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	}
+	for h := hourOf(start); !h.After(end); h = h.Add(time.Hour) {
+		c.Hours = append(c.Hours, CoverageHour{Hour: h, Shooters: make([]int, len(c.Shooters))})
+	}
+	first := hourOf(start)
+	for i, sh := range shots {
+		hr := &c.Hours[int(sh.t.Sub(first)/time.Hour)]
+		hr.Photos++
+		hr.Shooters[shooterIndex[shooter(sh.s)]]++
+		if i == 0 {
+			continue
+		}
+		if d := sh.t.Sub(shots[i-1].t); d >= opt.MinGap && d > 0 {
+			c.Gaps = append(c.Gaps, CoverageGap{From: shots[i-1].t, To: sh.t, Seconds: d.Seconds(), Before: shots[i-1].s.Path, After: sh.s.Path})
+		}
+	}
+	sort.SliceStable(c.Gaps, func(i, j int) bool { return c.Gaps[i].Seconds > c.Gaps[j].Seconds })
+
+	if opt.Rooms == RoomsNone || opt.Rooms == "" {
+		return c
+	}
+	type room struct {
+		CoverageRoom
+		lat, lon float64
+	}
+	var rooms []*room
+	byName := map[string]*room{}
+	for _, sh := range shots {
+		var r *room
+		switch opt.Rooms {
+		case RoomsKeyword:
+			for _, k := range sh.s.Keywords {
+				if len(k) > len(opt.RoomPrefix) && strings.EqualFold(k[:len(opt.RoomPrefix)], opt.RoomPrefix) {
+					name := strings.TrimSpace(k[len(opt.RoomPrefix):])
+					if r = byName[name]; r == nil {
+						r = &room{CoverageRoom: CoverageRoom{Name: name}}
+						byName[name] = r
+						rooms = append(rooms, r)
+					}
+					break
+				}
+			}
+		case RoomsGPS:
+			if sh.s.Latitude == nil || sh.s.Longitude == nil {
+				break
+			}
+			lat, lon := *sh.s.Latitude, *sh.s.Longitude
+			for _, x := range rooms {
+				if geo.Distance(x.lat, x.lon, lat, lon) <= opt.RoomRadius {
+					r = x
+					break
+				}
+			}
+			if r == nil {
+				r = &room{CoverageRoom: CoverageRoom{Name: "area " + strconv.Itoa(len(rooms)+1)}, lat: lat, lon: lon}
+				rooms = append(rooms, r)
+			} else {
+				// The center is the running mean of the cluster.
+				n := float64(r.Photos)
+				r.lat, r.lon = (r.lat*n+lat)/(n+1), (r.lon*n+lon)/(n+1)
+			}
+		}
+		if r == nil {
+			c.Unplaced++
+			continue
+		}
+		if r.Photos == 0 {
+			r.First = sh.t
+		} else if d := sh.t.Sub(r.Last).Seconds(); d > r.LongestGap {
+			r.LongestGap = d
+		}
+		r.Photos++
+		r.Last = sh.t
+	}
+	for _, r := range rooms {
+		if opt.Rooms == RoomsGPS {
+			lat, lon := roundCoord(r.lat), roundCoord(r.lon)
+			r.Latitude, r.Longitude = &lat, &lon
+		}
+		c.Rooms = append(c.Rooms, r.CoverageRoom)
+	}
+	return c
+}
+
+// roundCoord rounds a coordinate to 6 places, about 10 cm.
+func roundCoord(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
+
+// span formats d to the minute, or to the second when shorter.
+func span(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// coverageBar is the width in characters of the busiest hour's bar.
+const coverageBar = 30
+
+// WriteText renders the coverage in locale l: the event's span, the gaps,
+// a chart of photos per hour and the rooms; nil selects English.
+func (c *Coverage) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	if c.Start == nil {
+		ew.printf(l.Text("No photos with a capture time\n"))
+		return ew.err
+	}
+	ew.printf(l.Text("Coverage: %s - %s (%s), %d photos by %d shooters\n"), l.DateTime(*c.Start), l.DateTime(*c.End),
+		span(c.End.Sub(*c.Start)), c.Photos-c.Untimed, len(c.Shooters))
+	if c.Untimed > 0 {
+		ew.printf(l.Text("%d photos have no capture time\n"), c.Untimed)
+	}
+
+	ew.printf("\n%s\n", l.Text("Longest gaps:"))
+	if len(c.Gaps) == 0 {
+		ew.printf("  -\n")
+	}
+	for _, g := range c.Gaps {
+		ew.printf("  %s - %s  %s  %s\n", l.DateTime(g.From), l.DateTime(g.To), locale.Pad(span(time.Duration(g.Seconds*float64(time.Second))), 8), g.Before)
+	}
+
+	ew.printf("\n%s\n", l.Text("Photos per hour:"))
+	busiest := 1
+	for _, h := range c.Hours {
+		busiest = max(busiest, h.Photos)
+	}
+	for _, h := range c.Hours {
+		line := "  " + l.DateTime(h.Hour) + " " + locale.Pad(strconv.Itoa(h.Photos), 5) +
+			locale.Pad(strings.Repeat("#", (h.Photos*coverageBar+busiest-1)/busiest), coverageBar)
+		if len(c.Shooters) > 1 {
+			var parts []string
+			for i, n := range h.Shooters {
+				if n > 0 {
+					parts = append(parts, c.Shooters[i]+" "+strconv.Itoa(n))
+				}
+			}
+			line += " " + strings.Join(parts, ", ")
+		}
+		ew.printf("%s\n", strings.TrimRight(line, " "))
+	}
+
+	if c.Rooms == nil && c.Unplaced == 0 {
+		return ew.err
+	}
+	ew.printf("\n%s %s %s %s %s\n", locale.Pad(l.Text("Room"), 24), locale.Pad(l.Text("Photos"), 6),
+		locale.Pad(l.Text("First"), 16), locale.Pad(l.Text("Last"), 16), l.Text("Longest gap"))
+	for _, r := range c.Rooms {
+		ew.printf("%s %s %s %s %s\n", locale.Pad(r.Name, 24), locale.Pad(strconv.Itoa(r.Photos), 6),
+			locale.Pad(l.DateTime(r.First), 16), locale.Pad(l.DateTime(r.Last), 16), span(time.Duration(r.LongestGap*float64(time.Second))))
+	}
+	if c.Unplaced > 0 {
+		ew.printf(l.Text("%d photos are in no room\n"), c.Unplaced)
+	}
+	return ew.err
+}
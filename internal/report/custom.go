@@ -0,0 +1,127 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/album"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+)
+
+// TemplateExts are the extensions of report templates, in the order a
+// name is looked up. .html templates escape what they embed as
+// html/template does; the others are plain text/template, for Markdown
+// and the like.
+var TemplateExts = []string{".html", ".md", ".txt"}
+
+// TemplateData is what report templates are executed with.
+type TemplateData struct {
+	// Title is the album's title, or "Shooting report" in the locale.
+	Title string
+	// Lang is the BCP 47 tag of L.
+	Lang      string
+	Generated time.Time
+	// Session holds the aggregates of the text and JSON reports: the
+	// counts, time span, bursts, altitudes and weather.
+	Session *Session
+	// Photos are the summaries, in album order when reporting on one.
+	Photos []*exif.Summary
+	// Album is the album reported on, or nil; its Caption method gives
+	// the caption of a photo.
+	Album *album.Album
+	// L translates and formats, as in {{.L.Text "Shots"}}.
+	L *locale.Locale
+}
+
+// NewTemplateData returns the data of a report on summaries in locale l.
+func NewTemplateData(s *Session, summaries []*exif.Summary, l *locale.Locale, a *album.Album) TemplateData {
+	if l == nil {
+		l = locale.English
+	}
+	d := TemplateData{Title: l.Text("Shooting report"), Lang: l.Tag, Generated: time.Now(), Session: s, Photos: summaries, Album: a, L: l}
+	if a != nil && a.Title != "" {
+		d.Title = a.Title
+	}
+	return d
+}
+
+// FindTemplate returns the file of the report template name in dir:
+// name with the first of TemplateExts that exists. A name with a path
+// separator or an extension is a file of its own instead.
+func FindTemplate(dir, name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') || filepath.Ext(name) != "" {
+		if _, err := os.Stat(name); err != nil {
+			return "", fmt.Errorf("report template: %w", err)
+		}
+		return name, nil
+	}
+	for _, ext := range TemplateExts {
+		p := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no report template %q in %s", name, dir)
+}
+
+// ListTemplates returns the names of the report templates in dir, sorted.
+// Partials, whose names start with "_", are left out.
+func ListTemplates(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		name := strings.TrimSuffix(e.Name(), ext)
+		if e.IsDir() || strings.HasPrefix(name, "_") || !slices.Contains(TemplateExts, ext) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WriteTemplate executes the report template file at path with data.
+// Files of the same extension beside it whose names start with "_" are
+// partials, which it can include as {{template "_photo.html" .}}. The
+// functions of shootlog template-funcs are available.
+func WriteTemplate(w io.Writer, path string, data TemplateData) error {
+	ext := filepath.Ext(path)
+	partials, err := filepath.Glob(filepath.Join(filepath.Dir(path), "_*"+ext))
+	if err != nil {
+		return err
+	}
+	files := append([]string{path}, partials...)
+	name := filepath.Base(path)
+	if ext == ".html" {
+		t, err := htmltemplate.New(name).Funcs(tmplfunc.Funcs()).ParseFiles(files...)
+		if err != nil {
+			return fmt.Errorf("report template: %w", err)
+		}
+		return t.ExecuteTemplate(w, name, data)
+	}
+	t, err := template.New(name).Funcs(tmplfunc.Funcs()).ParseFiles(files...)
+	if err != nil {
+		return fmt.Errorf("report template: %w", err)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ElevationPoint is one photo of the elevation profile.
+type ElevationPoint struct {
+	Time     time.Time `json:"time"`
+	Altitude float64   `json:"altitude"`
+	Path     string    `json:"path"`
+}
+
+// Profile dimensions in pixels.
+const (
+	profileWidth  = 768
+	profileHeight = 240
+	profileLeft   = 64
+	profileBottom = 32
+	profileMargin = 12
+)
+
+// WriteElevationSVG draws the session's elevation profile, altitude over
+// capture time, as a standalone SVG image. Sessions with fewer than two
+// photos carrying both are rejected.
+func (s *Session) WriteElevationSVG(w io.Writer) error {
+	ew := &errWriter{w: w}
+	if len(s.Elevation) < 2 {
+		return fmt.Errorf("report: elevation profile needs at least two photos with GPS altitude and capture time")
+	}
+	s.writeElevation(ew)
+	return ew.err
+}
+
+func (s *Session) writeElevation(ew *errWriter) {
+	pts := s.Elevation
+	t0, t1 := pts[0].Time, pts[len(pts)-1].Time
+	lo, hi := pts[0].Altitude, pts[0].Altitude
+	for _, p := range pts {
+		lo, hi = min(lo, p.Altitude), max(hi, p.Altitude)
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	plotW := float64(profileWidth - profileLeft - profileMargin)
+	plotH := float64(profileHeight - profileBottom - profileMargin)
+	x := func(t time.Time) float64 {
+		if t1.Equal(t0) {
+			return profileLeft + plotW/2
+		}
+		return profileLeft + plotW*float64(t.Sub(t0))/float64(t1.Sub(t0))
+	}
+	y := func(alt float64) float64 {
+		return profileMargin + plotH*(hi-alt)/(hi-lo)
+	}
+	unit := s.Altitude.Unit
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Altitude))
+	}
+
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", profileWidth, profileHeight)
+	ew.printf(`<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#999"/>`+"\n", profileLeft, profileMargin, plotW, plotH)
+	ew.printf(`<text x="%d" y="%.1f" text-anchor="end">%s %s</text>`+"\n", profileLeft-6, y(hi)+4, number(hi), unit)
+	ew.printf(`<text x="%d" y="%.1f" text-anchor="end">%s %s</text>`+"\n", profileLeft-6, y(lo)+4, number(lo), unit)
+	ew.printf(`<text x="%d" y="%d">%s</text>`+"\n", profileLeft, profileHeight-10, t0.Format("2006-01-02 15:04"))
+	ew.printf(`<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", profileWidth-profileMargin, profileHeight-10, t1.Format("2006-01-02 15:04"))
+	ew.printf(`<polyline points="%s" fill="none" stroke="#36c" stroke-width="2"/>`+"\n", strings.Join(coords, " "))
+	for _, p := range pts {
+		ew.printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="#36c"><title>%s: %s %s</title></circle>`+"\n",
+			x(p.Time), y(p.Altitude), html.EscapeString(p.Path), number(p.Altitude), unit)
+	}
+	ew.printf("</svg>\n")
+}
+
+// number formats v in shortest form, keeping zero.
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
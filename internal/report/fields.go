@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Fields returns the summary fields, by JSON name, to write of s besides
+// its path, or nil for all of them. Writers project their columns to the
+// fields selected of any summary and leave the others of a summary out,
+// as they do unset fields; the summaries are not changed.
+type Fields func(s *exif.Summary) []string
+
+// selection is Fields resolved for the summaries of one output.
+type selection struct {
+	of  map[*exif.Summary][]string
+	any map[string]bool // nil when some summary writes every field
+}
+
+func newSelection(summaries []*exif.Summary, fields Fields) *selection {
+	sel := &selection{}
+	if fields == nil {
+		return sel
+	}
+	sel.of = make(map[*exif.Summary][]string, len(summaries))
+	sel.any = map[string]bool{}
+	for _, s := range summaries {
+		names := fields(s)
+		if names == nil {
+			sel.any = nil
+		}
+		sel.of[s] = names
+		for _, n := range names {
+			if sel.any != nil {
+				sel.any[n] = true
+			}
+		}
+	}
+	return sel
+}
+
+// column reports whether field is a column of the output.
+func (sel *selection) column(field string) bool {
+	return sel.any == nil || field == "path" || sel.any[field]
+}
+
+// writes reports whether field is written of s.
+func (sel *selection) writes(s *exif.Summary, field string) bool {
+	if sel.of == nil || field == "path" {
+		return true
+	}
+	names := sel.of[s]
+	return names == nil || slices.Contains(names, field)
+}
+
+// jsonNames are the JSON names of the summary fields, in output order.
+var jsonNames = func() []string {
+	t := reflect.TypeOf(exif.Summary{})
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// projected renders a summary as JSON with the fields of the selection
+// only, and the provenance of those.
+type projected struct {
+	s   *exif.Summary
+	sel *selection
+}
+
+func (p projected) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(p.s)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if len(p.s.Sources) > 0 {
+		sources := map[string]exif.Source{}
+		for f, src := range p.s.Sources {
+			if p.sel.writes(p.s, f) {
+				sources[f] = src
+			}
+		}
+		delete(m, "sources")
+		if len(sources) > 0 {
+			if m["sources"], err = json.Marshal(sources); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for _, name := range jsonNames {
+		v, ok := m[name]
+		if !ok || (name != "sources" && !p.sel.writes(p.s, name)) {
+			continue
+		}
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(v)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// projectedGroup is a Group whose photos are projected.
+type projectedGroup struct {
+	Key    string           `json:"key"`
+	Value  string           `json:"value"`
+	Count  int              `json:"count"`
+	Groups []projectedGroup `json:"groups,omitempty"`
+	Photos []projected      `json:"photos,omitempty"`
+}
+
+func (sel *selection) groups(groups []*Group) []projectedGroup {
+	out := make([]projectedGroup, len(groups))
+	for i, g := range groups {
+		out[i] = projectedGroup{Key: g.Key, Value: g.Value, Count: g.Count, Groups: sel.groups(g.Groups)}
+		for _, s := range g.Photos {
+			out[i].Photos = append(out[i].Photos, projected{s, sel})
+		}
+	}
+	return out
+}
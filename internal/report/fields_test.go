@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func TestWriteFields(t *testing.T) {
+	summaries := func() []*exif.Summary {
+		return []*exif.Summary{
+			{Path: "a/1.jpg", Make: "Fujifilm", Model: "X-T5", ISO: 400,
+				Sources: map[string]exif.Source{"iso": {Location: "Exif", Tag: "ISOSpeedRatings"}, "model": {Location: "IFD0", Tag: "Model"}}},
+			{Path: "b/2.jpg", Make: "Canon", Model: "R5", ISO: 800},
+		}
+	}
+	// a/ prints model and iso, b/ make only.
+	byDir := func(s *exif.Summary) []string {
+		if strings.HasPrefix(s.Path, "a/") {
+			return []string{"model", "iso"}
+		}
+		return []string{"make"}
+	}
+	tests := []struct {
+		name   string
+		format string
+		fields Fields
+		want   string
+	}{
+		{"csv", FormatCSV, byDir,
+			"path,make,model,iso,sources\na/1.jpg,,X-T5,400,iso=Exif:ISOSpeedRatings;model=IFD0:Model\nb/2.jpg,Canon,,,\n"},
+		{"csv one field", FormatCSV, func(*exif.Summary) []string { return []string{"iso"} },
+			"path,iso,sources\na/1.jpg,400,iso=Exif:ISOSpeedRatings\nb/2.jpg,800,\n"},
+		{"json", FormatJSON, byDir, `[
+  {
+    "path": "a/1.jpg",
+    "model": "X-T5",
+    "iso": 400,
+    "sources": {
+      "iso": {
+        "location": "Exif",
+        "tag": "ISOSpeedRatings"
+      },
+      "model": {
+        "location": "IFD0",
+        "tag": "Model"
+      }
+    }
+  },
+  {
+    "path": "b/2.jpg",
+    "make": "Canon"
+  }
+]
+`},
+		{"sql", FormatSQL, byDir, `CREATE TABLE "photos" (
+  "path" TEXT,
+  "make" TEXT,
+  "model" TEXT,
+  "iso" BIGINT
+);
+BEGIN;
+INSERT INTO "photos" ("path", "make", "model", "iso") VALUES
+  ('a/1.jpg', NULL, 'X-T5', 400),
+  ('b/2.jpg', 'Canon', NULL, NULL);
+COMMIT;
+`},
+		{"paths", FormatPaths, byDir, "a/1.jpg\nb/2.jpg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := summaries()
+			var b bytes.Buffer
+			if err := WriteFields(&b, tt.format, in, tt.fields); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(in, summaries()) {
+				t.Error("summaries changed")
+			}
+		})
+	}
+}
+
+func TestWriteFieldsAll(t *testing.T) {
+	s := []*exif.Summary{{Path: "a.jpg", Make: "Fujifilm", ISO: 400}, {Path: "b.jpg", Make: "Canon"}}
+	for _, format := range []string{FormatJSON, FormatCSV, FormatSQL, FormatParquet, FormatArrow} {
+		var all, none bytes.Buffer
+		if err := Write(&all, format, s); err != nil {
+			t.Fatal(err)
+		}
+		// A summary selecting nothing writes every field.
+		if err := WriteFields(&none, format, s, func(*exif.Summary) []string { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(all.Bytes(), none.Bytes()) {
+			t.Errorf("%s: nil selection differs from all fields", format)
+		}
+	}
+}
+
+func TestWriteGroupsFields(t *testing.T) {
+	s := []*exif.Summary{{Path: "a.jpg", Make: "Fujifilm", Model: "X-T5", ISO: 400}}
+	groups, err := GroupBy(s, []string{GroupCamera})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iso := func(*exif.Summary) []string { return []string{"iso"} }
+	tests := []struct {
+		format, want string
+	}{
+		{FormatCSV, "camera,path,iso\nFujifilm X-T5,a.jpg,400\n"},
+		{FormatJSON, `[
+  {
+    "key": "camera",
+    "value": "Fujifilm X-T5",
+    "count": 1,
+    "photos": [
+      {
+        "path": "a.jpg",
+        "iso": 400
+      }
+    ]
+  }
+]
+`},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := WriteGroups(&b, tt.format, groups, []string{GroupCamera}, iso); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Flight summarizes the photos of a drone flight: those carrying DJI
+// flight data, in capture order.
+type Flight struct {
+	Shots int        `json:"shots"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+	// Altitude is the range of heights above the take-off point, in the
+	// flight's units, and MaxSpeed the highest ground speed in m/s.
+	Altitude *Range        `json:"altitude,omitempty"`
+	MaxSpeed *float64      `json:"max_speed,omitempty"`
+	Points   []FlightPoint `json:"points"`
+}
+
+// FlightPoint is one photo of a flight.
+type FlightPoint struct {
+	Time *time.Time `json:"time,omitempty"`
+	Path string     `json:"path"`
+	// Altitude is the height above the take-off point.
+	Altitude    *float64 `json:"altitude,omitempty"`
+	Heading     *float64 `json:"heading,omitempty"`
+	GimbalPitch *float64 `json:"gimbal_pitch,omitempty"`
+	GimbalYaw   *float64 `json:"gimbal_yaw,omitempty"`
+	Speed       *float64 `json:"speed,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+}
+
+// NewFlight collects the photos of summaries that carry flight data.
+// Photos without a capture time follow the others in path order.
+// Altitudes are taken to be in meters; see ConvertUnits.
+func NewFlight(summaries []*exif.Summary) *Flight {
+	f := &Flight{Points: []FlightPoint{}}
+	for _, s := range summaries {
+		if s.RelativeAltitude == nil && s.FlightHeading == nil && s.GimbalPitch == nil && s.FlightSpeed == nil {
+			continue
+		}
+		p := FlightPoint{
+			Path: s.Path, Altitude: s.RelativeAltitude, Heading: s.FlightHeading,
+			GimbalPitch: s.GimbalPitch, GimbalYaw: s.GimbalYaw, Speed: s.FlightSpeed,
+			Latitude: s.Latitude, Longitude: s.Longitude,
+		}
+		if t, ok := s.CaptureTime(); ok {
+			p.Time = &t
+		}
+		f.Points = append(f.Points, p)
+	}
+	sort.SliceStable(f.Points, func(i, j int) bool {
+		a, b := f.Points[i].Time, f.Points[j].Time
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Before(*b)
+	})
+
+	f.Shots = len(f.Points)
+	for _, p := range f.Points {
+		if p.Time != nil {
+			if f.Start == nil {
+				f.Start = p.Time
+			}
+			f.End = p.Time
+		}
+		if alt := p.Altitude; alt != nil {
+			if f.Altitude == nil {
+				f.Altitude = &Range{Min: *alt, Max: *alt, Unit: DistanceUnit(UnitsMetric)}
+			}
+			f.Altitude.Min = min(f.Altitude.Min, *alt)
+			f.Altitude.Max = max(f.Altitude.Max, *alt)
+		}
+		if v := p.Speed; v != nil && (f.MaxSpeed == nil || *v > *f.MaxSpeed) {
+			f.MaxSpeed = v
+		}
+	}
+	return f
+}
+
+// ConvertUnits converts the flight's altitudes, in meters, into units.
+func (f *Flight) ConvertUnits(units string) error {
+	if err := checkUnits(units); err != nil {
+		return err
+	}
+	if a := f.Altitude; a != nil {
+		f.Altitude = &Range{Min: fromMeters(a.Min, units), Max: fromMeters(a.Max, units), Unit: DistanceUnit(units)}
+	}
+	for i, p := range f.Points {
+		if p.Altitude != nil {
+			v := fromMeters(*p.Altitude, units)
+			f.Points[i].Altitude = &v
+		}
+	}
+	return nil
+}
+
+// WriteText renders the flight as a table of its shots in locale l; nil
+// selects English.
+func (f *Flight) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	ew.printf(l.Text("Shots: %d\n"), f.Shots)
+	if f.Start != nil {
+		ew.printf(l.Text("Period: %s - %s\n"), l.DateTime(*f.Start), l.DateTime(*f.End))
+	}
+	unit := DistanceUnit(UnitsMetric)
+	if a := f.Altitude; a != nil {
+		unit = a.Unit
+		ew.printf(l.Text("Height above take-off: %s - %s %s\n"), number(a.Min), number(a.Max), a.Unit)
+	}
+	if f.MaxSpeed != nil {
+		ew.printf(l.Text("Top speed: %s m/s\n"), number(*f.MaxSpeed))
+	}
+	if f.Shots == 0 {
+		return ew.err
+	}
+	ew.printf("\n%s %s %s %s %s %s\n",
+		locale.Pad(l.Text("Time"), 16), locale.Pad(l.Text("Height")+" ("+unit+")", 12), locale.Pad(l.Text("Heading"), 8),
+		locale.Pad(l.Text("Gimbal"), 8), locale.Pad(l.Text("Speed")+" (m/s)", 12), l.Text("Path"))
+	cell := func(v *float64, width int) string {
+		if v == nil {
+			return locale.Pad("-", width)
+		}
+		return locale.Pad(number(*v), width)
+	}
+	for _, p := range f.Points {
+		t := "-"
+		if p.Time != nil {
+			t = l.DateTime(*p.Time)
+		}
+		ew.printf("%s %s %s %s %s %s\n",
+			locale.Pad(t, 16), cell(p.Altitude, 12), cell(p.Heading, 8), cell(p.GimbalPitch, 8), cell(p.Speed, 12), p.Path)
+	}
+	return ew.err
+}
+
+// WriteCSV writes one row per shot with a header row. Times are RFC 3339.
+func (f *Flight) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "path", "altitude", "heading", "gimbal_pitch", "gimbal_yaw", "speed", "latitude", "longitude"}); err != nil {
+		return err
+	}
+	for _, p := range f.Points {
+		t := ""
+		if p.Time != nil {
+			t = p.Time.Format(time.RFC3339)
+		}
+		row := []string{t, p.Path, formatFloatPtr(p.Altitude), formatFloatPtr(p.Heading), formatFloatPtr(p.GimbalPitch),
+			formatFloatPtr(p.GimbalYaw), formatFloatPtr(p.Speed), formatFloatPtr(p.Latitude), formatFloatPtr(p.Longitude)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,309 @@
+// Package report renders photo summaries and aggregates them into session
+// reports.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Formats accepted by Write. FormatPaths and FormatNull list only the
+// file paths, one per line or NUL-terminated, for xargs, rsync
+// --files-from and the like. FormatParquet is a binary file, FormatArrow
+// an Arrow IPC stream and FormatSQL a script loading a table, for data
+// tools.
+const (
+	FormatJSON    = "json"
+	FormatCSV     = "csv"
+	FormatPaths   = "paths"
+	FormatNull    = "null"
+	FormatParquet = "parquet"
+	FormatArrow   = "arrow"
+	FormatSQL     = "sql"
+)
+
+// Write renders summaries in the named format.
+func Write(w io.Writer, format string, summaries []*exif.Summary) error {
+	return WriteFields(w, format, summaries, nil)
+}
+
+// WriteFields is Write with the fields of each summary chosen by fields.
+func WriteFields(w io.Writer, format string, summaries []*exif.Summary, fields Fields) error {
+	sel := newSelection(summaries, fields)
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, summaries, sel)
+	case FormatCSV:
+		return writeCSV(w, summaries, nil, sel)
+	case FormatPaths:
+		return WritePaths(w, summaries, '\n')
+	case FormatNull:
+		return WritePaths(w, summaries, 0)
+	case FormatParquet:
+		return writeParquet(w, summaries, sel)
+	case FormatArrow:
+		return writeArrow(w, summaries, sel)
+	case FormatSQL:
+		return writeSQL(w, summaries, sel)
+	}
+	return fmt.Errorf("report: unknown format %q", format)
+}
+
+// WritePaths writes the path of each summary followed by sep. Paths that
+// contain sep are rejected, since the list could not be split again.
+func WritePaths(w io.Writer, summaries []*exif.Summary, sep byte) error {
+	var buf []byte
+	for _, s := range summaries {
+		if strings.IndexByte(s.Path, sep) >= 0 {
+			return fmt.Errorf("report: path %q contains the separator; use --output null", s.Path)
+		}
+		buf = append(append(buf, s.Path...), sep)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// WriteJSON writes summaries as an indented JSON array.
+func WriteJSON(w io.Writer, summaries []*exif.Summary) error {
+	return writeJSON(w, summaries, newSelection(summaries, nil))
+}
+
+func writeJSON(w io.Writer, summaries []*exif.Summary, sel *selection) error {
+	if sel.of == nil {
+		if summaries == nil {
+			summaries = []*exif.Summary{}
+		}
+		return writeIndented(w, summaries)
+	}
+	out := make([]projected, len(summaries))
+	for i, s := range summaries {
+		out[i] = projected{s, sel}
+	}
+	return writeIndented(w, out)
+}
+
+func writeIndented(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// column is a CSV column and the accessor producing its cell.
+type column struct {
+	name  string
+	value func(*exif.Summary) string
+}
+
+var columns = []column{
+	{"path", func(s *exif.Summary) string { return s.Path }},
+	{"make", func(s *exif.Summary) string { return s.Make }},
+	{"model", func(s *exif.Summary) string { return s.Model }},
+	{"lens_model", func(s *exif.Summary) string { return s.LensModel }},
+	{"datetime_original", func(s *exif.Summary) string { return s.DateTimeOriginal }},
+	{"inferred_date", func(s *exif.Summary) string { return s.InferredDate }},
+	{"date_source", func(s *exif.Summary) string { return s.DateSource }},
+	{"exposure_time", func(s *exif.Summary) string { return exif.FormatExposure(s.ExposureTime) }},
+	{"f_number", func(s *exif.Summary) string { return formatFloat(s.FNumber) }},
+	{"iso", func(s *exif.Summary) string { return formatInt(s.ISO) }},
+	{"focal_length", func(s *exif.Summary) string { return formatFloat(s.FocalLength) }},
+	{"focal_length_35mm", func(s *exif.Summary) string { return formatInt(s.FocalLength35mm) }},
+	{"latitude", func(s *exif.Summary) string { return formatFloatPtr(s.Latitude) }},
+	{"longitude", func(s *exif.Summary) string { return formatFloatPtr(s.Longitude) }},
+	{"altitude", func(s *exif.Summary) string { return formatFloatPtr(s.Altitude) }},
+	{"gps_time", func(s *exif.Summary) string { return s.GPSTime }},
+	{"relative_altitude", func(s *exif.Summary) string { return formatFloatPtr(s.RelativeAltitude) }},
+	{"flight_heading", func(s *exif.Summary) string { return formatFloatPtr(s.FlightHeading) }},
+	{"gimbal_pitch", func(s *exif.Summary) string { return formatFloatPtr(s.GimbalPitch) }},
+	{"gimbal_yaw", func(s *exif.Summary) string { return formatFloatPtr(s.GimbalYaw) }},
+	{"flight_speed", func(s *exif.Summary) string { return formatFloatPtr(s.FlightSpeed) }},
+	{"position", func(s *exif.Summary) string { return s.Position }},
+	{"sun_elevation", func(s *exif.Summary) string { return formatFloatPtr(s.SunElevation) }},
+	{"light_phase", func(s *exif.Summary) string { return s.LightPhase }},
+	{"moon_phase", func(s *exif.Summary) string { return formatFloatPtr(s.MoonPhase) }},
+	{"moon_illumination", func(s *exif.Summary) string { return formatFloatPtr(s.MoonIllumination) }},
+	{"moon_altitude", func(s *exif.Summary) string { return formatFloatPtr(s.MoonAltitude) }},
+	{"stabilization", func(s *exif.Summary) string { return s.Stabilization }},
+	{"stabilization_mode", func(s *exif.Summary) string { return s.StabilizationMode }},
+	{"drive_mode", func(s *exif.Summary) string { return s.DriveMode }},
+	{"shutter_type", func(s *exif.Summary) string { return s.ShutterType }},
+	{"focus_mode", func(s *exif.Summary) string { return s.FocusMode }},
+	{"live_photo_id", func(s *exif.Summary) string { return s.LivePhotoID }},
+	{"computational", func(s *exif.Summary) string { return strings.Join(s.Computational, ";") }},
+	{"projection", func(s *exif.Summary) string { return s.Projection }},
+	{"pano_full_width", func(s *exif.Summary) string { return formatInt(s.PanoFullWidth) }},
+	{"pano_fov", func(s *exif.Summary) string { return formatFloat(s.PanoFOV) }},
+	{"pose_heading", func(s *exif.Summary) string { return formatFloatPtr(s.PoseHeading) }},
+	{"composite", func(s *exif.Summary) string { return s.Composite }},
+	{"composite_frames", func(s *exif.Summary) string { return formatInt(s.CompositeFrames) }},
+	{"codec", func(s *exif.Summary) string { return s.Codec }},
+	{"encoding", func(s *exif.Summary) string { return s.Encoding }},
+	{"bit_depth", func(s *exif.Summary) string { return formatInt(s.BitDepth) }},
+	{"components", func(s *exif.Summary) string { return formatInt(s.Components) }},
+	{"chroma_subsampling", func(s *exif.Summary) string { return s.ChromaSubsampling }},
+	{"jfif_version", func(s *exif.Summary) string { return s.JFIFVersion }},
+	{"jfif_density_unit", func(s *exif.Summary) string { return s.JFIFDensityUnit }},
+	{"jfif_x_density", func(s *exif.Summary) string { return formatInt(s.JFIFXDensity) }},
+	{"jfif_y_density", func(s *exif.Summary) string { return formatInt(s.JFIFYDensity) }},
+	{"x_resolution", func(s *exif.Summary) string { return formatFloat(s.XResolution) }},
+	{"y_resolution", func(s *exif.Summary) string { return formatFloat(s.YResolution) }},
+	{"resolution_unit", func(s *exif.Summary) string { return s.ResolutionUnit }},
+	{"print_width", func(s *exif.Summary) string { return formatFloat(s.PrintWidth) }},
+	{"print_height", func(s *exif.Summary) string { return formatFloat(s.PrintHeight) }},
+	{"aspect_ratio", func(s *exif.Summary) string { return s.AspectRatio }},
+	{"crop_mode", func(s *exif.Summary) string { return s.CropMode }},
+	{"mean_luminance", func(s *exif.Summary) string { return formatFloat(s.MeanLuminance) }},
+	{"clipped_highlights", func(s *exif.Summary) string { return formatFloat(s.ClippedHighlights) }},
+	{"clipped_shadows", func(s *exif.Summary) string { return formatFloat(s.ClippedShadows) }},
+	{"luminance_histogram", func(s *exif.Summary) string { return joinInts(s.LuminanceHistogram) }},
+	{"exposure_warning", func(s *exif.Summary) string { return s.ExposureWarning }},
+	{"dominant_color", func(s *exif.Summary) string { return s.DominantColor }},
+	{"colors", func(s *exif.Summary) string { return strings.Join(s.Colors, ";") }},
+	{"image_hash", func(s *exif.Summary) string { return s.ImageHash }},
+	{"description", func(s *exif.Summary) string { return s.Description }},
+	{"comment", func(s *exif.Summary) string { return s.Comment }},
+	{"title", func(s *exif.Summary) string { return s.Title }},
+	{"keywords", func(s *exif.Summary) string { return strings.Join(s.Keywords, ";") }},
+	{"rating", func(s *exif.Summary) string { return formatInt(s.Rating) }},
+	{"collections", func(s *exif.Summary) string { return strings.Join(s.Collections, ";") }},
+	{"editor", func(s *exif.Summary) string { return s.Editor }},
+	{"edited", func(s *exif.Summary) string { return formatBool(s.Edited, s.Editor != "") }},
+	{"edit_modules", func(s *exif.Summary) string { return strings.Join(s.EditModules, ";") }},
+	{"c2pa_manifests", func(s *exif.Summary) string { return formatInt(s.C2PAManifests) }},
+	{"c2pa_claim_generator", func(s *exif.Summary) string { return s.C2PAClaimGenerator }},
+	{"c2pa_signer", func(s *exif.Summary) string { return s.C2PASigner }},
+	{"c2pa_actions", func(s *exif.Summary) string { return strings.Join(s.C2PAActions, ";") }},
+	{"c2pa_hash", func(s *exif.Summary) string { return s.C2PAHash }},
+	{"artist", func(s *exif.Summary) string { return s.Artist }},
+	{"copyright", func(s *exif.Summary) string { return s.Copyright }},
+	{"subject_distance", func(s *exif.Summary) string { return formatFloat(s.SubjectDistance) }},
+	{"hyperfocal_distance", func(s *exif.Summary) string { return formatFloat(s.HyperfocalDistance) }},
+	{"exposure_mode", func(s *exif.Summary) string { return s.ExposureMode }},
+	{"gain_control", func(s *exif.Summary) string { return s.GainControl }},
+	{"digital_zoom_ratio", func(s *exif.Summary) string { return formatFloat(s.DigitalZoomRatio) }},
+	{"subject_distance_range", func(s *exif.Summary) string { return s.SubjectDistanceRange }},
+	{"custom_rendered", func(s *exif.Summary) string { return s.CustomRendered }},
+	{"contrast", func(s *exif.Summary) string { return s.Contrast }},
+	{"saturation", func(s *exif.Summary) string { return s.Saturation }},
+	{"sharpness", func(s *exif.Summary) string { return s.Sharpness }},
+}
+
+// sourcesColumn renders field provenance as "field=Location:Tag" pairs,
+// for the fields sel writes.
+func sourcesColumn(sel *selection) column {
+	return column{"sources", func(s *exif.Summary) string {
+		fields := make([]string, 0, len(s.Sources))
+		for f := range s.Sources {
+			if sel.writes(s, f) {
+				fields = append(fields, f)
+			}
+		}
+		sort.Strings(fields)
+		for i, f := range fields {
+			src := s.Sources[f]
+			fields[i] = f + "=" + src.Location
+			if src.Tag != "" {
+				fields[i] += ":" + src.Tag
+			}
+		}
+		return strings.Join(fields, ";")
+	}}
+}
+
+// WriteCSV writes summaries as CSV with a header row. A trailing sources
+// column is added when any summary carries provenance.
+func WriteCSV(w io.Writer, summaries []*exif.Summary) error {
+	return writeCSV(w, summaries, nil, newSelection(summaries, nil))
+}
+
+// writeCSV is WriteCSV with lead columns placed before the standard ones
+// and those of the fields sel writes.
+func writeCSV(w io.Writer, summaries []*exif.Summary, lead []column, sel *selection) error {
+	cols := lead[:len(lead):len(lead)]
+	for _, c := range columns {
+		if !sel.column(c.name) {
+			continue
+		}
+		c := c
+		cols = append(cols, column{c.name, func(s *exif.Summary) string {
+			if !sel.writes(s, c.name) {
+				return ""
+			}
+			return c.value(s)
+		}})
+	}
+	for _, s := range summaries {
+		if len(s.Sources) > 0 {
+			cols = append(cols, sourcesColumn(sel))
+			break
+		}
+	}
+	return writeColumns(w, summaries, cols)
+}
+
+// writeColumns writes a header row and one row per summary.
+func writeColumns(w io.Writer, summaries []*exif.Summary, columns []column) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, s := range summaries {
+		for i, c := range columns {
+			row[i] = c.value(s)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// joinInts joins v with semicolons, as list columns are.
+func joinInts(v []int) string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ";")
+}
+
+func formatFloat(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatBool renders v as true or false when known, else "".
+func formatBool(v, known bool) string {
+	if !known {
+		return ""
+	}
+	return strconv.FormatBool(v)
+}
+
+func formatFloatPtr(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
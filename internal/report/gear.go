@@ -0,0 +1,222 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Kinds of a GearItem.
+const (
+	GearBody = "body"
+	GearLens = "lens"
+)
+
+// Gear is an inventory of the camera bodies and lenses that took a set of
+// photos, told apart by serial number, for insurance and asset records.
+type Gear struct {
+	Items []GearItem `json:"items"`
+	// Unidentified counts the photos whose body has no serial number.
+	Unidentified int `json:"unidentified"`
+}
+
+// GearItem is one body or lens.
+type GearItem struct {
+	Kind  string `json:"kind"`
+	Make  string `json:"make,omitempty"`
+	Model string `json:"model,omitempty"`
+	// Serial is the body or lens serial number; InternalSerial is the
+	// body's maker note serial, which some bodies write instead.
+	Serial         string `json:"serial,omitempty"`
+	InternalSerial string `json:"internal_serial,omitempty"`
+	Photos         int    `json:"photos"`
+	// First and Last are the earliest and latest capture times.
+	First *time.Time `json:"first,omitempty"`
+	Last  *time.Time `json:"last,omitempty"`
+	// Example is the path of the first photo seen.
+	Example string `json:"example"`
+	// Firmware is the history of a body's firmware versions in capture
+	// order; a new span starts whenever the version changes.
+	Firmware []Firmware `json:"firmware,omitempty"`
+}
+
+// Firmware is a run of photos taken with one firmware version.
+type Firmware struct {
+	Version string    `json:"version"`
+	Photos  int       `json:"photos"`
+	First   time.Time `json:"first"`
+	Last    time.Time `json:"last"`
+}
+
+// editorSoftware are words of the Software values written by editors
+// rather than camera firmware.
+var editorSoftware = []string{
+	"adobe", "lightroom", "photoshop", "capture one", "darktable", "rawtherapee", "gimp", "luminar",
+	"dxo", "affinity", "digikam", "snapseed", "picasa", "windows", "macos", "photos", "shootlog",
+}
+
+// firmware returns the camera firmware version in s, or "" when Software
+// is empty or names an editor.
+func firmware(s *exif.Summary) string {
+	v := strings.ToLower(s.Software)
+	for _, w := range editorSoftware {
+		if strings.Contains(v, w) {
+			return ""
+		}
+	}
+	return s.Software
+}
+
+// NewGear lists every body and lens serial number in summaries, bodies
+// first, each ordered by make, model and serial.
+func NewGear(summaries []*exif.Summary) *Gear {
+	g := &Gear{Items: []GearItem{}}
+	type key struct{ kind, make, model, serial, internal string }
+	seen := map[key]int{}
+	type shot struct {
+		t       time.Time
+		version string
+	}
+	shots := map[int][]shot{}
+	add := func(item GearItem, s *exif.Summary) {
+		k := key{item.Kind, item.Make, item.Model, item.Serial, item.InternalSerial}
+		i, ok := seen[k]
+		if !ok {
+			i = len(g.Items)
+			seen[k] = i
+			item.Example = s.Path
+			g.Items = append(g.Items, item)
+		}
+		it := &g.Items[i]
+		it.Photos++
+		if t, ok := s.CaptureTime(); ok {
+			if v := firmware(s); v != "" && item.Kind == GearBody {
+				shots[i] = append(shots[i], shot{t, v})
+			}
+			if it.First == nil || t.Before(*it.First) {
+				it.First = &t
+			}
+			if it.Last == nil || t.After(*it.Last) {
+				it.Last = &t
+			}
+		}
+	}
+	for _, s := range summaries {
+		if s.BodySerial != "" || s.InternalSerial != "" {
+			add(GearItem{Kind: GearBody, Make: s.Make, Model: s.Model, Serial: s.BodySerial, InternalSerial: s.InternalSerial}, s)
+		} else {
+			g.Unidentified++
+		}
+		if s.LensSerial != "" {
+			lensMake := s.LensMake
+			if lensMake == "" {
+				lensMake = s.Make
+			}
+			add(GearItem{Kind: GearLens, Make: lensMake, Model: s.LensModel, Serial: s.LensSerial}, s)
+		}
+	}
+	for i, sh := range shots {
+		sort.SliceStable(sh, func(a, b int) bool { return sh[a].t.Before(sh[b].t) })
+		var spans []Firmware
+		for _, x := range sh {
+			if n := len(spans); n > 0 && spans[n-1].Version == x.version {
+				spans[n-1].Photos++
+				spans[n-1].Last = x.t
+				continue
+			}
+			spans = append(spans, Firmware{Version: x.version, Photos: 1, First: x.t, Last: x.t})
+		}
+		g.Items[i].Firmware = spans
+	}
+	sort.SliceStable(g.Items, func(i, j int) bool {
+		a, b := g.Items[i], g.Items[j]
+		switch {
+		case a.Kind != b.Kind:
+			return a.Kind == GearBody
+		case a.Make != b.Make:
+			return a.Make < b.Make
+		case a.Model != b.Model:
+			return a.Model < b.Model
+		case a.Serial != b.Serial:
+			return a.Serial < b.Serial
+		}
+		return a.InternalSerial < b.InternalSerial
+	})
+	return g
+}
+
+// WriteText renders the inventory as a table in locale l, followed by the
+// firmware history of each body; nil selects English.
+func (g *Gear) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	ew.printf("%s %s %s %s %s %s\n",
+		locale.Pad(l.Text("Kind"), 6), locale.Pad(l.Text("Make"), 12), locale.Pad(l.Text("Model"), 24),
+		locale.Pad(l.Text("Serial"), 16), locale.Pad(l.Text("Photos"), 6), l.Text("Period"))
+	for _, it := range g.Items {
+		period := "-"
+		if it.First != nil {
+			period = l.DateTime(*it.First) + " - " + l.DateTime(*it.Last)
+		}
+		ew.printf("%s %s %s %s %s %s\n",
+			locale.Pad(l.Text(it.Kind), 6), locale.Pad(it.Make, 12), locale.Pad(it.Model, 24),
+			locale.Pad(it.serial(), 16), locale.Pad(strconv.Itoa(it.Photos), 6), period)
+	}
+	if g.Unidentified > 0 {
+		ew.printf(l.Text("%d photos name no body serial number\n"), g.Unidentified)
+	}
+	for _, it := range g.Items {
+		if len(it.Firmware) == 0 {
+			continue
+		}
+		ew.printf("\n%s %s %s %s\n", l.Text("Firmware history:"), it.Make, it.Model, it.serial())
+		for i, f := range it.Firmware {
+			ew.printf("  %s %s - %s  %s\n", locale.Pad(f.Version, 16), l.DateTime(f.First), l.DateTime(f.Last), strconv.Itoa(f.Photos))
+			if i+1 < len(it.Firmware) {
+				ew.printf(l.Text("  changed to %s between %s and %s\n"), it.Firmware[i+1].Version, l.DateTime(f.Last), l.DateTime(it.Firmware[i+1].First))
+			}
+		}
+	}
+	return ew.err
+}
+
+// serial returns the serial number, or the internal one without it.
+func (it GearItem) serial() string {
+	if it.Serial == "" {
+		return it.InternalSerial
+	}
+	return it.Serial
+}
+
+// WriteCSV writes one row per body or lens with a header row. Times are
+// RFC 3339, and firmware lists a body's versions in order.
+func (g *Gear) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"kind", "make", "model", "serial", "internal_serial", "photos", "first", "last", "example", "firmware"}); err != nil {
+		return err
+	}
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for _, it := range g.Items {
+		var versions []string
+		for _, f := range it.Firmware {
+			versions = append(versions, f.Version)
+		}
+		row := []string{it.Kind, it.Make, it.Model, it.Serial, it.InternalSerial, strconv.Itoa(it.Photos), format(it.First), format(it.Last), it.Example,
+			strings.Join(versions, ";")}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
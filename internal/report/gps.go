@@ -0,0 +1,30 @@
+package report
+
+import (
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/geo"
+)
+
+// FormatGPS renders the coordinates of summaries in format, one of
+// geo.Formats, keeping digits decimal places of degrees or geo.Full.
+// Decimal degrees stay in latitude and longitude, truncated; every other
+// format replaces them with Position, so the output never carries more
+// precision than asked for.
+func FormatGPS(summaries []*exif.Summary, format string, digits int) error {
+	if _, err := geo.Format(0, 0, format, digits); err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		if s.Latitude == nil || s.Longitude == nil {
+			continue
+		}
+		if format == geo.Decimal {
+			lat, lon := geo.Truncate(*s.Latitude, digits), geo.Truncate(*s.Longitude, digits)
+			s.Latitude, s.Longitude = &lat, &lon
+			continue
+		}
+		s.Position, _ = geo.Format(*s.Latitude, *s.Longitude, format, digits)
+		s.Latitude, s.Longitude = nil, nil
+	}
+	return nil
+}
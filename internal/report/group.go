@@ -0,0 +1,148 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Group keys accepted by GroupBy.
+const (
+	GroupDate     = "date"
+	GroupCamera   = "camera"
+	GroupLens     = "lens"
+	GroupLocation = "location"
+)
+
+// GroupKeys lists the accepted group keys.
+var GroupKeys = []string{GroupDate, GroupCamera, GroupLens, GroupLocation}
+
+// Group is one level of grouped output. Leaf groups hold photos; the
+// others hold the next level.
+type Group struct {
+	Key    string          `json:"key"`
+	Value  string          `json:"value"`
+	Count  int             `json:"count"`
+	Groups []*Group        `json:"groups,omitempty"`
+	Photos []*exif.Summary `json:"photos,omitempty"`
+}
+
+// GroupValue returns the value s is grouped under for key:
+//
+//   - date: the capture date, in the time zone it was recorded in
+//   - camera: the model, prefixed with the make unless it already is
+//   - lens: the lens model
+//   - location: the coordinates rounded to 0.01° (about 1 km)
+//
+// Photos without the value are grouped under "unknown".
+func GroupValue(s *exif.Summary, key string) (string, error) {
+	var v string
+	switch key {
+	case GroupDate:
+		if t, ok := s.CaptureTime(); ok {
+			v = t.Format("2006-01-02")
+		}
+	case GroupCamera:
+		v = s.Model
+		if s.Make != "" && !strings.HasPrefix(strings.ToLower(s.Model), strings.ToLower(s.Make)) {
+			v = strings.TrimSpace(s.Make + " " + s.Model)
+		}
+	case GroupLens:
+		v = s.LensModel
+	case GroupLocation:
+		v = s.Position
+		if s.Latitude != nil && s.Longitude != nil {
+			v = strconv.FormatFloat(*s.Latitude, 'f', 2, 64) + "," + strconv.FormatFloat(*s.Longitude, 'f', 2, 64)
+		}
+	default:
+		return "", fmt.Errorf("report: unknown group key %q", key)
+	}
+	return orUnknown(v), nil
+}
+
+// GroupBy nests summaries by each key in turn. Groups are ordered by
+// value, with "unknown" last; photos keep their order within a group.
+func GroupBy(summaries []*exif.Summary, keys []string) ([]*Group, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	key := keys[0]
+	byValue := map[string]*Group{}
+	var groups []*Group
+	for _, s := range summaries {
+		v, err := GroupValue(s, key)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := byValue[v]
+		if !ok {
+			g = &Group{Key: key, Value: v}
+			byValue[v] = g
+			groups = append(groups, g)
+		}
+		g.Count++
+		g.Photos = append(g.Photos, s)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Value, groups[j].Value
+		if (a == unknown) != (b == unknown) {
+			return b == unknown
+		}
+		return a < b
+	})
+	if len(keys) > 1 {
+		for _, g := range groups {
+			sub, err := GroupBy(g.Photos, keys[1:])
+			if err != nil {
+				return nil, err
+			}
+			g.Groups, g.Photos = sub, nil
+		}
+	}
+	return groups, nil
+}
+
+// leaves returns the photos of groups in output order.
+func leaves(groups []*Group) []*exif.Summary {
+	var out []*exif.Summary
+	for _, g := range groups {
+		out = append(out, g.Photos...)
+		out = append(out, leaves(g.Groups)...)
+	}
+	return out
+}
+
+// WriteGroups renders grouped summaries: nested JSON objects, CSV rows
+// in group order led by one column per key, or the paths in group order.
+// fields, when not nil, chooses the fields of each summary as for
+// WriteFields.
+func WriteGroups(w io.Writer, format string, groups []*Group, keys []string, fields Fields) error {
+	photos := leaves(groups)
+	sel := newSelection(photos, fields)
+	switch format {
+	case FormatJSON:
+		if sel.of != nil {
+			return writeIndented(w, sel.groups(groups))
+		}
+		if groups == nil {
+			groups = []*Group{}
+		}
+		return writeIndented(w, groups)
+	case FormatCSV:
+		lead := make([]column, len(keys))
+		for i, k := range keys {
+			lead[i] = column{k, func(s *exif.Summary) string {
+				v, _ := GroupValue(s, k)
+				return v
+			}}
+		}
+		return writeCSV(w, photos, lead, sel)
+	case FormatPaths, FormatNull, FormatParquet, FormatArrow, FormatSQL:
+		return WriteFields(w, format, photos, fields)
+	}
+	return fmt.Errorf("report: unknown format %q", format)
+}
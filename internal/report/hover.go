@@ -0,0 +1,59 @@
+package report
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Hover renders the gist of a summary as Markdown for the popup an editor
+// shows over a file name: the title, camera and lens, exposure, time,
+// place, rating and keywords, one paragraph each, in locale l.
+func Hover(s *exif.Summary, l *locale.Locale) string {
+	if l == nil {
+		l = locale.English
+	}
+	title := "**" + markdownEscape(filepath.Base(s.Path)) + "**"
+	if s.Title != "" {
+		title += " " + markdownEscape(s.Title)
+	}
+	parts := []string{title}
+	add := func(v string) {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, markdownEscape(v))
+		}
+	}
+	gear := strings.TrimSpace(s.Make + " " + s.Model)
+	if s.LensModel != "" {
+		gear = strings.TrimPrefix(gear+" · "+s.LensModel, " · ")
+	}
+	add(gear)
+	add(exposureText(s))
+	if t, ok := s.CaptureTime(); ok {
+		add(l.DateTime(t))
+	}
+	if s.Latitude != nil && s.Longitude != nil {
+		add(formatFloatPtr(s.Latitude) + ", " + formatFloatPtr(s.Longitude))
+	} else {
+		add(s.Position)
+	}
+	if s.Rating > 0 {
+		add(strings.Repeat("★", min(s.Rating, 5)) + strings.Repeat("☆", 5-min(s.Rating, 5)))
+	}
+	add(strings.Join(s.Keywords, ", "))
+	return strings.Join(parts, "\n\n")
+}
+
+// markdownEscape keeps metadata text from being read as Markdown.
+func markdownEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>#|~", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,282 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/album"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/geo"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/tmplfunc"
+)
+
+// DefaultTiles is the tile source of HTML maps: OpenStreetMap's servers.
+const DefaultTiles = "https://tile.openstreetmap.org/{z}/{x}/{y}.png"
+
+// Map dimensions in pixels. The zoom is the largest at which every photo
+// fits inside mapPadding of the edges.
+const (
+	mapWidth   = 768
+	mapHeight  = 512
+	mapPadding = 24
+	mapMaxZoom = 16
+)
+
+// WriteHTML renders the session and its photos as a standalone HTML page
+// in locale l. Geotagged photos are drawn on a map assembled from tiles,
+// a URL template with {z}, {x} and {y} or a directory holding an offline
+// z/x/y.png bundle; "" selects DefaultTiles. Markers link to the photo's
+// details, and the photos taken at known times are joined by a track in
+// capture order. An album, when given, titles the page, shows its cover
+// and captions the photos.
+func WriteHTML(w io.Writer, s *Session, summaries []*exif.Summary, l *locale.Locale, tiles string, a *album.Album) error {
+	var text bytes.Buffer
+	if err := s.WriteText(&text, l); err != nil {
+		return err
+	}
+	if l == nil {
+		l = locale.English
+	}
+	page := htmlPage{Lang: l.Tag, Title: l.Text("Shooting report"), Text: text.String(), L: l}
+	if a != nil {
+		if a.Title != "" {
+			page.Title = a.Title
+		}
+		page.Cover = &htmlCover{Path: a.CoverPath(), Caption: a.Caption(a.CoverPath())}
+	}
+	if len(s.Elevation) > 1 {
+		var svg bytes.Buffer
+		if err := s.WriteElevationSVG(&svg); err != nil {
+			return err
+		}
+		// The SVG is generated with every text value escaped.
+		page.Elevation = template.HTML(svg.String())
+	}
+	for i, sum := range summaries {
+		p := htmlPhoto{
+			ID:       fmt.Sprintf("photo-%d", i+1),
+			Path:     sum.Path,
+			Camera:   strings.TrimSpace(sum.Make + " " + sum.Model),
+			Lens:     sum.LensModel,
+			Exposure: exposureText(sum),
+		}
+		if a != nil {
+			p.Caption = a.Caption(sum.Path)
+		}
+		if t, ok := sum.CaptureTime(); ok {
+			p.Taken = l.DateTime(t)
+		}
+		if sum.Latitude != nil && sum.Longitude != nil {
+			p.Location = formatFloatPtr(sum.Latitude) + ", " + formatFloatPtr(sum.Longitude)
+		} else if sum.Position != "" {
+			p.Location = sum.Position
+		}
+		page.Photos = append(page.Photos, p)
+	}
+	page.Map = newStaticMap(summaries, tiles)
+	return htmlTemplate.Execute(w, page)
+}
+
+type htmlPage struct {
+	Lang      string
+	Title     string
+	Cover     *htmlCover
+	Text      string
+	Map       *staticMap
+	Elevation template.HTML
+	Photos    []htmlPhoto
+	L         *locale.Locale
+}
+
+type htmlPhoto struct {
+	ID, Path, Caption, Camera, Lens, Taken, Exposure, Location string
+}
+
+type htmlCover struct {
+	Path, Caption string
+}
+
+func exposureText(s *exif.Summary) string {
+	var parts []string
+	if s.ExposureTime > 0 {
+		parts = append(parts, exif.FormatExposure(s.ExposureTime)+"s")
+	}
+	if s.FNumber > 0 {
+		parts = append(parts, "f/"+formatFloat(s.FNumber))
+	}
+	if s.ISO > 0 {
+		parts = append(parts, "ISO "+strconv.Itoa(s.ISO))
+	}
+	if s.FocalLength > 0 {
+		parts = append(parts, formatFloat(s.FocalLength)+"mm")
+	}
+	return strings.Join(parts, " ")
+}
+
+// staticMap is a map laid out in page pixels, drawn without scripts.
+type staticMap struct {
+	Width, Height int
+	Tiles         []mapTile
+	Markers       []mapMarker
+	// Track holds the SVG polyline points of the capture-order track.
+	Track       string
+	Attribution bool
+}
+
+type mapTile struct {
+	URL       string
+	Left, Top int
+}
+
+type mapMarker struct {
+	Left, Top int
+	Href      string
+	Title     string
+}
+
+// newStaticMap lays out the geotagged photos of summaries, or returns nil
+// when there are none.
+func newStaticMap(summaries []*exif.Summary, tiles string) *staticMap {
+	type point struct {
+		lat, lon float64
+		index    int
+		t        time.Time
+		timed    bool
+	}
+	var points []point
+	for i, s := range summaries {
+		if s.Latitude == nil || s.Longitude == nil {
+			continue
+		}
+		t, ok := s.CaptureTime()
+		points = append(points, point{*s.Latitude, *s.Longitude, i, t, ok})
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	bounds := func(zoom int) (minX, minY, maxX, maxY float64) {
+		minX, minY, maxX, maxY = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, p := range points {
+			x, y := geo.Project(p.lat, p.lon, zoom)
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x), max(maxY, y)
+		}
+		return minX, minY, maxX, maxY
+	}
+	zoom := mapMaxZoom
+	for ; zoom > 0; zoom-- {
+		minX, minY, maxX, maxY := bounds(zoom)
+		if maxX-minX <= mapWidth-2*mapPadding && maxY-minY <= mapHeight-2*mapPadding {
+			break
+		}
+	}
+	minX, minY, maxX, maxY := bounds(zoom)
+	left := math.Round((minX+maxX)/2 - mapWidth/2)
+	top := math.Round((minY+maxY)/2 - mapHeight/2)
+
+	m := &staticMap{Width: mapWidth, Height: mapHeight, Attribution: tiles == "" || tiles == DefaultTiles}
+	n := 1 << zoom
+	for ty := int(math.Floor(top / geo.TileSize)); float64(ty*geo.TileSize) < top+mapHeight; ty++ {
+		if ty < 0 || ty >= n {
+			continue
+		}
+		for tx := int(math.Floor(left / geo.TileSize)); float64(tx*geo.TileSize) < left+mapWidth; tx++ {
+			m.Tiles = append(m.Tiles, mapTile{
+				URL:  tileURL(tiles, zoom, ((tx%n)+n)%n, ty),
+				Left: tx*geo.TileSize - int(left),
+				Top:  ty*geo.TileSize - int(top),
+			})
+		}
+	}
+	for _, p := range points {
+		x, y := geo.Project(p.lat, p.lon, zoom)
+		m.Markers = append(m.Markers, mapMarker{
+			Left:  int(math.Round(x - left)),
+			Top:   int(math.Round(y - top)),
+			Href:  fmt.Sprintf("#photo-%d", p.index+1),
+			Title: summaries[p.index].Path,
+		})
+	}
+
+	var timed []point
+	for _, p := range points {
+		if p.timed {
+			timed = append(timed, p)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].t.Before(timed[j].t) })
+	if len(timed) > 1 {
+		coords := make([]string, len(timed))
+		for i, p := range timed {
+			x, y := geo.Project(p.lat, p.lon, zoom)
+			coords[i] = fmt.Sprintf("%.1f,%.1f", x-left, y-top)
+		}
+		m.Track = strings.Join(coords, " ")
+	}
+	return m
+}
+
+// tileURL fills in a tile template. A template without placeholders is a
+// directory of z/x/y.png tiles.
+func tileURL(tiles string, z, x, y int) string {
+	if tiles == "" {
+		tiles = DefaultTiles
+	}
+	if !strings.Contains(tiles, "{z}") {
+		tiles = strings.TrimSuffix(tiles, "/") + "/{z}/{x}/{y}.png"
+	}
+	return strings.NewReplacer("{z}", strconv.Itoa(z), "{x}", strconv.Itoa(x), "{y}", strconv.Itoa(y)).Replace(tiles)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(tmplfunc.Funcs()).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.map { position: relative; overflow: hidden; border: 1px solid #999; }
+.map img { position: absolute; width: 256px; height: 256px; }
+.map svg { position: absolute; left: 0; top: 0; }
+.map a { position: absolute; width: 12px; height: 12px; margin: -6px 0 0 -6px; border-radius: 50%; background: #d33; border: 2px solid #fff; }
+.attribution { font-size: small; color: #666; }
+.cover img { max-width: 768px; max-height: 512px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th { text-align: left; padding-right: 1em; }
+:target { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Cover}}<figure class="cover"><img src="{{.Path}}" alt="{{.Caption}}">{{if .Caption}}<figcaption>{{.Caption}}</figcaption>{{end}}</figure>
+{{end}}<pre>{{.Text}}</pre>
+{{with .Map}}<h2>{{$.L.Text "Map"}}</h2>
+<div class="map" style="width: {{.Width}}px; height: {{.Height}}px">
+{{range .Tiles}}<img src="{{.URL}}" style="left: {{.Left}}px; top: {{.Top}}px" alt="">
+{{end}}{{if .Track}}<svg width="{{.Width}}" height="{{.Height}}"><polyline points="{{.Track}}" fill="none" stroke="#d33" stroke-width="3" stroke-opacity="0.7"/></svg>
+{{end}}{{range .Markers}}<a href="{{.Href}}" title="{{.Title}}" style="left: {{.Left}}px; top: {{.Top}}px"></a>
+{{end}}</div>
+{{if .Attribution}}<p class="attribution">© OpenStreetMap contributors</p>
+{{end}}{{end}}{{with .Elevation}}<h2>{{$.L.Text "Elevation profile"}}</h2>
+{{.}}{{end}}<h2>{{.L.Text "Photos"}}</h2>
+{{range .Photos}}<table id="{{.ID}}">
+<tr><th colspan="2"><a href="{{.Path}}">{{.Path}}</a></th></tr>
+{{if .Caption}}<tr><th>{{$.L.Text "Caption"}}</th><td>{{.Caption}}</td></tr>
+{{end}}{{if .Camera}}<tr><th>{{$.L.Text "Camera"}}</th><td>{{.Camera}}</td></tr>
+{{end}}{{if .Lens}}<tr><th>{{$.L.Text "Lens"}}</th><td>{{.Lens}}</td></tr>
+{{end}}{{if .Taken}}<tr><th>{{$.L.Text "Taken"}}</th><td>{{.Taken}}</td></tr>
+{{end}}{{if .Exposure}}<tr><th>{{$.L.Text "Exposure"}}</th><td>{{.Exposure}}</td></tr>
+{{end}}{{if .Location}}<tr><th>{{$.L.Text "Location"}}</th><td>{{.Location}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
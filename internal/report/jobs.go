@@ -0,0 +1,197 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/job"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Jobs summarizes the photos of each client job, for invoicing and studio
+// records.
+type Jobs struct {
+	Jobs []JobSummary `json:"jobs"`
+	// Unassigned counts the photos that belong to no job.
+	Unassigned int `json:"unassigned"`
+}
+
+// JobSummary is the work done for one job.
+type JobSummary struct {
+	Client string `json:"client,omitempty"`
+	Job    string `json:"job"`
+	Photos int    `json:"photos"`
+	// Deliverables counts the photos delivered, when the config file says
+	// which those are.
+	Deliverables *int `json:"deliverables,omitempty"`
+	// First and Last are the earliest and latest capture times, and Days
+	// the days with photos.
+	First *time.Time `json:"first,omitempty"`
+	Last  *time.Time `json:"last,omitempty"`
+	Days  int        `json:"days"`
+	// Cameras and Lenses count the photos per body and lens model, most
+	// used first.
+	Cameras []GearCount `json:"cameras"`
+	Lenses  []GearCount `json:"lenses"`
+}
+
+// GearCount is the number of photos taken with one camera or lens model.
+type GearCount struct {
+	Name   string `json:"name"`
+	Photos int    `json:"photos"`
+}
+
+// NewJobs groups summaries by the job m assigns them, ordered by client and
+// job.
+func NewJobs(summaries []*exif.Summary, m *job.Matcher) *Jobs {
+	type key struct{ client, job string }
+	type acc struct {
+		JobSummary
+		days            map[string]bool
+		cameras, lenses map[string]int
+	}
+	byJob := map[key]*acc{}
+	j := &Jobs{Jobs: []JobSummary{}}
+	for _, s := range summaries {
+		client, name := m.Job(s)
+		if name == "" {
+			j.Unassigned++
+			continue
+		}
+		a, ok := byJob[key{client, name}]
+		if !ok {
+			a = &acc{JobSummary: JobSummary{Client: client, Job: name}, days: map[string]bool{}, cameras: map[string]int{}, lenses: map[string]int{}}
+			if m.CountsDeliverables() {
+				a.Deliverables = new(int)
+			}
+			byJob[key{client, name}] = a
+		}
+		a.Photos++
+		if m.Deliverable(s) {
+			*a.Deliverables++
+		}
+		if t, ok := s.CaptureTime(); ok {
+			a.days[t.Format("2006-01-02")] = true
+			if a.First == nil || t.Before(*a.First) {
+				a.First = &t
+			}
+			if a.Last == nil || t.After(*a.Last) {
+				a.Last = &t
+			}
+		}
+		if c := strings.TrimSpace(s.Make + " " + s.Model); c != "" {
+			a.cameras[c]++
+		}
+		if s.LensModel != "" {
+			a.lenses[s.LensModel]++
+		}
+	}
+	for _, a := range byJob {
+		a.Days = len(a.days)
+		a.Cameras, a.Lenses = gearCounts(a.cameras), gearCounts(a.lenses)
+		j.Jobs = append(j.Jobs, a.JobSummary)
+	}
+	sort.Slice(j.Jobs, func(a, b int) bool {
+		x, y := j.Jobs[a], j.Jobs[b]
+		if x.Client != y.Client {
+			return x.Client < y.Client
+		}
+		return x.Job < y.Job
+	})
+	return j
+}
+
+func gearCounts(m map[string]int) []GearCount {
+	out := make([]GearCount, 0, len(m))
+	for name, n := range m {
+		out = append(out, GearCount{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Photos != out[j].Photos {
+			return out[i].Photos > out[j].Photos
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// formatGear formats each count with format, given the name and photos,
+// joined by sep.
+func formatGear(counts []GearCount, format, sep string) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf(format, c.Name, c.Photos)
+	}
+	return strings.Join(parts, sep)
+}
+
+// WriteText renders one block per job in locale l; nil selects English.
+func (j *Jobs) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	for i, js := range j.Jobs {
+		if i > 0 {
+			ew.printf("\n")
+		}
+		if js.Client != "" {
+			ew.printf("%s / %s\n", js.Client, js.Job)
+		} else {
+			ew.printf("%s\n", js.Job)
+		}
+		ew.printf("  %s %d", locale.Pad(l.Text("Photos:"), 16), js.Photos)
+		if js.Deliverables != nil {
+			ew.printf(l.Text(" (%d delivered)"), *js.Deliverables)
+		}
+		ew.printf("\n")
+		if js.First != nil {
+			ew.printf("  %s %s - %s"+l.Text(" (%d days)")+"\n", locale.Pad(l.Text("Period:"), 16), l.DateTime(*js.First), l.DateTime(*js.Last), js.Days)
+		}
+		if len(js.Cameras) > 0 {
+			ew.printf("  %s %s\n", locale.Pad(l.Text("Cameras:"), 16), formatGear(js.Cameras, "%s (%d)", ", "))
+		}
+		if len(js.Lenses) > 0 {
+			ew.printf("  %s %s\n", locale.Pad(l.Text("Lenses:"), 16), formatGear(js.Lenses, "%s (%d)", ", "))
+		}
+	}
+	if j.Unassigned > 0 {
+		if len(j.Jobs) > 0 {
+			ew.printf("\n")
+		}
+		ew.printf(l.Text("%d photos belong to no job\n"), j.Unassigned)
+	}
+	return ew.err
+}
+
+// WriteCSV writes one row per job with a header row. Times are RFC 3339,
+// deliverables is empty when not configured, and cameras and lenses list
+// name:photos pairs separated by semicolons.
+func (j *Jobs) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"client", "job", "photos", "deliverables", "first", "last", "days", "cameras", "lenses"}); err != nil {
+		return err
+	}
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for _, js := range j.Jobs {
+		delivered := ""
+		if js.Deliverables != nil {
+			delivered = strconv.Itoa(*js.Deliverables)
+		}
+		row := []string{js.Client, js.Job, strconv.Itoa(js.Photos), delivered, format(js.First), format(js.Last), strconv.Itoa(js.Days),
+			formatGear(js.Cameras, "%s:%d", ";"), formatGear(js.Lenses, "%s:%d", ";")}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
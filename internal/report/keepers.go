@@ -0,0 +1,221 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Keepers relates star ratings to shooting settings: the share of photos
+// rated at least MinRating, overall and per lens, focal length, shutter
+// speed and focus mode. Unrated photos count as not kept.
+type Keepers struct {
+	MinRating int     `json:"min_rating"`
+	Photos    int     `json:"photos"`
+	Keepers   int     `json:"keepers"`
+	Rate      float64 `json:"rate"`
+	// Lens and FocusMode are ordered by photos, FocalLength and Shutter by
+	// value.
+	Lens        []KeeperRow `json:"lens"`
+	FocalLength []KeeperRow `json:"focal_length"`
+	Shutter     []KeeperRow `json:"shutter"`
+	FocusMode   []KeeperRow `json:"focus_mode"`
+}
+
+// KeeperRow is the keeper rate of the photos sharing one value.
+type KeeperRow struct {
+	Value   string  `json:"value"`
+	Photos  int     `json:"photos"`
+	Keepers int     `json:"keepers"`
+	Rate    float64 `json:"rate"`
+}
+
+// focalBands are the lower bounds of the 35 mm equivalent focal length
+// bands, in millimeters.
+var focalBands = []float64{0, 24, 35, 50, 85, 135, 200, 400}
+
+// focalBand returns the index into focalBands of s's focal length and its
+// label, such as "35-49mm", or -1 when s records none.
+func focalBand(s *exif.Summary) (int, string) {
+	f := float64(s.FocalLength35mm)
+	if f <= 0 {
+		f = math.Round(s.FocalLength)
+	}
+	if f <= 0 {
+		return -1, unknown
+	}
+	i := len(focalBands) - 1
+	for focalBands[i] > f {
+		i--
+	}
+	switch {
+	case i == 0:
+		return i, "<24mm"
+	case i == len(focalBands)-1:
+		return i, strconv.FormatFloat(focalBands[i], 'f', -1, 64) + "mm+"
+	}
+	return i, strconv.FormatFloat(focalBands[i], 'f', -1, 64) + "-" + strconv.FormatFloat(focalBands[i+1]-1, 'f', -1, 64) + "mm"
+}
+
+// markedSpeeds are the shutter speeds cameras mark for whole stops, keyed
+// by the stop as the power of two in seconds.
+var markedSpeeds = map[int]string{
+	-13: "1/8000", -12: "1/4000", -11: "1/2000", -10: "1/1000", -9: "1/500", -8: "1/250", -7: "1/125",
+	-6: "1/60", -5: "1/30", -4: "1/15", -3: "1/8", -2: "1/4", -1: "1/2",
+	0: "1", 1: "2", 2: "4", 3: "8", 4: "15", 5: "30",
+}
+
+// shutterStop returns the whole stop nearest s's shutter time, as the
+// power of two in seconds, and its marked speed, such as "1/250".
+func shutterStop(s *exif.Summary) (int, string) {
+	if s.ExposureTime <= 0 {
+		return math.MaxInt, unknown
+	}
+	stop := int(math.Round(math.Log2(s.ExposureTime)))
+	if v, ok := markedSpeeds[stop]; ok {
+		return stop, v
+	}
+	if stop < 0 {
+		return stop, "1/" + strconv.FormatFloat(math.Exp2(float64(-stop)), 'f', 0, 64)
+	}
+	return stop, strconv.FormatFloat(math.Exp2(float64(stop)), 'f', 0, 64)
+}
+
+// NewKeepers computes the keeper rates of summaries, counting a photo
+// rated minRating stars or more as a keeper.
+func NewKeepers(summaries []*exif.Summary, minRating int) *Keepers {
+	k := &Keepers{MinRating: minRating}
+	type group struct {
+		rows  map[string]*KeeperRow
+		order map[string]int
+	}
+	groups := make([]group, 4)
+	for i := range groups {
+		groups[i] = group{rows: map[string]*KeeperRow{}, order: map[string]int{}}
+	}
+	count := func(g group, value string, order int, kept bool) {
+		r, ok := g.rows[value]
+		if !ok {
+			r = &KeeperRow{Value: value}
+			g.rows[value] = r
+			g.order[value] = order
+		}
+		r.Photos++
+		if kept {
+			r.Keepers++
+		}
+	}
+	for _, s := range summaries {
+		kept := s.Rating >= minRating
+		k.Photos++
+		if kept {
+			k.Keepers++
+		}
+		lens := s.LensModel
+		if lens == "" {
+			lens = unknown
+		}
+		count(groups[0], lens, 0, kept)
+		band, label := focalBand(s)
+		if band < 0 {
+			band = len(focalBands)
+		}
+		count(groups[1], label, band, kept)
+		stop, speed := shutterStop(s)
+		count(groups[2], speed, stop, kept)
+		focus := s.FocusMode
+		if focus == "" {
+			focus = unknown
+		}
+		count(groups[3], focus, 0, kept)
+	}
+	k.Rate = rate(k.Keepers, k.Photos)
+	rows := func(g group, byValue bool) []KeeperRow {
+		out := make([]KeeperRow, 0, len(g.rows))
+		for _, r := range g.rows {
+			r.Rate = rate(r.Keepers, r.Photos)
+			out = append(out, *r)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			a, b := out[i], out[j]
+			switch {
+			case byValue && g.order[a.Value] != g.order[b.Value]:
+				return g.order[a.Value] < g.order[b.Value]
+			case !byValue && a.Photos != b.Photos:
+				return a.Photos > b.Photos
+			}
+			return a.Value < b.Value
+		})
+		return out
+	}
+	k.Lens = rows(groups[0], false)
+	k.FocalLength = rows(groups[1], true)
+	k.Shutter = rows(groups[2], true)
+	k.FocusMode = rows(groups[3], false)
+	return k
+}
+
+// rate is kept/total rounded to three places, or 0 for no photos.
+func rate(kept, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(kept)/float64(total)*1000) / 1000
+}
+
+// WriteText renders the keeper rates as one table per setting in locale l;
+// nil selects English.
+func (k *Keepers) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	ew.printf(l.Text("Keepers (rated %d or more): %d of %d (%.1f%%)\n"), k.MinRating, k.Keepers, k.Photos, k.Rate*100)
+	for _, t := range k.tables() {
+		ew.printf("\n%s %s %s %s\n", locale.Pad(l.Text(t.name), 28), locale.Pad(l.Text("Photos"), 7),
+			locale.Pad(l.Text("Keepers"), 7), l.Text("Rate"))
+		for _, r := range t.rows {
+			value := r.Value
+			if t.name == "Focus mode" || value == unknown {
+				value = l.Text(value)
+			}
+			ew.printf("%s %s %s %.1f%%\n", locale.Pad(value, 28), locale.Pad(strconv.Itoa(r.Photos), 7),
+				locale.Pad(strconv.Itoa(r.Keepers), 7), r.Rate*100)
+		}
+	}
+	return ew.err
+}
+
+// WriteCSV writes one row per setting and value with a header row.
+func (k *Keepers) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"setting", "value", "photos", "keepers", "rate"}); err != nil {
+		return err
+	}
+	for _, t := range k.tables() {
+		for _, r := range t.rows {
+			row := []string{t.key, r.Value, strconv.Itoa(r.Photos), strconv.Itoa(r.Keepers), strconv.FormatFloat(r.Rate, 'f', -1, 64)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type keeperTable struct {
+	name, key string
+	rows      []KeeperRow
+}
+
+func (k *Keepers) tables() []keeperTable {
+	return []keeperTable{
+		{"Lens", "lens", k.Lens},
+		{"Focal length", "focal_length", k.FocalLength},
+		{"Shutter speed", "shutter", k.Shutter},
+		{"Focus mode", "focus_mode", k.FocusMode},
+	}
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Services accepted by WriteManifest.
+const (
+	ServiceFlickr    = "flickr"
+	ServiceInstagram = "instagram"
+	ServiceSmugMug   = "smugmug"
+)
+
+// Services lists the services upload manifests can be written for.
+var Services = []string{ServiceFlickr, ServiceInstagram, ServiceSmugMug}
+
+// manifests holds each service's columns, named as its upload API and
+// bulk tools name them.
+var manifests = map[string][]column{
+	ServiceFlickr: {
+		{"path", func(s *exif.Summary) string { return s.Path }},
+		{"title", func(s *exif.Summary) string { return s.Title }},
+		{"description", func(s *exif.Summary) string { return s.Description }},
+		{"tags", flickrTags},
+		{"latitude", func(s *exif.Summary) string { return formatFloatPtr(s.Latitude) }},
+		{"longitude", func(s *exif.Summary) string { return formatFloatPtr(s.Longitude) }},
+		{"date_taken", func(s *exif.Summary) string { return captureText(s, "2006-01-02 15:04:05") }},
+	},
+	ServiceInstagram: {
+		{"path", func(s *exif.Summary) string { return s.Path }},
+		{"caption", instagramCaption},
+		{"location", func(s *exif.Summary) string {
+			if s.Latitude == nil || s.Longitude == nil {
+				return ""
+			}
+			return formatFloatPtr(s.Latitude) + "," + formatFloatPtr(s.Longitude)
+		}},
+		{"date", func(s *exif.Summary) string { return captureText(s, "2006-01-02T15:04:05Z07:00") }},
+	},
+	ServiceSmugMug: {
+		{"FileName", func(s *exif.Summary) string { return s.Path }},
+		{"Title", func(s *exif.Summary) string { return s.Title }},
+		{"Caption", func(s *exif.Summary) string { return s.Description }},
+		{"Keywords", func(s *exif.Summary) string { return strings.Join(s.Keywords, "; ") }},
+		{"Latitude", func(s *exif.Summary) string { return formatFloatPtr(s.Latitude) }},
+		{"Longitude", func(s *exif.Summary) string { return formatFloatPtr(s.Longitude) }},
+		{"DateTimeOriginal", func(s *exif.Summary) string { return captureText(s, "2006-01-02 15:04:05") }},
+	},
+}
+
+// WriteManifest writes an upload manifest for service in format, CSV with
+// a header row or a JSON array of objects keyed by column name.
+func WriteManifest(w io.Writer, service, format string, summaries []*exif.Summary) error {
+	cols, ok := manifests[service]
+	if !ok {
+		return fmt.Errorf("report: unknown service %q (want one of %s)", service, strings.Join(Services, ", "))
+	}
+	switch format {
+	case FormatCSV:
+		return writeColumns(w, summaries, cols)
+	case FormatJSON:
+		rows := make([]map[string]string, len(summaries))
+		for i, s := range summaries {
+			rows[i] = map[string]string{}
+			for _, c := range cols {
+				if v := c.value(s); v != "" {
+					rows[i][c.name] = v
+				}
+			}
+		}
+		return writeIndented(w, rows)
+	}
+	return fmt.Errorf("report: unknown format %q", format)
+}
+
+// captureText formats the capture time with layout, or "" without one.
+func captureText(s *exif.Summary, layout string) string {
+	t, ok := s.CaptureTime()
+	if !ok {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// flickrTags joins keywords with spaces, quoting those that contain one,
+// as Flickr's tag syntax requires.
+func flickrTags(s *exif.Summary) string {
+	tags := make([]string, len(s.Keywords))
+	for i, k := range s.Keywords {
+		k = strings.ReplaceAll(k, `"`, "")
+		if strings.ContainsRune(k, ' ') {
+			k = `"` + k + `"`
+		}
+		tags[i] = k
+	}
+	return strings.Join(tags, " ")
+}
+
+// instagramCaption is the title and description followed by the keywords
+// as hashtags; Instagram has no separate title or tag fields.
+func instagramCaption(s *exif.Summary) string {
+	var parts []string
+	for _, p := range []string{s.Title, s.Description} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	var tags []string
+	for _, k := range s.Keywords {
+		tag := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, k)
+		if tag != "" {
+			tags = append(tags, "#"+tag)
+		}
+	}
+	if len(tags) > 0 {
+		parts = append(parts, strings.Join(tags, " "))
+	}
+	return strings.Join(parts, "\n\n")
+}
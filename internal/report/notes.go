@@ -0,0 +1,45 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Annotated is a photo with the shot list notes paired with it.
+type Annotated struct {
+	*exif.Summary
+	Notes map[string]string `json:"notes,omitempty"`
+}
+
+// WriteAnnotated renders an annotated shooting log in the named format.
+// CSV output appends one column per note, named after it, or prefixed
+// with note_ when a summary column has that name.
+func WriteAnnotated(w io.Writer, format string, rows []Annotated, noteColumns []string) error {
+	switch format {
+	case FormatJSON:
+		if rows == nil {
+			rows = []Annotated{}
+		}
+		return writeIndented(w, rows)
+	case FormatCSV:
+		summaries := make([]*exif.Summary, len(rows))
+		notes := make(map[*exif.Summary]map[string]string, len(rows))
+		for i, r := range rows {
+			summaries[i] = r.Summary
+			notes[r.Summary] = r.Notes
+		}
+		cols := slices.Clone(columns)
+		for _, name := range noteColumns {
+			header := name
+			if slices.ContainsFunc(columns, func(c column) bool { return c.name == name }) {
+				header = "note_" + name
+			}
+			cols = append(cols, column{header, func(s *exif.Summary) string { return notes[s][name] }})
+		}
+		return writeColumns(w, summaries, cols)
+	}
+	return fmt.Errorf("report: unknown format %q", format)
+}
@@ -0,0 +1,42 @@
+package report
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/pkg/parquet"
+)
+
+var parquetTypes = map[reflect.Kind]parquet.Type{
+	reflect.String:  parquet.String,
+	reflect.Int:     parquet.Int64,
+	reflect.Float64: parquet.Double,
+	reflect.Bool:    parquet.Boolean,
+}
+
+// WriteParquet writes summaries as a Parquet file with a typed column per
+// field, lists as repeated columns. Fields the JSON output leaves out are
+// null.
+func WriteParquet(w io.Writer, summaries []*exif.Summary) error {
+	return writeParquet(w, summaries, newSelection(summaries, nil))
+}
+
+func writeParquet(w io.Writer, summaries []*exif.Summary, sel *selection) error {
+	fields := sel.typedFields()
+	cols := make([]parquet.Column, len(fields))
+	for i, f := range fields {
+		cols[i] = parquet.Column{Name: f.name, Type: parquetTypes[f.kind], Repeated: f.list}
+	}
+	pw := parquet.NewWriter(w, cols)
+	row := make([]any, len(fields))
+	for _, s := range summaries {
+		for i, f := range fields {
+			row[i] = f.selected(s, sel)
+		}
+		if err := pw.Write(row); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
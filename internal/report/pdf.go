@@ -0,0 +1,183 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/album"
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+	"github.com/ryoh827/shootlog/internal/pdf"
+)
+
+// PDF layout in points on A4 paper.
+const (
+	pdfMargin  = 50.0
+	pdfLeading = 11.0
+	pdfText    = 9.0
+	pdfTable   = 8.0
+	// pdfFooter is the space kept free for the footer at the bottom of
+	// every page.
+	pdfFooter = 24.0
+)
+
+// pdfColumns are the photo table's columns and their widths, which add
+// up to the width between the margins.
+var pdfColumns = []struct {
+	title string
+	width float64
+}{
+	{"File", 115}, {"Captured", 80}, {"Camera", 105}, {"Lens", 105}, {"Exposure", 90},
+}
+
+// PDFOptions control a PDF report.
+type PDFOptions struct {
+	// Title heads the first page; "" selects the album title or
+	// "Shooting session report".
+	Title string
+	// Album, when set, names the cover on the first page and captions the
+	// photos below their rows.
+	Album *album.Album
+	// Signer, when set, signs the document so recipients can check that
+	// it was not altered after it left the photographer.
+	Signer *pdf.Signer
+	// Now is the generation time printed on the report; zero means now.
+	Now time.Time
+}
+
+// WritePDF renders the session and its photos as a paginated A4 PDF: the
+// text report followed by a table of the photos. The PDF fonts cover
+// Latin-1 only, so the report is in English.
+func WritePDF(w io.Writer, s *Session, summaries []*exif.Summary, o PDFOptions) error {
+	var text bytes.Buffer
+	if err := s.WriteText(&text, locale.English); err != nil {
+		return err
+	}
+	title := o.Title
+	if title == "" && o.Album != nil {
+		title = o.Album.Title
+	}
+	if title == "" {
+		title = "Shooting session report"
+	}
+	now := o.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	doc := &pdf.Document{Info: map[string]string{
+		"Title":        title,
+		"Creator":      "shootlog",
+		"CreationDate": pdf.Date(now),
+	}}
+	doc.Signature = o.Signer
+	l := &pdfLayout{doc: doc}
+
+	l.need(2 * pdfLeading)
+	l.page.Text(pdf.HelveticaBold, 16, pdfMargin, l.y+16, title)
+	l.y += 16 + pdfLeading
+	l.line(pdf.Helvetica, pdfText, "Generated "+now.Format("2006-01-02 15:04 MST"))
+	if o.Signer != nil {
+		l.line(pdf.Helvetica, pdfText, "Digitally signed by "+o.Signer.Name())
+	}
+	if o.Album != nil {
+		l.line(pdf.Helvetica, pdfText, "Cover: "+filepath.Base(o.Album.CoverPath()))
+	}
+	l.y += pdfLeading
+
+	sc := bufio.NewScanner(&text)
+	for sc.Scan() {
+		l.line(pdf.Courier, pdfText, sc.Text())
+	}
+
+	if len(summaries) > 0 {
+		l.y += pdfLeading
+		l.need(3 * pdfLeading)
+		l.page.Text(pdf.HelveticaBold, 12, pdfMargin, l.y+12, "Photos")
+		l.y += 12 + pdfLeading/2
+		// The column titles repeat at the top of each page of the table.
+		l.header = func() {
+			x := pdfMargin
+			for _, c := range pdfColumns {
+				l.page.Text(pdf.HelveticaBold, pdfTable, x, l.y+pdfTable, c.title)
+				x += c.width
+			}
+			l.y += pdfLeading
+			l.page.Line(pdfMargin, l.y-2, pdf.A4.Width-pdfMargin, l.y-2, 0.5)
+			l.y += 2
+		}
+		l.header()
+		for _, sum := range summaries {
+			taken := ""
+			if t, ok := sum.CaptureTime(); ok {
+				taken = locale.English.DateTime(t)
+			}
+			l.need(pdfLeading)
+			x := pdfMargin
+			for i, v := range []string{
+				filepath.Base(sum.Path),
+				taken,
+				strings.TrimSpace(sum.Make + " " + sum.Model),
+				sum.LensModel,
+				exposureText(sum),
+			} {
+				c := pdfColumns[i]
+				l.page.Text(pdf.Helvetica, pdfTable, x, l.y+pdfTable, pdf.Fit(pdf.Helvetica, pdfTable, v, c.width-6))
+				x += c.width
+			}
+			l.y += pdfLeading
+			if o.Album != nil {
+				if caption := o.Album.Caption(sum.Path); caption != "" {
+					l.need(pdfLeading)
+					l.page.Gray(0.35)
+					l.page.Text(pdf.Helvetica, pdfTable, pdfMargin+6, l.y+pdfTable, pdf.Fit(pdf.Helvetica, pdfTable, caption, pdf.A4.Width-2*pdfMargin-6))
+					l.page.Gray(0)
+					l.y += pdfLeading
+				}
+			}
+		}
+	}
+
+	for i, p := range doc.Pages {
+		y := pdf.A4.Height - pdfMargin
+		p.Gray(0.4)
+		p.Text(pdf.Helvetica, pdfTable, pdfMargin, y, title)
+		n := fmt.Sprintf("Page %d of %d", i+1, len(doc.Pages))
+		p.Text(pdf.Helvetica, pdfTable, pdf.A4.Width-pdfMargin-pdf.TextWidth(pdf.Helvetica, pdfTable, n), y, n)
+	}
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// pdfLayout places lines top to bottom, starting a page when one is full.
+type pdfLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	// y is the top of the next line.
+	y float64
+	// header, when set, is drawn at the top of every new page.
+	header func()
+}
+
+// need starts a new page unless h points fit above the footer.
+func (l *pdfLayout) need(h float64) {
+	if l.page != nil && l.y+h <= pdf.A4.Height-pdfMargin-pdfFooter {
+		return
+	}
+	l.page = l.doc.AddPage(pdf.A4)
+	l.y = pdfMargin
+	if l.header != nil {
+		l.header()
+	}
+}
+
+// line writes one line of text.
+func (l *pdfLayout) line(font string, size float64, s string) {
+	l.need(pdfLeading)
+	l.page.Text(font, size, pdfMargin, l.y+size, s)
+	l.y += pdfLeading
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// SearchGenerator marks the pages WriteSearchPage writes, so that stale
+// ones can be told apart from other HTML files.
+const SearchGenerator = "shootlog search-pages"
+
+// WriteSearchPage writes a small HTML page describing one photo for the
+// desktop search of the operating system. Spotlight and Windows Search both
+// index HTML: the title, the keywords, author and description meta tags
+// and the text, so the photo's camera, lens, exposure and every other
+// field become searchable, and opening the result shows the photo from
+// image, a URL relative to the page.
+func WriteSearchPage(w io.Writer, s *exif.Summary, image string) error {
+	name := filepath.Base(s.Path)
+	page := searchPage{
+		Name:        name,
+		Title:       name,
+		Image:       image,
+		Author:      s.Artist,
+		Description: s.Description,
+		Generator:   SearchGenerator,
+	}
+	if s.Title != "" {
+		page.Title = s.Title + " (" + name + ")"
+	}
+	if page.Description == "" {
+		page.Description = s.Comment
+	}
+	keywords := append([]string{}, s.Keywords...)
+	keywords = append(keywords, s.Collections...)
+	for _, k := range []string{strings.TrimSpace(s.Make + " " + s.Model), s.LensModel} {
+		if k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	page.Keywords = strings.Join(keywords, ", ")
+	for _, c := range columns {
+		if v := c.value(s); v != "" && c.name != "path" {
+			page.Fields = append(page.Fields, searchField{c.name, v})
+		}
+	}
+	return searchTemplate.Execute(w, page)
+}
+
+type searchPage struct {
+	Name, Title, Image, Author, Description, Keywords, Generator string
+	Fields                                                       []searchField
+}
+
+type searchField struct {
+	Name, Value string
+}
+
+var searchTemplate = template.Must(template.New("search").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="generator" content="{{.Generator}}">
+<title>{{.Title}}</title>
+{{- if .Keywords}}
+<meta name="keywords" content="{{.Keywords}}">
+{{- end}}
+{{- if .Author}}
+<meta name="author" content="{{.Author}}">
+{{- end}}
+{{- if .Description}}
+<meta name="description" content="{{.Description}}">
+{{- end}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><a href="{{.Image}}"><img src="{{.Image}}" alt="{{.Name}}" style="max-width:100%;max-height:80vh"></a></p>
+<table>
+{{- range .Fields}}
+<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
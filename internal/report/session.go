@@ -0,0 +1,325 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// burstGap is the longest pause between two continuous-drive frames that
+// still counts as the same burst.
+const burstGap = 2 * time.Second
+
+// unknown labels photos whose maker note did not record a value.
+const unknown = "unknown"
+
+// unrated labels photos without a star rating.
+const unrated = "unrated"
+
+// Session aggregates the photos of one shooting session.
+type Session struct {
+	Shots int        `json:"shots"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+
+	// Stabilization and DriveMode count photos per normalized value.
+	Stabilization map[string]int `json:"stabilization"`
+	DriveMode     map[string]int `json:"drive_mode"`
+	ShutterType   map[string]int `json:"shutter_type"`
+	// LightPhase counts geotagged photos by the sun's position: day,
+	// golden hour, blue hour or night.
+	LightPhase map[string]int `json:"light_phase"`
+	// Rating counts photos by star rating, or as unrated, when any photo
+	// is rated; Collections counts them per catalog collection or album.
+	Rating      map[string]int `json:"rating,omitempty"`
+	Collections map[string]int `json:"collections,omitempty"`
+	// Panoramas counts photos with a GPano projection and Composite the
+	// photos combined from several frames, per composite kind.
+	Panoramas int            `json:"panoramas,omitempty"`
+	Composite map[string]int `json:"composite,omitempty"`
+	// Computational counts phone shots per computational feature, such
+	// as HDR, night mode or portrait; a shot may count under several.
+	Computational map[string]int `json:"computational,omitempty"`
+	// PrintSizes counts photos by the largest standard print, such as
+	// "8x10" in inches, they fill at exif.PrintDPI.
+	PrintSizes map[string]int `json:"print_sizes,omitempty"`
+
+	Bursts Bursts `json:"bursts"`
+
+	// Altitude is the range of GPS altitudes, in the session's units.
+	Altitude *Range `json:"altitude,omitempty"`
+	// Elevation is the altitude of each photo with a capture time, in
+	// capture order and the session's units.
+	Elevation []ElevationPoint `json:"elevation,omitempty"`
+	// Weather is set by AddWeather.
+	Weather *Weather `json:"weather,omitempty"`
+}
+
+// Range is the span of a measured value.
+type Range struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Unit string  `json:"unit"`
+}
+
+// Bursts describes runs of continuous-drive frames.
+type Bursts struct {
+	Count   int `json:"count"`
+	Frames  int `json:"frames"`
+	Longest int `json:"longest"`
+}
+
+// NewSession aggregates summaries into a Session. Distances are taken to
+// be in meters; run ConvertUnits on the session, not on summaries, to
+// report them in other units.
+func NewSession(summaries []*exif.Summary) *Session {
+	s := &Session{
+		Shots:         len(summaries),
+		Stabilization: map[string]int{},
+		DriveMode:     map[string]int{},
+		ShutterType:   map[string]int{},
+		LightPhase:    map[string]int{},
+	}
+	type shot struct {
+		t          time.Time
+		continuous bool
+	}
+	var timed []shot
+	rated := false
+	for _, sum := range summaries {
+		if sum.Rating > 0 {
+			rated = true
+		}
+		for _, c := range sum.Collections {
+			if s.Collections == nil {
+				s.Collections = map[string]int{}
+			}
+			s.Collections[c]++
+		}
+		s.Stabilization[orUnknown(sum.Stabilization)]++
+		s.DriveMode[orUnknown(sum.DriveMode)]++
+		s.ShutterType[orUnknown(sum.ShutterType)]++
+		if sum.LightPhase != "" {
+			s.LightPhase[sum.LightPhase]++
+		}
+		if sum.Projection != "" {
+			s.Panoramas++
+		}
+		if sum.Composite != "" {
+			if s.Composite == nil {
+				s.Composite = map[string]int{}
+			}
+			s.Composite[sum.Composite]++
+		}
+		for _, c := range sum.Computational {
+			if s.Computational == nil {
+				s.Computational = map[string]int{}
+			}
+			s.Computational[c]++
+		}
+		if p := printSize(sum); p != "" {
+			if s.PrintSizes == nil {
+				s.PrintSizes = map[string]int{}
+			}
+			s.PrintSizes[p]++
+		}
+		t, timedShot := sum.CaptureTime()
+		if timedShot {
+			timed = append(timed, shot{t, sum.DriveMode == exif.DriveContinuous})
+		}
+		if alt := sum.Altitude; alt != nil {
+			if s.Altitude == nil {
+				s.Altitude = &Range{Min: *alt, Max: *alt, Unit: DistanceUnit(UnitsMetric)}
+			}
+			s.Altitude.Min = min(s.Altitude.Min, *alt)
+			s.Altitude.Max = max(s.Altitude.Max, *alt)
+			if timedShot {
+				s.Elevation = append(s.Elevation, ElevationPoint{Time: t, Altitude: *alt, Path: sum.Path})
+			}
+		}
+	}
+	if rated {
+		s.Rating = map[string]int{}
+		for _, sum := range summaries {
+			if sum.Rating > 0 {
+				s.Rating[strconv.Itoa(sum.Rating)]++
+			} else {
+				s.Rating[unrated]++
+			}
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].t.Before(timed[j].t) })
+	sort.SliceStable(s.Elevation, func(i, j int) bool { return s.Elevation[i].Time.Before(s.Elevation[j].Time) })
+	if len(timed) > 0 {
+		s.Start, s.End = &timed[0].t, &timed[len(timed)-1].t
+	}
+
+	run := 0
+	flush := func() {
+		// A single continuous-drive frame is not a burst.
+		if run > 1 {
+			s.Bursts.Count++
+			s.Bursts.Frames += run
+			s.Bursts.Longest = max(s.Bursts.Longest, run)
+		}
+		run = 0
+	}
+	for i, sh := range timed {
+		if !sh.continuous {
+			flush()
+			continue
+		}
+		if run > 0 && sh.t.Sub(timed[i-1].t) > burstGap {
+			flush()
+		}
+		run++
+	}
+	flush()
+	return s
+}
+
+// ConvertUnits converts the session's distances, in meters, and
+// temperatures, in degrees Celsius, into units.
+func (s *Session) ConvertUnits(units string) error {
+	if err := checkUnits(units); err != nil {
+		return err
+	}
+	if a := s.Altitude; a != nil {
+		s.Altitude = &Range{Min: fromMeters(a.Min, units), Max: fromMeters(a.Max, units), Unit: DistanceUnit(units)}
+	}
+	for i := range s.Elevation {
+		s.Elevation[i].Altitude = fromMeters(s.Elevation[i].Altitude, units)
+	}
+	if s.Weather != nil && s.Weather.Temperature != nil {
+		t := s.Weather.Temperature
+		s.Weather.Temperature = &Range{Min: fromCelsius(t.Min, units), Max: fromCelsius(t.Max, units), Unit: TemperatureUnit(units)}
+	}
+	return nil
+}
+
+func orUnknown(v string) string {
+	if v == "" {
+		return unknown
+	}
+	return v
+}
+
+// WriteText renders the session as a human-readable report in locale l;
+// nil selects English.
+func (s *Session) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	ew.printf(l.Text("Shots: %d\n"), s.Shots)
+	if s.Start != nil {
+		ew.printf(l.Text("Period: %s - %s\n"), l.DateTime(*s.Start), l.DateTime(*s.End))
+	}
+	if a := s.Altitude; a != nil {
+		ew.printf(l.Text("Altitude: %s - %s %s\n"), formatFloat(a.Min), formatFloat(a.Max), a.Unit)
+	}
+	s.writeBreakdown(ew, l, "Stabilization", s.Stabilization)
+	s.writeBreakdown(ew, l, "Drive mode", s.DriveMode)
+	s.writeBreakdown(ew, l, "Shutter", s.ShutterType)
+	if len(s.LightPhase) > 0 {
+		s.writeBreakdown(ew, l, "Light", s.LightPhase)
+	}
+	if len(s.Rating) > 0 {
+		s.writeBreakdown(ew, l, "Rating", s.Rating)
+	}
+	if len(s.Collections) > 0 {
+		s.writeBreakdown(ew, l, "Collections", s.Collections)
+	}
+	if s.Panoramas > 0 {
+		ew.printf(l.Text("Panoramas: %d\n"), s.Panoramas)
+	}
+	if len(s.Composite) > 0 {
+		s.writeBreakdown(ew, l, "Composite", s.Composite)
+	}
+	if len(s.Computational) > 0 {
+		s.writeBreakdown(ew, l, "Phone processing", s.Computational)
+	}
+	if len(s.PrintSizes) > 0 {
+		s.writeBreakdown(ew, l, "Print at 300 dpi", s.PrintSizes)
+	}
+	if w := s.Weather; w != nil && w.Photos > 0 {
+		if t := w.Temperature; t != nil {
+			ew.printf(l.Text("Temperature: %s - %s %s\n"), number(t.Min), number(t.Max), t.Unit)
+		}
+		if len(w.Conditions) > 0 {
+			s.writeBreakdown(ew, l, "Weather", w.Conditions)
+		}
+	}
+	ew.printf(l.Text("Bursts: %d"), s.Bursts.Count)
+	if s.Bursts.Count > 0 {
+		ew.printf(l.Text(" (%d frames, avg %.1f, longest %d)"), s.Bursts.Frames,
+			float64(s.Bursts.Frames)/float64(s.Bursts.Count), s.Bursts.Longest)
+	}
+	ew.printf("\n")
+	return ew.err
+}
+
+func (s *Session) writeBreakdown(ew *errWriter, l *locale.Locale, title string, counts map[string]int) {
+	ew.printf("%s:\n", l.Text(title))
+	for _, k := range sortedKeys(counts) {
+		ew.printf("  %s %5d  %5.1f%%\n", locale.Pad(l.Text(k), 26), counts[k], percent(counts[k], s.Shots))
+	}
+}
+
+// printSizes are standard photo print sizes in inches, long side first,
+// from the largest.
+var printSizes = [][2]float64{{30, 20}, {20, 16}, {14, 11}, {10, 8}, {7, 5}, {6, 4}}
+
+// printSize names the largest of printSizes the photo fills at
+// exif.PrintDPI, or "smaller than 4x6"; it returns "" without pixel
+// dimensions.
+func printSize(sum *exif.Summary) string {
+	long, short := max(sum.PrintWidth, sum.PrintHeight), min(sum.PrintWidth, sum.PrintHeight)
+	if short == 0 {
+		return ""
+	}
+	for _, p := range printSizes {
+		if long >= p[0] && short >= p[1] {
+			return fmt.Sprintf("%gx%g", p[1], p[0])
+		}
+	}
+	return "smaller than 4x6"
+}
+
+// sortedKeys orders breakdown keys by descending count, then by name.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+// errWriter remembers the first write error so report rendering can be
+// written as a straight sequence of prints.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Sort keys accepted by Sort.
+const (
+	SortPath     = "path"
+	SortDatetime = "datetime"
+	SortISO      = "iso"
+)
+
+// SortKeys lists the accepted sort keys, default first.
+var SortKeys = []string{SortPath, SortDatetime, SortISO}
+
+// Sort orders summaries by key. Ties, and photos missing the key, which
+// sort last, fall back to path order, so the result does not depend on
+// the input order.
+func Sort(summaries []*exif.Summary, key string) error {
+	// less reports whether a sorts before b, and whether key decided it.
+	var less func(a, b *exif.Summary) (before, decided bool)
+	switch key {
+	case SortPath:
+		less = func(a, b *exif.Summary) (bool, bool) { return false, false }
+	case SortDatetime:
+		less = func(a, b *exif.Summary) (bool, bool) {
+			ta, oka := a.CaptureTime()
+			tb, okb := b.CaptureTime()
+			switch {
+			case oka != okb:
+				return oka, true
+			case !oka || ta.Equal(tb):
+				return false, false
+			}
+			return ta.Before(tb), true
+		}
+	case SortISO:
+		less = func(a, b *exif.Summary) (bool, bool) {
+			switch {
+			case a.ISO == b.ISO:
+				return false, false
+			case a.ISO == 0 || b.ISO == 0:
+				return a.ISO != 0, true
+			}
+			return a.ISO < b.ISO, true
+		}
+	default:
+		return fmt.Errorf("report: unknown sort key %q", key)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if l, ok := less(summaries[i], summaries[j]); ok {
+			return l
+		}
+		return summaries[i].Path < summaries[j].Path
+	})
+	return nil
+}
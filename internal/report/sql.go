@@ -0,0 +1,108 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// SQLTable is the table WriteSQL creates.
+const SQLTable = "photos"
+
+// sqlTypes are column types SQLite, PostgreSQL and DuckDB all accept.
+var sqlTypes = map[reflect.Kind]string{
+	reflect.String:  "TEXT",
+	reflect.Int:     "BIGINT",
+	reflect.Float64: "DOUBLE PRECISION",
+	reflect.Bool:    "BOOLEAN",
+}
+
+// sqlBatch is the number of rows of each INSERT statement.
+const sqlBatch = 100
+
+// WriteSQL writes summaries as SQL statements that create the photos
+// table, with a typed column per field, and fill it in one transaction,
+// for loading into SQLite, PostgreSQL or DuckDB. Lists are joined with
+// ";" as in CSV, and fields the JSON output leaves out are NULL.
+func WriteSQL(w io.Writer, summaries []*exif.Summary) error {
+	return writeSQL(w, summaries, newSelection(summaries, nil))
+}
+
+func writeSQL(w io.Writer, summaries []*exif.Summary, sel *selection) error {
+	fields := sel.typedFields()
+	bw := bufio.NewWriter(w)
+	names := make([]string, len(fields))
+	fmt.Fprintf(bw, "CREATE TABLE %s (\n", sqlIdent(SQLTable))
+	for i, f := range fields {
+		names[i] = sqlIdent(f.name)
+		typ := sqlTypes[f.kind]
+		if f.list {
+			typ = "TEXT"
+		}
+		sep := ","
+		if i == len(fields)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "  %s %s%s\n", names[i], typ, sep)
+	}
+	bw.WriteString(");\n")
+	if len(summaries) > 0 {
+		bw.WriteString("BEGIN;\n")
+	}
+	for i, s := range summaries {
+		if i%sqlBatch == 0 {
+			fmt.Fprintf(bw, "INSERT INTO %s (%s) VALUES\n", sqlIdent(SQLTable), strings.Join(names, ", "))
+		}
+		vals := make([]string, len(fields))
+		for j, f := range fields {
+			vals[j] = sqlLiteral(f.selected(s, sel))
+		}
+		end := ","
+		if i%sqlBatch == sqlBatch-1 || i == len(summaries)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(bw, "  (%s)%s\n", strings.Join(vals, ", "), end)
+	}
+	if len(summaries) > 0 {
+		bw.WriteString("COMMIT;\n")
+	}
+	return bw.Flush()
+}
+
+// sqlIdent quotes a table or column name.
+func sqlIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral renders a typed field value as an SQL literal.
+func sqlLiteral(v any) string {
+	switch v := v.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "NULL"
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = fmt.Sprint(e)
+		}
+		return sqlLiteral(strings.Join(parts, ";"))
+	}
+	return "NULL"
+}
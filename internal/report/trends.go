@@ -0,0 +1,269 @@
+package report
+
+import (
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/locale"
+)
+
+// Trends compares the shooting of two sets of photos, such as two years:
+// how much was shot and the share of photos per camera, lens, focal
+// length band and ISO stop.
+type Trends struct {
+	A TrendPeriod `json:"a"`
+	B TrendPeriod `json:"b"`
+	// The rows of each table are ordered by value for FocalLength and ISO
+	// and by the photos of both periods for Cameras and Lenses.
+	Cameras     []TrendRow `json:"cameras"`
+	Lenses      []TrendRow `json:"lenses"`
+	FocalLength []TrendRow `json:"focal_length"`
+	ISO         []TrendRow `json:"iso"`
+}
+
+// TrendPeriod is the volume of one side of a comparison.
+type TrendPeriod struct {
+	Label  string `json:"label"`
+	Photos int    `json:"photos"`
+	// Days counts the days with photos of a known capture time.
+	Days int `json:"days"`
+	// MedianFocal is the median 35 mm equivalent focal length and
+	// MedianISO the median ISO, or 0 when no photo records them.
+	MedianFocal float64 `json:"median_focal_length"`
+	MedianISO   float64 `json:"median_iso"`
+}
+
+// TrendRow is one value of a setting in both periods. Shares are
+// fractions of each period's photos, and Change is ShareB-ShareA.
+type TrendRow struct {
+	Value  string  `json:"value"`
+	A      int     `json:"a"`
+	B      int     `json:"b"`
+	ShareA float64 `json:"share_a"`
+	ShareB float64 `json:"share_b"`
+	Change float64 `json:"change"`
+}
+
+// isoBand returns the full stop above ISO 100 nearest s's ISO, capped at
+// 12800, and its label, or -1 when s records none.
+func isoBand(s *exif.Summary) (int, string) {
+	if s.ISO <= 0 {
+		return -1, unknown
+	}
+	stop := int(math.Round(math.Log2(float64(s.ISO) / 100)))
+	switch {
+	case stop <= 0:
+		return 0, "<200"
+	case stop >= 7:
+		return 7, "12800+"
+	}
+	return stop, strconv.Itoa(100 << stop)
+}
+
+// NewTrends compares summaries a and b, labeled labelA and labelB.
+func NewTrends(labelA string, a []*exif.Summary, labelB string, b []*exif.Summary) *Trends {
+	t := &Trends{A: newTrendPeriod(labelA, a), B: newTrendPeriod(labelB, b)}
+	camera := func(s *exif.Summary) (int, string) {
+		if v := strings.TrimSpace(s.Make + " " + s.Model); v != "" {
+			return 0, v
+		}
+		return 0, unknown
+	}
+	lens := func(s *exif.Summary) (int, string) {
+		if s.LensModel != "" {
+			return 0, s.LensModel
+		}
+		return 0, unknown
+	}
+	t.Cameras = trendRows(a, b, camera, false)
+	t.Lenses = trendRows(a, b, lens, false)
+	t.FocalLength = trendRows(a, b, focalBand, true)
+	t.ISO = trendRows(a, b, isoBand, true)
+	return t
+}
+
+func newTrendPeriod(label string, summaries []*exif.Summary) TrendPeriod {
+	p := TrendPeriod{Label: label, Photos: len(summaries)}
+	days := map[string]bool{}
+	var focal, iso []float64
+	for _, s := range summaries {
+		if t, ok := s.CaptureTime(); ok {
+			days[t.Format("2006-01-02")] = true
+		}
+		if s.FocalLength35mm > 0 {
+			focal = append(focal, float64(s.FocalLength35mm))
+		} else if s.FocalLength > 0 {
+			focal = append(focal, s.FocalLength)
+		}
+		if s.ISO > 0 {
+			iso = append(iso, float64(s.ISO))
+		}
+	}
+	p.Days = len(days)
+	p.MedianFocal, p.MedianISO = median(focal), median(iso)
+	return p
+}
+
+// median returns the median of v, or 0 for none.
+func median(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	sort.Float64s(v)
+	if n := len(v); n%2 == 0 {
+		return (v[n/2-1] + v[n/2]) / 2
+	}
+	return v[len(v)/2]
+}
+
+// trendRows counts a and b by the value key returns, ordered by its rank
+// when byRank is set and unknown values last.
+func trendRows(a, b []*exif.Summary, key func(*exif.Summary) (int, string), byRank bool) []TrendRow {
+	rows := map[string]*TrendRow{}
+	rank := map[string]int{}
+	count := func(summaries []*exif.Summary, side func(*TrendRow) *int) {
+		for _, s := range summaries {
+			k, v := key(s)
+			r, ok := rows[v]
+			if !ok {
+				r = &TrendRow{Value: v}
+				rows[v] = r
+				if k < 0 {
+					k = math.MaxInt
+				}
+				rank[v] = k
+			}
+			*side(r)++
+		}
+	}
+	count(a, func(r *TrendRow) *int { return &r.A })
+	count(b, func(r *TrendRow) *int { return &r.B })
+	out := make([]TrendRow, 0, len(rows))
+	for _, r := range rows {
+		r.ShareA, r.ShareB = rate(r.A, len(a)), rate(r.B, len(b))
+		r.Change = math.Round((r.ShareB-r.ShareA)*1000) / 1000
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		x, y := out[i], out[j]
+		switch {
+		case (x.Value == unknown) != (y.Value == unknown):
+			return y.Value == unknown
+		case byRank && rank[x.Value] != rank[y.Value]:
+			return rank[x.Value] < rank[y.Value]
+		case !byRank && x.A+x.B != y.A+y.B:
+			return x.A+x.B > y.A+y.B
+		}
+		return x.Value < y.Value
+	})
+	return out
+}
+
+// trendBar is the width in characters of a full share in text charts.
+const trendBar = 20
+
+type trendTable struct {
+	name string
+	rows []TrendRow
+}
+
+func (t *Trends) tables() []trendTable {
+	return []trendTable{
+		{"Camera", t.Cameras},
+		{"Lens", t.Lenses},
+		{"Focal length", t.FocalLength},
+		{"ISO", t.ISO},
+	}
+}
+
+// WriteText renders the comparison in locale l as the volume of each
+// period followed by one table per setting, with bar charts of the
+// shares; nil selects English.
+func (t *Trends) WriteText(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	ew.printf("%s %s %s %s %s\n", locale.Pad("", 14), locale.Pad(l.Text("Photos"), 8), locale.Pad(l.Text("Days"), 6),
+		locale.Pad(l.Text("Median focal length"), 20), l.Text("Median ISO"))
+	for _, p := range []TrendPeriod{t.A, t.B} {
+		ew.printf("%s %s %s %s %s\n", locale.Pad(p.Label, 14), locale.Pad(strconv.Itoa(p.Photos), 8), locale.Pad(strconv.Itoa(p.Days), 6),
+			locale.Pad(trendNumber(p.MedianFocal, "mm"), 20), trendNumber(p.MedianISO, ""))
+	}
+	bar := func(share float64) string {
+		return locale.Pad(strings.Repeat("#", int(math.Round(share*trendBar))), trendBar)
+	}
+	for _, tb := range t.tables() {
+		ew.printf("\n%s %s %s %s\n", locale.Pad(l.Text(tb.name), 28), locale.Pad(t.A.Label, 7+trendBar), locale.Pad(t.B.Label, 7+trendBar),
+			l.Text("Change"))
+		for _, r := range tb.rows {
+			value := r.Value
+			if value == unknown {
+				value = l.Text(value)
+			}
+			ew.printf("%s %5.1f%% %s %5.1f%% %s %+.1fpt\n", locale.Pad(value, 28), r.ShareA*100, bar(r.ShareA),
+				r.ShareB*100, bar(r.ShareB), r.Change*100)
+		}
+	}
+	return ew.err
+}
+
+// trendNumber formats a median with its unit, or "-" for none.
+func trendNumber(v float64, unit string) string {
+	if v == 0 {
+		return "-"
+	}
+	return number(v) + unit
+}
+
+// Chart dimensions in pixels.
+const (
+	trendWidth  = 768
+	trendLabel  = 220
+	trendRow    = 22
+	trendTitle  = 28
+	trendMargin = 12
+)
+
+// WriteSVG draws the shares of each setting as a standalone SVG image of
+// grouped horizontal bars, period A above period B, in locale l.
+func (t *Trends) WriteSVG(w io.Writer, l *locale.Locale) error {
+	ew := &errWriter{w: w}
+	height := trendMargin + trendTitle
+	for _, tb := range t.tables() {
+		height += trendTitle + trendRow*len(tb.rows)
+	}
+	plotW := float64(trendWidth - trendLabel - trendMargin - 56)
+	ew.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", trendWidth, height)
+	y := trendMargin + 12
+	ew.printf(`<rect x="%d" y="%d" width="10" height="10" fill="#36c"/><text x="%d" y="%d">%s</text>`+"\n",
+		trendMargin, y-9, trendMargin+14, y, html.EscapeString(t.A.Label))
+	ew.printf(`<rect x="%d" y="%d" width="10" height="10" fill="#e80"/><text x="%d" y="%d">%s</text>`+"\n",
+		trendMargin+160, y-9, trendMargin+174, y, html.EscapeString(t.B.Label))
+	y = trendMargin + trendTitle
+	for _, tb := range t.tables() {
+		ew.printf(`<text x="%d" y="%d" font-weight="bold" font-size="13">%s</text>`+"\n", trendMargin, y+18, html.EscapeString(l.Text(tb.name)))
+		y += trendTitle
+		for _, r := range tb.rows {
+			value := r.Value
+			if value == unknown {
+				value = l.Text(value)
+			}
+			ew.printf(`<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", trendLabel-8, y+14, html.EscapeString(value))
+			for i, bar := range []struct {
+				share float64
+				fill  string
+			}{{r.ShareA, "#36c"}, {r.ShareB, "#e80"}} {
+				by := y + 3 + i*9
+				bw := plotW * bar.share
+				ew.printf(`<rect x="%d" y="%d" width="%.1f" height="8" fill="%s"/>`, trendLabel, by, bw, bar.fill)
+				ew.printf(`<text x="%.1f" y="%d" font-size="9">%.1f%%</text>`+"\n", float64(trendLabel)+bw+4, by+8, bar.share*100)
+			}
+			y += trendRow
+		}
+	}
+	ew.printf("</svg>\n")
+	return ew.err
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// typedField is a JSON field of a summary holding text, a whole number, a
+// decimal or a flag, or a list of them: a column of the typed outputs,
+// Parquet and SQL, which derive their columns from the summary so that
+// new fields need no column list kept in step.
+type typedField struct {
+	name  string
+	kind  reflect.Kind
+	list  bool
+	index int
+}
+
+// typedFields lists the typed fields in output order. The field sources
+// are left out, as in CSV.
+func typedFields() []typedField {
+	t := reflect.TypeOf(exif.Summary{})
+	var fields []typedField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "sources" {
+			continue
+		}
+		typ, list := f.Type, false
+		switch typ.Kind() {
+		case reflect.Pointer:
+			typ = typ.Elem()
+		case reflect.Slice:
+			typ, list = typ.Elem(), true
+		}
+		switch typ.Kind() {
+		case reflect.String, reflect.Int, reflect.Float64, reflect.Bool:
+			fields = append(fields, typedField{name, typ.Kind(), list, i})
+		}
+	}
+	return fields
+}
+
+// typedFields lists the typed fields of the columns of sel.
+func (sel *selection) typedFields() []typedField {
+	var fields []typedField
+	for _, f := range typedFields() {
+		if sel.column(f.name) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// selected is value, or nil where sel leaves the field of s out.
+func (f typedField) selected(s *exif.Summary, sel *selection) any {
+	if !sel.writes(s, f.name) {
+		return nil
+	}
+	return f.value(s)
+}
+
+// value returns the field of s as a string, int64, float64 or bool, a
+// []any of them for lists, or nil where the JSON output leaves the field
+// out, so a zero ISO or an unset flag reads as missing as it does there.
+// Pointers are nil only when unset, since a zero altitude or heading is
+// a value.
+func (f typedField) value(s *exif.Summary) any {
+	v := reflect.ValueOf(s).Elem().Field(f.index)
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return scalar(v.Elem())
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = scalar(v.Index(i))
+		}
+		return list
+	}
+	if v.IsZero() {
+		return nil
+	}
+	return scalar(v)
+}
+
+func scalar(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int:
+		return v.Int()
+	case reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Unit systems accepted by ConvertUnits.
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// UnitSystems lists the accepted unit systems, default first.
+var UnitSystems = []string{UnitsMetric, UnitsImperial}
+
+const metersPerFoot = 0.3048
+
+// DistanceUnit returns the symbol distances are shown in.
+func DistanceUnit(units string) string {
+	if units == UnitsImperial {
+		return "ft"
+	}
+	return "m"
+}
+
+// TemperatureUnit returns the symbol temperatures are shown in.
+func TemperatureUnit(units string) string {
+	if units == UnitsImperial {
+		return "°F"
+	}
+	return "°C"
+}
+
+// fromCelsius converts a temperature in degrees Celsius into units.
+func fromCelsius(c float64, units string) float64 {
+	if units != UnitsImperial {
+		return c
+	}
+	return math.Round((c*9/5+32)*10) / 10
+}
+
+// ConvertUnits rewrites the distances of summaries, which exif reports in
+// meters, into units: altitude, relative altitude, subject distance and
+// hyperfocal distance.
+// Values are rounded to hundredths.
+func ConvertUnits(summaries []*exif.Summary, units string) error {
+	if err := checkUnits(units); err != nil || units == UnitsMetric {
+		return err
+	}
+	for _, s := range summaries {
+		if s.Altitude != nil {
+			v := fromMeters(*s.Altitude, units)
+			s.Altitude = &v
+		}
+		if s.RelativeAltitude != nil {
+			v := fromMeters(*s.RelativeAltitude, units)
+			s.RelativeAltitude = &v
+		}
+		s.SubjectDistance = fromMeters(s.SubjectDistance, units)
+		s.HyperfocalDistance = fromMeters(s.HyperfocalDistance, units)
+	}
+	return nil
+}
+
+func checkUnits(units string) error {
+	if units != UnitsMetric && units != UnitsImperial {
+		return fmt.Errorf("report: unknown unit system %q", units)
+	}
+	return nil
+}
+
+// fromMeters converts a distance in meters into units.
+func fromMeters(m float64, units string) float64 {
+	if units != UnitsImperial {
+		return m
+	}
+	return math.Round(m/metersPerFoot*100) / 100
+}
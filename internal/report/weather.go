@@ -0,0 +1,67 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/weather"
+)
+
+// Weather summarizes the conditions a session was shot in.
+type Weather struct {
+	// Photos counts the photos an observation was found for.
+	Photos int `json:"photos"`
+	// Temperature is the range of temperatures, in the session's units.
+	Temperature *Range `json:"temperature,omitempty"`
+	// Conditions counts photos per reported condition.
+	Conditions map[string]int `json:"conditions"`
+}
+
+// AddWeather looks up the conditions of every photo with coordinates and
+// a capture time. Photos taken within the same hour and about a kilometer
+// of each other share one lookup.
+func (s *Session) AddWeather(ctx context.Context, summaries []*exif.Summary, p weather.Provider) error {
+	w := &Weather{Conditions: map[string]int{}}
+	cache := map[string]*weather.Conditions{}
+	for _, sum := range summaries {
+		t, ok := sum.CaptureTime()
+		if !ok || sum.Latitude == nil || sum.Longitude == nil {
+			continue
+		}
+		key := t.UTC().Truncate(time.Hour).Format(time.RFC3339) + " " +
+			strconv.FormatFloat(math.Round(*sum.Latitude*100)/100, 'f', 2, 64) + "," +
+			strconv.FormatFloat(math.Round(*sum.Longitude*100)/100, 'f', 2, 64)
+		c, seen := cache[key]
+		if !seen {
+			var err error
+			c, err = p.Lookup(ctx, t, *sum.Latitude, *sum.Longitude)
+			if errors.Is(err, weather.ErrNoData) {
+				c, err = nil, nil
+			}
+			if err != nil {
+				return err
+			}
+			cache[key] = c
+		}
+		if c == nil {
+			continue
+		}
+		w.Photos++
+		if c.Conditions != "" {
+			w.Conditions[c.Conditions]++
+		}
+		if temp := c.Temperature; temp != nil {
+			if w.Temperature == nil {
+				w.Temperature = &Range{Min: *temp, Max: *temp, Unit: TemperatureUnit(UnitsMetric)}
+			}
+			w.Temperature.Min = min(w.Temperature.Min, *temp)
+			w.Temperature.Max = max(w.Temperature.Max, *temp)
+		}
+	}
+	s.Weather = w
+	return nil
+}
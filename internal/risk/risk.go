@@ -0,0 +1,110 @@
+// Package risk scores how likely a shot is to be noisy or soft from its
+// exposure settings alone, so likely rejects can be flagged before
+// culling: a very high ISO, or a shutter time too slow for the focal
+// length without stabilization.
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Settings is the scoring formula. In YAML, with the defaults:
+//
+//	risk:
+//	  iso_base: 800
+//	  noise_stops: 3
+//	  shutter_factor: 1
+//	  stabilization_stops: 3
+//	  blur_stops: 2
+//	  noise_weight: 0.4
+//	  blur_weight: 0.6
+//
+// Noise rises from 0 at ISOBase to 1 at NoiseStops stops above it. The
+// slowest safe handheld time is 1/(ShutterFactor × the 35 mm focal
+// length) seconds, StabilizationStops slower with stabilization on; blur
+// rises from 0 there to 1 at BlurStops stops slower. The score is the
+// weighted mean of the two.
+type Settings struct {
+	ISOBase            float64 `json:"iso_base"`
+	NoiseStops         float64 `json:"noise_stops"`
+	ShutterFactor      float64 `json:"shutter_factor"`
+	StabilizationStops float64 `json:"stabilization_stops"`
+	BlurStops          float64 `json:"blur_stops"`
+	NoiseWeight        float64 `json:"noise_weight"`
+	BlurWeight         float64 `json:"blur_weight"`
+}
+
+// Default returns the formula used when the config file has none.
+func Default() Settings {
+	return Settings{ISOBase: 800, NoiseStops: 3, ShutterFactor: 1, StabilizationStops: 3, BlurStops: 2, NoiseWeight: 0.4, BlurWeight: 0.6}
+}
+
+// Validate reports values the formula cannot use.
+func (r *Settings) Validate() error {
+	for _, v := range []struct {
+		name string
+		v    float64
+	}{{"iso_base", r.ISOBase}, {"noise_stops", r.NoiseStops}, {"shutter_factor", r.ShutterFactor}, {"blur_stops", r.BlurStops}} {
+		if !(v.v > 0) {
+			return fmt.Errorf("risk: %s must be positive, got %g", v.name, v.v)
+		}
+	}
+	if r.StabilizationStops < 0 || r.NoiseWeight < 0 || r.BlurWeight < 0 {
+		return errors.New("risk: stabilization_stops and the weights must not be negative")
+	}
+	if r.NoiseWeight+r.BlurWeight == 0 {
+		return errors.New("risk: noise_weight and blur_weight are both 0")
+	}
+	return nil
+}
+
+// Score is the risk of one shot and its parts, each 0-1.
+type Score struct {
+	Risk  float64 `json:"risk"`
+	Noise float64 `json:"noise"`
+	Blur  float64 `json:"blur"`
+	// Safe is the slowest safe handheld shutter time in seconds, or 0
+	// when the focal length is unknown.
+	Safe float64 `json:"safe_exposure,omitempty"`
+}
+
+// Score rates s, and reports false when it records neither ISO nor
+// shutter time.
+func (r *Settings) Score(s *exif.Summary) (Score, bool) {
+	if s.ISO <= 0 && s.ExposureTime <= 0 {
+		return Score{}, false
+	}
+	var sc Score
+	if s.ISO > 0 {
+		sc.Noise = ramp(math.Log2(float64(s.ISO)/r.ISOBase) / r.NoiseStops)
+	}
+	focal := float64(s.FocalLength35mm)
+	if focal <= 0 {
+		focal = s.FocalLength
+	}
+	if focal > 0 {
+		sc.Safe = 1 / (r.ShutterFactor * focal)
+		if s.Stabilization == "on" {
+			sc.Safe *= math.Exp2(r.StabilizationStops)
+		}
+		if s.ExposureTime > 0 {
+			sc.Blur = ramp(math.Log2(s.ExposureTime/sc.Safe) / r.BlurStops)
+		}
+	}
+	sc.Risk = round((r.NoiseWeight*sc.Noise + r.BlurWeight*sc.Blur) / (r.NoiseWeight + r.BlurWeight))
+	sc.Noise, sc.Blur = round(sc.Noise), round(sc.Blur)
+	return sc, true
+}
+
+// ramp clamps v to 0-1.
+func ramp(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
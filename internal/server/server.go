@@ -0,0 +1,351 @@
+// Package server summarizes uploaded images over HTTP. It is built to run
+// as a shared extraction service: clients authenticate with bearer tokens
+// or TLS client certificates, uploads are size-limited and parsed within
+// exif.Limits, requests are time-boxed and admitted up to a concurrency
+// limit, every request is logged, and shutdown drains the requests in
+// flight.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/sink"
+)
+
+// Defaults of the Server fields left zero.
+const (
+	DefaultMaxUploadBytes  = 64 << 20
+	DefaultTimeout         = 30 * time.Second
+	DefaultMaxConcurrent   = 16
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// MaxTraceDuration bounds a trace started with /debug/trace/start, so one
+// left running does not fill memory.
+const MaxTraceDuration = 5 * time.Minute
+
+// Server answers POST /summary with the JSON summary of the image in the
+// request body. GET /healthz answers 200 while the process is up, and
+// GET /readyz 200 until shutdown begins and 503 after, so a load balancer
+// stops routing to a draining server. The probes need no token.
+type Server struct {
+	// Tokens, when set, are the bearer tokens /summary accepts in the
+	// Authorization header; other requests get 401.
+	Tokens []string
+	// TLS, when set, serves HTTPS. Setting its ClientAuth to
+	// tls.RequireAndVerifyClientCert and its ClientCAs authenticates
+	// clients by certificate, alone or along with Tokens.
+	TLS *tls.Config
+	// MaxUploadBytes bounds the request body; larger uploads get 413.
+	MaxUploadBytes int64
+	// Limits bounds the parser on each upload. Its MaxFileBytes is
+	// MaxUploadBytes.
+	Limits exif.Limits
+	// Timeout bounds each request, from reading the body to writing the
+	// summary. Requests that run over get 503.
+	Timeout time.Duration
+	// MaxConcurrent bounds the requests handled at once. Requests beyond
+	// it get 503 at once rather than queueing.
+	MaxConcurrent int
+	// ShutdownTimeout bounds how long shutdown waits for the requests in
+	// flight.
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long the server keeps accepting requests, with
+	// /readyz failing, before it shuts down: the time a load balancer
+	// takes to notice.
+	DrainDelay time.Duration
+	// Protect, when set, is applied to every summary before it is sent,
+	// e.g. to redact home locations.
+	Protect func(*exif.Summary)
+	// Sink, when set, receives every summary served, after Protect and
+	// without its sources, before the response is written, so a slow
+	// sink counts against Timeout. A failed send is logged; the client
+	// gets its summary all the same.
+	Sink sink.Sink
+	// Log receives one access record per request and the server's
+	// errors. Nil discards them.
+	Log *slog.Logger
+	// Debug mounts the net/http/pprof handlers under /debug/pprof/ and a
+	// runtime/trace toggle: POST /debug/trace/start begins an execution
+	// trace and POST /debug/trace/stop ends it and answers with it. A
+	// trace not stopped within MaxTraceDuration stops by itself. The
+	// endpoints take the same tokens as /summary, but not its timeout:
+	// profiles run for their ?seconds= as long as WriteTimeout allows.
+	Debug bool
+
+	draining atomic.Bool
+	trace    tracer
+}
+
+// ListenAndServe serves on addr until ctx is done, then fails /readyz for
+// DrainDelay, stops accepting connections and waits up to ShutdownTimeout
+// for the requests in flight.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is ListenAndServe on an existing listener, which it closes.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.TLS != nil {
+		ln = tls.NewListener(ln, s.TLS)
+	}
+	timeout := or(s.Timeout, DefaultTimeout)
+	hs := &http.Server{
+		Handler: s.Handler(),
+		// The handler's own timeout covers reading the body; these bound
+		// the connection around it.
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      timeout + 5*time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ErrorLog:          slog.NewLogLogger(s.logger().Handler(), slog.LevelWarn),
+	}
+	s.logger().Info("listening", "addr", ln.Addr().String())
+	errc := make(chan error, 1)
+	go func() { errc <- hs.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	s.draining.Store(true)
+	if s.DrainDelay > 0 {
+		s.logger().Info("draining", "delay", s.DrainDelay.String())
+		time.Sleep(s.DrainDelay)
+	}
+	s.logger().Info("shutting down")
+	sctx, cancel := context.WithTimeout(context.Background(), or(s.ShutdownTimeout, DefaultShutdownTimeout))
+	defer cancel()
+	if err := hs.Shutdown(sctx); err != nil {
+		hs.Close()
+		return err
+	}
+	return nil
+}
+
+// Handler returns the server's routes with the request limits and access
+// log applied.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	var summary http.Handler = http.HandlerFunc(s.summary)
+	// The limit goes inside the timeout: TimeoutHandler answers when the
+	// time is up but leaves the handler running, and the slot must stay
+	// taken until it returns.
+	summary = limitConcurrency(summary, int(or(int64(s.MaxConcurrent), DefaultMaxConcurrent)))
+	summary = http.TimeoutHandler(summary, or(s.Timeout, DefaultTimeout), `{"error":"request timed out"}`)
+	summary = s.authenticate(summary)
+	mux.Handle("POST /summary", summary)
+	if s.Debug {
+		for path, h := range map[string]http.HandlerFunc{
+			"/debug/pprof/":           pprof.Index,
+			"/debug/pprof/cmdline":    pprof.Cmdline,
+			"/debug/pprof/profile":    pprof.Profile,
+			"/debug/pprof/symbol":     pprof.Symbol,
+			"/debug/pprof/trace":      pprof.Trace,
+			"POST /debug/trace/start": s.trace.start,
+			"POST /debug/trace/stop":  s.trace.stop,
+		} {
+			mux.Handle(path, s.authenticate(h))
+		}
+	}
+	return s.accessLog(mux)
+}
+
+// summary decodes the request body. ?provenance=true keeps the sources of
+// the fields.
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	maxBytes := or(s.MaxUploadBytes, DefaultMaxUploadBytes)
+	limits := s.Limits
+	limits.MaxFileBytes = maxBytes
+	data, err := limits.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes+1))
+	var limit *exif.LimitExceededError
+	switch {
+	case errors.As(err, &limit):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Context().Err() != nil {
+		// Timed out while reading: nobody waits for the summary.
+		return
+	}
+	d, err := exif.NewLimitedDecoder(data, limits)
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	// Images without metadata and those over the parser limits alike
+	// cannot be summarized.
+	sum, err := d.Summary()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if s.Protect != nil {
+		s.Protect(sum)
+	}
+	if s.Sink != nil {
+		c := *sum
+		c.Sources = nil
+		if err := s.Sink.Send(r.Context(), &c); err != nil {
+			s.logger().Error("sink failed", "err", err)
+		}
+	}
+	if ok, _ := strconv.ParseBool(r.URL.Query().Get("provenance")); !ok {
+		sum.Sources = nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sum)
+}
+
+// writeError sends err as {"error": "..."}.
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// authenticate passes requests bearing one of s.Tokens to h. Every token
+// is compared in constant time, so the response time does not tell which
+// one a guess came closest to.
+func (s *Server) authenticate(h http.Handler) http.Handler {
+	if len(s.Tokens) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		ok := 0
+		if strings.EqualFold(scheme, "Bearer") && token != "" {
+			for _, t := range s.Tokens {
+				ok |= subtle.ConstantTimeCompare([]byte(token), []byte(t))
+			}
+		}
+		if ok == 0 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="shootlog"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or unknown bearer token"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// limitConcurrency admits up to n requests at once to h and turns the
+// rest away with 503 and Retry-After.
+func limitConcurrency(h http.Handler, n int) http.Handler {
+	slots := make(chan struct{}, n)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			h.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, errors.New("too many concurrent requests"))
+		}
+	})
+}
+
+// recorder captures the status and size of a response.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// accessLog writes one record per request with its method, path, status,
+// sizes and duration.
+func (s *Server) accessLog(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &recorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		s.logger().LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("request_bytes", r.ContentLength),
+			slog.Int64("response_bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+			slog.String("client", client(r)),
+		)
+	})
+}
+
+// client names the verified client certificate of r, or "" without one.
+func client(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.String()
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Log == nil {
+		return slog.New(discard{})
+	}
+	return s.Log
+}
+
+// discard is a slog.Handler that drops every record.
+type discard struct{}
+
+func (discard) Enabled(context.Context, slog.Level) bool  { return false }
+func (discard) Handle(context.Context, slog.Record) error { return nil }
+func (d discard) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discard) WithGroup(string) slog.Handler           { return d }
+
+// or returns v, or def when v is zero or negative.
+func or[T int64 | time.Duration](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/exiftest"
+)
+
+// TestServerConcurrencyTimeout checks that a request which timed out
+// keeps its slot until its handler returns.
+func TestServerConcurrencyTimeout(t *testing.T) {
+	image := exiftest.Scenarios()[0].Encode()
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	s := &Server{Timeout: 100 * time.Millisecond, MaxConcurrent: 1, Protect: func(*exif.Summary) {
+		entered <- struct{}{}
+		<-release
+	}}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	post := func() (int, string) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/summary", "image/jpeg", bytes.NewReader(image))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var b bytes.Buffer
+		b.ReadFrom(resp.Body)
+		return resp.StatusCode, b.String()
+	}
+
+	steps := []struct {
+		name   string
+		before func()
+		status int
+		body   string
+	}{
+		{"slow request times out", func() {}, http.StatusServiceUnavailable, "request timed out"},
+		{"its handler still holds the slot", func() { <-entered }, http.StatusServiceUnavailable, "too many concurrent requests"},
+		{"slot freed once it returns", func() {
+			close(release)
+			time.Sleep(50 * time.Millisecond)
+		}, http.StatusOK, `"model"`},
+	}
+	for _, st := range steps {
+		st.before()
+		status, body := post()
+		if status != st.status || !bytes.Contains([]byte(body), []byte(st.body)) {
+			t.Errorf("%s: %d %s, want %d with %q", st.name, status, body, st.status, st.body)
+		}
+	}
+}
+
+func TestServerDebug(t *testing.T) {
+	tests := []struct {
+		name   string
+		debug  bool
+		tokens []string
+		auth   string
+		steps  []debugStep
+	}{
+		{"off", false, nil, "", []debugStep{
+			{"GET", "/debug/pprof/", http.StatusNotFound, ""},
+			{"POST", "/debug/trace/start", http.StatusNotFound, ""},
+		}},
+		{"on", true, nil, "", []debugStep{
+			{"GET", "/debug/pprof/", http.StatusOK, "goroutine"},
+			{"GET", "/debug/pprof/heap?debug=1", http.StatusOK, "heap profile"},
+			{"POST", "/debug/trace/stop", http.StatusConflict, "no trace was started"},
+			{"POST", "/debug/trace/start", http.StatusAccepted, ""},
+			{"POST", "/debug/trace/start", http.StatusConflict, "already running"},
+			{"POST", "/debug/trace/stop", http.StatusOK, "go 1."},
+			{"POST", "/debug/trace/stop", http.StatusConflict, "no trace was started"},
+		}},
+		{"needs the token", true, []string{"secret"}, "", []debugStep{
+			{"GET", "/debug/pprof/", http.StatusUnauthorized, ""},
+			{"POST", "/debug/trace/start", http.StatusUnauthorized, ""},
+		}},
+		{"with the token", true, []string{"secret"}, "Bearer secret", []debugStep{
+			{"GET", "/debug/pprof/cmdline", http.StatusOK, ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer((&Server{Debug: tt.debug, Tokens: tt.tokens}).Handler())
+			defer ts.Close()
+			for _, st := range tt.steps {
+				req, err := http.NewRequest(st.method, ts.URL+st.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.auth != "" {
+					req.Header.Set("Authorization", tt.auth)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var b bytes.Buffer
+				b.ReadFrom(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != st.status || !bytes.Contains(b.Bytes(), []byte(st.body)) {
+					t.Errorf("%s %s: %d %.80q, want %d with %q", st.method, st.path, resp.StatusCode, b.String(), st.status, st.body)
+				}
+			}
+		})
+	}
+}
+
+type debugStep struct {
+	method, path string
+	status       int
+	body         string
+}
+
+// recordSink keeps the summaries sent to it, failing with err.
+type recordSink struct {
+	mu   sync.Mutex
+	sent []*exif.Summary
+	err  error
+}
+
+func (r *recordSink) Send(_ context.Context, s *exif.Summary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, s)
+	return r.err
+}
+
+func (r *recordSink) Close() error { return nil }
+
+func TestServerSink(t *testing.T) {
+	image := exiftest.Scenarios()[0].Encode()
+	tests := []struct {
+		name   string
+		query  string
+		body   []byte
+		err    error
+		status int
+		sent   int
+		log    string
+	}{
+		{"sent", "", image, nil, http.StatusOK, 1, ""},
+		{"sent without sources", "?provenance=true", image, nil, http.StatusOK, 1, ""},
+		{"failed send", "", image, errors.New("hook down"), http.StatusOK, 1, "hook down"},
+		{"no summary", "", []byte("not an image"), nil, http.StatusUnprocessableEntity, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &recordSink{err: tt.err}
+			var log bytes.Buffer
+			s := &Server{
+				Sink:    k,
+				Protect: func(s *exif.Summary) { s.Model = "protected" },
+				Log:     slog.New(slog.NewTextHandler(&log, nil)),
+			}
+			ts := httptest.NewServer(s.Handler())
+			defer ts.Close()
+			resp, err := http.Post(ts.URL+"/summary"+tt.query, "image/jpeg", bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body bytes.Buffer
+			body.ReadFrom(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d %s, want %d", resp.StatusCode, body.String(), tt.status)
+			}
+			if len(k.sent) != tt.sent {
+				t.Fatalf("%d summaries sent, want %d", len(k.sent), tt.sent)
+			}
+			for _, sum := range k.sent {
+				if sum.Model != "protected" || sum.Sources != nil {
+					t.Errorf("sent model %q with sources %v, want it protected without", sum.Model, sum.Sources)
+				}
+			}
+			if tt.query != "" && !bytes.Contains(body.Bytes(), []byte(`"sources"`)) {
+				t.Errorf("response without sources: %s", body.String())
+			}
+			if tt.log != "" && !bytes.Contains(log.Bytes(), []byte(tt.log)) {
+				t.Errorf("log %q, want %q", log.String(), tt.log)
+			}
+		})
+	}
+}
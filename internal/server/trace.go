@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"runtime/trace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tracer runs the execution trace toggled by /debug/trace/start and
+// /debug/trace/stop. The trace is held in memory until it is fetched.
+type tracer struct {
+	mu      sync.Mutex
+	running bool
+	buf     bytes.Buffer
+	timer   *time.Timer
+}
+
+func (t *tracer) start(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		writeError(w, http.StatusConflict, errors.New("a trace is already running"))
+		return
+	}
+	t.buf.Reset()
+	if err := trace.Start(&t.buf); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	t.running = true
+	t.timer = time.AfterFunc(MaxTraceDuration, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.running {
+			trace.Stop()
+			t.running = false
+		}
+	})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// stop ends the trace, if it has not stopped by itself, and sends it.
+func (t *tracer) stop(w http.ResponseWriter, r *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		t.timer.Stop()
+		trace.Stop()
+		t.running = false
+	}
+	if t.buf.Len() == 0 {
+		writeError(w, http.StatusConflict, errors.New("no trace was started; POST /debug/trace/start first"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace.out"`)
+	w.Header().Set("Content-Length", strconv.Itoa(t.buf.Len()))
+	w.Write(t.buf.Bytes())
+	t.buf.Reset()
+}
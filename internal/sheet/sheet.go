@@ -0,0 +1,294 @@
+// Package sheet lays out contact sheets: pages of thumbnails in a grid,
+// each captioned with its file name and key exposure settings, rendered
+// as images or as a PDF.
+package sheet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+	"github.com/ryoh827/shootlog/internal/pdf"
+)
+
+// Options control the layout of a sheet.
+type Options struct {
+	Columns, Rows int
+	// Page is the page size in points.
+	Page pdf.Size
+	// DPI is the resolution of raster pages and of the thumbnails
+	// embedded in a PDF.
+	DPI int
+	// Title is printed at the foot of every page.
+	Title string
+}
+
+// Layout in points.
+const (
+	margin  = 36.0
+	gap     = 12.0
+	leading = 9.0
+	// fontSize is the size of caption text in a PDF.
+	fontSize = 7.0
+	// captionLines is the number of caption lines under a thumbnail.
+	captionLines = 4
+	// minBox is the smallest thumbnail box that is still useful.
+	minBox = 36.0
+)
+
+type rect struct {
+	x, y, w, h float64
+}
+
+// cells returns the thumbnail boxes of a page in reading order.
+// Thumbnails sit centred on the bottom of their box, right above the
+// caption.
+func (o Options) cells() ([]rect, error) {
+	if o.Columns < 1 || o.Rows < 1 {
+		return nil, errors.New("sheet: columns and rows must be at least 1")
+	}
+	if o.DPI < 36 || o.DPI > 1200 {
+		return nil, fmt.Errorf("sheet: dpi %d out of range 36-1200", o.DPI)
+	}
+	w := (o.Page.Width - 2*margin - float64(o.Columns-1)*gap) / float64(o.Columns)
+	h := (o.Page.Height - 2*margin - float64(o.Rows-1)*gap) / float64(o.Rows)
+	box := h - captionHeight
+	if w < minBox || box < minBox {
+		return nil, fmt.Errorf("sheet: page too small for %d columns and %d rows", o.Columns, o.Rows)
+	}
+	var out []rect
+	for r := 0; r < o.Rows; r++ {
+		for c := 0; c < o.Columns; c++ {
+			out = append(out, rect{margin + float64(c)*(w+gap), margin + float64(r)*(h+gap), w, box})
+		}
+	}
+	return out, nil
+}
+
+// captionHeight is the space taken by the caption under a box.
+const captionHeight = 3 + captionLines*leading
+
+// Pages returns the number of pages needed for n photos.
+func (o Options) Pages(n int) int {
+	per := max(o.Columns*o.Rows, 1)
+	return max((n+per-1)/per, 1)
+}
+
+// Caption returns the caption lines of a photo: its file name, exposure,
+// focal length and camera, and capture time and rating. Missing values
+// are left out.
+func Caption(s *exif.Summary) []string {
+	var exposure, optics, when []string
+	if s.ExposureTime > 0 {
+		exposure = append(exposure, exif.FormatExposure(s.ExposureTime)+"s")
+	}
+	if s.FNumber > 0 {
+		exposure = append(exposure, "f/"+strconv.FormatFloat(s.FNumber, 'f', -1, 64))
+	}
+	if s.ISO > 0 {
+		exposure = append(exposure, "ISO "+strconv.Itoa(s.ISO))
+	}
+	if s.FocalLength > 0 {
+		optics = append(optics, strconv.FormatFloat(s.FocalLength, 'f', -1, 64)+"mm")
+	}
+	if s.Model != "" {
+		optics = append(optics, s.Model)
+	}
+	if t := s.DateTimeOriginal; len(t) >= 16 {
+		when = append(when, strings.Replace(t[:16], "T", " ", 1))
+	}
+	if s.Rating > 0 && s.Rating <= 5 {
+		when = append(when, strings.Repeat("*", s.Rating))
+	}
+	return []string{
+		filepath.Base(s.Path),
+		strings.Join(exposure, " "),
+		strings.Join(optics, " "),
+		strings.Join(when, "  "),
+	}
+}
+
+// load decodes the image to show for s, or returns nil when the file has
+// none.
+func load(s *exif.Summary) (image.Image, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data), nil
+}
+
+// Caption colors.
+var (
+	black = color.RGBA{0, 0, 0, 0xFF}
+	gray  = color.RGBA{0x59, 0x59, 0x59, 0xFF}
+	light = color.RGBA{0xBF, 0xBF, 0xBF, 0xFF}
+)
+
+// noPreview labels the box of a file without a preview.
+const noPreview = "no preview"
+
+// Raster renders the photos as page images.
+func Raster(photos []*exif.Summary, o Options) ([]*image.RGBA, error) {
+	cells, err := o.cells()
+	if err != nil {
+		return nil, err
+	}
+	k := float64(o.DPI) / 72
+	px := func(v float64) int { return int(v*k + 0.5) }
+	// The bitmap font is scaled to the nearest whole multiple of its
+	// size that fills the caption leading.
+	fs := max(int(leading*k/glyphHeight+0.5), 1)
+
+	var pages []*image.RGBA
+	total := o.Pages(len(photos))
+	for n := 0; n < total; n++ {
+		page := image.NewRGBA(image.Rect(0, 0, px(o.Page.Width), px(o.Page.Height)))
+		draw.Draw(page, page.Rect, image.White, image.Point{}, draw.Src)
+		for i, c := range cells {
+			idx := n*len(cells) + i
+			if idx >= len(photos) {
+				break
+			}
+			s := photos[idx]
+			img, err := load(s)
+			if err != nil {
+				return nil, err
+			}
+			bx, by, bw, bh := px(c.x), px(c.y), px(c.w), px(c.h)
+			if img == nil {
+				outline(page, image.Rect(bx, by, bx+bw, by+bh), light)
+				w := min(textWidth(noPreview, fs), bw)
+				drawText(page, bx+(bw-w)/2, by+(bh-7*fs)/2, fs, noPreview, gray)
+			} else {
+				t := thumbnail(img, s.Orientation, bw, bh)
+				at := image.Pt(bx+(bw-t.Rect.Dx())/2, by+bh-t.Rect.Dy())
+				draw.Draw(page, t.Rect.Add(at), t, image.Point{}, draw.Src)
+			}
+			for l, line := range Caption(s) {
+				if line == "" {
+					continue
+				}
+				col := gray
+				if l == 0 {
+					col = black
+				}
+				line = fit(line, func(s string) float64 { return float64(textWidth(s, fs)) }, float64(bw))
+				drawText(page, bx, by+bh+px(3+float64(l)*leading), fs, line, col)
+			}
+		}
+		if f := footer(o, n, total); f != "" {
+			drawText(page, px(margin), px(o.Page.Height-margin/2)-7*fs/2, fs, f, gray)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// outline strokes a one pixel rectangle.
+func outline(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		img.SetRGBA(x, r.Min.Y, c)
+		img.SetRGBA(x, r.Max.Y-1, c)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		img.SetRGBA(r.Min.X, y, c)
+		img.SetRGBA(r.Max.X-1, y, c)
+	}
+}
+
+// PDF renders the photos as a PDF, one page per grid. Thumbnails are
+// embedded as JPEGs at the resolution of the options.
+func PDF(photos []*exif.Summary, o Options) (*pdf.Document, error) {
+	cells, err := o.cells()
+	if err != nil {
+		return nil, err
+	}
+	k := float64(o.DPI) / 72
+	doc := &pdf.Document{Info: map[string]string{"Creator": "shootlog"}}
+	if o.Title != "" {
+		doc.Info["Title"] = o.Title
+	}
+	total := o.Pages(len(photos))
+	for n := 0; n < total; n++ {
+		page := doc.AddPage(o.Page)
+		for i, c := range cells {
+			idx := n*len(cells) + i
+			if idx >= len(photos) {
+				break
+			}
+			s := photos[idx]
+			img, err := load(s)
+			if err != nil {
+				return nil, err
+			}
+			if img == nil {
+				page.Gray(0.75)
+				page.Rect(c.x, c.y, c.w, c.h, 0.5)
+				page.Gray(0.35)
+				w := pdf.TextWidth(pdf.Helvetica, fontSize, noPreview)
+				page.Text(pdf.Helvetica, fontSize, c.x+(c.w-w)/2, c.y+(c.h+fontSize)/2, noPreview)
+			} else {
+				t := thumbnail(img, s.Orientation, int(c.w*k+0.5), int(c.h*k+0.5))
+				var buf bytes.Buffer
+				if err := jpeg.Encode(&buf, t, &jpeg.Options{Quality: 85}); err != nil {
+					return nil, err
+				}
+				w, h := float64(t.Rect.Dx())/k, float64(t.Rect.Dy())/k
+				page.Image(buf.Bytes(), t.Rect.Dx(), t.Rect.Dy(), false, c.x+(c.w-w)/2, c.y+c.h-h, w, h)
+			}
+			for l, line := range Caption(s) {
+				if line == "" {
+					continue
+				}
+				font := pdf.Helvetica
+				page.Gray(0.35)
+				if l == 0 {
+					font = pdf.HelveticaBold
+					page.Gray(0)
+				}
+				line = fit(line, func(s string) float64 { return pdf.TextWidth(font, fontSize, s) }, c.w)
+				page.Text(font, fontSize, c.x, c.y+c.h+3+float64(l+1)*leading-2, line)
+			}
+		}
+		if f := footer(o, n, total); f != "" {
+			page.Gray(0.35)
+			page.Text(pdf.Helvetica, fontSize, margin, o.Page.Height-margin/2+fontSize/2, f)
+		}
+	}
+	return doc, nil
+}
+
+// footer returns the text at the foot of page n (from 0) of total: the
+// title and, on sheets of several pages, the page number.
+func footer(o Options, n, total int) string {
+	parts := []string{}
+	if o.Title != "" {
+		parts = append(parts, o.Title)
+	}
+	if total > 1 {
+		parts = append(parts, fmt.Sprintf("%d/%d", n+1, total))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// fit shortens s with "..." until its width is at most w.
+func fit(s string, width func(string) float64, w float64) string {
+	if width(s) <= w {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && width(string(r)+"...") > w {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
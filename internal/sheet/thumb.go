@@ -0,0 +1,134 @@
+package sheet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// decode returns the image to show for a file: the file itself when it is
+// a JPEG, else the largest embedded preview that decodes, else the EXIF
+// thumbnail. It returns nil when there is nothing to show, such as for an
+// uncompressed TIFF.
+func decode(data []byte) image.Image {
+	if exif.IsJPEG(data) {
+		if img, err := jpeg.Decode(bytes.NewReader(data)); err == nil {
+			return img
+		}
+	}
+	var best []byte
+	bestArea := 0
+	for _, p := range exif.Previews(data) {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(p))
+		if err == nil && cfg.Width*cfg.Height > bestArea {
+			best, bestArea = p, cfg.Width*cfg.Height
+		}
+	}
+	if best != nil {
+		if img, err := jpeg.Decode(bytes.NewReader(best)); err == nil {
+			return img
+		}
+	}
+	if t := exif.Thumbnail(data); t != nil {
+		if img, err := jpeg.Decode(bytes.NewReader(t)); err == nil {
+			return img
+		}
+	}
+	return nil
+}
+
+// thumbnail scales img to fit within w by h pixels once turned upright
+// according to the EXIF orientation.
+func thumbnail(img image.Image, orientation, w, h int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if orientation >= 5 && orientation <= 8 {
+		// Rotated by a quarter turn: fit the swapped dimensions.
+		w, h = h, w
+	}
+	tw, th := w, sh*w/sw
+	if th > h {
+		tw, th = sw*h/sh, h
+	}
+	return orient(scale(img, max(tw, 1), max(th, 1)), orientation)
+}
+
+// samples bounds the source pixels averaged into one thumbnail pixel, per
+// direction, so that large images scale in time proportional to the
+// thumbnail rather than to the original.
+const samples = 4
+
+// scale resizes img to w by h pixels, averaging a grid of samples over the
+// area each destination pixel covers.
+func scale(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, n uint32
+			for _, sy := range steps(y0, y1) {
+				for _, sx := range steps(x0, x1) {
+					cr, cg, cb, _ := img.At(sx, sy).RGBA()
+					r, g, bl, n = r+cr, g+cg, bl+cb, n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), 0xFF})
+		}
+	}
+	return dst
+}
+
+// steps returns up to samples positions spread over [lo, hi), or lo alone
+// when the range is empty.
+func steps(lo, hi int) []int {
+	if hi <= lo {
+		return []int{lo}
+	}
+	n := min(hi-lo, samples)
+	out := make([]int, n)
+	for i := range out {
+		out[i] = lo + (2*i+1)*(hi-lo)/(2*n)
+	}
+	return out
+}
+
+// orient turns img upright according to an EXIF orientation value.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, img.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,104 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+func init() {
+	Register("http", openWebhook)
+	Register("https", openWebhook)
+	Register("kafka+http", openKafkaREST)
+	Register("kafka+https", openKafkaREST)
+}
+
+// client bounds every delivery so a stalled endpoint cannot block the
+// pipeline.
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// post sends body to endpoint and fails on non-2xx responses.
+func post(ctx context.Context, endpoint, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sink: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sink: POST %s: %s: %s", redact(endpoint), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// redact hides credentials in URLs printed in errors.
+func redact(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Redacted()
+	}
+	return endpoint
+}
+
+// webhook POSTs each summary as a JSON object.
+type webhook struct {
+	url string
+}
+
+func openWebhook(u *url.URL) (Sink, error) {
+	return &webhook{url: u.String()}, nil
+}
+
+func (w *webhook) Send(ctx context.Context, s *exif.Summary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.url, "application/json", body)
+}
+
+func (w *webhook) Close() error { return nil }
+
+// kafkaREST publishes to a Kafka topic through a Kafka REST Proxy, e.g.
+// kafka+http://proxy:8082/topics/photos. Records are keyed by path so all
+// events for a file land in the same partition.
+type kafkaREST struct {
+	url string
+}
+
+func openKafkaREST(u *url.URL) (Sink, error) {
+	if !strings.HasPrefix(u.Path, "/topics/") || len(u.Path) == len("/topics/") {
+		return nil, fmt.Errorf("sink: %s: want a /topics/<name> path", u.Redacted())
+	}
+	c := *u
+	c.Scheme = strings.TrimPrefix(u.Scheme, "kafka+")
+	return &kafkaREST{url: c.String()}, nil
+}
+
+func (k *kafkaREST) Send(ctx context.Context, s *exif.Summary) error {
+	type record struct {
+		Key   string        `json:"key"`
+		Value *exif.Summary `json:"value"`
+	}
+	body, err := json.Marshal(struct {
+		Records []record `json:"records"`
+	}{[]record{{Key: s.Path, Value: s}}})
+	if err != nil {
+		return err
+	}
+	return post(ctx, k.url, "application/vnd.kafka.json.v2+json", body)
+}
+
+func (k *kafkaREST) Close() error { return nil }
@@ -0,0 +1,92 @@
+// Package sink delivers summaries to external systems as photos are
+// processed: webhooks, NATS subjects and Kafka topics.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Sink receives the summary of every processed file.
+type Sink interface {
+	// Send delivers one summary. It must be safe to call from several
+	// goroutines.
+	Send(ctx context.Context, s *exif.Summary) error
+	// Close releases connections held by the sink.
+	Close() error
+}
+
+// Opener creates a sink from its URL.
+type Opener func(u *url.URL) (Sink, error)
+
+var (
+	mu      sync.RWMutex
+	openers = map[string]Opener{}
+)
+
+// Register makes a sink available under a URL scheme. Built-in sinks
+// register themselves for http, https, nats, kafka+http and kafka+https.
+func Register(scheme string, open Opener) {
+	mu.Lock()
+	defer mu.Unlock()
+	openers[scheme] = open
+}
+
+// Open returns the sink for rawURL, chosen by its scheme.
+func Open(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("sink: %w", err)
+	}
+	mu.RLock()
+	open, ok := openers[u.Scheme]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("sink: unsupported scheme %q (want one of %s)", u.Scheme, strings.Join(Schemes(), ", "))
+	}
+	return open(u)
+}
+
+// Schemes lists the registered URL schemes.
+func Schemes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	schemes := make([]string, 0, len(openers))
+	for s := range openers {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Multi sends every summary to all of its sinks.
+type Multi []Sink
+
+// Send sends s to each sink and returns the first error after trying all
+// of them.
+func (m Multi) Send(ctx context.Context, s *exif.Summary) error {
+	var first error
+	for _, k := range m {
+		if err := k.Send(ctx, s); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every sink.
+func (m Multi) Close() error {
+	var first error
+	for _, k := range m {
+		if err := k.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
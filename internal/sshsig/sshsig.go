@@ -0,0 +1,275 @@
+// Package sshsig signs and verifies files in the SSH signature format of
+// ssh-keygen -Y sign, with Ed25519 keys, so recipients can authenticate
+// a delivered file with the photographer's SSH public key and either
+// shootlog or ssh-keygen -Y verify.
+package sshsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Namespace is the signature namespace of shootlog manifests, which keeps
+// their signatures from being valid for other purposes.
+const Namespace = "shootlog-manifest@github.com/ryoh827/shootlog"
+
+const (
+	magic       = "SSHSIG"
+	keyType     = "ssh-ed25519"
+	hashAlg     = "sha512"
+	armorBegin  = "-----BEGIN SSH SIGNATURE-----"
+	armorEnd    = "-----END SSH SIGNATURE-----"
+	armorColumn = 70
+)
+
+// Sign returns the armored signature of data in namespace.
+func Sign(key ed25519.PrivateKey, namespace string, data []byte) []byte {
+	pub := publicBlob(key.Public().(ed25519.PublicKey))
+	sig := ed25519.Sign(key, signedData(namespace, data))
+	var blob []byte
+	blob = append(blob, magic...)
+	blob = binary.BigEndian.AppendUint32(blob, 1)
+	blob = appendString(blob, pub)
+	blob = appendString(blob, []byte(namespace))
+	blob = appendString(blob, nil)
+	blob = appendString(blob, []byte(hashAlg))
+	blob = appendString(blob, appendString(appendString(nil, []byte(keyType)), sig))
+
+	enc := base64.StdEncoding.EncodeToString(blob)
+	var b strings.Builder
+	b.WriteString(armorBegin + "\n")
+	for len(enc) > armorColumn {
+		b.WriteString(enc[:armorColumn] + "\n")
+		enc = enc[armorColumn:]
+	}
+	b.WriteString(enc + "\n" + armorEnd + "\n")
+	return []byte(b.String())
+}
+
+// Verify checks the armored signature of data in namespace and returns the
+// key that made it, which must be one of allowed.
+func Verify(allowed []ed25519.PublicKey, namespace string, data, armored []byte) (ed25519.PublicKey, error) {
+	s := strings.TrimSpace(string(armored))
+	body, begin := strings.CutPrefix(s, armorBegin)
+	body, end := strings.CutSuffix(body, armorEnd)
+	if !begin || !end {
+		return nil, errors.New("sshsig: not an armored SSH signature")
+	}
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("sshsig: %w", err)
+	}
+	r := reader(blob)
+	if string(r.bytes(len(magic))) != magic || r.uint32() != 1 {
+		return nil, errors.New("sshsig: unsupported signature version")
+	}
+	pubBlob, ns, _, alg, sigBlob := r.string(), r.string(), r.string(), r.string(), r.string()
+	if r.err != nil {
+		return nil, errors.New("sshsig: truncated signature")
+	}
+	if string(ns) != namespace {
+		return nil, fmt.Errorf("sshsig: signature is for namespace %q, not %q", ns, namespace)
+	}
+	if string(alg) != hashAlg {
+		return nil, fmt.Errorf("sshsig: unsupported hash %q", alg)
+	}
+	pub, err := parsePublicBlob(pubBlob)
+	if err != nil {
+		return nil, err
+	}
+	sr := reader(sigBlob)
+	typ, sig := sr.string(), sr.string()
+	if sr.err != nil || string(typ) != keyType {
+		return nil, fmt.Errorf("sshsig: unsupported signature type %q", typ)
+	}
+	known := false
+	for _, k := range allowed {
+		known = known || subtle.ConstantTimeCompare(k, pub) == 1
+	}
+	if !known {
+		return nil, fmt.Errorf("sshsig: signed by %s, which is not an allowed key", Fingerprint(pub))
+	}
+	if !ed25519.Verify(pub, signedData(namespace, data), sig) {
+		return nil, errors.New("sshsig: signature does not match; the file was altered or signed with another key")
+	}
+	return pub, nil
+}
+
+// Fingerprint formats pub like ssh-keygen -l: SHA256: and the unpadded
+// base64 of the SHA-256 of its wire encoding.
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicBlob(pub))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// signedData is the message an SSH signature covers.
+func signedData(namespace string, data []byte) []byte {
+	h := sha512.Sum512(data)
+	var b []byte
+	b = append(b, magic...)
+	b = appendString(b, []byte(namespace))
+	b = appendString(b, nil)
+	b = appendString(b, []byte(hashAlg))
+	return appendString(b, h[:])
+}
+
+func publicBlob(pub ed25519.PublicKey) []byte {
+	return appendString(appendString(nil, []byte(keyType)), pub)
+}
+
+func parsePublicBlob(blob []byte) (ed25519.PublicKey, error) {
+	r := reader(blob)
+	typ, key := r.string(), r.string()
+	if r.err != nil || string(typ) != keyType || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("sshsig: unsupported key type %q; only %s keys are supported", typ, keyType)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// LoadPrivateKey reads an Ed25519 private key from an unencrypted OpenSSH
+// key file (ssh-keygen -t ed25519 -N "") or a PKCS #8 PEM file (openssl
+// genpkey -algorithm ed25519).
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM key", path)
+	}
+	var key ed25519.PrivateKey
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		key, err = parseOpenSSH(block.Bytes)
+	case "PRIVATE KEY":
+		var k any
+		if k, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			var ok bool
+			if key, ok = k.(ed25519.PrivateKey); !ok {
+				err = errors.New("not an Ed25519 key")
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// parseOpenSSH decodes the openssh-key-v1 format.
+func parseOpenSSH(data []byte) (ed25519.PrivateKey, error) {
+	const header = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(data, []byte(header)) {
+		return nil, errors.New("not an OpenSSH key")
+	}
+	r := reader(data[len(header):])
+	cipher, kdf, _ := r.string(), r.string(), r.string()
+	n := r.uint32()
+	r.string() // public key, repeated in the private section
+	priv := r.string()
+	if r.err != nil {
+		return nil, errors.New("truncated OpenSSH key")
+	}
+	if string(cipher) != "none" || string(kdf) != "none" {
+		return nil, errors.New("encrypted keys are not supported; use a key without a passphrase")
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("%d keys in one file", n)
+	}
+	pr := reader(priv)
+	if pr.uint32() != pr.uint32() {
+		return nil, errors.New("corrupt OpenSSH key")
+	}
+	typ, _, seed := pr.string(), pr.string(), pr.string()
+	if pr.err != nil || string(typ) != keyType || len(seed) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("unsupported key type %q; only %s keys are supported", typ, keyType)
+	}
+	return ed25519.PrivateKey(seed), nil
+}
+
+// LoadPublicKeys reads Ed25519 public keys in authorized_keys form, one
+// per line, as in a .pub file or an allowed signers list without
+// principals. Blank lines, comments and other key types are skipped.
+func LoadPublicKeys(path string) ([]ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []ed25519.PublicKey
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		for i := 0; i+1 < len(f); i++ {
+			if f[i] != keyType {
+				continue
+			}
+			blob, err := base64.StdEncoding.DecodeString(f[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			k, err := parsePublicBlob(blob)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			keys = append(keys, k)
+			break
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no %s public keys", path, keyType)
+	}
+	return keys, nil
+}
+
+func appendString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// wire reads SSH wire encoding; the first short read sets err.
+type wire struct {
+	b   []byte
+	err error
+}
+
+func reader(b []byte) *wire { return &wire{b: b} }
+
+func (w *wire) bytes(n int) []byte {
+	if w.err != nil || n < 0 || n > len(w.b) {
+		w.err = errors.New("short read")
+		return nil
+	}
+	v := w.b[:n]
+	w.b = w.b[n:]
+	return v
+}
+
+func (w *wire) uint32() uint32 {
+	b := w.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (w *wire) string() []byte {
+	n := w.uint32()
+	if n > uint32(len(w.b)) {
+		w.err = errors.New("short read")
+		return nil
+	}
+	return w.bytes(int(n))
+}
@@ -0,0 +1,147 @@
+// Package takeout reads the JSON sidecars Google Photos Takeout writes
+// next to exported images and turns them back into EXIF edits.
+package takeout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Sidecar is the metadata of one image.
+type Sidecar struct {
+	Title       string
+	Description string
+	// Taken is the capture time, when recorded.
+	Taken                         *time.Time
+	Latitude, Longitude, Altitude *float64
+}
+
+// raw mirrors the parts of the Takeout format that are used.
+type raw struct {
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	PhotoTakenTime timestamp `json:"photoTakenTime"`
+	GeoData        geoData   `json:"geoData"`
+	GeoDataExif    geoData   `json:"geoDataExif"`
+}
+
+type timestamp struct {
+	Timestamp string `json:"timestamp"`
+}
+
+type geoData struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+}
+
+// Load reads a sidecar file.
+func Load(path string) (*Sidecar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("takeout: %w", err)
+	}
+	sc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// Parse parses a sidecar. Takeout writes 0,0 for images without a
+// location; those are treated as unset.
+func Parse(data []byte) (*Sidecar, error) {
+	var r raw
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("takeout: %w", err)
+	}
+	sc := &Sidecar{Title: r.Title, Description: strings.TrimSpace(r.Description)}
+	if r.PhotoTakenTime.Timestamp != "" {
+		sec, err := strconv.ParseInt(r.PhotoTakenTime.Timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("takeout: photoTakenTime: invalid timestamp %q", r.PhotoTakenTime.Timestamp)
+		}
+		t := time.Unix(sec, 0).UTC()
+		sc.Taken = &t
+	}
+	// geoData holds edits made in Google Photos; geoDataExif what the
+	// camera recorded.
+	for _, g := range []geoData{r.GeoData, r.GeoDataExif} {
+		if g.Latitude != 0 || g.Longitude != 0 {
+			sc.Latitude, sc.Longitude = &g.Latitude, &g.Longitude
+			if g.Altitude != 0 {
+				sc.Altitude = &g.Altitude
+			}
+			break
+		}
+	}
+	return sc, nil
+}
+
+// duplicate matches the "(1)" Takeout appends to repeated file names.
+var duplicate = regexp.MustCompile(`^(.*)\((\d+)\)(\.[^.]*)$`)
+
+// Find returns the sidecar of an image, trying the names Takeout uses:
+// image.jpg.json, image.jpg.supplemental-metadata.json (truncated when the
+// name gets long), image.jpg(1).json for image(1).jpg, and the original's
+// sidecar for image-edited.jpg.
+func Find(image string) (string, bool) {
+	dir, base := filepath.Split(image)
+	names := []string{base}
+	if m := duplicate.FindStringSubmatch(base); m != nil {
+		names = append(names, m[1]+m[3]+"("+m[2]+")")
+	}
+	ext := filepath.Ext(base)
+	if stem := strings.TrimSuffix(base, ext); strings.HasSuffix(stem, "-edited") {
+		names = append(names, strings.TrimSuffix(stem, "-edited")+ext)
+	}
+	for _, n := range names {
+		for _, c := range []string{n + ".json", n + ".supplemental-metadata.json"} {
+			if _, err := os.Stat(filepath.Join(dir, c)); err == nil {
+				return filepath.Join(dir, c), true
+			}
+		}
+	}
+	// Names beyond 51 characters are cut short, e.g.
+	// image.jpg.supplemental-metad.json.
+	matches, _ := filepath.Glob(filepath.Join(dir, globEscape(base)+".su*.json"))
+	if len(matches) > 0 {
+		return matches[0], true
+	}
+	return "", false
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`, `\`, `\\`).Replace(s)
+}
+
+// Edits returns the EXIF edits restoring the sidecar's capture time,
+// location and description onto an image summarized as s, and the
+// changes they make. Values the image already has are kept unless
+// overwrite is set. The capture time is written in loc.
+func (sc *Sidecar) Edits(s *exif.Summary, loc *time.Location, overwrite bool) ([]exif.Edit, []string) {
+	var edits []exif.Edit
+	var changes []string
+	if sc.Taken != nil && (s.DateTimeOriginal == "" || overwrite) {
+		t := sc.Taken.In(loc)
+		edits = append(edits, exif.SetCaptureTime(t)...)
+		changes = append(changes, "datetime_original="+t.Format("2006-01-02T15:04:05-07:00"))
+	}
+	if sc.Latitude != nil && (s.Latitude == nil || overwrite) {
+		edits = append(edits, exif.SetGPS(*sc.Latitude, *sc.Longitude, sc.Altitude)...)
+		changes = append(changes, fmt.Sprintf("gps=%v,%v", *sc.Latitude, *sc.Longitude))
+	}
+	if sc.Description != "" && (s.Description == "" || overwrite) && sc.Description != s.Description {
+		edits = append(edits, exif.SetASCII(exif.TagImageDescription, sc.Description))
+		changes = append(changes, fmt.Sprintf("description=%q", sc.Description))
+	}
+	return edits, changes
+}
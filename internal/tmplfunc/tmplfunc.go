@@ -0,0 +1,280 @@
+// Package tmplfunc is the function library of the Go templates shootlog
+// runs: the --template output of the main command, HTML report templates
+// and renumber --name templates. The functions read photo metadata
+// conveniently, in the units photographers use, and take any number type
+// so that summary fields can be passed as they are.
+package tmplfunc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/exif"
+)
+
+// Func documents a template function.
+type Func struct {
+	Name  string `json:"name"`
+	Usage string `json:"usage"`
+	Text  string `json:"description"`
+	fn    any
+}
+
+// Library lists the functions, in the order shootlog template-funcs
+// prints them.
+var Library = []Func{
+	{"formatExposure", "formatExposure seconds", `shutter time as photographers write it: "1/250" below a quarter second, else seconds such as "2.5"`, formatExposure},
+	{"fnumber", "fnumber n", `aperture as "f/2.8"`, fnumber},
+	{"ev", "ev fnumber seconds", "exposure value of an aperture and shutter time, log2(N²/t)", ev},
+	{"ev100", "ev100 fnumber seconds iso", "exposure value normalized to ISO 100, the scene brightness metered", ev100},
+	{"fracToFloat", "fracToFloat text", `number of a fraction such as "1/250" or a decimal such as "2.8"`, fracToFloat},
+	{"round", "round x digits", "x rounded to digits decimal places", round},
+	{"captureTime", "captureTime summary", "capture time of a photo, in the zone it was taken in when known; the zero time when it has none", captureTime},
+	{"tz", "tz zone time", `time in another zone: an IANA name such as "Asia/Tokyo", "UTC", "Local" or an offset such as "+09:00"; time is a time or an RFC 3339 text`, tz},
+	{"formatTime", "formatTime layout time", `time in a Go layout such as "2006-01-02 15:04"; "" for the zero time`, formatTime},
+	{"pluralize", "pluralize n singular [plural]", `singular when n is 1, else plural, by default the English plural of singular: "photo" → "photos", "lens" → "lenses", "body" → "bodies"`, pluralize},
+	{"field", "field summary name", `summary field by its JSON name, such as "lens_model"; nothing when unset`, field},
+	{"join", "join list sep", "elements of a list joined with sep", join},
+	{"default", "default fallback value", "value, or fallback when value is empty or zero", fallback},
+	{"upper", "upper text", "text in upper case", strings.ToUpper},
+	{"lower", "lower text", "text in lower case", strings.ToLower},
+	{"trim", "trim text", "text without leading and trailing white space", strings.TrimSpace},
+}
+
+// Funcs returns the functions for template.Funcs, of text/template or
+// html/template.
+func Funcs() map[string]any {
+	m := make(map[string]any, len(Library))
+	for _, f := range Library {
+		m[f.Name] = f.fn
+	}
+	return m
+}
+
+// Parse parses a text template with the functions of the library.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs()).Parse(text)
+}
+
+// number converts the numeric types fields and template literals have.
+func number(x any) (float64, error) {
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0, nil
+		}
+		return number(v.Elem().Interface())
+	case reflect.String:
+		return fracToFloat(v.String())
+	}
+	return 0, fmt.Errorf("%T is not a number", x)
+}
+
+func formatExposure(sec any) (string, error) {
+	t, err := number(sec)
+	return exif.FormatExposure(t), err
+}
+
+func fnumber(n any) (string, error) {
+	f, err := number(n)
+	if err != nil || f <= 0 {
+		return "", err
+	}
+	return "f/" + strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+func ev(fnumber, seconds any) (float64, error) {
+	n, err := number(fnumber)
+	if err != nil {
+		return 0, err
+	}
+	t, err := number(seconds)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 || t <= 0 {
+		return 0, errors.New("ev: aperture and shutter time must be positive")
+	}
+	return math.Log2(n * n / t), nil
+}
+
+func ev100(fnumber, seconds, iso any) (float64, error) {
+	e, err := ev(fnumber, seconds)
+	if err != nil {
+		return 0, err
+	}
+	s, err := number(iso)
+	if err != nil {
+		return 0, err
+	}
+	if s <= 0 {
+		return 0, errors.New("ev100: ISO must be positive")
+	}
+	return e - math.Log2(s/100), nil
+}
+
+func fracToFloat(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if num, den, ok := strings.Cut(text, "/"); ok {
+		n, err1 := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		d, err2 := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, fmt.Errorf("fracToFloat: %q is not a fraction", text)
+		}
+		return n / d, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("fracToFloat: %q is not a number", text)
+	}
+	return f, nil
+}
+
+func round(x, digits any) (float64, error) {
+	f, err := number(x)
+	if err != nil {
+		return 0, err
+	}
+	d, err := number(digits)
+	if err != nil {
+		return 0, err
+	}
+	p := math.Pow(10, d)
+	return math.Round(f*p) / p, nil
+}
+
+// summary accepts a summary or a struct embedding one, as the data of
+// rename templates is.
+func summary(x any) (*exif.Summary, error) {
+	if s, ok := x.(*exif.Summary); ok {
+		return s, nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(x))
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("Summary"); f.IsValid() {
+			if s, ok := f.Interface().(*exif.Summary); ok && s != nil {
+				return s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%T is not a summary", x)
+}
+
+func captureTime(x any) (time.Time, error) {
+	s, err := summary(x)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, _ := s.CaptureTime()
+	return t, nil
+}
+
+// toTime accepts a time or the text of one in RFC 3339 or the EXIF
+// layouts summaries use.
+func toTime(x any) (time.Time, error) {
+	switch x := x.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006:01:02 15:04:05", time.DateOnly} {
+			if t, err := time.Parse(layout, x); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a time", x)
+	}
+	return time.Time{}, fmt.Errorf("%T is not a time", x)
+}
+
+func tz(zone string, t any) (time.Time, error) {
+	tm, err := toTime(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if zone != "" && (zone[0] == '+' || zone[0] == '-') {
+		off, err := time.Parse("-07:00", zone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tz: %q is not an offset such as +09:00", zone)
+		}
+		_, secs := off.Zone()
+		return tm.In(time.FixedZone(zone, secs)), nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tz: %w", err)
+	}
+	return tm.In(loc), nil
+}
+
+func formatTime(layout string, t any) (string, error) {
+	tm, err := toTime(t)
+	if err != nil || tm.IsZero() {
+		return "", err
+	}
+	return tm.Format(layout), nil
+}
+
+func pluralize(n any, singular string, plural ...string) (string, error) {
+	f, err := number(n)
+	if err != nil {
+		return "", err
+	}
+	if f == 1 {
+		return singular, nil
+	}
+	if len(plural) > 0 {
+		return plural[0], nil
+	}
+	lower := strings.ToLower(singular)
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return singular + "es", nil
+	case len(lower) > 1 && strings.HasSuffix(lower, "y") && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return singular[:len(singular)-1] + "ies", nil
+	}
+	return singular + "s", nil
+}
+
+func field(x any, name string) (any, error) {
+	s, err := summary(x)
+	if err != nil {
+		return nil, err
+	}
+	return s.Fields()[name], nil
+}
+
+func join(list any, sep string) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %T is not a list", list)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func fallback(def, value any) any {
+	if value == nil {
+		return def
+	}
+	if v := reflect.ValueOf(value); v.IsZero() || (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,235 @@
+// Package trash keeps the originals of files shootlog replaces, in the
+// trash directory layout of the freedesktop.org specification: the
+// contents under files/ and, for each, a .trashinfo file under info/
+// recording where it came from and when. A directory of this layout can
+// be a quarantine of its own, or the desktop trash of Linux and BSD
+// systems, whose file managers then list and restore the originals too.
+package trash
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OS selects the user's desktop trash instead of a directory.
+const OS = "os"
+
+// dateLayout is the DeletionDate format, in local time.
+const dateLayout = "2006-01-02T15:04:05"
+
+// Item is a file in the trash.
+type Item struct {
+	// Name is the file's name under files/.
+	Name string `json:"name"`
+	// Path is the absolute path the file was at.
+	Path    string    `json:"path"`
+	Deleted time.Time `json:"deleted"`
+	// written orders items deleted within the same second, which
+	// DeletionDate does not tell apart.
+	written time.Time
+}
+
+// Trash is a trash directory.
+type Trash struct {
+	dir string
+}
+
+// Open returns the trash at dir, or the desktop trash for OS: the home
+// trash of $XDG_DATA_HOME, by default ~/.local/share/Trash. macOS and
+// Windows keep their trash in formats of their own, so they need a
+// directory.
+func Open(dir string) (*Trash, error) {
+	if dir != OS {
+		return &Trash{dir: dir}, nil
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("trash: the %s trash is not supported; set a directory instead", runtime.GOOS)
+	}
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("trash: %w", err)
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	return &Trash{dir: filepath.Join(data, "Trash")}, nil
+}
+
+// Dir returns the directory of the trash.
+func (t *Trash) Dir() string { return t.dir }
+
+// Put keeps data, the contents of the file at path, in the trash. The
+// info file is created first and exclusively, as the specification asks,
+// so that concurrent writers never share a name.
+func (t *Trash) Put(path string, data []byte) (Item, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Item{}, fmt.Errorf("trash: %w", err)
+	}
+	for _, d := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(t.dir, d), 0o700); err != nil {
+			return Item{}, fmt.Errorf("trash: %w", err)
+		}
+	}
+	now := time.Now()
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", escape(abs), now.Format(dateLayout))
+	base, ext := filepath.Base(abs), filepath.Ext(abs)
+	stem := strings.TrimSuffix(base, ext)
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, n, ext)
+		}
+		f, err := os.OpenFile(filepath.Join(t.dir, "info", name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return Item{}, fmt.Errorf("trash: %w", err)
+		}
+		_, err = f.WriteString(info)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(t.dir, "files", name), data, 0o600)
+		}
+		if err != nil {
+			os.Remove(filepath.Join(t.dir, "info", name+".trashinfo"))
+			return Item{}, fmt.Errorf("trash: %w", err)
+		}
+		return Item{Name: name, Path: abs, Deleted: now.Truncate(time.Second)}, nil
+	}
+}
+
+// List returns the items of the trash, the most recently deleted first.
+// Info files that cannot be read are skipped.
+func (t *Trash) List() ([]Item, error) {
+	entries, err := os.ReadDir(filepath.Join(t.dir, "info"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trash: %w", err)
+	}
+	var items []Item
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".trashinfo")
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(t.dir, "info", e.Name()))
+		if err != nil {
+			continue
+		}
+		if it, ok := parseInfo(name, data); ok {
+			if fi, err := e.Info(); err == nil {
+				it.written = fi.ModTime()
+			}
+			items = append(items, it)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].Deleted.Equal(items[j].Deleted) {
+			return items[i].Deleted.After(items[j].Deleted)
+		}
+		return items[i].written.After(items[j].written)
+	})
+	return items, nil
+}
+
+// Latest returns the most recently deleted item that was at path.
+func (t *Trash) Latest(path string) (Item, bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Item{}, false, fmt.Errorf("trash: %w", err)
+	}
+	items, err := t.List()
+	if err != nil {
+		return Item{}, false, err
+	}
+	for _, it := range items {
+		if it.Path == abs {
+			return it, true, nil
+		}
+	}
+	return Item{}, false, nil
+}
+
+// Read returns the contents of it.
+func (t *Trash) Read(it Item) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(t.dir, "files", it.Name))
+	if err != nil {
+		return nil, fmt.Errorf("trash: %w", err)
+	}
+	return data, nil
+}
+
+// Remove deletes it from the trash.
+func (t *Trash) Remove(it Item) error {
+	if err := os.Remove(filepath.Join(t.dir, "files", it.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("trash: %w", err)
+	}
+	if err := os.Remove(filepath.Join(t.dir, "info", it.Name+".trashinfo")); err != nil {
+		return fmt.Errorf("trash: %w", err)
+	}
+	return nil
+}
+
+// parseInfo reads a .trashinfo file. Paths relative to the trash's
+// volume, which only trashes on other volumes use, are left out.
+func parseInfo(name string, data []byte) (Item, bool) {
+	it := Item{Name: name}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	header := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			header = line == "[Trash Info]"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !header || !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			p, err := url.PathUnescape(value)
+			if err != nil || !filepath.IsAbs(p) {
+				return Item{}, false
+			}
+			it.Path = p
+		case "DeletionDate":
+			if d, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+				it.Deleted = d
+			}
+		}
+	}
+	return it, it.Path != ""
+}
+
+// escape URL-escapes a path for the Path key, keeping the separators.
+func escape(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+// Package watch reports files that appear or change in a directory tree.
+// It polls instead of relying on OS notification APIs, so it behaves the
+// same on every platform and on network shares and card readers.
+package watch
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watcher polls the files returned by List.
+type Watcher struct {
+	// List returns the files to watch. It is called once per poll.
+	List func() ([]string, error)
+	// Interval is the time between polls.
+	Interval time.Duration
+	// Existing reports the files present at the first poll instead of
+	// treating them as already seen.
+	Existing bool
+}
+
+// state is what a poll observed about a file.
+type state struct {
+	size    int64
+	modTime time.Time
+}
+
+// Run polls until ctx is done, calling fn for each new or modified file.
+// A file is reported once its size and modification time are unchanged
+// across two polls, so files still being copied are not read half
+// written.
+func (w *Watcher) Run(ctx context.Context, fn func(path string)) error {
+	seen := map[string]state{}
+	pending := map[string]state{}
+	first := true
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		paths, err := w.List()
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			fi, err := os.Stat(p)
+			if err != nil {
+				// Removed between listing and stat.
+				continue
+			}
+			cur := state{size: fi.Size(), modTime: fi.ModTime()}
+			switch {
+			case first && !w.Existing:
+				seen[p] = cur
+			case seen[p] == cur:
+			case pending[p] == cur:
+				delete(pending, p)
+				seen[p] = cur
+				fn(p)
+			default:
+				pending[p] = cur
+			}
+		}
+		first = false
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package weather
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryoh827/shootlog/internal/geo"
+)
+
+// Observations farther than these from a photo are not used for it.
+const (
+	MaxGap      = 3 * time.Hour
+	MaxDistance = 50000 // meters
+)
+
+// History is a file-backed provider: observations read from a CSV file
+// with a header row naming the columns
+//
+//	time,latitude,longitude,temperature,conditions
+//
+// time is RFC 3339 (or "2006-01-02 15:04" in UTC) and temperature is in
+// degrees Celsius. latitude and longitude may be left out, for a single
+// station's log that applies everywhere.
+type History struct {
+	records []record
+}
+
+type record struct {
+	c        Conditions
+	lat, lon float64
+	located  bool
+}
+
+// columns lists the accepted CSV columns.
+var columns = []string{"time", "latitude", "longitude", "temperature", "conditions"}
+
+// LoadCSV reads a history file.
+func LoadCSV(path string) (*History, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("weather: %w", err)
+	}
+	defer f.Close()
+	h, err := ParseCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return h, nil
+}
+
+// ParseCSV parses a history with a header row naming the columns in
+// columns.
+func ParseCSV(r io.Reader) (*History, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("weather: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("weather: empty history")
+	}
+	header := records[0]
+	has := map[string]bool{}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+		if !validColumn(header[i]) {
+			return nil, fmt.Errorf("weather: unknown column %q", h)
+		}
+		has[header[i]] = true
+	}
+	if !has["time"] {
+		return nil, errors.New("weather: missing time column")
+	}
+	if has["latitude"] != has["longitude"] {
+		return nil, errors.New("weather: latitude and longitude columns go together")
+	}
+	h := &History{}
+	for n, rec := range records[1:] {
+		row := map[string]string{}
+		for i, v := range rec {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		r, err := parseRecord(row)
+		if err != nil {
+			return nil, fmt.Errorf("weather: row %d: %w", n+2, err)
+		}
+		h.records = append(h.records, r)
+	}
+	return h, nil
+}
+
+func validColumn(c string) bool {
+	for _, k := range columns {
+		if c == k {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRecord(row map[string]string) (record, error) {
+	var r record
+	t, err := time.Parse(time.RFC3339, row["time"])
+	if err != nil {
+		if t, err = time.Parse("2006-01-02 15:04", row["time"]); err != nil {
+			return r, fmt.Errorf("time: invalid value %q", row["time"])
+		}
+	}
+	r.c.Time = t
+	r.c.Conditions = row["conditions"]
+	if v := row["temperature"]; v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return r, fmt.Errorf("temperature: invalid value %q", v)
+		}
+		r.c.Temperature = &f
+	}
+	if row["latitude"] != "" || row["longitude"] != "" {
+		if r.lat, err = strconv.ParseFloat(row["latitude"], 64); err != nil {
+			return r, fmt.Errorf("latitude: invalid value %q", row["latitude"])
+		}
+		if r.lon, err = strconv.ParseFloat(row["longitude"], 64); err != nil {
+			return r, fmt.Errorf("longitude: invalid value %q", row["longitude"])
+		}
+		r.located = true
+	}
+	return r, nil
+}
+
+// Lookup returns the observation closest in time among those within
+// MaxDistance of the place and MaxGap of t.
+func (h *History) Lookup(_ context.Context, t time.Time, lat, lon float64) (*Conditions, error) {
+	var best *Conditions
+	bestGap := time.Duration(math.MaxInt64)
+	for i := range h.records {
+		r := &h.records[i]
+		if r.located && geo.Distance(lat, lon, r.lat, r.lon) > MaxDistance {
+			continue
+		}
+		gap := r.c.Time.Sub(t).Abs()
+		if gap <= MaxGap && gap < bestGap {
+			best, bestGap = &r.c, gap
+		}
+	}
+	if best == nil {
+		return nil, ErrNoData
+	}
+	c := *best
+	return &c, nil
+}
@@ -0,0 +1,90 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// client bounds every request so a stalled service cannot hang a report.
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// HTTPProvider asks a web service for the conditions. The URL template's
+// {time} (RFC 3339, UTC), {latitude} and {longitude} placeholders are
+// filled in, and the service answers with a Conditions JSON object, e.g.
+//
+//	{"temperature": 18.5, "conditions": "clear"}
+//
+// 404 Not Found means no data.
+type HTTPProvider struct {
+	template string
+}
+
+// NewHTTPProvider checks that template is an absolute URL naming the time
+// and place.
+func NewHTTPProvider(template string) (*HTTPProvider, error) {
+	u, err := url.Parse(strings.NewReplacer("{", "", "}", "").Replace(template))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("weather: invalid URL %q", template)
+	}
+	for _, p := range []string{"{time}", "{latitude}", "{longitude}"} {
+		if !strings.Contains(template, p) {
+			return nil, fmt.Errorf("weather: URL template lacks %s", p)
+		}
+	}
+	return &HTTPProvider{template: template}, nil
+}
+
+// Lookup fetches the conditions at t and lat, lon.
+func (p *HTTPProvider) Lookup(ctx context.Context, t time.Time, lat, lon float64) (*Conditions, error) {
+	endpoint := strings.NewReplacer(
+		"{time}", url.QueryEscape(t.UTC().Format(time.RFC3339)),
+		"{latitude}", strconv.FormatFloat(lat, 'f', -1, 64),
+		"{longitude}", strconv.FormatFloat(lon, 'f', -1, 64),
+	).Replace(p.template)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("weather: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return nil, fmt.Errorf("weather: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNoData
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("weather: GET %s: %s", redact(endpoint), resp.Status)
+	}
+	var c Conditions
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, fmt.Errorf("weather: %s: %w", redact(endpoint), err)
+	}
+	if c.Time.IsZero() {
+		c.Time = t
+	}
+	return &c, nil
+}
+
+// redact hides credentials, including API keys in the query, in URLs
+// printed in errors.
+func redact(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	u.RawQuery = ""
+	return u.Redacted()
+}
@@ -0,0 +1,38 @@
+// Package weather looks up the weather at the time and place a photo was
+// taken, from a user-supplied history file or an HTTP service.
+package weather
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNoData is returned when a provider has no observation close enough
+// to the requested time and place.
+var ErrNoData = errors.New("weather: no observation")
+
+// Conditions is one observation.
+type Conditions struct {
+	Time time.Time `json:"time"`
+	// Temperature is in degrees Celsius.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// Conditions is a short description such as "clear" or "rain".
+	Conditions string `json:"conditions,omitempty"`
+}
+
+// Provider returns the conditions at a time and place.
+type Provider interface {
+	Lookup(ctx context.Context, t time.Time, lat, lon float64) (*Conditions, error)
+}
+
+// Open returns the provider for source: an http:// or https:// URL
+// template for HTTPProvider, otherwise the path of a CSV history for
+// LoadCSV.
+func Open(source string) (Provider, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return NewHTTPProvider(source)
+	}
+	return LoadCSV(source)
+}
@@ -0,0 +1,336 @@
+// Package arrow writes the Apache Arrow IPC stream format, as needed to
+// hand photo summaries to analytics tools as typed columns without a JSON
+// parser in between. It writes only: a schema of nullable columns of
+// booleans, 64-bit integers, doubles, UTF-8 text and lists of them,
+// followed by uncompressed record batches. There is no reader.
+package arrow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+// Column types. String columns hold UTF-8 text.
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+)
+
+// Type union members, message header types and versions of the Arrow
+// format.
+const (
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeUtf8          = 5
+	typeBool          = 6
+	typeList          = 12
+
+	precisionDouble = 2
+
+	headerSchema      = 1
+	headerRecordBatch = 3
+
+	metadataV5 = 4
+)
+
+var unionTypes = map[Type]uint8{Boolean: typeBool, Int64: typeInt, Double: typeFloatingPoint, String: typeUtf8}
+
+// continuation opens each encapsulated message.
+const continuation = 0xFFFFFFFF
+
+// BatchSize is the number of rows a Writer buffers before writing them
+// out as a record batch.
+const BatchSize = 10000
+
+var errClosed = errors.New("arrow: writer is closed")
+
+// Column is a column of a stream. Every column is nullable.
+type Column struct {
+	Name string
+	Type Type
+	// Repeated columns hold a list of values per row.
+	Repeated bool
+}
+
+// Writer writes rows to an Arrow IPC stream.
+type Writer struct {
+	w      io.Writer
+	cols   []Column
+	arrays []*array
+	rows   int
+	schema bool
+	err    error
+}
+
+// NewWriter returns a writer of a stream with the given columns to w.
+func NewWriter(w io.Writer, cols []Column) *Writer {
+	wr := &Writer{w: w, cols: cols}
+	wr.reset()
+	return wr
+}
+
+func (w *Writer) reset() {
+	w.arrays = make([]*array, len(w.cols))
+	for i, col := range w.cols {
+		w.arrays[i] = newArray(col.Type)
+		if col.Repeated {
+			w.arrays[i] = &array{list: true, offsets: []int32{0}, child: newArray(col.Type)}
+		}
+	}
+	w.rows = 0
+}
+
+// Write adds a row holding a value for each column, in order. A value is
+// nil for null, or a bool, int64, float64 or string as the column's type
+// asks; a repeated column takes a []any of them. After a value of the
+// wrong type the writer fails.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.cols) {
+		return fmt.Errorf("arrow: row has %d values for %d columns", len(row), len(w.cols))
+	}
+	for i, col := range w.cols {
+		// A row added in part would misalign the columns.
+		if err := w.arrays[i].add(row[i]); err != nil {
+			w.err = fmt.Errorf("arrow: column %s: %w", col.Name, err)
+			return w.err
+		}
+	}
+	if w.rows++; w.rows == BatchSize {
+		w.flush()
+	}
+	return w.err
+}
+
+// Close writes the buffered rows and the end-of-stream marker. It does
+// not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.rows > 0 || !w.schema {
+		w.flush()
+	}
+	w.write(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, continuation), 0))
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errClosed
+	return nil
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+// flush writes the schema ahead of the first batch, then the buffered
+// rows as a record batch.
+func (w *Writer) flush() {
+	if !w.schema {
+		w.message(headerSchema, w.schemaHeader, nil)
+		w.schema = true
+	}
+	if w.rows == 0 {
+		return
+	}
+	var nodes, bufs [][2]int64
+	var body []byte
+	for _, a := range w.arrays {
+		a.emit(&nodes, func(b []byte) {
+			bufs = append(bufs, [2]int64{int64(len(body)), int64(len(b))})
+			body = append(body, b...)
+			body = append(body, make([]byte, pad8(len(body)))...)
+		})
+	}
+	rows := int64(w.rows)
+	w.message(headerRecordBatch, func(b *builder) int {
+		n := b.structs(nodes)
+		bf := b.structs(bufs)
+		b.startTable()
+		b.addInt64(0, rows)
+		b.addOffset(1, n)
+		b.addOffset(2, bf)
+		return b.endTable()
+	}, body)
+	w.reset()
+}
+
+// message writes an encapsulated message: its metadata, padded to eight
+// bytes, and body.
+func (w *Writer) message(kind uint8, header func(*builder) int, body []byte) {
+	b := &builder{}
+	h := header(b)
+	b.startTable()
+	b.addInt64(3, int64(len(body)))
+	b.addOffset(2, h)
+	b.addInt16(0, metadataV5)
+	b.addUint8(1, kind)
+	meta := b.finish(b.endTable())
+	meta = append(meta, make([]byte, pad8(8+len(meta)))...)
+	out := binary.LittleEndian.AppendUint32(nil, continuation)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(meta)))
+	w.write(append(out, meta...))
+	w.write(body)
+}
+
+func (w *Writer) schemaHeader(b *builder) int {
+	fields := make([]int, len(w.cols))
+	for i, col := range w.cols {
+		var children []int
+		if col.Repeated {
+			children = []int{field(b, "item", col.Type, false, nil)}
+		}
+		fields[i] = field(b, col.Name, col.Type, col.Repeated, children)
+	}
+	fv := b.offsets(fields)
+	b.startTable()
+	b.addOffset(1, fv)
+	b.addInt16(0, 0) // little-endian
+	return b.endTable()
+}
+
+// field writes a Field table of typ, or of a list when list is set.
+func field(b *builder, name string, typ Type, list bool, children []int) int {
+	ut := unionTypes[typ]
+	b.startTable()
+	switch {
+	case list:
+		ut = typeList
+	case typ == Int64:
+		b.addInt32(0, 64)
+		b.addBool(1, true)
+	case typ == Double:
+		b.addInt16(0, precisionDouble)
+	}
+	t := b.endTable()
+	c := b.offsets(children)
+	n := b.string(name)
+	b.startTable()
+	b.addOffset(0, n)
+	b.addOffset(3, t)
+	b.addOffset(5, c)
+	b.addBool(1, true)
+	b.addUint8(2, ut)
+	return b.endTable()
+}
+
+// array buffers the values of a column of the current batch.
+type array struct {
+	typ      Type
+	list     bool
+	n, nulls int
+	valid    []byte
+	// offsets index data for String, and child for lists.
+	offsets []int32
+	data    []byte
+	child   *array
+}
+
+func newArray(typ Type) *array {
+	a := &array{typ: typ}
+	if typ == String {
+		a.offsets = []int32{0}
+	}
+	return a
+}
+
+func setBit(bm []byte, i int, v bool) []byte {
+	if i%8 == 0 {
+		bm = append(bm, 0)
+	}
+	if v {
+		bm[i/8] |= 1 << (i % 8)
+	}
+	return bm
+}
+
+func (a *array) add(v any) error {
+	a.valid = setBit(a.valid, a.n, v != nil)
+	if v == nil {
+		a.nulls++
+	}
+	i := a.n
+	a.n++
+	if a.list {
+		if v != nil {
+			l, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("%T is not a list", v)
+			}
+			for _, e := range l {
+				if e == nil {
+					return errors.New("null list element")
+				}
+				if err := a.child.add(e); err != nil {
+					return err
+				}
+			}
+		}
+		a.offsets = append(a.offsets, int32(a.child.n))
+		return nil
+	}
+	// Null slots hold zero values.
+	ok := v == nil
+	switch a.typ {
+	case Boolean:
+		b, isBool := v.(bool)
+		ok = ok || isBool
+		a.data = setBit(a.data, i, b)
+	case Int64:
+		n, isInt := v.(int64)
+		ok = ok || isInt
+		a.data = binary.LittleEndian.AppendUint64(a.data, uint64(n))
+	case Double:
+		f, isFloat := v.(float64)
+		ok = ok || isFloat
+		a.data = binary.LittleEndian.AppendUint64(a.data, math.Float64bits(f))
+	case String:
+		str, isString := v.(string)
+		ok = ok || isString
+		a.data = append(a.data, str...)
+		a.offsets = append(a.offsets, int32(len(a.data)))
+	}
+	if !ok {
+		return fmt.Errorf("%T value", v)
+	}
+	return nil
+}
+
+// emit records the field node of a and hands out its buffers, then those
+// of its child, in the order of the format.
+func (a *array) emit(nodes *[][2]int64, buf func([]byte)) {
+	*nodes = append(*nodes, [2]int64{int64(a.n), int64(a.nulls)})
+	buf(a.valid)
+	offsets := func() {
+		b := make([]byte, 0, 4*len(a.offsets))
+		for _, o := range a.offsets {
+			b = binary.LittleEndian.AppendUint32(b, uint32(o))
+		}
+		buf(b)
+	}
+	if a.list {
+		offsets()
+		a.child.emit(nodes, buf)
+		return
+	}
+	if a.typ == String {
+		offsets()
+	}
+	buf(a.data)
+}
+
+func pad8(n int) int {
+	return (8 - n%8) % 8
+}
@@ -0,0 +1,432 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// table reads a FlatBuffer table, independently of the builder.
+type table struct {
+	buf []byte
+	pos int
+}
+
+var le = binary.LittleEndian
+
+func root(buf []byte) table {
+	return table{buf, int(le.Uint32(buf))}
+}
+
+// field returns the position of the slot's value, or 0 when it is absent.
+func (t table) field(slot int) int {
+	vt := t.pos - int(int32(le.Uint32(t.buf[t.pos:])))
+	if 4+2*slot >= int(le.Uint16(t.buf[vt:])) {
+		return 0
+	}
+	if off := int(le.Uint16(t.buf[vt+4+2*slot:])); off != 0 {
+		return t.pos + off
+	}
+	return 0
+}
+
+func (t table) int(slot, size int) int64 {
+	p := t.field(slot)
+	if p == 0 {
+		return 0
+	}
+	switch size {
+	case 1:
+		return int64(t.buf[p])
+	case 2:
+		return int64(int16(le.Uint16(t.buf[p:])))
+	case 4:
+		return int64(int32(le.Uint32(t.buf[p:])))
+	}
+	return int64(le.Uint64(t.buf[p:]))
+}
+
+// ref follows the reference in a slot.
+func (t table) ref(slot int) int {
+	p := t.field(slot)
+	if p == 0 {
+		return 0
+	}
+	return p + int(le.Uint32(t.buf[p:]))
+}
+
+func (t table) table(slot int) table { return table{t.buf, t.ref(slot)} }
+
+func (t table) string(slot int) string {
+	p := t.ref(slot)
+	if p == 0 {
+		return ""
+	}
+	n := int(le.Uint32(t.buf[p:]))
+	if t.buf[p+4+n] != 0 {
+		panic("string without a terminator")
+	}
+	return string(t.buf[p+4 : p+4+n])
+}
+
+// tables returns the tables of a vector of them.
+func (t table) tables(slot int) []table {
+	p := t.ref(slot)
+	if p == 0 {
+		return nil
+	}
+	var out []table
+	for i := 0; i < int(le.Uint32(t.buf[p:])); i++ {
+		e := p + 4 + 4*i
+		out = append(out, table{t.buf, e + int(le.Uint32(t.buf[e:]))})
+	}
+	return out
+}
+
+// pairs returns a vector of structs of two int64 and its position.
+func (t table) pairs(slot int) ([][2]int64, int) {
+	p := t.ref(slot)
+	var out [][2]int64
+	for i := 0; i < int(le.Uint32(t.buf[p:])); i++ {
+		e := p + 4 + 16*i
+		out = append(out, [2]int64{int64(le.Uint64(t.buf[e:])), int64(le.Uint64(t.buf[e+8:]))})
+	}
+	return out, p + 4
+}
+
+// message is an encapsulated message of a stream.
+type message struct {
+	meta table
+	body []byte
+}
+
+// readStream splits a stream into its messages, checking the framing:
+// continuation markers, metadata padded to eight bytes, body lengths and
+// the end-of-stream marker.
+func readStream(t *testing.T, data []byte) []message {
+	t.Helper()
+	var out []message
+	for pos := 0; ; {
+		if pos+8 > len(data) || le.Uint32(data[pos:]) != continuation {
+			t.Fatalf("no continuation marker at %d", pos)
+		}
+		n := int(le.Uint32(data[pos+4:]))
+		if n == 0 {
+			if pos+8 != len(data) {
+				t.Fatalf("%d bytes after the end of the stream", len(data)-pos-8)
+			}
+			return out
+		}
+		if (pos+8+n)%8 != 0 || pos+8+n > len(data) {
+			t.Fatalf("metadata of %d bytes at %d does not end on a multiple of eight", n, pos)
+		}
+		meta := root(data[pos+8 : pos+8+n])
+		if meta.int(0, 2) != metadataV5 {
+			t.Errorf("message at %d: version %d", pos, meta.int(0, 2))
+		}
+		body := int(meta.int(3, 8))
+		pos += 8 + n
+		if body%8 != 0 || pos+body > len(data) {
+			t.Fatalf("body of %d bytes at %d", body, pos)
+		}
+		out = append(out, message{meta, data[pos : pos+body]})
+		pos += body
+	}
+}
+
+// fieldDesc describes a schema field as read back.
+type fieldDesc struct {
+	name     string
+	nullable bool
+	typ      uint8
+	detail   [2]int64 // Int bit width and signedness, or FloatingPoint precision
+	children []fieldDesc
+}
+
+func readField(f table) fieldDesc {
+	d := fieldDesc{name: f.string(0), nullable: f.int(1, 1) == 1, typ: uint8(f.int(2, 1))}
+	switch ty := f.table(3); d.typ {
+	case typeInt:
+		d.detail = [2]int64{ty.int(0, 4), ty.int(1, 1)}
+	case typeFloatingPoint:
+		d.detail = [2]int64{ty.int(0, 2)}
+	}
+	for _, c := range f.tables(5) {
+		d.children = append(d.children, readField(c))
+	}
+	return d
+}
+
+// readBatch decodes a record batch of the columns into rows.
+func readBatch(t *testing.T, m message, cols []Column) [][]any {
+	t.Helper()
+	if m.meta.int(1, 1) != headerRecordBatch {
+		t.Fatalf("header type %d, want a record batch", m.meta.int(1, 1))
+	}
+	rb := m.meta.table(2)
+	n := int(rb.int(0, 8))
+	nodes, np := rb.pairs(1)
+	bufs, bp := rb.pairs(2)
+	if np%8 != 0 || bp%8 != 0 {
+		t.Errorf("struct vectors at %d and %d are not aligned to eight bytes", np, bp)
+	}
+	next := func() []byte {
+		b := bufs[0]
+		bufs = bufs[1:]
+		if b[0]%8 != 0 || b[0]+b[1] > int64(len(m.body)) {
+			t.Fatalf("buffer %v in a body of %d bytes", b, len(m.body))
+		}
+		return m.body[b[0] : b[0]+b[1]]
+	}
+	var column func(typ Type, list bool) []any
+	column = func(typ Type, list bool) []any {
+		node := nodes[0]
+		nodes = nodes[1:]
+		valid := next()
+		var offsets []byte
+		if list || typ == String {
+			offsets = next()
+		}
+		offset := func(i int) int { return int(le.Uint32(offsets[4*i:])) }
+		var child []any
+		var data []byte
+		if list {
+			child = column(typ, false)
+		} else {
+			data = next()
+		}
+		out := make([]any, node[0])
+		nulls := 0
+		for i := range out {
+			if valid[i/8]>>(i%8)&1 == 0 {
+				nulls++
+				continue
+			}
+			switch {
+			case list:
+				out[i] = append([]any{}, child[offset(i):offset(i+1)]...)
+			case typ == Boolean:
+				out[i] = data[i/8]>>(i%8)&1 == 1
+			case typ == Int64:
+				out[i] = int64(le.Uint64(data[8*i:]))
+			case typ == Double:
+				out[i] = math.Float64frombits(le.Uint64(data[8*i:]))
+			case typ == String:
+				out[i] = string(data[offset(i):offset(i+1)])
+			}
+		}
+		if int64(nulls) != node[1] {
+			t.Errorf("node %v has %d nulls", node, nulls)
+		}
+		return out
+	}
+	rows := make([][]any, n)
+	for i := range rows {
+		rows[i] = make([]any, len(cols))
+	}
+	for c, col := range cols {
+		values := column(col.Type, col.Repeated)
+		if len(values) != n {
+			t.Fatalf("column %s has %d values in a batch of %d", col.Name, len(values), n)
+		}
+		for i, v := range values {
+			rows[i][c] = v
+		}
+	}
+	if len(nodes) != 0 || len(bufs) != 0 {
+		t.Errorf("%d nodes and %d buffers left over", len(nodes), len(bufs))
+	}
+	return rows
+}
+
+var testColumns = []Column{
+	{Name: "path", Type: String},
+	{Name: "iso", Type: Int64},
+	{Name: "aperture", Type: Double},
+	{Name: "flash", Type: Boolean},
+	{Name: "keywords", Type: String, Repeated: true},
+	{Name: "apertures", Type: Double, Repeated: true},
+}
+
+func TestWriterSchema(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewWriter(&b, testColumns).Close(); err != nil {
+		t.Fatal(err)
+	}
+	msgs := readStream(t, b.Bytes())
+	if len(msgs) != 1 {
+		t.Fatalf("%d messages, want the schema only", len(msgs))
+	}
+	m := msgs[0]
+	if m.meta.int(1, 1) != headerSchema || len(m.body) != 0 {
+		t.Fatalf("header type %d with a body of %d bytes, want a schema", m.meta.int(1, 1), len(m.body))
+	}
+	schema := m.meta.table(2)
+	if schema.int(0, 2) != 0 {
+		t.Error("schema is not little-endian")
+	}
+	var got []fieldDesc
+	for _, f := range schema.tables(1) {
+		got = append(got, readField(f))
+	}
+	item := func(typ uint8, detail [2]int64) []fieldDesc {
+		return []fieldDesc{{name: "item", nullable: true, typ: typ, detail: detail}}
+	}
+	want := []fieldDesc{
+		{name: "path", nullable: true, typ: typeUtf8},
+		{name: "iso", nullable: true, typ: typeInt, detail: [2]int64{64, 1}},
+		{name: "aperture", nullable: true, typ: typeFloatingPoint, detail: [2]int64{precisionDouble}},
+		{name: "flash", nullable: true, typ: typeBool},
+		{name: "keywords", nullable: true, typ: typeList, children: item(typeUtf8, [2]int64{})},
+		{name: "apertures", nullable: true, typ: typeList, children: item(typeFloatingPoint, [2]int64{precisionDouble})},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	rows := [][]any{
+		{"a.jpg", int64(400), 2.8, true, []any{"sea", "sunset"}, []any{1.4, 16.0}},
+		{"b.jpg", nil, nil, false, nil, nil},
+		{"", int64(-1), math.Inf(-1), nil, []any{}, []any{}},
+		{"日本.jpg", int64(math.MinInt64), -0.5, true, []any{"一"}, nil},
+		{nil, nil, nil, nil, []any{"x", "", "z"}, []any{2.0}},
+	}
+	for i := 0; i < 20; i++ {
+		rows = append(rows, []any{fmt.Sprint(i), int64(i), float64(i) / 4, i%3 == 0, nil, []any{float64(i)}})
+	}
+	var b bytes.Buffer
+	w := NewWriter(&b, testColumns)
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msgs := readStream(t, b.Bytes())
+	if len(msgs) != 2 {
+		t.Fatalf("%d messages, want a schema and a record batch", len(msgs))
+	}
+	if got := readBatch(t, msgs[1], testColumns); !reflect.DeepEqual(got, rows) {
+		t.Errorf("rows\n%v\nwant\n%v", got, rows)
+	}
+}
+
+func TestWriterBatches(t *testing.T) {
+	cols := []Column{{Name: "n", Type: Int64}, {Name: "even", Type: Boolean}}
+	var b bytes.Buffer
+	w := NewWriter(&b, cols)
+	n := 2*BatchSize + 3
+	for i := 0; i < n; i++ {
+		var v any
+		if i%7 != 0 {
+			v = int64(i)
+		}
+		if err := w.Write([]any{v, i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msgs := readStream(t, b.Bytes())
+	if len(msgs) != 4 {
+		t.Fatalf("%d messages, want a schema and 3 batches", len(msgs))
+	}
+	i := 0
+	for bi, m := range msgs[1:] {
+		rows := readBatch(t, m, cols)
+		want := BatchSize
+		if bi == 2 {
+			want = 3
+		}
+		if len(rows) != want {
+			t.Errorf("batch %d has %d rows, want %d", bi, len(rows), want)
+		}
+		for _, row := range rows {
+			var v any
+			if i%7 != 0 {
+				v = int64(i)
+			}
+			if row[0] != v || row[1] != (i%2 == 0) {
+				t.Fatalf("row %d is %v", i, row)
+			}
+			i++
+		}
+	}
+}
+
+// failWriter fails once it has taken n bytes.
+type failWriter struct{ n int }
+
+var errDisk = errors.New("disk full")
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return w.n, errDisk
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriterErrors(t *testing.T) {
+	row := []any{"a.jpg", int64(1), 1.0, true, nil, nil}
+	tests := []struct {
+		name string
+		row  []any
+		err  string
+	}{
+		{"short row", []any{"a.jpg"}, "arrow: row has 1 values for 6 columns"},
+		{"wrong type", []any{"a.jpg", 1, nil, nil, nil, nil}, "arrow: column iso: int value"},
+		{"wrong string", []any{[]byte("a.jpg"), nil, nil, nil, nil, nil}, "arrow: column path: []uint8 value"},
+		{"wrong bool", []any{"a.jpg", nil, nil, "yes", nil, nil}, "arrow: column flash: string value"},
+		{"not a list", []any{"a.jpg", nil, nil, nil, "sea", nil}, "arrow: column keywords: string is not a list"},
+		{"wrong element", []any{"a.jpg", nil, nil, nil, []any{"a", 1.5}, nil}, "arrow: column keywords: float64 value"},
+		{"null element", []any{"a.jpg", nil, nil, nil, nil, []any{1.0, nil}}, "arrow: column apertures: null list element"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(&bytes.Buffer{}, testColumns)
+			if err := w.Write(row); err != nil {
+				t.Fatal(err)
+			}
+			err := w.Write(tt.row)
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+			if len(tt.row) == len(testColumns) {
+				// A row that failed part way fails the writer.
+				if err2 := w.Write(row); err2 != err {
+					t.Errorf("next Write: %v, want %v", err2, err)
+				}
+				if err2 := w.Close(); err2 != err {
+					t.Errorf("Close: %v, want %v", err2, err)
+				}
+			}
+		})
+	}
+
+	w := NewWriter(&bytes.Buffer{}, testColumns)
+	w.Close()
+	if err := w.Write(row); err != errClosed {
+		t.Errorf("Write after Close: %v, want %v", err, errClosed)
+	}
+	if err := w.Close(); err != errClosed {
+		t.Errorf("Close after Close: %v, want %v", err, errClosed)
+	}
+
+	for _, n := range []int{0, 3, 100, 500} {
+		w := NewWriter(&failWriter{n}, testColumns)
+		w.Write(row)
+		if err := w.Close(); !errors.Is(err, errDisk) {
+			t.Errorf("writer failing after %d bytes: Close returned %v, want %v", n, err, errDisk)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package arrow
+
+import "encoding/binary"
+
+// builder builds a FlatBuffer back to front, as the reference builders
+// do: children are written before the tables referring to them, so that
+// every offset points forward. Offsets are counted from the end of the
+// buffer until finish fixes the layout.
+type builder struct {
+	buf      []byte
+	minAlign int
+	// fields are the slots of the open table and where they were written.
+	fields    map[int]int
+	tableFrom int
+}
+
+func (b *builder) prepend(p []byte) {
+	buf := make([]byte, len(p), len(p)+len(b.buf))
+	copy(buf, p)
+	b.buf = append(buf, b.buf...)
+}
+
+func (b *builder) pad(n int) {
+	b.prepend(make([]byte, n))
+}
+
+// align pads the buffer so that size-aligned data can be prepended after
+// another extra bytes.
+func (b *builder) align(size, extra int) {
+	b.minAlign = max(b.minAlign, size)
+	b.pad((size - (len(b.buf)+extra)%size) % size)
+}
+
+func (b *builder) uint16(v uint16) { b.prepend(binary.LittleEndian.AppendUint16(nil, v)) }
+func (b *builder) uint32(v uint32) { b.prepend(binary.LittleEndian.AppendUint32(nil, v)) }
+func (b *builder) uint64(v uint64) { b.prepend(binary.LittleEndian.AppendUint64(nil, v)) }
+
+// offset prepends a reference to the object written at off.
+func (b *builder) offset(off int) {
+	b.align(4, 0)
+	b.uint32(uint32(len(b.buf) + 4 - off))
+}
+
+func (b *builder) string(s string) int {
+	b.align(4, len(s)+1)
+	b.prepend(append([]byte(s), 0))
+	b.uint32(uint32(len(s)))
+	return len(b.buf)
+}
+
+// offsets writes a vector of references to tables.
+func (b *builder) offsets(offs []int) int {
+	b.align(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.offset(offs[i])
+	}
+	b.uint32(uint32(len(offs)))
+	return len(b.buf)
+}
+
+// structs writes a vector of structs of two 64-bit fields, as Arrow's
+// FieldNode and Buffer are.
+func (b *builder) structs(pairs [][2]int64) int {
+	b.align(4, 16*len(pairs))
+	b.align(8, 16*len(pairs))
+	for i := len(pairs) - 1; i >= 0; i-- {
+		b.uint64(uint64(pairs[i][1]))
+		b.uint64(uint64(pairs[i][0]))
+	}
+	b.uint32(uint32(len(pairs)))
+	return len(b.buf)
+}
+
+func (b *builder) startTable() {
+	b.fields = map[int]int{}
+	b.tableFrom = len(b.buf)
+}
+
+func (b *builder) addBool(slot int, v bool) {
+	var x byte
+	if v {
+		x = 1
+	}
+	b.addUint8(slot, x)
+}
+
+func (b *builder) addUint8(slot int, v uint8) {
+	b.prepend([]byte{v})
+	b.fields[slot] = len(b.buf)
+}
+
+func (b *builder) addInt16(slot int, v int16) {
+	b.align(2, 0)
+	b.uint16(uint16(v))
+	b.fields[slot] = len(b.buf)
+}
+
+func (b *builder) addInt32(slot int, v int32) {
+	b.align(4, 0)
+	b.uint32(uint32(v))
+	b.fields[slot] = len(b.buf)
+}
+
+func (b *builder) addInt64(slot int, v int64) {
+	b.align(8, 0)
+	b.uint64(uint64(v))
+	b.fields[slot] = len(b.buf)
+}
+
+func (b *builder) addOffset(slot int, off int) {
+	b.offset(off)
+	b.fields[slot] = len(b.buf)
+}
+
+// endTable writes the table's offset to its vtable and the vtable
+// ahead of it.
+func (b *builder) endTable() int {
+	b.align(4, 0)
+	b.uint32(0)
+	table := len(b.buf)
+	slots := 0
+	for s := range b.fields {
+		slots = max(slots, s+1)
+	}
+	for s := slots - 1; s >= 0; s-- {
+		var at uint16
+		if f, ok := b.fields[s]; ok {
+			at = uint16(table - f)
+		}
+		b.uint16(at)
+	}
+	b.uint16(uint16(table - b.tableFrom))
+	b.uint16(uint16(4 + 2*slots))
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-table:], uint32(len(b.buf)-table))
+	b.fields = nil
+	return table
+}
+
+// finish writes the reference to the root table and returns the buffer.
+func (b *builder) finish(root int) []byte {
+	b.align(max(b.minAlign, 4), 4)
+	b.offset(root)
+	return b.buf
+}
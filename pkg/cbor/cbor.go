@@ -0,0 +1,283 @@
+// Package cbor decodes CBOR (RFC 8949) data items into Go values, as
+// needed to read C2PA claims, assertions and COSE signatures, and
+// encodes them back in the deterministic encoding. There is no mapping
+// onto structs.
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrFormat is returned for malformed or truncated data.
+var ErrFormat = errors.New("cbor: malformed data")
+
+// maxDepth bounds the nesting of arrays, maps and tags.
+const maxDepth = 64
+
+// Tag is a tagged data item, such as a COSE_Sign1 structure (tag 18).
+type Tag struct {
+	Number  uint64
+	Content any
+}
+
+// Undefined is the value of the CBOR undefined simple value.
+type Undefined struct{}
+
+// Unmarshal decodes the single data item held in data. Unsigned and
+// negative integers become int64, or uint64 for unsigned values beyond
+// its range; byte strings []byte; text strings string; arrays []any; maps
+// map[any]any; floats float64; false and true bool; null nil and tags
+// Tag. Map keys that are arrays, maps or byte strings are rejected.
+func Unmarshal(data []byte) (any, error) {
+	d := &decoder{data: data}
+	v, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: %d bytes after the data item", ErrFormat, len(d.data)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// errBreak marks the end of an indefinite-length item.
+var errBreak = errors.New("cbor: break")
+
+// item reads a data item where a break may not appear.
+func (d *decoder) item(depth int) (any, error) {
+	start := d.pos
+	v, err := d.value(depth)
+	if err == errBreak {
+		return nil, fmt.Errorf("%w: unexpected break at offset %d", ErrFormat, start)
+	}
+	return v, err
+}
+
+// head reads an initial byte and its argument. Indefinite lengths are
+// returned with indefinite set.
+func (d *decoder) head() (major byte, arg uint64, indefinite bool, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, false, fmt.Errorf("%w: truncated at offset %d", ErrFormat, d.pos)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1F
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	case info == 31:
+		return major, 0, true, nil
+	default:
+		return 0, 0, false, fmt.Errorf("%w: reserved additional information %d at offset %d", ErrFormat, info, d.pos-1)
+	}
+	if d.pos+n > len(d.data) {
+		return 0, 0, false, fmt.Errorf("%w: truncated at offset %d", ErrFormat, d.pos)
+	}
+	buf := make([]byte, 8)
+	copy(buf[8-n:], d.data[d.pos:d.pos+n])
+	d.pos += n
+	return major, binary.BigEndian.Uint64(buf), false, nil
+}
+
+// length checks that n items of at least one byte each can follow.
+func (d *decoder) length(n uint64) (int, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, fmt.Errorf("%w: length %d exceeds the data at offset %d", ErrFormat, n, d.pos)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrFormat)
+	}
+	start := d.pos
+	major, arg, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major < 2 || major == 6) {
+		return nil, fmt.Errorf("%w: indefinite length for major type %d at offset %d", ErrFormat, major, start)
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: negative integer out of range at offset %d", ErrFormat, start)
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		var b []byte
+		if indefinite {
+			// Chunks are definite-length strings of the same type.
+			for {
+				if d.pos < len(d.data) && d.data[d.pos]&0x1F == 31 && d.data[d.pos]>>5 != 7 {
+					return nil, fmt.Errorf("%w: invalid string chunk at offset %d", ErrFormat, start)
+				}
+				chunk, err := d.value(depth + 1)
+				if err == errBreak {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				switch c := chunk.(type) {
+				case []byte:
+					if major != 2 {
+						return nil, fmt.Errorf("%w: mixed string chunks at offset %d", ErrFormat, start)
+					}
+					b = append(b, c...)
+				case string:
+					if major != 3 {
+						return nil, fmt.Errorf("%w: mixed string chunks at offset %d", ErrFormat, start)
+					}
+					b = append(b, c...)
+				default:
+					return nil, fmt.Errorf("%w: invalid string chunk at offset %d", ErrFormat, start)
+				}
+			}
+		} else {
+			n, err := d.length(arg)
+			if err != nil {
+				return nil, err
+			}
+			b = append([]byte(nil), d.data[d.pos:d.pos+n]...)
+			d.pos += n
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		if b == nil {
+			b = []byte{}
+		}
+		return b, nil
+	case 4:
+		if !indefinite {
+			if _, err := d.length(arg); err != nil {
+				return nil, err
+			}
+		}
+		var out []any
+		for i := uint64(0); indefinite || i < arg; i++ {
+			next := d.item
+			if indefinite {
+				next = d.value
+			}
+			v, err := next(depth + 1)
+			if err == errBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		if out == nil {
+			out = []any{}
+		}
+		return out, nil
+	case 5:
+		if !indefinite {
+			if _, err := d.length(arg); err != nil {
+				return nil, err
+			}
+		}
+		out := map[any]any{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			next := d.item
+			if indefinite {
+				next = d.value
+			}
+			k, err := next(depth + 1)
+			if err == errBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case []any, map[any]any, []byte, Tag:
+				return nil, fmt.Errorf("%w: unsupported map key type %T at offset %d", ErrFormat, k, start)
+			}
+			v, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	case 6:
+		v, err := d.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: arg, Content: v}, nil
+	}
+	// Major type 7: simple values, floats and break.
+	if indefinite {
+		return nil, errBreak
+	}
+	switch info := d.data[start] & 0x1F; {
+	case info == 25:
+		return halfFloat(uint16(arg)), nil
+	case info == 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case info == 27:
+		return math.Float64frombits(arg), nil
+	}
+	switch arg {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22:
+		return nil, nil
+	case 23:
+		return Undefined{}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported simple value %d at offset %d", ErrFormat, arg, start)
+}
+
+// halfFloat converts an IEEE 754 half-precision value.
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1F
+	mant := float64(h & 0x3FF)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,309 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestUnmarshalVectors decodes the examples of RFC 8949, Appendix A.
+// Those marked canonical are also what Marshal writes for the value.
+func TestUnmarshalVectors(t *testing.T) {
+	tests := []struct {
+		hex       string
+		want      any
+		canonical bool
+	}{
+		{"00", int64(0), true},
+		{"17", int64(23), true},
+		{"1818", int64(24), true},
+		{"1903e8", int64(1000), true},
+		{"1a000f4240", int64(1000000), true},
+		{"1b000000e8d4a51000", int64(1000000000000), true},
+		{"1bffffffffffffffff", uint64(math.MaxUint64), true},
+		{"20", int64(-1), true},
+		{"3863", int64(-100), true},
+		{"3b7fffffffffffffff", int64(math.MinInt64), true},
+		{"f90000", 0.0, true},
+		{"f98000", math.Copysign(0, -1), true},
+		{"f93c00", 1.0, true},
+		{"fb3ff199999999999a", 1.1, true},
+		{"f93e00", 1.5, true},
+		{"f97bff", 65504.0, true},
+		{"fa47c35000", 100000.0, true},
+		{"fa7f7fffff", 3.4028234663852886e+38, true},
+		{"fb7e37e43c8800759c", 1.0e+300, true},
+		{"f90001", 5.960464477539063e-8, true},
+		{"f90400", 0.00006103515625, true},
+		{"f9c400", -4.0, true},
+		{"fbc010666666666666", -4.1, true},
+		{"f97c00", math.Inf(1), true},
+		{"f9fc00", math.Inf(-1), true},
+		{"fa7f800000", math.Inf(1), false},
+		{"fb7ff0000000000000", math.Inf(1), false},
+		{"f4", false, true},
+		{"f5", true, true},
+		{"f6", nil, true},
+		{"f7", Undefined{}, true},
+		{"c074323031332d30332d32315432303a30343a30305a", Tag{0, "2013-03-21T20:04:00Z"}, true},
+		{"c11a514b67b0", Tag{1, int64(1363896240)}, true},
+		{"d74401020304", Tag{23, []byte{1, 2, 3, 4}}, true},
+		{"40", []byte{}, true},
+		{"4401020304", []byte{1, 2, 3, 4}, true},
+		{"60", "", true},
+		{"6161", "a", true},
+		{"6449455446", "IETF", true},
+		{"62225c", "\"\\", true},
+		{"62c3bc", "ü", true},
+		{"64f0908591", "\U00010151", true},
+		{"80", []any{}, true},
+		{"83010203", []any{int64(1), int64(2), int64(3)}, true},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}, true},
+		{"a0", map[any]any{}, true},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}, true},
+		{"a26161016162820203", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, true},
+		{"826161a161626163", []any{"a", map[any]any{"b": "c"}}, true},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}, false},
+		{"7f657374726561646d696e67ff", "streaming", false},
+		{"9fff", []any{}, false},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}, false},
+		{"83018202039f0405ff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}, false},
+		{"bf61610161629f0203ffff", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, false},
+		{"bf6346756ef563416d7421ff", map[any]any{"Fun": true, "Amt": int64(-2)}, false},
+	}
+	for _, tt := range tests {
+		data := unhex(t, tt.hex)
+		got, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.hex, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || math.Signbit(float(got)) != math.Signbit(float(tt.want)) {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.hex, got, tt.want)
+		}
+		if !tt.canonical {
+			continue
+		}
+		enc, err := Marshal(tt.want)
+		if err != nil {
+			t.Errorf("Marshal(%#v): %v", tt.want, err)
+		} else if !bytes.Equal(enc, data) {
+			t.Errorf("Marshal(%#v) = %x, want %s", tt.want, enc, tt.hex)
+		}
+	}
+}
+
+// float returns v if it is a float64, for comparing the sign of zeros.
+func float(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func TestUnmarshalNaN(t *testing.T) {
+	for _, s := range []string{"f97e00", "fa7fc00000", "fb7ff8000000000000"} {
+		v, err := Unmarshal(unhex(t, s))
+		if f, ok := v.(float64); err != nil || !ok || !math.IsNaN(f) {
+			t.Errorf("Unmarshal(%s) = %v, %v, want NaN", s, v, err)
+		}
+	}
+	if b, _ := Marshal(math.NaN()); !bytes.Equal(b, []byte{0xF9, 0x7E, 0x00}) {
+		t.Errorf("Marshal(NaN) = %x, want f97e00", b)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want any // what Unmarshal returns, when it differs from in
+	}{
+		{"int", 42, int64(42)},
+		{"uint", uint(7), int64(7)},
+		{"negative", -500, int64(-500)},
+		{"float32", float32(0.25), 0.25},
+		{"float", 1.0 / 3, nil},
+		{"long string", strings.Repeat("x", 300), nil},
+		{"long bytes", bytes.Repeat([]byte{0xAB}, 70000), nil},
+		{"string map", map[string]any{"alg": "sha256", "exclusions": []any{map[string]any{"start": 20, "length": 100}}},
+			map[any]any{"alg": "sha256", "exclusions": []any{map[any]any{"start": int64(20), "length": int64(100)}}}},
+		{"mixed keys", map[any]any{int64(1): int64(-7), "b": true, false: nil, 2.5: "f"}, nil},
+		{"cose sign1", Tag{18, []any{[]byte{0xA1, 0x01, 0x26}, map[any]any{int64(33): []byte("cert")}, nil, []byte("sig")}}, nil},
+		{"nested", []any{[]any{[]any{map[any]any{"a": []any{}}}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Unmarshal(b)
+			if err != nil {
+				t.Fatalf("Unmarshal(%x): %v", b, err)
+			}
+			want := tt.want
+			if want == nil {
+				want = tt.in
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+			again, err := Marshal(got)
+			if err != nil || !bytes.Equal(again, b) {
+				t.Errorf("re-encoding gives %x, %v, want %x", again, err, b)
+			}
+		})
+	}
+}
+
+func TestMarshalDeterministic(t *testing.T) {
+	// Keys sort by their encodings: shorter first, then bytewise.
+	m := map[any]any{"aa": 1, "b": 2, int64(10): 3, int64(-1): 4, int64(100): 5, false: 6}
+	want := "a6 0a03 1864 05 2004 6162 02 626161 01 f4 06"
+	for i := 0; i < 10; i++ {
+		b, err := Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, unhex(t, want)) {
+			t.Fatalf("Marshal = %x, want %s", b, want)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	deep := any(int64(0))
+	for i := 0; i <= maxDepth; i++ {
+		deep = []any{deep}
+	}
+	tests := []struct {
+		name string
+		in   any
+		err  string
+	}{
+		{"unsupported type", struct{}{}, "unsupported type struct {}"},
+		{"unsupported element", []any{int8(1)}, "unsupported type int8"},
+		{"array key", map[any]any{[2]int{}: 1}, "unsupported type [2]int"},
+		{"tag key", map[any]any{Tag{1, int64(2)}: 1}, "unsupported map key type cbor.Tag"},
+		{"too deep", deep, "nested too deeply"},
+	}
+	for _, tt := range tests {
+		_, err := Marshal(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	nest := func(prefix string, n int, leaf string) string {
+		return strings.Repeat(prefix, n) + leaf
+	}
+	tests := []struct {
+		name, hex, err string
+	}{
+		{"empty", "", "truncated at offset 0"},
+		{"truncated argument", "19 03", "truncated at offset 1"},
+		{"truncated string", "64 4945", "length 4 exceeds the data"},
+		{"truncated array", "83 0102", "length 3 exceeds the data"},
+		{"truncated array item", "82 01 19", "truncated at offset 3"},
+		{"truncated map value", "a1 01", "truncated at offset 2"},
+		{"truncated tag", "c1", "truncated at offset 1"},
+		{"truncated float", "fb 3ff19999", "truncated at offset 1"},
+		{"unterminated indefinite array", "9f 0102", "truncated at offset 3"},
+		{"unterminated chunks", "5f 4101", "truncated at offset 3"},
+		{"huge byte string", "5b ffffffffffffffff 00", "length 18446744073709551615 exceeds the data"},
+		{"huge text string", "7b 7fffffffffffffff", "exceeds the data"},
+		{"huge array", "9b ffffffffffffffff 00", "length 18446744073709551615 exceeds the data"},
+		{"huge map", "bb 00000000ffffffff 0000", "length 4294967295 exceeds the data"},
+		{"array longer than data", "9a 00010000 00", "exceeds the data"},
+		{"huge chunk", "5f 5b 0000000100000000 ff", "exceeds the data"},
+		{"deep arrays", nest("81", 100, "00"), "nested too deeply"},
+		{"deep maps", nest("a1 00", 100, "00"), "nested too deeply"},
+		{"deep tags", nest("c1", 100, "00"), "nested too deeply"},
+		{"deep indefinite arrays", nest("9f", 100, ""), "nested too deeply"},
+		{"reserved info", "1c", "reserved additional information 28"},
+		{"reserved info type 7", "fe", "reserved additional information 30"},
+		{"indefinite integer", "1f", "indefinite length for major type 0"},
+		{"indefinite negative", "3f", "indefinite length for major type 1"},
+		{"indefinite tag", "df 00", "indefinite length for major type 6"},
+		{"lone break", "ff", "unexpected break at offset 0"},
+		{"break in array", "82 01 ff", "unexpected break at offset 2"},
+		{"break as map value", "bf 01 ff", "unexpected break at offset 2"},
+		{"trailing bytes", "01 02 03", "2 bytes after the data item"},
+		{"mixed chunks", "5f 6161 ff", "mixed string chunks"},
+		{"mixed text chunks", "7f 4101 ff", "mixed string chunks"},
+		{"nested indefinite chunk", "5f 5f ff ff", "invalid string chunk"},
+		{"non-string chunk", "7f 01 ff", "invalid string chunk"},
+		{"array key", "a1 80 01", "unsupported map key type []interface {}"},
+		{"bytes key", "a1 40 01", "unsupported map key type []uint8"},
+		{"tag key", "a1 c100 01", "unsupported map key type cbor.Tag"},
+		{"negative out of range", "3b 8000000000000000", "negative integer out of range"},
+		{"simple value", "f0", "unsupported simple value 16"},
+		{"two-byte simple value", "f8 ff", "unsupported simple value 255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Unmarshal(unhex(t, tt.hex))
+			if !errors.Is(err, ErrFormat) {
+				t.Fatalf("got %#v, %v, want %v", v, err, ErrFormat)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestUnmarshalNoPanic decodes every truncation and single-byte
+// corruption of a COSE-like structure, which must fail or succeed
+// without panicking; every proper prefix must fail.
+func TestUnmarshalNoPanic(t *testing.T) {
+	data, err := Marshal(Tag{18, []any{
+		[]byte{0xA1, 0x01, 0x26},
+		map[any]any{int64(33): []any{bytes.Repeat([]byte{0x30}, 300)}, "pad": strings.Repeat("p", 30)},
+		nil,
+		[]byte("signature"),
+		[]any{1.5, -1, uint64(math.MaxUint64), true, Undefined{}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A two-item array of that and indefinite-length items.
+	data = append(append([]byte{0x82}, data...), unhex(t, "9f 5f 4101 ff bf 6161 f93c00 ff ff")...)
+	for i := range data {
+		if _, err := unmarshal(t, data[:i]); err == nil {
+			t.Errorf("prefix of %d bytes decoded", i)
+		}
+		for _, b := range []byte{0x00, 0x1B, 0x5B, 0x9F, 0xBB, 0xD8, 0xFF} {
+			c := bytes.Clone(data)
+			c[i] = b
+			unmarshal(t, c)
+		}
+	}
+	if _, err := unmarshal(t, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func unmarshal(t *testing.T, data []byte) (v any, err error) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("Unmarshal(%x) panicked: %v", data, r)
+		}
+	}()
+	return Unmarshal(data)
+}
@@ -0,0 +1,160 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Marshal encodes v as a CBOR data item in the core deterministic
+// encoding (RFC 8949, section 4.2.1): the shortest heads and floats,
+// definite lengths and map keys in the order of their encodings. It takes
+// the values Unmarshal returns, as well as int, uint, float32 and
+// map[string]any, so that Unmarshal of the result returns v with those
+// widened to int64, float64 and map[any]any.
+func Marshal(v any) ([]byte, error) {
+	return appendItem(nil, v, 0)
+}
+
+func appendItem(b []byte, v any, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("cbor: nested too deeply")
+	}
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xF6), nil
+	case Undefined:
+		return append(b, 0xF7), nil
+	case bool:
+		if v {
+			return append(b, 0xF5), nil
+		}
+		return append(b, 0xF4), nil
+	case int:
+		return appendInt(b, int64(v)), nil
+	case int64:
+		return appendInt(b, v), nil
+	case uint:
+		return appendHead(b, 0, uint64(v)), nil
+	case uint64:
+		return appendHead(b, 0, v), nil
+	case float32:
+		return appendFloat(b, float64(v)), nil
+	case float64:
+		return appendFloat(b, v), nil
+	case []byte:
+		return append(appendHead(b, 2, uint64(len(v))), v...), nil
+	case string:
+		return append(appendHead(b, 3, uint64(len(v))), v...), nil
+	case []any:
+		b = appendHead(b, 4, uint64(len(v)))
+		for _, e := range v {
+			var err error
+			if b, err = appendItem(b, e, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		m := make(map[any]any, len(v))
+		for k, e := range v {
+			m[k] = e
+		}
+		return appendItem(b, m, depth)
+	case map[any]any:
+		type pair struct{ k, v []byte }
+		pairs := make([]pair, 0, len(v))
+		for k, e := range v {
+			switch k.(type) {
+			case []any, map[any]any, map[string]any, []byte, Tag:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+			kb, err := appendItem(nil, k, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			eb, err := appendItem(nil, e, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair{kb, eb})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i].k, pairs[j].k) < 0 })
+		b = appendHead(b, 5, uint64(len(pairs)))
+		for _, p := range pairs {
+			b = append(append(b, p.k...), p.v...)
+		}
+		return b, nil
+	case Tag:
+		return appendItem(appendHead(b, 6, v.Number), v.Content, depth+1)
+	}
+	return nil, fmt.Errorf("cbor: unsupported type %T", v)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendHead(b, 1, uint64(-1-v))
+	}
+	return appendHead(b, 0, uint64(v))
+}
+
+// appendHead appends an initial byte of major type and its argument in
+// the fewest bytes.
+func appendHead(b []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(b, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), arg)
+}
+
+// appendFloat appends v as the shortest float that holds it exactly.
+func appendFloat(b []byte, v float64) []byte {
+	if math.IsNaN(v) {
+		return append(b, 0xF9, 0x7E, 0x00)
+	}
+	if h, ok := toHalf(v); ok {
+		return binary.BigEndian.AppendUint16(append(b, 0xF9), h)
+	}
+	if f := float32(v); float64(f) == v {
+		return binary.BigEndian.AppendUint32(append(b, 0xFA), math.Float32bits(f))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xFB), math.Float64bits(v))
+}
+
+// toHalf returns the half-precision encoding of v, if it has one.
+func toHalf(v float64) (uint16, bool) {
+	f := math.Float32bits(float32(v))
+	if float64(float32(v)) != v {
+		return 0, false
+	}
+	sign := uint16(f>>16) & 0x8000
+	exp := int(f>>23&0xFF) - 127
+	mant := f & 0x7FFFFF
+	switch {
+	case f&0x7FFFFFFF == 0:
+		return sign, true
+	case exp == 128: // infinity; NaN is handled by the caller
+		return sign | 0x7C00, true
+	case exp >= -14 && exp <= 15 && mant&0x1FFF == 0:
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		// Subnormal: the implicit bit joins the mantissa.
+		shift := uint(-exp - 14 + 13)
+		m := mant | 0x800000
+		if m&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(m>>shift), true
+	}
+	return 0, false
+}
@@ -0,0 +1,274 @@
+// Package jsonrpc speaks JSON-RPC 2.0 over a byte stream, one JSON value
+// per message, as needed for the shootlog daemon, editor plugins and
+// their clients. It serves a connection with a handler and calls methods
+// over one; there is no reflection onto Go methods and no HTTP transport.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Version is the protocol version every message carries.
+const Version = "2.0"
+
+// Error codes of the specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeRequestCancelled answers a request cancelled with CancelMethod,
+	// as in the Language Server Protocol.
+	CodeRequestCancelled = -32800
+)
+
+// Error is an error object of a response. Handlers return one to choose
+// the code; other errors are sent as CodeInternalError.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+// Errorf returns an Error of code with a formatted message.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler answers the request of method with its result, which is
+// marshaled as JSON, or an error.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// request is a request or, without an ID, a notification.
+type request struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// CancelMethod is the notification that cancels a request in flight, as
+// in the Language Server Protocol: its params hold the request's "id".
+const CancelMethod = "$/cancelRequest"
+
+// Serve reads requests from r and writes the responses to w until r ends
+// or ctx is done; at the end of r it waits for the requests in flight and
+// returns nil. Requests are handled concurrently, each with a context
+// that CancelMethod cancels, so responses may come out of order. An
+// array of requests is a batch, answered with one array once all its
+// requests are handled. Notifications are handled without a response.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, h Handler) error {
+	ctx, cancelAll := context.WithCancel(ctx)
+	defer cancelAll()
+	s := &session{h: h, enc: json.NewEncoder(w), inflight: map[string]context.CancelFunc{}}
+	defer s.wg.Wait()
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for ctx.Err() == nil {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			// The stream cannot be resynchronized after malformed JSON.
+			s.send(response{Version: Version, ID: json.RawMessage("null"), Error: Errorf(CodeParseError, "%v", err)})
+			return err
+		}
+		if raw[0] != '[' {
+			run := s.start(ctx, raw)
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				if resp, ok := run(); ok {
+					s.send(resp)
+				}
+			}()
+			continue
+		}
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil || len(batch) == 0 {
+			s.send(response{Version: Version, ID: json.RawMessage("null"), Error: Errorf(CodeInvalidRequest, "not a batch of requests")})
+			continue
+		}
+		runs := make([]func() (response, bool), len(batch))
+		for i, raw := range batch {
+			runs[i] = s.start(ctx, raw)
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.batch(runs)
+		}()
+	}
+	return ctx.Err()
+}
+
+// session is the state of one connection Serve handles.
+type session struct {
+	h        Handler
+	wmu      sync.Mutex
+	enc      *json.Encoder
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func (s *session) send(v any) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.enc.Encode(v)
+}
+
+// batch runs the requests of a batch at once and sends their responses
+// together.
+func (s *session) batch(runs []func() (response, bool)) {
+	resps := make([]*response, len(runs))
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, ok := run(); ok {
+				resps[i] = &resp
+			}
+		}()
+	}
+	wg.Wait()
+	var out []*response
+	for _, r := range resps {
+		if r != nil {
+			out = append(out, r)
+		}
+	}
+	if len(out) > 0 {
+		s.send(out)
+	}
+}
+
+// start reads one message and returns the function answering it, which
+// reports whether it takes a response. Requests are registered for
+// cancellation here, in the order they arrive, so a cancellation sent
+// after a request always finds it; cancellations take effect at once.
+func (s *session) start(ctx context.Context, raw json.RawMessage) func() (response, bool) {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil || req.Version != Version || req.Method == "" {
+		return func() (response, bool) {
+			return response{Version: Version, ID: json.RawMessage("null"), Error: Errorf(CodeInvalidRequest, "not a JSON-RPC 2.0 request")}, true
+		}
+	}
+	if req.Method == CancelMethod {
+		var p struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal(req.Params, &p) == nil {
+			s.mu.Lock()
+			if cancel, ok := s.inflight[string(p.ID)]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+		return func() (response, bool) { return response{}, false }
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(req.ID)
+	if req.ID != nil {
+		s.mu.Lock()
+		s.inflight[key] = cancel
+		s.mu.Unlock()
+	}
+	return func() (response, bool) {
+		defer cancel()
+		result, err := s.h(ctx, req.Method, req.Params)
+		if req.ID == nil {
+			return response{}, false
+		}
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		resp := response{Version: Version, ID: req.ID}
+		if err != nil {
+			var rpcErr *Error
+			switch {
+			case errors.As(err, &rpcErr):
+			case ctx.Err() != nil:
+				rpcErr = Errorf(CodeRequestCancelled, "request cancelled")
+			default:
+				rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+			return resp, true
+		}
+		if result == nil {
+			result = struct{}{}
+		}
+		resp.Result = result
+		return resp, true
+	}
+}
+
+// Client calls methods over a connection, one call at a time.
+type Client struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	dec  *json.Decoder
+	next int
+}
+
+// NewClient returns a client speaking over rw.
+func NewClient(rw io.ReadWriter) *Client {
+	return &Client{enc: json.NewEncoder(rw), dec: json.NewDecoder(bufio.NewReader(rw))}
+}
+
+// Call sends a request of method with params and decodes its result into
+// result, unless result is nil. An error response is returned as *Error.
+func (c *Client) Call(method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	id := json.RawMessage(fmt.Sprint(c.next))
+	req := request{Version: Version, ID: id, Method: method}
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = p
+	}
+	if err := c.enc.Encode(req); err != nil {
+		return err
+	}
+	var resp struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := c.dec.Decode(&resp); err != nil {
+		return fmt.Errorf("jsonrpc: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if string(resp.ID) != string(id) {
+		return fmt.Errorf("jsonrpc: response to request %s, want %s", resp.ID, id)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
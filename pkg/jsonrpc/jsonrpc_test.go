@@ -0,0 +1,288 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync/atomic"
+	"testing"
+)
+
+// testHandler answers echo with its params, null with no result, fail
+// with a plain error, invalid with an *Error, slow once its context is
+// done, and counts notify calls in notified.
+func testHandler(notified *atomic.Int32) Handler {
+	return func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "echo":
+			return params, nil
+		case "null":
+			return nil, nil
+		case "fail":
+			return nil, errors.New("disk on fire")
+		case "invalid":
+			return nil, fmt.Errorf("checking: %w", Errorf(CodeInvalidParams, "no path"))
+		case "slow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		case "notify":
+			notified.Add(1)
+			return "ignored", nil
+		}
+		return nil, Errorf(CodeMethodNotFound, "no method %q", method)
+	}
+}
+
+// pipe serves h on one end of a net.Pipe and returns the other, and a
+// channel receiving what Serve returned.
+func pipe(t *testing.T, ctx context.Context, h Handler) (net.Conn, <-chan error) {
+	t.Helper()
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, server, server, h)
+		server.Close()
+	}()
+	t.Cleanup(func() { client.Close() })
+	return client, done
+}
+
+// compact returns the compact form of a JSON value, so values that differ
+// only in spacing compare equal.
+func compact(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(s)); err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	return b.String()
+}
+
+func TestServe(t *testing.T) {
+	const end = `{"jsonrpc":"2.0","id":"end","method":"null"}`
+	tests := []struct {
+		name string
+		// in is written to the connection in the given pieces.
+		in []string
+		// want are the responses, in any order.
+		want     []string
+		notified int32
+	}{
+		{"request", []string{`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"a":[1,2]}}`},
+			[]string{`{"jsonrpc":"2.0","id":1,"result":{"a":[1,2]}}`}, 0},
+		{"string id", []string{`{"jsonrpc":"2.0","id":"x-7","method":"echo","params":["p"]}`},
+			[]string{`{"jsonrpc":"2.0","id":"x-7","result":["p"]}`}, 0},
+		{"null id", []string{`{"jsonrpc":"2.0","id":null,"method":"null"}`},
+			[]string{`{"jsonrpc":"2.0","id":null,"result":{}}`}, 0},
+		{"no result", []string{`{"jsonrpc":"2.0","id":2,"method":"null"}`},
+			[]string{`{"jsonrpc":"2.0","id":2,"result":{}}`}, 0},
+		{"split across writes", []string{`{"jsonrpc":"2.0",`, `"id":3,"meth`, `od":"null"}`},
+			[]string{`{"jsonrpc":"2.0","id":3,"result":{}}`}, 0},
+		{"several in a write", []string{`{"jsonrpc":"2.0","id":4,"method":"null"}{"jsonrpc":"2.0","id":5,"method":"null"}` + "\n\n  "},
+			[]string{`{"jsonrpc":"2.0","id":4,"result":{}}`, `{"jsonrpc":"2.0","id":5,"result":{}}`}, 0},
+		{"notification", []string{`{"jsonrpc":"2.0","method":"notify","params":{}}`, `{"jsonrpc":"2.0","method":"fail"}`}, nil, 1},
+		{"method not found", []string{`{"jsonrpc":"2.0","id":6,"method":"nope"}`},
+			[]string{`{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"no method \"nope\""}}`}, 0},
+		{"internal error", []string{`{"jsonrpc":"2.0","id":7,"method":"fail"}`},
+			[]string{`{"jsonrpc":"2.0","id":7,"error":{"code":-32603,"message":"disk on fire"}}`}, 0},
+		{"wrapped error", []string{`{"jsonrpc":"2.0","id":8,"method":"invalid"}`},
+			[]string{`{"jsonrpc":"2.0","id":8,"error":{"code":-32602,"message":"no path"}}`}, 0},
+		{"wrong version", []string{`{"jsonrpc":"1.0","id":9,"method":"echo"}`},
+			[]string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}`}, 0},
+		{"no method", []string{`{"jsonrpc":"2.0","id":10}`},
+			[]string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}`}, 0},
+		{"not an object", []string{`42 "x"`}, []string{
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}`,
+		}, 0},
+		{"batch", []string{`[{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},{"jsonrpc":"2.0","method":"notify"},{"jsonrpc":"2.0","id":2,"method":"nope"},7]`},
+			[]string{`[{"jsonrpc":"2.0","id":1,"result":[1]},` +
+				`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"no method \"nope\""}},` +
+				`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a JSON-RPC 2.0 request"}}]`}, 1},
+		{"batch of notifications", []string{`[{"jsonrpc":"2.0","method":"notify"},{"jsonrpc":"2.0","method":"notify"}]`}, nil, 2},
+		{"empty batch", []string{`[]`},
+			[]string{`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"not a batch of requests"}}`}, 0},
+		{"cancel unknown request", []string{`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":99}}`, `{"jsonrpc":"2.0","method":"$/cancelRequest"}`}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified atomic.Int32
+			conn, done := pipe(t, context.Background(), testHandler(&notified))
+			go func() {
+				for _, p := range tt.in {
+					if _, err := io.WriteString(conn, p); err != nil {
+						return
+					}
+				}
+				// Every message before it has been read once this is
+				// answered, though maybe not handled.
+				io.WriteString(conn, "\n"+end)
+			}()
+			dec := json.NewDecoder(conn)
+			var got []string
+			for ended := false; !ended || len(got) < len(tt.want); {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					t.Fatal(err)
+				}
+				if string(raw) == `{"jsonrpc":"2.0","id":"end","result":{}}` {
+					ended = true
+					continue
+				}
+				got = append(got, compact(t, string(raw)))
+			}
+			conn.Close()
+			if err := <-done; err != nil {
+				t.Errorf("Serve: %v", err)
+			}
+			// Handling runs concurrently, but Serve has waited for it.
+			want := make([]string, len(tt.want))
+			for i, w := range tt.want {
+				want[i] = compact(t, w)
+			}
+			sort.Strings(got)
+			sort.Strings(want)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("responses\n%s\nwant\n%s", got, want)
+			}
+			if n := notified.Load(); n != tt.notified {
+				t.Errorf("%d notifications handled, want %d", n, tt.notified)
+			}
+		})
+	}
+}
+
+func TestServeParseError(t *testing.T) {
+	conn, done := pipe(t, context.Background(), testHandler(new(atomic.Int32)))
+	go io.WriteString(conn, `{"jsonrpc":"2.0","id":1,"method":}`)
+	var resp struct {
+		ID    json.RawMessage
+		Error *Error
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.ID) != "null" || resp.Error == nil || resp.Error.Code != CodeParseError {
+		t.Errorf("response %s %+v, want a parse error", resp.ID, resp.Error)
+	}
+	// The stream cannot be read on, so Serve gives up on it.
+	if err := <-done; err == nil {
+		t.Error("Serve returned nil after malformed JSON")
+	}
+}
+
+func TestServeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, done := pipe(t, ctx, testHandler(new(atomic.Int32)))
+	dec := json.NewDecoder(conn)
+	read := func() string {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatal(err)
+		}
+		return string(raw)
+	}
+	send := func(s string) {
+		go io.WriteString(conn, s)
+	}
+
+	// A slow request does not hold up the ones after it; cancelling it
+	// answers it.
+	send(`{"jsonrpc":"2.0","id":1,"method":"slow"}` + `{"jsonrpc":"2.0","id":2,"method":"echo","params":2}`)
+	if got := read(); got != `{"jsonrpc":"2.0","id":2,"result":2}` {
+		t.Errorf("first response %s, want that of request 2", got)
+	}
+	send(`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`)
+	if got := read(); got != `{"jsonrpc":"2.0","id":1,"error":{"code":-32800,"message":"request cancelled"}}` {
+		t.Errorf("cancelled request answered %s", got)
+	}
+
+	// Ending the server's context cancels what is in flight.
+	send(`{"jsonrpc":"2.0","id":"s","method":"slow"}`)
+	cancel()
+	if got := read(); got != `{"jsonrpc":"2.0","id":"s","error":{"code":-32800,"message":"request cancelled"}}` {
+		t.Errorf("request in flight at shutdown answered %s", got)
+	}
+	// Serve notices at the next message.
+	send(`{"jsonrpc":"2.0","method":"notify"}`)
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve: %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClient(t *testing.T) {
+	conn, _ := pipe(t, context.Background(), testHandler(new(atomic.Int32)))
+	c := NewClient(conn)
+
+	var got map[string]int
+	if err := c.Call("echo", map[string]int{"a": 1}, &got); err != nil || got["a"] != 1 {
+		t.Errorf("echo: %v, %v", got, err)
+	}
+	if err := c.Call("null", nil, nil); err != nil {
+		t.Errorf("null: %v", err)
+	}
+	var n int
+	if err := c.Call("echo", "five", &n); err == nil {
+		t.Error("a string result decoded into an int")
+	}
+	tests := []struct {
+		method string
+		code   int
+	}{
+		{"nope", CodeMethodNotFound},
+		{"fail", CodeInternalError},
+		{"invalid", CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		err := c.Call(tt.method, []int{1}, nil)
+		var e *Error
+		if !errors.As(err, &e) || e.Code != tt.code {
+			t.Errorf("%s: error %v, want code %d", tt.method, err, tt.code)
+		}
+	}
+	if err := c.Call("echo", make(chan int), nil); err == nil {
+		t.Error("params that cannot be marshaled were sent")
+	}
+	// The client still works after a call that sent nothing.
+	if err := c.Call("echo", 5, &n); err != nil || n != 5 {
+		t.Errorf("echo after a failed call: %v, %v", n, err)
+	}
+}
+
+func TestClientBadResponses(t *testing.T) {
+	tests := []struct {
+		name, reply, err string
+	}{
+		{"wrong id", `{"jsonrpc":"2.0","id":9,"result":1}`, "jsonrpc: response to request 9, want 1"},
+		{"malformed", `{"jsonrpc"`, "jsonrpc: unexpected EOF"},
+		{"closed", ``, "jsonrpc: EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				json.NewDecoder(server).Decode(new(json.RawMessage))
+				io.WriteString(server, tt.reply)
+				server.Close()
+			}()
+			err := NewClient(client).Call("echo", nil, nil)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestErrorf(t *testing.T) {
+	e := Errorf(CodeInvalidParams, "no %s", "path")
+	if e.Code != CodeInvalidParams || e.Message != "no path" || e.Error() != "jsonrpc: no path (code -32602)" {
+		t.Errorf("Errorf = %+v, %q", e, e.Error())
+	}
+}
@@ -0,0 +1,355 @@
+// Package parquet writes Apache Parquet files, as needed to export photo
+// summaries for pandas, DuckDB and other data tools. It writes only: flat
+// schemas of optional and repeated columns, uncompressed PLAIN pages and
+// one page per column of each row group. There is no reader.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+// Column types. String columns hold UTF-8 text.
+const (
+	Boolean Type = iota
+	Int64
+	Double
+	String
+)
+
+// Physical and converted types of the Parquet format.
+var physical = map[Type]int32{Boolean: 0, Int64: 2, Double: 5, String: 6}
+
+const convertedUTF8 = 0
+
+// Repetition types, encodings and the page type of the Parquet format.
+const (
+	optional = 1
+	repeated = 2
+
+	encPlain = 0
+	encRLE   = 3
+
+	dataPage = 0
+)
+
+// magic opens and ends a Parquet file.
+const magic = "PAR1"
+
+// RowGroupSize is the number of rows a Writer buffers before writing them
+// out as a row group.
+const RowGroupSize = 10000
+
+var errClosed = errors.New("parquet: writer is closed")
+
+// Column is a column of a file. Every column is nullable.
+type Column struct {
+	Name string
+	Type Type
+	// Repeated columns hold a list of values per row, which readers
+	// present as a list column. An empty list and a null are the same.
+	Repeated bool
+}
+
+// Writer writes rows to a Parquet file.
+type Writer struct {
+	w      io.Writer
+	cols   []Column
+	chunks []chunk
+	rows   int
+	// pos is the number of bytes written so far; the footer refers to
+	// pages by their offsets in the file.
+	pos    int64
+	total  int64
+	groups []rowGroup
+	err    error
+}
+
+// chunk buffers the values of one column of the current row group.
+type chunk struct {
+	reps, defs []byte
+	values     bytes.Buffer
+	bools      []bool
+}
+
+// rowGroup records where the columns of a written row group are.
+type rowGroup struct {
+	rows    int
+	size    int64
+	columns []columnChunk
+}
+
+type columnChunk struct {
+	offset, size int64
+	values       int
+}
+
+// NewWriter returns a writer of a file with the given columns to w.
+func NewWriter(w io.Writer, cols []Column) *Writer {
+	return &Writer{w: w, cols: cols, chunks: make([]chunk, len(cols))}
+}
+
+// Write adds a row holding a value for each column, in order. A value is
+// nil for null, or a bool, int64, float64 or string as the column's type
+// asks; a repeated column takes a []any of them. After a value of the
+// wrong type the writer fails.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.cols) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(row), len(w.cols))
+	}
+	if w.rows == 0 && w.pos == 0 {
+		w.write([]byte(magic))
+	}
+	// A row added in part would misalign the columns.
+	if err := w.add(row); err != nil {
+		w.err = err
+		return err
+	}
+	if w.rows++; w.rows == RowGroupSize {
+		w.flush()
+	}
+	return w.err
+}
+
+// add appends the values of row to the column chunks.
+func (w *Writer) add(row []any) error {
+	for i, col := range w.cols {
+		c := &w.chunks[i]
+		if !col.Repeated {
+			if err := c.add(col, row[i], 0); err != nil {
+				return err
+			}
+			continue
+		}
+		var list []any
+		if row[i] != nil {
+			l, ok := row[i].([]any)
+			if !ok {
+				return fmt.Errorf("parquet: column %s: %T is not a list", col.Name, row[i])
+			}
+			list = l
+		}
+		if len(list) == 0 {
+			c.reps, c.defs = append(c.reps, 0), append(c.defs, 0)
+			continue
+		}
+		for j, v := range list {
+			if v == nil {
+				return fmt.Errorf("parquet: column %s: null list element", col.Name)
+			}
+			if err := c.add(col, v, byte(min(j, 1))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add appends v to c with the repetition level rep.
+func (c *chunk) add(col Column, v any, rep byte) error {
+	if col.Repeated {
+		c.reps = append(c.reps, rep)
+	}
+	if v == nil {
+		c.defs = append(c.defs, 0)
+		return nil
+	}
+	ok := true
+	switch col.Type {
+	case Boolean:
+		var b bool
+		if b, ok = v.(bool); ok {
+			c.bools = append(c.bools, b)
+		}
+	case Int64:
+		var n int64
+		if n, ok = v.(int64); ok {
+			_ = binary.Write(&c.values, binary.LittleEndian, n)
+		}
+	case Double:
+		var f float64
+		if f, ok = v.(float64); ok {
+			_ = binary.Write(&c.values, binary.LittleEndian, math.Float64bits(f))
+		}
+	case String:
+		var s string
+		if s, ok = v.(string); ok {
+			_ = binary.Write(&c.values, binary.LittleEndian, uint32(len(s)))
+			c.values.WriteString(s)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %s: %T value", col.Name, v)
+	}
+	c.defs = append(c.defs, 1)
+	return nil
+}
+
+// Close writes the buffered rows and the file footer. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.pos == 0 {
+		w.write([]byte(magic))
+	}
+	if w.rows > 0 {
+		w.flush()
+	}
+	meta := w.footer()
+	w.write(meta)
+	w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta))))
+	w.write([]byte(magic))
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errClosed
+	return nil
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.pos += int64(n)
+	w.err = err
+}
+
+// flush writes the buffered rows as a row group of one data page per
+// column.
+func (w *Writer) flush() {
+	g := rowGroup{rows: w.rows}
+	for i, col := range w.cols {
+		c := &w.chunks[i]
+		var page []byte
+		if col.Repeated {
+			page = appendLevels(page, c.reps)
+		}
+		page = appendLevels(page, c.defs)
+		if col.Type == Boolean {
+			page = appendBits(page, c.bools)
+		} else {
+			page = append(page, c.values.Bytes()...)
+		}
+		h := newCompact()
+		h.i32(1, dataPage)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(page)))
+		h.structField(5)
+		h.i32(1, int32(len(c.defs)))
+		h.i32(2, encPlain)
+		h.i32(3, encRLE)
+		h.i32(4, encRLE)
+		h.end()
+		h.end()
+		cc := columnChunk{offset: w.pos, size: int64(len(h.buf) + len(page)), values: len(c.defs)}
+		w.write(h.buf)
+		w.write(page)
+		g.columns = append(g.columns, cc)
+		g.size += cc.size
+		*c = chunk{}
+	}
+	w.groups = append(w.groups, g)
+	w.total += int64(w.rows)
+	w.rows = 0
+}
+
+// appendLevels appends levels of at most 1 in the RLE encoding of data
+// page version 1: a length, then runs of equal levels.
+func appendLevels(b []byte, levels []byte) []byte {
+	var runs []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		runs = append(runs, levels[i])
+		i = j
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(runs)))
+	return append(b, runs...)
+}
+
+// appendBits appends booleans in the PLAIN encoding: packed eight to a
+// byte, the first in the lowest bit.
+func appendBits(b []byte, bools []bool) []byte {
+	packed := make([]byte, (len(bools)+7)/8)
+	for i, v := range bools {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(b, packed...)
+}
+
+// footer encodes the FileMetaData of the file.
+func (w *Writer) footer() []byte {
+	m := newCompact()
+	m.i32(1, 1)
+	m.list(2, tStruct, len(w.cols)+1)
+	m.begin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(w.cols)))
+	m.end()
+	for _, col := range w.cols {
+		m.begin()
+		m.i32(1, physical[col.Type])
+		m.i32(3, repetition(col))
+		m.binary(4, col.Name)
+		if col.Type == String {
+			m.i32(6, convertedUTF8)
+		}
+		m.end()
+	}
+	m.i64(3, w.total)
+	m.list(4, tStruct, len(w.groups))
+	for _, g := range w.groups {
+		m.begin()
+		m.list(1, tStruct, len(g.columns))
+		for i, cc := range g.columns {
+			col := w.cols[i]
+			m.begin()
+			m.i64(2, cc.offset)
+			m.structField(3)
+			m.i32(1, physical[col.Type])
+			m.list(2, tI32, 2)
+			m.elemI32(encPlain)
+			m.elemI32(encRLE)
+			m.list(3, tBinary, 1)
+			m.str(col.Name)
+			m.i32(4, 0) // uncompressed
+			m.i64(5, int64(cc.values))
+			m.i64(6, cc.size)
+			m.i64(7, cc.size)
+			m.i64(9, cc.offset)
+			m.end()
+			m.end()
+		}
+		m.i64(2, g.size)
+		m.i64(3, int64(g.rows))
+		m.end()
+	}
+	m.binary(6, "shootlog")
+	m.end()
+	return m.buf
+}
+
+func repetition(col Column) int32 {
+	if col.Repeated {
+		return repeated
+	}
+	return optional
+}
@@ -0,0 +1,338 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// file is a Parquet file as read back by readFile.
+type file struct {
+	meta map[int16]any
+	// rows are the values of each row, with lists as []any and empty
+	// lists as nil.
+	rows [][]any
+}
+
+// readFile decodes a file written by a Writer, checking its framing and
+// that the footer agrees with the pages it points to.
+func readFile(t *testing.T, data []byte, cols []Column) file {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("file does not start and end with %q", magic)
+	}
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if n > len(data)-12 {
+		t.Fatalf("footer length %d exceeds the file", n)
+	}
+	r := &thriftReader{b: data[len(data)-8-n : len(data)-8]}
+	meta, err := r.structure()
+	if err != nil || r.pos != n {
+		t.Fatalf("footer: %v after %d of %d bytes", err, r.pos, n)
+	}
+	f := file{meta: meta}
+	groups, _ := meta[4].([]any)
+	for gi, g := range groups {
+		g := g.(map[int16]any)
+		chunks := g[1].([]any)
+		if len(chunks) != len(cols) {
+			t.Fatalf("row group %d has %d columns, want %d", gi, len(chunks), len(cols))
+		}
+		nrows := int(g[3].(int64))
+		rows := make([][]any, nrows)
+		for i := range rows {
+			rows[i] = make([]any, len(cols))
+		}
+		var size int64
+		for ci, cc := range chunks {
+			cc := cc.(map[int16]any)
+			md := cc[3].(map[int16]any)
+			offset := md[9].(int64)
+			if cc[2] != offset {
+				t.Errorf("column %d: file offset %v, data page offset %d", ci, cc[2], offset)
+			}
+			r := &thriftReader{b: data[:len(data)-8-n], pos: int(offset)}
+			header, err := r.structure()
+			if err != nil {
+				t.Fatalf("page header at %d: %v", offset, err)
+			}
+			body := int(header[3].(int64))
+			if header[1] != int64(dataPage) || header[2] != int64(body) {
+				t.Errorf("column %d: page header %v", ci, header)
+			}
+			if got := int64(r.pos) - offset + int64(body); md[6] != got || md[7] != got {
+				t.Errorf("column %d: chunk sizes %v, %v, want %d", ci, md[6], md[7], got)
+			}
+			size += int64(r.pos) - offset + int64(body)
+			dph := header[5].(map[int16]any)
+			if dph[1] != md[5] || dph[2] != int64(encPlain) || dph[3] != int64(encRLE) || dph[4] != int64(encRLE) {
+				t.Errorf("column %d: data page header %v, column metadata %v", ci, dph, md)
+			}
+			values := readPage(t, data[r.pos:r.pos+body], cols[ci], int(dph[1].(int64)))
+			if len(values) != nrows {
+				t.Fatalf("column %d: %d rows, want %d", ci, len(values), nrows)
+			}
+			for i, v := range values {
+				rows[i][ci] = v
+			}
+		}
+		if g[2] != size {
+			t.Errorf("row group %d: total byte size %v, want %d", gi, g[2], size)
+		}
+		f.rows = append(f.rows, rows...)
+	}
+	return f
+}
+
+// readPage decodes the n values of a data page into a value per row.
+func readPage(t *testing.T, page []byte, col Column, n int) []any {
+	t.Helper()
+	var reps []byte
+	if col.Repeated {
+		reps, page = readLevels(t, page, n)
+	}
+	defs, page := readLevels(t, page, n)
+	var out []any
+	var bit int
+	for i, def := range defs {
+		var v any
+		if def == 1 {
+			switch col.Type {
+			case Boolean:
+				v = page[bit/8]>>(bit%8)&1 == 1
+				bit++
+			case Int64:
+				v, page = int64(binary.LittleEndian.Uint64(page)), page[8:]
+			case Double:
+				v, page = math.Float64frombits(binary.LittleEndian.Uint64(page)), page[8:]
+			case String:
+				l := binary.LittleEndian.Uint32(page)
+				v, page = string(page[4:4+l]), page[4+l:]
+			}
+		}
+		switch {
+		case !col.Repeated:
+			out = append(out, v)
+		case reps[i] == 0 && v == nil:
+			out = append(out, nil)
+		case reps[i] == 0:
+			out = append(out, []any{v})
+		default:
+			out[len(out)-1] = append(out[len(out)-1].([]any), v)
+		}
+	}
+	if col.Type == Boolean {
+		page = page[(bit+7)/8:]
+	}
+	if len(page) != 0 {
+		t.Errorf("column %s: %d bytes after the values", col.Name, len(page))
+	}
+	return out
+}
+
+// readLevels decodes n levels in the RLE encoding.
+func readLevels(t *testing.T, page []byte, n int) ([]byte, []byte) {
+	t.Helper()
+	l := binary.LittleEndian.Uint32(page)
+	runs, rest := page[4:4+l], page[4+l:]
+	var levels []byte
+	for len(runs) > 0 {
+		h, k := binary.Uvarint(runs)
+		if k <= 0 || h&1 != 0 || len(runs) < k+1 {
+			t.Fatalf("bad level run %x", runs)
+		}
+		levels = append(levels, bytes.Repeat(runs[k:k+1], int(h>>1))...)
+		runs = runs[k+1:]
+	}
+	if len(levels) != n {
+		t.Fatalf("%d levels, want %d", len(levels), n)
+	}
+	return levels, rest
+}
+
+var testColumns = []Column{
+	{Name: "path", Type: String},
+	{Name: "iso", Type: Int64},
+	{Name: "aperture", Type: Double},
+	{Name: "flash", Type: Boolean},
+	{Name: "keywords", Type: String, Repeated: true},
+}
+
+func TestWriter(t *testing.T) {
+	rows := [][]any{
+		{"a.jpg", int64(400), 2.8, true, []any{"sea", "sunset"}},
+		{"b.jpg", nil, nil, false, nil},
+		{"", int64(-1), math.Inf(1), nil, []any{}},
+		{"日本.jpg", int64(math.MaxInt64), -0.5, true, []any{"one"}},
+		{nil, nil, nil, nil, []any{"x", "y", "z"}},
+	}
+	for i := 0; i < 9; i++ {
+		rows = append(rows, []any{fmt.Sprint(i), int64(i), float64(i), i%3 == 0, nil})
+	}
+	var b bytes.Buffer
+	w := NewWriter(&b, testColumns)
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f := readFile(t, b.Bytes(), testColumns)
+
+	want := make([][]any, len(rows))
+	for i, row := range rows {
+		want[i] = append([]any(nil), row...)
+		if l, ok := row[4].([]any); ok && len(l) == 0 {
+			want[i][4] = nil
+		}
+	}
+	if !reflect.DeepEqual(f.rows, want) {
+		t.Errorf("rows\n%v\nwant\n%v", f.rows, want)
+	}
+	if f.meta[1] != int64(1) || f.meta[3] != int64(len(rows)) || f.meta[6] != "shootlog" {
+		t.Errorf("version %v, rows %v, created by %v", f.meta[1], f.meta[3], f.meta[6])
+	}
+	schema := f.meta[2].([]any)
+	wantSchema := []any{
+		map[int16]any{4: "schema", 5: int64(5)},
+		map[int16]any{1: int64(6), 3: int64(optional), 4: "path", 6: int64(convertedUTF8)},
+		map[int16]any{1: int64(2), 3: int64(optional), 4: "iso"},
+		map[int16]any{1: int64(5), 3: int64(optional), 4: "aperture"},
+		map[int16]any{1: int64(0), 3: int64(optional), 4: "flash"},
+		map[int16]any{1: int64(6), 3: int64(repeated), 4: "keywords", 6: int64(convertedUTF8)},
+	}
+	if !reflect.DeepEqual(schema, wantSchema) {
+		t.Errorf("schema\n%v\nwant\n%v", schema, wantSchema)
+	}
+	md := f.meta[4].([]any)[0].(map[int16]any)[1].([]any)[4].(map[int16]any)[3].(map[int16]any)
+	if md[1] != int64(6) || !reflect.DeepEqual(md[3], []any{"keywords"}) || md[4] != int64(0) || md[5] != int64(len(rows)+3) {
+		t.Errorf("keywords metadata %v", md)
+	}
+}
+
+func TestWriterRowGroups(t *testing.T) {
+	cols := []Column{{Name: "n", Type: Int64}, {Name: "even", Type: Boolean}}
+	var b bytes.Buffer
+	w := NewWriter(&b, cols)
+	n := 2*RowGroupSize + 3
+	for i := 0; i < n; i++ {
+		var v any
+		if i%7 != 0 {
+			v = int64(i)
+		}
+		if err := w.Write([]any{v, i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f := readFile(t, b.Bytes(), cols)
+	groups := f.meta[4].([]any)
+	if len(groups) != 3 || f.meta[3] != int64(n) {
+		t.Fatalf("%d row groups of %v rows, want 3 of %d", len(groups), f.meta[3], n)
+	}
+	for i, g := range groups {
+		want := int64(RowGroupSize)
+		if i == 2 {
+			want = 3
+		}
+		if rows := g.(map[int16]any)[3]; rows != want {
+			t.Errorf("row group %d has %v rows, want %d", i, rows, want)
+		}
+	}
+	for i, row := range f.rows {
+		var v any
+		if i%7 != 0 {
+			v = int64(i)
+		}
+		if row[0] != v || row[1] != (i%2 == 0) {
+			t.Fatalf("row %d is %v", i, row)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := NewWriter(&b, testColumns).Close(); err != nil {
+		t.Fatal(err)
+	}
+	f := readFile(t, b.Bytes(), testColumns)
+	if f.meta[3] != int64(0) || len(f.meta[4].([]any)) != 0 || len(f.meta[2].([]any)) != len(testColumns)+1 {
+		t.Errorf("metadata %v", f.meta)
+	}
+}
+
+// failWriter fails once it has taken n bytes.
+type failWriter struct{ n int }
+
+var errDisk = errors.New("disk full")
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return w.n, errDisk
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriterErrors(t *testing.T) {
+	row := []any{"a.jpg", int64(1), 1.0, true, nil}
+	tests := []struct {
+		name string
+		row  []any
+		err  string
+	}{
+		{"short row", []any{"a.jpg"}, "row has 1 values for 5 columns"},
+		{"wrong type", []any{"a.jpg", 1, nil, nil, nil}, "column iso: int value"},
+		{"wrong bool", []any{"a.jpg", nil, nil, "yes", nil}, "column flash: string value"},
+		{"not a list", []any{"a.jpg", nil, nil, nil, "sea"}, "column keywords: string is not a list"},
+		{"wrong element", []any{"a.jpg", nil, nil, nil, []any{"a", 1.5}}, "column keywords: float64 value"},
+		{"null element", []any{"a.jpg", nil, nil, nil, []any{"a", nil}}, "column keywords: null list element"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(&bytes.Buffer{}, testColumns)
+			if err := w.Write(row); err != nil {
+				t.Fatal(err)
+			}
+			err := w.Write(tt.row)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want %q", err, tt.err)
+			}
+			if len(tt.row) == len(testColumns) {
+				// A row that failed part way fails the writer.
+				if err2 := w.Write(row); err2 != err {
+					t.Errorf("next Write: %v, want %v", err2, err)
+				}
+				if err2 := w.Close(); err2 != err {
+					t.Errorf("Close: %v, want %v", err2, err)
+				}
+			}
+		})
+	}
+
+	w := NewWriter(&bytes.Buffer{}, testColumns)
+	w.Close()
+	if err := w.Write(row); err != errClosed {
+		t.Errorf("Write after Close: %v, want %v", err, errClosed)
+	}
+	if err := w.Close(); err != errClosed {
+		t.Errorf("Close after Close: %v, want %v", err, errClosed)
+	}
+
+	for _, n := range []int{0, 3, 40, 200} {
+		w := NewWriter(&failWriter{n}, testColumns)
+		w.Write(row)
+		if err := w.Close(); err != errDisk {
+			t.Errorf("writer failing after %d bytes: Close returned %v, want %v", n, err, errDisk)
+		}
+	}
+}
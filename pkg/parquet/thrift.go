@@ -0,0 +1,95 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol types, as used in Parquet page headers and file
+// metadata.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// compact encodes Thrift structs in the compact protocol. Field IDs are
+// written as deltas from the previous field of the same struct, so each
+// struct keeps its own last ID.
+type compact struct {
+	buf  []byte
+	last []int16
+}
+
+func newCompact() *compact {
+	return &compact{last: []int16{0}}
+}
+
+func (c *compact) field(id int16, typ byte) {
+	top := len(c.last) - 1
+	if d := id - c.last[top]; d > 0 && d <= 15 {
+		c.buf = append(c.buf, byte(d)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.buf = binary.AppendUvarint(c.buf, zigzag(int64(id)))
+	}
+	c.last[top] = id
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, tI32)
+	c.buf = binary.AppendUvarint(c.buf, zigzag(int64(v)))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, tI64)
+	c.buf = binary.AppendUvarint(c.buf, zigzag(v))
+}
+
+func (c *compact) binary(id int16, b string) {
+	c.field(id, tBinary)
+	c.str(b)
+}
+
+// str writes a string list element, or the value of a binary field.
+func (c *compact) str(b string) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(len(b)))
+	c.buf = append(c.buf, b...)
+}
+
+// list starts a list field of n elements of typ, which follow as bare
+// values or structs opened with begin.
+func (c *compact) list(id int16, typ byte, n int) {
+	c.field(id, tList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|typ)
+		return
+	}
+	c.buf = append(c.buf, 0xF0|typ)
+	c.buf = binary.AppendUvarint(c.buf, uint64(n))
+}
+
+// elemI32 writes an i32 list element.
+func (c *compact) elemI32(v int32) {
+	c.buf = binary.AppendUvarint(c.buf, zigzag(int64(v)))
+}
+
+// structField opens a struct field, closed with end.
+func (c *compact) structField(id int16) {
+	c.field(id, tStruct)
+	c.begin()
+}
+
+// begin opens a struct list element, closed with end.
+func (c *compact) begin() {
+	c.last = append(c.last, 0)
+}
+
+// end closes the innermost struct, or the top-level one.
+func (c *compact) end() {
+	c.buf = append(c.buf, 0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,190 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// thriftReader decodes the Thrift compact protocol into generic values,
+// independently of the encoder: structs become map[int16]any, lists
+// []any, integers int64, binaries string and booleans bool.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+var errThrift = errors.New("malformed thrift")
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errThrift
+	}
+	r.pos++
+	return r.b[r.pos-1], nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	u, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, errThrift
+	}
+	r.pos += n
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (r *thriftReader) structure() (map[int16]any, error) {
+	out := map[int16]any{}
+	var last int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return out, nil
+		}
+		typ, delta := b&0x0F, int16(b>>4)
+		id := last + delta
+		if delta == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+		var v any
+		switch typ {
+		case 1, 2:
+			v = typ == 1
+		default:
+			if v, err = r.value(typ); err != nil {
+				return nil, err
+			}
+		}
+		if _, dup := out[id]; dup {
+			return nil, fmt.Errorf("%w: field %d twice", errThrift, id)
+		}
+		out[id] = v
+	}
+}
+
+func (r *thriftReader) value(typ byte) (any, error) {
+	switch typ {
+	case 1, 2: // list element booleans
+		b, err := r.byte()
+		return b == 1, err
+	case 3:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case 4, 5, 6:
+		return r.varint()
+	case 7:
+		if r.pos+8 > len(r.b) {
+			return nil, errThrift
+		}
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos-8:])), nil
+	case 8:
+		n, k := binary.Uvarint(r.b[r.pos:])
+		if k <= 0 || n > uint64(len(r.b)-r.pos-k) {
+			return nil, errThrift
+		}
+		r.pos += k + int(n)
+		return string(r.b[r.pos-int(n) : r.pos]), nil
+	case 9, 10:
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			var k int
+			if n, k = binary.Uvarint(r.b[r.pos:]); k <= 0 {
+				return nil, errThrift
+			}
+			r.pos += k
+		}
+		out := []any{}
+		for i := uint64(0); i < n; i++ {
+			v, err := r.value(h & 0x0F)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case 12:
+		return r.structure()
+	}
+	return nil, fmt.Errorf("%w: type %d", errThrift, typ)
+}
+
+func TestCompact(t *testing.T) {
+	c := newCompact()
+	c.i32(1, -1)
+	c.i64(20, 300) // a jump past 15 takes the long form
+	c.structField(21)
+	c.binary(1, "ab") // field IDs restart inside the struct
+	c.end()
+	c.list(22, tI32, 15) // 15 elements take the long form
+	for i := 0; i < 15; i++ {
+		c.elemI32(int32(i - 7))
+	}
+	c.i32(23, 1)
+	c.i32(2, 0) // a step back takes the long form
+	c.list(24, tBinary, 2)
+	c.str("x")
+	c.str("")
+	c.list(25, tStruct, 1)
+	c.begin()
+	c.i64(3, math.MinInt64)
+	c.end()
+	c.end()
+
+	want := "15 01" + " 06 28 d804" + " 1c 18 02 6162 00" +
+		" 19 f5 0f 0d0b09070503010002040608 0a0c0e" + " 15 02" + " 05 04 00" +
+		" 09 30 28 0178 00" + " 19 1c 36 ffffffffffffffffff01 00" + " 00"
+	wantBytes, _ := hex.DecodeString(strings.ReplaceAll(want, " ", ""))
+	if !bytes.Equal(c.buf, wantBytes) {
+		t.Errorf("encoded\n%x\nwant\n%x", c.buf, wantBytes)
+	}
+
+	r := &thriftReader{b: c.buf}
+	got, err := r.structure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []any{}
+	for i := 0; i < 15; i++ {
+		list = append(list, int64(i-7))
+	}
+	wantValues := map[int16]any{
+		1: int64(-1), 20: int64(300), 21: map[int16]any{1: "ab"}, 22: list, 23: int64(1), 2: int64(0),
+		24: []any{"x", ""}, 25: []any{map[int16]any{3: int64(math.MinInt64)}},
+	}
+	if !reflect.DeepEqual(got, wantValues) || r.pos != len(c.buf) {
+		t.Errorf("decoded %v, want %v", got, wantValues)
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want uint64
+	}{
+		{0, 0}, {-1, 1}, {1, 2}, {-2, 3}, {math.MaxInt64, math.MaxUint64 - 1}, {math.MinInt64, math.MaxUint64},
+	}
+	for _, tt := range tests {
+		if got := zigzag(tt.in); got != tt.want {
+			t.Errorf("zigzag(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
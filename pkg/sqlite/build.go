@@ -0,0 +1,341 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// pageSize is the page size of the databases Build writes.
+const pageSize = 4096
+
+// NewTable is a table for Build to write.
+type NewTable struct {
+	Name string
+	// Columns are the column definitions of the CREATE TABLE statement,
+	// such as "path TEXT NOT NULL". Constraints that need an index, such
+	// as UNIQUE or a PRIMARY KEY other than INTEGER PRIMARY KEY, are not
+	// supported, as Build writes no indexes.
+	Columns []string
+	// Rows hold nil, int64, float64, string or []byte values, in column
+	// order. Rows get rowids from 1 in order, unless the table has an
+	// INTEGER PRIMARY KEY column, whose int64 values become the rowids.
+	Rows [][]any
+}
+
+// Build returns a database file holding tables, as a whole: databases are
+// written at once, the way shootlog writes its other files, rather than
+// changed in place. userVersion is stored where PRAGMA user_version reads
+// it, for the version of the application's schema.
+func Build(userVersion int32, tables ...NewTable) ([]byte, error) {
+	b := &builder{pages: [][]byte{nil}} // page 1 is filled in last
+	var schema [][]any
+	for _, t := range tables {
+		root, err := b.table(t)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: table %s: %w", t.Name, err)
+		}
+		sql := "CREATE TABLE " + t.Name + " (" + strings.Join(t.Columns, ", ") + ")"
+		schema = append(schema, []any{"table", t.Name, t.Name, int64(root), sql})
+	}
+	var cells [][]byte
+	for i, r := range schema {
+		cell, err := b.leafCell(int64(i+1), encodeRecord(r), 100)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: schema: %w", err)
+		}
+		cells = append(cells, cell)
+	}
+	first, rest := b.fill(cells, 100)
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("sqlite: schema of %d tables does not fit the first page", len(tables))
+	}
+	b.pages[0] = first
+
+	out := make([]byte, 0, len(b.pages)*pageSize)
+	for _, p := range b.pages {
+		out = append(out, p...)
+	}
+	h := out[:100]
+	copy(h, magic)
+	binary.BigEndian.PutUint16(h[16:], pageSize)
+	h[18], h[19] = 1, 1 // legacy journal, not WAL
+	h[21], h[22], h[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(h[24:], 1) // change counter
+	binary.BigEndian.PutUint32(h[28:], uint32(len(b.pages)))
+	binary.BigEndian.PutUint32(h[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4) // schema format
+	binary.BigEndian.PutUint32(h[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(h[60:], uint32(userVersion))
+	binary.BigEndian.PutUint32(h[92:], 1)       // version-valid-for
+	binary.BigEndian.PutUint32(h[96:], 3045000) // SQLite version the format matches
+	return out, nil
+}
+
+// UserVersion returns the user_version of the database header.
+func (db *DB) UserVersion() int32 {
+	return int32(binary.BigEndian.Uint32(db.data[60:]))
+}
+
+// builder accumulates the pages of a database, page n at pages[n-1].
+type builder struct {
+	pages [][]byte
+}
+
+// add appends page p and returns its number.
+func (b *builder) add(p []byte) int {
+	b.pages = append(b.pages, p)
+	return len(b.pages)
+}
+
+// table writes the b-tree of t and returns its root page.
+func (b *builder) table(t NewTable) (int, error) {
+	_, rowidColumn := columns("(" + strings.Join(t.Columns, ", ") + ")")
+	type leaf struct {
+		rowid int64
+		cell  []byte
+	}
+	leaves := make([]leaf, 0, len(t.Rows))
+	for i, r := range t.Rows {
+		if len(r) != len(t.Columns) {
+			return 0, fmt.Errorf("row %d has %d values for %d columns", i, len(r), len(t.Columns))
+		}
+		rowid := int64(i + 1)
+		if rowidColumn >= 0 {
+			id, ok := r[rowidColumn].(int64)
+			if !ok {
+				return 0, fmt.Errorf("row %d: INTEGER PRIMARY KEY %v is not an int64", i, r[rowidColumn])
+			}
+			rowid = id
+			r = append([]any(nil), r...)
+			r[rowidColumn] = nil // stored as the rowid
+		}
+		for _, v := range r {
+			switch v.(type) {
+			case nil, int64, float64, string, []byte:
+			default:
+				return 0, fmt.Errorf("row %d: unsupported value type %T", i, v)
+			}
+		}
+		cell, err := b.leafCell(rowid, encodeRecord(r), 0)
+		if err != nil {
+			return 0, err
+		}
+		leaves = append(leaves, leaf{rowid, cell})
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return leaves[i].rowid < leaves[j].rowid })
+	for i := 1; i < len(leaves); i++ {
+		if leaves[i].rowid == leaves[i-1].rowid {
+			return 0, fmt.Errorf("duplicate rowid %d", leaves[i].rowid)
+		}
+	}
+
+	// The leaves, then levels of interior pages over them until one page
+	// is left: the root.
+	type child struct {
+		page   int
+		maxKey int64
+	}
+	var level []child
+	for start := 0; start < len(leaves) || len(level) == 0; {
+		cells := make([][]byte, 0, len(leaves)-start)
+		for _, l := range leaves[start:] {
+			cells = append(cells, l.cell)
+		}
+		p, rest := b.fill(cells, 0)
+		n := len(cells) - len(rest)
+		start += n
+		var maxKey int64
+		if n > 0 {
+			maxKey = leaves[start-1].rowid
+		}
+		level = append(level, child{b.add(p), maxKey})
+	}
+	for len(level) > 1 {
+		var up []child
+		for len(level) > 0 {
+			// Every child but the last of a page gets a cell; the last is
+			// its right-most pointer. No page is left a single child.
+			n, used := 0, 0
+			for n+1 < len(level) {
+				size := 4 + len(appendVarint(nil, level[n].maxKey))
+				if 12+2*(n+1)+used+size > pageSize {
+					break
+				}
+				used += size
+				n++
+			}
+			if len(level)-n-1 == 1 {
+				n--
+			}
+			p := make([]byte, pageSize)
+			p[0] = pageInterior
+			content := pageSize
+			for i, c := range level[:n] {
+				cell := binary.BigEndian.AppendUint32(nil, uint32(c.page))
+				cell = appendVarint(cell, c.maxKey)
+				content -= len(cell)
+				copy(p[content:], cell)
+				binary.BigEndian.PutUint16(p[12+2*i:], uint16(content))
+			}
+			binary.BigEndian.PutUint16(p[3:], uint16(n))
+			binary.BigEndian.PutUint16(p[5:], uint16(content))
+			binary.BigEndian.PutUint32(p[8:], uint32(level[n].page))
+			up = append(up, child{b.add(p), level[n].maxKey})
+			level = level[n+1:]
+		}
+		level = up
+	}
+	return level[0].page, nil
+}
+
+// fill lays out a leaf page holding as many of cells as fit, its header
+// at hdr, and returns it with the cells that did not fit.
+func (b *builder) fill(cells [][]byte, hdr int) ([]byte, [][]byte) {
+	p := make([]byte, pageSize)
+	p[hdr] = pageLeaf
+	content := pageSize
+	n := 0
+	for ; n < len(cells); n++ {
+		if content-len(cells[n]) < hdr+8+2*(n+1) {
+			break
+		}
+		content -= len(cells[n])
+		copy(p[content:], cells[n])
+		binary.BigEndian.PutUint16(p[hdr+8+2*n:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(p[hdr+3:], uint16(n))
+	binary.BigEndian.PutUint16(p[hdr+5:], uint16(content))
+	return p, cells[n:]
+}
+
+// leafCell returns the table leaf cell of payload under rowid, writing
+// what does not fit on the page, as DB.payload reads it, to overflow
+// pages. hdr is where the b-tree header of the cell's page starts: 100 on
+// page 1, after the database header, and 0 elsewhere.
+func (b *builder) leafCell(rowid int64, payload []byte, hdr int) ([]byte, error) {
+	u := pageSize
+	x := u - 35
+	local := len(payload)
+	if local > x {
+		m := (u-12)*32/255 - 23
+		k := m + (len(payload)-m)%(u-4)
+		local = m
+		if k <= x {
+			local = k
+		}
+	}
+	cell := appendVarint(nil, int64(len(payload)))
+	cell = appendVarint(cell, rowid)
+	cell = append(cell, payload[:local]...)
+	if len(cell)+4 > pageSize-hdr-8-2 {
+		return nil, fmt.Errorf("cell of %d bytes does not fit a page", len(cell))
+	}
+	if local == len(payload) {
+		return cell, nil
+	}
+	// Overflow pages follow each other, each starting with the number
+	// of the next, or 0.
+	first := len(b.pages) + 1
+	for rest := payload[local:]; len(rest) > 0; {
+		n := min(len(rest), u-4)
+		p := make([]byte, pageSize)
+		if n < len(rest) {
+			binary.BigEndian.PutUint32(p, uint32(len(b.pages)+2))
+		}
+		copy(p[4:], rest[:n])
+		b.add(p)
+		rest = rest[n:]
+	}
+	return binary.BigEndian.AppendUint32(cell, uint32(first)), nil
+}
+
+// encodeRecord returns the record format of values.
+func encodeRecord(values []any) []byte {
+	var types, body []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			switch {
+			case v == 0:
+				types = appendVarint(types, 8)
+			case v == 1:
+				types = appendVarint(types, 9)
+			default:
+				st, size := intSerial(v)
+				types = appendVarint(types, st)
+				for i := size - 1; i >= 0; i-- {
+					body = append(body, byte(v>>(8*i)))
+				}
+			}
+		case float64:
+			types = appendVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendVarint(types, int64(13+2*len(v)))
+			body = append(body, v...)
+		case []byte:
+			types = appendVarint(types, int64(12+2*len(v)))
+			body = append(body, v...)
+		}
+	}
+	// The header size counts its own varint.
+	hdrLen := len(types) + 1
+	for len(appendVarint(nil, int64(hdrLen)))+len(types) != hdrLen {
+		hdrLen++
+	}
+	out := appendVarint(make([]byte, 0, hdrLen+len(body)), int64(hdrLen))
+	return append(append(out, types...), body...)
+}
+
+// intSerial returns the serial type and size of the smallest integer
+// encoding of v.
+func intSerial(v int64) (int64, int) {
+	for _, s := range []struct {
+		st   int64
+		size int
+	}{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 6}} {
+		if lim := int64(1) << (8*s.size - 1); v >= -lim && v < lim {
+			return s.st, s.size
+		}
+	}
+	return 6, 8
+}
+
+// appendVarint appends the SQLite variable-length integer of v.
+func appendVarint(b []byte, v int64) []byte {
+	u := uint64(v)
+	if u > 0x00FFFFFFFFFFFFFF {
+		// Nine bytes: eight of seven bits and a last one of eight.
+		var buf [9]byte
+		buf[8] = byte(u)
+		u >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(u&0x7f) | 0x80
+			u >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [9]byte
+	n := 0
+	for {
+		buf[n] = byte(u & 0x7f)
+		n++
+		u >>= 7
+		if u == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := buf[i]
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
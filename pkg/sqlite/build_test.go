@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildRoundTrip(t *testing.T) {
+	many := make([][]any, 5000)
+	for i := range many {
+		many[i] = []any{fmt.Sprintf("/photos/%05d.jpg", i), int64(i * 1000), float64(i) / 3}
+	}
+	tests := []struct {
+		name  string
+		table NewTable
+	}{
+		{"empty", NewTable{Name: "files", Columns: []string{"path TEXT"}}},
+		{"types", NewTable{Name: "kinds", Columns: []string{"a", "b", "c", "d", "e"}, Rows: [][]any{
+			{nil, int64(0), int64(1), int64(-1), int64(1 << 40)},
+			{int64(-1 << 63), int64(127), int64(128), int64(-129), int64(1<<63 - 1)},
+			{"テキスト", []byte{0, 1, 2}, 3.25, "", []byte{}},
+		}}},
+		{"overflow", NewTable{Name: "big", Columns: []string{"name TEXT", "data BLOB"}, Rows: [][]any{
+			{"small", bytes.Repeat([]byte{1}, 4000)},
+			{"spans pages", bytes.Repeat([]byte("0123456789"), 2000)},
+			{"exact", bytes.Repeat([]byte{2}, 4061)},
+		}}},
+		{"interior pages", NewTable{Name: "many", Columns: []string{"path TEXT", "size INTEGER", "ratio REAL"}, Rows: many}},
+		{"integer primary key", NewTable{Name: "keyed", Columns: []string{"id INTEGER PRIMARY KEY", "name TEXT"}, Rows: [][]any{
+			{int64(30), "c"}, {int64(-5), "a"}, {int64(7), "b"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Build(7, tt.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := db.UserVersion(); v != 7 {
+				t.Errorf("user_version %d, want 7", v)
+			}
+			tb := db.Table(tt.table.Name)
+			if tb == nil {
+				t.Fatalf("no table %s in %v", tt.table.Name, db.Tables())
+			}
+			var names []string
+			for _, c := range tt.table.Columns {
+				names = append(names, strings.Fields(c)[0])
+			}
+			if !reflect.DeepEqual(tb.Columns, names) {
+				t.Errorf("columns %v, want %v", tb.Columns, names)
+			}
+			var got [][]any
+			var ids []int64
+			if err := tb.Rows(func(r Row) error {
+				got = append(got, r.Values)
+				ids = append(ids, r.RowID)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.table.Rows
+			if tb.rowidColumn >= 0 {
+				want = [][]any{{int64(-5), "a"}, {int64(7), "b"}, {int64(30), "c"}}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%d rows, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(got[i], want[i]) {
+					t.Errorf("row %d (rowid %d): got %v, want %v", i, ids[i], got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table NewTable
+		want  string
+	}{
+		{"short row", NewTable{Name: "t", Columns: []string{"a", "b"}, Rows: [][]any{{int64(1)}}}, "1 values for 2 columns"},
+		{"value type", NewTable{Name: "t", Columns: []string{"a"}, Rows: [][]any{{3}}}, "unsupported value type int"},
+		{"duplicate key", NewTable{Name: "t", Columns: []string{"id INTEGER PRIMARY KEY"}, Rows: [][]any{{int64(1)}, {int64(1)}}}, "duplicate rowid 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(0, tt.table)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+// Package sqlite reads tables from SQLite 3 database files without cgo or
+// a database driver: it walks the table b-trees of the file format
+// directly. It ignores indexes and supports UTF-8 databases only, which
+// covers the catalogs of the photo applications shootlog imports from.
+// Changes still held in a write-ahead log are not seen, so databases must
+// be checkpointed, usually by closing the application.
+//
+// Build writes new databases of plain tables, such as shootlog's own
+// library index; it does not change existing ones.
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrFormat is returned for files that are not valid SQLite databases.
+var ErrFormat = errors.New("sqlite: invalid database")
+
+// ErrWAL is returned by Open when the database has an active write-ahead
+// log whose changes would be missed.
+var ErrWAL = errors.New("sqlite: database has unsaved changes in its write-ahead log; close the application using it")
+
+const magic = "SQLite format 3\x00"
+
+// Page types of table b-trees.
+const (
+	pageInterior = 0x05
+	pageLeaf     = 0x0d
+)
+
+// DB is a database read into memory.
+type DB struct {
+	data     []byte
+	pageSize int
+	usable   int
+	tables   map[string]*Table
+}
+
+// Table is one table of a database.
+type Table struct {
+	Name string
+	// Columns are the column names in declaration order.
+	Columns []string
+
+	db   *DB
+	root int
+	// rowidColumn is the INTEGER PRIMARY KEY column, stored as the rowid,
+	// or -1.
+	rowidColumn int
+}
+
+// Row is one record. Values are nil, int64, float64, string or []byte.
+type Row struct {
+	RowID  int64
+	Values []any
+	table  *Table
+}
+
+// Open reads the database at path.
+func Open(path string) (*DB, error) {
+	if fi, err := os.Stat(path + "-wal"); err == nil && fi.Size() > 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrWAL)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	db, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Parse reads a database held in data.
+func Parse(data []byte) (*DB, error) {
+	if len(data) < 100 || string(data[:16]) != magic {
+		return nil, fmt.Errorf("%w: bad header", ErrFormat)
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("%w: page size %d", ErrFormat, pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(data[56:]); enc > 1 {
+		return nil, fmt.Errorf("sqlite: text encoding %d not supported (want UTF-8)", enc)
+	}
+	db := &DB{data: data, pageSize: pageSize, usable: pageSize - int(data[20]), tables: map[string]*Table{}}
+	master := &Table{Name: "sqlite_master", Columns: []string{"type", "name", "tbl_name", "rootpage", "sql"}, db: db, root: 1, rowidColumn: -1}
+	err := master.Rows(func(r Row) error {
+		if r.String("type") != "table" {
+			return nil
+		}
+		cols, rowid := columns(r.String("sql"))
+		name := r.String("name")
+		db.tables[strings.ToLower(name)] = &Table{Name: name, Columns: cols, db: db, root: int(r.Int("rootpage")), rowidColumn: rowid}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Table returns the named table, matched case-insensitively as SQL does,
+// or nil.
+func (db *DB) Table(name string) *Table {
+	return db.tables[strings.ToLower(name)]
+}
+
+// Tables returns the names of all tables, sorted.
+func (db *DB) Tables() []string {
+	names := make([]string, 0, len(db.tables))
+	for _, t := range db.tables {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// page returns page n, 1-based.
+func (db *DB) page(n int) ([]byte, error) {
+	if n < 1 || n*db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("%w: page %d out of range", ErrFormat, n)
+	}
+	return db.data[(n-1)*db.pageSize : n*db.pageSize], nil
+}
+
+// Column returns the index of the named column, matched
+// case-insensitively, or -1.
+func (t *Table) Column(name string) int {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rows calls fn for every row in rowid order and stops at the first error.
+func (t *Table) Rows(fn func(Row) error) error {
+	return t.walk(t.root, fn, 0)
+}
+
+// maxDepth bounds b-tree recursion so corrupt page loops terminate.
+const maxDepth = 64
+
+func (t *Table) walk(n int, fn func(Row) error, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: b-tree too deep", ErrFormat)
+	}
+	p, err := t.db.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	if len(p) < hdr+8 {
+		return fmt.Errorf("%w: page %d truncated", ErrFormat, n)
+	}
+	kind := p[hdr]
+	cells := int(binary.BigEndian.Uint16(p[hdr+3:]))
+	ptrs := hdr + 8
+	if kind == pageInterior {
+		ptrs = hdr + 12
+	}
+	if ptrs+2*cells > len(p) {
+		return fmt.Errorf("%w: page %d cell pointers out of range", ErrFormat, n)
+	}
+	for i := 0; i < cells; i++ {
+		off := int(binary.BigEndian.Uint16(p[ptrs+2*i:]))
+		if off >= len(p) {
+			return fmt.Errorf("%w: page %d cell %d out of range", ErrFormat, n, i)
+		}
+		switch kind {
+		case pageInterior:
+			if off+4 > len(p) {
+				return fmt.Errorf("%w: page %d cell %d truncated", ErrFormat, n, i)
+			}
+			if err := t.walk(int(binary.BigEndian.Uint32(p[off:])), fn, depth+1); err != nil {
+				return err
+			}
+		case pageLeaf:
+			row, err := t.leafCell(p, off)
+			if err != nil {
+				return fmt.Errorf("page %d cell %d: %w", n, i, err)
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: page %d has type %#x, not a table page", ErrFormat, n, kind)
+		}
+	}
+	if kind == pageInterior {
+		return t.walk(int(binary.BigEndian.Uint32(p[hdr+8:])), fn, depth+1)
+	}
+	return nil
+}
+
+// leafCell decodes the table leaf cell at off of page p.
+func (t *Table) leafCell(p []byte, off int) (Row, error) {
+	size, n := varint(p[off:])
+	off += n
+	rowid, n := varint(p[off:])
+	off += n
+	if n == 0 || size < 0 {
+		return Row{}, fmt.Errorf("%w: bad cell header", ErrFormat)
+	}
+	payload, err := t.db.payload(p, off, int(size))
+	if err != nil {
+		return Row{}, err
+	}
+	values, err := record(payload)
+	if err != nil {
+		return Row{}, err
+	}
+	if t.rowidColumn >= 0 {
+		for len(values) <= t.rowidColumn {
+			values = append(values, nil)
+		}
+		values[t.rowidColumn] = rowid
+	}
+	return Row{RowID: rowid, Values: values, table: t}, nil
+}
+
+// payload assembles a cell payload of size bytes starting at off, following
+// overflow pages when it does not fit on the page.
+func (db *DB) payload(p []byte, off, size int) ([]byte, error) {
+	u := db.usable
+	x := u - 35
+	local := size
+	if size > x {
+		m := (u-12)*32/255 - 23
+		k := m + (size-m)%(u-4)
+		local = m
+		if k <= x {
+			local = k
+		}
+	}
+	if off+local > len(p) {
+		return nil, fmt.Errorf("%w: cell exceeds page", ErrFormat)
+	}
+	if local == size {
+		return p[off : off+size], nil
+	}
+	if off+local+4 > len(p) {
+		return nil, fmt.Errorf("%w: cell exceeds page", ErrFormat)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, p[off:off+local]...)
+	next := int(binary.BigEndian.Uint32(p[off+local:]))
+	for pages := 0; len(out) < size; pages++ {
+		if next == 0 || pages > len(db.data)/db.pageSize {
+			return nil, fmt.Errorf("%w: overflow chain ends early", ErrFormat)
+		}
+		op, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		n := min(size-len(out), u-4)
+		out = append(out, op[4:4+n]...)
+		next = int(binary.BigEndian.Uint32(op))
+	}
+	return out, nil
+}
+
+// varint decodes a SQLite variable-length integer and returns it with its
+// length, or a length of 0 when b is too short.
+func varint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	return 0, 0
+}
+
+// record decodes a record: a header of serial types followed by values.
+func record(b []byte) ([]any, error) {
+	hdrLen, n := varint(b)
+	if n == 0 || hdrLen < int64(n) || hdrLen > int64(len(b)) {
+		return nil, fmt.Errorf("%w: bad record header", ErrFormat)
+	}
+	var types []int64
+	for pos := n; pos < int(hdrLen); {
+		st, n := varint(b[pos:int(hdrLen)])
+		if n == 0 {
+			return nil, fmt.Errorf("%w: bad record header", ErrFormat)
+		}
+		types = append(types, st)
+		pos += n
+	}
+	values := make([]any, len(types))
+	body := b[hdrLen:]
+	for i, st := range types {
+		size := serialSize(st)
+		if size > len(body) {
+			return nil, fmt.Errorf("%w: record value exceeds payload", ErrFormat)
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case st == 0:
+			values[i] = nil
+		case st >= 1 && st <= 6:
+			// Big-endian two's complement of 1-8 bytes.
+			x := int64(int8(v[0]))
+			for _, c := range v[1:] {
+				x = x<<8 | int64(c)
+			}
+			values[i] = x
+		case st == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(v))
+		case st == 8:
+			values[i] = int64(0)
+		case st == 9:
+			values[i] = int64(1)
+		case st >= 12 && st%2 == 0:
+			values[i] = bytes.Clone(v)
+		case st >= 13:
+			values[i] = string(v)
+		default:
+			return nil, fmt.Errorf("%w: reserved serial type %d", ErrFormat, st)
+		}
+	}
+	return values, nil
+}
+
+func serialSize(st int64) int {
+	switch {
+	case st >= 1 && st <= 4:
+		return int(st)
+	case st == 5:
+		return 6
+	case st == 6 || st == 7:
+		return 8
+	case st >= 12:
+		return int((st - 12) / 2)
+	}
+	return 0
+}
+
+// columns extracts the column names from a CREATE TABLE statement, and
+// the index of an INTEGER PRIMARY KEY column, which aliases the rowid.
+func columns(sql string) ([]string, int) {
+	open, end := strings.IndexByte(sql, '('), strings.LastIndexByte(sql, ')')
+	if open < 0 || end < open {
+		return nil, -1
+	}
+	var defs []string
+	depth, start := 0, open+1
+	var quote byte
+	for i := open + 1; i < end; i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, sql[start:i])
+			start = i + 1
+		}
+	}
+	defs = append(defs, sql[start:end])
+
+	var cols []string
+	rowid := -1
+	for _, d := range defs {
+		d = strings.TrimSpace(d)
+		name, rest := splitName(d)
+		switch strings.ToUpper(name) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			if name == d[:len(name)] {
+				// An unquoted keyword starts a table constraint, not a
+				// column.
+				continue
+			}
+		}
+		if strings.HasPrefix(strings.ToUpper(strings.Join(strings.Fields(rest), " ")), "INTEGER PRIMARY KEY") {
+			rowid = len(cols)
+		}
+		cols = append(cols, name)
+	}
+	return cols, rowid
+}
+
+// splitName splits the leading, possibly quoted, identifier off def.
+func splitName(def string) (name, rest string) {
+	if def == "" {
+		return "", ""
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[def[0]]
+	if closing != 0 {
+		if i := strings.IndexByte(def[1:], closing); i >= 0 {
+			return def[1 : i+1], def[i+2:]
+		}
+	}
+	if i := strings.IndexAny(def, " \t\n\r("); i >= 0 {
+		return def[:i], def[i:]
+	}
+	return def, ""
+}
+
+// Value returns the named column, or nil when the table or this row lacks
+// it.
+func (r Row) Value(column string) any {
+	i := r.table.Column(column)
+	if i < 0 || i >= len(r.Values) {
+		return nil
+	}
+	return r.Values[i]
+}
+
+// String returns the named column as text: strings and blobs as is,
+// numbers formatted, NULL as "".
+func (r Row) String(column string) string {
+	switch v := r.Value(column).(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Int returns the named column as an integer, or 0.
+func (r Row) Int(column string) int64 {
+	switch v := r.Value(column).(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// Float returns the named column as a float, reporting whether it holds
+// a number.
+func (r Row) Float(column string) (float64, bool) {
+	switch v := r.Value(column).(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
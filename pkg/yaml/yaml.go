@@ -0,0 +1,481 @@
+// Package yaml parses the subset of YAML used by shootlog configuration
+// and log files: block mappings and sequences, flow sequences of scalars,
+// literal and folded block scalars, quoted and plain scalars and comments.
+// Anchors, tags, multiple documents and flow mappings are not supported.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error reports a parse error at a 1-based line number.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("yaml: line %d: %s", e.Line, e.Msg)
+}
+
+// Unmarshal parses data and stores the result in v, which is decoded
+// through encoding/json so that struct fields are matched by their json
+// tags.
+func Unmarshal(data []byte, v any) error {
+	doc, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// UnmarshalStrict is like Unmarshal but rejects mapping keys that match no
+// struct field, so misspelled options are reported instead of ignored.
+func UnmarshalStrict(data []byte, v any) error {
+	doc, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		// Errors name JSON; the user wrote YAML.
+		return errors.New("yaml: " + strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// Parse parses data into map[string]any, []any, string, int64, float64,
+// bool and nil values.
+func Parse(data []byte) (any, error) {
+	p := &parser{}
+	if err := p.split(string(data)); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+// line is a non-blank source line with its comment removed.
+type line struct {
+	num    int
+	indent int
+	text   string
+	// raw keeps the line as written, for block scalars.
+	raw string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	n := 0
+	if p.pos < len(p.lines) {
+		n = p.lines[p.pos].num
+	} else if len(p.lines) > 0 {
+		n = p.lines[len(p.lines)-1].num
+	}
+	return &Error{Line: n, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) split(src string) error {
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+		if strings.HasPrefix(text, "\t") {
+			return &Error{Line: i + 1, Msg: "tabs are not allowed for indentation"}
+		}
+		text = strings.TrimRight(stripComment(text), " \t")
+		if text == "" || text == "---" {
+			// Blank lines are kept for block scalars only.
+			p.lines = append(p.lines, line{num: i + 1, indent: -1, raw: raw})
+			continue
+		}
+		if text == "..." {
+			break
+		}
+		p.lines = append(p.lines, line{num: i + 1, indent: indent, text: text, raw: raw})
+	}
+	// Drop blank lines; block scalars re-read them through raw.
+	kept := p.lines[:0]
+	for _, l := range p.lines {
+		if l.indent >= 0 {
+			kept = append(kept, l)
+		} else if len(kept) > 0 {
+			kept[len(kept)-1].raw += "\n"
+		}
+	}
+	p.lines = kept
+	return nil
+}
+
+// stripComment removes a trailing comment outside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' || c == '\'' && quote == c && i+1 < len(s) && s[i+1] == c {
+				// An escape, or '' standing for a quote in single quotes.
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || s[i-1] == ' ' || s[i-1] == '[' || s[i-1] == ',' || s[i-1] == ':' || s[i-1] == '-' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence starting at the current line.
+func (p *parser) block(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *parser) sequence(indent int) (any, error) {
+	out := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		if !isSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			v, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		if _, _, ok := splitKey(rest); ok || isSeqItem(rest) {
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.pos] = line{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest, raw: l.raw}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		v, err := p.scalarValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (p *parser) mapping(indent int) (any, error) {
+	out := map[string]any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		if isSeqItem(l.text) {
+			break
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf("expected \"key: value\", got %q", l.text)
+		}
+		if _, dup := out[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		if rest == "" {
+			p.pos++
+			v, err := p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+			continue
+		}
+		v, err := p.scalarValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// nested parses the value of a key or dash with nothing after it: a block
+// indented further, a sequence at the same indentation as a mapping key
+// (when key is set), or null.
+func (p *parser) nested(indent int, key bool) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	switch {
+	case next.indent > indent:
+		return p.block(next.indent)
+	case key && next.indent == indent && isSeqItem(next.text):
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+// scalarValue parses the inline value of the current line and advances
+// past it and, for block scalars, past their content.
+func (p *parser) scalarValue(s string, indent int) (any, error) {
+	p.pos++
+	switch {
+	case s == "|" || s == "|-" || s == ">" || s == ">-":
+		return p.blockScalar(s, indent), nil
+	case strings.HasPrefix(s, "["):
+		return p.flowSequence(s)
+	case s == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(s, "{"):
+		p.pos--
+		return nil, p.errorf("flow mappings are not supported")
+	case strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") || strings.HasPrefix(s, "!"):
+		p.pos--
+		return nil, p.errorf("anchors, aliases and tags are not supported")
+	}
+	v, err := scalar(s)
+	if err != nil {
+		p.pos--
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+func (p *parser) blockScalar(style string, indent int) string {
+	var lines []string
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		lines = append(lines, p.lines[p.pos].raw)
+		p.pos++
+	}
+	text := strings.Join(lines, "\n")
+	// Strip the indentation of the first content line from every line.
+	if len(lines) > 0 {
+		first := lines[0]
+		n := len(first) - len(strings.TrimLeft(first, " "))
+		var b strings.Builder
+		for i, l := range strings.Split(text, "\n") {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			if len(l) >= n {
+				l = l[n:]
+			} else {
+				l = strings.TrimLeft(l, " ")
+			}
+			b.WriteString(l)
+		}
+		text = b.String()
+	}
+	text = strings.TrimRight(text, "\n")
+	if strings.HasPrefix(style, ">") {
+		text = fold(text)
+	}
+	if !strings.HasSuffix(style, "-") && text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+// fold joins lines of a folded block scalar, keeping empty lines and
+// more-indented lines as line breaks.
+func fold(s string) string {
+	var b strings.Builder
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if l == "" || prev == "" || strings.HasPrefix(l, " ") || strings.HasPrefix(prev, " ") {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+func (p *parser) flowSequence(s string) (any, error) {
+	if !strings.HasSuffix(s, "]") {
+		p.pos--
+		return nil, p.errorf("unterminated flow sequence")
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	out := []any{}
+	if inner == "" {
+		return out, nil
+	}
+	for _, item := range splitFlow(inner) {
+		v, err := scalar(strings.TrimSpace(item))
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("%v", err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// splitFlow splits a flow sequence body on commas outside quotes.
+func splitFlow(s string) []string {
+	var out []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// splitKey splits "key: value" into its parts.
+func splitKey(s string) (key, rest string, ok bool) {
+	if s[0] == '"' || s[0] == '\'' {
+		end := closingQuote(s)
+		if end < 0 || end+1 >= len(s) || s[end+1] != ':' {
+			return "", "", false
+		}
+		k, err := scalar(s[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		rest = s[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		return k.(string), strings.TrimSpace(rest), true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q:
+			if q == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// scalar resolves a flow scalar using the YAML 1.2 core schema.
+func scalar(s string) (any, error) {
+	switch {
+	case s == "":
+		return nil, nil
+	case s[0] == '"':
+		if closingQuote(s) != len(s)-1 {
+			return nil, fmt.Errorf("malformed double-quoted string %s", s)
+		}
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("malformed double-quoted string %s", s)
+		}
+		return v, nil
+	case s[0] == '\'':
+		if closingQuote(s) != len(s)-1 {
+			return nil, fmt.Errorf("malformed single-quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if intPattern.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	if floatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,254 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestYAMLFixtures parses each testdata/*.yaml and compares the result,
+// as indented JSON, with the .json file beside it.
+func TestYAMLFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			got := b.Bytes()
+			golden := strings.TrimSuffix(file, ".yaml") + ".json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file (rerun with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs (rerun with -update to accept)\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestYAMLScalars(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"", nil},
+		{"~", nil},
+		{"NULL", nil},
+		{"true", true},
+		{"False", false},
+		{"yes", "yes"}, // YAML 1.2: not a boolean
+		{"0", int64(0)},
+		{"-12", int64(-12)},
+		{"+5", int64(5)},
+		{"3.25", 3.25},
+		{"-.5", -0.5},
+		{"2e-3", 0.002},
+		{"1.", 1.0},
+		{"9223372036854775808", 9223372036854775808.0}, // past int64
+		{"0x1F", "0x1F"},
+		{"1_000", "1_000"},
+		{"12:30", "12:30"},
+		{`"a\nb"`, "a\nb"},
+		{`"\u00e9"`, "é"},
+		{`'don''t'`, "don't"},
+		{`''`, ""},
+		{`""`, ""},
+		{`f/2.8`, "f/2.8"},
+	}
+	for _, tt := range tests {
+		got, err := scalar(tt.in)
+		if err != nil {
+			t.Errorf("scalar(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scalar(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestYAMLDocuments(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     any
+	}{
+		{"empty", "", nil},
+		{"comments only", "# nothing\n\n  # here\n", nil},
+		{"document markers", "---\na: 1\n...\nb: 2\n", map[string]any{"a": int64(1)}},
+		{"crlf", "a: 1\r\nb:\r\n  - x\r\n", map[string]any{"a": int64(1), "b": []any{"x"}}},
+		{"top-level sequence", "- 1\n- two\n", []any{int64(1), "two"}},
+		{"sequence under key at same indent", "a:\n- 1\n- 2\nb: 3\n", map[string]any{"a": []any{int64(1), int64(2)}, "b": int64(3)}},
+		{"null values", "a:\nb: ~\n", map[string]any{"a": nil, "b": nil}},
+		{"dash with nested block", "-\n  a: 1\n-\n", []any{map[string]any{"a": int64(1)}, nil}},
+		{"sequence of sequences", "- - 1\n  - 2\n", []any{[]any{int64(1), int64(2)}}},
+		{"flow sequence", `a: [1, "b, c", 'd', ]`, map[string]any{"a": []any{int64(1), "b, c", "d", nil}}},
+		{"empty flow", "a: []\nb: {}\n", map[string]any{"a": []any{}, "b": map[string]any{}}},
+		{"comment after quote", `a: "x # y" # z`, map[string]any{"a": "x # y"}},
+		{"hash without space", "a: b#c\n", map[string]any{"a": "b#c"}},
+		{"quoted keys", "\"a: b\": 1\n'c''d': 2\n", map[string]any{"a: b": int64(1), "c'd": int64(2)}},
+		{"literal keeps lines", "a: |\n  x\n\n  y\nb: 1\n", map[string]any{"a": "x\n\ny\n", "b": int64(1)}},
+		{"folded", "a: >-\n  x\n  y\n", map[string]any{"a": "x y"}},
+		{"empty block scalar", "a: |\nb: 1\n", map[string]any{"a": "", "b": int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name, in string
+		line     int
+		msg      string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", 2, "tabs are not allowed"},
+		{"not a mapping entry", "a: 1\nb\n", 2, `expected "key: value", got "b"`},
+		{"duplicate key", "a: 1\na: 2\n", 2, `duplicate key "a"`},
+		{"over-indented", "a: 1\n  b: 2\n", 2, "unexpected indentation"},
+		{"over-indented item", "- 1\n  - 2\n", 2, "unexpected indentation"},
+		{"dedent below document", "  a: 1\nb: 2\n", 2, "unexpected indentation"},
+		{"unterminated flow", "a: [1, 2\n", 1, "unterminated flow sequence"},
+		{"malformed flow item", `a: ["x]`, 1, "malformed double-quoted string"},
+		{"flow mapping", "a: {b: 1}\n", 1, "flow mappings are not supported"},
+		{"anchor", "a: &x 1\n", 1, "anchors, aliases and tags are not supported"},
+		{"alias", "a: 1\nb: *x\n", 2, "anchors, aliases and tags are not supported"},
+		{"tag", "a: !!str 1\n", 1, "anchors, aliases and tags are not supported"},
+		{"unterminated double quote", `a: "x`, 1, "malformed double-quoted string"},
+		{"bad escape", `a: "\q"`, 1, "malformed double-quoted string"},
+		{"text after quote", `a: "x" y`, 1, "malformed double-quoted string"},
+		{"unterminated single quote", "a: 'x\n", 1, "malformed single-quoted string"},
+		{"unterminated quoted key", "\"a: 1\n", 1, "expected \"key: value\""},
+		{"item in mapping", "a: 1\n- 2\n", 2, "unexpected indentation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.in))
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error %v, want a *yaml.Error", err)
+			}
+			if e.Line != tt.line || !strings.Contains(e.Msg, tt.msg) {
+				t.Errorf("error %q at line %d, want %q at line %d", e.Msg, e.Line, tt.msg, tt.line)
+			}
+		})
+	}
+}
+
+// TestYAMLNoPanic feeds truncations and corruptions of the fixtures to
+// Parse, which must return rather than panic.
+func TestYAMLNoPanic(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	inputs := []string{":", "- :", "\"", "'", "[", "]", "- - -", "a: |", ">", "? a", "a:\n  - b\n c: d", "\xff\xfe: \x00"}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range data {
+			inputs = append(inputs, string(data[:i]))
+			corrupt := bytes.Clone(data)
+			corrupt[i] = "\t\"'[]:-#|>\n "[i%12]
+			inputs = append(inputs, string(corrupt))
+		}
+	}
+	for _, in := range inputs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Parse(%q) panicked: %v", in, r)
+				}
+			}()
+			Parse([]byte(in))
+		}()
+	}
+}
+
+func TestYAMLUnmarshal(t *testing.T) {
+	type config struct {
+		Units  string   `json:"units"`
+		Fields []string `json:"fields"`
+		Home   []struct {
+			Name   string  `json:"name"`
+			Radius float64 `json:"radius"`
+		} `json:"home"`
+	}
+	tests := []struct {
+		name   string
+		in     string
+		strict bool
+		want   config
+		err    string
+	}{
+		{"fields", "units: metric\nfields: [iso]\nhome:\n  - name: Home\n    radius: 250\n", true,
+			config{Units: "metric", Fields: []string{"iso"}, Home: []struct {
+				Name   string  `json:"name"`
+				Radius float64 `json:"radius"`
+			}{{"Home", 250}}}, ""},
+		{"unknown ignored", "unit: imperial\n", false, config{}, ""},
+		{"unknown rejected", "unit: imperial\n", true, config{}, `yaml: unknown field "unit"`},
+		{"wrong type", "fields: 3\n", false, config{}, "cannot unmarshal number"},
+		{"parse error", "a: [\n", true, config{}, "yaml: line 1: unterminated flow sequence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got config
+			var err error
+			if tt.strict {
+				err = UnmarshalStrict([]byte(tt.in), &got)
+			} else {
+				err = Unmarshal([]byte(tt.in), &got)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}